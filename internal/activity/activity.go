@@ -0,0 +1,224 @@
+// Package activity tracks where time goes: which workspace and branch a tmux
+// client has focused, and how long agents have been working in them. Events
+// come from tmux hooks and agent hooks; the report adds up the spans
+// between them.
+package activity
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/store"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/worktree"
+)
+
+// Event kinds and the states they record.
+const (
+	KindFocus = "focus"
+	KindAgent = "agent"
+
+	StateFocused = "focused"
+	StateBlurred = "blurred"
+	StateWorking = "working"
+)
+
+// Side effects, replaced in tests.
+var (
+	appendEvent   = store.AppendActivityEvent
+	appendChange  = store.AppendActivityChange
+	lastEvent     = store.LastActivityEvent
+	pruneEvents   = store.PruneActivityEvents
+	listWorktrees = worktree.ListInDir
+	now           = time.Now
+)
+
+// focusHooks are the tmux hooks that report focus changes, with whether
+// the hook passes the session path.
+var focusHooks = []struct {
+	name    string
+	focused bool
+}{
+	{"client-attached", true},
+	{"client-session-changed", true},
+	{"client-detached", false},
+}
+
+// FocusHookCommand is the tmux command a focus hook runs. Without the path
+// the client is recorded as blurred.
+func FocusHookCommand(focused bool) string {
+	if !focused {
+		return `run-shell -b "kitmux hook focus --client=#{q:client_tty}"`
+	}
+	return `run-shell -b "kitmux hook focus --client=#{q:client_tty} --path #{q:session_path}"`
+}
+
+// InstallFocusHooks sets the global tmux hooks that record which session
+// each client shows.
+func InstallFocusHooks(client tmux.Client) error {
+	for _, h := range focusHooks {
+		if err := client.SetGlobalHook(h.name, FocusHookCommand(h.focused)); err != nil {
+			return fmt.Errorf("set hook %s: %w", h.name, err)
+		}
+	}
+	return nil
+}
+
+// RecordFocus records that client now shows a session rooted at dir. An
+// empty dir means the client detached or lost focus. Attaching fires more
+// than one hook, so a change already recorded for the client is dropped.
+// Each recorded change also drops events older than the retention period.
+func RecordFocus(client, dir string) error {
+	e := store.ActivityEvent{At: now(), Kind: KindFocus, Source: client, State: StateBlurred}
+	if dir != "" {
+		e.State = StateFocused
+		e.Workspace, e.Branch = locate(dir)
+	}
+	added, err := appendChange(e)
+	if err != nil || !added {
+		return err
+	}
+	if keep, err := config.ParseDuration(config.ActivityRetention()); err == nil {
+		return pruneEvents(e.At.Add(-keep))
+	}
+	return nil
+}
+
+// RecordAgent records the state of the agent running in source (a pane or
+// session) from dir. Repeats of the current state are dropped before git is
+// asked for the branch, since agents report on every tool call.
+func RecordAgent(source, dir, agent, state string) error {
+	last, ok, err := lastEvent(KindAgent, source)
+	if err == nil && ok && last.State == state && last.Agent == agent {
+		return nil
+	}
+	e := store.ActivityEvent{At: now(), Kind: KindAgent, Source: source, Agent: agent, State: state}
+	e.Workspace, e.Branch = locate(dir)
+	return appendEvent(e)
+}
+
+// locate returns the main worktree of the repo containing dir and the branch
+// of the worktree dir is in. Outside a repo the workspace is dir itself.
+func locate(dir string) (workspace, branch string) {
+	dir = filepath.Clean(dir)
+	wts, err := listWorktrees(dir)
+	if err != nil || len(wts) == 0 {
+		return dir, ""
+	}
+	workspace = wts[0].Path
+	best := ""
+	for _, wt := range wts {
+		if wt.IsMain {
+			workspace = wt.Path
+		}
+		if within(dir, wt.Path) && len(wt.Path) > len(best) {
+			best, branch = wt.Path, wt.Branch
+		}
+	}
+	return workspace, branch
+}
+
+func within(dir, root string) bool {
+	root = filepath.Clean(root)
+	return dir == root || strings.HasPrefix(dir, root+string(filepath.Separator))
+}
+
+// Row is the time spent on one branch of a workspace.
+type Row struct {
+	Workspace string
+	Branch    string
+	Focused   time.Duration
+	Agents    map[string]time.Duration
+}
+
+// AgentTime is the working time of all agents on the row.
+func (r Row) AgentTime() time.Duration {
+	var total time.Duration
+	for _, d := range r.Agents {
+		total += d
+	}
+	return total
+}
+
+// Report adds up the spans of events between since and until. A span runs
+// from an event to the next event of the same source; only focused and
+// working spans count, each at most maxSpan long. Rows are ordered by
+// focused time, then agent time.
+func Report(events []store.ActivityEvent, since, until time.Time, maxSpan time.Duration) []Row {
+	events = append([]store.ActivityEvent(nil), events...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+
+	type key struct{ workspace, branch string }
+	rows := map[key]*Row{}
+	row := func(e store.ActivityEvent) *Row {
+		k := key{e.Workspace, e.Branch}
+		if rows[k] == nil {
+			rows[k] = &Row{Workspace: e.Workspace, Branch: e.Branch, Agents: map[string]time.Duration{}}
+		}
+		return rows[k]
+	}
+	add := func(e store.ActivityEvent, end time.Time) {
+		start := e.At
+		if start.Before(since) {
+			start = since
+		}
+		if maxSpan > 0 && end.After(e.At.Add(maxSpan)) {
+			end = e.At.Add(maxSpan)
+		}
+		if end.After(until) {
+			end = until
+		}
+		if !end.After(start) {
+			return
+		}
+		switch {
+		case e.Kind == KindFocus && e.State == StateFocused:
+			row(e).Focused += end.Sub(start)
+		case e.Kind == KindAgent && e.State == StateWorking:
+			row(e).Agents[e.Agent] += end.Sub(start)
+		}
+	}
+
+	open := map[string]store.ActivityEvent{}
+	for _, e := range events {
+		source := e.Kind + "\x00" + e.Source
+		if prev, ok := open[source]; ok {
+			add(prev, e.At)
+		}
+		open[source] = e
+	}
+	for _, e := range open {
+		add(e, until)
+	}
+
+	out := make([]Row, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Focused != out[j].Focused {
+			return out[i].Focused > out[j].Focused
+		}
+		if a, b := out[i].AgentTime(), out[j].AgentTime(); a != b {
+			return a > b
+		}
+		if out[i].Workspace != out[j].Workspace {
+			return out[i].Workspace < out[j].Workspace
+		}
+		return out[i].Branch < out[j].Branch
+	})
+	return out
+}
+
+// Load returns the report for the events since since.
+func Load(since, until time.Time, maxSpan time.Duration) ([]Row, error) {
+	events, err := store.LoadActivityEvents(since)
+	if err != nil {
+		return nil, err
+	}
+	return Report(events, since, until, maxSpan), nil
+}
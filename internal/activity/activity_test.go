@@ -0,0 +1,100 @@
+package activity
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/store"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
+	"github.com/miltonparedes/kitmux/internal/worktree"
+)
+
+var t0 = time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+
+func at(minutes int) time.Time { return t0.Add(time.Duration(minutes) * time.Minute) }
+
+func TestReportAddsFocusedAndWorkingSpans(t *testing.T) {
+	events := []store.ActivityEvent{
+		{At: at(-30), Kind: KindFocus, Source: "tty1", Workspace: "/src/web", Branch: "main", State: StateFocused},
+		{At: at(60), Kind: KindFocus, Source: "tty1", Workspace: "/src/api", Branch: "feat/pay", State: StateFocused},
+		{At: at(70), Kind: KindAgent, Source: "%1", Workspace: "/src/api", Branch: "feat/pay", Agent: "claude", State: StateWorking},
+		{At: at(100), Kind: KindAgent, Source: "%1", Workspace: "/src/api", Branch: "feat/pay", Agent: "claude", State: "idle"},
+		{At: at(120), Kind: KindFocus, Source: "tty1", State: StateBlurred},
+		// Left working with no further events: capped at maxSpan.
+		{At: at(200), Kind: KindAgent, Source: "%2", Workspace: "/src/web", Branch: "main", Agent: "codex", State: StateWorking},
+	}
+	rows := Report(events, t0, at(24*60), 2*time.Hour)
+	if len(rows) != 2 {
+		t.Fatalf("rows = %+v", rows)
+	}
+	web, api := rows[0], rows[1]
+	if web.Workspace != "/src/web" || web.Focused != time.Hour || web.Agents["codex"] != 2*time.Hour {
+		t.Fatalf("web = %+v", web)
+	}
+	if api.Branch != "feat/pay" || api.Focused != time.Hour || api.Agents["claude"] != 30*time.Minute {
+		t.Fatalf("api = %+v", api)
+	}
+}
+
+func TestRecordFocusResolvesBranchAndDropsRepeats(t *testing.T) {
+	t.Setenv("KITMUX_ACTIVITY_RETENTION", "30d")
+	var recorded []store.ActivityEvent
+	var pruned []time.Time
+	prevChange, prevPrune, prevList, prevNow := appendChange, pruneEvents, listWorktrees, now
+	t.Cleanup(func() { appendChange, pruneEvents, listWorktrees, now = prevChange, prevPrune, prevList, prevNow })
+	appendChange = func(e store.ActivityEvent) (bool, error) {
+		for i := len(recorded) - 1; i >= 0; i-- {
+			if last := recorded[i]; last.Kind == e.Kind && last.Source == e.Source {
+				if last.State == e.State && last.Workspace == e.Workspace && last.Branch == e.Branch {
+					return false, nil
+				}
+				break
+			}
+		}
+		recorded = append(recorded, e)
+		return true, nil
+	}
+	pruneEvents = func(cutoff time.Time) error {
+		pruned = append(pruned, cutoff)
+		return nil
+	}
+	listWorktrees = func(string) ([]worktree.Worktree, error) {
+		return []worktree.Worktree{
+			{Path: "/src/api", Branch: "main", IsMain: true},
+			{Path: "/src/api.pay", Branch: "feat/pay"},
+		}, nil
+	}
+	now = func() time.Time { return t0 }
+
+	for _, dir := range []string{"/src/api.pay/cmd", "/src/api.pay", "/src/api", ""} {
+		if err := RecordFocus("tty1", dir); err != nil {
+			t.Fatalf("RecordFocus(%q): %v", dir, err)
+		}
+	}
+	if len(recorded) != 3 {
+		t.Fatalf("recorded = %+v", recorded)
+	}
+	if recorded[0].Workspace != "/src/api" || recorded[0].Branch != "feat/pay" || recorded[1].Branch != "main" || recorded[2].State != StateBlurred {
+		t.Fatalf("recorded = %+v", recorded)
+	}
+	if len(pruned) != 3 || !pruned[0].Equal(t0.Add(-30*24*time.Hour)) {
+		t.Fatalf("pruned = %v, want one cutoff per recorded change", pruned)
+	}
+}
+
+func TestInstallFocusHooks(t *testing.T) {
+	srv := tmuxtest.New()
+	if err := InstallFocusHooks(srv); err != nil {
+		t.Fatalf("InstallFocusHooks: %v", err)
+	}
+	for hook, want := range map[string]string{
+		"client-attached":        "--path #{q:session_path}",
+		"client-session-changed": "--path #{q:session_path}",
+		"client-detached":        "kitmux hook focus --client=#{q:client_tty}\"",
+	} {
+		if got := srv.GlobalHook(hook); !strings.Contains(got, want) {
+			t.Fatalf("%s hook = %q, want it to contain %q", hook, got, want)
+		}
+	}
+}
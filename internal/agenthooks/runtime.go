@@ -12,9 +12,11 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/miltonparedes/kitmux/internal/activity"
 	"github.com/miltonparedes/kitmux/internal/agentresume"
 	"github.com/miltonparedes/kitmux/internal/agents"
 	"github.com/miltonparedes/kitmux/internal/agenttrack"
+	"github.com/miltonparedes/kitmux/internal/config"
 	"github.com/miltonparedes/kitmux/internal/tmux"
 )
 
@@ -38,7 +40,14 @@ const (
 
 var SpinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 
-var resolveAncestorContext = agenttrack.ResolveAncestor
+// Side effects outside tmux, replaced in tests.
+var (
+	resolveAncestorContext = agenttrack.ResolveAncestor
+	ringBell               = emitBell
+	launchSpinner          = startSpinner
+	recordActivity         = activity.RecordAgent
+	now                    = time.Now
+)
 
 type StateEvent struct {
 	State string
@@ -54,20 +63,6 @@ type AgentEvent struct {
 	StdinJSON bool
 }
 
-type StateOps struct {
-	CurrentPaneTitle        func() (string, error)
-	SetCurrentPaneOption    func(string, string) error
-	SetCurrentSessionOption func(string, string) error
-	SetPaneOption           func(string, string, string) error
-	SetSessionOption        func(string, string, string) error
-	ShowPaneOption          func(string, string) (string, error)
-	ShowSessionOption       func(string, string) (string, error)
-	EmitBell                func(io.Writer) error
-	StartSpinner            func(SpinnerTarget) error
-	RefreshSessionClients   func(string)
-	Now                     func() time.Time
-}
-
 type SpinnerTarget struct {
 	PaneID      string
 	SessionName string
@@ -97,34 +92,15 @@ type hookInput struct {
 	ID               string         `json:"id"`
 }
 
-func DefaultStateOps() StateOps {
-	return StateOps{
-		CurrentPaneTitle:        tmux.CurrentPaneTitle,
-		SetCurrentPaneOption:    tmux.SetCurrentPaneOption,
-		SetCurrentSessionOption: tmux.SetCurrentSessionOption,
-		SetPaneOption:           tmux.SetPaneOption,
-		SetSessionOption:        tmux.SetSessionOption,
-		ShowPaneOption:          tmux.ShowPaneOption,
-		ShowSessionOption:       tmux.ShowSessionOption,
-		EmitBell:                emitBell,
-		StartSpinner:            startSpinner,
-		RefreshSessionClients:   refreshSessionClients,
-		Now:                     time.Now,
-	}
-}
-
-func RunStateEvent(event StateEvent, out io.Writer, ops StateOps) error {
+func RunStateEvent(event StateEvent, out io.Writer, client tmux.Client) error {
 	return RunAgentEvent(AgentEvent{
 		Event: "legacy-state",
 		State: event.State,
 		Bell:  event.Bell,
-	}, nil, out, ops)
+	}, nil, out, client)
 }
 
-func RunAgentEvent(event AgentEvent, in io.Reader, out io.Writer, ops StateOps) error {
-	canCompareSessionOptions := ops.ShowSessionOption != nil
-	ops = ops.withDefaults()
-
+func RunAgentEvent(event AgentEvent, in io.Reader, out io.Writer, client tmux.Client) error {
 	var input hookInput
 	var rawInput []byte
 	if event.StdinJSON && in != nil {
@@ -147,28 +123,28 @@ func RunAgentEvent(event AgentEvent, in io.Reader, out io.Writer, ops StateOps)
 	detail := sanitizeDetail(firstNonEmpty(event.Detail, deriveDetail(input)))
 	sessionPath := firstNonEmpty(input.TranscriptPath, input.SessionPath)
 	sessionID := deriveAgentSessionID(eventName, input)
-	if shouldIgnoreAgentSessionEvent(agentID, eventName, input, sessionID, sessionPath, ctx, ops) {
+	if shouldIgnoreAgentSessionEvent(agentID, eventName, input, sessionID, sessionPath, ctx, client) {
 		return nil
 	}
 	sessionID = agentresume.CanonicalSessionID(agentID, sessionID, sessionPath)
-	updated := fmt.Sprintf("%d", ops.Now().UnixMilli())
-	prefix, displayTitle := agentTitleParts(ctx, state, agentID, ops)
+	recordAgentActivity(ctx, agentID, state)
+	updated := fmt.Sprintf("%d", now().UnixMilli())
+	prefix, displayTitle := agentTitleParts(ctx, state, agentID, client)
 
-	setPaneOptions(ops, ctx.PaneID, state, eventName, detail, updated, prefix, displayTitle, sessionID)
+	setPaneOptions(client, ctx.PaneID, state, eventName, detail, updated, prefix, displayTitle, sessionID)
 	if shouldSyncSession(ctx) {
-		syncSessionState(ops, sessionStateUpdate{
-			sessionName:     ctx.SessionName,
-			state:           state,
-			eventName:       eventName,
-			detail:          detail,
-			updated:         updated,
-			prefix:          prefix,
-			displayTitle:    displayTitle,
-			sessionID:       sessionID,
-			compareExisting: canCompareSessionOptions,
+		syncSessionState(client, sessionStateUpdate{
+			sessionName:  ctx.SessionName,
+			state:        state,
+			eventName:    eventName,
+			detail:       detail,
+			updated:      updated,
+			prefix:       prefix,
+			displayTitle: displayTitle,
+			sessionID:    sessionID,
 		})
 		if state == stateWorking && prefix != "" {
-			_ = ops.StartSpinner(SpinnerTarget{
+			_ = launchSpinner(SpinnerTarget{
 				PaneID:      ctx.PaneID,
 				SessionName: ctx.SessionName,
 				AgentID:     agentID,
@@ -176,14 +152,14 @@ func RunAgentEvent(event AgentEvent, in io.Reader, out io.Writer, ops StateOps)
 			})
 		}
 	} else if ctx.PaneID != "" && state == stateWorking && prefix != "" {
-		_ = ops.StartSpinner(SpinnerTarget{
+		_ = launchSpinner(SpinnerTarget{
 			PaneID:  ctx.PaneID,
 			AgentID: agentID,
 			Token:   updated,
 		})
 	}
 	if bell {
-		_ = ops.EmitBell(out)
+		_ = ringBell(out)
 	}
 	return nil
 }
@@ -193,7 +169,7 @@ func shouldIgnoreAgentSessionEvent(
 	input hookInput,
 	sessionID, sessionPath string,
 	ctx tmux.ThreadContext,
-	ops StateOps,
+	client tmux.Client,
 ) bool {
 	if agentID != "droid" || sessionID == "" {
 		return false
@@ -201,7 +177,7 @@ func shouldIgnoreAgentSessionEvent(
 	if agentresume.IsChildSession(agentID, sessionID, sessionPath) {
 		return true
 	}
-	currentID := currentAgentSessionID(agentID, ctx, ops)
+	currentID := currentAgentSessionID(agentID, ctx, client)
 	if currentID == "" {
 		return false
 	}
@@ -212,13 +188,13 @@ func shouldIgnoreAgentSessionEvent(
 	return !isDroidMainSessionRestart(eventName, input)
 }
 
-func currentAgentSessionID(agentID string, ctx tmux.ThreadContext, ops StateOps) string {
+func currentAgentSessionID(agentID string, ctx tmux.ThreadContext, client tmux.Client) string {
 	var id string
-	if ctx.SessionName != "" && ops.ShowSessionOption != nil {
-		id, _ = ops.ShowSessionOption(ctx.SessionName, agentSessionIDOption)
+	if ctx.SessionName != "" {
+		id, _ = client.ShowSessionOption(ctx.SessionName, agentSessionIDOption)
 	}
-	if id == "" && ctx.PaneID != "" && ops.ShowPaneOption != nil {
-		id, _ = ops.ShowPaneOption(ctx.PaneID, agentSessionIDOption)
+	if id == "" && ctx.PaneID != "" {
+		id, _ = client.ShowPaneOption(ctx.PaneID, agentSessionIDOption)
 	}
 	return agentresume.CanonicalSessionID(agentID, id, "")
 }
@@ -235,11 +211,11 @@ func isDroidMainSessionRestart(eventName string, input hookInput) bool {
 	}
 }
 
-func agentTitleParts(ctx tmux.ThreadContext, state, agentID string, ops StateOps) (string, string) {
+func agentTitleParts(ctx tmux.ThreadContext, state, agentID string, client tmux.Client) (string, string) {
 	if !hasTmuxTarget(ctx) {
 		return "", ""
 	}
-	paneTitle := currentPaneTitle(ops)
+	paneTitle := currentPaneTitle(client)
 	prefix := titlePrefix(state, agentID, paneTitle)
 	if state == stateWorking && ctx.PaneID == "" {
 		return "", ""
@@ -280,12 +256,12 @@ func hasTmuxTarget(ctx tmux.ThreadContext) bool {
 	return ctx.PaneID != "" || shouldSyncSession(ctx)
 }
 
-func setPaneOptions(ops StateOps, paneID, state, eventName, detail, updated, prefix, displayTitle, sessionID string) {
+func setPaneOptions(client tmux.Client, paneID, state, eventName, detail, updated, prefix, displayTitle, sessionID string) {
 	if paneID == "" {
 		return
 	}
 	set := func(option, value string) error {
-		return ops.SetPaneOption(paneID, option, value)
+		return client.SetPaneOption(paneID, option, value)
 	}
 	_ = set(agentStateOption, state)
 	_ = set(agentEventOption, eventName)
@@ -299,37 +275,30 @@ func setPaneOptions(ops StateOps, paneID, state, eventName, detail, updated, pre
 }
 
 type sessionStateUpdate struct {
-	sessionName     string
-	state           string
-	eventName       string
-	detail          string
-	updated         string
-	prefix          string
-	displayTitle    string
-	sessionID       string
-	compareExisting bool
+	sessionName  string
+	state        string
+	eventName    string
+	detail       string
+	updated      string
+	prefix       string
+	displayTitle string
+	sessionID    string
 }
 
-func syncSessionState(ops StateOps, update sessionStateUpdate) {
-	if setSessionOptions(ops, update) {
-		ops.RefreshSessionClients(update.sessionName)
+func syncSessionState(client tmux.Client, update sessionStateUpdate) {
+	if setSessionOptions(client, update) {
+		_ = client.RefreshClients(update.sessionName)
 	}
 }
 
-func setSessionOptions(ops StateOps, update sessionStateUpdate) bool {
+func setSessionOptions(client tmux.Client, update sessionStateUpdate) bool {
 	if update.sessionName == "" {
 		return false
 	}
-	titleChanged := true
-	if update.compareExisting {
-		titleChanged = sessionOptionWillChange(ops, update.sessionName, agentTitlePrefixOption, update.prefix) ||
-			sessionOptionWillChange(ops, update.sessionName, agentTitleDisplayOption, update.displayTitle)
-	}
-	set := ops.SetCurrentSessionOption
-	if update.sessionName != "" && ops.SetSessionOption != nil {
-		set = func(option, value string) error {
-			return ops.SetSessionOption(update.sessionName, option, value)
-		}
+	titleChanged := sessionOptionWillChange(client, update.sessionName, agentTitlePrefixOption, update.prefix) ||
+		sessionOptionWillChange(client, update.sessionName, agentTitleDisplayOption, update.displayTitle)
+	set := func(option, value string) error {
+		return client.SetSessionOption(update.sessionName, option, value)
 	}
 	_ = set(agentStateOption, update.state)
 	_ = set(agentEventOption, update.eventName)
@@ -343,11 +312,8 @@ func setSessionOptions(ops StateOps, update sessionStateUpdate) bool {
 	return titleChanged
 }
 
-func sessionOptionWillChange(ops StateOps, sessionName, option, value string) bool {
-	if ops.ShowSessionOption == nil {
-		return true
-	}
-	current, err := ops.ShowSessionOption(sessionName, option)
+func sessionOptionWillChange(client tmux.Client, sessionName, option, value string) bool {
+	current, err := client.ShowSessionOption(sessionName, option)
 	if err != nil {
 		return true
 	}
@@ -366,6 +332,20 @@ func readHookInputRaw(in io.Reader) (hookInput, []byte) {
 	return input, data
 }
 
+// recordAgentActivity feeds the time report. Failures are ignored so a
+// broken state database never blocks the agent.
+func recordAgentActivity(ctx tmux.ThreadContext, agentID, state string) {
+	source := firstNonEmpty(ctx.PaneID, ctx.SessionName)
+	if source == "" || !config.ActivityTracking() {
+		return
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	_ = recordActivity(source, dir, agentID, state)
+}
+
 func logHookEvent(event AgentEvent, eventName, state string, ctx tmux.ThreadContext, raw []byte) {
 	path := os.Getenv("KITMUX_HOOK_LOG")
 	if path == "" {
@@ -575,11 +555,8 @@ func sanitizeDetail(value string) string {
 	return value
 }
 
-func currentPaneTitle(ops StateOps) string {
-	if ops.CurrentPaneTitle == nil {
-		return ""
-	}
-	title, err := ops.CurrentPaneTitle()
+func currentPaneTitle(client tmux.Client) string {
+	title, err := client.CurrentPaneTitle()
 	if err != nil {
 		return ""
 	}
@@ -659,44 +636,6 @@ func firstNonEmpty(values ...string) string {
 	return ""
 }
 
-func (ops StateOps) withDefaults() StateOps {
-	defaults := DefaultStateOps()
-	if ops.CurrentPaneTitle == nil {
-		ops.CurrentPaneTitle = defaults.CurrentPaneTitle
-	}
-	if ops.SetCurrentPaneOption == nil {
-		ops.SetCurrentPaneOption = defaults.SetCurrentPaneOption
-	}
-	if ops.SetCurrentSessionOption == nil {
-		ops.SetCurrentSessionOption = defaults.SetCurrentSessionOption
-	}
-	if ops.SetPaneOption == nil {
-		ops.SetPaneOption = defaults.SetPaneOption
-	}
-	if ops.SetSessionOption == nil {
-		ops.SetSessionOption = defaults.SetSessionOption
-	}
-	if ops.ShowPaneOption == nil {
-		ops.ShowPaneOption = defaults.ShowPaneOption
-	}
-	if ops.ShowSessionOption == nil {
-		ops.ShowSessionOption = defaults.ShowSessionOption
-	}
-	if ops.EmitBell == nil {
-		ops.EmitBell = defaults.EmitBell
-	}
-	if ops.StartSpinner == nil {
-		ops.StartSpinner = defaults.StartSpinner
-	}
-	if ops.RefreshSessionClients == nil {
-		ops.RefreshSessionClients = defaults.RefreshSessionClients
-	}
-	if ops.Now == nil {
-		ops.Now = defaults.Now
-	}
-	return ops
-}
-
 func emitBell(out io.Writer) error {
 	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
 	if err == nil {
@@ -804,22 +743,6 @@ func setSpinnerPrefix(paneID, sessionName, prefix string) {
 	_ = exec.Command("tmux", "set-option", "-p", "-q", "-t", paneID, agentTitlePrefixOption, prefix).Run()
 	if sessionName != "" {
 		_ = exec.Command("tmux", "set-option", "-q", "-t", sessionName, agentTitlePrefixOption, prefix).Run()
-		refreshSessionClients(sessionName)
-	}
-}
-
-func refreshSessionClients(sessionName string) {
-	if sessionName == "" {
-		return
-	}
-	out, err := exec.Command("tmux", "list-clients", "-t", sessionName, "-F", "#{client_name}").Output()
-	if err != nil {
-		return
-	}
-	for _, client := range strings.Split(strings.TrimSpace(string(out)), "\n") {
-		if client == "" {
-			continue
-		}
-		_ = exec.Command("tmux", "refresh-client", "-t", client).Run()
+		_ = tmux.RefreshClients(sessionName)
 	}
 }
@@ -2,252 +2,207 @@ package agenthooks
 
 import (
 	"bytes"
-	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/miltonparedes/kitmux/internal/agenttrack"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
 )
 
 const testHookTitle = "hooks"
 
-func TestRunStateEventUpdatesPaneForAnyTmuxPane(t *testing.T) {
-	t.Setenv("KITMUX_TMUX_PANE", "%1")
-	paneOptions := make(map[string]string)
-	sessionOptions := make(map[string]string)
-	err := RunStateEvent(StateEvent{State: stateWorking}, nil, StateOps{
-		CurrentPaneTitle: func() (string, error) {
-			return "feat/threads", nil
-		},
-		SetCurrentPaneOption: func(option, value string) error {
-			paneOptions[option] = value
-			return nil
-		},
-		SetPaneOption: func(_, option, value string) error {
-			paneOptions[option] = value
-			return nil
-		},
-		SetCurrentSessionOption: func(option, value string) error {
-			sessionOptions[option] = value
-			return nil
-		},
-		EmitBell:     func(_ io.Writer) error { return nil },
-		StartSpinner: func(SpinnerTarget) error { return nil },
-		Now:          func() time.Time { return time.UnixMilli(1234) },
+// hookHarness runs hook events against a fake tmux server and records the
+// side effects that happen outside tmux.
+type hookHarness struct {
+	srv      *tmuxtest.Server
+	mark     int
+	spinners []SpinnerTarget
+	bells    int
+	activity []string
+}
+
+func newHookHarness(t *testing.T, at int64) *hookHarness {
+	t.Helper()
+	h := &hookHarness{srv: tmuxtest.New()}
+	originalBell, originalSpinner, originalNow, originalRecord := ringBell, launchSpinner, now, recordActivity
+	t.Cleanup(func() {
+		ringBell, launchSpinner, now, recordActivity = originalBell, originalSpinner, originalNow, originalRecord
 	})
-	if err != nil {
+	recordActivity = func(source, _, agent, state string) error {
+		h.activity = append(h.activity, source+" "+agent+" "+state)
+		return nil
+	}
+	ringBell = func(w io.Writer) error {
+		h.bells++
+		if w != nil {
+			_, _ = w.Write([]byte("\a"))
+		}
+		return nil
+	}
+	launchSpinner = func(target SpinnerTarget) error {
+		h.spinners = append(h.spinners, target)
+		return nil
+	}
+	now = func() time.Time { return time.UnixMilli(at) }
+	return h
+}
+
+// thread creates an agent thread session, focuses its pane and points the
+// tracking env at it.
+func (h *hookHarness) thread(t *testing.T, agentID, session, title string) string {
+	t.Helper()
+	pane := h.srv.AddSession(session, "/tmp/"+session)
+	_ = h.srv.SetPaneTitle(pane, title)
+	h.srv.Attach(pane)
+	t.Setenv("KITMUX_AGENT_ID", agentID)
+	t.Setenv("KITMUX_TMUX_SESSION", session)
+	t.Setenv("KITMUX_TMUX_PANE", pane)
+	t.Setenv("KITMUX_TMUX_THREAD", "1")
+	h.mark = len(h.srv.Calls())
+	return pane
+}
+
+// pane creates a plain session, focuses its pane and tracks only the pane.
+func (h *hookHarness) pane(t *testing.T, agentID, title string) string {
+	t.Helper()
+	pane := h.srv.AddSession("work", "/tmp/work")
+	_ = h.srv.SetPaneTitle(pane, title)
+	h.srv.Attach(pane)
+	t.Setenv("KITMUX_AGENT_ID", agentID)
+	t.Setenv("KITMUX_TMUX_SESSION", "")
+	t.Setenv("KITMUX_TMUX_PANE", pane)
+	t.Setenv("KITMUX_TMUX_THREAD", "")
+	h.mark = len(h.srv.Calls())
+	return pane
+}
+
+// calls returns the tmux commands run since setup that start with prefix.
+func (h *hookHarness) calls(prefix string) []string {
+	var out []string
+	for _, call := range h.srv.Calls()[h.mark:] {
+		if strings.HasPrefix(call, prefix) {
+			out = append(out, call)
+		}
+	}
+	return out
+}
+
+func TestRunStateEventUpdatesPaneForAnyTmuxPane(t *testing.T) {
+	h := newHookHarness(t, 1234)
+	pane := h.pane(t, "", "feat/threads")
+
+	if err := RunStateEvent(StateEvent{State: stateWorking}, nil, h.srv); err != nil {
 		t.Fatalf("RunStateEvent() error = %v", err)
 	}
-	if paneOptions[agentStateOption] != stateWorking || paneOptions[agentUpdatedOption] != "1234" {
-		t.Fatalf("paneOptions = %#v", paneOptions)
+	if h.srv.PaneOption(pane, agentStateOption) != stateWorking || h.srv.PaneOption(pane, agentUpdatedOption) != "1234" {
+		t.Fatalf("pane writes = %#v", h.calls("set-option -p"))
 	}
-	if sessionOptions[agentStateOption] != "" {
-		t.Fatalf("sessionOptions = %#v", sessionOptions)
+	if got := h.srv.SessionOption("work", agentStateOption); got != "" {
+		t.Fatalf("session state = %q", got)
 	}
 }
 
 func TestRunStateEventSyncsSessionForThread(t *testing.T) {
-	t.Setenv("KITMUX_AGENT_ID", "droid")
-	t.Setenv("KITMUX_TMUX_SESSION", "droid-app")
-	t.Setenv("KITMUX_TMUX_PANE", "%2")
-	t.Setenv("KITMUX_TMUX_THREAD", "1")
-	paneOptions := make(map[string]string)
-	sessionOptions := make(map[string]string)
-	var spinner SpinnerTarget
-	err := RunAgentEvent(AgentEvent{Agent: "droid", Event: "pre-tool-use", State: stateWorking}, nil, nil, StateOps{
-		CurrentPaneTitle: func() (string, error) {
-			return "feat/threads", nil
-		},
-		SetCurrentPaneOption: func(option, value string) error {
-			paneOptions[option] = value
-			return nil
-		},
-		SetPaneOption: func(_, option, value string) error {
-			paneOptions[option] = value
-			return nil
-		},
-		SetCurrentSessionOption: func(option, value string) error {
-			sessionOptions[option] = value
-			return nil
-		},
-		SetSessionOption: func(_, option, value string) error {
-			sessionOptions[option] = value
-			return nil
-		},
-		EmitBell: func(_ io.Writer) error { return nil },
-		StartSpinner: func(target SpinnerTarget) error {
-			spinner = target
-			return nil
-		},
-		RefreshSessionClients: func(string) {},
-		Now:                   func() time.Time { return time.UnixMilli(5678) },
-	})
+	h := newHookHarness(t, 5678)
+	pane := h.thread(t, "droid", "droid-app", "feat/threads")
+
+	err := RunAgentEvent(AgentEvent{Agent: "droid", Event: "pre-tool-use", State: stateWorking}, nil, nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if paneOptions[agentStateOption] != stateWorking || sessionOptions[agentStateOption] != stateWorking {
-		t.Fatalf("paneOptions=%#v sessionOptions=%#v", paneOptions, sessionOptions)
+	if h.srv.PaneOption(pane, agentStateOption) != stateWorking || h.srv.SessionOption("droid-app", agentStateOption) != stateWorking {
+		t.Fatalf("writes = %#v", h.calls("set-option"))
+	}
+	if h.srv.PaneOption(pane, agentEventOption) != "pre-tool-use" || h.srv.PaneOption(pane, agentTitlePrefixOption) != SpinnerFrames[0] {
+		t.Fatalf("pane writes = %#v", h.calls("set-option -p"))
 	}
-	if paneOptions[agentEventOption] != "pre-tool-use" || paneOptions[agentTitlePrefixOption] != SpinnerFrames[0] {
-		t.Fatalf("paneOptions = %#v", paneOptions)
+	if len(h.spinners) != 1 {
+		t.Fatalf("spinners = %#v", h.spinners)
 	}
-	if spinner.PaneID != "%2" || spinner.SessionName != "droid-app" || spinner.Token != "5678" {
+	if spinner := h.spinners[0]; spinner.PaneID != pane || spinner.SessionName != "droid-app" || spinner.Token != "5678" {
 		t.Fatalf("spinner = %#v", spinner)
 	}
+	if want := []string{pane + " droid working"}; !reflect.DeepEqual(h.activity, want) {
+		t.Fatalf("activity = %q", h.activity)
+	}
 }
 
 func TestRunAgentEventSkipsRefreshWhenSessionTitleStateIsUnchanged(t *testing.T) {
-	t.Setenv("KITMUX_AGENT_ID", "droid")
-	t.Setenv("KITMUX_TMUX_SESSION", "droid-app")
-	t.Setenv("KITMUX_TMUX_PANE", "%2")
-	t.Setenv("KITMUX_TMUX_THREAD", "1")
-	var refreshes int
-	err := RunAgentEvent(AgentEvent{Agent: "droid", Event: "stop", State: stateIdle}, nil, nil, StateOps{
-		CurrentPaneTitle: func() (string, error) {
-			return testHookTitle, nil
-		},
-		SetPaneOption:    func(_, _, _ string) error { return nil },
-		SetSessionOption: func(_, _, _ string) error { return nil },
-		ShowSessionOption: func(_, option string) (string, error) {
-			switch option {
-			case agentTitlePrefixOption:
-				return "⛬", nil
-			case agentTitleDisplayOption:
-				return testHookTitle, nil
-			default:
-				return "", nil
-			}
-		},
-		EmitBell: func(_ io.Writer) error { return nil },
-		StartSpinner: func(SpinnerTarget) error {
-			t.Fatal("spinner should not start for idle state")
-			return nil
-		},
-		RefreshSessionClients: func(string) { refreshes++ },
-		Now:                   func() time.Time { return time.UnixMilli(5678) },
-	})
+	h := newHookHarness(t, 5678)
+	h.thread(t, "droid", "droid-app", testHookTitle)
+	_ = h.srv.SetSessionOption("droid-app", agentTitlePrefixOption, "⛬")
+	_ = h.srv.SetSessionOption("droid-app", agentTitleDisplayOption, testHookTitle)
+	h.mark = len(h.srv.Calls())
+
+	err := RunAgentEvent(AgentEvent{Agent: "droid", Event: "stop", State: stateIdle}, nil, nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if refreshes != 0 {
-		t.Fatalf("refreshes = %d, want 0", refreshes)
+	if refreshes := h.calls("refresh-client"); len(refreshes) != 0 {
+		t.Fatalf("refreshes = %#v, want none", refreshes)
+	}
+	if len(h.spinners) != 0 {
+		t.Fatal("spinner should not start for idle state")
 	}
 }
 
 func TestRunAgentEventRefreshesWhenSessionTitleStateChanges(t *testing.T) {
-	t.Setenv("KITMUX_AGENT_ID", "droid")
-	t.Setenv("KITMUX_TMUX_SESSION", "droid-app")
-	t.Setenv("KITMUX_TMUX_PANE", "%2")
-	t.Setenv("KITMUX_TMUX_THREAD", "1")
-	var refreshed string
-	err := RunAgentEvent(AgentEvent{Agent: "droid", Event: "stop", State: stateIdle}, nil, nil, StateOps{
-		CurrentPaneTitle: func() (string, error) {
-			return testHookTitle, nil
-		},
-		SetPaneOption:    func(_, _, _ string) error { return nil },
-		SetSessionOption: func(_, _, _ string) error { return nil },
-		ShowSessionOption: func(_, option string) (string, error) {
-			switch option {
-			case agentTitlePrefixOption:
-				return "", nil
-			case agentTitleDisplayOption:
-				return "old", nil
-			default:
-				return "", nil
-			}
-		},
-		EmitBell:              func(_ io.Writer) error { return nil },
-		StartSpinner:          func(SpinnerTarget) error { return nil },
-		RefreshSessionClients: func(sessionName string) { refreshed = sessionName },
-		Now:                   func() time.Time { return time.UnixMilli(5678) },
-	})
+	h := newHookHarness(t, 5678)
+	h.thread(t, "droid", "droid-app", testHookTitle)
+	_ = h.srv.SetSessionOption("droid-app", agentTitleDisplayOption, "old")
+	h.mark = len(h.srv.Calls())
+
+	err := RunAgentEvent(AgentEvent{Agent: "droid", Event: "stop", State: stateIdle}, nil, nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if refreshed != "droid-app" {
-		t.Fatalf("refreshed = %q", refreshed)
+	if refreshes := h.calls("refresh-client"); !reflect.DeepEqual(refreshes, []string{"refresh-client droid-app"}) {
+		t.Fatalf("refreshes = %#v", refreshes)
 	}
 }
 
 func TestRunAgentEventPersistsSessionIDFromHookPayload(t *testing.T) {
-	t.Setenv("KITMUX_AGENT_ID", "claude")
-	t.Setenv("KITMUX_TMUX_SESSION", "claude-app")
-	t.Setenv("KITMUX_TMUX_PANE", "%3")
-	t.Setenv("KITMUX_TMUX_THREAD", "1")
-	paneOptions := make(map[string]string)
-	sessionOptions := make(map[string]string)
+	h := newHookHarness(t, 999)
+	pane := h.thread(t, "claude", "claude-app", "Claude · app")
 	payload := `{"hook_event_name":"SessionStart","session_id":"33333333-3333-4333-8333-333333333333"}`
 
-	err := RunAgentEvent(AgentEvent{Agent: "claude", StdinJSON: true}, strings.NewReader(payload), nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return "Claude · app", nil },
-		SetPaneOption: func(_, option, value string) error {
-			paneOptions[option] = value
-			return nil
-		},
-		SetSessionOption: func(_, option, value string) error {
-			sessionOptions[option] = value
-			return nil
-		},
-		EmitBell:              func(_ io.Writer) error { return nil },
-		StartSpinner:          func(SpinnerTarget) error { return nil },
-		RefreshSessionClients: func(string) {},
-		Now:                   func() time.Time { return time.UnixMilli(999) },
-	})
+	err := RunAgentEvent(AgentEvent{Agent: "claude", StdinJSON: true}, strings.NewReader(payload), nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if paneOptions[agentSessionIDOption] != "33333333-3333-4333-8333-333333333333" {
-		t.Fatalf("pane session id = %q", paneOptions[agentSessionIDOption])
+	if got := h.srv.PaneOption(pane, agentSessionIDOption); got != "33333333-3333-4333-8333-333333333333" {
+		t.Fatalf("pane session id = %q", got)
 	}
-	if sessionOptions[agentSessionIDOption] != "33333333-3333-4333-8333-333333333333" {
-		t.Fatalf("session id = %q", sessionOptions[agentSessionIDOption])
+	if got := h.srv.SessionOption("claude-app", agentSessionIDOption); got != "33333333-3333-4333-8333-333333333333" {
+		t.Fatalf("session id = %q", got)
 	}
 }
 
 func TestRunAgentEventPersistsDroidOpaqueSessionIDFromHookPayload(t *testing.T) {
-	t.Setenv("KITMUX_AGENT_ID", "droid")
-	t.Setenv("KITMUX_TMUX_SESSION", "droid-app")
-	t.Setenv("KITMUX_TMUX_PANE", "%3")
-	t.Setenv("KITMUX_TMUX_THREAD", "1")
-	paneOptions := make(map[string]string)
-	sessionOptions := make(map[string]string)
+	h := newHookHarness(t, 999)
+	pane := h.thread(t, "droid", "droid-app", "Droid · app")
 	payload := `{"hook_event_name":"SessionStart","session_id":"abc123","transcript_path":"/Users/me/.factory/projects/app/33333333-3333-4333-8333-333333333333.jsonl"}`
 
-	err := RunAgentEvent(AgentEvent{Agent: "droid", StdinJSON: true}, strings.NewReader(payload), nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return "Droid · app", nil },
-		SetPaneOption: func(_, option, value string) error {
-			paneOptions[option] = value
-			return nil
-		},
-		SetSessionOption: func(_, option, value string) error {
-			sessionOptions[option] = value
-			return nil
-		},
-		EmitBell:              func(_ io.Writer) error { return nil },
-		StartSpinner:          func(SpinnerTarget) error { return nil },
-		RefreshSessionClients: func(string) {},
-		Now:                   func() time.Time { return time.UnixMilli(999) },
-	})
+	err := RunAgentEvent(AgentEvent{Agent: "droid", StdinJSON: true}, strings.NewReader(payload), nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if paneOptions[agentSessionIDOption] != "abc123" {
-		t.Fatalf("pane session id = %q", paneOptions[agentSessionIDOption])
+	if got := h.srv.PaneOption(pane, agentSessionIDOption); got != "abc123" {
+		t.Fatalf("pane session id = %q", got)
 	}
-	if sessionOptions[agentSessionIDOption] != "abc123" {
-		t.Fatalf("session id = %q", sessionOptions[agentSessionIDOption])
+	if got := h.srv.SessionOption("droid-app", agentSessionIDOption); got != "abc123" {
+		t.Fatalf("session id = %q", got)
 	}
 }
 
 func TestRunAgentEventIgnoresDroidChildSessionEvents(t *testing.T) {
-	t.Setenv("KITMUX_AGENT_ID", "droid")
-	t.Setenv("KITMUX_TMUX_SESSION", "droid-app")
-	t.Setenv("KITMUX_TMUX_PANE", "%3")
-	t.Setenv("KITMUX_TMUX_THREAD", "1")
+	h := newHookHarness(t, 1)
+	h.thread(t, "droid", "droid-app", "⠹ hooks")
 
 	root := t.TempDir()
 	childID := "22222222-2222-4222-8222-222222222222"
@@ -264,38 +219,19 @@ func TestRunAgentEventIgnoresDroidChildSessionEvents(t *testing.T) {
 		t.Fatalf("write child session: %v", err)
 	}
 
-	paneOptions := make(map[string]string)
-	sessionOptions := make(map[string]string)
-	spinnerStarted := false
 	payload := `{"hook_event_name":"Stop","session_id":"` + childID + `","transcript_path":"` + childPath + `"}`
-	err := RunAgentEvent(AgentEvent{Agent: "droid", StdinJSON: true}, strings.NewReader(payload), nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return "⠹ hooks", nil },
-		SetPaneOption: func(_, option, value string) error {
-			paneOptions[option] = value
-			return nil
-		},
-		SetSessionOption: func(_, option, value string) error {
-			sessionOptions[option] = value
-			return nil
-		},
-		StartSpinner: func(SpinnerTarget) error {
-			spinnerStarted = true
-			return nil
-		},
-	})
+	err := RunAgentEvent(AgentEvent{Agent: "droid", StdinJSON: true}, strings.NewReader(payload), nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if len(paneOptions) != 0 || len(sessionOptions) != 0 || spinnerStarted {
-		t.Fatalf("child event wrote pane=%#v session=%#v spinner=%t", paneOptions, sessionOptions, spinnerStarted)
+	if writes := h.calls("set-option"); len(writes) != 0 || len(h.spinners) != 0 {
+		t.Fatalf("child event wrote %#v spinners=%#v", writes, h.spinners)
 	}
 }
 
 func TestRunAgentEventIgnoresDroidMismatchedNestedSessionID(t *testing.T) {
-	t.Setenv("KITMUX_AGENT_ID", "droid")
-	t.Setenv("KITMUX_TMUX_SESSION", "droid-app")
-	t.Setenv("KITMUX_TMUX_PANE", "%3")
-	t.Setenv("KITMUX_TMUX_THREAD", "1")
+	h := newHookHarness(t, 1)
+	h.thread(t, "droid", "droid-app", testHookTitle)
 
 	parentID := "11111111-1111-4111-8111-111111111111"
 	nestedID := "22222222-2222-4222-8222-222222222222"
@@ -311,177 +247,89 @@ func TestRunAgentEventIgnoresDroidMismatchedNestedSessionID(t *testing.T) {
 	); err != nil {
 		t.Fatalf("write child session: %v", err)
 	}
-	paneOptions := make(map[string]string)
-	sessionOptions := make(map[string]string)
+	_ = h.srv.SetSessionOption("droid-app", agentSessionIDOption, parentID)
+	h.mark = len(h.srv.Calls())
+
 	payload := `{"hook_event_name":"SessionStart","session_id":"` + nestedID + `","source":"startup","transcript_path":"` + childPath + `"}`
-	err := RunAgentEvent(AgentEvent{Agent: "droid", StdinJSON: true}, strings.NewReader(payload), nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return testHookTitle, nil },
-		SetPaneOption: func(_, option, value string) error {
-			paneOptions[option] = value
-			return nil
-		},
-		SetSessionOption: func(_, option, value string) error {
-			sessionOptions[option] = value
-			return nil
-		},
-		ShowSessionOption: func(_, option string) (string, error) {
-			if option == agentSessionIDOption {
-				return parentID, nil
-			}
-			return "", nil
-		},
-	})
+	err := RunAgentEvent(AgentEvent{Agent: "droid", StdinJSON: true}, strings.NewReader(payload), nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if len(paneOptions) != 0 || len(sessionOptions) != 0 {
-		t.Fatalf("nested event wrote pane=%#v session=%#v", paneOptions, sessionOptions)
+	if writes := h.calls("set-option"); len(writes) != 0 {
+		t.Fatalf("nested event wrote %#v", writes)
 	}
 }
 
 func TestRunAgentEventAcceptsDroidMainSessionRestartOnStartup(t *testing.T) {
-	t.Setenv("KITMUX_AGENT_ID", "droid")
-	t.Setenv("KITMUX_TMUX_SESSION", "droid-app")
-	t.Setenv("KITMUX_TMUX_PANE", "%3")
-	t.Setenv("KITMUX_TMUX_THREAD", "1")
+	h := newHookHarness(t, 1)
+	h.thread(t, "droid", "droid-app", testHookTitle)
 
 	parentID := "11111111-1111-4111-8111-111111111111"
 	newMainID := "33333333-3333-4333-8333-333333333333"
-	sessionOptions := make(map[string]string)
+	_ = h.srv.SetSessionOption("droid-app", agentSessionIDOption, parentID)
 	payload := `{"hook_event_name":"SessionStart","session_id":"` + newMainID + `","source":"startup"}`
-	err := RunAgentEvent(AgentEvent{Agent: "droid", StdinJSON: true}, strings.NewReader(payload), nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return testHookTitle, nil },
-		SetPaneOption:    func(_, _, _ string) error { return nil },
-		SetSessionOption: func(_, option, value string) error {
-			sessionOptions[option] = value
-			return nil
-		},
-		ShowSessionOption: func(_, option string) (string, error) {
-			if option == agentSessionIDOption {
-				return parentID, nil
-			}
-			return "", nil
-		},
-	})
+	err := RunAgentEvent(AgentEvent{Agent: "droid", StdinJSON: true}, strings.NewReader(payload), nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if sessionOptions[agentSessionIDOption] != newMainID {
-		t.Fatalf("session id = %q, want %q", sessionOptions[agentSessionIDOption], newMainID)
+	if got := h.srv.SessionOption("droid-app", agentSessionIDOption); got != newMainID {
+		t.Fatalf("session id = %q, want %q", got, newMainID)
 	}
 }
 
 func TestRunAgentEventAcceptsDroidSessionIDWhenThreadHasNoSessionID(t *testing.T) {
-	t.Setenv("KITMUX_AGENT_ID", "droid")
-	t.Setenv("KITMUX_TMUX_SESSION", "droid-app")
-	t.Setenv("KITMUX_TMUX_PANE", "%3")
-	t.Setenv("KITMUX_TMUX_THREAD", "1")
+	h := newHookHarness(t, 1)
+	h.thread(t, "droid", "droid-app", testHookTitle)
 
-	sessionOptions := make(map[string]string)
 	sessionID := "11111111-1111-4111-8111-111111111111"
 	payload := `{"hook_event_name":"SessionStart","session_id":"` + sessionID + `","source":"startup"}`
-	err := RunAgentEvent(AgentEvent{Agent: "droid", StdinJSON: true}, strings.NewReader(payload), nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return testHookTitle, nil },
-		SetPaneOption:    func(_, _, _ string) error { return nil },
-		SetSessionOption: func(_, option, value string) error {
-			sessionOptions[option] = value
-			return nil
-		},
-		ShowSessionOption: func(_, option string) (string, error) {
-			if option == agentSessionIDOption {
-				return "", nil
-			}
-			return "", nil
-		},
-	})
+	err := RunAgentEvent(AgentEvent{Agent: "droid", StdinJSON: true}, strings.NewReader(payload), nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if sessionOptions[agentSessionIDOption] != sessionID {
-		t.Fatalf("session id = %q", sessionOptions[agentSessionIDOption])
+	if got := h.srv.SessionOption("droid-app", agentSessionIDOption); got != sessionID {
+		t.Fatalf("session id = %q", got)
 	}
 }
 
 func TestRunAgentEventKeepsTrackedThreadsIsolated(t *testing.T) {
-	paneOptions := map[string]map[string]string{}
-	sessionOptions := map[string]map[string]string{}
-	var refreshed []string
-	var spinners []SpinnerTarget
-	ops := StateOps{
-		CurrentPaneTitle: func() (string, error) { return "feat/thread", nil },
-		SetPaneOption: func(target, option, value string) error {
-			if paneOptions[target] == nil {
-				paneOptions[target] = map[string]string{}
-			}
-			paneOptions[target][option] = value
-			return nil
-		},
-		SetSessionOption: func(target, option, value string) error {
-			if sessionOptions[target] == nil {
-				sessionOptions[target] = map[string]string{}
-			}
-			sessionOptions[target][option] = value
-			return nil
-		},
-		EmitBell: func(_ io.Writer) error { return nil },
-		StartSpinner: func(target SpinnerTarget) error {
-			spinners = append(spinners, target)
-			return nil
-		},
-		RefreshSessionClients: func(sessionName string) {
-			refreshed = append(refreshed, sessionName)
-		},
-		Now: func() time.Time { return time.UnixMilli(777) },
-	}
-
-	t.Setenv("KITMUX_AGENT_ID", "droid")
-	t.Setenv("KITMUX_TMUX_SESSION", "droid-one")
-	t.Setenv("KITMUX_TMUX_PANE", "%1")
-	t.Setenv("KITMUX_TMUX_THREAD", "1")
-	if err := RunAgentEvent(AgentEvent{Event: "pre-tool-use", State: stateWorking}, nil, nil, ops); err != nil {
+	h := newHookHarness(t, 777)
+	one := h.thread(t, "droid", "droid-one", "feat/thread")
+	mark := h.mark
+	if err := RunAgentEvent(AgentEvent{Event: "pre-tool-use", State: stateWorking}, nil, nil, h.srv); err != nil {
 		t.Fatalf("first RunAgentEvent() error = %v", err)
 	}
 
-	t.Setenv("KITMUX_TMUX_SESSION", "droid-two")
-	t.Setenv("KITMUX_TMUX_PANE", "%2")
-	if err := RunAgentEvent(AgentEvent{Event: "notification", State: stateInput}, nil, nil, ops); err != nil {
+	two := h.thread(t, "droid", "droid-two", "feat/thread")
+	if err := RunAgentEvent(AgentEvent{Event: "notification", State: stateInput}, nil, nil, h.srv); err != nil {
 		t.Fatalf("second RunAgentEvent() error = %v", err)
 	}
+	h.mark = mark
 
-	if paneOptions["%1"][agentStateOption] != stateWorking || sessionOptions["droid-one"][agentStateOption] != stateWorking {
-		t.Fatalf("thread one options leaked or missing: panes=%#v sessions=%#v", paneOptions, sessionOptions)
+	if h.srv.PaneOption(one, agentStateOption) != stateWorking || h.srv.SessionOption("droid-one", agentStateOption) != stateWorking {
+		t.Fatalf("thread one options leaked or missing: %#v", h.calls("set-option"))
 	}
-	if paneOptions["%2"][agentStateOption] != stateInput || sessionOptions["droid-two"][agentStateOption] != stateInput {
-		t.Fatalf("thread two options leaked or missing: panes=%#v sessions=%#v", paneOptions, sessionOptions)
+	if h.srv.PaneOption(two, agentStateOption) != stateInput || h.srv.SessionOption("droid-two", agentStateOption) != stateInput {
+		t.Fatalf("thread two options leaked or missing: %#v", h.calls("set-option"))
 	}
-	if _, ok := paneOptions[""]; ok {
-		t.Fatalf("unexpected current-pane write: %#v", paneOptions)
+	if untargeted := h.calls("set-option -p @"); len(untargeted) != 0 {
+		t.Fatalf("unexpected current-pane write: %#v", untargeted)
 	}
-	if len(spinners) != 1 || spinners[0].PaneID != "%1" || spinners[0].SessionName != "droid-one" {
-		t.Fatalf("spinners = %#v", spinners)
+	if len(h.spinners) != 1 || h.spinners[0].PaneID != one || h.spinners[0].SessionName != "droid-one" {
+		t.Fatalf("spinners = %#v", h.spinners)
 	}
-	if strings.Join(refreshed, "|") != "droid-one|droid-two" {
+	want := []string{"refresh-client droid-one", "refresh-client droid-two"}
+	if refreshed := h.calls("refresh-client"); !reflect.DeepEqual(refreshed, want) {
 		t.Fatalf("refreshed = %#v", refreshed)
 	}
 }
 
 func TestRunStateEventEmitsBellAndIgnoresTmuxErrors(t *testing.T) {
+	h := newHookHarness(t, 1)
+	t.Setenv("KITMUX_TMUX_PANE", "%404")
+
 	var out bytes.Buffer
-	err := RunStateEvent(StateEvent{State: stateIdle, Bell: true}, &out, StateOps{
-		CurrentPaneTitle: func() (string, error) {
-			return "", fmt.Errorf("not in tmux")
-		},
-		SetCurrentPaneOption: func(_, _ string) error {
-			return fmt.Errorf("not in tmux")
-		},
-		EmitBell: func(w io.Writer) error {
-			_, err := w.Write([]byte("\a"))
-			return err
-		},
-		StartSpinner: func(SpinnerTarget) error { return nil },
-		Now:          func() time.Time { return time.UnixMilli(1) },
-	})
-	if err != nil {
+	if err := RunStateEvent(StateEvent{State: stateIdle, Bell: true}, &out, h.srv); err != nil {
 		t.Fatalf("RunStateEvent() error = %v", err)
 	}
 	if out.String() != "\a" {
@@ -490,229 +338,140 @@ func TestRunStateEventEmitsBellAndIgnoresTmuxErrors(t *testing.T) {
 }
 
 func TestRunStateEventRejectsUnknownState(t *testing.T) {
-	err := RunStateEvent(StateEvent{State: "paused"}, nil, StateOps{})
+	err := RunStateEvent(StateEvent{State: "paused"}, nil, tmuxtest.New())
 	if err == nil {
 		t.Fatal("expected invalid state error")
 	}
 }
 
 func TestRunAgentEventIgnoresDifferentTrackedAgent(t *testing.T) {
-	t.Setenv("KITMUX_AGENT_ID", "droid")
-	t.Setenv("KITMUX_TMUX_SESSION", "droid-app")
-	t.Setenv("KITMUX_TMUX_PANE", "%1")
-	t.Setenv("KITMUX_TMUX_THREAD", "1")
+	h := newHookHarness(t, 1)
+	h.thread(t, "droid", "droid-app", testHookTitle)
 
-	paneOptions := make(map[string]string)
-	sessionOptions := make(map[string]string)
-	err := RunAgentEvent(AgentEvent{Agent: "cursor", Event: "pre-tool-use"}, nil, nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return testHookTitle, nil },
-		SetPaneOption: func(_, option, value string) error {
-			paneOptions[option] = value
-			return nil
-		},
-		SetSessionOption: func(_, option, value string) error {
-			sessionOptions[option] = value
-			return nil
-		},
-	})
+	err := RunAgentEvent(AgentEvent{Agent: "cursor", Event: "pre-tool-use"}, nil, nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if len(paneOptions) != 0 || len(sessionOptions) != 0 {
-		t.Fatalf("cross-agent event wrote pane=%#v session=%#v", paneOptions, sessionOptions)
+	if writes := h.calls("set-option"); len(writes) != 0 {
+		t.Fatalf("cross-agent event wrote %#v", writes)
 	}
 }
 
 func TestRunAgentEventDoesNotMergeMismatchedAncestorAgent(t *testing.T) {
+	h := newHookHarness(t, 1)
+	droidPane := h.srv.AddSession("droid-thread", "/tmp/droid")
 	originalResolve := resolveAncestorContext
 	t.Cleanup(func() { resolveAncestorContext = originalResolve })
 	resolveAncestorContext = func(int) (agenttrack.Context, bool) {
 		return agenttrack.Context{
 			AgentID:     "droid",
 			SessionName: "droid-thread",
-			PaneID:      "%7",
+			PaneID:      droidPane,
 			Thread:      true,
 		}, true
 	}
-	t.Setenv("KITMUX_AGENT_ID", "cursor")
-	t.Setenv("KITMUX_TMUX_PANE", "%1")
-	t.Setenv("KITMUX_TMUX_SESSION", "")
-	t.Setenv("KITMUX_TMUX_THREAD", "")
+	pane := h.pane(t, "cursor", "cursor")
 
-	paneOptions := make(map[string]string)
-	sessionOptions := make(map[string]string)
-	err := RunAgentEvent(AgentEvent{Agent: "cursor", Event: "pre-tool-use"}, nil, nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return "cursor", nil },
-		SetPaneOption: func(target, option, value string) error {
-			if target != "%1" {
-				t.Fatalf("pane target = %q", target)
-			}
-			paneOptions[option] = value
-			return nil
-		},
-		SetSessionOption: func(_, option, value string) error {
-			sessionOptions[option] = value
-			return nil
-		},
-	})
+	err := RunAgentEvent(AgentEvent{Agent: "cursor", Event: "pre-tool-use"}, nil, nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if paneOptions[agentStateOption] != stateWorking {
-		t.Fatalf("paneOptions = %#v", paneOptions)
+	if got := h.srv.PaneOption(pane, agentStateOption); got != stateWorking {
+		t.Fatalf("pane state = %q", got)
 	}
-	if len(sessionOptions) != 0 {
-		t.Fatalf("mismatched ancestor session was merged: %#v", sessionOptions)
+	if got := h.srv.PaneOption(droidPane, agentStateOption); got != "" {
+		t.Fatalf("mismatched ancestor pane was written: %q", got)
+	}
+	if writes := h.calls("set-option droid-thread"); len(writes) != 0 {
+		t.Fatalf("mismatched ancestor session was merged: %#v", writes)
 	}
 }
 
 func TestRunAgentEventMergesMissingPaneFromRegistry(t *testing.T) {
+	h := newHookHarness(t, 42)
+	pane := h.thread(t, "droid", "droid-app", "Droid app")
+	t.Setenv("KITMUX_TMUX_PANE", "")
 	originalResolve := resolveAncestorContext
 	t.Cleanup(func() { resolveAncestorContext = originalResolve })
 	resolveAncestorContext = func(int) (agenttrack.Context, bool) {
 		return agenttrack.Context{
 			AgentID:     "droid",
 			SessionName: "droid-app",
-			PaneID:      "%9",
+			PaneID:      pane,
 			Thread:      true,
 		}, true
 	}
 
-	t.Setenv("KITMUX_AGENT_ID", "droid")
-	t.Setenv("KITMUX_TMUX_SESSION", "droid-app")
-	t.Setenv("KITMUX_TMUX_PANE", "")
-	t.Setenv("KITMUX_TMUX_THREAD", "1")
-	paneOptions := make(map[string]string)
-	sessionOptions := make(map[string]string)
-	var spinner SpinnerTarget
-	err := RunAgentEvent(AgentEvent{Event: "pre-tool-use", State: stateWorking}, nil, nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return "Droid app", nil },
-		SetPaneOption: func(target, option, value string) error {
-			if target != "%9" {
-				t.Fatalf("pane target = %q", target)
-			}
-			paneOptions[option] = value
-			return nil
-		},
-		SetSessionOption: func(target, option, value string) error {
-			if target != "droid-app" {
-				t.Fatalf("session target = %q", target)
-			}
-			sessionOptions[option] = value
-			return nil
-		},
-		EmitBell: func(_ io.Writer) error { return nil },
-		StartSpinner: func(target SpinnerTarget) error {
-			spinner = target
-			return nil
-		},
-		RefreshSessionClients: func(string) {},
-		Now:                   func() time.Time { return time.UnixMilli(42) },
-	})
+	err := RunAgentEvent(AgentEvent{Event: "pre-tool-use", State: stateWorking}, nil, nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if paneOptions[agentStateOption] != stateWorking || sessionOptions[agentStateOption] != stateWorking {
-		t.Fatalf("pane=%#v session=%#v", paneOptions, sessionOptions)
+	if h.srv.PaneOption(pane, agentStateOption) != stateWorking || h.srv.SessionOption("droid-app", agentStateOption) != stateWorking {
+		t.Fatalf("writes = %#v", h.calls("set-option"))
 	}
-	if spinner.PaneID != "%9" || spinner.SessionName != "droid-app" || spinner.Token != "42" {
+	if len(h.spinners) != 1 {
+		t.Fatalf("spinners = %#v", h.spinners)
+	}
+	if spinner := h.spinners[0]; spinner.PaneID != pane || spinner.SessionName != "droid-app" || spinner.Token != "42" {
 		t.Fatalf("spinner = %#v", spinner)
 	}
 }
 
 func TestRunAgentEventDoesNotLeaveStaticSpinnerWithoutPane(t *testing.T) {
+	h := newHookHarness(t, 43)
+	h.thread(t, "droid", "droid-app", "Droid app")
+	t.Setenv("KITMUX_TMUX_PANE", "")
 	originalResolve := resolveAncestorContext
 	t.Cleanup(func() { resolveAncestorContext = originalResolve })
 	resolveAncestorContext = func(int) (agenttrack.Context, bool) {
 		return agenttrack.Context{}, false
 	}
 
-	t.Setenv("KITMUX_AGENT_ID", "droid")
-	t.Setenv("KITMUX_TMUX_SESSION", "droid-app")
-	t.Setenv("KITMUX_TMUX_PANE", "")
-	t.Setenv("KITMUX_TMUX_THREAD", "1")
-	sessionOptions := make(map[string]string)
-	spinnerStarted := false
-	err := RunAgentEvent(AgentEvent{Event: "pre-tool-use", State: stateWorking}, nil, nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return "Droid app", nil },
-		SetSessionOption: func(_, option, value string) error {
-			sessionOptions[option] = value
-			return nil
-		},
-		EmitBell:              func(_ io.Writer) error { return nil },
-		StartSpinner:          func(SpinnerTarget) error { spinnerStarted = true; return nil },
-		RefreshSessionClients: func(string) {},
-		Now:                   func() time.Time { return time.UnixMilli(43) },
-	})
+	err := RunAgentEvent(AgentEvent{Event: "pre-tool-use", State: stateWorking}, nil, nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if sessionOptions[agentTitlePrefixOption] != "" {
-		t.Fatalf("static spinner prefix = %q", sessionOptions[agentTitlePrefixOption])
+	if got := h.srv.SessionOption("droid-app", agentTitlePrefixOption); got != "" {
+		t.Fatalf("static spinner prefix = %q", got)
 	}
-	if spinnerStarted {
+	if len(h.spinners) != 0 {
 		t.Fatal("spinner should not start without a pane")
 	}
 }
 
 func TestRunAgentEventDerivesPermissionAndDetailFromHookJSON(t *testing.T) {
-	t.Setenv("KITMUX_AGENT_ID", "codex")
-	t.Setenv("KITMUX_TMUX_PANE", "%1")
-	paneOptions := make(map[string]string)
-	var bells int
+	h := newHookHarness(t, 99)
+	pane := h.pane(t, "codex", "branch-title")
 	input := `{"hook_event_name":"PermissionRequest","tool_name":"Bash","tool_input":{"description":"Run tests"}}`
-	err := RunAgentEvent(AgentEvent{Agent: "codex", Event: "permission-request", StdinJSON: true}, strings.NewReader(input), nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return "branch-title", nil },
-		SetCurrentPaneOption: func(option, value string) error {
-			paneOptions[option] = value
-			return nil
-		},
-		SetPaneOption: func(_, option, value string) error {
-			paneOptions[option] = value
-			return nil
-		},
-		SetCurrentSessionOption: func(_, _ string) error { return nil },
-		EmitBell:                func(_ io.Writer) error { bells++; return nil },
-		StartSpinner:            func(SpinnerTarget) error { return nil },
-		Now:                     func() time.Time { return time.UnixMilli(99) },
-	})
+
+	err := RunAgentEvent(AgentEvent{Agent: "codex", Event: "permission-request", StdinJSON: true}, strings.NewReader(input), nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if paneOptions[agentStateOption] != statePermission || paneOptions[agentDetailOption] != "Bash" || paneOptions[agentTitlePrefixOption] != "!" {
-		t.Fatalf("paneOptions = %#v", paneOptions)
+	if h.srv.PaneOption(pane, agentStateOption) != statePermission ||
+		h.srv.PaneOption(pane, agentDetailOption) != "Bash" ||
+		h.srv.PaneOption(pane, agentTitlePrefixOption) != "!" {
+		t.Fatalf("pane writes = %#v", h.calls("set-option -p"))
 	}
-	if bells != 1 {
-		t.Fatalf("bells = %d, want 1", bells)
+	if h.bells != 1 {
+		t.Fatalf("bells = %d, want 1", h.bells)
 	}
 }
 
 func TestRunAgentEventPreToolAskUserIsInputNoSpinner(t *testing.T) {
 	clearTrackingEnv(t)
-	t.Setenv("KITMUX_AGENT_ID", "droid")
-	t.Setenv("KITMUX_TMUX_PANE", "%1")
-	paneOptions := make(map[string]string)
-	spinnerStarted := false
+	h := newHookHarness(t, 7)
+	pane := h.pane(t, "droid", "branch-title")
 	input := `{"hook_event_name":"PreToolUse","tool_name":"AskUser"}`
-	err := RunAgentEvent(AgentEvent{Agent: "droid", Event: "pre-tool-use", StdinJSON: true}, strings.NewReader(input), nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return "branch-title", nil },
-		SetPaneOption: func(_, option, value string) error {
-			paneOptions[option] = value
-			return nil
-		},
-		SetCurrentPaneOption:    func(_, _ string) error { return nil },
-		SetCurrentSessionOption: func(_, _ string) error { return nil },
-		EmitBell:                func(_ io.Writer) error { return nil },
-		StartSpinner:            func(SpinnerTarget) error { spinnerStarted = true; return nil },
-		Now:                     func() time.Time { return time.UnixMilli(7) },
-	})
+
+	err := RunAgentEvent(AgentEvent{Agent: "droid", Event: "pre-tool-use", StdinJSON: true}, strings.NewReader(input), nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if paneOptions[agentStateOption] != stateInput || paneOptions[agentTitlePrefixOption] != "⮞" {
-		t.Fatalf("paneOptions = %#v", paneOptions)
+	if h.srv.PaneOption(pane, agentStateOption) != stateInput || h.srv.PaneOption(pane, agentTitlePrefixOption) != "⮞" {
+		t.Fatalf("pane writes = %#v", h.calls("set-option -p"))
 	}
-	if spinnerStarted {
+	if len(h.spinners) != 0 {
 		t.Fatalf("spinner must not start for an attention state")
 	}
 }
@@ -729,24 +488,18 @@ func TestRunAgentEventMapsCursorPromptSubmitAndSubagentStop(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			clearTrackingEnv(t)
-			t.Setenv("KITMUX_AGENT_ID", "cursor")
-			t.Setenv("KITMUX_TMUX_PANE", "%1")
-			paneOptions := make(map[string]string)
-			err := RunAgentEvent(AgentEvent{Agent: "cursor", StdinJSON: true}, strings.NewReader(`{"hook_event_name":"`+tt.eventName+`","chat_id":"44444444-4444-4444-8444-444444444444"}`), nil, StateOps{
-				CurrentPaneTitle: func() (string, error) { return "⌬ cursor task", nil },
-				SetPaneOption: func(_, option, value string) error {
-					paneOptions[option] = value
-					return nil
-				},
-			})
+			h := newHookHarness(t, 1)
+			pane := h.pane(t, "cursor", "⌬ cursor task")
+			payload := `{"hook_event_name":"` + tt.eventName + `","chat_id":"44444444-4444-4444-8444-444444444444"}`
+			err := RunAgentEvent(AgentEvent{Agent: "cursor", StdinJSON: true}, strings.NewReader(payload), nil, h.srv)
 			if err != nil {
 				t.Fatalf("RunAgentEvent() error = %v", err)
 			}
-			if paneOptions[agentStateOption] != tt.wantState {
-				t.Fatalf("state = %q, want %q", paneOptions[agentStateOption], tt.wantState)
+			if got := h.srv.PaneOption(pane, agentStateOption); got != tt.wantState {
+				t.Fatalf("state = %q, want %q", got, tt.wantState)
 			}
-			if paneOptions[agentSessionIDOption] != "44444444-4444-4444-8444-444444444444" {
-				t.Fatalf("session id = %q", paneOptions[agentSessionIDOption])
+			if got := h.srv.PaneOption(pane, agentSessionIDOption); got != "44444444-4444-4444-8444-444444444444" {
+				t.Fatalf("session id = %q", got)
 			}
 		})
 	}
@@ -810,60 +563,34 @@ func TestDeriveBellForAttentionEvents(t *testing.T) {
 }
 
 func TestRunAgentEventAddsConsistentSpinnerAndTrimsNativeLoaderForCodex(t *testing.T) {
-	t.Setenv("KITMUX_AGENT_ID", "codex")
-	t.Setenv("KITMUX_TMUX_PANE", "%1")
-	paneOptions := make(map[string]string)
-	var spinner SpinnerTarget
-	err := RunAgentEvent(AgentEvent{Event: "pre-tool-use", State: stateWorking}, nil, nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return "⠹ › kitmux", nil },
-		SetPaneOption: func(_, option, value string) error {
-			paneOptions[option] = value
-			return nil
-		},
-		SetCurrentPaneOption:    func(_, _ string) error { return nil },
-		SetCurrentSessionOption: func(_, _ string) error { return nil },
-		EmitBell:                func(_ io.Writer) error { return nil },
-		StartSpinner: func(target SpinnerTarget) error {
-			spinner = target
-			return nil
-		},
-		Now: func() time.Time { return time.UnixMilli(4040) },
-	})
+	h := newHookHarness(t, 4040)
+	pane := h.pane(t, "codex", "⠹ › kitmux")
+
+	err := RunAgentEvent(AgentEvent{Event: "pre-tool-use", State: stateWorking}, nil, nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if paneOptions[agentStateOption] != stateWorking {
-		t.Fatalf("paneOptions = %#v", paneOptions)
+	if h.srv.PaneOption(pane, agentStateOption) != stateWorking {
+		t.Fatalf("pane writes = %#v", h.calls("set-option -p"))
 	}
-	if paneOptions[agentTitlePrefixOption] != SpinnerFrames[0] || paneOptions[agentTitleDisplayOption] != "kitmux" {
-		t.Fatalf("paneOptions = %#v", paneOptions)
+	if h.srv.PaneOption(pane, agentTitlePrefixOption) != SpinnerFrames[0] || h.srv.PaneOption(pane, agentTitleDisplayOption) != "kitmux" {
+		t.Fatalf("pane writes = %#v", h.calls("set-option -p"))
 	}
-	if spinner.PaneID != "%1" || spinner.Token != "4040" {
-		t.Fatalf("spinner = %#v", spinner)
+	if len(h.spinners) != 1 || h.spinners[0].PaneID != pane || h.spinners[0].Token != "4040" {
+		t.Fatalf("spinners = %#v", h.spinners)
 	}
 }
 
 func TestRunAgentEventReplacesDroidSymbolWithSpinnerInTitleDisplay(t *testing.T) {
-	t.Setenv("KITMUX_AGENT_ID", "droid")
-	t.Setenv("KITMUX_TMUX_PANE", "%1")
-	paneOptions := make(map[string]string)
-	err := RunAgentEvent(AgentEvent{Event: "pre-tool-use", State: stateWorking}, nil, nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return "⠂ ⛬ Android app", nil },
-		SetPaneOption: func(_, option, value string) error {
-			paneOptions[option] = value
-			return nil
-		},
-		SetCurrentPaneOption:    func(_, _ string) error { return nil },
-		SetCurrentSessionOption: func(_, _ string) error { return nil },
-		EmitBell:                func(_ io.Writer) error { return nil },
-		StartSpinner:            func(SpinnerTarget) error { return nil },
-		Now:                     func() time.Time { return time.UnixMilli(5050) },
-	})
+	h := newHookHarness(t, 5050)
+	pane := h.pane(t, "droid", "⠂ ⛬ Android app")
+
+	err := RunAgentEvent(AgentEvent{Event: "pre-tool-use", State: stateWorking}, nil, nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if paneOptions[agentTitlePrefixOption] != SpinnerFrames[0] || paneOptions[agentTitleDisplayOption] != "Android app" {
-		t.Fatalf("paneOptions = %#v", paneOptions)
+	if h.srv.PaneOption(pane, agentTitlePrefixOption) != SpinnerFrames[0] || h.srv.PaneOption(pane, agentTitleDisplayOption) != "Android app" {
+		t.Fatalf("pane writes = %#v", h.calls("set-option -p"))
 	}
 }
 
@@ -874,45 +601,33 @@ func TestRunAgentEventTargetsSessionFromEnvWithoutTmuxPane(t *testing.T) {
 		return agenttrack.Context{}, false
 	}
 
+	// The hook runs outside tmux: the session exists but no client is
+	// attached, so there is no current pane to fall back to.
+	h := newHookHarness(t, 2026)
+	h.srv.AddSession("droid-kitmux", "/tmp/kitmux")
 	t.Setenv("KITMUX_AGENT_ID", "droid")
 	t.Setenv("KITMUX_TMUX_SESSION", "droid-kitmux")
 	t.Setenv("KITMUX_TMUX_PANE", "")
 	t.Setenv("KITMUX_TMUX_THREAD", "1")
-	sessionOptions := make(map[string]string)
-	var spinner SpinnerTarget
-	err := RunAgentEvent(AgentEvent{Event: "pre-tool-use", State: stateWorking}, nil, nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return "", fmt.Errorf("not in tmux") },
-		SetCurrentPaneOption: func(_, _ string) error {
-			t.Fatal("expected no implicit pane writes without KITMUX_TMUX_PANE")
-			return nil
-		},
-		SetCurrentSessionOption: func(_, _ string) error {
-			t.Fatal("expected targeted session writes")
-			return nil
-		},
-		SetSessionOption: func(target, option, value string) error {
-			if target != "droid-kitmux" {
-				t.Fatalf("target = %q", target)
-			}
-			sessionOptions[option] = value
-			return nil
-		},
-		EmitBell: func(_ io.Writer) error { return nil },
-		StartSpinner: func(target SpinnerTarget) error {
-			spinner = target
-			return nil
-		},
-		RefreshSessionClients: func(string) {},
-		Now:                   func() time.Time { return time.UnixMilli(2026) },
-	})
+	h.mark = len(h.srv.Calls())
+
+	err := RunAgentEvent(AgentEvent{Event: "pre-tool-use", State: stateWorking}, nil, nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if sessionOptions[agentStateOption] != stateWorking || sessionOptions[agentTitlePrefixOption] != "" {
-		t.Fatalf("sessionOptions = %#v", sessionOptions)
+	if paneWrites := h.calls("set-option -p"); len(paneWrites) != 0 {
+		t.Fatalf("expected no implicit pane writes without KITMUX_TMUX_PANE: %#v", paneWrites)
 	}
-	if spinner != (SpinnerTarget{}) {
-		t.Fatalf("spinner = %#v", spinner)
+	for _, call := range h.calls("set-option") {
+		if !strings.HasPrefix(call, "set-option droid-kitmux ") {
+			t.Fatalf("expected targeted session writes, got %q", call)
+		}
+	}
+	if h.srv.SessionOption("droid-kitmux", agentStateOption) != stateWorking || h.srv.SessionOption("droid-kitmux", agentTitlePrefixOption) != "" {
+		t.Fatalf("session writes = %#v", h.calls("set-option"))
+	}
+	if len(h.spinners) != 0 {
+		t.Fatalf("spinners = %#v", h.spinners)
 	}
 }
 
@@ -938,40 +653,24 @@ func TestTitlePrefixRevertsToAgentSymbolWhenIdle(t *testing.T) {
 }
 
 func TestRunAgentEventTargetsPaneFromEnvWithoutSessionSync(t *testing.T) {
-	t.Setenv("KITMUX_AGENT_ID", "codex")
+	h := newHookHarness(t, 3030)
+	pane := h.pane(t, "codex", "branch")
 	t.Setenv("KITMUX_TMUX_SESSION", "work")
-	t.Setenv("KITMUX_TMUX_PANE", "%42")
-	paneOptions := make(map[string]string)
-	sessionOptions := make(map[string]string)
-	err := RunAgentEvent(AgentEvent{Event: "permission-request", State: statePermission}, nil, nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return "branch", nil },
-		SetCurrentPaneOption: func(_, _ string) error {
-			t.Fatal("expected targeted pane writes")
-			return nil
-		},
-		SetPaneOption: func(target, option, value string) error {
-			if target != "%42" {
-				t.Fatalf("target = %q", target)
-			}
-			paneOptions[option] = value
-			return nil
-		},
-		SetCurrentSessionOption: func(option, value string) error {
-			sessionOptions[option] = value
-			return nil
-		},
-		EmitBell:     func(_ io.Writer) error { return nil },
-		StartSpinner: func(SpinnerTarget) error { return nil },
-		Now:          func() time.Time { return time.UnixMilli(3030) },
-	})
+
+	err := RunAgentEvent(AgentEvent{Event: "permission-request", State: statePermission}, nil, nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if paneOptions[agentStateOption] != statePermission || paneOptions[agentTitlePrefixOption] != "!" {
-		t.Fatalf("paneOptions = %#v", paneOptions)
+	for _, call := range h.calls("set-option -p") {
+		if !strings.HasPrefix(call, "set-option -p "+pane+" ") {
+			t.Fatalf("expected targeted pane writes, got %q", call)
+		}
 	}
-	if len(sessionOptions) != 0 {
-		t.Fatalf("sessionOptions = %#v", sessionOptions)
+	if h.srv.PaneOption(pane, agentStateOption) != statePermission || h.srv.PaneOption(pane, agentTitlePrefixOption) != "!" {
+		t.Fatalf("pane writes = %#v", h.calls("set-option -p"))
+	}
+	if writes := h.calls("set-option work"); len(writes) != 0 {
+		t.Fatalf("session writes = %#v", writes)
 	}
 }
 
@@ -984,45 +683,28 @@ func TestRunAgentEventWithoutTrackingEnvDoesNotTouchTmuxTargets(t *testing.T) {
 	resolveAncestorContext = func(int) (agenttrack.Context, bool) {
 		return agenttrack.Context{}, false
 	}
+	h := newHookHarness(t, 11)
+	h.srv.Attach(h.srv.AddSession("work", "/tmp/work"))
+	h.mark = len(h.srv.Calls())
 
-	var paneWrites int
-	var sessionWrites int
-	spinnerStarted := false
-	err := RunAgentEvent(AgentEvent{Agent: "droid", Event: "pre-tool-use", State: stateWorking}, nil, nil, StateOps{
-		CurrentPaneTitle: func() (string, error) {
-			t.Fatal("expected no pane title lookup without explicit tracking env")
-			return "", nil
-		},
-		SetPaneOption: func(_, _, _ string) error {
-			paneWrites++
-			return nil
-		},
-		SetCurrentPaneOption: func(_, _ string) error {
-			paneWrites++
-			return nil
-		},
-		SetSessionOption: func(_, _, _ string) error {
-			sessionWrites++
-			return nil
-		},
-		SetCurrentSessionOption: func(_, _ string) error {
-			sessionWrites++
-			return nil
-		},
-		EmitBell:     func(_ io.Writer) error { return nil },
-		StartSpinner: func(SpinnerTarget) error { spinnerStarted = true; return nil },
-		Now:          func() time.Time { return time.UnixMilli(11) },
-	})
+	err := RunAgentEvent(AgentEvent{Agent: "droid", Event: "pre-tool-use", State: stateWorking}, nil, nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if paneWrites != 0 || sessionWrites != 0 || spinnerStarted {
-		t.Fatalf("paneWrites=%d sessionWrites=%d spinnerStarted=%v", paneWrites, sessionWrites, spinnerStarted)
+	if lookups := h.calls("display-message"); len(lookups) != 0 {
+		t.Fatalf("expected no pane title lookup without explicit tracking env: %#v", lookups)
+	}
+	if writes := h.calls("set-option"); len(writes) != 0 || len(h.spinners) != 0 {
+		t.Fatalf("writes=%#v spinners=%#v", writes, h.spinners)
 	}
 }
 
 func TestRunAgentEventResolvesTargetFromRegisteredAncestor(t *testing.T) {
 	clearTrackingEnv(t)
+	h := newHookHarness(t, 22)
+	pane := h.srv.AddSession("droid-thread", "/tmp/thread")
+	_ = h.srv.SetPaneTitle(pane, "registered thread")
+	h.srv.Attach(pane)
 	original := resolveAncestorContext
 	t.Cleanup(func() {
 		resolveAncestorContext = original
@@ -1031,46 +713,20 @@ func TestRunAgentEventResolvesTargetFromRegisteredAncestor(t *testing.T) {
 		return agenttrack.Context{
 			AgentID:     "droid",
 			SessionName: "droid-thread",
-			PaneID:      "%7",
+			PaneID:      pane,
 			Thread:      true,
 		}, true
 	}
 
-	paneOptions := make(map[string]string)
-	sessionOptions := make(map[string]string)
-	var spinner SpinnerTarget
-	err := RunAgentEvent(AgentEvent{Agent: "droid", Event: "pre-tool-use", State: stateWorking}, nil, nil, StateOps{
-		CurrentPaneTitle: func() (string, error) { return "registered thread", nil },
-		SetPaneOption: func(target, option, value string) error {
-			if target != "%7" {
-				t.Fatalf("pane target = %q", target)
-			}
-			paneOptions[option] = value
-			return nil
-		},
-		SetSessionOption: func(target, option, value string) error {
-			if target != "droid-thread" {
-				t.Fatalf("session target = %q", target)
-			}
-			sessionOptions[option] = value
-			return nil
-		},
-		EmitBell: func(_ io.Writer) error { return nil },
-		StartSpinner: func(target SpinnerTarget) error {
-			spinner = target
-			return nil
-		},
-		RefreshSessionClients: func(string) {},
-		Now:                   func() time.Time { return time.UnixMilli(22) },
-	})
+	err := RunAgentEvent(AgentEvent{Agent: "droid", Event: "pre-tool-use", State: stateWorking}, nil, nil, h.srv)
 	if err != nil {
 		t.Fatalf("RunAgentEvent() error = %v", err)
 	}
-	if paneOptions[agentStateOption] != stateWorking || sessionOptions[agentStateOption] != stateWorking {
-		t.Fatalf("paneOptions=%#v sessionOptions=%#v", paneOptions, sessionOptions)
+	if h.srv.PaneOption(pane, agentStateOption) != stateWorking || h.srv.SessionOption("droid-thread", agentStateOption) != stateWorking {
+		t.Fatalf("writes = %#v", h.calls("set-option"))
 	}
-	if spinner.PaneID != "%7" || spinner.SessionName != "droid-thread" {
-		t.Fatalf("spinner = %#v", spinner)
+	if len(h.spinners) != 1 || h.spinners[0].PaneID != pane || h.spinners[0].SessionName != "droid-thread" {
+		t.Fatalf("spinners = %#v", h.spinners)
 	}
 }
 
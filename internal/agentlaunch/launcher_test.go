@@ -5,57 +5,70 @@ import (
 
 	"github.com/miltonparedes/kitmux/internal/agentenv"
 	"github.com/miltonparedes/kitmux/internal/agents"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
 )
 
 func TestLaunchInSessionFreshSessionOpensSidepanel(t *testing.T) {
 	t.Setenv("KITMUX_AGENT_SIDEPANEL", "always")
 	t.Setenv("KITMUX_SIDEPANEL_COMMAND", "kitmux sidepanel")
+	srv := launchServer(t)
 
-	calls := &launchCalls{}
-	err := LaunchInSession(sessionReq(true, TargetWindow), stubOps(calls))
+	err := LaunchInSession(sessionReq(true, TargetWindow), srv)
 	if err != nil {
 		t.Fatalf("launch: %v", err)
 	}
-	if calls.renamedTarget != "kitmux-main:0" {
-		t.Fatalf("expected window 0 rename, got %q", calls.renamedTarget)
+	windows, _ := srv.ListWindows("kitmux-main")
+	if len(windows) != 1 || windows[0].Name != "droid" {
+		t.Fatalf("expected window 0 renamed to droid, got %#v", windows)
 	}
-	if calls.sentTarget != "kitmux-main:0" || calls.sentKeys != trackedDroidCommand("kitmux-main") {
-		t.Fatalf("expected droid sent to window 0, got target=%q keys=%q", calls.sentTarget, calls.sentKeys)
+	if sent := srv.SentKeys("kitmux-main:0.0"); len(sent) != 1 || sent[0] != trackedDroidCommand("kitmux-main") {
+		t.Fatalf("expected droid sent to window 0, got %#v", sent)
 	}
-	if calls.sidepanelTarget != "kitmux-main:0" || calls.sidepanelDir != "/repo" {
-		t.Fatalf("expected sidepanel beside reused pane, got target=%q dir=%q", calls.sidepanelTarget, calls.sidepanelDir)
+	side, ok := sidepanelPane(srv)
+	if !ok || side.WindowIndex != 0 || side.Path != "/repo" {
+		t.Fatalf("expected sidepanel beside reused pane, got %#v", side)
 	}
 }
 
 func TestLaunchInSessionWindowOpensSidepanelFromPaneID(t *testing.T) {
 	t.Setenv("KITMUX_AGENT_SIDEPANEL", "always")
+	srv := launchServer(t)
 
-	calls := &launchCalls{}
-	err := LaunchInSession(sessionReq(false, TargetWindow), stubOps(calls))
+	err := LaunchInSession(sessionReq(false, TargetWindow), srv)
 	if err != nil {
 		t.Fatalf("launch: %v", err)
 	}
-	if calls.windowName != "droid" || calls.windowDir != "/repo" || calls.windowCommand != trackedDroidCommand("kitmux-main") {
-		t.Fatalf("unexpected window launch: name=%q dir=%q command=%q", calls.windowName, calls.windowDir, calls.windowCommand)
+	windows, _ := srv.ListWindows("kitmux-main")
+	if len(windows) != 2 || windows[1].Name != "droid" {
+		t.Fatalf("expected a droid window, got %#v", windows)
 	}
-	if calls.sidepanelTarget != "%9" {
-		t.Fatalf("expected sidepanel target %%9, got %q", calls.sidepanelTarget)
+	if srv.PanePath("kitmux-main:1.0") != "/repo" || srv.PaneCommand("kitmux-main:1.0") != trackedDroidCommand("kitmux-main") {
+		t.Fatalf("unexpected window launch: dir=%q command=%q",
+			srv.PanePath("kitmux-main:1.0"), srv.PaneCommand("kitmux-main:1.0"))
+	}
+	if !windows[0].Active {
+		t.Fatal("launching into a session window should not steal focus")
+	}
+	if side, ok := sidepanelPane(srv); !ok || side.WindowIndex != 1 {
+		t.Fatalf("expected sidepanel in the new window, got %#v", side)
 	}
 }
 
 func TestLaunchInSessionSplitOpensSidepanelFromPaneID(t *testing.T) {
 	t.Setenv("KITMUX_AGENT_SIDEPANEL", "always")
+	srv := launchServer(t)
 
-	calls := &launchCalls{}
-	err := LaunchInSession(sessionReq(false, TargetSplit), stubOps(calls))
+	err := LaunchInSession(sessionReq(false, TargetSplit), srv)
 	if err != nil {
 		t.Fatalf("launch: %v", err)
 	}
-	if calls.splitTarget != "kitmux-main:" || calls.splitCommand != trackedDroidCommand("kitmux-main") {
-		t.Fatalf("unexpected split launch: target=%q command=%q", calls.splitTarget, calls.splitCommand)
+	if got := srv.PaneCommand("kitmux-main:0.1"); got != trackedDroidCommand("kitmux-main") {
+		t.Fatalf("unexpected split launch: command=%q", got)
 	}
-	if calls.sidepanelTarget != "%7" {
-		t.Fatalf("expected sidepanel target %%7, got %q", calls.sidepanelTarget)
+	side, ok := sidepanelPane(srv)
+	if !ok || side.WindowIndex != 0 || side.PaneIndex != 2 {
+		t.Fatalf("expected sidepanel beside the split, got %#v", side)
 	}
 }
 
@@ -65,29 +78,67 @@ func trackedDroidCommand(sessionName string) string {
 
 func TestLaunchInSessionHonorsSidepanelOff(t *testing.T) {
 	t.Setenv("KITMUX_AGENT_SIDEPANEL", "off")
+	srv := launchServer(t)
 
-	calls := &launchCalls{}
-	err := LaunchInSession(sessionReq(false, TargetWindow), stubOps(calls))
+	err := LaunchInSession(sessionReq(false, TargetWindow), srv)
 	if err != nil {
 		t.Fatalf("launch: %v", err)
 	}
-	if calls.sidepanelCommand != "" {
-		t.Fatalf("expected no sidepanel split, got %q", calls.sidepanelCommand)
+	if side, ok := sidepanelPane(srv); ok {
+		t.Fatalf("expected no sidepanel split, got %#v", side)
 	}
 }
 
-type launchCalls struct {
-	sentTarget       string
-	sentKeys         string
-	renamedTarget    string
-	windowName       string
-	windowDir        string
-	windowCommand    string
-	splitTarget      string
-	splitCommand     string
-	sidepanelTarget  string
-	sidepanelDir     string
-	sidepanelCommand string
+func TestShouldOpenSidepanelAutoUsesClientWidth(t *testing.T) {
+	t.Setenv("KITMUX_AGENT_SIDEPANEL", "auto")
+	t.Setenv("KITMUX_AGENT_SIDEPANEL_MIN_WIDTH", "160")
+	srv := launchServer(t)
+
+	srv.SetClientWidth(120)
+	if ShouldOpenSidepanel(srv) {
+		t.Fatal("narrow client should not open the sidepanel")
+	}
+	srv.SetClientWidth(200)
+	if !ShouldOpenSidepanel(srv) {
+		t.Fatal("wide client should open the sidepanel")
+	}
+}
+
+func TestLaunchCurrentSendsToCurrentPane(t *testing.T) {
+	t.Setenv("KITMUX_AGENT_SIDEPANEL", "off")
+	srv := launchServer(t)
+	agent := agents.Agent{ID: "droid", Name: "Droid", Command: "droid"}
+
+	if err := LaunchCurrent(agent, agents.AgentMode{ID: "default"}, TargetPane, srv); err != nil {
+		t.Fatalf("launch: %v", err)
+	}
+	want := agentenv.WrapTmuxCommand("droid", "", "droid", false)
+	if sent := srv.SentKeys("kitmux-main"); len(sent) != 1 || sent[0] != want {
+		t.Fatalf("sent = %#v", sent)
+	}
+}
+
+func launchServer(t *testing.T) *tmuxtest.Server {
+	t.Helper()
+	original := installHooks
+	t.Cleanup(func() { installHooks = original })
+	installHooks = func(string) error { return nil }
+
+	srv := tmuxtest.New()
+	srv.AddSession("kitmux-main", "/repo")
+	srv.Attach("kitmux-main")
+	srv.SetClientWidth(240)
+	return srv
+}
+
+func sidepanelPane(srv *tmuxtest.Server) (tmux.Pane, bool) {
+	panes, _ := srv.ListPanes()
+	for _, pane := range panes {
+		if pane.Command == "kitmux" {
+			return pane, true
+		}
+	}
+	return tmux.Pane{}, false
 }
 
 func sessionReq(fresh bool, target Target) SessionRequest {
@@ -102,36 +153,3 @@ func sessionReq(fresh bool, target Target) SessionRequest {
 		OpenSidepanel: true,
 	}
 }
-
-func stubOps(calls *launchCalls) Ops {
-	return Ops{
-		SendKeys: func(target, keys string) error {
-			calls.sentTarget = target
-			calls.sentKeys = keys
-			return nil
-		},
-		NewWindowInSession: func(_, name, dir, command string) (string, error) {
-			calls.windowName = name
-			calls.windowDir = dir
-			calls.windowCommand = command
-			return "%9", nil
-		},
-		SplitWindowInDir: func(target, _, command string) (string, error) {
-			calls.splitTarget = target
-			calls.splitCommand = command
-			return "%7", nil
-		},
-		SplitWindowInDirPercent: func(target, dir, command string, _ int) (string, error) {
-			calls.sidepanelTarget = target
-			calls.sidepanelDir = dir
-			calls.sidepanelCommand = command
-			return "%8", nil
-		},
-		CurrentClientWidth: func() (int, error) { return 240, nil },
-		RenameWindow: func(target, _ string) error {
-			calls.renamedTarget = target
-			return nil
-		},
-		InstallHooks: func(string) error { return nil },
-	}
-}
@@ -6,7 +6,10 @@ type Agent struct {
 	Name    string
 	Symbol  string
 	Command string
-	Modes   []AgentMode
+	// Exec runs the agent non-interactively: it reads a prompt on stdin
+	// and prints the answer. Empty when the agent has no such mode.
+	Exec  string
+	Modes []AgentMode
 }
 
 // AgentMode represents a launch mode for an agent.
@@ -22,6 +25,7 @@ var defaultAgents = []Agent{
 		Name:    "Droid",
 		Symbol:  "⛬",
 		Command: "droid",
+		Exec:    "droid exec",
 		Modes: []AgentMode{
 			{ID: "default", Name: "Default", Flags: ""},
 		},
@@ -31,6 +35,7 @@ var defaultAgents = []Agent{
 		Name:    "Codex CLI",
 		Symbol:  "⌘",
 		Command: "codex",
+		Exec:    "codex exec -",
 		Modes: []AgentMode{
 			{ID: "default", Name: "Default", Flags: ""},
 			{ID: "exec", Name: "Exec", Flags: "--approval-mode full-auto"},
@@ -43,6 +48,7 @@ var defaultAgents = []Agent{
 		Name:    "Cursor CLI",
 		Symbol:  "⌬",
 		Command: "cursor-agent",
+		Exec:    "cursor-agent -p",
 		Modes: []AgentMode{
 			{ID: "default", Name: "Default", Flags: ""},
 		},
@@ -52,6 +58,7 @@ var defaultAgents = []Agent{
 		Name:    "Claude Code",
 		Symbol:  "✳",
 		Command: "claude",
+		Exec:    "claude -p",
 		Modes: []AgentMode{
 			{ID: "default", Name: "Default", Flags: ""},
 			{ID: "skip-perms", Name: "Skip Permissions", Flags: "--dangerously-skip-permissions"},
@@ -62,6 +69,7 @@ var defaultAgents = []Agent{
 		Name:    "OpenCode",
 		Symbol:  "□",
 		Command: "opencode",
+		Exec:    "opencode run",
 		Modes: []AgentMode{
 			{ID: "default", Name: "Default", Flags: ""},
 		},
@@ -93,6 +101,33 @@ func FindMode(a Agent, id string) (AgentMode, bool) {
 	return AgentMode{}, false
 }
 
+// Index returns the position of the agent with id in list, or 0 when it is
+// not there.
+func Index(list []Agent, id string) int {
+	for i, a := range list {
+		if a.ID == id {
+			return i
+		}
+	}
+	return 0
+}
+
+// ModeIndexes returns, per agent in list, the index of the mode modeFor
+// picks, or 0 when the agent has no such mode.
+func ModeIndexes(list []Agent, modeFor func(agentID string) string) []int {
+	indexes := make([]int, len(list))
+	for i, a := range list {
+		want := modeFor(a.ID)
+		for j, mode := range a.Modes {
+			if mode.ID == want {
+				indexes[i] = j
+				break
+			}
+		}
+	}
+	return indexes
+}
+
 func CommandMap() map[string]Agent {
 	byCommand := make(map[string]Agent)
 	for _, a := range DefaultAgents() {
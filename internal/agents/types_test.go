@@ -95,3 +95,29 @@ func TestAgentDisplayNameUsesSymbol(t *testing.T) {
 		t.Fatalf("OpenCode DisplayName() = %q", opencode.DisplayName())
 	}
 }
+
+func TestIndexAndModeIndexes(t *testing.T) {
+	list := DefaultAgents()
+	if got := Index(list, "claude"); list[got].ID != "claude" {
+		t.Fatalf("Index(claude) = %d (%s)", got, list[got].ID)
+	}
+	if got := Index(list, "missing"); got != 0 {
+		t.Fatalf("Index(missing) = %d, want 0", got)
+	}
+
+	indexes := ModeIndexes(list, func(id string) string {
+		if id == "claude" {
+			return "skip-perms"
+		}
+		return "nope"
+	})
+	for i, a := range list {
+		want := "default"
+		if a.ID == "claude" {
+			want = "skip-perms"
+		}
+		if got := a.Modes[indexes[i]].ID; got != want {
+			t.Fatalf("%s mode = %q, want %q", a.ID, got, want)
+		}
+	}
+}
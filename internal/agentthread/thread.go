@@ -10,12 +10,14 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/miltonparedes/kitmux/internal/activity"
 	"github.com/miltonparedes/kitmux/internal/agentenv"
 	"github.com/miltonparedes/kitmux/internal/agents"
 	"github.com/miltonparedes/kitmux/internal/tmux"
 )
 
-var switchClient = tmux.SwitchClient
+// now is replaced in tests.
+var now = time.Now
 
 type Spec struct {
 	AgentID string
@@ -32,34 +34,6 @@ type Resolved struct {
 	Mode        agents.AgentMode
 }
 
-type Ops struct {
-	HasSession            func(string) bool
-	NewSessionWithCommand func(string, string, string) (string, error)
-	SetSessionOption      func(string, string, string) error
-	SetWindowOption       func(string, string, string) error
-	SetPaneOption         func(string, string, string) error
-	SetPaneTitle          func(string, string) error
-	SetHook               func(string, string, string) error
-	ListThreads           func() ([]tmux.Session, error)
-	Attach                func(string) error
-	Now                   func() time.Time
-}
-
-func DefaultOps() Ops {
-	return Ops{
-		HasSession:            tmux.HasSession,
-		NewSessionWithCommand: tmux.NewSessionWithCommand,
-		SetSessionOption:      tmux.SetSessionOption,
-		SetWindowOption:       tmux.SetWindowOption,
-		SetPaneOption:         tmux.SetPaneOption,
-		SetPaneTitle:          tmux.SetPaneTitle,
-		SetHook:               tmux.SetHook,
-		ListThreads:           tmux.ListThreads,
-		Attach:                Attach,
-		Now:                   time.Now,
-	}
-}
-
 func Resolve(spec Spec) (Resolved, error) {
 	agent, ok := agents.Find(spec.AgentID)
 	if !ok {
@@ -103,33 +77,32 @@ func Resolve(spec Spec) (Resolved, error) {
 	}, nil
 }
 
-func EnsureAndAttach(spec Spec, ops Ops) error {
-	resolved, err := Ensure(spec, ops)
+func EnsureAndAttach(spec Spec, client tmux.Client) error {
+	resolved, err := Ensure(spec, client)
 	if err != nil {
 		return err
 	}
-	return ops.withDefaults().Attach(resolved.SessionName)
+	return attach(client, resolved.SessionName)
 }
 
-func CreateAndAttach(spec Spec, ops Ops) error {
-	resolved, err := Create(spec, ops)
+func CreateAndAttach(spec Spec, client tmux.Client) error {
+	resolved, err := Create(spec, client)
 	if err != nil {
 		return err
 	}
-	return ops.withDefaults().Attach(resolved.SessionName)
+	return attach(client, resolved.SessionName)
 }
 
-func Ensure(spec Spec, ops Ops) (Resolved, error) {
+func Ensure(spec Spec, client tmux.Client) (Resolved, error) {
 	resolved, err := Resolve(spec)
 	if err != nil {
 		return Resolved{}, err
 	}
 
-	ops = ops.withDefaults()
 	targetPane := resolved.SessionName
 	created := false
-	if !ops.HasSession(resolved.SessionName) {
-		paneID, err := ops.NewSessionWithCommand(
+	if !client.HasSession(resolved.SessionName) {
+		paneID, err := client.NewSessionWithCommand(
 			resolved.SessionName,
 			resolved.Dir,
 			threadCommand(resolved.Agent.ID, resolved.SessionName, resolved.Agent.FullCommand(resolved.Mode)),
@@ -146,7 +119,7 @@ func Ensure(spec Spec, ops Ops) (Resolved, error) {
 		AgentID:      resolved.Agent.ID,
 		InitialTitle: resolved.Title,
 		Created:      created,
-	}, ops); err != nil {
+	}, client); err != nil {
 		return Resolved{}, err
 	}
 	return resolved, nil
@@ -154,15 +127,14 @@ func Ensure(spec Spec, ops Ops) (Resolved, error) {
 
 // Create always starts a new thread, picking a unique session name so multiple
 // agents of the same kind can run in the same project.
-func Create(spec Spec, ops Ops) (Resolved, error) {
+func Create(spec Spec, client tmux.Client) (Resolved, error) {
 	resolved, err := Resolve(spec)
 	if err != nil {
 		return Resolved{}, err
 	}
 
-	ops = ops.withDefaults()
-	resolved.SessionName = uniqueSessionName(resolved.SessionName, ops.HasSession)
-	paneID, err := ops.NewSessionWithCommand(
+	resolved.SessionName = uniqueSessionName(resolved.SessionName, client.HasSession)
+	paneID, err := client.NewSessionWithCommand(
 		resolved.SessionName,
 		resolved.Dir,
 		threadCommand(resolved.Agent.ID, resolved.SessionName, resolved.Agent.FullCommand(resolved.Mode)),
@@ -176,7 +148,7 @@ func Create(spec Spec, ops Ops) (Resolved, error) {
 		AgentID:      resolved.Agent.ID,
 		InitialTitle: resolved.Title,
 		Created:      true,
-	}, ops); err != nil {
+	}, client); err != nil {
 		return Resolved{}, err
 	}
 	return resolved, nil
@@ -214,55 +186,53 @@ type SupportSpec struct {
 	Created      bool
 }
 
-func InstallAllSupport(ops Ops) (int, error) {
-	ops = ops.withDefaults()
-	threads, err := ops.ListThreads()
+func InstallAllSupport(client tmux.Client) (int, error) {
+	sessions, err := client.ListSessions()
 	if err != nil {
 		return 0, err
 	}
+	threads := tmux.ThreadSessions(sessions)
 	for _, thread := range threads {
-		if err := InstallSupportForSession(thread, ops); err != nil {
+		if err := InstallSupportForSession(thread, client); err != nil {
 			return 0, err
 		}
 	}
 	return len(threads), nil
 }
 
-func InstallSupportForSession(session tmux.Session, ops Ops) error {
-	ops = ops.withDefaults()
+func InstallSupportForSession(session tmux.Session, client tmux.Client) error {
 	title := initialTitle(session)
 	if err := ApplySupport(SupportSpec{
 		SessionName:  session.Name,
 		TargetPane:   session.Name,
 		AgentID:      session.AgentID,
 		InitialTitle: title,
-	}, ops); err != nil {
+	}, client); err != nil {
 		return err
 	}
-	return repairStaleWorkingState(session, ops)
+	return repairStaleWorkingState(session, client)
 }
 
-func ApplySupport(spec SupportSpec, ops Ops) error {
-	ops = ops.withDefaults()
+func ApplySupport(spec SupportSpec, client tmux.Client) error {
 	spec = normalizeSupportSpec(spec)
-	if err := setSessionOptions(spec.SessionName, supportSessionOptions(spec), ops); err != nil {
+	if err := setSessionOptions(spec.SessionName, supportSessionOptions(spec), client); err != nil {
 		return err
 	}
 	if spec.Created {
-		if err := setSessionOptions(spec.SessionName, createdSessionOptions(), ops); err != nil {
+		if err := setSessionOptions(spec.SessionName, createdSessionOptions(), client); err != nil {
 			return err
 		}
 	}
-	if err := ops.SetWindowOption(spec.TargetPane, "allow-passthrough", "on"); err != nil {
+	if err := client.SetWindowOption(spec.TargetPane, "allow-passthrough", "on"); err != nil {
 		return fmt.Errorf("set allow-passthrough: %w", err)
 	}
-	if err := setThreadHooks(spec.SessionName, ops); err != nil {
+	if err := setThreadHooks(spec.SessionName, client); err != nil {
 		return err
 	}
 	if !spec.Created {
 		return nil
 	}
-	if err := ops.SetPaneTitle(spec.TargetPane, spec.InitialTitle); err != nil {
+	if err := client.SetPaneTitle(spec.TargetPane, spec.InitialTitle); err != nil {
 		return fmt.Errorf("set pane title: %w", err)
 	}
 	return nil
@@ -315,30 +285,30 @@ func createdSessionOptions() []sessionOption {
 	}
 }
 
-func setSessionOptions(sessionName string, options []sessionOption, ops Ops) error {
+func setSessionOptions(sessionName string, options []sessionOption, client tmux.Client) error {
 	for _, opt := range options {
-		if err := ops.SetSessionOption(sessionName, opt.name, opt.value); err != nil {
+		if err := client.SetSessionOption(sessionName, opt.name, opt.value); err != nil {
 			return fmt.Errorf("set session option %s: %w", opt.name, err)
 		}
 	}
 	return nil
 }
 
-func setThreadHooks(sessionName string, ops Ops) error {
+func setThreadHooks(sessionName string, client tmux.Client) error {
 	for _, hook := range threadHooks() {
-		if err := ops.SetHook(sessionName, hook.name, hook.command); err != nil {
+		if err := client.SetHook(sessionName, hook.name, hook.command); err != nil {
 			return fmt.Errorf("set hook %s: %w", hook.name, err)
 		}
 	}
 	return nil
 }
 
-func repairStaleWorkingState(session tmux.Session, ops Ops) error {
+func repairStaleWorkingState(session tmux.Session, client tmux.Client) error {
 	if session.Name == "" || session.AgentState != "working" {
 		return nil
 	}
-	now := ops.Now()
-	if !staleWorkingTimestamp(session.AgentUpdated, now) {
+	at := now()
+	if !staleWorkingTimestamp(session.AgentUpdated, at) {
 		return nil
 	}
 	prefix := agentSymbol(session.AgentID)
@@ -346,15 +316,15 @@ func repairStaleWorkingState(session tmux.Session, ops Ops) error {
 		{"@kitmux_agent_state", "idle"},
 		{"@kitmux_agent_event", "stale-working"},
 		{"@kitmux_agent_detail", ""},
-		{"@kitmux_agent_updated", fmt.Sprintf("%d", now.UnixMilli())},
+		{"@kitmux_agent_updated", fmt.Sprintf("%d", at.UnixMilli())},
 		{"@kitmux_agent_title_prefix", prefix},
 		{"@kitmux_agent_title_display", ""},
 	}
-	if err := setSessionOptions(session.Name, options, ops); err != nil {
+	if err := setSessionOptions(session.Name, options, client); err != nil {
 		return err
 	}
 	for _, opt := range options {
-		if err := ops.SetPaneOption(session.Name, opt.name, opt.value); err != nil {
+		if err := client.SetPaneOption(session.Name, opt.name, opt.value); err != nil {
 			return fmt.Errorf("set pane option %s: %w", opt.name, err)
 		}
 	}
@@ -399,22 +369,26 @@ func threadTitleFormat() string {
 		"#{?#{@kitmux_thread_title},#{@kitmux_thread_title},#{session_name}}"
 }
 
-const supportVersion = "8"
+const supportVersion = "9"
 
 type threadHook struct {
 	name    string
 	command string
 }
 
+// threadHooks are set on every thread session. Session hooks replace the
+// global ones, so the client hooks repeat the global focus hook that records
+// the focus change for "kitmux report".
 func threadHooks() []threadHook {
+	focus := "refresh-client ; " + activity.FocusHookCommand(true)
 	return []threadHook{
 		{
 			name:    "client-attached",
-			command: "refresh-client",
+			command: focus,
 		},
 		{
 			name:    "client-session-changed",
-			command: "refresh-client",
+			command: focus,
 		},
 		{
 			name:    "alert-bell",
@@ -429,9 +403,15 @@ func alertBellHookCommand() string {
 		`printf "\007" > "$tty"; done'`
 }
 
+// Attach switches the current client to the thread inside tmux, or replaces
+// this process with `tmux attach-session` outside it.
 func Attach(sessionName string) error {
+	return attach(tmux.Default(), sessionName)
+}
+
+func attach(client tmux.Client, sessionName string) error {
 	if os.Getenv("TMUX") != "" {
-		return switchClient(sessionName)
+		return client.SwitchClient(sessionName)
 	}
 	tmuxPath, err := exec.LookPath("tmux")
 	if err != nil {
@@ -440,41 +420,6 @@ func Attach(sessionName string) error {
 	return syscall.Exec(tmuxPath, []string{"tmux", "attach-session", "-t", sessionName}, os.Environ())
 }
 
-func (ops Ops) withDefaults() Ops {
-	defaults := DefaultOps()
-	if ops.HasSession == nil {
-		ops.HasSession = defaults.HasSession
-	}
-	if ops.NewSessionWithCommand == nil {
-		ops.NewSessionWithCommand = defaults.NewSessionWithCommand
-	}
-	if ops.SetSessionOption == nil {
-		ops.SetSessionOption = defaults.SetSessionOption
-	}
-	if ops.SetWindowOption == nil {
-		ops.SetWindowOption = defaults.SetWindowOption
-	}
-	if ops.SetPaneOption == nil {
-		ops.SetPaneOption = defaults.SetPaneOption
-	}
-	if ops.SetPaneTitle == nil {
-		ops.SetPaneTitle = defaults.SetPaneTitle
-	}
-	if ops.SetHook == nil {
-		ops.SetHook = defaults.SetHook
-	}
-	if ops.ListThreads == nil {
-		ops.ListThreads = defaults.ListThreads
-	}
-	if ops.Attach == nil {
-		ops.Attach = defaults.Attach
-	}
-	if ops.Now == nil {
-		ops.Now = defaults.Now
-	}
-	return ops
-}
-
 var unsafeSessionChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
 
 func sanitizeSessionName(name string) string {
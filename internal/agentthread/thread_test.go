@@ -1,12 +1,14 @@
 package agentthread
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
 
-	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/activity"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
 )
 
 func TestResolveDerivesStableSessionName(t *testing.T) {
@@ -26,58 +28,43 @@ func TestResolveDerivesStableSessionName(t *testing.T) {
 }
 
 func TestEnsureCreatesMissingThread(t *testing.T) {
-	var calls []string
-	ops := Ops{
-		HasSession: func(string) bool { return false },
-		NewSessionWithCommand: func(name, dir, command string) (string, error) {
-			calls = append(calls, "new:"+name+":"+dir+":"+command)
-			return "%1", nil
-		},
-		SetSessionOption: func(_, option, value string) error {
-			calls = append(calls, "session:"+option+"="+value)
-			return nil
-		},
-		SetWindowOption: func(_, option, value string) error {
-			calls = append(calls, "window:"+option+"="+value)
-			return nil
-		},
-		SetPaneTitle: func(_, title string) error {
-			calls = append(calls, "title:"+title)
-			return nil
-		},
-		SetHook: func(_, hook, command string) error {
-			calls = append(calls, "hook:"+hook+"="+command)
-			return nil
-		},
-	}
+	srv := tmuxtest.New()
 
-	resolved, err := Ensure(Spec{AgentID: "droid", Dir: "/tmp/app"}, ops)
+	resolved, err := Ensure(Spec{AgentID: "droid", Dir: "/tmp/app"}, srv)
 	if err != nil {
 		t.Fatalf("Ensure() error = %v", err)
 	}
 	if resolved.SessionName != "droid-app" {
 		t.Fatalf("SessionName = %q", resolved.SessionName)
 	}
+	calls := srv.Calls()
 	wantPrefix := []string{
-		"new:droid-app:/tmp/app:" + threadCommand("droid", "droid-app", "droid"),
-		"session:status=off",
-		"session:set-titles=on",
-		"session:set-titles-string=" + threadTitleFormat(),
+		"has-session droid-app",
+		"new-session droid-app /tmp/app " + threadCommand("droid", "droid-app", "droid"),
+		"set-option droid-app status off",
+		"set-option droid-app set-titles on",
+		"set-option droid-app set-titles-string " + threadTitleFormat(),
 	}
 	if !reflect.DeepEqual(calls[:len(wantPrefix)], wantPrefix) {
 		t.Fatalf("calls prefix = %#v", calls[:len(wantPrefix)])
 	}
-	if !contains(calls, "session:@kitmux_agent_support="+supportVersion) {
-		t.Fatalf("missing support version option: %#v", calls)
+	if got := srv.SessionOption("droid-app", "@kitmux_agent_support"); got != supportVersion {
+		t.Fatalf("support version option = %q", got)
+	}
+	if got := srv.SessionOption("droid-app", "@kitmux_initial_title"); got != "⛬ Droid · app" {
+		t.Fatalf("initial title option = %q", got)
+	}
+	if got := srv.SessionOption("droid-app", "@kitmux_agent_state"); got != "idle" {
+		t.Fatalf("initial agent state option = %q", got)
 	}
-	if !contains(calls, "session:@kitmux_initial_title=⛬ Droid · app") {
-		t.Fatalf("missing initial title option: %#v", calls)
+	if got := srv.WindowOption("droid-app", "allow-passthrough"); got != "on" {
+		t.Fatalf("allow-passthrough = %q", got)
 	}
-	if !contains(calls, "session:@kitmux_agent_state=idle") {
-		t.Fatalf("missing initial agent state option: %#v", calls)
+	if got := srv.Hook("droid-app", "alert-bell"); got != bellHookCommand() {
+		t.Fatalf("alert-bell hook = %q", got)
 	}
-	if !contains(calls, `hook:alert-bell=`+bellHookCommand()) {
-		t.Fatalf("missing alert-bell hook: %#v", calls)
+	if got := srv.PaneTitle("droid-app"); got != "⛬ Droid · app" {
+		t.Fatalf("pane title = %q", got)
 	}
 }
 
@@ -101,172 +88,135 @@ func TestThreadTitleFormatKeepsSessionNameDynamic(t *testing.T) {
 }
 
 func TestEnsureSkipsExistingThreadCreate(t *testing.T) {
-	called := false
-	var titleTarget string
-	ops := Ops{
-		HasSession: func(string) bool { return true },
-		NewSessionWithCommand: func(_, _, _ string) (string, error) {
-			called = true
-			return "", nil
-		},
-		SetSessionOption: func(_, _, _ string) error { return nil },
-		SetWindowOption:  func(_, _, _ string) error { return nil },
-		SetHook:          func(_, _, _ string) error { return nil },
-		SetPaneTitle: func(target, _ string) error {
-			titleTarget = target
-			return nil
-		},
-	}
+	srv := tmuxtest.New()
+	srv.AddSession("droid-app", "/tmp/app")
 
-	if _, err := Ensure(Spec{AgentID: "droid", Dir: "/tmp/app"}, ops); err != nil {
+	if _, err := Ensure(Spec{AgentID: "droid", Dir: "/tmp/app"}, srv); err != nil {
 		t.Fatalf("Ensure() error = %v", err)
 	}
-	if called {
-		t.Fatal("NewSessionWithCommand was called for existing thread")
+	for _, call := range srv.Calls()[1:] {
+		if strings.HasPrefix(call, "new-session") {
+			t.Fatalf("new-session ran for existing thread: %#v", srv.Calls())
+		}
+		if strings.HasPrefix(call, "select-pane") {
+			t.Fatalf("pane title was set for existing thread: %q", call)
+		}
 	}
-	if titleTarget != "" {
-		t.Fatalf("title target = %q", titleTarget)
+	if got := srv.SessionOption("droid-app", "@kitmux_thread"); got != "1" {
+		t.Fatalf("@kitmux_thread = %q", got)
 	}
 }
 
 func TestCreateGeneratesUniqueName(t *testing.T) {
-	existing := map[string]bool{"droid-app": true}
-	var created string
-	ops := Ops{
-		HasSession:            func(name string) bool { return existing[name] },
-		NewSessionWithCommand: func(name, _, _ string) (string, error) { created = name; return "%2", nil },
-		SetSessionOption:      func(_, _, _ string) error { return nil },
-		SetWindowOption:       func(_, _, _ string) error { return nil },
-		SetPaneTitle:          func(_, _ string) error { return nil },
-		SetHook:               func(_, _, _ string) error { return nil },
-	}
+	srv := tmuxtest.New()
+	srv.AddSession("droid-app", "/tmp/app")
 
-	resolved, err := Create(Spec{AgentID: "droid", Dir: "/tmp/app"}, ops)
+	resolved, err := Create(Spec{AgentID: "droid", Dir: "/tmp/app"}, srv)
 	if err != nil {
 		t.Fatalf("Create() error = %v", err)
 	}
 	if resolved.SessionName != "droid-app-2" {
 		t.Fatalf("SessionName = %q", resolved.SessionName)
 	}
-	if created != "droid-app-2" {
-		t.Fatalf("created session = %q", created)
+	if !reflect.DeepEqual(srv.SessionNames(), []string{"droid-app", "droid-app-2"}) {
+		t.Fatalf("sessions = %#v", srv.SessionNames())
+	}
+	if got := srv.PanePath("droid-app-2"); got != "/tmp/app" {
+		t.Fatalf("pane path = %q", got)
 	}
 }
 
 func TestAttachSwitchesClientInsideTmux(t *testing.T) {
 	t.Setenv("TMUX", "/tmp/tmux-123/default,1,0")
-	original := switchClient
-	t.Cleanup(func() {
-		switchClient = original
-	})
-
-	var switched string
-	switchClient = func(target string) error {
-		switched = target
-		return nil
-	}
+	srv := tmuxtest.Install(t)
+	srv.AddSession("main", "/tmp")
+	srv.AddSession("droid-app-2", "/tmp/app")
+	srv.Attach("main")
 
 	if err := Attach("droid-app-2"); err != nil {
 		t.Fatalf("Attach() error = %v", err)
 	}
-	if switched != "droid-app-2" {
-		t.Fatalf("switched target = %q", switched)
+	if current, _ := srv.CurrentSession(); current != "droid-app-2" {
+		t.Fatalf("current session = %q", current)
 	}
 }
 
 func TestClientHooksDoNotTargetLiteralHookClient(t *testing.T) {
 	for _, hook := range threadHooks() {
-		if (hook.name == "client-attached" || hook.name == "client-session-changed") && hook.command != "refresh-client" {
+		if (hook.name == "client-attached" || hook.name == "client-session-changed") &&
+			(hook.command != "refresh-client ; "+activity.FocusHookCommand(true) || strings.Contains(hook.command, "hook_client")) {
 			t.Fatalf("%s command = %q", hook.name, hook.command)
 		}
 	}
 }
 
+func addThread(srv *tmuxtest.Server, name, dir, agentID string) {
+	srv.AddSession(name, dir)
+	_ = srv.SetSessionOption(name, "@kitmux_thread", "1")
+	_ = srv.SetSessionOption(name, "@kitmux_agent", agentID)
+}
+
 func TestInstallAllSupportUpdatesExistingThreads(t *testing.T) {
-	var sessions []string
-	var hooks []string
-	ops := Ops{
-		ListThreads: func() ([]tmux.Session, error) {
-			return []tmux.Session{
-				{Name: "droid-app", Path: "/tmp/app", AgentID: "droid"},
-				{Name: "codex-api", Path: "/tmp/api", AgentID: "codex"},
-			}, nil
-		},
-		SetSessionOption: func(target, _, _ string) error {
-			sessions = append(sessions, target)
-			return nil
-		},
-		SetWindowOption: func(_, _, _ string) error { return nil },
-		SetHook: func(target, hook, _ string) error {
-			hooks = append(hooks, target+":"+hook)
-			return nil
-		},
-		SetPaneTitle: func(_, _ string) error {
-			t.Fatal("SetPaneTitle should not run when installing support on existing threads")
-			return nil
-		},
-	}
+	srv := tmuxtest.New()
+	addThread(srv, "droid-app", "/tmp/app", "droid")
+	addThread(srv, "codex-api", "/tmp/api", "codex")
+	srv.AddSession("plain", "/tmp/plain")
 
-	count, err := InstallAllSupport(ops)
+	count, err := InstallAllSupport(srv)
 	if err != nil {
 		t.Fatalf("InstallAllSupport() error = %v", err)
 	}
 	if count != 2 {
 		t.Fatalf("count = %d", count)
 	}
-	if !contains(sessions, "droid-app") || !contains(sessions, "codex-api") {
-		t.Fatalf("sessions = %#v", sessions)
+	for _, name := range []string{"droid-app", "codex-api"} {
+		if got := srv.SessionOption(name, "@kitmux_agent_support"); got != supportVersion {
+			t.Fatalf("%s support version = %q", name, got)
+		}
+		if srv.Hook(name, "alert-bell") == "" {
+			t.Fatalf("%s is missing the alert-bell hook", name)
+		}
+	}
+	if srv.SessionOption("plain", "@kitmux_agent_support") != "" {
+		t.Fatal("support was installed on a non-thread session")
 	}
-	if !contains(hooks, "droid-app:alert-bell") || !contains(hooks, "codex-api:alert-bell") {
-		t.Fatalf("hooks = %#v", hooks)
+	for _, call := range srv.Calls() {
+		if strings.HasPrefix(call, "select-pane") {
+			t.Fatalf("pane title should not change when installing support on existing threads: %q", call)
+		}
 	}
 }
 
 func TestInstallAllSupportClearsStaleWorkingState(t *testing.T) {
-	now := time.UnixMilli(1781897000000)
-	options := map[string]map[string]string{}
-	paneOptions := map[string]map[string]string{}
-	ops := Ops{
-		ListThreads: func() ([]tmux.Session, error) {
-			return []tmux.Session{
-				{Name: "droid-stale", Path: "/tmp/stale", AgentID: "droid", AgentState: "working", AgentUpdated: 2026},
-				{Name: "droid-fresh", Path: "/tmp/fresh", AgentID: "droid", AgentState: "working", AgentUpdated: now.Add(-time.Minute).UnixMilli()},
-			}, nil
-		},
-		SetSessionOption: func(target, option, value string) error {
-			if options[target] == nil {
-				options[target] = map[string]string{}
-			}
-			options[target][option] = value
-			return nil
-		},
-		SetPaneOption: func(target, option, value string) error {
-			if paneOptions[target] == nil {
-				paneOptions[target] = map[string]string{}
-			}
-			paneOptions[target][option] = value
-			return nil
-		},
-		SetWindowOption: func(_, _, _ string) error { return nil },
-		SetHook:         func(_, _, _ string) error { return nil },
-		Now:             func() time.Time { return now },
-	}
-
-	if _, err := InstallAllSupport(ops); err != nil {
+	at := time.UnixMilli(1781897000000)
+	original := now
+	t.Cleanup(func() { now = original })
+	now = func() time.Time { return at }
+
+	srv := tmuxtest.New()
+	addThread(srv, "droid-stale", "/tmp/stale", "droid")
+	_ = srv.SetSessionOption("droid-stale", "@kitmux_agent_state", "working")
+	_ = srv.SetSessionOption("droid-stale", "@kitmux_agent_updated", "2026")
+	addThread(srv, "droid-fresh", "/tmp/fresh", "droid")
+	_ = srv.SetSessionOption("droid-fresh", "@kitmux_agent_state", "working")
+	_ = srv.SetSessionOption("droid-fresh", "@kitmux_agent_updated", fmt.Sprint(at.Add(-time.Minute).UnixMilli()))
+
+	if _, err := InstallAllSupport(srv); err != nil {
 		t.Fatalf("InstallAllSupport() error = %v", err)
 	}
-	stale := options["droid-stale"]
-	if stale["@kitmux_agent_state"] != "idle" ||
-		stale["@kitmux_agent_event"] != "stale-working" ||
-		stale["@kitmux_agent_updated"] != "1781897000000" ||
-		stale["@kitmux_agent_title_prefix"] != "⛬" {
-		t.Fatalf("stale options = %#v", stale)
+	stale := func(option string) string { return srv.SessionOption("droid-stale", option) }
+	if stale("@kitmux_agent_state") != "idle" ||
+		stale("@kitmux_agent_event") != "stale-working" ||
+		stale("@kitmux_agent_updated") != "1781897000000" ||
+		stale("@kitmux_agent_title_prefix") != "⛬" {
+		t.Fatalf("stale state = %s/%s/%s/%s", stale("@kitmux_agent_state"), stale("@kitmux_agent_event"),
+			stale("@kitmux_agent_updated"), stale("@kitmux_agent_title_prefix"))
 	}
-	if paneOptions["droid-stale"]["@kitmux_agent_state"] != "idle" {
-		t.Fatalf("stale pane options = %#v", paneOptions["droid-stale"])
+	if got := srv.PaneOption("droid-stale", "@kitmux_agent_state"); got != "idle" {
+		t.Fatalf("stale pane state = %q", got)
 	}
-	if options["droid-fresh"]["@kitmux_agent_event"] == "stale-working" {
-		t.Fatalf("fresh working state was cleared: %#v", options["droid-fresh"])
+	if got := srv.SessionOption("droid-fresh", "@kitmux_agent_event"); got == "stale-working" {
+		t.Fatal("fresh working state was cleared")
 	}
 }
 
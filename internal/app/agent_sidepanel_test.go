@@ -2,16 +2,19 @@ package app
 
 import (
 	"errors"
+	"strconv"
+	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/miltonparedes/kitmux/internal/agentenv"
-	"github.com/miltonparedes/kitmux/internal/agentlaunch"
 	"github.com/miltonparedes/kitmux/internal/app/messages"
 	"github.com/miltonparedes/kitmux/internal/cache"
+	"github.com/miltonparedes/kitmux/internal/command"
 	"github.com/miltonparedes/kitmux/internal/store"
 	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
 )
 
 func TestLaunchAgentAutoSidepanelWhenWide(t *testing.T) {
@@ -19,11 +22,12 @@ func TestLaunchAgentAutoSidepanelWhenWide(t *testing.T) {
 	t.Setenv("KITMUX_AGENT_SIDEPANEL_MIN_WIDTH", "160")
 	t.Setenv("KITMUX_SIDEPANEL_COMMAND", "")
 
-	calls := stubAgentLaunch(t, 200, nil)
+	srv := stubAgentLaunch(t, 200, nil)
 
 	m := New(ModeAgents)
 	m.launchAgent(messages.LaunchAgentMsg{AgentID: "codex", ModeID: "default", Target: "pane"})
 
+	calls := launched(srv)
 	if calls.sent != trackedCommand("codex", "codex") {
 		t.Fatalf("expected codex to be sent, got %q", calls.sent)
 	}
@@ -39,11 +43,12 @@ func TestLaunchAgentAutoSidepanelSkipsWhenNarrow(t *testing.T) {
 	t.Setenv("KITMUX_AGENT_SIDEPANEL", "auto")
 	t.Setenv("KITMUX_AGENT_SIDEPANEL_MIN_WIDTH", "160")
 
-	calls := stubAgentLaunch(t, 120, nil)
+	srv := stubAgentLaunch(t, 120, nil)
 
 	m := New(ModeAgents)
 	m.launchAgent(messages.LaunchAgentMsg{AgentID: "codex", ModeID: "default", Target: "pane"})
 
+	calls := launched(srv)
 	if calls.sent != trackedCommand("codex", "codex") {
 		t.Fatalf("expected codex to be sent, got %q", calls.sent)
 	}
@@ -55,11 +60,12 @@ func TestLaunchAgentAutoSidepanelSkipsWhenNarrow(t *testing.T) {
 func TestLaunchAgentAlwaysSidepanelIgnoresWidthError(t *testing.T) {
 	t.Setenv("KITMUX_AGENT_SIDEPANEL", "always")
 
-	calls := stubAgentLaunch(t, 0, errors.New("no tmux"))
+	srv := stubAgentLaunch(t, 0, errors.New("no tmux"))
 
 	m := New(ModeAgents)
 	m.launchAgent(messages.LaunchAgentMsg{AgentID: "codex", ModeID: "default", Target: "pane"})
 
+	calls := launched(srv)
 	if calls.sidepanelCommand != "kitmux sidepanel" {
 		t.Fatalf("expected sidepanel split, got %q", calls.sidepanelCommand)
 	}
@@ -68,11 +74,12 @@ func TestLaunchAgentAlwaysSidepanelIgnoresWidthError(t *testing.T) {
 func TestLaunchAgentOffNeverStartsSidepanel(t *testing.T) {
 	t.Setenv("KITMUX_AGENT_SIDEPANEL", "off")
 
-	calls := stubAgentLaunch(t, 240, nil)
+	srv := stubAgentLaunch(t, 240, nil)
 
 	m := New(ModeAgents)
 	m.launchAgent(messages.LaunchAgentMsg{AgentID: "codex", ModeID: "default", Target: "pane"})
 
+	calls := launched(srv)
 	if calls.sidepanelCommand != "" {
 		t.Fatalf("expected no sidepanel split, got %q", calls.sidepanelCommand)
 	}
@@ -81,11 +88,12 @@ func TestLaunchAgentOffNeverStartsSidepanel(t *testing.T) {
 func TestLaunchAgentExplicitSplitDoesNotStartSidepanel(t *testing.T) {
 	t.Setenv("KITMUX_AGENT_SIDEPANEL", "always")
 
-	calls := stubAgentLaunch(t, 240, nil)
+	srv := stubAgentLaunch(t, 240, nil)
 
 	m := New(ModeAgents)
 	m.launchAgent(messages.LaunchAgentMsg{AgentID: "codex", ModeID: "default", Target: "split"})
 
+	calls := launched(srv)
 	if calls.split != trackedCommand("codex", "codex") {
 		t.Fatalf("expected explicit split command codex, got %q", calls.split)
 	}
@@ -181,41 +189,46 @@ type launchCalls struct {
 	sidepanelRatio   int
 }
 
-func stubAgentLaunch(t *testing.T, width int, widthErr error) *launchCalls {
+// stubAgentLaunch starts a fake tmux server with the client in a single
+// pane of the given width. HOME points at a temp dir so hook installs stay
+// out of the real config.
+func stubAgentLaunch(t *testing.T, width int, widthErr error) *tmuxtest.Server {
 	t.Helper()
+	t.Setenv("HOME", t.TempDir())
 
-	originalOps := agentLaunchOps
-	t.Cleanup(func() {
-		agentLaunchOps = originalOps
-	})
-
-	calls := &launchCalls{}
-	agentLaunchOps = agentlaunch.Ops{}
-	agentLaunchOps.SendKeys = func(_, keys string) error {
-		calls.sent = keys
-		return nil
-	}
-	agentLaunchOps.SplitWindow = func(command string) error {
-		calls.split = command
-		return nil
-	}
-	agentLaunchOps.NewWindowWithCommand = func(_, _ string) error {
-		return nil
+	srv := tmuxtest.Install(t)
+	srv.Attach(srv.AddSession("main", "/tmp/main"))
+	srv.SetClientWidth(width)
+	if widthErr != nil {
+		srv.Fail("display-message", widthErr)
 	}
-	agentLaunchOps.NewWindowInDir = func(_, dir, command string) (string, error) {
-		calls.windowDir = dir
-		calls.windowCommand = command
-		return "%9", nil
-	}
-	agentLaunchOps.CurrentClientWidth = func() (int, error) {
-		return width, widthErr
-	}
-	agentLaunchOps.SplitWindowInDirPercent = func(_, _, command string, percent int) (string, error) {
-		calls.sidepanelCommand = command
-		calls.sidepanelRatio = percent
-		return "%2", nil
+	return srv
+}
+
+// launched reads back what an agent launch did to the fake server.
+func launched(srv *tmuxtest.Server) launchCalls {
+	var calls launchCalls
+	if sent := srv.SentKeys("main:0.0"); len(sent) > 0 {
+		calls.sent = sent[len(sent)-1]
+	}
+	for _, call := range srv.Calls() {
+		if fields := strings.Fields(call); len(fields) > 2 && fields[0] == "split-window" && fields[1] == "-p" {
+			calls.sidepanelRatio, _ = strconv.Atoi(fields[2])
+		}
+	}
+	panes, _ := srv.ListPanes()
+	for _, pane := range panes[1:] {
+		started := srv.PaneCommand(pane.ID)
+		switch {
+		case pane.Command == "kitmux":
+			calls.sidepanelCommand = started
+		case pane.WindowIndex == 0:
+			calls.split = started
+		default:
+			calls.windowDir = pane.Path
+			calls.windowCommand = started
+		}
 	}
-	agentLaunchOps.InstallHooks = func(string) error { return nil }
 	return calls
 }
 
@@ -227,13 +240,14 @@ func TestSidepanelLaunchAgentCreatesWindowAndSplitWhenWide(t *testing.T) {
 	t.Setenv("KITMUX_AGENT_SIDEPANEL", "auto")
 	t.Setenv("KITMUX_AGENT_SIDEPANEL_MIN_WIDTH", "160")
 
-	calls := stubAgentLaunch(t, 220, nil)
+	srv := stubAgentLaunch(t, 220, nil)
 	m := New(ModeSidepanel)
 	cmd := m.launchSidepanelAgent(messages.LaunchSidepanelAgentMsg{AgentID: "codex", ModeID: "default", Dir: "/tmp/repo"})
 	if cmd == nil {
 		t.Fatal("expected command")
 	}
 	_ = cmd()
+	calls := launched(srv)
 	if calls.windowDir != "/tmp/repo" {
 		t.Fatalf("expected window dir /tmp/repo, got %q", calls.windowDir)
 	}
@@ -252,9 +266,10 @@ func TestSidepanelLaunchAgentSkipsSplitWhenNarrow(t *testing.T) {
 	t.Setenv("KITMUX_AGENT_SIDEPANEL", "auto")
 	t.Setenv("KITMUX_AGENT_SIDEPANEL_MIN_WIDTH", "160")
 
-	calls := stubAgentLaunch(t, 100, nil)
+	srv := stubAgentLaunch(t, 100, nil)
 	m := New(ModeSidepanel)
 	_ = m.launchSidepanelAgent(messages.LaunchSidepanelAgentMsg{AgentID: "opencode", ModeID: "default", Dir: "/tmp/repo"})()
+	calls := launched(srv)
 	if calls.windowCommand != trackedCommand("opencode", "opencode") {
 		t.Fatalf("expected opencode command, got %q", calls.windowCommand)
 	}
@@ -275,7 +290,7 @@ func TestSessionsEnterPreservesSwitchCommand(t *testing.T) {
 		t.Fatalf("save cache: %v", err)
 	}
 
-	m := New(ModeSessions)
+	m := New(ModeSessions, WithEnv(command.Env{}))
 	initCmd := m.Init()
 	if initCmd == nil {
 		t.Fatal("expected cached init command")
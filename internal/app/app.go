@@ -11,13 +11,21 @@ import (
 	"github.com/miltonparedes/kitmux/internal/agentlaunch"
 	"github.com/miltonparedes/kitmux/internal/agents"
 	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/command"
+	"github.com/miltonparedes/kitmux/internal/commitmsg"
 	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/frecency"
+	"github.com/miltonparedes/kitmux/internal/macro"
 	"github.com/miltonparedes/kitmux/internal/openlocal"
-	"github.com/miltonparedes/kitmux/internal/recency"
+	"github.com/miltonparedes/kitmux/internal/plugin"
 	"github.com/miltonparedes/kitmux/internal/tmux"
 	agentabview "github.com/miltonparedes/kitmux/internal/views/agentab"
 	agentsview "github.com/miltonparedes/kitmux/internal/views/agents"
+	commitview "github.com/miltonparedes/kitmux/internal/views/commit"
+	diffview "github.com/miltonparedes/kitmux/internal/views/diff"
 	"github.com/miltonparedes/kitmux/internal/views/palette"
+	pluginlistview "github.com/miltonparedes/kitmux/internal/views/pluginlist"
+	searchview "github.com/miltonparedes/kitmux/internal/views/search"
 	"github.com/miltonparedes/kitmux/internal/views/sessions"
 	sidepanelview "github.com/miltonparedes/kitmux/internal/views/sidepanel"
 	threadsview "github.com/miltonparedes/kitmux/internal/views/threads"
@@ -25,6 +33,7 @@ import (
 	workspacesview "github.com/miltonparedes/kitmux/internal/views/workspaces"
 	"github.com/miltonparedes/kitmux/internal/views/worktrees"
 	"github.com/miltonparedes/kitmux/internal/worktree"
+	"github.com/miltonparedes/kitmux/internal/worktreesetup"
 )
 
 // Mode determines how kitmux starts.
@@ -40,6 +49,9 @@ const (
 	ModeWorkspaces             // Workspaces dashboard
 	ModeSidepanel              // Agent sidepanel
 	ModeThreads                // Agent threads
+	ModeDiff                   // Diff viewer
+	ModeCommit                 // Commit with a generated message
+	ModeSearch                 // Global search
 )
 
 type activeView int
@@ -53,6 +65,10 @@ const (
 	viewWorkspaces            // Workspaces dashboard
 	viewSidepanel             // Agent sidepanel
 	viewThreads               // Agent threads
+	viewDiff                  // Diff viewer
+	viewCommit                // Commit message editor
+	viewSearch                // Global search
+	viewPluginList            // A plugin's list provider
 )
 
 type Model struct {
@@ -66,18 +82,26 @@ type Model struct {
 	workspacesView workspacesview.Model
 	sidepanelView  sidepanelview.Model
 	threadsView    threadsview.Model
+	diffView       diffview.Model
+	commitView     commitview.Model
+	searchView     searchview.Model
+	pluginList     pluginlistview.Model
 	palette        palette.Model
 	paletteActive  bool
-	paletteReturn  bool        // return to palette after sub-action completes
-	returnView     activeView  // view to return to from transient forms
-	pendingKey     *tea.KeyMsg // key to inject after sessions load
+	paletteReturn  bool       // return to palette after sub-action completes
+	returnView     activeView // view to return to from transient forms
+	commitReturn   activeView // view to return to from the commit view
 	width          int
 	height         int
-	runCommandID   string // for ModeRun: the command to execute
+	runCommandID   string            // for ModeRun: the command to execute
+	runArgs        map[string]string // for ModeRun: the command's parameters
+	runErr         error             // for ModeRun: why the command failed
+	env            *command.Env      // where kitmux runs, nil until detected
+	// pendingRun is a command asked for before env was known; it runs
+	// once palette.EnvMsg arrives.
+	pendingRun *messages.ExecuteCommandMsg
 }
 
-var agentLaunchOps = agentlaunch.DefaultOps()
-
 func New(mode Mode, opts ...Option) Model {
 	m := Model{
 		mode:           mode,
@@ -90,6 +114,9 @@ func New(mode Mode, opts ...Option) Model {
 		workspacesView: workspacesview.New(),
 		sidepanelView:  sidepanelview.New(),
 		threadsView:    threadsview.New(),
+		diffView:       diffview.New(""),
+		commitView:     commitview.New(""),
+		searchView:     searchview.New(),
 		palette:        palette.New(),
 	}
 	for _, opt := range opts {
@@ -111,6 +138,12 @@ func New(mode Mode, opts ...Option) Model {
 		m.view = viewSidepanel
 	case ModeThreads:
 		m.view = viewThreads
+	case ModeDiff:
+		m.view = viewDiff
+	case ModeCommit:
+		m.view = viewCommit
+	case ModeSearch:
+		m.view = viewSearch
 	}
 	return m
 }
@@ -125,18 +158,68 @@ func WithRunCommand(id string) Option {
 	}
 }
 
+// WithEnv sets where kitmux runs, for callers that already detected it, so
+// the model does not detect it again.
+func WithEnv(env command.Env) Option {
+	return func(m *Model) {
+		m.env = &env
+		m.palette.SetEnv(env)
+	}
+}
+
+// WithRunArgs sets the parameters of the ModeRun command. Without them a
+// command that takes parameters asks for them in the palette.
+func WithRunArgs(args map[string]string) Option {
+	return func(m *Model) {
+		m.runArgs = args
+	}
+}
+
+// WithWorkspaceTags opens the workspaces dashboard filtered to workspaces
+// carrying any of tags.
+func WithWorkspaceTags(tags []string) Option {
+	return func(m *Model) {
+		m.workspacesView.SetTagFilter(tags)
+	}
+}
+
 func WithThreadsAll(showAll bool) Option {
 	return func(m *Model) {
 		m.threadsView.SetShowAll(showAll)
 	}
 }
 
+// Err reports why the command run in ModeRun failed, so the CLI can exit
+// non-zero.
+func (m Model) Err() error {
+	return m.runErr
+}
+
+func (m *Model) failRun(err error) {
+	if m.mode == ModeRun && m.runErr == nil {
+		m.runErr = err
+	}
+}
+
 func (m Model) Init() tea.Cmd {
+	return tea.Batch(m.initView(), watchTmuxEvents(), watchWorktreeStats(), palette.LoadPlugins(), m.detectEnv())
+}
+
+// detectEnv finds out where kitmux runs in the background, unless WithEnv
+// already said.
+func (m Model) detectEnv() tea.Cmd {
+	if m.env != nil {
+		return nil
+	}
+	return palette.DetectEnvCmd()
+}
+
+func (m Model) initView() tea.Cmd {
 	switch m.mode {
 	case ModeRun:
-		id := m.runCommandID
+		id, args := m.runCommandID, m.runArgs
 		return func() tea.Msg {
-			return messages.ExecuteCommandMsg{ID: id}
+			return messages.ExecuteCommandMsg{ID: id, Args: args}
 		}
 	case ModeWindows:
 		return m.initCurrentSessionWindows()
@@ -154,6 +237,12 @@ func (m Model) Init() tea.Cmd {
 			return m.sidepanelView.Init()
 		case viewThreads:
 			return m.threadsView.Init()
+		case viewDiff:
+			return m.diffView.Init()
+		case viewCommit:
+			return m.commitView.Init()
+		case viewSearch:
+			return m.searchView.Init()
 		default:
 			return m.sessions.Init()
 		}
@@ -209,8 +298,32 @@ func (m Model) dispatchInput(msg tea.Msg) (tea.Model, tea.Cmd, bool) {
 		return m.handleTogglePalette()
 	case messages.ExecuteCommandMsg:
 		m.paletteActive = false
-		updated, cmd := m.executeCommand(msg.ID)
+		updated, cmd := m.executeCommand(msg.ID, msg.Args)
 		return updated, cmd, true
+	case messages.PluginsLoadedMsg:
+		m.palette.Refresh()
+		return m, nil, true
+	case palette.EnvMsg:
+		m.env = &msg.Env
+		m.palette.SetEnv(msg.Env)
+		if run := m.pendingRun; run != nil {
+			m.pendingRun = nil
+			updated, cmd := m.executeCommand(run.ID, run.Args)
+			return updated, cmd, true
+		}
+		return m, nil, true
+	case messages.CommandDoneMsg:
+		if msg.Err != nil {
+			m.failRun(msg.Err)
+			_ = tmux.DisplayMessage(msg.Err.Error())
+		} else if msg.Message != "" {
+			_ = tmux.DisplayMessage(msg.Message)
+		}
+		if msg.Err == nil && msg.Exit {
+			return m, tea.Quit, true
+		}
+		cmd := m.returnToPalette()
+		return m, cmd, true
 	}
 	return m, nil, false
 }
@@ -219,6 +332,7 @@ func (m Model) dispatchInput(msg tea.Msg) (tea.Model, tea.Cmd, bool) {
 func (m Model) dispatchNavigation(msg tea.Msg) (tea.Model, tea.Cmd, bool) {
 	switch msg := msg.(type) {
 	case messages.SwitchSessionMsg:
+		frecency.Record(frecency.KindSession, msg.Name)
 		return m, openTmuxSessionCmd(msg.Name), true
 	case messages.SwitchWindowMsg:
 		return m, openTmuxPaneCmd(msg.Target), true
@@ -240,10 +354,22 @@ func (m Model) dispatchNavigation(msg tea.Msg) (tea.Model, tea.Cmd, bool) {
 	case messages.ReloadSessionsMsg:
 		m.view = viewSessions
 		return m, m.sessions.Reload(), true
+	case messages.TmuxChangedMsg:
+		return m.handleTmuxChanged()
+	case messages.WorktreeStatsChangedMsg:
+		return m.handleWorktreeStatsChanged(msg)
 	case messages.SwitchViewMsg:
 		return m.handleSwitchView(msg)
 	case messages.OpenWorkspacesMsg:
 		return m.handleOpenWorkspaces(msg)
+	case messages.OpenDiffMsg:
+		return m.handleOpenDiff(msg)
+	case messages.CloseDiffMsg:
+		return m.handleCloseDiff()
+	case messages.OpenCommitMsg:
+		return m.handleOpenCommit(msg)
+	case messages.CloseCommitMsg:
+		return m.handleCloseCommit(msg)
 	}
 	return m, nil, false
 }
@@ -255,13 +381,25 @@ func (m Model) dispatchWorktreeAction(msg tea.Msg) (tea.Model, tea.Cmd, bool) {
 		_ = tmux.SwitchClient(msg.Name)
 		return m, tea.Quit, true
 	case messages.SwitchWorktreeMsg:
-		_ = worktree.SwitchTo(msg.Branch)
+		if err := worktree.SwitchTo(msg.Branch); err != nil {
+			m.failRun(err)
+		}
 		return m, tea.Quit, true
 	case messages.CreateWorktreeMsg:
-		_ = worktree.Create(msg.Branch)
+		if err := worktree.Create(msg.Branch); err != nil {
+			m.failRun(err)
+			return m, tea.Quit, true
+		}
+		paths := createdWorktreePath(msg.Branch)
+		if cmd := m.setupWorktreesCmd(worktreeSession(paths), paths...); cmd != nil {
+			return m, cmd, true
+		}
 		return m, tea.Quit, true
 	case messages.RemoveWorktreeMsg:
-		_ = worktree.Remove(msg.Branch)
+		err := worktree.Remove(msg.Branch)
+		if msg.Source == "palette" {
+			return m, func() tea.Msg { return messages.CommandDoneMsg{Err: err} }, true
+		}
 		return m, m.worktreeView.Reload(), true
 	case messages.ReloadWorktreesMsg:
 		return m, m.worktreeView.Reload(), true
@@ -269,6 +407,39 @@ func (m Model) dispatchWorktreeAction(msg tea.Msg) (tea.Model, tea.Cmd, bool) {
 	return m, nil, false
 }
 
+// createdWorktreePath finds the worktree just created for branch.
+func createdWorktreePath(branch string) []string {
+	wts, err := worktree.List()
+	if err != nil {
+		return nil
+	}
+	for _, wt := range wts {
+		if wt.Branch == branch && wt.Path != "" {
+			return []string{wt.Path}
+		}
+	}
+	return nil
+}
+
+// worktreeSession names the tmux session env setup steps should land in:
+// the session already rooted at the new worktree, or none. The current
+// session belongs to some other worktree and must not get its variables.
+func worktreeSession(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	sessions, err := tmux.ListSessions()
+	if err != nil {
+		return ""
+	}
+	for _, s := range sessions {
+		if s.Path == paths[0] {
+			return s.Name
+		}
+	}
+	return ""
+}
+
 func (m Model) dispatchAgentAction(msg tea.Msg) (tea.Model, tea.Cmd, bool) {
 	switch msg := msg.(type) {
 	case messages.LaunchAgentMsg:
@@ -301,7 +472,7 @@ func (m Model) dispatchPaneAction(msg tea.Msg) (tea.Model, tea.Cmd, bool) {
 	case messages.RunPaneCommandMsg:
 		return m, runPaneCommand(msg.Command), true
 	case messages.SendPaneKeysMsg:
-		_ = agentLaunchOps.SendKeys(msg.Target, msg.Keys)
+		_ = tmux.SendKeys(msg.Target, msg.Keys)
 		return m, nil, true
 	case messages.OpenLocalEditorMsg:
 		return m.handleOpenLocalEditor(msg)
@@ -320,6 +491,10 @@ func (m Model) applyWindowSize(msg tea.WindowSizeMsg) Model {
 	m.workspacesView.SetSize(m.width, m.height-1)
 	m.sidepanelView.SetSize(m.width, m.height-1)
 	m.threadsView.SetSize(m.width, m.height-1)
+	m.diffView.SetSize(m.width, m.height-1)
+	m.commitView.SetSize(m.width, m.height-1)
+	m.searchView.SetSize(m.width, m.height-1)
+	m.pluginList.SetSize(m.width, m.height-1)
 	m.palette.SetSize(m.width, m.height)
 	return m
 }
@@ -356,7 +531,7 @@ func (m Model) handleSwitchView(msg messages.SwitchViewMsg) (tea.Model, tea.Cmd,
 		return m, m.worktreeView.Init(), true
 	case "agents":
 		m.view = viewAgents
-		return m, nil, true
+		return m, m.agentsView.Init(), true
 	case "sidepanel":
 		m.view = viewSidepanel
 		return m, m.sidepanelView.Init(), true
@@ -377,6 +552,72 @@ func (m Model) handleOpenWorkspaces(msg messages.OpenWorkspacesMsg) (tea.Model,
 	return m, m.workspacesView.Init(), true
 }
 
+func (m Model) handleOpenDiff(msg messages.OpenDiffMsg) (tea.Model, tea.Cmd, bool) {
+	if m.view != viewDiff {
+		m.returnView = m.view
+	}
+	m.view = viewDiff
+	m.diffView = diffview.New(msg.Dir)
+	m.diffView.SetSize(m.width, m.height-1)
+	return m, m.diffView.Init(), true
+}
+
+func (m Model) handleCloseDiff() (tea.Model, tea.Cmd, bool) {
+	if m.mode == ModeDiff {
+		return m, tea.Quit, true
+	}
+	if m.paletteReturn {
+		return m, m.returnToPalette(), true
+	}
+	m.view = m.returnView
+	if m.view == viewDiff {
+		m.view = viewSessions
+	}
+	return m, nil, true
+}
+
+func (m Model) handleOpenCommit(msg messages.OpenCommitMsg) (tea.Model, tea.Cmd, bool) {
+	if m.view != viewCommit {
+		m.commitReturn = m.view
+	}
+	m.view = viewCommit
+	m.commitView = commitview.New(msg.Dir)
+	m.commitView.SetSize(m.width, m.height-1)
+	return m, m.commitView.Init(), true
+}
+
+func (m Model) handleCloseCommit(msg messages.CloseCommitMsg) (tea.Model, tea.Cmd, bool) {
+	if msg.Committed {
+		_ = tmux.DisplayMessage("committed")
+	}
+	if m.mode == ModeCommit {
+		return m, tea.Quit, true
+	}
+	if m.commitReturn == viewDiff {
+		// Back to the diff the commit was started from, minus what was
+		// just committed.
+		m.view = viewDiff
+		return m, m.diffView.Reload(), true
+	}
+	if m.paletteReturn {
+		return m, m.returnToPalette(), true
+	}
+	m.view = m.commitReturn
+	if m.view == viewCommit {
+		m.view = viewSessions
+	}
+	return m, nil, true
+}
+
+// commitCmd opens the commit view when the repository has a commit
+// message generator, and the worktree backend's commit popup otherwise.
+func commitCmd() tea.Cmd {
+	if _, ok := commitmsg.FromRepo(config.CurrentRepo()); ok {
+		return func() tea.Msg { return messages.OpenCommitMsg{} }
+	}
+	return popupCmd(worktree.Current().CommitCommand(), "80%", "80%")
+}
+
 func (m Model) handleBackFromAgentAB() (tea.Model, tea.Cmd, bool) {
 	if m.paletteReturn {
 		return m, m.returnToPalette(), true
@@ -421,6 +662,21 @@ func (m Model) handleKeyMsg(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
 		return m.handlePaletteKey(msg)
 	}
 
+	if m.view == viewDiff || m.view == viewCommit {
+		// The diff viewer and the commit editor own every key but ctrl+c:
+		// q, esc and the letters the app binds globally are their input.
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit, true
+		}
+		return m, nil, false
+	}
+	switch m.view {
+	case viewSearch:
+		return m.handleQueryKey(msg, ModeSearch)
+	case viewPluginList:
+		return m.handleQueryKey(msg, ModeRun)
+	}
+
 	isEditing := m.isEditing()
 	switch msg.String() {
 	case "ctrl+c":
@@ -439,10 +695,29 @@ func (m Model) handleKeyMsg(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
 	return m, nil, false
 }
 
+// handleQueryKey leaves every key but esc and ctrl+c to the query of the
+// search or plugin list view.
+func (m Model) handleQueryKey(msg tea.KeyMsg, startMode Mode) (Model, tea.Cmd, bool) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit, true
+	case "esc":
+		if m.paletteReturn {
+			return m, m.returnToPalette(), true
+		}
+		return m.escWithMode(startMode)
+	}
+	return m, nil, false
+}
+
 func (m Model) handlePaletteKey(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
 	switch msg.String() {
 	case "esc":
-		if m.mode == ModePalette {
+		if m.palette.InForm() && m.mode != ModeRun {
+			// Back from the parameter form to the command list.
+			break
+		}
+		if m.mode == ModePalette || m.mode == ModeRun {
 			return m, tea.Quit, true
 		}
 		m.paletteActive = false
@@ -506,7 +781,7 @@ func (m Model) handleAgentsKey(isEditing bool) (Model, tea.Cmd, bool) {
 		return m, nil, false
 	}
 	m.view = viewAgents
-	return m, nil, true
+	return m, m.agentsView.Init(), true
 }
 
 // handleEscKey implements esc semantics. Workspaces owns its own esc handling
@@ -569,7 +844,7 @@ func (m Model) handleEscByView() (Model, tea.Cmd, bool) {
 	case viewThreads:
 		return m.escWithMode(ModeThreads)
 	default:
-		if m.sessions.IsEditing() {
+		if m.sessions.IsEditing() || m.sessions.HasMarks() {
 			return m, nil, false
 		}
 		return m, tea.Quit, true
@@ -606,6 +881,14 @@ func (m Model) routeToView(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.sidepanelView, cmd = m.sidepanelView.Update(msg)
 	case viewThreads:
 		m.threadsView, cmd = m.threadsView.Update(msg)
+	case viewDiff:
+		m.diffView, cmd = m.diffView.Update(msg)
+	case viewCommit:
+		m.commitView, cmd = m.commitView.Update(msg)
+	case viewSearch:
+		m.searchView, cmd = m.searchView.Update(msg)
+	case viewPluginList:
+		m.pluginList, cmd = m.pluginList.Update(msg)
 	}
 	return m, cmd
 }
@@ -613,13 +896,6 @@ func (m Model) routeToView(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) routeToSessions(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	m.sessions, cmd = m.sessions.Update(msg)
-	if m.pendingKey != nil && m.sessions.ConsumeLoaded() {
-		km := *m.pendingKey
-		m.pendingKey = nil
-		var pendingCmd tea.Cmd
-		m.sessions, pendingCmd = m.sessions.Update(km)
-		cmd = tea.Batch(cmd, pendingCmd)
-	}
 	if m.paletteReturn && !m.sessions.IsEditing() {
 		if cmd != nil {
 			return m, cmd
@@ -667,6 +943,14 @@ func (m Model) View() string {
 		return m.sidepanelView.View()
 	case viewThreads:
 		return m.threadsView.View()
+	case viewDiff:
+		return m.diffView.View()
+	case viewCommit:
+		return m.commitView.View()
+	case viewSearch:
+		return m.searchView.View()
+	case viewPluginList:
+		return m.pluginList.View()
 	default:
 		return m.sessions.View()
 	}
@@ -684,10 +968,30 @@ func (m *Model) returnToPalette() tea.Cmd {
 	return nil
 }
 
-func (m Model) executeCommand(id string) (tea.Model, tea.Cmd) {
-	recency.RecordCommand(id)
+// executeCommand runs command id, refusing it where its requirements are
+// not met. A command asked for before the env is known waits for it.
+func (m Model) executeCommand(id string, args map[string]string) (tea.Model, tea.Cmd) {
+	if m.env == nil {
+		m.pendingRun = &messages.ExecuteCommandMsg{ID: id, Args: args}
+		return m, nil
+	}
+	if cmd, ok := command.FindCommand(id); ok {
+		if err := cmd.Check(*m.env); err != nil {
+			return m, func() tea.Msg { return messages.CommandDoneMsg{Err: err} }
+		}
+		if len(cmd.Params) > 0 && args == nil {
+			m.paletteActive = true
+			m.palette.Reset()
+			m.palette.StartForm(id)
+			return m, nil
+		}
+	}
+	frecency.Record(frecency.KindCommand, id)
 	m.paletteReturn = true
 
+	if updated, cmd, handled := m.execParamCommand(id, args); handled {
+		return updated, cmd
+	}
 	if updated, cmd, handled := m.execSessionCommand(id); handled {
 		return updated, cmd
 	}
@@ -706,9 +1010,60 @@ func (m Model) executeCommand(id string) (tea.Model, tea.Cmd) {
 	if updated, cmd, handled := m.execViewCommand(id); handled {
 		return updated, cmd
 	}
+	if updated, cmd, handled := m.execRepoCommand(id); handled {
+		return updated, cmd
+	}
+	if updated, cmd, handled := m.execPluginCommand(id); handled {
+		return updated, cmd
+	}
+	if updated, cmd, handled := m.execMacroCommand(id, args); handled {
+		return updated, cmd
+	}
 	return m, nil
 }
 
+func commandCmd(run func() error) tea.Cmd {
+	return func() tea.Msg {
+		return messages.CommandDoneMsg{Err: run()}
+	}
+}
+
+// execParamCommand runs the commands that take parameters, with args
+// already answered in the palette or given with `kitmux run --arg`.
+func (m Model) execParamCommand(id string, args map[string]string) (tea.Model, tea.Cmd, bool) {
+	switch id {
+	case "kill_session", "wt_remove":
+		if args["confirm"] != command.Yes {
+			cmd := m.returnToPalette()
+			return m, cmd, true
+		}
+	}
+	switch id {
+	case "kill_session":
+		session := args["session"]
+		return m, commandCmd(func() error { return tmux.KillSession(session) }), true
+	case "rename_session":
+		session, name := args["session"], args["name"]
+		return m, commandCmd(func() error { return tmux.RenameSession(session, name) }), true
+	case "wt_switch":
+		return m, func() tea.Msg { return messages.SwitchWorktreeMsg{Branch: args["branch"]} }, true
+	case "wt_create":
+		return m, func() tea.Msg { return messages.CreateWorktreeMsg{Branch: args["branch"]} }, true
+	case "wt_remove":
+		branch := args["branch"]
+		return m, func() tea.Msg {
+			return messages.RemoveWorktreeMsg{Branch: branch, Source: "palette"}
+		}, true
+	case "wt_open":
+		return m, m.macroStepCmd(config.MacroStep{Command: id, Args: args}), true
+	case "launch_agent":
+		return m, func() tea.Msg {
+			return messages.LaunchAgentMsg{AgentID: args["agent"], ModeID: args["mode"], Target: "pane"}
+		}, true
+	}
+	return m, nil, false
+}
+
 func (m Model) execSessionCommand(id string) (tea.Model, tea.Cmd, bool) {
 	switch id {
 	case "switch_session":
@@ -719,31 +1074,20 @@ func (m Model) execSessionCommand(id string) (tea.Model, tea.Cmd, bool) {
 		return m, openWorkspacesCmd(false), true
 	case "add_workspace":
 		return m, openWorkspacesCmd(true), true
-	case "kill_session":
-		return m.sessionsWithPendingRune('d'), m.sessions.Reload(), true
 	case "kill_current_session":
 		return m, killCurrentSessionCmd(), true
-	case "rename_session":
-		return m.sessionsWithPendingRune('r'), m.sessions.Reload(), true
 	}
 	return m, nil, false
 }
 
 func (m Model) execWorktreeCommand(id string) (tea.Model, tea.Cmd, bool) {
 	switch id {
-	case "wt_switch":
-		m.view = viewWorktrees
-		return m, m.worktreeView.Init(), true
-	case "wt_create":
-		return m.worktreesWithInjectedRune('n')
 	case "wt_create_describe":
 		return m.worktreesWithInjectedRune('N')
-	case "wt_remove":
-		return m.worktreesWithInjectedRune('d')
 	case "wt_merge":
-		return m, popupCmd("wt merge", "80%", "80%"), true
+		return m, popupCmd(worktree.Current().MergeCommand(), "80%", "80%"), true
 	case "wt_commit":
-		return m, popupCmd("wt step commit", "80%", "80%"), true
+		return m, commitCmd(), true
 	}
 	return m, nil, false
 }
@@ -762,6 +1106,8 @@ func (m Model) execAgentCommand(id string) (tea.Model, tea.Cmd, bool) {
 		return m, launchAgentCmd("opencode"), true
 	case "agent_ab":
 		return m, func() tea.Msg { return messages.OpenAgentABMsg{Source: "palette"} }, true
+	case "open_sidepanel":
+		return m, m.macroStepCmd(config.MacroStep{Command: id}), true
 	}
 	return m, nil, false
 }
@@ -793,22 +1139,97 @@ func (m Model) execViewCommand(id string) (tea.Model, tea.Cmd, bool) {
 		return m, m.worktreeView.Init(), true
 	case "view_agents":
 		m.view = viewAgents
-		return m, nil, true
+		return m, m.agentsView.Init(), true
 	case "view_sidepanel":
 		m.view = viewSidepanel
 		return m, m.sidepanelView.Init(), true
 	case "view_threads":
 		m.view = viewThreads
 		return m, m.threadsView.Init(), true
+	case "view_diff":
+		return m, func() tea.Msg { return messages.OpenDiffMsg{} }, true
+	case "view_search":
+		m.view = viewSearch
+		m.searchView = searchview.New()
+		m.searchView.SetSize(m.width, m.height-1)
+		return m, m.searchView.Init(), true
 	}
 	return m, nil, false
 }
 
-func (m Model) sessionsWithPendingRune(r rune) Model {
-	m.view = viewSessions
-	km := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}}
-	m.pendingKey = &km
-	return m
+// execRepoCommand runs a command from the repository's .kitmux.toml in a
+// popup rooted at the repository.
+func (m Model) execRepoCommand(id string) (tea.Model, tea.Cmd, bool) {
+	name, ok := strings.CutPrefix(id, command.RepoCommandPrefix)
+	if !ok {
+		return m, nil, false
+	}
+	repo := config.CurrentRepo()
+	for _, c := range repo.Commands {
+		if c.ID == name {
+			return m, popupCmd("cd "+shellQuote(repo.Root)+" && "+c.Run, c.Width, c.Height), true
+		}
+	}
+	return m, nil, true
+}
+
+// execMacroCommand runs a macro from the repository's .kitmux.toml and
+// reports how far it got.
+func (m Model) execMacroCommand(id string, args map[string]string) (tea.Model, tea.Cmd, bool) {
+	name, ok := strings.CutPrefix(id, command.MacroCommandPrefix)
+	if !ok {
+		return m, nil, false
+	}
+	mc, ok := macro.Find(name)
+	if !ok {
+		err := fmt.Errorf("macro %s is not defined in %s", name, config.RepoFile)
+		return m, func() tea.Msg { return messages.CommandDoneMsg{Err: err} }, true
+	}
+	ops := m.macroOps()
+	return m, func() tea.Msg {
+		report, err := macro.Run(mc, args, ops)
+		return messages.CommandDoneMsg{Message: report.String(), Err: err, Exit: true}
+	}, true
+}
+
+// macroStepCmd runs a command that is implemented as a macro step.
+func (m Model) macroStepCmd(step config.MacroStep) tea.Cmd {
+	ops := m.macroOps()
+	return func() tea.Msg {
+		return messages.CommandDoneMsg{Err: macro.RunStep(step, ops), Exit: true}
+	}
+}
+
+// macroOps checks macro steps against the env the model already knows.
+func (m Model) macroOps() macro.Ops {
+	ops := macro.DefaultOps()
+	if m.env != nil {
+		env := *m.env
+		ops.Env = func() command.Env { return env }
+	}
+	return ops
+}
+
+// execPluginCommand runs a plugin's command: one with a provider opens its
+// list, one without is sent to the plugin to run.
+func (m Model) execPluginCommand(id string) (tea.Model, tea.Cmd, bool) {
+	name, cmdID, ok := command.ParsePluginCommandID(id)
+	if !ok {
+		return m, nil, false
+	}
+	p, ok := plugin.Find(name)
+	c, found := p.Command(cmdID)
+	if !ok || !found {
+		err := fmt.Errorf("plugin command %s is not available", id)
+		return m, func() tea.Msg { return messages.CommandDoneMsg{Err: err} }, true
+	}
+	if c.Provider == "" {
+		return m, pluginlistview.RunCmd(p, c.ID), true
+	}
+	m.view = viewPluginList
+	m.pluginList = pluginlistview.New(p, c.Provider)
+	m.pluginList.SetSize(m.width, m.height-1)
+	return m, m.pluginList.Init(), true
 }
 
 func (m Model) worktreesWithInjectedRune(r rune) (tea.Model, tea.Cmd, bool) {
@@ -834,7 +1255,7 @@ func popupCmd(command, width, height string) tea.Cmd {
 
 func launchAgentCmd(agentID string) tea.Cmd {
 	return func() tea.Msg {
-		return messages.LaunchAgentMsg{AgentID: agentID, ModeID: "default", Target: "pane"}
+		return messages.LaunchAgentMsg{AgentID: agentID, ModeID: config.AgentMode(agentID), Target: "pane"}
 	}
 }
 
@@ -911,7 +1332,8 @@ func (m Model) launchAgent(msg messages.LaunchAgentMsg) (tea.Model, tea.Cmd) {
 	if !ok {
 		return m, tea.Quit
 	}
-	_ = agentlaunch.LaunchCurrent(a, mode, agentlaunch.Target(msg.Target), appAgentLaunchOps())
+	frecency.Record(frecency.KindAgent, a.ID)
+	_ = agentlaunch.LaunchCurrent(a, mode, agentlaunch.Target(msg.Target), tmux.Default())
 	return m, tea.Quit
 }
 
@@ -925,15 +1347,13 @@ func (m Model) launchSidepanelAgent(msg messages.LaunchSidepanelAgentMsg) tea.Cm
 		if !ok {
 			return messages.SidepanelCommandDoneMsg{}
 		}
-		err := agentlaunch.LaunchSidepanelWindow(a, mode, msg.Dir, appAgentLaunchOps())
+		frecency.Record(frecency.KindAgent, a.ID)
+		frecency.Record(frecency.KindDir, msg.Dir)
+		err := agentlaunch.LaunchSidepanelWindow(a, mode, msg.Dir, tmux.Default())
 		return messages.SidepanelCommandDoneMsg{Err: err}
 	}
 }
 
-func appAgentLaunchOps() agentlaunch.Ops {
-	return agentLaunchOps
-}
-
 func (m Model) launchAgentAB(msg messages.LaunchAgentABMsg) (tea.Model, tea.Cmd) {
 	prompt := strings.TrimSpace(msg.Prompt)
 	if prompt == "" {
@@ -973,22 +1393,51 @@ func (m Model) launchAgentAB(msg messages.LaunchAgentABMsg) (tea.Model, tea.Cmd)
 		return m, nil
 	}
 
-	codexPath, claudePath, err := worktree.PrepareABWorktrees(currentPath, config.ABBaseBranch())
+	ab, err := worktree.PrepareABWorktrees(currentPath, config.ABBaseBranch())
 	if err != nil {
 		_ = tmux.DisplayMessage(fmt.Sprintf("agent_ab worktree error: %v", err))
 		return m, nil
 	}
 
-	paneID, err := tmux.NewWindowInDir("A/B", codexPath, codexCmd)
+	// Provision the new worktrees before the agents start in them; both
+	// panes share the current session, so each gets its env exported.
+	env := make(map[string]map[string]string, len(ab.Created))
+	for _, path := range ab.Created {
+		vars, err := worktreesetup.Provision(tmux.Default(), path)
+		if err != nil {
+			_ = tmux.DisplayMessage(fmt.Sprintf("agent_ab setup error: %v", err))
+			return m, nil
+		}
+		env[path] = vars
+	}
+
+	paneID, err := tmux.NewWindowInDir("A/B", ab.CodexPath, worktreesetup.Export(env[ab.CodexPath], codexCmd))
 	if err != nil {
 		_ = tmux.DisplayMessage(fmt.Sprintf("agent_ab new-window error: %v", err))
 		return m, nil
 	}
-	if _, err := tmux.SplitWindowInDir(paneID, claudePath, claudeCmd); err != nil {
+	if _, err := tmux.SplitWindowInDir(paneID, ab.ClaudePath, worktreesetup.Export(env[ab.ClaudePath], claudeCmd)); err != nil {
 		_ = tmux.DisplayMessage(fmt.Sprintf("agent_ab split-window error: %v", err))
 		return m, nil
 	}
 	_ = tmux.SelectLayout(paneID, "even-horizontal")
-
 	return m, tea.Quit
 }
+
+// setupWorktreesCmd streams the repo's worktree setup steps for freshly
+// created worktrees in a popup, or in the sidepanel pane when running as
+// one. It returns nil when there is nothing to provision.
+func (m Model) setupWorktreesCmd(session string, paths ...string) tea.Cmd {
+	if len(paths) == 0 || !worktreesetup.HasSteps(paths[0]) {
+		return nil
+	}
+	command := worktreesetup.Command(session, paths...)
+	if m.mode == ModeSidepanel {
+		return func() tea.Msg { return messages.RunPaneCommandMsg{Command: command} }
+	}
+	return popupCmd(command, "80%", "80%")
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
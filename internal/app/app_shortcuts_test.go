@@ -1,9 +1,20 @@
 package app
 
 import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/command"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
+	"github.com/miltonparedes/kitmux/internal/views/palette"
 )
 
 func appKeyMsg(s string) tea.KeyMsg {
@@ -109,3 +120,226 @@ func TestHandleKeyMsgEscInWorkspacesEditingDoesNotQuit(t *testing.T) {
 		t.Fatal("expected no app-level command (no tea.Quit) when workspaces is editing")
 	}
 }
+
+func TestHandleKeyMsgFallsThroughForDiffView(t *testing.T) {
+	m := New(ModeSessions)
+	m.view = viewDiff
+
+	for _, key := range []string{"q", "w", "a", "esc"} {
+		if _, _, handled := m.handleKeyMsg(appKeyMsg(key)); handled {
+			t.Fatalf("expected app-level %s to fall through for diff view", key)
+		}
+	}
+}
+
+func TestCloseDiffReturnsToOpeningView(t *testing.T) {
+	m := New(ModeSessions)
+	m.view = viewWorkspaces
+
+	updated, _, _ := m.handleOpenDiff(messages.OpenDiffMsg{Dir: t.TempDir()})
+	opened := updated.(Model)
+	if opened.view != viewDiff {
+		t.Fatalf("expected diff view, got %d", opened.view)
+	}
+	closed, cmd, _ := opened.handleCloseDiff()
+	if cmd != nil || closed.(Model).view != viewWorkspaces {
+		t.Fatalf("expected to return to workspaces, got %d", closed.(Model).view)
+	}
+
+	diffMode := New(ModeDiff)
+	if _, cmd, _ := diffMode.handleCloseDiff(); cmd == nil {
+		t.Fatal("expected closing diff mode to quit")
+	}
+}
+
+func TestHandleKeyMsgSearchViewKeepsLettersForQuery(t *testing.T) {
+	m := New(ModeSearch)
+
+	for _, key := range []string{"q", "w", "a"} {
+		updated, _, handled := m.handleKeyMsg(appKeyMsg(key))
+		if handled {
+			t.Fatalf("expected %q to reach the search query", key)
+		}
+		if updated.view != viewSearch {
+			t.Fatalf("expected to remain on search view after %q, got %d", key, updated.view)
+		}
+	}
+
+	_, cmd, handled := m.handleKeyMsg(appKeyMsg("esc"))
+	if !handled || cmd == nil {
+		t.Fatal("expected esc to quit search mode")
+	}
+}
+
+// everywhere meets every command requirement.
+var everywhere = command.Env{
+	GitRepo: true, Worktree: true, Branch: "feat/x", SSH: true, Thread: true,
+	HasBinary: func(string) bool { return true },
+}
+
+func TestExecuteCommandAsksForMissingParams(t *testing.T) {
+	m := New(ModeSessions, WithEnv(everywhere))
+
+	updated, _ := m.executeCommand("wt_create", nil)
+	got := updated.(Model)
+	if !got.paletteActive || !got.palette.InForm() {
+		t.Fatal("expected the palette to ask for wt_create's branch")
+	}
+}
+
+func TestExecuteCommandWithArgsRunsDirectly(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := New(ModeRun, WithEnv(everywhere))
+
+	_, cmd := m.executeCommand("wt_create", map[string]string{"branch": "feat/x"})
+	if cmd == nil {
+		t.Fatal("expected a command")
+	}
+	if msg, ok := cmd().(messages.CreateWorktreeMsg); !ok || msg.Branch != "feat/x" {
+		t.Fatalf("msg = %#v, want CreateWorktreeMsg for feat/x", cmd())
+	}
+
+	_, cmd = m.executeCommand("kill_session", map[string]string{"session": "api", "confirm": "no"})
+	if cmd == nil {
+		t.Fatal("expected run mode to quit")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Fatalf("declined kill_session should quit without killing, got %#v", cmd())
+	}
+}
+
+func TestExecuteCommandRefusesUnavailableCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tmuxtest.Install(t)
+	m := New(ModeRun, WithEnv(command.Env{}))
+
+	_, cmd := m.executeCommand("wt_create", map[string]string{"branch": "feat/x"})
+	msg, ok := cmd().(messages.CommandDoneMsg)
+	if !ok || msg.Err == nil || msg.Err.Error() != "wt_create is not available here: not in a git repository" {
+		t.Fatalf("msg = %#v, want wt_create refused", cmd())
+	}
+	updated, _ := m.Update(msg)
+	if updated.(Model).Err() == nil {
+		t.Fatal("a refused run should fail the CLI")
+	}
+}
+
+func TestExecuteCommandWaitsForEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := New(ModeRun)
+
+	updated, cmd := m.executeCommand("wt_create", map[string]string{"branch": "feat/x"})
+	if cmd != nil {
+		t.Fatalf("cmd = %#v, want nothing to run before the env is known", cmd())
+	}
+	_, cmd = updated.(Model).Update(palette.EnvMsg{Env: everywhere})
+	if cmd == nil {
+		t.Fatal("expected the waiting command to run once the env arrived")
+	}
+	if msg, ok := cmd().(messages.CreateWorktreeMsg); !ok || msg.Branch != "feat/x" {
+		t.Fatalf("msg = %#v, want CreateWorktreeMsg for feat/x", cmd())
+	}
+}
+
+func TestRunModeKeepsCommandError(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tmuxtest.Install(t)
+	m := New(ModeRun, WithEnv(everywhere))
+
+	_, cmd := m.executeCommand("wt_remove", map[string]string{"branch": "feat/x", "confirm": "yes"})
+	if msg, ok := cmd().(messages.RemoveWorktreeMsg); !ok || msg.Branch != "feat/x" || msg.Source != "palette" {
+		t.Fatalf("msg = %#v, want RemoveWorktreeMsg from the palette", cmd())
+	}
+
+	updated, cmd := m.Update(messages.CommandDoneMsg{Err: errors.New("boom")})
+	if cmd == nil {
+		t.Fatal("expected run mode to quit")
+	}
+	if err := updated.(Model).Err(); err == nil || err.Error() != "boom" {
+		t.Fatalf("Err() = %v, want boom", err)
+	}
+}
+
+func TestPluginListEscReturnsToPalette(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("KITMUX_PLUGINS", "off")
+	m := New(ModeSessions, WithEnv(everywhere))
+
+	updated, cmd := m.executeCommand("plugin:gh:prs", nil)
+	if cmd == nil {
+		t.Fatal("expected a missing plugin to be reported")
+	}
+	if msg, ok := cmd().(messages.CommandDoneMsg); !ok || msg.Err == nil {
+		t.Fatalf("msg = %#v, want CommandDoneMsg with an error", cmd())
+	}
+
+	m = updated.(Model)
+	m.view = viewPluginList
+	m, _, handled := m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	if handled || m.view != viewPluginList {
+		t.Fatal("letters should go to the plugin list filter")
+	}
+	m, _, _ = m.handleKeyMsg(tea.KeyMsg{Type: tea.KeyEsc})
+	if !m.paletteActive {
+		t.Fatal("esc should return to the palette")
+	}
+}
+
+func TestWorktreeSessionPrefersSessionAtWorktree(t *testing.T) {
+	srv := tmuxtest.Install(t)
+	srv.Attach(srv.AddSession("main", "/repo"))
+
+	if got := worktreeSession([]string{"/repo.feat"}); got != "" {
+		t.Fatalf("session = %q, want none rather than the current session", got)
+	}
+	srv.AddSession("repo-feat", "/repo.feat")
+	if got := worktreeSession([]string{"/repo.feat"}); got != "repo-feat" {
+		t.Fatalf("session = %q, want repo-feat rooted at the worktree", got)
+	}
+}
+
+func TestLaunchAgentABProvisionsWorktreesBeforeAgents(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("KITMUX_AB_BASE_BRANCH", "main")
+	repo := filepath.Join(t.TempDir(), "app")
+	if err := os.MkdirAll(repo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	toml := "[[worktree.setup]]\nenv = { PORT = \"3001\" }\n"
+	if err := os.WriteFile(filepath.Join(repo, ".kitmux.toml"), []byte(toml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"add", ".kitmux.toml"},
+		{"-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	srv := tmuxtest.Install(t)
+	srv.Attach(srv.AddSession("app", repo))
+
+	m := New(ModeSessions, WithEnv(everywhere))
+	if _, cmd := m.launchAgentAB(messages.LaunchAgentABMsg{Prompt: "fix it"}); cmd == nil {
+		t.Fatalf("launch failed: %q", srv.Messages())
+	}
+
+	var order []string
+	for _, c := range srv.Calls() {
+		switch {
+		case strings.HasPrefix(c, "display-popup"):
+			order = append(order, "popup")
+		case strings.HasPrefix(c, "new-window"), strings.HasPrefix(c, "split-window"):
+			if !strings.Contains(c, "export PORT='3001'; ") {
+				t.Fatalf("%q should start the agent with the setup env", c)
+			}
+			order = append(order, strings.Fields(c)[0])
+		}
+	}
+	want := []string{"popup", "popup", "new-window", "split-window"}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+}
@@ -21,25 +21,55 @@ type SwitchWindowMsg struct {
 // TogglePaletteMsg toggles the command palette.
 type TogglePaletteMsg struct{}
 
-// ExecuteCommandMsg runs a palette command.
+// ExecuteCommandMsg runs a palette command. Args holds the values of the
+// command's parameters; nil asks for them first.
 type ExecuteCommandMsg struct {
-	ID string
+	ID   string
+	Args map[string]string
+}
+
+// PluginsLoadedMsg reports that the plugins answered their handshake, so
+// their commands can be listed.
+type PluginsLoadedMsg struct{}
+
+// CommandDoneMsg reports the end of a command that ran in the background.
+// Err, or else Message, is shown in tmux before returning to the palette.
+// Exit quits instead when the command succeeded, for commands that moved
+// the client elsewhere.
+type CommandDoneMsg struct {
+	Message string
+	Err     error
+	Exit    bool
 }
 
 // ReloadSessionsMsg signals that sessions should be reloaded.
 type ReloadSessionsMsg struct{}
 
+// TmuxChangedMsg reports that tmux announced session, window or pane changes
+// over the control-mode connection. Views reload instead of waiting for
+// their next poll.
+type TmuxChangedMsg struct {
+	Events []string
+}
+
+// WorktreeStatsChangedMsg reports that the workspace watcher refreshed the
+// cached stats of one worktree.
+type WorktreeStatsChangedMsg struct {
+	WorkspacePath string
+	WorktreePath  string
+}
+
 // SessionCursorMsg notifies that the session cursor changed (for auto-loading windows).
 type SessionCursorMsg struct {
 	SessionName string
 }
 
-// SwitchWorktreeMsg exits kitmux and runs wt switch to the given branch.
+// SwitchWorktreeMsg exits kitmux and switches to the given branch's worktree.
 type SwitchWorktreeMsg struct {
 	Branch string
 }
 
-// CreateWorktreeMsg exits kitmux and runs wt switch --create for a new branch.
+// CreateWorktreeMsg exits kitmux and creates a worktree for a new branch.
 type CreateWorktreeMsg struct {
 	Branch string
 }
@@ -47,6 +77,7 @@ type CreateWorktreeMsg struct {
 // RemoveWorktreeMsg runs wt remove for a branch, then reloads.
 type RemoveWorktreeMsg struct {
 	Branch string
+	Source string // "palette" or "worktrees"
 }
 
 // ReloadWorktreesMsg signals that worktrees should be reloaded.
@@ -124,3 +155,25 @@ type OpenLocalEditorMsg struct {
 type OpenWorkspacesMsg struct {
 	AddMode bool
 }
+
+// OpenDiffMsg opens the diff viewer for the worktree containing Dir. An
+// empty Dir uses the current pane's directory.
+type OpenDiffMsg struct {
+	Dir string
+}
+
+// CloseDiffMsg leaves the diff viewer.
+type CloseDiffMsg struct{}
+
+// OpenCommitMsg opens the commit view for the worktree containing Dir,
+// which drafts a message for the staged changes. An empty Dir uses the
+// current pane's directory.
+type OpenCommitMsg struct {
+	Dir string
+}
+
+// CloseCommitMsg leaves the commit view. Committed is set when a commit
+// was made.
+type CloseCommitMsg struct {
+	Committed bool
+}
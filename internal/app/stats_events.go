@@ -0,0 +1,41 @@
+package app
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+// watchWorktreeStats waits for the next stats refresh from the workspace
+// watcher. It returns nil when the watcher is off.
+func watchWorktreeStats() tea.Cmd {
+	w := wsdata.ActiveWatcher()
+	if w == nil {
+		return nil
+	}
+	changes := w.Changes()
+	return func() tea.Msg {
+		change, ok := <-changes
+		if !ok {
+			return nil
+		}
+		return messages.WorktreeStatsChangedMsg{
+			WorkspacePath: change.WorkspacePath,
+			WorktreePath:  change.WorktreePath,
+		}
+	}
+}
+
+// handleWorktreeStatsChanged forwards a refreshed worktree to the views
+// that show worktree stats and keeps watching.
+func (m Model) handleWorktreeStatsChanged(msg messages.WorktreeStatsChangedMsg) (tea.Model, tea.Cmd, bool) {
+	var reload tea.Cmd
+	switch m.view {
+	case viewWorkspaces:
+		reload = m.workspacesView.StatsChanged(msg.WorkspacePath)
+	case viewSidepanel:
+		reload = m.sidepanelView.WorktreeChanged(msg.WorktreePath)
+	}
+	return m, tea.Batch(reload, watchWorktreeStats()), true
+}
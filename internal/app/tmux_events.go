@@ -0,0 +1,88 @@
+package app
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+)
+
+// tmuxEventSettle coalesces bursts (e.g. a new session announces itself as
+// sessions-changed plus window-add) into a single reload.
+const tmuxEventSettle = 75 * time.Millisecond
+
+// reloadEvents lists the control-mode notifications that change what the
+// views show. Layout and client focus notifications are ignored.
+var reloadEvents = map[string]bool{
+	"sessions-changed":        true,
+	"session-renamed":         true,
+	"session-window-changed":  true,
+	"window-add":              true,
+	"window-close":            true,
+	"window-renamed":          true,
+	"unlinked-window-add":     true,
+	"unlinked-window-close":   true,
+	"unlinked-window-renamed": true,
+	"subscription-changed":    true,
+}
+
+// watchTmuxEvents waits for the next relevant notification on the active
+// control connection. It returns nil when kitmux is running on exec.
+func watchTmuxEvents() tea.Cmd {
+	c := tmux.ActiveControl()
+	if c == nil {
+		return nil
+	}
+	events := c.Events()
+	return func() tea.Msg {
+		return nextTmuxChange(events, tmuxEventSettle)
+	}
+}
+
+func nextTmuxChange(events <-chan tmux.Event, settle time.Duration) tea.Msg {
+	var names []string
+	for ev := range events {
+		if reloadEvents[ev.Name] {
+			names = append(names, ev.Name)
+			break
+		}
+	}
+	if len(names) == 0 {
+		return nil // connection closed; views keep polling
+	}
+	timer := time.NewTimer(settle)
+	defer timer.Stop()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return messages.TmuxChangedMsg{Events: names}
+			}
+			if reloadEvents[ev.Name] {
+				names = append(names, ev.Name)
+			}
+		case <-timer.C:
+			return messages.TmuxChangedMsg{Events: names}
+		}
+	}
+}
+
+// handleTmuxChanged reloads the active view and keeps watching.
+func (m Model) handleTmuxChanged() (tea.Model, tea.Cmd, bool) {
+	var reload tea.Cmd
+	switch m.view {
+	case viewSessions:
+		if !m.sessions.IsEditing() {
+			reload = m.sessions.Reload()
+		}
+	case viewWindows:
+		reload = m.windows.Reload()
+	case viewSidepanel:
+		reload = m.sidepanelView.Reload()
+	case viewThreads:
+		reload = m.threadsView.Reload()
+	}
+	return m, tea.Batch(reload, watchTmuxEvents()), true
+}
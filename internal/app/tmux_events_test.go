@@ -0,0 +1,49 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+)
+
+func TestNextTmuxChangeCoalescesBurst(t *testing.T) {
+	events := make(chan tmux.Event, 8)
+	events <- tmux.Event{Name: "session-changed"} // the control client's own attach
+	events <- tmux.Event{Name: "sessions-changed"}
+	events <- tmux.Event{Name: "layout-change"}
+	events <- tmux.Event{Name: "window-add", Args: []string{"@4"}}
+
+	msg := nextTmuxChange(events, 10*time.Millisecond)
+	changed, ok := msg.(messages.TmuxChangedMsg)
+	if !ok {
+		t.Fatalf("msg = %#v", msg)
+	}
+	want := []string{"sessions-changed", "window-add"}
+	if !reflect.DeepEqual(changed.Events, want) {
+		t.Fatalf("events = %#v, want %#v", changed.Events, want)
+	}
+}
+
+func TestNextTmuxChangeStopsWhenConnectionCloses(t *testing.T) {
+	events := make(chan tmux.Event, 1)
+	events <- tmux.Event{Name: "layout-change"}
+	close(events)
+
+	if msg := nextTmuxChange(events, time.Millisecond); msg != nil {
+		t.Fatalf("expected nil after close, got %#v", msg)
+	}
+}
+
+func TestTmuxChangedReloadsActiveView(t *testing.T) {
+	m := New(ModeThreads)
+	updated, cmd := m.Update(messages.TmuxChangedMsg{Events: []string{"sessions-changed"}})
+	if _, ok := updated.(Model); !ok {
+		t.Fatalf("unexpected model %T", updated)
+	}
+	if cmd == nil {
+		t.Fatal("expected a reload command for the threads view")
+	}
+}
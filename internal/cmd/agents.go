@@ -16,10 +16,7 @@ import (
 	"github.com/miltonparedes/kitmux/internal/tmux"
 )
 
-var (
-	agentThreadOps       = agentthread.DefaultOps
-	installLaunchHooksFn = installLaunchHooks
-)
+var installLaunchHooksFn = installLaunchHooks
 
 func addAgentCommands(parent *cobra.Command) {
 	for _, agent := range agents.DefaultAgents() {
@@ -59,7 +56,7 @@ func agentCmd(agent agents.Agent) *cobra.Command {
 					ModeID:  mode.ID,
 					Dir:     dir,
 					Name:    name,
-				}, agentThreadOps())
+				}, tmux.Default())
 			}
 			return execShell(agent.ID, agent.FullCommand(mode), dir)
 		},
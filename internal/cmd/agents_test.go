@@ -5,39 +5,19 @@ import (
 	"testing"
 
 	"github.com/miltonparedes/kitmux/internal/agents"
-	"github.com/miltonparedes/kitmux/internal/agentthread"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
 )
 
 func TestHeadlessAgentCreatesAndAttachesUniqueThread(t *testing.T) {
-	originalOps := agentThreadOps
+	t.Setenv("TMUX", "/tmp/tmux-test,1,0")
 	originalInstall := installLaunchHooksFn
 	t.Cleanup(func() {
-		agentThreadOps = originalOps
 		installLaunchHooksFn = originalInstall
 	})
 
-	existing := map[string]bool{"droid-app": true}
+	srv := tmuxtest.Install(t)
+	srv.AddSession("droid-app", "/tmp/app")
 	var calls []string
-	agentThreadOps = func() agentthread.Ops {
-		return agentthread.Ops{
-			HasSession: func(name string) bool {
-				return existing[name]
-			},
-			NewSessionWithCommand: func(name, dir, _ string) (string, error) {
-				calls = append(calls, "new:"+name+":"+dir)
-				existing[name] = true
-				return "%2", nil
-			},
-			SetSessionOption: func(_, _, _ string) error { return nil },
-			SetWindowOption:  func(_, _, _ string) error { return nil },
-			SetPaneTitle:     func(_, _ string) error { return nil },
-			SetHook:          func(_, _, _ string) error { return nil },
-			Attach: func(name string) error {
-				calls = append(calls, "attach:"+name)
-				return nil
-			},
-		}
-	}
 	installLaunchHooksFn = func(agentID string) error {
 		calls = append(calls, "install:"+agentID)
 		return nil
@@ -52,8 +32,16 @@ func TestHeadlessAgentCreatesAndAttachesUniqueThread(t *testing.T) {
 	if err := cmd.Execute(); err != nil {
 		t.Fatalf("Execute() error = %v", err)
 	}
-	want := []string{"install:droid", "new:droid-app-2:/tmp/app", "attach:droid-app-2"}
-	if !reflect.DeepEqual(calls, want) {
+	if want := []string{"install:droid"}; !reflect.DeepEqual(calls, want) {
 		t.Fatalf("calls = %#v, want %#v", calls, want)
 	}
+	if got := srv.SessionNames(); !reflect.DeepEqual(got, []string{"droid-app", "droid-app-2"}) {
+		t.Fatalf("sessions = %#v", got)
+	}
+	if got := srv.PanePath("droid-app-2:0.0"); got != "/tmp/app" {
+		t.Fatalf("new thread dir = %q, want /tmp/app", got)
+	}
+	if current, _ := srv.CurrentSession(); current != "droid-app-2" {
+		t.Fatalf("current session = %q, want droid-app-2", current)
+	}
 }
@@ -2,19 +2,33 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 
-	"github.com/miltonparedes/kitmux/internal/views/palette"
+	"github.com/miltonparedes/kitmux/internal/command"
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/plugin"
 )
 
 func addCommandsCommand(parent *cobra.Command) {
-	parent.AddCommand(&cobra.Command{
+	var available bool
+	cmd := &cobra.Command{
 		Use:   "commands",
 		Short: "List all available command IDs",
 		Run: func(_ *cobra.Command, _ []string) {
+			if dir, err := os.Getwd(); err == nil {
+				if _, err := config.LoadRepo(dir); err != nil {
+					fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+				}
+			}
+			plugin.Load()
+			cmds := command.Commands()
+			if available {
+				cmds = command.Available(cmds, command.DetectEnv())
+			}
 			var lastCat string
-			for _, c := range palette.DefaultCommands() {
+			for _, c := range cmds {
 				if c.Category != lastCat {
 					if lastCat != "" {
 						fmt.Println()
@@ -22,8 +36,15 @@ func addCommandsCommand(parent *cobra.Command) {
 					fmt.Printf("  %s:\n", c.Category)
 					lastCat = c.Category
 				}
-				fmt.Printf("    %-24s %s\n", c.ID, c.Description)
+				desc := c.Description
+				if len(c.Params) > 0 {
+					desc += " (--arg " + c.ParamNames() + ")"
+				}
+				fmt.Printf("    %-24s %s\n", c.ID, desc)
 			}
 		},
-	})
+	}
+	cmd.Flags().BoolVar(&available, "available", false,
+		"only list commands that can run here, as the palette would")
+	parent.AddCommand(cmd)
 }
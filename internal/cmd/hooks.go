@@ -5,8 +5,11 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/miltonparedes/kitmux/internal/activity"
 	"github.com/miltonparedes/kitmux/internal/agenthooks"
 	"github.com/miltonparedes/kitmux/internal/agenttrack"
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/tmux"
 )
 
 func addHookCommand(parent *cobra.Command) {
@@ -26,7 +29,7 @@ func addHookCommand(parent *cobra.Command) {
 			return agenthooks.RunStateEvent(agenthooks.StateEvent{
 				State: state,
 				Bell:  bell,
-			}, cmd.OutOrStdout(), agenthooks.DefaultStateOps())
+			}, cmd.OutOrStdout(), tmux.Default())
 		},
 	}
 	agentStateCmd.Flags().StringVar(&state, "state", "", "agent state: idle, working, input")
@@ -49,7 +52,7 @@ func addHookCommand(parent *cobra.Command) {
 				Detail:    detail,
 				Bell:      bell,
 				StdinJSON: stdinJSON,
-			}, cmd.InOrStdin(), cmd.OutOrStdout(), agenthooks.DefaultStateOps())
+			}, cmd.InOrStdin(), cmd.OutOrStdout(), tmux.Default())
 		},
 	}
 	agentEventCmd.Flags().StringVar(&agent, "agent", "", "agent id")
@@ -83,6 +86,7 @@ func addHookCommand(parent *cobra.Command) {
 	hookCmd.AddCommand(agentSpinnerCmd)
 
 	hookCmd.AddCommand(agentRegisterCommand())
+	hookCmd.AddCommand(focusCommand())
 
 	parent.AddCommand(hookCmd)
 }
@@ -117,3 +121,23 @@ func agentRegisterCommand() *cobra.Command {
 	cmd.Flags().StringVar(&thread, "thread", "", "tmux thread marker")
 	return cmd
 }
+
+func focusCommand() *cobra.Command {
+	var client string
+	var path string
+	cmd := &cobra.Command{
+		Use:    "focus",
+		Short:  "Record the session a tmux client focuses for kitmux report",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if client == "" || !config.ActivityTracking() {
+				return nil
+			}
+			return activity.RecordFocus(client, path)
+		},
+	}
+	cmd.Flags().StringVar(&client, "client", "", "tmux client tty")
+	cmd.Flags().StringVar(&path, "path", "", "session path; empty when the client detached or lost focus")
+	return cmd
+}
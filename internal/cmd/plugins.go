@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/miltonparedes/kitmux/internal/command"
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/plugin"
+)
+
+func addPluginsCommand(parent *cobra.Command) {
+	parent.AddCommand(&cobra.Command{
+		Use:   "plugins",
+		Short: "List discovered kitmux-<name> plugins and what they provide",
+		Run: func(_ *cobra.Command, _ []string) {
+			if !config.Plugins() {
+				fmt.Println("plugins are off; set KITMUX_PLUGINS=on")
+				return
+			}
+			plugins := plugin.Load()
+			if len(plugins) == 0 {
+				fmt.Println("no plugins found")
+				return
+			}
+			for i, p := range plugins {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("  %s  %s\n", p.Title, p.Path)
+				if p.Err != nil {
+					fmt.Printf("    error: %v\n", p.Err)
+					continue
+				}
+				for _, c := range p.Commands {
+					desc := c.Title
+					if c.Provider != "" {
+						desc += " (list: " + c.Provider + ")"
+					}
+					fmt.Printf("    %-32s %s\n", command.PluginCommandID(p.Name, c.ID), desc)
+				}
+				for _, pr := range p.Providers {
+					fmt.Printf("    provider %-23s %s\n", pr.ID, pr.Title)
+				}
+			}
+		},
+	})
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/sessionprune"
+)
+
+var sessionPruneOps = sessionprune.DefaultOps
+
+func sessionsPruneCmd() *cobra.Command {
+	var (
+		idle  string
+		yes   bool
+		every time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Kill sessions idle longer than a threshold",
+		Long: "List sessions idle longer than --idle. Attached sessions, sessions with a " +
+			"working or waiting agent, and sessions whose worktree has uncommitted " +
+			"changes are kept. Nothing is killed without --yes; killed sessions are " +
+			"logged to ~/.config/kitmux/prune.log.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			threshold, err := sessionprune.ParseIdle(idle)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if every <= 0 {
+				return pruneOnce(out, threshold, yes, sessionPruneOps())
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return pruneEvery(ctx, out, threshold, yes, every, sessionPruneOps())
+		},
+	}
+	cmd.Flags().StringVar(&idle, "idle", config.PruneIdle(), "idle threshold, e.g. 12h or 3d")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "kill the listed sessions instead of a dry run")
+	cmd.Flags().DurationVar(&every, "every", 0, "repeat the prune on this interval until interrupted")
+	return cmd
+}
+
+func pruneEvery(ctx context.Context, out io.Writer, threshold time.Duration, yes bool, every time.Duration, ops sessionprune.Ops) error {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		if err := pruneOnce(out, threshold, yes, ops); err != nil {
+			_, _ = fmt.Fprintf(out, "prune: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func pruneOnce(out io.Writer, threshold time.Duration, yes bool, ops sessionprune.Ops) error {
+	candidates, err := sessionprune.Plan(threshold, ops)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		_, _ = fmt.Fprintf(out, "No sessions idle for %s.\n", sessionprune.FormatIdle(threshold))
+		return nil
+	}
+
+	prunable := 0
+	for _, c := range candidates {
+		if c.Skip != "" {
+			_, _ = fmt.Fprintf(out, "  keep  %-28s %5s  (%s)\n", c.Session.Name, sessionprune.FormatIdle(c.Idle), c.Skip)
+			continue
+		}
+		prunable++
+		_, _ = fmt.Fprintf(out, "  kill  %-28s %5s  %s\n", c.Session.Name, sessionprune.FormatIdle(c.Idle), c.Session.Path)
+	}
+	if !yes {
+		_, _ = fmt.Fprintf(out, "Dry run: %d session(s) would be killed. Re-run with --yes to prune.\n", prunable)
+		return nil
+	}
+	if prunable == 0 {
+		return nil
+	}
+
+	res, err := sessionprune.Prune(candidates, ops)
+	for _, f := range res.Failed {
+		_, _ = fmt.Fprintf(out, "failed: %s\n", f)
+	}
+	_, _ = fmt.Fprintf(out, "Killed %d session(s).\n", len(res.Killed))
+	return err
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/sessionprune"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+func stubPruneOps(t *testing.T) *tmuxtest.Server {
+	t.Helper()
+	original := sessionPruneOps
+	t.Cleanup(func() { sessionPruneOps = original })
+
+	now := time.Unix(1_700_000_000, 0)
+	logPath := filepath.Join(t.TempDir(), "prune.log")
+	srv := tmuxtest.New()
+	srv.AddSession("stale", "/tmp/stale")
+	srv.AddSession("here", "/tmp/here")
+	srv.Attach("here")
+	srv.SetActivity("stale", now.Add(-96*time.Hour))
+	srv.SetActivity("here", now.Add(-96*time.Hour))
+	sessionPruneOps = func() sessionprune.Ops {
+		return sessionprune.Ops{
+			Tmux:              srv,
+			LoadWorktreeStats: func() (map[string]wsdata.WorktreeStat, error) { return nil, nil },
+			LogPath:           func() (string, error) { return logPath, nil },
+			Now:               func() time.Time { return now },
+		}
+	}
+	return srv
+}
+
+func TestSessionsPruneDefaultsToDryRun(t *testing.T) {
+	srv := stubPruneOps(t)
+	var out bytes.Buffer
+	cmd := sessionsPruneCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--idle", "3d"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := srv.SessionNames(); len(got) != 2 {
+		t.Fatalf("dry run killed sessions, left %v", got)
+	}
+	text := out.String()
+	if !strings.Contains(text, "kill  stale") || !strings.Contains(text, "(attached)") || !strings.Contains(text, "Dry run: 1") {
+		t.Fatalf("unexpected output:\n%s", text)
+	}
+}
+
+func TestSessionsPruneYesKills(t *testing.T) {
+	srv := stubPruneOps(t)
+	var out bytes.Buffer
+	cmd := sessionsPruneCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--idle", "3d", "--yes"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := strings.Join(srv.SessionNames(), ","); got != "here" {
+		t.Fatalf("sessions left = %v, want here", got)
+	}
+	if !strings.Contains(out.String(), "Killed 1 session(s).") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/miltonparedes/kitmux/internal/activity"
+	"github.com/miltonparedes/kitmux/internal/config"
+	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
+)
+
+func addReportCommand(parent *cobra.Command) {
+	var (
+		since  string
+		asJSON bool
+	)
+	command := &cobra.Command{
+		Use:   "report",
+		Short: "Show time spent per workspace, branch and agent",
+		Long: "Add up the time tmux clients had each workspace and branch focused, and " +
+			"the time agents spent working in them, over the last --since. Focus is " +
+			"recorded by the tmux hooks described in the README; agent time by the " +
+			"agent hooks (`kitmux threads install-agent-hooks`).",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			window, err := config.ParseDuration(since)
+			if err != nil {
+				return err
+			}
+			maxSpan, err := config.ParseDuration(config.ActivityMaxSpan())
+			if err != nil {
+				return err
+			}
+			until := time.Now()
+			from := until.Add(-window)
+			rows, err := activity.Load(from, until, maxSpan)
+			if err != nil {
+				return err
+			}
+			names := map[string]string{}
+			for _, w := range wsreg.LoadRegistry() {
+				names[w.Path] = w.Name
+			}
+			if asJSON {
+				return writeReportJSON(cmd.OutOrStdout(), rows, names, from, until)
+			}
+			writeReport(cmd.OutOrStdout(), rows, names, from)
+			return nil
+		},
+	}
+	command.Flags().StringVar(&since, "since", "7d", "report period, e.g. 8h, 1d or 7d")
+	command.Flags().BoolVar(&asJSON, "json", false, "print the report as JSON")
+	parent.AddCommand(command)
+}
+
+func workspaceLabel(path string, names map[string]string) string {
+	if name, ok := names[path]; ok {
+		return name
+	}
+	return filepath.Base(path)
+}
+
+func writeReport(out io.Writer, rows []activity.Row, names map[string]string, since time.Time) {
+	if len(rows) == 0 {
+		_, _ = fmt.Fprintf(out, "No activity since %s.\n", since.Format("Mon Jan 2 15:04"))
+		return
+	}
+	var focused, agents time.Duration
+	_, _ = fmt.Fprintf(out, "%-20s %-28s %8s  %s\n", "WORKSPACE", "BRANCH", "FOCUSED", "AGENTS")
+	for _, r := range rows {
+		branch := r.Branch
+		if branch == "" {
+			branch = "-"
+		}
+		_, _ = fmt.Fprintf(out, "%-20s %-28s %8s  %s\n",
+			workspaceLabel(r.Workspace, names), branch, formatSpan(r.Focused), formatAgents(r.Agents))
+		focused += r.Focused
+		agents += r.AgentTime()
+	}
+	_, _ = fmt.Fprintf(out, "Total: %s focused, %s of agent work since %s.\n",
+		formatSpan(focused), formatSpan(agents), since.Format("Mon Jan 2 15:04"))
+}
+
+func formatAgents(agents map[string]time.Duration) string {
+	ids := make([]string, 0, len(agents))
+	for id := range agents {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return agents[ids[i]] > agents[ids[j]] })
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, id+" "+formatSpan(agents[id]))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatSpan renders a duration as "3h05m" or "12m".
+func formatSpan(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d == 0 {
+		return "-"
+	}
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
+
+type reportJSON struct {
+	Since time.Time       `json:"since"`
+	Until time.Time       `json:"until"`
+	Rows  []reportRowJSON `json:"rows"`
+}
+
+type reportRowJSON struct {
+	Workspace      string           `json:"workspace"`
+	Path           string           `json:"path"`
+	Branch         string           `json:"branch"`
+	FocusedSeconds int64            `json:"focused_seconds"`
+	AgentSeconds   map[string]int64 `json:"agent_seconds"`
+}
+
+func writeReportJSON(out io.Writer, rows []activity.Row, names map[string]string, since, until time.Time) error {
+	report := reportJSON{Since: since, Until: until, Rows: []reportRowJSON{}}
+	for _, r := range rows {
+		row := reportRowJSON{
+			Workspace:      workspaceLabel(r.Workspace, names),
+			Path:           r.Workspace,
+			Branch:         r.Branch,
+			FocusedSeconds: int64(r.Focused / time.Second),
+			AgentSeconds:   map[string]int64{},
+		}
+		for id, d := range r.Agents {
+			row.AgentSeconds[id] = int64(d / time.Second)
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
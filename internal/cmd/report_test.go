@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/activity"
+)
+
+func TestWriteReportNamesWorkspacesAndTotals(t *testing.T) {
+	since := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	rows := []activity.Row{
+		{Workspace: "/src/kitmux", Branch: "main", Focused: 3*time.Hour + 5*time.Minute,
+			Agents: map[string]time.Duration{"claude": 65 * time.Minute, "codex": 20 * time.Minute}},
+		{Workspace: "/src/api", Branch: "feat/pay", Agents: map[string]time.Duration{"codex": 40 * time.Minute}},
+	}
+	names := map[string]string{"/src/kitmux": "kitmux"}
+
+	var out bytes.Buffer
+	writeReport(&out, rows, names, since)
+	got := out.String()
+	for _, want := range []string{
+		"kitmux", "3h05m", "claude 1h05m, codex 20m",
+		"api", "feat/pay", "codex 40m",
+		"Total: 3h05m focused, 2h05m of agent work since Mon Oct 12 09:00.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("report missing %q:\n%s", want, got)
+		}
+	}
+
+	out.Reset()
+	if err := writeReportJSON(&out, rows, names, since, since.Add(24*time.Hour)); err != nil {
+		t.Fatalf("writeReportJSON: %v", err)
+	}
+	var decoded reportJSON
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode: %v\n%s", err, out.String())
+	}
+	if len(decoded.Rows) != 2 || decoded.Rows[0].FocusedSeconds != 11100 || decoded.Rows[1].AgentSeconds["codex"] != 2400 {
+		t.Fatalf("json = %+v", decoded)
+	}
+}
+
+func TestFocusCommandRejectsPositionalArgs(t *testing.T) {
+	cmd := focusCommand()
+	cmd.SetArgs([]string{"/dev/ttys003"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err == nil {
+		t.Fatal("focus accepted a positional client tty; want --client only")
+	}
+}
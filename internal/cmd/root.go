@@ -6,8 +6,8 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/miltonparedes/kitmux/internal/app"
+	"github.com/miltonparedes/kitmux/internal/command"
 	"github.com/miltonparedes/kitmux/internal/config"
-	"github.com/miltonparedes/kitmux/internal/views/palette"
 )
 
 // Execute builds the CLI and runs the root command.
@@ -39,10 +39,12 @@ func newRootCmd() *cobra.Command {
 	addBridgeCommand(cmd)
 	addHookCommand(cmd)
 	addAgentCommands(cmd)
+	addReportCommand(cmd)
+	addPluginsCommand(cmd)
 
 	// Register each palette command ID as a hidden subcommand so that
 	// "kitmux switch_session" works as shorthand for "kitmux run switch_session".
-	for _, c := range palette.DefaultCommands() {
+	for _, c := range command.DefaultCommands() {
 		cmd.AddCommand(hiddenRunCmd(c.ID, c.Description))
 	}
 
@@ -2,34 +2,105 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/miltonparedes/kitmux/internal/app"
-	"github.com/miltonparedes/kitmux/internal/views/palette"
+	"github.com/miltonparedes/kitmux/internal/command"
+	"github.com/miltonparedes/kitmux/internal/frecency"
+	"github.com/miltonparedes/kitmux/internal/macro"
+	"github.com/miltonparedes/kitmux/internal/plugin"
 )
 
 func addRunCommand(parent *cobra.Command) {
-	parent.AddCommand(&cobra.Command{
+	var rawArgs []string
+	cmd := &cobra.Command{
 		Use:   "run <command-id>",
 		Short: "Run a palette command by ID",
-		Args:  cobra.ExactArgs(1),
+		Long: "Run a palette command by ID. Commands that take parameters ask for them\n" +
+			"in the palette unless every one is given with --arg name=value. A macro\n" +
+			"whose parameters are all given runs without the TUI and prints what it did.",
+		Args: cobra.ExactArgs(1),
 		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
 			if len(args) > 0 {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
+			plugin.Load()
 			var ids []string
-			for _, c := range palette.DefaultCommands() {
+			for _, c := range command.Commands() {
 				ids = append(ids, c.ID+"\t"+c.Description)
 			}
 			return ids, cobra.ShellCompDirectiveNoFileComp
 		},
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			id := args[0]
-			if !palette.IsValidCommand(id) {
+			plugin.Load()
+			c, ok := command.FindCommand(id)
+			if !ok {
 				return fmt.Errorf("unknown command %q", id)
 			}
-			return runTUI(app.ModeRun, app.WithRunCommand(id))
+			env := command.DetectEnv()
+			if err := c.Check(env); err != nil {
+				return err
+			}
+			var resolved map[string]string
+			if len(rawArgs) > 0 {
+				values, err := parseRunArgs(rawArgs)
+				if err != nil {
+					return err
+				}
+				if resolved, err = c.ResolveArgs(values); err != nil {
+					return err
+				}
+			}
+			if name, ok := strings.CutPrefix(id, command.MacroCommandPrefix); ok && (resolved != nil || len(c.Params) == 0) {
+				return runMacro(cmd.OutOrStdout(), name, resolved, env)
+			}
+			opts := []app.Option{app.WithRunCommand(id), app.WithEnv(env)}
+			if resolved != nil {
+				opts = append(opts, app.WithRunArgs(resolved))
+			}
+			return runTUI(app.ModeRun, opts...)
 		},
-	})
+	}
+	cmd.Flags().StringArrayVar(&rawArgs, "arg", nil,
+		"command parameter as name=value (repeatable)")
+	parent.AddCommand(cmd)
+}
+
+// runMacro runs a macro in the terminal, printing the steps it ran to out.
+// Its steps are checked against env.
+func runMacro(out io.Writer, name string, args map[string]string, env command.Env) error {
+	m, ok := macro.Find(name)
+	if !ok {
+		return fmt.Errorf("unknown macro %q", name)
+	}
+	frecency.Record(frecency.KindCommand, command.MacroCommandPrefix+name)
+	ops := macro.DefaultOps()
+	ops.Env = func() command.Env { return env }
+	report, err := macro.Run(m, args, ops)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, report)
+	return nil
+}
+
+// parseRunArgs reads repeated name=value flags.
+func parseRunArgs(raw []string) (map[string]string, error) {
+	values := make(map[string]string, len(raw))
+	for _, arg := range raw {
+		name, value, ok := strings.Cut(arg, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("--arg %q: want name=value", arg)
+		}
+		if _, dup := values[name]; dup {
+			return nil, fmt.Errorf("--arg %s given twice", name)
+		}
+		values[name] = value
+	}
+	return values, nil
 }
@@ -0,0 +1,19 @@
+package cmd
+
+import "testing"
+
+func TestParseRunArgs(t *testing.T) {
+	got, err := parseRunArgs([]string{"session=api", "name=api=v2"})
+	if err != nil {
+		t.Fatalf("parseRunArgs: %v", err)
+	}
+	if got["session"] != "api" || got["name"] != "api=v2" {
+		t.Fatalf("args = %v", got)
+	}
+
+	for _, bad := range [][]string{{"session"}, {"=api"}, {"a=1", "a=2"}} {
+		if _, err := parseRunArgs(bad); err == nil {
+			t.Errorf("parseRunArgs(%q) should fail", bad)
+		}
+	}
+}
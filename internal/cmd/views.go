@@ -6,9 +6,13 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 
+	"github.com/miltonparedes/kitmux/internal/activity"
 	"github.com/miltonparedes/kitmux/internal/agenthooks"
 	"github.com/miltonparedes/kitmux/internal/agentthread"
 	"github.com/miltonparedes/kitmux/internal/app"
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
 )
 
 type viewDef struct {
@@ -27,6 +31,9 @@ var views = []viewDef{
 	{"workspaces", []string{"o"}, "Workspace manager", app.ModeWorkspaces},
 	{"sidepanel", nil, "Agent sidepanel", app.ModeSidepanel},
 	{"threads", []string{"t"}, "Running agent threads", app.ModeThreads},
+	{"diff", []string{"d"}, "Diff viewer for the current worktree", app.ModeDiff},
+	{"commit", nil, "Commit staged changes with a generated message", app.ModeCommit},
+	{"search", []string{"f"}, "Search sessions, threads, windows, workspaces, worktrees and commands", app.ModeSearch},
 }
 
 func addViewCommands(parent *cobra.Command) {
@@ -39,6 +46,7 @@ func viewCmd(v viewDef) *cobra.Command {
 	var installHooks bool
 	var installAgentHooks bool
 	var showAllThreads bool
+	var workspaceTags []string
 	command := &cobra.Command{
 		Use:     v.name,
 		Aliases: v.aliases,
@@ -54,9 +62,23 @@ func viewCmd(v viewDef) *cobra.Command {
 			if v.mode == app.ModeThreads {
 				opts = append(opts, app.WithThreadsAll(showAllThreads))
 			}
+			if v.mode == app.ModeWorkspaces && len(workspaceTags) > 0 {
+				opts = append(opts, app.WithWorkspaceTags(workspaceTags))
+			}
 			return runTUI(v.mode, opts...)
 		},
 	}
+	if v.mode == app.ModeSessions {
+		command.AddCommand(sessionsPruneCmd())
+	}
+	if v.mode == app.ModeWorkspaces {
+		command.Flags().StringSliceVar(&workspaceTags, "tag", nil,
+			"only show workspaces with this tag (repeatable)")
+		command.AddCommand(workspacesExportCmd(), workspacesImportCmd(), workspacesStackCmd())
+	}
+	if v.mode == app.ModeWorktrees {
+		command.AddCommand(worktreesMergeCmd(), worktreesSetupCmd(), worktreesCleanupCmd())
+	}
 	if v.mode == app.ModeThreads {
 		command.Flags().BoolVar(&showAllThreads, "all", false,
 			"show agent threads from all directories")
@@ -84,11 +106,18 @@ func viewCmd(v viewDef) *cobra.Command {
 }
 
 func installAgentSupport(cmd *cobra.Command, _ []string) error {
-	count, err := agentthread.InstallAllSupport(agentthread.DefaultOps())
+	count, err := agentthread.InstallAllSupport(tmux.Default())
 	if err != nil {
 		return err
 	}
 	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Installed kitmux agent support for %d thread(s).\n", count)
+	if !config.ActivityTracking() {
+		return nil
+	}
+	if err := activity.InstallFocusHooks(tmux.Default()); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Installed tmux focus hooks for kitmux report.")
 	return nil
 }
 
@@ -104,14 +133,25 @@ func installAgentHookSupport(cmd *cobra.Command, _ []string) error {
 		}
 		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: %s (%s)\n", result.AgentID, status, result.Path)
 	}
-	if count, err := agentthread.InstallAllSupport(agentthread.DefaultOps()); err == nil {
+	if count, err := agentthread.InstallAllSupport(tmux.Default()); err == nil {
 		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "threads: synced support for %d thread(s)\n", count)
 	}
 	return nil
 }
 
 func runTUI(mode app.Mode, opts ...app.Option) error {
+	stop := tmux.UseBackend(config.TmuxBackend())
+	defer stop()
+	if config.WorkspaceWatch() {
+		defer wsdata.UseWatcher()()
+	}
 	p := tea.NewProgram(app.New(mode, opts...), tea.WithAltScreen(), tea.WithMouseCellMotion())
-	_, err := p.Run()
-	return err
+	final, err := p.Run()
+	if err != nil {
+		return err
+	}
+	if m, ok := final.(app.Model); ok {
+		return m.Err()
+	}
+	return nil
 }
@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/miltonparedes/kitmux/internal/stack"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+func workspacesExportCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "export [file]",
+		Short: "Write the workspace registry to a portable JSON file",
+		Long: "Export registered workspaces with their tags, pins, timestamps and " +
+			"archived worktrees, plus saved tag filter presets. Paths under $HOME " +
+			"are written as ~/… so the file can be imported on another machine. " +
+			"Writes to stdout when no file (or -) is given.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			home, _ := os.UserHomeDir()
+			export, err := wsreg.BuildExport(home)
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 || args[0] == "-" {
+				return wsreg.WriteExport(cmd.OutOrStdout(), export)
+			}
+			f, err := os.Create(args[0])
+			if err != nil {
+				return err
+			}
+			if err := wsreg.WriteExport(f, export); err != nil {
+				_ = f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Exported %d workspace(s) to %s.\n", len(export.Workspaces), args[0])
+			return nil
+		},
+	}
+}
+
+func workspacesImportCmd() *cobra.Command {
+	var dryRun bool
+	command := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Merge an exported workspace registry into this machine's",
+		Long: "Register the workspaces from a file written by `kitmux workspaces export`, " +
+			"expanding ~/ against this machine's $HOME. Workspaces already registered " +
+			"gain the imported tags, pin and archived worktrees. Paths that do not " +
+			"exist here are skipped and listed. Reads stdin when file is -.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var in io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer func() { _ = f.Close() }()
+				in = f
+			}
+			export, err := wsreg.ReadExport(in)
+			if err != nil {
+				return err
+			}
+			home, _ := os.UserHomeDir()
+			return importWorkspaces(cmd.OutOrStdout(), export, home, dryRun)
+		},
+	}
+	command.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "show what would be imported without writing")
+	return command
+}
+
+func importWorkspaces(out io.Writer, export wsreg.Export, home string, dryRun bool) error {
+	res, err := wsreg.Import(export, home, dryRun)
+	if err != nil {
+		return err
+	}
+	for _, path := range res.Added {
+		_, _ = fmt.Fprintf(out, "  add      %s\n", path)
+	}
+	for _, path := range res.Merged {
+		_, _ = fmt.Fprintf(out, "  merge    %s\n", path)
+	}
+	for _, path := range res.Missing {
+		_, _ = fmt.Fprintf(out, "  missing  %s\n", path)
+	}
+	for _, name := range res.Filters {
+		_, _ = fmt.Fprintf(out, "  preset   %s\n", name)
+	}
+	if dryRun {
+		_, _ = fmt.Fprintf(out, "Dry run: %d workspace(s) would be added, %d merged, %d missing. Re-run without --dry-run to import.\n",
+			len(res.Added), len(res.Merged), len(res.Missing))
+		return nil
+	}
+	_, _ = fmt.Fprintf(out, "Imported %d workspace(s), merged %d, skipped %d missing.\n",
+		len(res.Added), len(res.Merged), len(res.Missing))
+	return nil
+}
+
+var stackOps = stack.DefaultOps
+
+func workspacesStackCmd() *cobra.Command {
+	var branch string
+	command := &cobra.Command{
+		Use:   "stack",
+		Short: "Open groups of workspaces together",
+		Long: "A stack is a named group of workspaces, e.g. a frontend, a backend and a " +
+			"shared proto repo, opened in one action on the same branch.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return listStacks(cmd.OutOrStdout(), branch)
+		},
+	}
+	command.Flags().StringVarP(&branch, "branch", "b", "", "summarize the worktrees on this branch instead of the main ones")
+	command.AddCommand(workspacesStackCreateCmd(), workspacesStackOpenCmd(), workspacesStackRemoveCmd())
+	return command
+}
+
+func workspacesStackCreateCmd() *cobra.Command {
+	var layout string
+	command := &cobra.Command{
+		Use:   "create <name> <workspace>...",
+		Short: "Create or replace a stack of registered workspaces",
+		Long: "Create a stack from registered workspaces, given by name or path, in the " +
+			"order they should open. Creating an existing stack replaces it.",
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paths, err := resolveWorkspaceArgs(args[1:], wsreg.LoadRegistry())
+			if err != nil {
+				return err
+			}
+			s := wsreg.Stack{Name: args[0], Layout: stack.NormalizeLayout(layout), Workspaces: paths}
+			if !wsreg.SaveStack(s) {
+				return fmt.Errorf("could not save stack %q", s.Name)
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Saved stack %s with %d workspace(s).\n", s.Name, len(paths))
+			return nil
+		},
+	}
+	command.Flags().StringVar(&layout, "layout", stack.LayoutSessions, "how the stack opens: sessions (one per repo) or windows (one session)")
+	return command
+}
+
+func workspacesStackOpenCmd() *cobra.Command {
+	var branch, layout string
+	command := &cobra.Command{
+		Use:   "open <name>",
+		Short: "Open every workspace of a stack",
+		Long: "Open the stack's workspaces with its layout, or --layout. With --branch every " +
+			"repo is put on that branch, creating its worktree when needed, and the " +
+			"sessions are named after it. Switches to the first session.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, ok := findStack(args[0])
+			if !ok {
+				return fmt.Errorf("no stack named %q", args[0])
+			}
+			if layout == "" {
+				layout = s.Layout
+			}
+			members, missing := stack.Members(s, wsreg.LoadRegistry())
+			for _, p := range missing {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "  missing  %s (no longer registered)\n", p)
+			}
+			res, err := stack.Open(s.Name, members, branch, layout, stackOps())
+			if err != nil {
+				return err
+			}
+			for _, m := range res.Members {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "  %-16s %-24s %s\n", m.Name, m.Session, m.Dir)
+			}
+			_ = tmux.SwitchClient(res.Session)
+			return nil
+		},
+	}
+	command.Flags().StringVarP(&branch, "branch", "b", "", "branch to put every repo on")
+	command.Flags().StringVar(&layout, "layout", "", "sessions or windows; defaults to the stack's layout")
+	return command
+}
+
+func workspacesStackRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "rm <name>",
+		Aliases: []string{"remove"},
+		Short:   "Delete a stack; its workspaces stay registered",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, ok := findStack(args[0]); !ok {
+				return fmt.Errorf("no stack named %q", args[0])
+			}
+			if !wsreg.DeleteStack(args[0]) {
+				return fmt.Errorf("could not delete stack %q", args[0])
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Deleted stack %s.\n", args[0])
+			return nil
+		},
+	}
+}
+
+func listStacks(out io.Writer, branch string) error {
+	stacks := wsreg.LoadStacks()
+	if len(stacks) == 0 {
+		_, _ = fmt.Fprintln(out, "No stacks. Create one with `kitmux workspaces stack create <name> <workspace>...`.")
+		return nil
+	}
+	cached, _ := wsdata.NewStatsService().LoadAllCached()
+	for _, s := range stacks {
+		_, _ = fmt.Fprintf(out, "%-16s %-8s %s\n", s.Name, stack.NormalizeLayout(s.Layout), stack.Summarize(s.Workspaces, branch, cached))
+	}
+	return nil
+}
+
+func findStack(name string) (wsreg.Stack, bool) {
+	for _, s := range wsreg.LoadStacks() {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return wsreg.Stack{}, false
+}
+
+// resolveWorkspaceArgs maps workspace names or paths to registered paths.
+func resolveWorkspaceArgs(args []string, registry []wsreg.Workspace) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		abs, _ := filepath.Abs(arg)
+		found := ""
+		for _, w := range registry {
+			if w.Name == arg || w.Path == arg || w.Path == abs {
+				found = w.Path
+				break
+			}
+		}
+		if found == "" {
+			return nil, fmt.Errorf("%q is not a registered workspace", arg)
+		}
+		paths = append(paths, found)
+	}
+	return paths, nil
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/sessionprune"
+	"github.com/miltonparedes/kitmux/internal/worktree"
+	"github.com/miltonparedes/kitmux/internal/worktreeprune"
+	"github.com/miltonparedes/kitmux/internal/worktreesetup"
+)
+
+var worktreePruneOps = worktreeprune.DefaultOps
+
+func worktreesMergeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "merge [target]",
+		Short: "Merge the current worktree into target and remove it",
+		Long: "Rebase the branch checked out in the current worktree onto target " +
+			"(the main worktree's branch by default), fast-forward target, then " +
+			"remove the worktree and its branch.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target := ""
+			if len(args) == 1 {
+				target = args[0]
+			}
+			if err := worktree.Merge("", target); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Merged.")
+			return nil
+		},
+	}
+}
+
+func worktreesSetupCmd() *cobra.Command {
+	var session string
+	var wait bool
+	command := &cobra.Command{
+		Use:   "setup [path...]",
+		Short: "Provision worktrees with the repo's setup steps",
+		Long: "Run the [[worktree.setup]] steps from the repo's .kitmux.toml in each " +
+			"worktree (the current directory by default): copy or symlink files from " +
+			"the main worktree, run setup commands and set the tmux session " +
+			"environment. A failed step is reported and the worktree is kept.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+			out := cmd.OutOrStdout()
+			var failed error
+			for _, path := range args {
+				if err := setupWorktree(path, session, cmd); err != nil {
+					_, _ = fmt.Fprintf(out, "setup failed: %v\nThe worktree was kept at %s.\n", err, path)
+					failed = err
+				}
+			}
+			if failed == nil {
+				_, _ = fmt.Fprintln(out, "Setup done.")
+			}
+			if wait {
+				_, _ = fmt.Fprint(out, "Press enter to close.")
+				_, _ = bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+			}
+			return failed
+		},
+	}
+	command.Flags().StringVar(&session, "session", "", "tmux session whose environment env steps set")
+	command.Flags().BoolVar(&wait, "wait", false, "wait for enter before exiting, for popups")
+	return command
+}
+
+func setupWorktree(path, session string, cmd *cobra.Command) error {
+	steps, err := worktreesetup.Steps(path)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		return nil
+	}
+	main, err := worktreesetup.MainWorktree(path)
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Setting up %s\n", abs)
+	return worktreesetup.Run(worktreesetup.Target{Main: main, Path: abs, Session: session}, steps, cmd.OutOrStdout())
+}
+
+func worktreesCleanupCmd() *cobra.Command {
+	var (
+		stale string
+		force bool
+		yes   bool
+	)
+	command := &cobra.Command{
+		Use:   "cleanup [workspace]",
+		Short: "Remove merged, gone and stale worktrees",
+		Long: "Classify every worktree of the workspace (the current repo by default) as " +
+			"merged into the main branch, gone upstream, without commits for --stale, " +
+			"and clean or dirty. Worktrees that are done are removed together with " +
+			"their tmux sessions. Dirty worktrees and worktrees with an attached " +
+			"session are kept unless --force is given for the dirty ones. Nothing is " +
+			"removed without --yes.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			staleAfter, err := sessionprune.ParseIdle(stale)
+			if err != nil {
+				return err
+			}
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			workspace, err := worktree.MainPath(dir)
+			if err != nil {
+				return err
+			}
+			opts := worktreeprune.Options{StaleAfter: staleAfter, Force: force}
+			return cleanupWorktrees(cmd.OutOrStdout(), workspace, opts, yes, worktreePruneOps())
+		},
+	}
+	command.Flags().StringVar(&stale, "stale", config.WorktreeStale(), "age without commits after which a worktree is stale, e.g. 14d")
+	command.Flags().BoolVar(&force, "force", false, "also remove dirty worktrees, discarding their changes")
+	command.Flags().BoolVarP(&yes, "yes", "y", false, "remove the listed worktrees instead of a dry run")
+	return command
+}
+
+func cleanupWorktrees(out io.Writer, workspace string, opts worktreeprune.Options, yes bool, ops worktreeprune.Ops) error {
+	candidates, err := worktreeprune.Plan(workspace, opts, ops)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		_, _ = fmt.Fprintf(out, "No linked worktrees in %s.\n", workspace)
+		return nil
+	}
+
+	var removable []worktreeprune.Candidate
+	sessions := 0
+	for _, c := range candidates {
+		action := "keep"
+		if c.Removable() {
+			action = "remove"
+			removable = append(removable, c)
+			sessions += len(c.Sessions)
+		}
+		line := fmt.Sprintf("  %-6s  %-28s %5s  %s", action, c.Worktree.Branch, sessionprune.FormatIdle(c.Age), c.Describe())
+		if len(c.Sessions) > 0 {
+			line += "  [" + strings.Join(c.Sessions, " ") + "]"
+		}
+		if c.Skip != "" && len(c.Reasons) > 0 {
+			line += "  (" + c.Skip + ")"
+		}
+		_, _ = fmt.Fprintln(out, line)
+	}
+	if !yes {
+		_, _ = fmt.Fprintf(out, "Dry run: %d worktree(s) would be removed and %d session(s) killed. Re-run with --yes to clean up.\n", len(removable), sessions)
+		return nil
+	}
+	if len(removable) == 0 {
+		return nil
+	}
+
+	res := worktreeprune.Apply(workspace, removable, ops)
+	for _, f := range res.Failed {
+		_, _ = fmt.Fprintf(out, "failed: %s\n", f)
+	}
+	_, _ = fmt.Fprintf(out, "Removed %d worktree(s), killed %d session(s).\n", len(res.Removed), len(res.Killed))
+	return nil
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+	"github.com/miltonparedes/kitmux/internal/worktreeprune"
+)
+
+// cleanupOps cleans up a fake workspace whose "done" worktree has the
+// app-done session rooted in it.
+func cleanupOps(removed *[]string) (worktreeprune.Ops, *tmuxtest.Server) {
+	now := time.Unix(1_700_000_000, 0)
+	srv := tmuxtest.New()
+	srv.AddSession("app-done", "/repos/app.done")
+	return worktreeprune.Ops{
+		Tmux: srv,
+		LoadStats: func(string) ([]wsdata.WorktreeStat, error) {
+			return []wsdata.WorktreeStat{
+				{WorktreePath: "/repos/app", Branch: "main", IsMain: true},
+				{WorktreePath: "/repos/app.done", Branch: "done", CommitTS: now.Add(-48 * time.Hour).Unix()},
+				{WorktreePath: "/repos/app.wip", Branch: "wip", CommitTS: now.Add(-48 * time.Hour).Unix(), Untracked: true},
+			}, nil
+		},
+		MergedBranches: func(string, string) (map[string]bool, error) {
+			return map[string]bool{"done": true, "wip": true}, nil
+		},
+		GoneBranches: func(string) (map[string]bool, error) { return nil, nil },
+		RemoveWorktree: func(_ string, wt wsdata.WorktreeStat, _ bool) error {
+			*removed = append(*removed, wt.Branch)
+			return nil
+		},
+		Forget: func(string, string) {},
+		Now:    func() time.Time { return now },
+	}, srv
+}
+
+func TestWorktreesCleanupDefaultsToDryRun(t *testing.T) {
+	var removed []string
+	var out bytes.Buffer
+	ops, srv := cleanupOps(&removed)
+	err := cleanupWorktrees(&out, "/repos/app", worktreeprune.Options{}, false, ops)
+	if err != nil {
+		t.Fatalf("cleanupWorktrees() error = %v", err)
+	}
+	if len(removed) != 0 || !srv.HasSession("app-done") {
+		t.Fatalf("dry run removed %v, sessions left %v", removed, srv.SessionNames())
+	}
+	text := out.String()
+	if !strings.Contains(text, "remove  done") || !strings.Contains(text, "keep    wip") ||
+		!strings.Contains(text, "(uncommitted changes)") || !strings.Contains(text, "Dry run: 1 worktree(s) would be removed and 1 session(s)") {
+		t.Fatalf("unexpected output:\n%s", text)
+	}
+}
+
+func TestWorktreesCleanupYesRemovesClean(t *testing.T) {
+	var removed []string
+	var out bytes.Buffer
+	ops, srv := cleanupOps(&removed)
+	err := cleanupWorktrees(&out, "/repos/app", worktreeprune.Options{}, true, ops)
+	if err != nil {
+		t.Fatalf("cleanupWorktrees() error = %v", err)
+	}
+	if strings.Join(removed, ",") != "done" || srv.HasSession("app-done") {
+		t.Fatalf("removed %v, sessions left %v", removed, srv.SessionNames())
+	}
+	if !strings.Contains(out.String(), "Removed 1 worktree(s), killed 1 session(s).") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
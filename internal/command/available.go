@@ -0,0 +1,196 @@
+package command
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/miltonparedes/kitmux/internal/openlocal"
+	"github.com/miltonparedes/kitmux/internal/plugin"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+type requirementKind int
+
+const (
+	reqGitRepo requirementKind = iota
+	reqWorktree
+	reqNotMain
+	reqSSH
+	reqThread
+	reqBinary
+	reqUnknown
+)
+
+// Requirement is something a command needs from where it runs. The palette
+// dims commands whose requirements are not met and shows why; running one
+// anyway fails with Check.
+type Requirement struct {
+	kind requirementKind
+	name string // the binary, or the unrecognized requirement
+}
+
+// Requirements commands can declare.
+var (
+	InGitRepo     = Requirement{kind: reqGitRepo}
+	InWorktree    = Requirement{kind: reqWorktree} // a linked worktree, not the main checkout
+	NotOnMain     = Requirement{kind: reqNotMain}
+	InSSH         = Requirement{kind: reqSSH}
+	InAgentThread = Requirement{kind: reqThread}
+)
+
+// Binary requires name to be on PATH.
+func Binary(name string) Requirement {
+	return Requirement{kind: reqBinary, name: name}
+}
+
+// ParseRequirement reads a requirement as written in .kitmux.toml or by a
+// plugin: git, worktree, not-main, ssh, thread or bin:<name>. Anything else
+// is never met, so a typo hides the command rather than running it in the
+// wrong place.
+func ParseRequirement(s string) Requirement {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "git":
+		return InGitRepo
+	case "worktree":
+		return InWorktree
+	case "not-main":
+		return NotOnMain
+	case "ssh":
+		return InSSH
+	case "thread":
+		return InAgentThread
+	}
+	if name, ok := strings.CutPrefix(s, "bin:"); ok && strings.TrimSpace(name) != "" {
+		return Binary(strings.TrimSpace(name))
+	}
+	return Requirement{kind: reqUnknown, name: s}
+}
+
+func parseRequirements(specs []string) []Requirement {
+	var reqs []Requirement
+	for _, s := range specs {
+		reqs = append(reqs, ParseRequirement(s))
+	}
+	return reqs
+}
+
+// Env describes where commands run.
+type Env struct {
+	GitRepo  bool
+	Worktree bool // in a linked worktree
+	Branch   string
+	SSH      bool
+	Thread   bool // in a kitmux agent thread
+	// HasBinary reports whether a binary is on PATH.
+	HasBinary func(name string) bool
+}
+
+// DetectEnv describes the current tmux pane, or the working directory
+// outside tmux.
+func DetectEnv() Env {
+	ctx := plugin.CurrentContext()
+	env := Env{
+		GitRepo:   ctx.Repo != "",
+		Branch:    ctx.Branch,
+		SSH:       openlocal.IsSSH(),
+		HasBinary: lookPath(),
+	}
+	if env.GitRepo {
+		root := wsdata.ResolveWorktreeRoot(ctx.Path)
+		env.Worktree = root != "" && filepath.Clean(root) != filepath.Clean(ctx.Repo)
+	}
+	if tc, err := tmux.CurrentThreadContext(); err == nil {
+		env.Thread = tc.Thread
+	}
+	return env
+}
+
+// lookPath checks PATH once per binary. The Env is shared with commands
+// running in the background, hence the lock.
+func lookPath() func(string) bool {
+	var mu sync.Mutex
+	found := map[string]bool{}
+	return func(name string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		ok, seen := found[name]
+		if !seen {
+			_, err := exec.LookPath(name)
+			ok = err == nil
+			found[name] = ok
+		}
+		return ok
+	}
+}
+
+// unmet returns why r is not met in env, or "".
+func (r Requirement) unmet(env Env) string {
+	switch r.kind {
+	case reqGitRepo:
+		if !env.GitRepo {
+			return "not in a git repository"
+		}
+	case reqWorktree:
+		if !env.Worktree {
+			return "not in a worktree"
+		}
+	case reqNotMain:
+		switch {
+		case env.Branch == "":
+			return "not on a branch"
+		case wsdata.IsMainBranch(env.Branch):
+			return "on " + env.Branch
+		}
+	case reqSSH:
+		if !env.SSH {
+			return "not over SSH"
+		}
+	case reqThread:
+		if !env.Thread {
+			return "not in an agent thread"
+		}
+	case reqBinary:
+		if env.HasBinary == nil || !env.HasBinary(r.name) {
+			return r.name + " not installed"
+		}
+	case reqUnknown:
+		return fmt.Sprintf("unknown requirement %q", r.name)
+	}
+	return ""
+}
+
+// Unavailable returns why c cannot run in env, or "" when it can.
+func (c Command) Unavailable(env Env) string {
+	for _, r := range c.Requires {
+		if reason := r.unmet(env); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// Check refuses c when it cannot run in env. Every way of running a
+// command goes through it: the palette, the search view, `kitmux run`,
+// `kitmux <id>` and macro steps.
+func (c Command) Check(env Env) error {
+	if reason := c.Unavailable(env); reason != "" {
+		return fmt.Errorf("%s is not available here: %s", c.ID, reason)
+	}
+	return nil
+}
+
+// Available returns the commands that can run in env, in order.
+func Available(cmds []Command, env Env) []Command {
+	var out []Command
+	for _, c := range cmds {
+		if c.Unavailable(env) == "" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package command
+
+import "testing"
+
+// everywhere meets every requirement.
+var everywhere = Env{
+	GitRepo: true, Worktree: true, Branch: "feat/x", SSH: true, Thread: true,
+	HasBinary: func(string) bool { return true },
+}
+
+func TestParseRequirement(t *testing.T) {
+	cases := map[string]Requirement{
+		"git":         InGitRepo,
+		" worktree ":  InWorktree,
+		"not-main":    NotOnMain,
+		"ssh":         InSSH,
+		"thread":      InAgentThread,
+		"bin:lazygit": Binary("lazygit"),
+		"bin:":        {kind: reqUnknown, name: "bin:"},
+		"gti":         {kind: reqUnknown, name: "gti"},
+	}
+	for in, want := range cases {
+		if got := ParseRequirement(in); got != want {
+			t.Errorf("ParseRequirement(%q) = %+v, want %+v", in, got, want)
+		}
+	}
+}
+
+func TestUnavailableGivesFirstUnmetReason(t *testing.T) {
+	has := func(names ...string) func(string) bool {
+		return func(name string) bool {
+			for _, n := range names {
+				if n == name {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	cases := []struct {
+		requires []Requirement
+		env      Env
+		want     string
+	}{
+		{nil, Env{}, ""},
+		{[]Requirement{InGitRepo}, Env{}, "not in a git repository"},
+		{[]Requirement{InGitRepo}, Env{GitRepo: true}, ""},
+		{[]Requirement{InWorktree, NotOnMain}, Env{GitRepo: true}, "not in a worktree"},
+		{[]Requirement{InWorktree, NotOnMain}, Env{Worktree: true}, "not on a branch"},
+		{[]Requirement{InWorktree, NotOnMain}, Env{Worktree: true, Branch: "master"}, "on master"},
+		{[]Requirement{InWorktree, NotOnMain}, Env{Worktree: true, Branch: "feat/x"}, ""},
+		{[]Requirement{InSSH}, Env{}, "not over SSH"},
+		{[]Requirement{InAgentThread}, Env{}, "not in an agent thread"},
+		{[]Requirement{Binary("codex"), Binary("claude")}, Env{HasBinary: has("codex")}, "claude not installed"},
+		{[]Requirement{Binary("codex")}, Env{HasBinary: has("codex")}, ""},
+		{[]Requirement{ParseRequirement("gti")}, everywhere, `unknown requirement "gti"`},
+	}
+	for _, c := range cases {
+		cmd := Command{ID: "x", Requires: c.requires}
+		if got := cmd.Unavailable(c.env); got != c.want {
+			t.Errorf("%+v in %+v: got %q, want %q", c.requires, c.env, got, c.want)
+		}
+	}
+}
+
+func TestAvailableKeepsOrder(t *testing.T) {
+	cmds := []Command{
+		{ID: "a"},
+		{ID: "b", Requires: []Requirement{InSSH}},
+		{ID: "c", Requires: []Requirement{InGitRepo}},
+	}
+	got := Available(cmds, Env{GitRepo: true})
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "c" {
+		t.Fatalf("Available = %+v", got)
+	}
+}
+
+func TestCheckRefusesUnavailableCommands(t *testing.T) {
+	merge, _ := FindCommand("wt_merge")
+	if err := merge.Check(everywhere); err != nil {
+		t.Fatalf("Check everywhere = %v", err)
+	}
+	err := merge.Check(Env{})
+	if err == nil || err.Error() != "wt_merge is not available here: not in a worktree" {
+		t.Fatalf("Check = %v", err)
+	}
+}
@@ -0,0 +1,373 @@
+// Package command is the registry of what kitmux can run: the built-in
+// commands, those of the current repository and of plugins, the parameters
+// they ask for and the requirements that decide where they are available.
+// The palette, the search view, `kitmux run` and macros all run commands
+// from here.
+package command
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/agents"
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/plugin"
+)
+
+// RepoCommandPrefix namespaces the commands defined in a repository's
+// .kitmux.toml so they cannot shadow built-in IDs.
+const RepoCommandPrefix = "repo:"
+
+// MacroCommandPrefix namespaces the macros defined in a repository's
+// .kitmux.toml.
+const MacroCommandPrefix = "macro:"
+
+// PluginCommandPrefix namespaces plugin commands as plugin:<name>:<id>.
+const PluginCommandPrefix = "plugin:"
+
+// loadPlugins returns the plugins loaded so far, without waiting for their
+// handshake; swapped in tests.
+var loadPlugins = plugin.Loaded
+
+// Command represents an executable command.
+type Command struct {
+	ID          string
+	Title       string
+	Description string
+	Category    string
+	Action      func() tea.Msg
+	// Params are asked for before the command runs; see Param.
+	Params []Param
+	// Requires lists what the command needs to be available; see
+	// Requirement.
+	Requires []Requirement
+}
+
+// IsValidCommand returns true if the given ID matches a registered command.
+func IsValidCommand(id string) bool {
+	_, ok := FindCommand(id)
+	return ok
+}
+
+// FindCommand returns a registered command by ID.
+func FindCommand(id string) (Command, bool) {
+	for _, cmd := range Commands() {
+		if cmd.ID == id {
+			return cmd, true
+		}
+	}
+	return Command{}, false
+}
+
+// Commands returns the built-in commands followed by the commands and
+// macros of the current repository and those of plugins.
+func Commands() []Command {
+	cmds := DefaultCommands()
+	for _, c := range config.RepoCommands() {
+		cmds = append(cmds, Command{
+			ID:          RepoCommandPrefix + c.ID,
+			Title:       c.Title,
+			Description: c.Description,
+			Category:    "Repo",
+			Requires:    parseRequirements(c.Requires),
+		})
+	}
+	for _, m := range config.RepoMacros() {
+		params := make([]Param, len(m.Params))
+		for i, name := range m.Params {
+			params[i] = Param{Name: name, Prompt: name, Kind: ParamText}
+		}
+		cmds = append(cmds, Command{
+			ID:          MacroCommandPrefix + m.ID,
+			Title:       m.Title,
+			Description: m.Description,
+			Category:    "Macro",
+			Params:      params,
+			Requires:    parseRequirements(m.Requires),
+		})
+	}
+	for _, p := range loadPlugins() {
+		for _, c := range p.Commands {
+			desc := c.Description
+			if desc == "" {
+				desc = "From the " + p.Title + " plugin"
+			}
+			cmds = append(cmds, Command{
+				ID:          PluginCommandID(p.Name, c.ID),
+				Title:       c.Title,
+				Description: desc,
+				Category:    "Plugin",
+				Requires:    parseRequirements(c.Requires),
+			})
+		}
+	}
+	return cmds
+}
+
+// agentBinary requires the binary that starts agent id.
+func agentBinary(id string) Requirement {
+	a, _ := agents.Find(id)
+	return Binary(a.Command)
+}
+
+// PluginCommandID is the palette ID of a plugin's command.
+func PluginCommandID(name, id string) string {
+	return PluginCommandPrefix + name + ":" + id
+}
+
+// ParsePluginCommandID splits a plugin command ID into the plugin name and
+// the plugin's own command id.
+func ParsePluginCommandID(id string) (name, command string, ok bool) {
+	rest, ok := strings.CutPrefix(id, PluginCommandPrefix)
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(rest, ":")
+}
+
+// DefaultCommands returns the built-in command registry.
+func DefaultCommands() []Command {
+	return []Command{
+		// Session
+		{
+			ID:          "switch_session",
+			Title:       "Switch Session",
+			Description: "Switch to a session (use the tree view)",
+			Category:    "Session",
+		},
+		{
+			ID:          "kill_session",
+			Title:       "Kill Session",
+			Description: "Kill a session",
+			Category:    "Session",
+			Params: []Param{
+				{Name: "session", Prompt: "Session", Kind: ParamChoice, Options: sessionOptions},
+				{Name: "confirm", Prompt: "Kill {session}?", Kind: ParamConfirm},
+			},
+		},
+		{
+			ID:          "kill_current_session",
+			Title:       "Kill Current Session",
+			Description: "Kill the current tmux session and switch to another",
+			Category:    "Session",
+		},
+		{
+			ID:          "rename_session",
+			Title:       "Rename Session",
+			Description: "Rename a session",
+			Category:    "Session",
+			Params: []Param{
+				{Name: "session", Prompt: "Session", Kind: ParamChoice, Options: sessionOptions},
+				{Name: "name", Prompt: "New name for {session}", Kind: ParamText},
+			},
+		},
+		{
+			ID:          "open_workspace",
+			Title:       "Open Workspace",
+			Description: "Open the workspaces dashboard",
+			Category:    "Session",
+		},
+		{
+			ID:          "add_workspace",
+			Title:       "Add Workspace",
+			Description: "Pick a discovered directory and add it as a workspace",
+			Category:    "Session",
+		},
+
+		// Worktree
+		{
+			ID:          "wt_switch",
+			Title:       "Switch Worktree",
+			Description: "Switch to a worktree branch",
+			Category:    "Worktree",
+			Requires:    []Requirement{InGitRepo},
+			Params: []Param{
+				{Name: "branch", Prompt: "Branch", Kind: ParamChoice, Options: branchOptions},
+			},
+		},
+		{
+			ID:          "wt_create",
+			Title:       "Create Worktree",
+			Description: "Create a new worktree branch",
+			Category:    "Worktree",
+			Requires:    []Requirement{InGitRepo},
+			Params: []Param{
+				{Name: "branch", Prompt: "New branch", Kind: ParamText},
+			},
+		},
+		{
+			ID:          "wt_open",
+			Title:       "Open Worktree",
+			Description: "Open a worktree's session, creating it if needed",
+			Category:    "Worktree",
+			Requires:    []Requirement{InGitRepo},
+			Params: []Param{
+				{Name: "branch", Prompt: "Branch", Kind: ParamChoice, Options: branchOptions},
+			},
+		},
+		{
+			ID:          "wt_create_describe",
+			Title:       "Create Worktree from Description",
+			Description: "Describe a task and auto-generate a branch name",
+			Category:    "Worktree",
+			Requires:    []Requirement{InGitRepo},
+		},
+		{
+			ID:          "wt_remove",
+			Title:       "Remove Worktree",
+			Description: "Remove a worktree",
+			Category:    "Worktree",
+			Requires:    []Requirement{InGitRepo},
+			Params: []Param{
+				{Name: "branch", Prompt: "Branch", Kind: ParamChoice, Options: removableBranchOptions},
+				{Name: "confirm", Prompt: "Remove the {branch} worktree?", Kind: ParamConfirm},
+			},
+		},
+		{
+			ID:          "wt_merge",
+			Title:       "Merge Worktree",
+			Description: "Merge a worktree branch into main",
+			Category:    "Worktree",
+			Requires:    []Requirement{InWorktree, NotOnMain},
+		},
+		{
+			ID:          "wt_commit",
+			Title:       "LLM Commit",
+			Description: "Generate a commit message with LLM",
+			Category:    "Worktree",
+			Requires:    []Requirement{InGitRepo},
+		},
+
+		// Agent
+		{
+			ID:          "launch_agent",
+			Title:       "Launch Agent",
+			Description: "Start an agent in a chosen mode in the current pane",
+			Category:    "Agent",
+			Params: []Param{
+				{Name: "agent", Prompt: "Agent", Kind: ParamChoice, Options: agentOptions},
+				{Name: "mode", Prompt: "Mode for {agent}", Kind: ParamChoice, Options: modeOptions},
+			},
+		},
+		{
+			ID:          "launch_droid",
+			Title:       "Launch Droid",
+			Description: "Start Droid in the current pane",
+			Category:    "Agent",
+			Requires:    []Requirement{agentBinary("droid")},
+		},
+		{
+			ID:          "launch_codex",
+			Title:       "Launch Codex CLI",
+			Description: "Start Codex CLI in the current pane",
+			Category:    "Agent",
+			Requires:    []Requirement{agentBinary("codex")},
+		},
+		{
+			ID:          "launch_cursor",
+			Title:       "Launch Cursor CLI",
+			Description: "Start Cursor CLI in the current pane",
+			Category:    "Agent",
+			Requires:    []Requirement{agentBinary("cursor")},
+		},
+		{
+			ID:          "launch_claude",
+			Title:       "Launch Claude Code",
+			Description: "Start Claude Code in the current pane",
+			Category:    "Agent",
+			Requires:    []Requirement{agentBinary("claude")},
+		},
+		{
+			ID:          "launch_opencode",
+			Title:       "Launch OpenCode",
+			Description: "Start OpenCode in the current pane",
+			Category:    "Agent",
+			Requires:    []Requirement{agentBinary("opencode")},
+		},
+		{
+			ID:          "open_sidepanel",
+			Title:       "Open Sidepanel",
+			Description: "Split the agent sidepanel next to the current pane",
+			Category:    "Agent",
+		},
+		{
+			ID:          "agent_ab",
+			Title:       "Launch A/B (Codex + Claude)",
+			Description: "Start Codex and Claude side-by-side in a new tmux window",
+			Category:    "Agent",
+			Requires:    []Requirement{InGitRepo, agentBinary("codex"), agentBinary("claude")},
+		},
+
+		// Editor
+		{
+			ID:          "open_local_editor",
+			Title:       "Open in Local Editor",
+			Description: "Open current session in your local editor",
+			Category:    "Editor",
+			Requires:    []Requirement{InSSH},
+		},
+
+		// Tools
+		{
+			ID:          "tool_lazygit",
+			Title:       "Lazygit",
+			Description: "Open lazygit in a popup",
+			Category:    "Tool",
+			Requires:    []Requirement{Binary("lazygit")},
+		},
+		{
+			ID:          "tool_lumen_diff",
+			Title:       "Lumen Diff",
+			Description: "Open lumen diff in a popup",
+			Category:    "Tool",
+			Requires:    []Requirement{InGitRepo, Binary("lumen")},
+		},
+
+		// View
+		{
+			ID:          "view_sessions",
+			Title:       "Sessions View",
+			Description: "Switch to sessions view",
+			Category:    "View",
+		},
+		{
+			ID:          "view_worktrees",
+			Title:       "Worktrees View",
+			Description: "Switch to worktrees view",
+			Category:    "View",
+			Requires:    []Requirement{InGitRepo},
+		},
+		{
+			ID:          "view_agents",
+			Title:       "Agents View",
+			Description: "Switch to agents view",
+			Category:    "View",
+		},
+		{
+			ID:          "view_sidepanel",
+			Title:       "Sidepanel",
+			Description: "Open the agent sidepanel",
+			Category:    "View",
+		},
+		{
+			ID:          "view_threads",
+			Title:       "Agent Threads",
+			Description: "Open the running agent thread list",
+			Category:    "View",
+		},
+		{
+			ID:          "view_diff",
+			Title:       "Diff View",
+			Description: "Stage, unstage and discard changes of the current worktree",
+			Category:    "View",
+			Requires:    []Requirement{InGitRepo},
+		},
+		{
+			ID:          "view_search",
+			Title:       "Search Everything",
+			Description: "Search sessions, threads, windows, workspaces, worktrees and commands",
+			Category:    "View",
+		},
+	}
+}
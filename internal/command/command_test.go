@@ -0,0 +1,85 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/plugin"
+)
+
+func TestIsValidCommand_Canonical(t *testing.T) {
+	if !IsValidCommand("open_workspace") {
+		t.Error("expected open_workspace to be a valid command")
+	}
+	if !IsValidCommand("switch_session") {
+		t.Error("expected switch_session to be a valid command")
+	}
+	if IsValidCommand("launch_gemini") {
+		t.Error("expected launch_gemini to be invalid")
+	}
+	if IsValidCommand("launch_aichat") {
+		t.Error("expected launch_aichat to be invalid")
+	}
+	if IsValidCommand("nope") {
+		t.Error("expected 'nope' to be invalid")
+	}
+}
+
+func TestDefaultCommands_HasUniqueIDs(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, c := range DefaultCommands() {
+		if seen[c.ID] {
+			t.Errorf("duplicate command ID %q", c.ID)
+		}
+		seen[c.ID] = true
+	}
+}
+
+func TestCommands_IncludesRepoCommands(t *testing.T) {
+	restore := config.UseRepo(config.Repo{Commands: []config.RepoCommand{
+		{ID: "test", Title: "Run Tests", Description: "make test", Run: "make test"},
+	}})
+	defer restore()
+
+	cmd, ok := FindCommand(RepoCommandPrefix + "test")
+	if !ok {
+		t.Fatal("expected repo command to be registered")
+	}
+	if cmd.Title != "Run Tests" || cmd.Category != "Repo" {
+		t.Fatalf("command = %+v", cmd)
+	}
+	if IsValidCommand("test") {
+		t.Fatal("repo commands must be namespaced")
+	}
+	all := Commands()
+	if all[len(all)-1].ID != RepoCommandPrefix+"test" {
+		t.Fatalf("expected repo commands after built-ins, got %q last", all[len(all)-1].ID)
+	}
+}
+
+func TestCommands_IncludesPluginCommandsAfterRepo(t *testing.T) {
+	restore := config.UseRepo(config.Repo{Commands: []config.RepoCommand{
+		{ID: "test", Title: "Run Tests", Run: "make test"},
+	}})
+	defer restore()
+	orig := loadPlugins
+	loadPlugins = func() []plugin.Plugin {
+		return []plugin.Plugin{{Name: "gh", Commands: []plugin.Command{
+			{ID: "prs", Title: "Pull Requests", Provider: "prs"},
+		}}}
+	}
+	t.Cleanup(func() { loadPlugins = orig })
+
+	all := Commands()
+	last := all[len(all)-1]
+	if last.ID != "plugin:gh:prs" || last.Title != "Pull Requests" || last.Category != "Plugin" {
+		t.Fatalf("last command = %+v, want the plugin command", last)
+	}
+	if all[len(all)-2].ID != RepoCommandPrefix+"test" {
+		t.Fatalf("expected repo command before plugin commands, got %q", all[len(all)-2].ID)
+	}
+	name, id, ok := ParsePluginCommandID(last.ID)
+	if !ok || name != "gh" || id != "prs" {
+		t.Fatalf("ParsePluginCommandID = %q, %q, %v", name, id, ok)
+	}
+}
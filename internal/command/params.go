@@ -0,0 +1,186 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miltonparedes/kitmux/internal/agents"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/worktree"
+)
+
+// ParamKind is the type of value a command parameter takes.
+type ParamKind int
+
+const (
+	ParamText    ParamKind = iota // free text
+	ParamChoice                   // one of the parameter's options
+	ParamConfirm                  // yes or no
+)
+
+// Confirm values. A confirm parameter set to anything but Yes cancels the
+// command.
+const (
+	Yes = "yes"
+	No  = "no"
+)
+
+// Param is a value a command asks for before it runs. The palette prompts
+// for each in order; `kitmux run --arg name=value` supplies them instead.
+type Param struct {
+	Name   string
+	Prompt string // may refer to earlier values as {name}
+	Kind   ParamKind
+	// Options lists the values of a choice, given the values chosen so
+	// far. An empty list accepts any value.
+	Options func(args map[string]string) []string
+}
+
+// Option sources; swapped in tests.
+var (
+	listSessions  = tmux.ListSessions
+	listWorktrees = worktree.List
+)
+
+func sessionOptions(map[string]string) []string {
+	sessions, err := listSessions()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, s := range tmux.NormalSessions(sessions) {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func branchOptions(map[string]string) []string {
+	wts, err := listWorktrees()
+	if err != nil {
+		return nil
+	}
+	var branches []string
+	for _, wt := range wts {
+		if wt.Branch != "" {
+			branches = append(branches, wt.Branch)
+		}
+	}
+	return branches
+}
+
+// removableBranchOptions lists the branches the worktrees view lets you
+// remove: everything except the main and the current worktree.
+func removableBranchOptions(map[string]string) []string {
+	wts, err := listWorktrees()
+	if err != nil {
+		return nil
+	}
+	var branches []string
+	for _, wt := range wts {
+		if wt.Branch != "" && !wt.IsMain && !wt.IsCurrent {
+			branches = append(branches, wt.Branch)
+		}
+	}
+	return branches
+}
+
+func agentOptions(map[string]string) []string {
+	var ids []string
+	for _, a := range agents.DefaultAgents() {
+		ids = append(ids, a.ID)
+	}
+	return ids
+}
+
+func modeOptions(args map[string]string) []string {
+	a, ok := agents.Find(args["agent"])
+	if !ok {
+		return nil
+	}
+	var ids []string
+	for _, mode := range a.Modes {
+		ids = append(ids, mode.ID)
+	}
+	return ids
+}
+
+// PromptFor expands {name} references in p's prompt with args.
+func PromptFor(p Param, args map[string]string) string {
+	prompt := p.Prompt
+	if prompt == "" {
+		prompt = p.Name
+	}
+	for name, value := range args {
+		prompt = strings.ReplaceAll(prompt, "{"+name+"}", value)
+	}
+	return prompt
+}
+
+// parseConfirm reads a confirm value as given on the command line.
+func parseConfirm(value string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "y", "yes", "true", "1":
+		return Yes, true
+	case "n", "no", "false", "0":
+		return No, true
+	}
+	return "", false
+}
+
+// ResolveArgs checks args against the command's parameters: each must be
+// given, a choice must be one of its options and a confirm is read as yes
+// or no. It returns the normalized values.
+func (c Command) ResolveArgs(args map[string]string) (map[string]string, error) {
+	known := make(map[string]bool, len(c.Params))
+	for _, p := range c.Params {
+		known[p.Name] = true
+	}
+	for name := range args {
+		if !known[name] {
+			if len(c.Params) == 0 {
+				return nil, fmt.Errorf("%s takes no arguments", c.ID)
+			}
+			return nil, fmt.Errorf("%s has no argument %q (want %s)", c.ID, name, c.ParamNames())
+		}
+	}
+
+	resolved := make(map[string]string, len(c.Params))
+	for _, p := range c.Params {
+		value, ok := args[p.Name]
+		if !ok || strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("%s: missing --arg %s=<value>", c.ID, p.Name)
+		}
+		switch p.Kind {
+		case ParamConfirm:
+			v, ok := parseConfirm(value)
+			if !ok {
+				return nil, fmt.Errorf("%s: %s must be yes or no, got %q", c.ID, p.Name, value)
+			}
+			value = v
+		case ParamChoice:
+			if options := p.Options(resolved); len(options) > 0 && !contains(options, value) {
+				return nil, fmt.Errorf("%s: %s %q is not one of %s", c.ID, p.Name, value, strings.Join(options, ", "))
+			}
+		}
+		resolved[p.Name] = value
+	}
+	return resolved, nil
+}
+
+// ParamNames lists the command's parameter names, comma separated.
+func (c Command) ParamNames() string {
+	names := make([]string, len(c.Params))
+	for i, p := range c.Params {
+		names[i] = p.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/worktree"
+)
+
+func stubSessions(t *testing.T, names ...string) {
+	t.Helper()
+	original := listSessions
+	listSessions = func() ([]tmux.Session, error) {
+		sessions := make([]tmux.Session, len(names))
+		for i, n := range names {
+			sessions[i] = tmux.Session{Name: n}
+		}
+		return sessions, nil
+	}
+	t.Cleanup(func() { listSessions = original })
+}
+
+func TestResolveArgsValidatesParams(t *testing.T) {
+	stubSessions(t, "api", "web")
+	kill, _ := FindCommand("kill_session")
+
+	got, err := kill.ResolveArgs(map[string]string{"session": "web", "confirm": "Y"})
+	if err != nil {
+		t.Fatalf("ResolveArgs: %v", err)
+	}
+	if got["session"] != "web" || got["confirm"] != Yes {
+		t.Fatalf("resolved = %v", got)
+	}
+
+	for _, tc := range []struct {
+		args map[string]string
+		want string
+	}{
+		{map[string]string{"session": "web"}, "missing --arg confirm"},
+		{map[string]string{"session": "nope", "confirm": "yes"}, "not one of api, web"},
+		{map[string]string{"session": "web", "confirm": "maybe"}, "must be yes or no"},
+		{map[string]string{"session": "web", "confirm": "yes", "force": "1"}, "no argument \"force\""},
+	} {
+		_, err := kill.ResolveArgs(tc.args)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("ResolveArgs(%v) = %v, want %q", tc.args, err, tc.want)
+		}
+	}
+
+	diff, _ := FindCommand("view_diff")
+	if _, err := diff.ResolveArgs(map[string]string{"x": "1"}); err == nil {
+		t.Error("commands without parameters should reject arguments")
+	}
+}
+
+func TestRemoveWorktreeSkipsMainAndCurrent(t *testing.T) {
+	original := listWorktrees
+	listWorktrees = func() ([]worktree.Worktree, error) {
+		return []worktree.Worktree{
+			{Branch: "main", IsMain: true},
+			{Branch: "feat/here", IsCurrent: true},
+			{Branch: "feat/done"},
+		}, nil
+	}
+	t.Cleanup(func() { listWorktrees = original })
+	remove, _ := FindCommand("wt_remove")
+
+	if got := remove.Params[0].Options(nil); len(got) != 1 || got[0] != "feat/done" {
+		t.Fatalf("options = %v, want only feat/done", got)
+	}
+	if _, err := remove.ResolveArgs(map[string]string{"branch": "main", "confirm": "y"}); err == nil {
+		t.Fatal("removing the main worktree should be rejected")
+	}
+}
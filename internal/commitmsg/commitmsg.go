@@ -0,0 +1,260 @@
+// Package commitmsg drafts commit messages for the staged changes of a
+// worktree with a configurable local command, and commits them.
+package commitmsg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/miltonparedes/kitmux/internal/agents"
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/worktree"
+)
+
+// ErrNothingStaged is returned when there is no staged change to describe
+// or commit.
+var ErrNothingStaged = errors.New("nothing staged")
+
+const (
+	// maxDiff caps the diff sent to the generator.
+	maxDiff = 48 << 10
+	timeout = 2 * time.Minute
+)
+
+// Generator drafts commit messages by running Command through sh in the
+// worktree. The command reads a prompt with the staged diff on stdin and
+// prints the message on stdout.
+type Generator struct {
+	Command      string
+	Conventional bool
+	// Prefixes are the repository's branch prefix overrides, used to read
+	// the commit type off the branch name.
+	Prefixes map[string]string
+}
+
+// ForDir resolves the generator configured for the repository containing
+// dir: commit.generator, else commit.agent run in its non-interactive mode.
+// ok is false when neither is set.
+func ForDir(dir string) (g Generator, ok bool) {
+	repo, _ := config.LoadRepo(dir)
+	return FromRepo(repo)
+}
+
+// FromRepo is ForDir for already loaded settings.
+func FromRepo(repo config.Repo) (g Generator, ok bool) {
+	g = Generator{
+		Command:      repo.CommitGenerator(),
+		Conventional: repo.CommitConventional(),
+		Prefixes:     repo.BranchPrefixes(),
+	}
+	if g.Command == "" {
+		if a, found := agents.Find(repo.CommitAgent()); found {
+			g.Command = a.Exec
+		}
+	}
+	return g, g.Command != ""
+}
+
+// Generate drafts a message for the changes staged in dir.
+func (g Generator) Generate(dir string) (string, error) {
+	if g.Command == "" {
+		return "", errors.New("no commit message generator configured")
+	}
+	stat, err := git(dir, "diff", "--cached", "--stat")
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(stat) == "" {
+		return "", ErrNothingStaged
+	}
+	diff, err := git(dir, "diff", "--cached")
+	if err != nil {
+		return "", err
+	}
+	branch, _ := git(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	branch = strings.TrimSpace(branch)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", g.Command)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(Prompt(stat, diff, g.Conventional))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", g.Command, firstLine(msg))
+		}
+		return "", fmt.Errorf("%s: %w", g.Command, err)
+	}
+	msg := Clean(string(out))
+	if msg == "" {
+		return "", fmt.Errorf("%s printed no message", g.Command)
+	}
+	if g.Conventional {
+		msg = Conventional(msg, Type(branch, msg, g.Prefixes))
+	}
+	return msg, nil
+}
+
+// Prompt is the instruction sent to the generator.
+func Prompt(stat, diff string, conventional bool) string {
+	var b strings.Builder
+	b.WriteString("Write a git commit message for the staged changes below.\n")
+	b.WriteString("Reply with the message only: a subject line of at most 72 characters in the imperative mood, ")
+	b.WriteString("then, if the change needs it, a blank line and a short body. No code fences, no commentary.\n")
+	if conventional {
+		b.WriteString("Use the conventional commit format \"type(scope): subject\" with type one of feat, fix, refactor, test, docs, chore.\n")
+	}
+	b.WriteString("\nFiles:\n")
+	b.WriteString(stat)
+	b.WriteString("\nDiff:\n")
+	if len(diff) > maxDiff {
+		b.WriteString(diff[:maxDiff])
+		b.WriteString("\n[diff truncated]\n")
+	} else {
+		b.WriteString(diff)
+	}
+	return b.String()
+}
+
+// Clean trims a generator's answer to a commit message: code fences and
+// surrounding blank lines are dropped, and the subject is separated from
+// the body by one blank line.
+func Clean(out string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(out, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 1 && lines[1] != "" {
+		lines = append([]string{lines[0], ""}, lines[1:]...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+var conventionalSubject = regexp.MustCompile(`^[a-z]+(\([^)]*\))?!?: `)
+
+// Type picks the conventional commit type: the intent of the branch
+// prefix (fix/…, feat/…, or a configured override), else the intent of
+// the message's leading verb, as worktree.GenerateBranchName detects it.
+// Work in progress maps to chore.
+func Type(branch, msg string, prefixes map[string]string) string {
+	intent := worktree.BranchIntent(branch, prefixes)
+	if intent == "" || intent == "wip" {
+		subject, _, _ := strings.Cut(msg, "\n")
+		intent, _ = worktree.Intent(subject)
+	}
+	if intent == "wip" {
+		return "chore"
+	}
+	return intent
+}
+
+// Conventional prefixes the subject with typ unless it already carries a
+// conventional type.
+func Conventional(msg, typ string) string {
+	subject, rest, hasBody := strings.Cut(msg, "\n")
+	if conventionalSubject.MatchString(subject) {
+		return msg
+	}
+	subject = typ + ": " + lowerFirst(subject)
+	if hasBody {
+		return subject + "\n" + rest
+	}
+	return subject
+}
+
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError || size == len(s) {
+		return strings.ToLower(s)
+	}
+	// Keep acronyms such as "API" intact.
+	if next, _ := utf8.DecodeRuneInString(s[size:]); unicode.IsUpper(next) {
+		return s
+	}
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+// Staged reports whether dir has staged changes.
+func Staged(dir string) (bool, error) {
+	err := exec.Command("git", "-C", dir, "diff", "--cached", "--quiet").Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, err
+}
+
+// StageAll stages every change in dir, like the commit commands of the
+// worktree backends do.
+func StageAll(dir string) error {
+	_, err := git(dir, "add", "-A")
+	return err
+}
+
+// Unstage empties the index of dir again, undoing StageAll.
+func Unstage(dir string) error {
+	_, err := git(dir, "reset", "-q")
+	return err
+}
+
+// Commit commits the staged changes of dir with msg.
+func Commit(dir, msg string) error {
+	if strings.TrimSpace(msg) == "" {
+		return errors.New("empty commit message")
+	}
+	staged, err := Staged(dir)
+	if err != nil {
+		return err
+	}
+	if !staged {
+		return ErrNothingStaged
+	}
+	cmd := exec.Command("git", "-C", dir, "commit", "-q", "-F", "-")
+	cmd.Stdin = strings.NewReader(msg)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if text := strings.TrimSpace(string(out)); text != "" {
+			return fmt.Errorf("git commit: %s", firstLine(text))
+		}
+		return fmt.Errorf("git commit: %w", err)
+	}
+	return nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], firstLine(msg))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,151 @@
+package commitmsg
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+)
+
+func gitRepo(t *testing.T, branch string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+		{"checkout", "-q", "-b", branch},
+	} {
+		runGit(t, dir, args...)
+	}
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func TestTypeFromBranchThenSubject(t *testing.T) {
+	t.Parallel()
+
+	prefixes := map[string]string{"fix": "bugfix"}
+	cases := []struct {
+		branch, msg, want string
+	}{
+		{"fix/login", "Add retry", "fix"},
+		{"bugfix/login", "Add retry", "fix"},
+		{"feat/avatars", "Tweak sizes", "feat"},
+		{"wip/spike", "Refactor the auth middleware", "refactor"},
+		{"main", "Add user avatars", "feat"},
+		{"main", "Bump deps", "chore"},
+	}
+	for _, c := range cases {
+		if got := Type(c.branch, c.msg, prefixes); got != c.want {
+			t.Errorf("Type(%q, %q) = %q, want %q", c.branch, c.msg, got, c.want)
+		}
+	}
+}
+
+func TestConventionalKeepsTypedSubjects(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"Add avatars\n\nBody.":   "feat: add avatars\n\nBody.",
+		"API keys rotate":        "feat: API keys rotate",
+		"fix(auth): handle nil":  "fix(auth): handle nil",
+		"refactor!: drop v1 api": "refactor!: drop v1 api",
+	}
+	for msg, want := range cases {
+		if got := Conventional(msg, "feat"); got != want {
+			t.Errorf("Conventional(%q) = %q, want %q", msg, got, want)
+		}
+	}
+}
+
+func TestCleanStripsFencesAndSeparatesBody(t *testing.T) {
+	t.Parallel()
+
+	got := Clean("\n```\nAdd avatars  \nResize on upload\n```\n\n")
+	if got != "Add avatars\n\nResize on upload" {
+		t.Fatalf("Clean() = %q", got)
+	}
+}
+
+func TestGenerateRunsCommandWithStagedDiff(t *testing.T) {
+	dir := gitRepo(t, "fix/login")
+	if err := os.WriteFile(filepath.Join(dir, "login.go"), []byte("package login\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The generator echoes back whether it saw the diff.
+	g := Generator{
+		Command:      `if grep -q "package login"; then echo "Handle expired sessions"; else echo "no diff"; fi`,
+		Conventional: true,
+	}
+	if _, err := g.Generate(dir); !errors.Is(err, ErrNothingStaged) {
+		t.Fatalf("Generate() without staged changes error = %v", err)
+	}
+	if err := StageAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := g.Generate(dir)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if msg != "fix: handle expired sessions" {
+		t.Fatalf("Generate() = %q", msg)
+	}
+
+	if err := Commit(dir, msg); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if got := strings.TrimSpace(runGit(t, dir, "log", "-1", "--format=%s")); got != msg {
+		t.Fatalf("last commit = %q", got)
+	}
+	if err := Commit(dir, msg); !errors.Is(err, ErrNothingStaged) {
+		t.Fatalf("second Commit() error = %v", err)
+	}
+}
+
+func TestGenerateReportsCommandFailure(t *testing.T) {
+	dir := gitRepo(t, "main-work")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", "-A")
+
+	_, err := Generator{Command: "echo model not found >&2; exit 3"}.Generate(dir)
+	if err == nil || !strings.Contains(err.Error(), "model not found") {
+		t.Fatalf("Generate() error = %v", err)
+	}
+}
+
+func TestFromRepoFallsBackToAgent(t *testing.T) {
+	t.Setenv("KITMUX_COMMIT_GENERATOR", "")
+	t.Setenv("KITMUX_COMMIT_AGENT", "")
+
+	if _, ok := FromRepo(config.Repo{}); ok {
+		t.Fatal("expected no generator without settings")
+	}
+	g, ok := FromRepo(config.Repo{Commit: config.RepoCommit{Agent: "claude", Conventional: true}})
+	if !ok || g.Command != "claude -p" || !g.Conventional {
+		t.Fatalf("agent generator = %+v (ok %v)", g, ok)
+	}
+	g, _ = FromRepo(config.Repo{Commit: config.RepoCommit{Generator: "llm -m local", Agent: "claude"}})
+	if g.Command != "llm -m local" {
+		t.Fatalf("generator should win over agent: %+v", g)
+	}
+}
@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // SuperKey controls the modifier for digit quick-select shortcuts.
@@ -20,6 +22,25 @@ const (
 	defaultAgentSidepanelMinWidth = 160
 	defaultAgentSidepanelRatio    = 30
 	defaultSidepanelCommand       = "kitmux sidepanel"
+
+	defaultPruneIdle     = "3d"
+	defaultWorktreeStale = "14d"
+
+	defaultTmuxBackend = "auto"
+
+	defaultWorktreeBackend = "auto"
+
+	defaultDiscoverySources = "zoxide,sessions,recent,scan"
+	defaultDiscoveryDepth   = 3
+	defaultDiscoveryIgnore  = "node_modules,vendor,.*"
+
+	defaultSubprojectMarkers = "package.json,go.mod,Cargo.toml,pyproject.toml"
+	defaultSubprojectDepth   = 3
+
+	defaultActivityMaxSpan   = "4h"
+	defaultActivityRetention = "90d"
+
+	defaultPluginTimeout = "5s"
 )
 
 func ABCodexTemplate() string {
@@ -34,18 +55,26 @@ func ABPlanPrefix() string {
 	return envOrDefault("KITMUX_AB_PLAN_PREFIX", defaultABPlanPrefix)
 }
 
+// ABBaseBranch resolves from KITMUX_AB_BASE_BRANCH, then the repo's
+// base_branch, then "main".
 func ABBaseBranch() string {
-	return envOrDefault("KITMUX_AB_BASE_BRANCH", defaultABBaseBranch)
+	return envOrDefault("KITMUX_AB_BASE_BRANCH", repoOrDefault(CurrentRepo().BaseBranch, defaultABBaseBranch))
 }
 
+// AgentSidepanel resolves from KITMUX_AGENT_SIDEPANEL, then the repo's
+// sidepanel setting, then "auto".
 func AgentSidepanel() string {
-	value := strings.ToLower(envOrDefault("KITMUX_AGENT_SIDEPANEL", defaultAgentSidepanel))
-	switch value {
-	case "auto", "always", "off":
-		return value
-	default:
-		return defaultAgentSidepanel
+	for _, value := range []string{os.Getenv("KITMUX_AGENT_SIDEPANEL"), CurrentRepo().Sidepanel} {
+		value = strings.ToLower(strings.TrimSpace(value))
+		switch value {
+		case "auto", "always", "off":
+			return value
+		default:
+			// Unset or invalid: fall through to the next source.
+			continue
+		}
 	}
+	return defaultAgentSidepanel
 }
 
 func AgentSidepanelMinWidth() int {
@@ -64,6 +93,136 @@ func SidepanelCommand() string {
 	return envOrDefault("KITMUX_SIDEPANEL_COMMAND", defaultSidepanelCommand)
 }
 
+// TmuxBackend selects how kitmux talks to tmux from a TUI: "control" keeps
+// one `tmux -C` connection, "exec" forks per command, "auto" uses control
+// mode when running inside tmux.
+func TmuxBackend() string {
+	value := strings.ToLower(envOrDefault("KITMUX_TMUX_BACKEND", defaultTmuxBackend))
+	switch value {
+	case "auto", "control", "exec":
+		return value
+	default:
+		return defaultTmuxBackend
+	}
+}
+
+// WorktreeBackend selects how kitmux manages worktrees: "wt" uses
+// worktrunk, "git" uses plain git, "auto" uses worktrunk when it is on PATH.
+func WorktreeBackend() string {
+	value := strings.ToLower(envOrDefault("KITMUX_WORKTREE_BACKEND", defaultWorktreeBackend))
+	switch value {
+	case "auto", "wt", "git":
+		return value
+	default:
+		return defaultWorktreeBackend
+	}
+}
+
+// WorkspaceWatch turns on the filesystem watcher that refreshes worktree
+// stats as files change.
+func WorkspaceWatch() bool {
+	switch strings.ToLower(envOrDefault("KITMUX_WORKSPACE_WATCH", "off")) {
+	case "1", "on", "true", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// ActivityTracking records focus and agent activity for "kitmux report".
+func ActivityTracking() bool {
+	switch strings.ToLower(envOrDefault("KITMUX_ACTIVITY", "on")) {
+	case "0", "off", "false", "no":
+		return false
+	default:
+		return true
+	}
+}
+
+// ActivityMaxSpan caps how long a single focus or agent span can count, so
+// a client left attached overnight does not inflate the report.
+func ActivityMaxSpan() string {
+	return envOrDefault("KITMUX_ACTIVITY_MAX_SPAN", defaultActivityMaxSpan)
+}
+
+// ActivityRetention is how long recorded focus and agent events are kept.
+func ActivityRetention() string {
+	return envOrDefault("KITMUX_ACTIVITY_RETENTION", defaultActivityRetention)
+}
+
+// Plugins turns on discovery of kitmux-<name> plugin executables.
+func Plugins() bool {
+	switch strings.ToLower(envOrDefault("KITMUX_PLUGINS", "off")) {
+	case "1", "on", "true", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// PluginTimeout bounds each call to a plugin executable.
+func PluginTimeout() string {
+	return envOrDefault("KITMUX_PLUGIN_TIMEOUT", defaultPluginTimeout)
+}
+
+// HideUnavailable hides palette commands that cannot run where the palette
+// was opened instead of dimming them.
+func HideUnavailable() bool {
+	return strings.ToLower(envOrDefault("KITMUX_PALETTE_UNAVAILABLE", "dim")) == "hide"
+}
+
+// PruneIdle is the default idle threshold for "kitmux sessions prune".
+func PruneIdle() string {
+	return envOrDefault("KITMUX_PRUNE_IDLE", defaultPruneIdle)
+}
+
+// WorktreeStale is the default age after which a worktree without new
+// commits counts as stale in worktree cleanup.
+func WorktreeStale() string {
+	return envOrDefault("KITMUX_WORKTREE_STALE", defaultWorktreeStale)
+}
+
+// ParseDuration reads the durations these settings and their flags take:
+// Go durations plus a "d" suffix for days (e.g. "14d"). Zero and negative
+// values are rejected.
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
+
+// DiscoverySources lists the directory sources of the workspace and
+// sidepanel pickers, in the order their results are shown.
+func DiscoverySources() []string {
+	return envList("KITMUX_DISCOVERY_SOURCES", defaultDiscoverySources)
+}
+
+// DiscoveryRoots are the directories the scan source searches for git
+// repositories. The scan is off while it is empty.
+func DiscoveryRoots() []string {
+	return envList("KITMUX_DISCOVERY_ROOTS", "")
+}
+
+// DiscoveryDepth is how many directory levels below a root the scan descends.
+func DiscoveryDepth() int {
+	return envIntOrDefault("KITMUX_DISCOVERY_DEPTH", defaultDiscoveryDepth)
+}
+
+// DiscoveryIgnore holds the base-name glob patterns the scan skips.
+func DiscoveryIgnore() []string {
+	return envList("KITMUX_DISCOVERY_IGNORE", defaultDiscoveryIgnore)
+}
+
 func envOrDefault(key, fallback string) string {
 	value := strings.TrimSpace(os.Getenv(key))
 	if value == "" {
@@ -72,6 +231,24 @@ func envOrDefault(key, fallback string) string {
 	return value
 }
 
+func repoOrDefault(value, fallback string) string {
+	if value = strings.TrimSpace(value); value == "" {
+		return fallback
+	}
+	return value
+}
+
+// envList splits a comma-separated variable, dropping empty items.
+func envList(key, fallback string) []string {
+	var out []string
+	for _, item := range strings.Split(envOrDefault(key, fallback), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func envIntOrDefault(key string, fallback int) int {
 	value := strings.TrimSpace(os.Getenv(key))
 	if value == "" {
@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestAgentSidepanel_DefaultAndValidValues(t *testing.T) {
 	t.Setenv("KITMUX_AGENT_SIDEPANEL", "")
@@ -60,3 +63,43 @@ func TestSidepanelCommand(t *testing.T) {
 		t.Fatalf("expected custom command, got %q", got)
 	}
 }
+
+func TestWorktreeBackend(t *testing.T) {
+	for value, want := range map[string]string{"": "auto", "GIT": "git", "wt": "wt", "jj": "auto"} {
+		t.Setenv("KITMUX_WORKTREE_BACKEND", value)
+		if got := WorktreeBackend(); got != want {
+			t.Fatalf("WorktreeBackend() with %q = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestWorkspaceWatch(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "on": true, "1": true, "TRUE": true, "off": false} {
+		t.Setenv("KITMUX_WORKSPACE_WATCH", value)
+		if got := WorkspaceWatch(); got != want {
+			t.Fatalf("WorkspaceWatch() with %q = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestPluginsAreOptIn(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "on": true, "yes": true, "off": false} {
+		t.Setenv("KITMUX_PLUGINS", value)
+		if got := Plugins(); got != want {
+			t.Fatalf("Plugins() with %q = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for in, want := range map[string]time.Duration{"90m": 90 * time.Minute, " 14d ": 14 * 24 * time.Hour, "1h30m": 90 * time.Minute} {
+		if got, err := ParseDuration(in); err != nil || got != want {
+			t.Fatalf("ParseDuration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "0d", "-1h", "d", "soon"} {
+		if _, err := ParseDuration(in); err == nil {
+			t.Fatalf("ParseDuration(%q) should fail", in)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/BurntSushi/toml"
+)
+
+// RepoFile is the per-repo settings file, read from the repository root.
+const RepoFile = ".kitmux.toml"
+
+// Repo holds the settings checked into a repository's .kitmux.toml. Every
+// field is optional; an empty value defers to the built-in default. Values
+// set through KITMUX_* environment variables win over the file.
+type Repo struct {
+	// Root is the repository root the file was looked up in.
+	Root string `toml:"-"`
+
+	BaseBranch  string          `toml:"base_branch"`
+	Sidepanel   string          `toml:"sidepanel"`
+	Agent       RepoAgent       `toml:"agent"`
+	Branch      RepoBranch      `toml:"branch"`
+	Worktree    RepoWorktree    `toml:"worktree"`
+	Commit      RepoCommit      `toml:"commit"`
+	Subprojects RepoSubprojects `toml:"subprojects"`
+	Commands    []RepoCommand   `toml:"commands"`
+	Macros      []RepoMacro     `toml:"macros"`
+}
+
+// RepoAgent picks the agent and modes preselected in agent pickers.
+type RepoAgent struct {
+	Default string            `toml:"default"`
+	Modes   map[string]string `toml:"modes"` // agent ID → mode ID
+}
+
+// RepoBranch customises worktree.GenerateBranchName.
+type RepoBranch struct {
+	// Prefixes maps a detected intent (fix, feat, refactor, test, docs,
+	// chore, wip) to the prefix used for it, e.g. fix = "bugfix".
+	Prefixes map[string]string `toml:"prefixes"`
+}
+
+// RepoCommit configures the commit message generator.
+type RepoCommit struct {
+	// Generator is a shell command run in the worktree that reads a prompt
+	// with the staged diff on stdin and prints a commit message.
+	Generator string `toml:"generator"`
+	// Agent names a registered agent to run non-interactively when no
+	// generator is set.
+	Agent string `toml:"agent"`
+	// Conventional formats messages as conventional commits.
+	Conventional bool `toml:"conventional"`
+}
+
+// RepoSubprojects configures how subprojects of a monorepo are detected.
+type RepoSubprojects struct {
+	// Markers are extra file names that mark a directory as a subproject,
+	// on top of the built-in ones.
+	Markers []string `toml:"markers"`
+	// Depth is how many levels below the repository root are searched.
+	Depth int `toml:"depth"`
+}
+
+// RepoWorktree provisions worktrees kitmux creates.
+type RepoWorktree struct {
+	// Setup runs in order after a worktree is created.
+	Setup []SetupStep `toml:"setup"`
+}
+
+// SetupStep is one provisioning step and sets exactly one field. Copy and
+// Symlink list paths relative to the main worktree, Run is a shell command
+// run in the new worktree, and Env is set in the worktree's tmux session.
+type SetupStep struct {
+	Copy    []string          `toml:"copy"`
+	Symlink []string          `toml:"symlink"`
+	Run     string            `toml:"run"`
+	Env     map[string]string `toml:"env"`
+}
+
+// Kind names the field the step sets: "copy", "symlink", "run" or "env".
+func (s SetupStep) Kind() string {
+	var kinds []string
+	if len(s.Copy) > 0 {
+		kinds = append(kinds, "copy")
+	}
+	if len(s.Symlink) > 0 {
+		kinds = append(kinds, "symlink")
+	}
+	if strings.TrimSpace(s.Run) != "" {
+		kinds = append(kinds, "run")
+	}
+	if len(s.Env) > 0 {
+		kinds = append(kinds, "env")
+	}
+	if len(kinds) != 1 {
+		return ""
+	}
+	return kinds[0]
+}
+
+// RepoCommand is a palette command scoped to the repository. Run is a
+// shell command executed from the repository root in a tmux popup.
+type RepoCommand struct {
+	ID          string `toml:"id"`
+	Title       string `toml:"title"`
+	Description string `toml:"description"`
+	Run         string `toml:"run"`
+	Width       string `toml:"width"`
+	Height      string `toml:"height"`
+	// Requires marks the command unavailable where it cannot run: git, worktree,
+	// not-main, ssh, thread or bin:<name>.
+	Requires []string `toml:"requires"`
+}
+
+// RepoMacro is a palette command that runs other palette commands in
+// order. Params are asked for before the first step; step arguments may
+// refer to them, and to values earlier steps produced, as {name}.
+type RepoMacro struct {
+	ID          string      `toml:"id"`
+	Title       string      `toml:"title"`
+	Description string      `toml:"description"`
+	Params      []string    `toml:"params"`
+	Steps       []MacroStep `toml:"steps"`
+	Requires    []string    `toml:"requires"` // as for RepoCommand
+}
+
+// MacroStep runs the palette command Command with Args as its parameters.
+type MacroStep struct {
+	Command string            `toml:"command"`
+	Args    map[string]string `toml:"args"`
+}
+
+// LoadRepo reads .kitmux.toml from the root of the git repository containing
+// dir. A directory outside a repository, or a repository without the file,
+// yields an empty Repo and no error.
+func LoadRepo(dir string) (Repo, error) {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return Repo{}, nil
+	}
+	root := strings.TrimSpace(string(out))
+	repo := Repo{Root: root}
+
+	path := filepath.Join(root, RepoFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return repo, nil
+	}
+	if err != nil {
+		return repo, err
+	}
+	if _, err := toml.Decode(string(data), &repo); err != nil {
+		return Repo{Root: root}, fmt.Errorf("%s: %w", path, err)
+	}
+	repo.Root = root
+	repo.Commands = validCommands(repo.Commands)
+	repo.Macros = validMacros(repo.Macros)
+	for i, step := range repo.Worktree.Setup {
+		if step.Kind() == "" {
+			return Repo{Root: root}, fmt.Errorf("%s: worktree.setup[%d] must set exactly one of copy, symlink, run or env", path, i)
+		}
+	}
+	return repo, nil
+}
+
+// validCommands drops commands without an ID or something to run, and
+// fills in the title and size.
+func validCommands(cmds []RepoCommand) []RepoCommand {
+	var valid []RepoCommand
+	seen := make(map[string]bool)
+	for _, c := range cmds {
+		c.ID = strings.TrimSpace(c.ID)
+		c.Run = strings.TrimSpace(c.Run)
+		if c.ID == "" || c.Run == "" || seen[c.ID] {
+			continue
+		}
+		seen[c.ID] = true
+		if c.Title == "" {
+			c.Title = c.ID
+		}
+		if c.Description == "" {
+			c.Description = c.Run
+		}
+		if c.Width == "" {
+			c.Width = "80%"
+		}
+		if c.Height == "" {
+			c.Height = "80%"
+		}
+		valid = append(valid, c)
+	}
+	return valid
+}
+
+// validMacros drops macros without an ID or steps, and fills in the title
+// and description.
+func validMacros(macros []RepoMacro) []RepoMacro {
+	var valid []RepoMacro
+	seen := make(map[string]bool)
+	for _, m := range macros {
+		m.ID = strings.TrimSpace(m.ID)
+		var steps []MacroStep
+		for _, step := range m.Steps {
+			step.Command = strings.TrimSpace(step.Command)
+			if step.Command != "" {
+				steps = append(steps, step)
+			}
+		}
+		m.Steps = steps
+		if m.ID == "" || len(m.Steps) == 0 || seen[m.ID] {
+			continue
+		}
+		seen[m.ID] = true
+		if m.Title == "" {
+			m.Title = m.ID
+		}
+		if m.Description == "" {
+			ids := make([]string, len(m.Steps))
+			for i, step := range m.Steps {
+				ids[i] = step.Command
+			}
+			m.Description = "Runs " + strings.Join(ids, ", ")
+		}
+		valid = append(valid, m)
+	}
+	return valid
+}
+
+var (
+	currentRepo  atomic.Pointer[Repo]
+	loadRepoOnce sync.Once
+)
+
+// CurrentRepo returns the settings of the repository kitmux was started in,
+// loaded on first use. A file that fails to parse is ignored here; LoadRepo
+// reports the error.
+func CurrentRepo() Repo {
+	loadRepoOnce.Do(func() {
+		if currentRepo.Load() != nil {
+			return
+		}
+		var repo Repo
+		if dir, err := os.Getwd(); err == nil {
+			repo, _ = LoadRepo(dir)
+		}
+		currentRepo.CompareAndSwap(nil, &repo)
+	})
+	if r := currentRepo.Load(); r != nil {
+		return *r
+	}
+	return Repo{}
+}
+
+// UseRepo makes r the current repository's settings until restore is called.
+func UseRepo(r Repo) (restore func()) {
+	loadRepoOnce.Do(func() {})
+	prev := currentRepo.Swap(&r)
+	return func() { currentRepo.Store(prev) }
+}
+
+// DefaultAgent is the agent preselected in agent pickers, or "" for the
+// first one.
+func (r Repo) DefaultAgent() string {
+	return envOrDefault("KITMUX_DEFAULT_AGENT", strings.TrimSpace(r.Agent.Default))
+}
+
+// AgentMode is the mode preselected for agentID.
+func (r Repo) AgentMode(agentID string) string {
+	if mode := strings.TrimSpace(r.Agent.Modes[agentID]); mode != "" {
+		return mode
+	}
+	return "default"
+}
+
+// BranchPrefixes returns the intent → prefix overrides for branch names.
+func (r Repo) BranchPrefixes() map[string]string {
+	prefixes := make(map[string]string, len(r.Branch.Prefixes))
+	for intent, prefix := range r.Branch.Prefixes {
+		prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+		if prefix != "" {
+			prefixes[strings.ToLower(intent)] = prefix
+		}
+	}
+	return prefixes
+}
+
+// CommitGenerator is the shell command drafting commit messages, from
+// KITMUX_COMMIT_GENERATOR, then commit.generator.
+func (r Repo) CommitGenerator() string {
+	return envOrDefault("KITMUX_COMMIT_GENERATOR", strings.TrimSpace(r.Commit.Generator))
+}
+
+// CommitAgent is the agent drafting commit messages when no generator is
+// set, from KITMUX_COMMIT_AGENT, then commit.agent.
+func (r Repo) CommitAgent() string {
+	return envOrDefault("KITMUX_COMMIT_AGENT", strings.TrimSpace(r.Commit.Agent))
+}
+
+// CommitConventional reports whether drafted messages are formatted as
+// conventional commits, from KITMUX_COMMIT_CONVENTIONAL, then
+// commit.conventional.
+func (r Repo) CommitConventional() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("KITMUX_COMMIT_CONVENTIONAL"))) {
+	case "1", "on", "true", "yes":
+		return true
+	case "0", "off", "false", "no":
+		return false
+	}
+	return r.Commit.Conventional
+}
+
+// SubprojectMarkers lists the file names marking a subproject directory:
+// KITMUX_SUBPROJECT_MARKERS, else the built-in markers plus
+// subprojects.markers.
+func (r Repo) SubprojectMarkers() []string {
+	markers := envList("KITMUX_SUBPROJECT_MARKERS", "")
+	if len(markers) > 0 {
+		return markers
+	}
+	markers = strings.Split(defaultSubprojectMarkers, ",")
+	for _, marker := range r.Subprojects.Markers {
+		if marker = strings.TrimSpace(marker); marker != "" {
+			markers = append(markers, marker)
+		}
+	}
+	return markers
+}
+
+// SubprojectDepth is how deep below the repository root subprojects are
+// searched, from KITMUX_SUBPROJECT_DEPTH, then subprojects.depth.
+func (r Repo) SubprojectDepth() int {
+	fallback := defaultSubprojectDepth
+	if r.Subprojects.Depth > 0 {
+		fallback = r.Subprojects.Depth
+	}
+	return envIntOrDefault("KITMUX_SUBPROJECT_DEPTH", fallback)
+}
+
+// DefaultAgent is the current repository's preselected agent.
+func DefaultAgent() string {
+	return CurrentRepo().DefaultAgent()
+}
+
+// AgentMode is the current repository's preselected mode for agentID.
+func AgentMode(agentID string) string {
+	return CurrentRepo().AgentMode(agentID)
+}
+
+// BranchPrefixes returns the current repository's branch prefix overrides.
+func BranchPrefixes() map[string]string {
+	return CurrentRepo().BranchPrefixes()
+}
+
+// RepoCommands returns the current repository's palette commands.
+func RepoCommands() []RepoCommand {
+	return CurrentRepo().Commands
+}
+
+// RepoMacros returns the current repository's macros.
+func RepoMacros() []RepoMacro {
+	return CurrentRepo().Macros
+}
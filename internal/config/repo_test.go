@@ -0,0 +1,231 @@
+package config
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadRepoReadsFileAtRoot(t *testing.T) {
+	root := gitRepo(t)
+	writeRepoFile(t, root, `
+base_branch = "develop"
+sidepanel = "always"
+
+[agent]
+default = "claude"
+
+[agent.modes]
+claude = "skip-perms"
+
+[branch.prefixes]
+fix = "bugfix/"
+feat = "feature"
+
+[[commands]]
+id = "test"
+title = "Run Tests"
+run = "make test"
+
+[[commands]]
+id = "lint"
+run = "make lint"
+height = "40%"
+
+[[commands]]
+id = "broken"
+`)
+	sub := filepath.Join(root, "pkg", "api")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := LoadRepo(sub)
+	if err != nil {
+		t.Fatalf("LoadRepo: %v", err)
+	}
+	if repo.BaseBranch != "develop" || repo.Sidepanel != "always" {
+		t.Fatalf("repo = %+v", repo)
+	}
+	if repo.DefaultAgent() != "claude" || repo.AgentMode("claude") != "skip-perms" || repo.AgentMode("codex") != "default" {
+		t.Fatalf("agent = %+v", repo.Agent)
+	}
+	prefixes := repo.BranchPrefixes()
+	if prefixes["fix"] != "bugfix" || prefixes["feat"] != "feature" {
+		t.Fatalf("prefixes = %v", prefixes)
+	}
+	if len(repo.Commands) != 2 {
+		t.Fatalf("commands = %+v, want the two runnable ones", repo.Commands)
+	}
+	lint := repo.Commands[1]
+	if lint.Title != "lint" || lint.Description != "make lint" || lint.Width != "80%" || lint.Height != "40%" {
+		t.Fatalf("lint defaults = %+v", lint)
+	}
+}
+
+func TestLoadRepoWithoutFile(t *testing.T) {
+	root := gitRepo(t)
+	repo, err := LoadRepo(root)
+	if err != nil {
+		t.Fatalf("LoadRepo: %v", err)
+	}
+	if repo.BaseBranch != "" || len(repo.Commands) != 0 || repo.Root == "" {
+		t.Fatalf("repo = %+v", repo)
+	}
+}
+
+func TestLoadRepoReportsInvalidFile(t *testing.T) {
+	root := gitRepo(t)
+	writeRepoFile(t, root, "base_branch = \n")
+	_, err := LoadRepo(root)
+	if err == nil || !strings.Contains(err.Error(), RepoFile) {
+		t.Fatalf("err = %v, want a parse error naming %s", err, RepoFile)
+	}
+}
+
+func TestLoadRepoReadsWorktreeSetup(t *testing.T) {
+	root := gitRepo(t)
+	writeRepoFile(t, root, `
+[[worktree.setup]]
+copy = [".env"]
+
+[[worktree.setup]]
+run = "pnpm install"
+
+[[worktree.setup]]
+env = { PORT = "3001" }
+`)
+	repo, err := LoadRepo(root)
+	if err != nil {
+		t.Fatalf("LoadRepo: %v", err)
+	}
+	var kinds []string
+	for _, step := range repo.Worktree.Setup {
+		kinds = append(kinds, step.Kind())
+	}
+	if strings.Join(kinds, ",") != "copy,run,env" {
+		t.Fatalf("setup kinds = %v", kinds)
+	}
+
+	writeRepoFile(t, root, `
+[[worktree.setup]]
+copy = [".env"]
+run = "pnpm install"
+`)
+	if _, err := LoadRepo(root); err == nil || !strings.Contains(err.Error(), "worktree.setup[0]") {
+		t.Fatalf("err = %v, want an ambiguous step error", err)
+	}
+}
+
+func TestLoadRepoReadsMacros(t *testing.T) {
+	root := gitRepo(t)
+	writeRepoFile(t, root, `
+[[macros]]
+id = "feature"
+title = "Start Feature"
+params = ["description"]
+
+[[macros.steps]]
+command = "wt_create"
+args = { branch = "{description|branch}" }
+
+[[macros.steps]]
+command = " wt_open "
+args = { branch = "{branch}" }
+
+[[macros]]
+id = "empty"
+
+[[macros]]
+id = "sidepanel"
+steps = [{ command = "open_sidepanel" }, { command = "" }]
+`)
+	repo, err := LoadRepo(root)
+	if err != nil {
+		t.Fatalf("LoadRepo: %v", err)
+	}
+	if len(repo.Macros) != 2 {
+		t.Fatalf("macros = %+v, want the two with steps", repo.Macros)
+	}
+	feature := repo.Macros[0]
+	if feature.Title != "Start Feature" || len(feature.Params) != 1 || len(feature.Steps) != 2 {
+		t.Fatalf("feature = %+v", feature)
+	}
+	if feature.Steps[1].Command != "wt_open" || feature.Steps[0].Args["branch"] != "{description|branch}" {
+		t.Fatalf("steps = %+v", feature.Steps)
+	}
+	side := repo.Macros[1]
+	if side.Title != "sidepanel" || side.Description != "Runs open_sidepanel" || len(side.Steps) != 1 {
+		t.Fatalf("sidepanel defaults = %+v", side)
+	}
+}
+
+func TestSubprojectMarkers(t *testing.T) {
+	t.Setenv("KITMUX_SUBPROJECT_MARKERS", "")
+	t.Setenv("KITMUX_SUBPROJECT_DEPTH", "")
+	repo := Repo{Subprojects: RepoSubprojects{Markers: []string{"BUILD.bazel"}, Depth: 2}}
+	if got := strings.Join(repo.SubprojectMarkers(), ","); got != "package.json,go.mod,Cargo.toml,pyproject.toml,BUILD.bazel" {
+		t.Fatalf("markers = %s", got)
+	}
+	if repo.SubprojectDepth() != 2 || (Repo{}).SubprojectDepth() != 3 {
+		t.Fatalf("depth = %d", repo.SubprojectDepth())
+	}
+
+	t.Setenv("KITMUX_SUBPROJECT_MARKERS", "deno.json")
+	if got := strings.Join(repo.SubprojectMarkers(), ","); got != "deno.json" {
+		t.Fatalf("env markers = %s", got)
+	}
+}
+
+func TestResolutionOrder(t *testing.T) {
+	restore := UseRepo(Repo{BaseBranch: "develop", Sidepanel: "off", Agent: RepoAgent{Default: "codex"}})
+	defer restore()
+
+	t.Setenv("KITMUX_AB_BASE_BRANCH", "")
+	t.Setenv("KITMUX_AGENT_SIDEPANEL", "")
+	t.Setenv("KITMUX_DEFAULT_AGENT", "")
+	if ABBaseBranch() != "develop" || AgentSidepanel() != "off" || DefaultAgent() != "codex" {
+		t.Fatalf("repo values not used: %q %q %q", ABBaseBranch(), AgentSidepanel(), DefaultAgent())
+	}
+
+	t.Setenv("KITMUX_AB_BASE_BRANCH", "trunk")
+	t.Setenv("KITMUX_AGENT_SIDEPANEL", "always")
+	t.Setenv("KITMUX_DEFAULT_AGENT", "claude")
+	if ABBaseBranch() != "trunk" || AgentSidepanel() != "always" || DefaultAgent() != "claude" {
+		t.Fatalf("env did not win: %q %q %q", ABBaseBranch(), AgentSidepanel(), DefaultAgent())
+	}
+
+	UseRepo(Repo{Sidepanel: "off"})
+	t.Setenv("KITMUX_AGENT_SIDEPANEL", "sometimes")
+	if AgentSidepanel() != "off" {
+		t.Fatalf("invalid env should fall through to the repo, got %q", AgentSidepanel())
+	}
+
+	UseRepo(Repo{Sidepanel: "sometimes"})
+	t.Setenv("KITMUX_AB_BASE_BRANCH", "")
+	t.Setenv("KITMUX_AGENT_SIDEPANEL", "")
+	if ABBaseBranch() != "main" || AgentSidepanel() != "auto" {
+		t.Fatalf("defaults = %q %q", ABBaseBranch(), AgentSidepanel())
+	}
+}
+
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	if out, err := exec.Command("git", "-C", root, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v (%s)", err, out)
+	}
+	return root
+}
+
+func writeRepoFile(t *testing.T, root, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(root, RepoFile), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,234 @@
+// Package discovery finds directories worth opening as workspaces. Each
+// source lists candidates from one place: zoxide, a scan of root directories
+// for git repositories, the paths of open tmux sessions, and the repos kitmux
+// has recently seen. Collect merges them in order, keeping the first
+// occurrence of each path.
+package discovery
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/store"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+)
+
+// Source names accepted by KITMUX_DISCOVERY_SOURCES.
+const (
+	SourceZoxide   = "zoxide"
+	SourceScan     = "scan"
+	SourceSessions = "sessions"
+	SourceRecent   = "recent"
+)
+
+// Dir is a candidate directory. Score is zoxide's frecency score and zero
+// for the other sources.
+type Dir struct {
+	Path   string
+	Score  float64
+	Source string
+}
+
+// Short returns the path with the home directory abbreviated to ~.
+func (d Dir) Short() string {
+	home, _ := os.UserHomeDir()
+	if home != "" && (d.Path == home || strings.HasPrefix(d.Path, home+string(filepath.Separator))) {
+		return "~" + d.Path[len(home):]
+	}
+	return d.Path
+}
+
+// Source lists directories from one place. A source that is unavailable
+// (zoxide not installed, no tmux server) returns an error and is skipped.
+type Source struct {
+	Name string
+	List func() ([]Dir, error)
+}
+
+// Seams for tests.
+var (
+	execCommand = func(name string, args ...string) ([]byte, error) {
+		return exec.Command(name, args...).Output()
+	}
+	listSessions  = tmux.ListSessions
+	loadRepoRoots = store.LoadRepoRootCache
+)
+
+// Configured returns the sources named by config.DiscoverySources, in order.
+// Unknown names are ignored.
+func Configured() []Source {
+	var sources []Source
+	for _, name := range config.DiscoverySources() {
+		switch strings.ToLower(name) {
+		case SourceZoxide:
+			sources = append(sources, Zoxide())
+		case SourceScan:
+			sources = append(sources, Scan(config.DiscoveryRoots(), config.DiscoveryDepth(), config.DiscoveryIgnore()))
+		case SourceSessions:
+			sources = append(sources, Sessions())
+		case SourceRecent:
+			sources = append(sources, Recent())
+		}
+	}
+	return sources
+}
+
+// Collect lists every source in order and drops repeated paths.
+func Collect(sources []Source) []Dir {
+	seen := make(map[string]bool)
+	var dirs []Dir
+	for _, src := range sources {
+		found, err := src.List()
+		if err != nil {
+			continue
+		}
+		for _, d := range found {
+			d.Path = filepath.Clean(strings.TrimSpace(d.Path))
+			if d.Path == "." || seen[d.Path] {
+				continue
+			}
+			seen[d.Path] = true
+			d.Source = src.Name
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+// Zoxide lists `zoxide query -ls`, highest score first.
+func Zoxide() Source {
+	return Source{Name: SourceZoxide, List: func() ([]Dir, error) {
+		out, err := execCommand("zoxide", "query", "-ls")
+		if err != nil {
+			return nil, err
+		}
+		var dirs []Dir
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			line = strings.TrimSpace(line)
+			idx := strings.IndexByte(line, ' ')
+			if idx < 0 {
+				continue
+			}
+			score, _ := strconv.ParseFloat(line[:idx], 64)
+			dirs = append(dirs, Dir{Path: strings.TrimSpace(line[idx+1:]), Score: score})
+		}
+		return dirs, nil
+	}}
+}
+
+// Sessions lists the working directories of the open tmux sessions.
+func Sessions() Source {
+	return Source{Name: SourceSessions, List: func() ([]Dir, error) {
+		sessions, err := listSessions()
+		if err != nil {
+			return nil, err
+		}
+		var dirs []Dir
+		for _, s := range sessions {
+			if s.Path != "" {
+				dirs = append(dirs, Dir{Path: s.Path})
+			}
+		}
+		return dirs, nil
+	}}
+}
+
+// Recent lists the repository roots kitmux resolved for session and
+// workspace paths, most recently refreshed first.
+func Recent() Source {
+	return Source{Name: SourceRecent, List: func() ([]Dir, error) {
+		cache, err := loadRepoRoots()
+		if err != nil {
+			return nil, err
+		}
+		latest := make(map[string]int64)
+		for _, entry := range cache {
+			ts := entry.RefreshedAt.UnixNano()
+			if prev, ok := latest[entry.RepoRoot]; !ok || ts > prev {
+				latest[entry.RepoRoot] = ts
+			}
+		}
+		roots := make([]string, 0, len(latest))
+		for root := range latest {
+			roots = append(roots, root)
+		}
+		sort.Slice(roots, func(i, j int) bool {
+			if latest[roots[i]] != latest[roots[j]] {
+				return latest[roots[i]] > latest[roots[j]]
+			}
+			return roots[i] < roots[j]
+		})
+		dirs := make([]Dir, len(roots))
+		for i, root := range roots {
+			dirs[i] = Dir{Path: root}
+		}
+		return dirs, nil
+	}}
+}
+
+// Scan walks roots for git repositories, descending at most depth levels
+// and skipping directories whose base name matches an ignore pattern. A
+// repository is not searched for nested repositories. A leading ~ in a
+// root is expanded to the home directory.
+func Scan(roots []string, depth int, ignore []string) Source {
+	return Source{Name: SourceScan, List: func() ([]Dir, error) {
+		var dirs []Dir
+		for _, root := range roots {
+			dirs = append(dirs, scanRoot(expandHome(root), depth, ignore)...)
+		}
+		return dirs, nil
+	}}
+}
+
+func scanRoot(dir string, depth int, ignore []string) []Dir {
+	if isRepo(dir) {
+		return []Dir{{Path: dir}}
+	}
+	if depth <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var dirs []Dir
+	for _, e := range entries {
+		if !e.IsDir() || ignored(e.Name(), ignore) {
+			continue
+		}
+		dirs = append(dirs, scanRoot(filepath.Join(dir, e.Name()), depth-1, ignore)...)
+	}
+	return dirs
+}
+
+// isRepo reports whether dir holds a .git directory, or a .git file as in
+// linked worktrees and submodules.
+func isRepo(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+func ignored(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
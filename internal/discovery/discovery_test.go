@@ -0,0 +1,101 @@
+package discovery
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/store"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+)
+
+func TestScanFindsReposWithinDepth(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{
+		"work/api/.git",
+		"work/api/nested/.git", // inside a repo: not searched
+		"work/group/web/.git",
+		"work/group/deep/er/.git", // below depth
+		"work/node_modules/pkg/.git",
+		"work/.cache/tool/.git",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Linked worktrees have a .git file.
+	if err := os.MkdirAll(filepath.Join(root, "work", "wt"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "work", "wt", ".git"), []byte("gitdir: x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dirs, err := Scan([]string{filepath.Join(root, "work")}, 2, []string{"node_modules", ".*"}).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range dirs {
+		rel, _ := filepath.Rel(root, d.Path)
+		got = append(got, rel)
+	}
+	want := []string{"work/api", "work/group/web", "work/wt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("scan = %v, want %v", got, want)
+	}
+}
+
+func TestCollectMergesSourcesInOrder(t *testing.T) {
+	prevExec, prevSessions, prevRoots := execCommand, listSessions, loadRepoRoots
+	t.Cleanup(func() { execCommand, listSessions, loadRepoRoots = prevExec, prevSessions, prevRoots })
+
+	execCommand = func(string, ...string) ([]byte, error) {
+		return []byte("  12.5 /src/api\n   3.0 /src/web\n"), nil
+	}
+	listSessions = func() ([]tmux.Session, error) {
+		return []tmux.Session{{Name: "api", Path: "/src/api/"}, {Name: "notes", Path: "/notes"}}, nil
+	}
+	now := time.Now()
+	loadRepoRoots = func() (map[string]store.PathRepoRoot, error) {
+		return map[string]store.PathRepoRoot{
+			"/old":        {Path: "/old", RepoRoot: "/old", RefreshedAt: now.Add(-time.Hour)},
+			"/new/sub":    {Path: "/new/sub", RepoRoot: "/new", RefreshedAt: now},
+			"/src/web/ui": {Path: "/src/web/ui", RepoRoot: "/src/web", RefreshedAt: now},
+		}, nil
+	}
+	failing := Source{Name: "broken", List: func() ([]Dir, error) { return nil, errors.New("boom") }}
+
+	dirs := Collect([]Source{Zoxide(), failing, Sessions(), Recent()})
+	var got []string
+	for _, d := range dirs {
+		got = append(got, d.Source+" "+d.Path)
+	}
+	want := []string{
+		"zoxide /src/api",
+		"zoxide /src/web",
+		"sessions /notes",
+		"recent /new",
+		"recent /old",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("collect = %v, want %v", got, want)
+	}
+	if dirs[0].Score != 12.5 {
+		t.Fatalf("score = %v", dirs[0].Score)
+	}
+}
+
+func TestConfiguredFollowsSourceList(t *testing.T) {
+	t.Setenv("KITMUX_DISCOVERY_SOURCES", "recent, scan,bogus")
+	var names []string
+	for _, src := range Configured() {
+		names = append(names, src.Name)
+	}
+	if !reflect.DeepEqual(names, []string{SourceRecent, SourceScan}) {
+		t.Fatalf("sources = %v", names)
+	}
+}
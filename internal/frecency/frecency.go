@@ -0,0 +1,126 @@
+// Package frecency ranks things by how often and how recently they were
+// used. Every use adds one to an item's score and scores halve every
+// halfLife, so something used daily still ranks first after a day away,
+// while a one-off from last month fades out.
+package frecency
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sahilm/fuzzy"
+
+	"github.com/miltonparedes/kitmux/internal/store"
+)
+
+// Kinds of ranked items; each is scored separately.
+const (
+	KindCommand   = store.FrecencyCommand // palette command IDs
+	KindSession   = "session"             // tmux session names
+	KindWorkspace = "workspace"           // workspace paths
+	KindDir       = "dir"                 // directories picked in zoxide and directory pickers
+	KindAgent     = "agent"               // agent IDs
+)
+
+const (
+	halfLife = 7 * 24 * time.Hour
+
+	// fuzzyWeight scales the bonus a score adds to a fuzzy match; a handful
+	// of recent uses is worth about a better-placed match character.
+	fuzzyWeight = 8
+)
+
+// Side effects, replaced in tests.
+var (
+	loadEntries = store.LoadFrecency
+	saveEntry   = store.SaveFrecency
+	now         = time.Now
+)
+
+var mu sync.Mutex
+
+// Scores are the current scores of one kind, keyed by item.
+type Scores map[string]float64
+
+// Load returns the decayed scores of kind. Errors yield no scores, so
+// ranking falls back to the callers' own order.
+func Load(kind string) Scores {
+	entries, err := loadEntries(kind)
+	if err != nil {
+		return Scores{}
+	}
+	at := now()
+	scores := make(Scores, len(entries))
+	for key, e := range entries {
+		scores[key] = decay(e, at)
+	}
+	return scores
+}
+
+// Record adds a use of key.
+func Record(kind, key string) {
+	if key == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	entries, err := loadEntries(kind)
+	if err != nil {
+		return
+	}
+	at := now()
+	_ = saveEntry(kind, key, store.FrecencyEntry{Score: decay(entries[key], at) + 1, UpdatedAt: at})
+}
+
+func decay(e store.FrecencyEntry, at time.Time) float64 {
+	age := at.Sub(e.UpdatedAt)
+	if age <= 0 {
+		return e.Score
+	}
+	return e.Score * math.Exp2(-float64(age)/float64(halfLife))
+}
+
+// Sort returns items ordered by score, highest first. Items without a score
+// keep their order after the scored ones.
+func Sort[T any](items []T, scores Scores, key func(T) string) []T {
+	out := append([]T(nil), items...)
+	if len(scores) == 0 {
+		return out
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return scores[key(out[i])] > scores[key(out[j])]
+	})
+	return out
+}
+
+// Rank fuzzy-matches query against texts and returns the indices of the
+// matches, best first. Each match's fuzzy score is raised by the frecency
+// of keys[i], so among similar matches the most used one wins. An empty
+// query returns every index ordered by score alone.
+func Rank(query string, texts, keys []string, scores Scores) []int {
+	if query == "" {
+		idx := make([]int, len(texts))
+		for i := range idx {
+			idx[i] = i
+		}
+		if len(scores) > 0 {
+			sort.SliceStable(idx, func(a, b int) bool {
+				return scores[keys[idx[a]]] > scores[keys[idx[b]]]
+			})
+		}
+		return idx
+	}
+	matches := fuzzy.Find(query, texts)
+	ranked := make([]int, len(matches))
+	combined := make(map[int]float64, len(matches))
+	for i, m := range matches {
+		ranked[i] = m.Index
+		combined[m.Index] = float64(m.Score) + fuzzyWeight*math.Log1p(scores[keys[m.Index]])
+	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		return combined[ranked[a]] > combined[ranked[b]]
+	})
+	return ranked
+}
@@ -0,0 +1,58 @@
+package frecency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/store"
+)
+
+func TestRecordDecaysOldUses(t *testing.T) {
+	entries := map[string]store.FrecencyEntry{}
+	prevLoad, prevSave, prevNow := loadEntries, saveEntry, now
+	t.Cleanup(func() { loadEntries, saveEntry, now = prevLoad, prevSave, prevNow })
+	loadEntries = func(string) (map[string]store.FrecencyEntry, error) { return entries, nil }
+	saveEntry = func(_, key string, e store.FrecencyEntry) error {
+		entries[key] = e
+		return nil
+	}
+	start := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	// Used daily for a week, then a one-off yesterday.
+	for day := 0; day < 7; day++ {
+		now = func() time.Time { return start.Add(time.Duration(day) * 24 * time.Hour) }
+		Record(KindSession, "kitmux")
+	}
+	now = func() time.Time { return start.Add(7 * 24 * time.Hour) }
+	Record(KindSession, "scratch")
+
+	now = func() time.Time { return start.Add(8 * 24 * time.Hour) }
+	scores := Load(KindSession)
+	if scores["kitmux"] <= scores["scratch"] || scores["scratch"] >= 1 {
+		t.Fatalf("scores = %v", scores)
+	}
+
+	now = func() time.Time { return start.Add(14 * 24 * time.Hour) }
+	if got := Load(KindSession)["scratch"]; got < 0.49 || got > 0.51 {
+		t.Fatalf("scratch after one half-life = %v", got)
+	}
+}
+
+func TestRankPrefersUsedItemsAmongSimilarMatches(t *testing.T) {
+	texts := []string{"kitmux", "kitmux-feature", "notes"}
+	scores := Scores{"kitmux-feature": 6}
+
+	if got := Rank("kit", texts, texts, scores); len(got) != 2 || got[0] != 1 {
+		t.Fatalf("Rank(kit) = %v", got)
+	}
+	if got := Rank("kit", texts, texts, nil); got[0] != 0 {
+		t.Fatalf("Rank(kit) without scores = %v", got)
+	}
+	if got := Rank("", texts, texts, scores); got[0] != 1 || got[1] != 0 || got[2] != 2 {
+		t.Fatalf("Rank() = %v", got)
+	}
+	sorted := Sort(texts, scores, func(s string) string { return s })
+	if sorted[0] != "kitmux-feature" || texts[0] != "kitmux" {
+		t.Fatalf("Sort = %v (input %v)", sorted, texts)
+	}
+}
@@ -0,0 +1,119 @@
+package gitdiff
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrBaseView is returned for hunk actions and discards on the base view,
+// whose hunks are not relative to the index.
+var ErrBaseView = errors.New("switch to the unstaged or staged view to change hunks")
+
+// Stage adds the whole file to the index.
+func (t Target) Stage(f File) error {
+	_, err := git(t.Dir, append([]string{"add", "-A", "--"}, f.pathspec()...)...)
+	return err
+}
+
+// Unstage resets the file in the index to HEAD, keeping the working tree.
+func (t Target) Unstage(f File) error {
+	if f.Untracked() {
+		return nil
+	}
+	_, err := git(t.Dir, append([]string{"restore", "--staged", "--"}, f.pathspec()...)...)
+	return err
+}
+
+// Discard throws away the file's changes shown in src: unstaged changes
+// restore the working tree from the index, staged changes restore both the
+// index and the working tree from HEAD. Untracked files are deleted.
+func (t Target) Discard(src Source, f File) error {
+	if f.Untracked() {
+		return os.Remove(filepath.Join(t.Dir, f.Path))
+	}
+	switch src {
+	case Unstaged:
+		_, err := git(t.Dir, append([]string{"restore", "--"}, f.pathspec()...)...)
+		return err
+	case Staged:
+		if f.Status == StatusAdded {
+			// Not in HEAD: drop it from the index and the disk.
+			_, err := git(t.Dir, "rm", "-f", "-q", "--", f.Path)
+			return err
+		}
+		_, err := git(t.Dir, append([]string{"restore", "--source=HEAD", "--staged", "--worktree", "--"}, f.pathspec()...)...)
+		return err
+	}
+	return ErrBaseView
+}
+
+// StageHunk applies hunk i of an unstaged diff to the index.
+func (t Target) StageHunk(fd FileDiff, i int) error {
+	if fd.File.Untracked() {
+		return t.Stage(fd.File)
+	}
+	return t.applyHunk(fd, i, "--cached")
+}
+
+// UnstageHunk removes hunk i of a staged diff from the index.
+func (t Target) UnstageHunk(fd FileDiff, i int) error {
+	return t.applyHunk(fd, i, "--cached", "-R")
+}
+
+// DiscardHunk reverts hunk i of the diff shown in src. A staged hunk is
+// reverted in the index and the working tree, which fails when the same
+// lines also have unstaged changes.
+func (t Target) DiscardHunk(src Source, fd FileDiff, i int) error {
+	if fd.File.Untracked() {
+		return t.Discard(src, fd.File)
+	}
+	switch src {
+	case Unstaged:
+		return t.applyHunk(fd, i, "-R")
+	case Staged:
+		return t.applyHunk(fd, i, "--index", "-R")
+	}
+	return ErrBaseView
+}
+
+// Patch renders hunk i of fd as a patch git apply accepts.
+func (fd FileDiff) Patch(i int) (string, error) {
+	if i < 0 || i >= len(fd.Hunks) {
+		return "", fmt.Errorf("no hunk %d", i+1)
+	}
+	var b strings.Builder
+	for _, line := range fd.Header {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	h := fd.Hunks[i]
+	b.WriteString(h.Header)
+	b.WriteByte('\n')
+	for _, line := range h.Lines {
+		b.WriteByte(line.Kind)
+		b.WriteString(line.Text)
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+func (t Target) applyHunk(fd FileDiff, i int, flags ...string) error {
+	patch, err := fd.Patch(i)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("git", append([]string{"-C", t.Dir, "apply", "--whitespace=nowarn"}, append(flags, "-")...)...)
+	cmd.Stdin = strings.NewReader(patch)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("git apply: %s", firstLine(msg))
+		}
+		return fmt.Errorf("git apply: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+// Package gitdiff reads the changes of a worktree for the diff viewer and
+// stages, unstages or discards them a file or a hunk at a time.
+package gitdiff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/worktree"
+)
+
+// Source selects which changes are shown.
+type Source int
+
+const (
+	Unstaged Source = iota // working tree against the index, plus untracked files
+	Staged                 // index against HEAD
+	Base                   // working tree against the merge-base with the base branch
+)
+
+func (s Source) String() string {
+	switch s {
+	case Staged:
+		return "staged"
+	case Base:
+		return "base"
+	default:
+		return "unstaged"
+	}
+}
+
+// Status letters, as reported by `git diff --name-status`, plus Untracked.
+const (
+	StatusModified  = "M"
+	StatusAdded     = "A"
+	StatusDeleted   = "D"
+	StatusRenamed   = "R"
+	StatusUntracked = "?"
+)
+
+// File is one changed file.
+type File struct {
+	Path    string
+	OldPath string // source of a rename
+	Status  string
+	Added   int
+	Deleted int
+	Binary  bool
+}
+
+// Untracked reports whether git does not know the file yet.
+func (f File) Untracked() bool {
+	return f.Status == StatusUntracked
+}
+
+func (f File) pathspec() []string {
+	if f.OldPath != "" && f.OldPath != f.Path {
+		return []string{f.Path, f.OldPath}
+	}
+	return []string{f.Path}
+}
+
+// Line is one line of a hunk. Kind is ' ', '+', '-' or '\\' for "\ No
+// newline at end of file".
+type Line struct {
+	Kind byte
+	Text string
+}
+
+// Hunk is one "@@" section of a file diff.
+type Hunk struct {
+	Header string
+	Lines  []Line
+}
+
+// FileDiff is the parsed unified diff of one file. Header holds the lines
+// before the first hunk ("diff --git", "index", "---", "+++"), which git
+// apply needs to patch a single hunk.
+type FileDiff struct {
+	File   File
+	Header []string
+	Hunks  []Hunk
+}
+
+// Target is the worktree the diff is read from.
+type Target struct {
+	Dir  string // worktree root
+	Base string // base branch for Source Base
+}
+
+// Open resolves the worktree containing dir and its base branch: the
+// repository's base_branch setting, else the main worktree's branch.
+func Open(dir string) (Target, error) {
+	root, err := gitText(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return Target{}, fmt.Errorf("%s is not in a git repository", dir)
+	}
+	t := Target{Dir: root}
+	if repo, err := config.LoadRepo(root); err == nil && repo.BaseBranch != "" {
+		t.Base = repo.BaseBranch
+	} else if branch, err := worktree.MainBranch(root); err == nil {
+		t.Base = branch
+	}
+	return t, nil
+}
+
+// Files lists the changed files for src.
+func (t Target) Files(src Source) ([]File, error) {
+	rev, err := t.revArgs(src)
+	if err != nil {
+		return nil, err
+	}
+	statusOut, err := git(t.Dir, append([]string{"diff", "--name-status", "-z", "-M"}, rev...)...)
+	if err != nil {
+		return nil, err
+	}
+	numOut, err := git(t.Dir, append([]string{"diff", "--numstat", "-z", "-M"}, rev...)...)
+	if err != nil {
+		return nil, err
+	}
+	files := parseNameStatus(statusOut)
+	applyNumstat(files, numOut)
+	if src != Staged {
+		untracked, err := t.untracked()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, untracked...)
+	}
+	return files, nil
+}
+
+// Load returns the parsed diff of f for src.
+func (t Target) Load(src Source, f File) (FileDiff, error) {
+	if f.Untracked() {
+		// --no-index exits 1 when the files differ, which they always do.
+		out, err := git(t.Dir, "diff", "--no-index", "--", os.DevNull, f.Path)
+		var exitErr *exec.ExitError
+		if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+			return FileDiff{File: f}, err
+		}
+		fd := ParseDiff(out)
+		fd.File = f
+		return fd, nil
+	}
+	rev, err := t.revArgs(src)
+	if err != nil {
+		return FileDiff{File: f}, err
+	}
+	args := append([]string{"diff", "-M"}, rev...)
+	args = append(append(args, "--"), f.pathspec()...)
+	out, err := git(t.Dir, args...)
+	if err != nil {
+		return FileDiff{File: f}, err
+	}
+	fd := ParseDiff(out)
+	fd.File = f
+	return fd, nil
+}
+
+func (t Target) revArgs(src Source) ([]string, error) {
+	switch src {
+	case Staged:
+		return []string{"--cached"}, nil
+	case Base:
+		if t.Base == "" {
+			return nil, errors.New("no base branch")
+		}
+		mb, err := gitText(t.Dir, "merge-base", t.Base, "HEAD")
+		if err != nil {
+			return nil, fmt.Errorf("merge-base with %s: %w", t.Base, err)
+		}
+		return []string{mb}, nil
+	}
+	return nil, nil
+}
+
+func (t Target) untracked() ([]File, error) {
+	out, err := git(t.Dir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	for _, p := range strings.Split(out, "\x00") {
+		if p == "" {
+			continue
+		}
+		f := File{Path: p, Status: StatusUntracked}
+		if data, err := os.ReadFile(filepath.Join(t.Dir, p)); err == nil {
+			if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+				f.Binary = true
+			} else {
+				f.Added = bytes.Count(data, []byte("\n"))
+				if len(data) > 0 && data[len(data)-1] != '\n' {
+					f.Added++
+				}
+			}
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// parseNameStatus parses `git diff --name-status -z`: a status field
+// followed by one path, or two for renames and copies.
+func parseNameStatus(out string) []File {
+	fields := strings.Split(out, "\x00")
+	var files []File
+	for i := 0; i < len(fields); i++ {
+		status := fields[i]
+		if status == "" {
+			continue
+		}
+		kind := status[:1]
+		if (kind == StatusRenamed || kind == "C") && i+2 < len(fields) {
+			files = append(files, File{Status: kind, OldPath: fields[i+1], Path: fields[i+2]})
+			i += 2
+			continue
+		}
+		if i+1 < len(fields) {
+			files = append(files, File{Status: kind, Path: fields[i+1]})
+			i++
+		}
+	}
+	return files
+}
+
+// applyNumstat fills line counts from `git diff --numstat -z`. Renames
+// report an empty path followed by the old and new paths.
+func applyNumstat(files []File, out string) {
+	byPath := make(map[string]*File, len(files))
+	for i := range files {
+		byPath[files[i].Path] = &files[i]
+	}
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		parts := strings.SplitN(fields[i], "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		path := parts[2]
+		if path == "" && i+2 < len(fields) {
+			path = fields[i+2]
+			i += 2
+		}
+		f, ok := byPath[path]
+		if !ok {
+			continue
+		}
+		if parts[0] == "-" {
+			f.Binary = true
+			continue
+		}
+		f.Added, _ = strconv.Atoi(parts[0])
+		f.Deleted, _ = strconv.Atoi(parts[1])
+	}
+}
+
+// ParseDiff parses the unified diff of a single file.
+func ParseDiff(out string) FileDiff {
+	var fd FileDiff
+	var hunk *Hunk
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			fd.Hunks = append(fd.Hunks, Hunk{Header: line})
+			hunk = &fd.Hunks[len(fd.Hunks)-1]
+		case hunk == nil:
+			if line != "" {
+				fd.Header = append(fd.Header, line)
+			}
+		case line == "":
+			// Some tools strip the trailing space of empty context lines.
+			hunk.Lines = append(hunk.Lines, Line{Kind: ' '})
+		default:
+			hunk.Lines = append(hunk.Lines, Line{Kind: line[0], Text: line[1:]})
+		}
+	}
+	return fd
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return string(out), fmt.Errorf("git %s: %s: %w", args[0], firstLine(msg), err)
+		}
+		return string(out), err
+	}
+	return string(out), nil
+}
+
+func gitText(dir string, args ...string) (string, error) {
+	out, err := git(dir, args...)
+	return strings.TrimSpace(out), err
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,245 @@
+package gitdiff
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func gitRepo(t *testing.T) Target {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+	} {
+		runGit(t, dir, args...)
+	}
+	writeFile(t, dir, "app.go", numbered(1, 20))
+	writeFile(t, dir, "old.txt", "rename me\n")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", "initial")
+	target, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return target
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func numbered(from, to int) string {
+	var b strings.Builder
+	for i := from; i <= to; i++ {
+		b.WriteString("line ")
+		b.WriteString(strings.Repeat("x", i%3))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// editBothEnds changes the first and last line of app.go so its diff has
+// two hunks.
+func editBothEnds(t *testing.T, dir string) {
+	t.Helper()
+	lines := strings.Split(strings.TrimSuffix(readFile(t, dir, "app.go"), "\n"), "\n")
+	lines[0] = "top changed"
+	lines[len(lines)-1] = "bottom changed"
+	writeFile(t, dir, "app.go", strings.Join(lines, "\n")+"\n")
+}
+
+func fileByPath(files []File, path string) (File, bool) {
+	for _, f := range files {
+		if f.Path == path {
+			return f, true
+		}
+	}
+	return File{}, false
+}
+
+func TestFilesListsChangesPerSource(t *testing.T) {
+	repo := gitRepo(t)
+	editBothEnds(t, repo.Dir)
+	writeFile(t, repo.Dir, "new.txt", "a\nb")
+	runGit(t, repo.Dir, "mv", "old.txt", "renamed.txt")
+
+	unstaged, err := repo.Files(Unstaged)
+	if err != nil {
+		t.Fatalf("Files(Unstaged) error = %v", err)
+	}
+	app, ok := fileByPath(unstaged, "app.go")
+	if !ok || app.Status != StatusModified || app.Added != 2 || app.Deleted != 2 {
+		t.Fatalf("app.go = %+v (found %v)", app, ok)
+	}
+	if nf, ok := fileByPath(unstaged, "new.txt"); !ok || !nf.Untracked() || nf.Added != 2 {
+		t.Fatalf("new.txt = %+v (found %v)", nf, ok)
+	}
+
+	staged, err := repo.Files(Staged)
+	if err != nil {
+		t.Fatalf("Files(Staged) error = %v", err)
+	}
+	if len(staged) != 1 || staged[0].Status != StatusRenamed || staged[0].OldPath != "old.txt" || staged[0].Path != "renamed.txt" {
+		t.Fatalf("staged = %+v", staged)
+	}
+
+	base, err := repo.Files(Base)
+	if err != nil {
+		t.Fatalf("Files(Base) error = %v", err)
+	}
+	if _, ok := fileByPath(base, "renamed.txt"); !ok {
+		t.Fatalf("base view misses the staged rename: %+v", base)
+	}
+	if _, ok := fileByPath(base, "app.go"); !ok {
+		t.Fatalf("base view misses the unstaged edit: %+v", base)
+	}
+}
+
+func TestBaseComparesAgainstMergeBase(t *testing.T) {
+	repo := gitRepo(t)
+	runGit(t, repo.Dir, "checkout", "-q", "-b", "feature")
+	writeFile(t, repo.Dir, "feature.txt", "feature\n")
+	runGit(t, repo.Dir, "add", "-A")
+	runGit(t, repo.Dir, "commit", "-q", "-m", "feature")
+
+	files, err := repo.Files(Base)
+	if err != nil {
+		t.Fatalf("Files(Base) error = %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "feature.txt" || files[0].Status != StatusAdded {
+		t.Fatalf("Files(Base) = %+v", files)
+	}
+	if unstaged, _ := repo.Files(Unstaged); len(unstaged) != 0 {
+		t.Fatalf("committed work shows as unstaged: %+v", unstaged)
+	}
+}
+
+func TestHunkActions(t *testing.T) {
+	repo := gitRepo(t)
+	editBothEnds(t, repo.Dir)
+	app := File{Path: "app.go", Status: StatusModified}
+
+	fd, err := repo.Load(Unstaged, app)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(fd.Hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d:\n%+v", len(fd.Hunks), fd)
+	}
+
+	// Stage the first hunk only.
+	if err := repo.StageHunk(fd, 0); err != nil {
+		t.Fatalf("StageHunk() error = %v", err)
+	}
+	staged, err := repo.Load(Staged, app)
+	if err != nil || len(staged.Hunks) != 1 || !strings.Contains(staged.Hunks[0].Header, "@@ -1,") {
+		t.Fatalf("staged diff = %+v, err = %v", staged, err)
+	}
+	fd, _ = repo.Load(Unstaged, app)
+	if len(fd.Hunks) != 1 {
+		t.Fatalf("unstaged hunks after staging = %d", len(fd.Hunks))
+	}
+
+	// Discard the remaining unstaged hunk.
+	if err := repo.DiscardHunk(Unstaged, fd, 0); err != nil {
+		t.Fatalf("DiscardHunk() error = %v", err)
+	}
+	content := readFile(t, repo.Dir, "app.go")
+	if !strings.HasPrefix(content, "top changed\n") || strings.Contains(content, "bottom changed") {
+		t.Fatalf("app.go after discard:\n%s", content)
+	}
+
+	// Unstage the staged hunk; the change stays in the working tree.
+	if err := repo.UnstageHunk(staged, 0); err != nil {
+		t.Fatalf("UnstageHunk() error = %v", err)
+	}
+	if files, _ := repo.Files(Staged); len(files) != 0 {
+		t.Fatalf("still staged: %+v", files)
+	}
+	if !strings.HasPrefix(readFile(t, repo.Dir, "app.go"), "top changed\n") {
+		t.Fatal("unstaging touched the working tree")
+	}
+
+	if err := repo.DiscardHunk(Base, fd, 0); err != ErrBaseView {
+		t.Fatalf("DiscardHunk(Base) error = %v, want ErrBaseView", err)
+	}
+}
+
+func TestFileActions(t *testing.T) {
+	repo := gitRepo(t)
+	editBothEnds(t, repo.Dir)
+	writeFile(t, repo.Dir, "new.txt", "new\n")
+
+	files, _ := repo.Files(Unstaged)
+	nf, _ := fileByPath(files, "new.txt")
+	fd, err := repo.Load(Unstaged, nf)
+	if err != nil || len(fd.Hunks) != 1 || fd.Hunks[0].Lines[0] != (Line{Kind: '+', Text: "new"}) {
+		t.Fatalf("untracked diff = %+v, err = %v", fd, err)
+	}
+	if err := repo.Stage(nf); err != nil {
+		t.Fatalf("Stage() error = %v", err)
+	}
+	staged, _ := repo.Files(Staged)
+	added, ok := fileByPath(staged, "new.txt")
+	if !ok || added.Status != StatusAdded {
+		t.Fatalf("staged = %+v", staged)
+	}
+	if err := repo.Discard(Staged, added); err != nil {
+		t.Fatalf("Discard(Staged, added) error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.Dir, "new.txt")); !os.IsNotExist(err) {
+		t.Fatalf("discarded new file still on disk: %v", err)
+	}
+
+	app := File{Path: "app.go", Status: StatusModified}
+	if err := repo.Stage(app); err != nil {
+		t.Fatalf("Stage() error = %v", err)
+	}
+	if err := repo.Unstage(app); err != nil {
+		t.Fatalf("Unstage() error = %v", err)
+	}
+	if err := repo.Discard(Unstaged, app); err != nil {
+		t.Fatalf("Discard(Unstaged) error = %v", err)
+	}
+	if files, _ := repo.Files(Unstaged); len(files) != 0 {
+		t.Fatalf("changes left after discard: %+v", files)
+	}
+}
+
+func TestParseDiffKeepsNoNewlineMarker(t *testing.T) {
+	fd := ParseDiff("diff --git a/x b/x\n--- a/x\n+++ b/x\n@@ -1 +1 @@\n-old\n\\ No newline at end of file\n+new\n\\ No newline at end of file\n")
+	if len(fd.Header) != 3 || len(fd.Hunks) != 1 || len(fd.Hunks[0].Lines) != 4 {
+		t.Fatalf("ParseDiff() = %+v", fd)
+	}
+	patch, err := fd.Patch(0)
+	if err != nil || !strings.HasSuffix(patch, "+new\n\\ No newline at end of file\n") {
+		t.Fatalf("Patch() = %q, err = %v", patch, err)
+	}
+}
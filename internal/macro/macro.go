@@ -0,0 +1,436 @@
+// Package macro runs the macros of a repository's .kitmux.toml: palette
+// commands run one after another, each seeing what the earlier ones did.
+//
+// A macro keeps a set of variables. It starts with the current tmux session,
+// pane path, branch and repo (as session, path, branch, repo) plus the
+// macro's own parameters, and steps update them: creating or opening a
+// worktree sets branch and path, opening a session sets session. Step
+// arguments refer to variables as {name}; {name|branch} turns a description
+// into a branch name.
+package macro
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/miltonparedes/kitmux/internal/agentlaunch"
+	"github.com/miltonparedes/kitmux/internal/agents"
+	"github.com/miltonparedes/kitmux/internal/command"
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/plugin"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/worktree"
+	"github.com/miltonparedes/kitmux/internal/worktreesetup"
+)
+
+// Ops are the git and tmux operations steps need; DefaultOps runs the real
+// ones.
+type Ops struct {
+	// Tmux opens sessions and launches agents.
+	Tmux    tmux.Client
+	Context func() plugin.Context
+	// Env is where the macro runs; steps whose requirements it does not
+	// meet fail.
+	Env            func() command.Env
+	ListWorktrees  func() ([]worktree.Worktree, error)
+	CreateWorktree func(branch string) error
+	SwitchWorktree func(branch string) error
+	RemoveWorktree func(branch string) error
+	// Setup provisions a worktree the macro created and returns the
+	// variables the session opened on it should start with.
+	Setup func(path string) (map[string]string, error)
+}
+
+// DefaultOps runs the steps against git and the real tmux server,
+// provisioning new worktrees like the worktrees view does.
+func DefaultOps() Ops {
+	return Ops{
+		Tmux:           tmux.Default(),
+		Context:        plugin.CurrentContext,
+		Env:            command.DetectEnv,
+		ListWorktrees:  worktree.List,
+		CreateWorktree: worktree.Create,
+		SwitchWorktree: worktree.SwitchTo,
+		RemoveWorktree: worktree.Remove,
+		Setup: func(path string) (map[string]string, error) {
+			return worktreesetup.Provision(tmux.Default(), path)
+		},
+	}
+}
+
+// Report lists the steps a macro got through.
+type Report struct {
+	Macro string
+	Done  []string
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf("%s: ran %s", r.Macro, strings.Join(r.Done, ", "))
+}
+
+// StepError is the first step of a macro that failed. Steps before it ran;
+// steps after it did not.
+type StepError struct {
+	Macro   string
+	Step    int // 1-based
+	Total   int
+	Command string
+	Done    []string
+	Err     error
+}
+
+func (e *StepError) Error() string {
+	msg := fmt.Sprintf("%s: step %d of %d (%s) failed: %v", e.Macro, e.Step, e.Total, e.Command, e.Err)
+	if len(e.Done) > 0 {
+		msg += "; already ran " + strings.Join(e.Done, ", ")
+	}
+	return msg
+}
+
+func (e *StepError) Unwrap() error { return e.Err }
+
+// Find returns the current repository's macro id.
+func Find(id string) (config.RepoMacro, bool) {
+	for _, m := range config.RepoMacros() {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return config.RepoMacro{}, false
+}
+
+// run is the state a macro's steps share.
+type run struct {
+	ops  Ops
+	vars map[string]string
+	// opened is the session an earlier step opened, created whether the
+	// macro created it, and launched whether an agent took its first window.
+	opened   string
+	created  bool
+	launched bool
+	// env holds what Setup returned for the worktrees the macro created,
+	// by path.
+	env map[string]map[string]string
+	// here is Ops.Env, detected at the first step.
+	here *command.Env
+}
+
+// Run runs m's steps in order with args as its parameters, stopping at the
+// first step that fails with a *StepError.
+func Run(m config.RepoMacro, args map[string]string, ops Ops) (Report, error) {
+	r := newRun(ops)
+	for _, name := range m.Params {
+		value, ok := args[name]
+		if !ok || strings.TrimSpace(value) == "" {
+			return Report{Macro: m.ID}, fmt.Errorf("%s: missing --arg %s=<value>", m.ID, name)
+		}
+		r.vars[name] = value
+	}
+
+	report := Report{Macro: m.ID}
+	for i, step := range m.Steps {
+		if err := r.step(step); err != nil {
+			return report, &StepError{
+				Macro:   m.ID,
+				Step:    i + 1,
+				Total:   len(m.Steps),
+				Command: step.Command,
+				Done:    report.Done,
+				Err:     err,
+			}
+		}
+		report.Done = append(report.Done, step.Command)
+	}
+	return report, nil
+}
+
+// RunStep runs a single command the way a macro step would.
+func RunStep(step config.MacroStep, ops Ops) error {
+	return newRun(ops).step(step)
+}
+
+func newRun(ops Ops) *run {
+	ctx := ops.Context()
+	return &run{ops: ops, env: map[string]map[string]string{}, vars: map[string]string{
+		"session": ctx.Session,
+		"path":    ctx.Path,
+		"branch":  ctx.Branch,
+		"repo":    ctx.Repo,
+	}}
+}
+
+func (r *run) step(step config.MacroStep) error {
+	cmd, ok := command.FindCommand(step.Command)
+	if !ok {
+		return fmt.Errorf("unknown command %q", step.Command)
+	}
+	if r.here == nil {
+		env := r.ops.Env()
+		r.here = &env
+	}
+	if err := cmd.Check(*r.here); err != nil {
+		return err
+	}
+	handler, ok := handlers[step.Command]
+	if !ok {
+		if agentID, isLaunch := strings.CutPrefix(step.Command, "launch_"); isLaunch {
+			handler = launchAgentNamed(agentID)
+		} else {
+			return fmt.Errorf("%s cannot run in a macro", step.Command)
+		}
+	}
+	expanded := make(map[string]string, len(step.Args))
+	for name, value := range step.Args {
+		v, err := r.expand(value)
+		if err != nil {
+			return fmt.Errorf("argument %s: %w", name, err)
+		}
+		expanded[name] = v
+	}
+	args, err := cmd.ResolveArgs(expanded)
+	if err != nil {
+		return err
+	}
+	return handler(r, args)
+}
+
+var varRef = regexp.MustCompile(`\{([A-Za-z0-9_]+)(\|[A-Za-z]+)?\}`)
+
+// expand replaces {name} and {name|filter} with the macro's variables.
+func (r *run) expand(value string) (string, error) {
+	var err error
+	out := varRef.ReplaceAllStringFunc(value, func(ref string) string {
+		parts := varRef.FindStringSubmatch(ref)
+		v, ok := r.vars[parts[1]]
+		if !ok {
+			err = fmt.Errorf("unknown variable {%s}", parts[1])
+			return ref
+		}
+		switch parts[2] {
+		case "":
+			return v
+		case "|branch":
+			return worktree.GenerateBranchNameWithPrefixes(v, config.BranchPrefixes())
+		}
+		err = fmt.Errorf("unknown filter %q in %s", strings.TrimPrefix(parts[2], "|"), ref)
+		return ref
+	})
+	return out, err
+}
+
+type handler func(r *run, args map[string]string) error
+
+// handlers are the commands a macro can run besides launch_<agent>. The
+// rest open views or pickers and need someone at the keyboard.
+var handlers = map[string]handler{
+	"wt_create":      createWorktree,
+	"wt_switch":      switchWorktree,
+	"wt_open":        openWorktree,
+	"wt_remove":      removeWorktree,
+	"kill_session":   killSession,
+	"rename_session": renameSession,
+	"launch_agent":   launchAgent,
+	"open_sidepanel": openSidepanel,
+}
+
+// createWorktree creates branch's worktree and runs the repository's setup
+// in it before any step opens a session there.
+func createWorktree(r *run, args map[string]string) error {
+	if err := r.ops.CreateWorktree(args["branch"]); err != nil {
+		return err
+	}
+	if err := r.useWorktree(args["branch"]); err != nil {
+		return err
+	}
+	path := r.vars["path"]
+	env, err := r.ops.Setup(path)
+	if err != nil {
+		return fmt.Errorf("setup: %w", err)
+	}
+	r.env[path] = env
+	return nil
+}
+
+func switchWorktree(r *run, args map[string]string) error {
+	if err := r.ops.SwitchWorktree(args["branch"]); err != nil {
+		return err
+	}
+	return r.useWorktree(args["branch"])
+}
+
+// useWorktree points branch and path at branch's worktree.
+func (r *run) useWorktree(branch string) error {
+	wt, _, err := r.findWorktree(branch)
+	if err != nil {
+		return err
+	}
+	r.vars["branch"] = branch
+	r.vars["path"] = wt.Path
+	return nil
+}
+
+// findWorktree returns branch's worktree and the repository's main one.
+func (r *run) findWorktree(branch string) (wt, main worktree.Worktree, err error) {
+	wts, err := r.ops.ListWorktrees()
+	if err != nil {
+		return wt, main, err
+	}
+	found := false
+	for _, w := range wts {
+		if w.IsMain && main.Path == "" {
+			main = w
+		}
+		if w.Branch == branch && !found {
+			wt, found = w, true
+		}
+	}
+	if !found || wt.Path == "" {
+		return wt, main, fmt.Errorf("no worktree for %q", branch)
+	}
+	if main.Path == "" && len(wts) > 0 {
+		main = wts[0]
+	}
+	return wt, main, nil
+}
+
+// openWorktree switches to the session at branch's worktree, creating one
+// named <repo>-<branch> when there is none.
+func openWorktree(r *run, args map[string]string) error {
+	branch := args["branch"]
+	wt, main, err := r.findWorktree(branch)
+	if err != nil {
+		return err
+	}
+	sessions, err := r.ops.Tmux.ListSessions()
+	if err != nil {
+		return err
+	}
+	name, created := "", false
+	taken := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		taken[s.Name] = true
+		if name == "" && s.Path != "" && filepath.Clean(s.Path) == filepath.Clean(wt.Path) {
+			name = s.Name
+		}
+	}
+	if name == "" {
+		name = uniqueName(worktreeSessionName(main.Path, branch, wt.IsMain), taken)
+		if err := r.ops.Tmux.NewSessionWithEnv(name, wt.Path, r.env[wt.Path]); err != nil {
+			return fmt.Errorf("tmux new-session: %w", err)
+		}
+		created = true
+	}
+	if err := r.ops.Tmux.SwitchClient(name); err != nil {
+		return fmt.Errorf("tmux switch-client: %w", err)
+	}
+	r.vars["session"] = name
+	r.vars["branch"] = branch
+	r.vars["path"] = wt.Path
+	r.opened, r.created, r.launched = name, created, false
+	return nil
+}
+
+// worktreeSessionName names a worktree's session after its repository and
+// branch. tmux does not allow . or : in session names.
+func worktreeSessionName(mainPath, branch string, isMain bool) string {
+	name := filepath.Base(mainPath)
+	if !isMain {
+		name += "-" + strings.ReplaceAll(branch, "/", "-")
+	}
+	return strings.NewReplacer(".", "-", ":", "-").Replace(name)
+}
+
+func uniqueName(name string, taken map[string]bool) string {
+	if !taken[name] {
+		return name
+	}
+	for i := 2; i <= 99; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+	return name
+}
+
+func removeWorktree(r *run, args map[string]string) error {
+	if args["confirm"] != command.Yes {
+		return fmt.Errorf("not confirmed (set confirm = \"yes\")")
+	}
+	return r.ops.RemoveWorktree(args["branch"])
+}
+
+func killSession(r *run, args map[string]string) error {
+	if args["confirm"] != command.Yes {
+		return fmt.Errorf("not confirmed (set confirm = \"yes\")")
+	}
+	return r.ops.Tmux.KillSession(args["session"])
+}
+
+func renameSession(r *run, args map[string]string) error {
+	old, name := args["session"], args["name"]
+	if err := r.ops.Tmux.RenameSession(old, name); err != nil {
+		return err
+	}
+	if r.vars["session"] == old {
+		r.vars["session"] = name
+	}
+	if r.opened == old {
+		r.opened = name
+	}
+	return nil
+}
+
+func launchAgent(r *run, args map[string]string) error {
+	return r.launch(args["agent"], args["mode"])
+}
+
+// launchAgentNamed runs launch_<agent> in the repository's mode for it.
+func launchAgentNamed(agentID string) handler {
+	return func(r *run, _ map[string]string) error {
+		return r.launch(agentID, config.AgentMode(agentID))
+	}
+}
+
+// launch starts an agent in the session an earlier step opened, or in the
+// current pane.
+func (r *run) launch(agentID, modeID string) error {
+	a, ok := agents.Find(agentID)
+	if !ok {
+		return fmt.Errorf("unknown agent %q", agentID)
+	}
+	mode, ok := agents.FindMode(a, modeID)
+	if !ok {
+		return fmt.Errorf("agent %s has no mode %q", agentID, modeID)
+	}
+	var err error
+	if r.opened == "" {
+		err = agentlaunch.LaunchCurrent(a, mode, agentlaunch.TargetPane, r.ops.Tmux)
+	} else {
+		err = agentlaunch.LaunchInSession(agentlaunch.SessionRequest{
+			SessionName:  r.opened,
+			WindowName:   a.ID,
+			Dir:          r.vars["path"],
+			Agent:        a,
+			Mode:         mode,
+			FreshSession: r.created && !r.launched,
+		}, r.ops.Tmux)
+	}
+	if err != nil {
+		return err
+	}
+	r.launched = true
+	return nil
+}
+
+// openSidepanel splits the sidepanel into the session an earlier step
+// opened, or next to the current pane.
+func openSidepanel(r *run, _ map[string]string) error {
+	target := agentlaunch.CurrentPaneTarget
+	if r.opened != "" {
+		target = r.opened + ":"
+	}
+	return agentlaunch.SplitSidepanel(r.vars["path"], target, r.ops.Tmux)
+}
@@ -0,0 +1,223 @@
+package macro
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/kitmux/internal/command"
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/plugin"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
+	"github.com/miltonparedes/kitmux/internal/worktree"
+)
+
+// fakeOps keeps worktrees in memory, runs tmux on a tmuxtest server and
+// records the git calls and setups.
+type fakeOps struct {
+	worktrees []worktree.Worktree
+	srv       *tmuxtest.Server
+	seen      int // server calls made before the macro ran
+	setupEnv  map[string]string
+	setupErr  error
+	missing   map[string]bool // binaries not on PATH
+	calls     []string
+}
+
+func newFakeOps(worktrees ...worktree.Worktree) *fakeOps {
+	srv := tmuxtest.New()
+	srv.Attach(srv.AddSession("api", "/src/api"))
+	return &fakeOps{worktrees: worktrees, srv: srv, seen: len(srv.Calls())}
+}
+
+func (f *fakeOps) ops() Ops {
+	return Ops{
+		Tmux: f.srv,
+		Context: func() plugin.Context {
+			return plugin.Context{Session: "api", Path: "/src/api", Branch: "main", Repo: "/src/api"}
+		},
+		Env: func() command.Env {
+			return command.Env{GitRepo: true, Branch: "main", HasBinary: func(name string) bool { return !f.missing[name] }}
+		},
+		ListWorktrees: func() ([]worktree.Worktree, error) { return f.worktrees, nil },
+		CreateWorktree: func(branch string) error {
+			if branch == "taken" {
+				return errors.New("branch exists")
+			}
+			f.worktrees = append(f.worktrees, worktree.Worktree{Branch: branch, Path: "/src/api." + branch})
+			f.calls = append(f.calls, "create "+branch)
+			return nil
+		},
+		SwitchWorktree: func(branch string) error {
+			f.calls = append(f.calls, "switch "+branch)
+			return nil
+		},
+		RemoveWorktree: func(branch string) error {
+			f.calls = append(f.calls, "remove "+branch)
+			return nil
+		},
+		Setup: func(path string) (map[string]string, error) {
+			f.calls = append(f.calls, "setup "+path)
+			return f.setupEnv, f.setupErr
+		},
+	}
+}
+
+// tmuxCalls returns the calls the macro made to the server that change it,
+// with agent commands cut down to what runs after exec.
+func (f *fakeOps) tmuxCalls() []string {
+	var calls []string
+	for _, c := range f.srv.Calls()[f.seen:] {
+		if strings.HasPrefix(c, "list-sessions") {
+			continue
+		}
+		if call, wrapped, ok := strings.Cut(c, " KITMUX_AGENT_ID="); ok {
+			_, agent, _ := strings.Cut(wrapped, " exec ")
+			c = call + " " + agent
+		}
+		calls = append(calls, c)
+	}
+	return calls
+}
+
+// isolate keeps palette option sources, plugins and agent hooks from
+// touching the machine.
+func isolate(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("KITMUX_PLUGINS", "off")
+	t.Setenv("KITMUX_AGENT_SIDEPANEL", "off")
+	t.Chdir(t.TempDir())
+}
+
+func TestRunPassesContextBetweenSteps(t *testing.T) {
+	isolate(t)
+	f := newFakeOps(worktree.Worktree{Branch: "main", Path: "/src/api", IsMain: true})
+	f.setupEnv = map[string]string{"PORT": "3001"}
+	m := config.RepoMacro{
+		ID:     "feature",
+		Params: []string{"description"},
+		Steps: []config.MacroStep{
+			{Command: "wt_create", Args: map[string]string{"branch": "{description|branch}"}},
+			{Command: "wt_open", Args: map[string]string{"branch": "{branch}"}},
+			{Command: "launch_agent", Args: map[string]string{"agent": "claude", "mode": "skip-perms"}},
+			{Command: "open_sidepanel"},
+		},
+	}
+
+	report, err := Run(m, map[string]string{"description": "fix login redirect"}, f.ops())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := []string{"create fix/login-redirect", "setup /src/api.fix/login-redirect"}
+	if !reflect.DeepEqual(f.calls, want) {
+		t.Fatalf("calls = %q, want %q", f.calls, want)
+	}
+	wantTmux := []string{
+		"new-session api-fix-login-redirect /src/api.fix/login-redirect",
+		"switch-client api-fix-login-redirect",
+		"rename-window api-fix-login-redirect:0 claude",
+		"send-keys api-fix-login-redirect:0 claude --dangerously-skip-permissions",
+		"split-window -p 30 api-fix-login-redirect: /src/api.fix/login-redirect kitmux sidepanel",
+	}
+	if got := f.tmuxCalls(); !reflect.DeepEqual(got, wantTmux) {
+		t.Fatalf("tmux calls:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(wantTmux, "\n"))
+	}
+	if got := f.srv.Environment("api-fix-login-redirect", "PORT"); got != "3001" {
+		t.Fatalf("PORT = %q, want the setup's 3001 in the new session", got)
+	}
+	if report.String() != "feature: ran wt_create, wt_open, launch_agent, open_sidepanel" {
+		t.Fatalf("report = %q", report)
+	}
+}
+
+func TestRunReusesSessionAtWorktree(t *testing.T) {
+	isolate(t)
+	f := newFakeOps(
+		worktree.Worktree{Branch: "main", Path: "/src/api", IsMain: true},
+		worktree.Worktree{Branch: "feat/x", Path: "/src/api.feat-x"},
+	)
+	f.srv.AddSession("x", "/src/api.feat-x")
+	f.seen = len(f.srv.Calls())
+	m := config.RepoMacro{ID: "open", Steps: []config.MacroStep{
+		{Command: "wt_open", Args: map[string]string{"branch": "feat/x"}},
+		{Command: "launch_claude"},
+	}}
+	if _, err := Run(m, nil, f.ops()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := []string{"switch-client x", "new-window x claude /src/api.feat-x claude"}
+	if got := f.tmuxCalls(); !reflect.DeepEqual(got, want) || len(f.calls) != 0 {
+		t.Fatalf("tmux calls = %q, want %q; git calls = %q", got, want, f.calls)
+	}
+}
+
+func TestRunStopsAtFirstFailingStep(t *testing.T) {
+	isolate(t)
+	f := newFakeOps(worktree.Worktree{Branch: "main", Path: "/src/api", IsMain: true})
+	m := config.RepoMacro{ID: "broken", Steps: []config.MacroStep{
+		{Command: "wt_switch", Args: map[string]string{"branch": "main"}},
+		{Command: "wt_create", Args: map[string]string{"branch": "taken"}},
+		{Command: "open_sidepanel"},
+	}}
+
+	report, err := Run(m, nil, f.ops())
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) {
+		t.Fatalf("err = %v, want a StepError", err)
+	}
+	if stepErr.Step != 2 || stepErr.Command != "wt_create" {
+		t.Fatalf("failed step = %d %s", stepErr.Step, stepErr.Command)
+	}
+	if got := err.Error(); got != "broken: step 2 of 3 (wt_create) failed: branch exists; already ran wt_switch" {
+		t.Fatalf("err = %q", got)
+	}
+	if !reflect.DeepEqual(report.Done, []string{"wt_switch"}) || len(f.calls) != 1 || len(f.tmuxCalls()) != 0 {
+		t.Fatalf("done = %v, calls = %v %v; later steps must not run", report.Done, f.calls, f.tmuxCalls())
+	}
+}
+
+func TestRunStopsWhenSetupFails(t *testing.T) {
+	isolate(t)
+	f := newFakeOps(worktree.Worktree{Branch: "main", Path: "/src/api", IsMain: true})
+	f.setupErr = errors.New("setup popup: no client")
+	m := config.RepoMacro{ID: "feature", Steps: []config.MacroStep{
+		{Command: "wt_create", Args: map[string]string{"branch": "feat/x"}},
+		{Command: "wt_open", Args: map[string]string{"branch": "{branch}"}},
+	}}
+
+	_, err := Run(m, nil, f.ops())
+	if err == nil || !strings.Contains(err.Error(), "step 1 of 2 (wt_create) failed: setup: setup popup: no client") {
+		t.Fatalf("err = %v, want the setup failure", err)
+	}
+	if got := f.tmuxCalls(); len(got) != 0 {
+		t.Fatalf("tmux calls = %q; no session should open on an unprovisioned worktree", got)
+	}
+}
+
+func TestRunRejectsStepsItCannotRun(t *testing.T) {
+	isolate(t)
+	cases := []struct {
+		step config.MacroStep
+		want string
+	}{
+		{config.MacroStep{Command: "nope"}, `unknown command "nope"`},
+		{config.MacroStep{Command: "view_sessions"}, "view_sessions cannot run in a macro"},
+		{config.MacroStep{Command: "wt_create", Args: map[string]string{"branch": "{ticket}"}}, "unknown variable {ticket}"},
+		{config.MacroStep{Command: "wt_create"}, "missing --arg branch"},
+		{config.MacroStep{Command: "wt_remove", Args: map[string]string{"branch": "x", "confirm": "no"}}, "not confirmed"},
+		{config.MacroStep{Command: "launch_claude"}, "launch_claude is not available here: claude not installed"},
+	}
+	for _, c := range cases {
+		f := newFakeOps()
+		f.missing = map[string]bool{"claude": true}
+		_, err := Run(config.RepoMacro{ID: "m", Steps: []config.MacroStep{c.step}}, nil, f.ops())
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v, want %q", c.step.Command, err, c.want)
+		}
+		if len(f.calls) != 0 || len(f.tmuxCalls()) != 0 {
+			t.Errorf("%s: calls = %v %v, want none", c.step.Command, f.calls, f.tmuxCalls())
+		}
+	}
+}
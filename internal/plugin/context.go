@@ -0,0 +1,25 @@
+package plugin
+
+import (
+	"os"
+
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+// CurrentContext describes the current tmux pane. Outside tmux the path is
+// the working directory.
+func CurrentContext() Context {
+	var ctx Context
+	ctx.Session, _ = tmux.CurrentSession()
+	if path, err := tmux.CurrentPanePath(); err == nil && path != "" {
+		ctx.Path = path
+	} else if wd, err := os.Getwd(); err == nil {
+		ctx.Path = wd
+	}
+	if ctx.Path != "" {
+		ctx.Branch = wsdata.ResolveGitBranch(ctx.Path)
+		ctx.Repo = wsdata.ResolveRepoRoot(ctx.Path)
+	}
+	return ctx
+}
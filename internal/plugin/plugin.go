@@ -0,0 +1,310 @@
+// Package plugin runs external kitmux plugins: executables named
+// kitmux-<name> in ~/.config/kitmux/plugins or on PATH.
+//
+// kitmux calls a plugin once per request, as `kitmux-<name> <op>` with a
+// JSON request on stdin, and reads one JSON response from stdout. The ops
+// are:
+//
+//   - handshake: the plugin declares its palette commands and list
+//     providers.
+//   - list: the plugin returns the items of a provider.
+//   - select: the user picked an item from a provider's list.
+//   - run: the user ran a command that has no provider.
+//
+// select and run may answer with an action for kitmux to take. Every
+// request carries the context built-in commands see: the tmux session, the
+// current pane's path, its branch and repository. The same values are set
+// as KITMUX_SESSION, KITMUX_PATH, KITMUX_BRANCH and KITMUX_REPO.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+)
+
+// ProtocolVersion is sent with every request.
+const ProtocolVersion = 1
+
+const (
+	execPrefix     = "kitmux-"
+	defaultTimeout = 5 * time.Second
+)
+
+// Context is what a plugin learns about where it was invoked from.
+type Context struct {
+	Session string `json:"session,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Branch  string `json:"branch,omitempty"`
+	Repo    string `json:"repo,omitempty"`
+}
+
+func (c Context) env() []string {
+	return []string{
+		"KITMUX_SESSION=" + c.Session,
+		"KITMUX_PATH=" + c.Path,
+		"KITMUX_BRANCH=" + c.Branch,
+		"KITMUX_REPO=" + c.Repo,
+	}
+}
+
+// Command is a palette command declared by a plugin. Running one with a
+// Provider opens that provider's list; running one without sends "run".
+type Command struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Provider    string `json:"provider,omitempty"`
+	// Requires lists where the command can run, as for .kitmux.toml
+	// commands: git, worktree, not-main, ssh, thread or bin:<name>.
+	Requires []string `json:"requires,omitempty"`
+}
+
+// Provider is a list a plugin can fill with items.
+type Provider struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// Item is one entry of a provider's list. Meta is passed back untouched
+// when the item is selected.
+type Item struct {
+	ID          string         `json:"id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Meta        map[string]any `json:"meta,omitempty"`
+}
+
+// Result is what kitmux should do after select or run. All fields are
+// optional; Message is shown in tmux.
+type Result struct {
+	Message       string `json:"message,omitempty"`
+	SwitchSession string `json:"switch_session,omitempty"`
+	SwitchWindow  string `json:"switch_window,omitempty"`
+	Popup         string `json:"popup,omitempty"`
+}
+
+// Plugin is a discovered plugin executable and what it declared.
+type Plugin struct {
+	Name      string // from the executable name, kitmux-<name>
+	Path      string
+	Title     string // the name the plugin gave itself, or Name
+	Commands  []Command
+	Providers []Provider
+	Err       error // set when the handshake failed
+}
+
+// Command returns the plugin's command id.
+func (p Plugin) Command(id string) (Command, bool) {
+	for _, c := range p.Commands {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return Command{}, false
+}
+
+type request struct {
+	Version  int     `json:"version"`
+	Type     string  `json:"type"`
+	Context  Context `json:"context"`
+	Command  string  `json:"command,omitempty"`
+	Provider string  `json:"provider,omitempty"`
+	Item     *Item   `json:"item,omitempty"`
+}
+
+type handshakeResponse struct {
+	Name      string     `json:"name"`
+	Commands  []Command  `json:"commands"`
+	Providers []Provider `json:"providers"`
+}
+
+type listResponse struct {
+	Items []Item `json:"items"`
+}
+
+// Dirs are searched for plugins in order: the plugins directory, then PATH.
+func Dirs() []string {
+	var dirs []string
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".config", "kitmux", "plugins"))
+	}
+	return append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+}
+
+// Discover finds kitmux-<name> executables in dirs. When two share a name
+// the one in the earlier directory wins.
+func Discover(dirs []string) []Plugin {
+	seen := map[string]bool{}
+	var found []Plugin
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := strings.CutPrefix(e.Name(), execPrefix)
+			if !ok || name == "" || seen[name] {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+				continue
+			}
+			seen[name] = true
+			found = append(found, Plugin{Name: name, Path: path, Title: name})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Name < found[j].Name })
+	return found
+}
+
+// Handshake asks p what it provides.
+func Handshake(p Plugin, ctx Context) Plugin {
+	var resp handshakeResponse
+	if err := call(p, request{Type: "handshake", Context: ctx}, &resp); err != nil {
+		p.Err = err
+		return p
+	}
+	if strings.TrimSpace(resp.Name) != "" {
+		p.Title = resp.Name
+	}
+	for _, c := range resp.Commands {
+		if c.ID != "" {
+			if c.Title == "" {
+				c.Title = c.ID
+			}
+			p.Commands = append(p.Commands, c)
+		}
+	}
+	p.Providers = resp.Providers
+	return p
+}
+
+// List returns the items of p's provider.
+func (p Plugin) List(ctx Context, provider string) ([]Item, error) {
+	var resp listResponse
+	if err := call(p, request{Type: "list", Context: ctx, Provider: provider}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+// Select sends the item picked from provider's list back to p.
+func (p Plugin) Select(ctx Context, provider string, item Item) (Result, error) {
+	var res Result
+	err := call(p, request{Type: "select", Context: ctx, Provider: provider, Item: &item}, &res)
+	return res, err
+}
+
+// Run runs p's command id.
+func (p Plugin) Run(ctx Context, id string) (Result, error) {
+	var res Result
+	err := call(p, request{Type: "run", Context: ctx, Command: id}, &res)
+	return res, err
+}
+
+func call(p Plugin, req request, out any) error {
+	req.Version = ProtocolVersion
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	timeout, err := time.ParseDuration(config.PluginTimeout())
+	if err != nil || timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.Path, req.Type)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(), req.Context.env()...)
+	if req.Context.Path != "" {
+		cmd.Dir = req.Context.Path
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.Output()
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("plugin %s %s: timed out after %s", p.Name, req.Type, timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("plugin %s %s: %w: %s", p.Name, req.Type, err, msg)
+		}
+		return fmt.Errorf("plugin %s %s: %w", p.Name, req.Type, err)
+	}
+	if len(bytes.TrimSpace(stdout)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(stdout, out); err != nil {
+		return fmt.Errorf("plugin %s %s: bad response: %w", p.Name, req.Type, err)
+	}
+	return nil
+}
+
+var (
+	loadOnce sync.Once
+	loaded   []Plugin
+	loadDone atomic.Bool
+)
+
+// Load discovers the plugins and shakes hands with each, once per process.
+// It includes plugins whose handshake failed, with Err set.
+func Load() []Plugin {
+	loadOnce.Do(func() {
+		if !config.Plugins() {
+			return
+		}
+		found := Discover(Dirs())
+		if len(found) == 0 {
+			return
+		}
+		ctx := CurrentContext()
+		var wg sync.WaitGroup
+		for i := range found {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				found[i] = Handshake(found[i], ctx)
+			}(i)
+		}
+		wg.Wait()
+		loaded = found
+	})
+	loadDone.Store(true)
+	return loaded
+}
+
+// Loaded returns the plugins Load found without waiting for it: nil until
+// the first Load has finished.
+func Loaded() []Plugin {
+	if !loadDone.Load() {
+		return nil
+	}
+	return loaded
+}
+
+// Find returns the loaded plugin called name.
+func Find(name string) (Plugin, bool) {
+	for _, p := range Load() {
+		if p.Name == name && p.Err == nil {
+			return p, true
+		}
+	}
+	return Plugin{}, false
+}
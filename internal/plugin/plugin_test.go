@@ -0,0 +1,117 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const echoPlugin = `#!/bin/sh
+req=$(cat)
+case "$1" in
+handshake)
+	echo '{"name":"Echo","commands":[{"id":"pick","title":"Pick","provider":"things"},{"id":"hello","title":"Hello"},{"title":"no id"}],"providers":[{"id":"things","title":"Things"}]}'
+	;;
+list)
+	echo '{"items":[{"id":"a","title":"Alpha","meta":{"n":1}},{"id":"b","title":"Beta"}]}'
+	;;
+select)
+	case "$req" in
+	*'"id":"a"'*) echo '{"switch_session":"alpha"}' ;;
+	*) echo '{"message":"unknown"}' ;;
+	esac
+	;;
+run)
+	echo "{\"message\":\"hello from $KITMUX_SESSION\"}"
+	;;
+esac
+`
+
+func writeExec(t *testing.T, dir, name, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscoverPrefersEarlierDirs(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	writeExec(t, first, "kitmux-echo", echoPlugin)
+	writeExec(t, second, "kitmux-echo", echoPlugin)
+	writeExec(t, second, "kitmux-other", echoPlugin)
+	if err := os.WriteFile(filepath.Join(second, "kitmux-plain"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeExec(t, second, "unrelated", echoPlugin)
+
+	found := Discover([]string{first, second, filepath.Join(first, "missing")})
+	if len(found) != 2 {
+		t.Fatalf("found %d plugins, want 2: %+v", len(found), found)
+	}
+	if found[0].Name != "echo" || found[0].Path != filepath.Join(first, "kitmux-echo") {
+		t.Fatalf("echo = %+v, want the one in the first dir", found[0])
+	}
+	if found[1].Name != "other" {
+		t.Fatalf("second plugin = %q, want other", found[1].Name)
+	}
+}
+
+func TestPluginProtocol(t *testing.T) {
+	dir := t.TempDir()
+	writeExec(t, dir, "kitmux-echo", echoPlugin)
+	p := Handshake(Discover([]string{dir})[0], Context{})
+	if p.Err != nil {
+		t.Fatal(p.Err)
+	}
+	if p.Title != "Echo" || len(p.Commands) != 2 || len(p.Providers) != 1 {
+		t.Fatalf("handshake = %+v", p)
+	}
+	if c, ok := p.Command("pick"); !ok || c.Provider != "things" {
+		t.Fatalf("pick = %+v, %v", c, ok)
+	}
+
+	ctx := Context{Session: "work", Path: dir}
+	items, err := p.List(ctx, "things")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Title != "Alpha" || items[0].Meta["n"] != float64(1) {
+		t.Fatalf("items = %+v", items)
+	}
+
+	res, err := p.Select(ctx, "things", items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.SwitchSession != "alpha" {
+		t.Fatalf("select = %+v, want switch to alpha", res)
+	}
+
+	res, err = p.Run(ctx, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Message != "hello from work" {
+		t.Fatalf("run = %+v", res)
+	}
+}
+
+func TestCallReportsStderr(t *testing.T) {
+	dir := t.TempDir()
+	writeExec(t, dir, "kitmux-broken", "#!/bin/sh\necho 'no token' >&2\nexit 3\n")
+	p := Handshake(Discover([]string{dir})[0], Context{})
+	if p.Err == nil || !strings.Contains(p.Err.Error(), "no token") {
+		t.Fatalf("err = %v, want stderr in it", p.Err)
+	}
+}
+
+func TestCallTimesOut(t *testing.T) {
+	t.Setenv("KITMUX_PLUGIN_TIMEOUT", "100ms")
+	dir := t.TempDir()
+	writeExec(t, dir, "kitmux-slow", "#!/bin/sh\nexec sleep 5\n")
+	p := Handshake(Discover([]string{dir})[0], Context{})
+	if p.Err == nil || !strings.Contains(p.Err.Error(), "timed out") {
+		t.Fatalf("err = %v, want a timeout", p.Err)
+	}
+}
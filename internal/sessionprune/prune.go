@@ -0,0 +1,271 @@
+// Package sessionprune finds tmux sessions that have been idle for too long
+// and kills them, logging enough about each one to recreate it later.
+package sessionprune
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+const logFile = "prune.log"
+
+// Skip reasons reported for idle sessions that are kept.
+const (
+	SkipAttached = "attached"
+	SkipAgent    = "agent busy"
+	SkipDirty    = "uncommitted changes"
+)
+
+// Candidate is a session idle past the threshold. Skip is empty when the
+// session is safe to kill, otherwise it names the rule that protects it.
+type Candidate struct {
+	Session tmux.Session
+	Idle    time.Duration
+	Skip    string
+}
+
+// Result summarizes a prune run.
+type Result struct {
+	Killed []string
+	Failed []string
+}
+
+// LogEntry is one line of the prune log.
+type LogEntry struct {
+	KilledAt time.Time   `json:"killed_at"`
+	Name     string      `json:"name"`
+	Path     string      `json:"path"`
+	AgentID  string      `json:"agent_id,omitempty"`
+	Thread   bool        `json:"thread,omitempty"`
+	Idle     string      `json:"idle"`
+	Windows  []LogWindow `json:"windows,omitempty"`
+}
+
+// LogWindow records a window of a killed session.
+type LogWindow struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+}
+
+// Ops are the side effects of a prune: the tmux server, the cached worktree
+// stats, the log file and the clock.
+type Ops struct {
+	Tmux              tmux.Client
+	LoadWorktreeStats func() (map[string]wsdata.WorktreeStat, error)
+	LogPath           func() (string, error)
+	Now               func() time.Time
+}
+
+// DefaultOps prunes the real tmux server.
+func DefaultOps() Ops {
+	return Ops{
+		Tmux:              tmux.Default(),
+		LoadWorktreeStats: wsdata.NewStatsService().LoadCachedByWorktreePath,
+		LogPath:           LogPath,
+		Now:               time.Now,
+	}
+}
+
+// LogPath returns the file that records killed sessions.
+func LogPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "kitmux", logFile), nil
+}
+
+// Plan returns every session idle for at least idleAfter, oldest first.
+// Protected sessions are included with Skip set so a dry run can explain
+// why they stay.
+func Plan(idleAfter time.Duration, ops Ops) ([]Candidate, error) {
+	sessions, err := ops.Tmux.ListSessions()
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	now := ops.Now()
+
+	var idle []Candidate
+	for _, s := range sessions {
+		if s.Activity <= 0 {
+			continue
+		}
+		d := now.Sub(time.Unix(s.Activity, 0))
+		if d < idleAfter {
+			continue
+		}
+		idle = append(idle, Candidate{Session: s, Idle: d})
+	}
+	if len(idle) == 0 {
+		return nil, nil
+	}
+
+	busy := busyAgentSessions(sessions, ops)
+	stats, err := ops.LoadWorktreeStats()
+	if err != nil {
+		return nil, fmt.Errorf("load worktree stats: %w", err)
+	}
+	for i := range idle {
+		idle[i].Skip = skipReason(idle[i].Session, busy, stats)
+	}
+	sort.SliceStable(idle, func(i, j int) bool { return idle[i].Idle > idle[j].Idle })
+	return idle, nil
+}
+
+func skipReason(s tmux.Session, busy map[string]bool, stats map[string]wsdata.WorktreeStat) string {
+	switch {
+	case s.Attached:
+		return SkipAttached
+	case busy[s.Name]:
+		return SkipAgent
+	}
+	if stat, ok := worktreeFor(s.Path, stats); ok && stat.Dirty() {
+		return SkipDirty
+	}
+	return ""
+}
+
+// busyAgentSessions collects sessions whose agent is working or waiting on
+// the user, from the thread session state and from individual panes.
+func busyAgentSessions(sessions []tmux.Session, ops Ops) map[string]bool {
+	busy := make(map[string]bool)
+	for _, s := range sessions {
+		if agentBusy(s.AgentState) {
+			busy[s.Name] = true
+		}
+	}
+	panes, err := ops.Tmux.ListPanes()
+	if err != nil {
+		return busy
+	}
+	for _, p := range panes {
+		if agentBusy(p.AgentState) {
+			busy[p.SessionName] = true
+		}
+	}
+	return busy
+}
+
+func agentBusy(state string) bool {
+	switch state {
+	case "working", "input", "permission":
+		return true
+	default:
+		return false
+	}
+}
+
+// worktreeFor returns the stats of the worktree containing dir, preferring
+// the deepest match so nested worktrees resolve to themselves.
+func worktreeFor(dir string, stats map[string]wsdata.WorktreeStat) (wsdata.WorktreeStat, bool) {
+	if dir == "" {
+		return wsdata.WorktreeStat{}, false
+	}
+	dir = filepath.Clean(dir)
+	var best wsdata.WorktreeStat
+	bestLen := -1
+	for path, stat := range stats {
+		path = filepath.Clean(path)
+		if dir != path && !strings.HasPrefix(dir, path+string(filepath.Separator)) {
+			continue
+		}
+		if len(path) > bestLen {
+			best, bestLen = stat, len(path)
+		}
+	}
+	return best, bestLen >= 0
+}
+
+// Prune kills every candidate without a skip reason and appends one log
+// line per killed session. Failures do not stop the run.
+func Prune(candidates []Candidate, ops Ops) (Result, error) {
+	var res Result
+	var entries []LogEntry
+	for _, c := range candidates {
+		if c.Skip != "" {
+			continue
+		}
+		windows := logWindows(c.Session.Name, ops)
+		if err := ops.Tmux.KillSession(c.Session.Name); err != nil {
+			res.Failed = append(res.Failed, fmt.Sprintf("%s (%v)", c.Session.Name, err))
+			continue
+		}
+		res.Killed = append(res.Killed, c.Session.Name)
+		entries = append(entries, LogEntry{
+			KilledAt: ops.Now(),
+			Name:     c.Session.Name,
+			Path:     c.Session.Path,
+			AgentID:  c.Session.AgentID,
+			Thread:   c.Session.Thread,
+			Idle:     FormatIdle(c.Idle),
+			Windows:  windows,
+		})
+	}
+	if len(entries) == 0 {
+		return res, nil
+	}
+	if err := appendLog(entries, ops); err != nil {
+		return res, fmt.Errorf("write prune log: %w", err)
+	}
+	return res, nil
+}
+
+func logWindows(session string, ops Ops) []LogWindow {
+	windows, err := ops.Tmux.ListWindows(session)
+	if err != nil {
+		return nil
+	}
+	out := make([]LogWindow, 0, len(windows))
+	for _, w := range windows {
+		out = append(out, LogWindow{Index: w.Index, Name: w.Name})
+	}
+	return out
+}
+
+func appendLog(entries []LogEntry, ops Ops) error {
+	path, err := ops.LogPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// FormatIdle renders an idle duration compactly: 45m, 5h, 3d.
+func FormatIdle(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	}
+}
+
+// ParseIdle accepts Go durations plus a "d" suffix for days (e.g. "3d").
+func ParseIdle(value string) (time.Duration, error) {
+	return config.ParseDuration(value)
+}
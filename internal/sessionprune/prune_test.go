@@ -0,0 +1,162 @@
+package sessionprune
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+var testNow = time.Unix(1_700_000_000, 0)
+
+// fakeSession describes a session to create on the fake server.
+type fakeSession struct {
+	name, path string
+	idleHours  int
+	attached   bool
+	options    map[string]string
+}
+
+func testOps(t *testing.T, sessions []fakeSession, stats map[string]wsdata.WorktreeStat) (Ops, *tmuxtest.Server) {
+	t.Helper()
+	srv := tmuxtest.New()
+	srv.SetNow(func() time.Time { return testNow })
+	for _, s := range sessions {
+		srv.AddSession(s.name, s.path)
+		_ = srv.RenameWindow(s.name+":0", "editor")
+		srv.SetActivity(s.name, testNow.Add(-time.Duration(s.idleHours)*time.Hour))
+		for option, value := range s.options {
+			_ = srv.SetSessionOption(s.name, option, value)
+		}
+	}
+	for _, s := range sessions {
+		if s.attached {
+			srv.Attach(s.name)
+		}
+	}
+	logPath := filepath.Join(t.TempDir(), "prune.log")
+	return Ops{
+		Tmux:              srv,
+		LoadWorktreeStats: func() (map[string]wsdata.WorktreeStat, error) { return stats, nil },
+		LogPath:           func() (string, error) { return logPath, nil },
+		Now:               func() time.Time { return testNow },
+	}, srv
+}
+
+func TestPlanSkipsProtectedSessions(t *testing.T) {
+	sessions := []fakeSession{
+		{name: "fresh", path: "/tmp/fresh", idleHours: 1},
+		{name: "old", path: "/tmp/old", idleHours: 100},
+		{name: "attached", path: "/tmp/a", idleHours: 100, attached: true},
+		{name: "thread", path: "/tmp/t", idleHours: 100, options: map[string]string{
+			"@kitmux_thread": "1", "@kitmux_agent_state": "working",
+		}},
+		{name: "waiting", path: "/tmp/w", idleHours: 100},
+		{name: "dirty", path: "/repos/app-feat/src", idleHours: 80},
+	}
+	stats := map[string]wsdata.WorktreeStat{
+		"/repos/app":      {WorktreePath: "/repos/app"},
+		"/repos/app-feat": {WorktreePath: "/repos/app-feat", Modified: true},
+	}
+	ops, srv := testOps(t, sessions, stats)
+	_ = srv.SetPaneOption("waiting:0.0", "@kitmux_agent_state", "permission")
+
+	got, err := Plan(72*time.Hour, ops)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	skips := map[string]string{}
+	for _, c := range got {
+		skips[c.Session.Name] = c.Skip
+	}
+	want := map[string]string{
+		"old":      "",
+		"attached": SkipAttached,
+		"thread":   SkipAgent,
+		"waiting":  SkipAgent,
+		"dirty":    SkipDirty,
+	}
+	if !reflect.DeepEqual(skips, want) {
+		t.Fatalf("skips = %#v, want %#v", skips, want)
+	}
+	if got[len(got)-1].Session.Name != "dirty" {
+		t.Fatalf("expected oldest first, got %q last", got[len(got)-1].Session.Name)
+	}
+}
+
+func TestPruneKillsAndLogs(t *testing.T) {
+	sessions := []fakeSession{
+		{name: "old", path: "/tmp/old", idleHours: 100, options: map[string]string{"@kitmux_agent": "codex"}},
+		{name: "attached", path: "/tmp/a", idleHours: 100, attached: true},
+	}
+	ops, srv := testOps(t, sessions, nil)
+	candidates, err := Plan(72*time.Hour, ops)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+
+	res, err := Prune(candidates, ops)
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if !reflect.DeepEqual(srv.SessionNames(), []string{"attached"}) || !reflect.DeepEqual(res.Killed, []string{"old"}) {
+		t.Fatalf("sessions left = %v, result = %+v", srv.SessionNames(), res)
+	}
+
+	path, _ := ops.LogPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	var entry LogEntry
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(data))), &entry); err != nil {
+		t.Fatalf("parse log: %v", err)
+	}
+	if entry.Name != "old" || entry.Path != "/tmp/old" || entry.AgentID != "codex" || entry.Idle != "4d" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+	if len(entry.Windows) != 1 || entry.Windows[0].Name != "editor" {
+		t.Fatalf("expected windows in log, got %+v", entry.Windows)
+	}
+}
+
+func TestPruneContinuesPastFailures(t *testing.T) {
+	// "a" is already gone from the server, so killing it fails.
+	ops, _ := testOps(t, []fakeSession{{name: "b", path: "/tmp/b"}}, nil)
+	res, err := Prune([]Candidate{
+		{Session: tmux.Session{Name: "a"}},
+		{Session: tmux.Session{Name: "b"}},
+	}, ops)
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if !reflect.DeepEqual(res.Killed, []string{"b"}) || len(res.Failed) != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestParseIdle(t *testing.T) {
+	cases := map[string]time.Duration{
+		"3d":  72 * time.Hour,
+		"12h": 12 * time.Hour,
+		"90m": 90 * time.Minute,
+	}
+	for in, want := range cases {
+		got, err := ParseIdle(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseIdle(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "0d", "-1h", "xd", "soon"} {
+		if _, err := ParseIdle(in); err == nil {
+			t.Fatalf("ParseIdle(%q) should fail", in)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+// Package stack opens a named group of workspaces, such as a frontend, a
+// backend and a shared proto repo, as one unit. Every repo is put on the same
+// branch, creating its worktree when needed, and then gets either a session
+// of its own or a window in one shared session.
+package stack
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+	"github.com/miltonparedes/kitmux/internal/worktree"
+	"github.com/miltonparedes/kitmux/internal/worktreesetup"
+)
+
+// Layouts of an opened stack.
+const (
+	LayoutSessions = "sessions" // one session per repo
+	LayoutWindows  = "windows"  // one session, a window per repo
+)
+
+// NormalizeLayout returns layout when it is known, else LayoutSessions.
+func NormalizeLayout(layout string) string {
+	if strings.TrimSpace(strings.ToLower(layout)) == LayoutWindows {
+		return LayoutWindows
+	}
+	return LayoutSessions
+}
+
+// Member is one repo of the stack as opened: the worktree it landed in and
+// the session it runs in. With LayoutWindows its window is named Name.
+type Member struct {
+	Name      string
+	Workspace string
+	Dir       string
+	Session   string
+	Created   bool // the worktree was created for this open
+}
+
+// Result is an opened stack. Session is the one to switch to.
+type Result struct {
+	Session string
+	Members []Member
+}
+
+// Ops are the git and tmux operations Open needs; DefaultOps runs the real
+// ones.
+type Ops struct {
+	Tmux          tmux.Client
+	ListWorktrees func(dir string) ([]worktree.Worktree, error)
+	Switch        func(dir, branch string) error
+	Remove        func(dir, branch string) error
+	// Setup provisions a freshly created worktree and returns the variables
+	// the session opened on it should start with.
+	Setup func(path string) (map[string]string, error)
+}
+
+// DefaultOps opens stacks with the configured worktree backend on the real
+// tmux server, provisioning new worktrees like the dashboard does.
+func DefaultOps() Ops {
+	return Ops{
+		Tmux:          tmux.Default(),
+		ListWorktrees: worktree.ListInDir,
+		Switch: func(dir, branch string) error {
+			return worktree.SwitchInDir(dir, branch, true)
+		},
+		Remove: worktree.RemoveInDir,
+		Setup: func(path string) (map[string]string, error) {
+			return worktreesetup.Provision(tmux.Default(), path)
+		},
+	}
+}
+
+// Workspace is a member of the stack to open: its registry name and path.
+type Workspace struct {
+	Name string
+	Path string
+}
+
+// Open puts every workspace on branch and opens them with layout. An empty
+// branch keeps each repo on its main worktree. All worktrees are resolved
+// before any session is touched, so a repo that cannot switch leaves tmux
+// as it was, and the worktrees created for the other repos are removed
+// again. New worktrees are provisioned before their session opens; if one
+// fails to provision, every created worktree is removed the same way.
+// Sessions and windows already pointing at a worktree are reused.
+func Open(name string, workspaces []Workspace, branch, layout string, ops Ops) (Result, error) {
+	if len(workspaces) == 0 {
+		return Result{}, fmt.Errorf("stack %q has no workspaces", name)
+	}
+	branch = strings.TrimSpace(branch)
+
+	var res Result
+	var failed []string
+	for _, ws := range workspaces {
+		m, err := resolve(ws, branch, ops)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", ws.Name, err))
+			continue
+		}
+		res.Members = append(res.Members, m)
+	}
+	if len(failed) > 0 {
+		failed = append(failed, rollback(branch, res.Members, ops)...)
+		return Result{}, fmt.Errorf("open stack %q: %s", name, strings.Join(failed, "; "))
+	}
+
+	env := make(map[string]map[string]string)
+	for _, m := range res.Members {
+		if !m.Created {
+			continue
+		}
+		vars, err := ops.Setup(m.Dir)
+		if err != nil {
+			failed = append([]string{fmt.Sprintf("%s: setup: %v", m.Name, err)}, rollback(branch, res.Members, ops)...)
+			return Result{}, fmt.Errorf("open stack %q: %s", name, strings.Join(failed, "; "))
+		}
+		env[m.Dir] = vars
+	}
+
+	var err error
+	if NormalizeLayout(layout) == LayoutWindows {
+		err = openWindows(name, branch, env, &res, ops)
+	} else {
+		err = openSessions(branch, env, &res, ops)
+	}
+	return res, err
+}
+
+// rollback removes the worktrees created for members, returning what could
+// not be removed.
+func rollback(branch string, members []Member, ops Ops) []string {
+	var kept []string
+	for _, m := range members {
+		if !m.Created {
+			continue
+		}
+		if err := ops.Remove(m.Workspace, branch); err != nil {
+			kept = append(kept, fmt.Sprintf("%s: created worktree %s kept: %v", m.Name, m.Dir, err))
+		}
+	}
+	return kept
+}
+
+// resolve finds (or creates) the worktree of ws checked out on branch.
+func resolve(ws Workspace, branch string, ops Ops) (Member, error) {
+	m := Member{Name: ws.Name, Workspace: ws.Path, Dir: ws.Path}
+	if branch == "" {
+		return m, nil
+	}
+	wt, ok, err := findBranch(ws.Path, branch, ops)
+	if err != nil {
+		return m, err
+	}
+	if !ok {
+		if err := ops.Switch(ws.Path, branch); err != nil {
+			return m, err
+		}
+		if wt, ok, err = findBranch(ws.Path, branch, ops); err != nil {
+			return m, err
+		}
+		if !ok {
+			return m, fmt.Errorf("no worktree for %q after switching", branch)
+		}
+		m.Created = true
+	}
+	m.Dir = wt.Path
+	return m, nil
+}
+
+func findBranch(dir, branch string, ops Ops) (worktree.Worktree, bool, error) {
+	wts, err := ops.ListWorktrees(dir)
+	if err != nil {
+		return worktree.Worktree{}, false, err
+	}
+	for _, wt := range wts {
+		if wt.Branch == branch {
+			return wt, true, nil
+		}
+	}
+	return worktree.Worktree{}, false, nil
+}
+
+func openSessions(branch string, env map[string]map[string]string, res *Result, ops Ops) error {
+	existing, _ := ops.Tmux.ListSessions()
+	for i := range res.Members {
+		m := &res.Members[i]
+		for _, s := range existing {
+			if filepath.Clean(s.Path) == filepath.Clean(m.Dir) {
+				m.Session = s.Name
+				break
+			}
+		}
+		if m.Session == "" {
+			m.Session = uniqueName(sessionName(m.Name, branch), ops)
+			if err := ops.Tmux.NewSessionWithEnv(m.Session, m.Dir, env[m.Dir]); err != nil {
+				return fmt.Errorf("%s: tmux new-session: %w", m.Name, err)
+			}
+		}
+	}
+	res.Session = res.Members[0].Session
+	return nil
+}
+
+// openWindows opens one session named after the stack (and branch) with a
+// window per repo named after its workspace. The session carries the setup
+// variables of every new worktree, so each window starts with them. tmux has
+// one environment per session, so when two repos set the same variable, such
+// as PORT, the later member in the stack wins.
+func openWindows(name, branch string, env map[string]map[string]string, res *Result, ops Ops) error {
+	vars := make(map[string]string)
+	for _, m := range res.Members { // in stack order, so later repos win
+		for k, v := range env[m.Dir] {
+			vars[k] = v
+		}
+	}
+	session := sessionName(name, branch)
+	fresh := !ops.Tmux.HasSession(session)
+	if fresh {
+		if err := ops.Tmux.NewSessionWithEnv(session, res.Members[0].Dir, vars); err != nil {
+			return fmt.Errorf("tmux new-session: %w", err)
+		}
+		_ = ops.Tmux.RenameWindow(session+":", res.Members[0].Name)
+	} else {
+		for k, v := range vars {
+			if err := ops.Tmux.SetEnvironment(session, k, v); err != nil {
+				return fmt.Errorf("tmux set-environment: %w", err)
+			}
+		}
+	}
+	windows := map[string]bool{}
+	if fresh {
+		windows[res.Members[0].Name] = true
+	} else if list, err := ops.Tmux.ListWindows(session); err == nil {
+		for _, w := range list {
+			windows[w.Name] = true
+		}
+	}
+	for i := range res.Members {
+		m := &res.Members[i]
+		m.Session = session
+		if windows[m.Name] {
+			continue
+		}
+		if err := ops.Tmux.NewWindowInSession(session, m.Name, m.Dir, ""); err != nil {
+			return fmt.Errorf("%s: tmux new-window: %w", m.Name, err)
+		}
+		windows[m.Name] = true
+	}
+	res.Session = session
+	return nil
+}
+
+func sessionName(base, branch string) string {
+	if branch == "" {
+		return base
+	}
+	return base + "-" + strings.ReplaceAll(branch, "/", "-")
+}
+
+func uniqueName(name string, ops Ops) string {
+	if !ops.Tmux.HasSession(name) {
+		return name
+	}
+	for i := 2; i <= 99; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !ops.Tmux.HasSession(candidate) {
+			return candidate
+		}
+	}
+	return name
+}
+
+// Summary is the combined state of the worktrees of a stack's repos.
+type Summary struct {
+	Repos   int
+	Dirty   int // worktrees with staged, modified or untracked files
+	Ahead   int
+	Behind  int
+	Added   int
+	Deleted int
+}
+
+// Summarize adds up the cached stats of the worktrees the stack opens on
+// branch: in each workspace the worktree checked out on it, or the main
+// worktree when branch is empty. Repos without that worktree add nothing.
+func Summarize(paths []string, branch string, stats map[string]wsdata.WorkspaceStats) Summary {
+	branch = strings.TrimSpace(branch)
+	s := Summary{Repos: len(paths)}
+	for _, p := range paths {
+		for _, wt := range stats[p].Worktrees {
+			if branch == "" && !wt.IsMain || branch != "" && wt.Branch != branch {
+				continue
+			}
+			if wt.Dirty() {
+				s.Dirty++
+			}
+			s.Ahead += wt.Ahead
+			s.Behind += wt.Behind
+			s.Added += wt.Added
+			s.Deleted += wt.Deleted
+		}
+	}
+	return s
+}
+
+// String renders the summary as "3 repos  2 dirty  ↑4 ↓1  +10 -3", leaving
+// out zero counts.
+func (s Summary) String() string {
+	parts := []string{fmt.Sprintf("%d repos", s.Repos)}
+	if s.Dirty > 0 {
+		parts = append(parts, fmt.Sprintf("%d dirty", s.Dirty))
+	}
+	var sync []string
+	if s.Ahead > 0 {
+		sync = append(sync, fmt.Sprintf("↑%d", s.Ahead))
+	}
+	if s.Behind > 0 {
+		sync = append(sync, fmt.Sprintf("↓%d", s.Behind))
+	}
+	if len(sync) > 0 {
+		parts = append(parts, strings.Join(sync, " "))
+	}
+	if s.Added > 0 || s.Deleted > 0 {
+		parts = append(parts, fmt.Sprintf("+%d -%d", s.Added, s.Deleted))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// Members pairs the stack's workspace paths with their registry names.
+// Paths that are no longer registered are returned in missing.
+func Members(s wsreg.Stack, registry []wsreg.Workspace) (members []Workspace, missing []string) {
+	names := make(map[string]string, len(registry))
+	for _, w := range registry {
+		names[w.Path] = w.Name
+	}
+	for _, p := range s.Workspaces {
+		name, ok := names[p]
+		if !ok {
+			missing = append(missing, p)
+			continue
+		}
+		members = append(members, Workspace{Name: name, Path: p})
+	}
+	return members, missing
+}
@@ -0,0 +1,203 @@
+package stack
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+	"github.com/miltonparedes/kitmux/internal/worktree"
+)
+
+// fakeOps keeps worktrees per repo on top of a fake tmux server.
+type fakeOps struct {
+	srv       *tmuxtest.Server
+	worktrees map[string][]worktree.Worktree
+	env       map[string]map[string]string // setup variables by worktree path
+	broken    map[string]error             // setup failures by worktree path
+	removed   []string
+	provision []string
+}
+
+func (f *fakeOps) ops() Ops {
+	return Ops{
+		Tmux:          f.srv,
+		ListWorktrees: func(dir string) ([]worktree.Worktree, error) { return f.worktrees[dir], nil },
+		Switch: func(dir, branch string) error {
+			if dir == "/src/broken" {
+				return errors.New("not a git repository")
+			}
+			f.worktrees[dir] = append(f.worktrees[dir], worktree.Worktree{Branch: branch, Path: dir + "." + branch})
+			return nil
+		},
+		Remove: func(dir, branch string) error {
+			f.removed = append(f.removed, dir+" "+branch)
+			return nil
+		},
+		Setup: func(path string) (map[string]string, error) {
+			f.provision = append(f.provision, path)
+			return f.env[path], f.broken[path]
+		},
+	}
+}
+
+func newFake() *fakeOps {
+	return &fakeOps{
+		srv: tmuxtest.New(),
+		worktrees: map[string][]worktree.Worktree{
+			"/src/web":   {{Branch: "main", Path: "/src/web", IsMain: true}},
+			"/src/api":   {{Branch: "main", Path: "/src/api", IsMain: true}, {Branch: "feat/pay", Path: "/src/api.pay"}},
+			"/src/proto": {{Branch: "main", Path: "/src/proto", IsMain: true}},
+		},
+	}
+}
+
+// tmuxCalls lists the calls that changed the fake server.
+func (f *fakeOps) tmuxCalls() []string {
+	var out []string
+	for _, call := range f.srv.Calls() {
+		if !strings.HasPrefix(call, "list-") && !strings.HasPrefix(call, "has-") {
+			out = append(out, call)
+		}
+	}
+	return out
+}
+
+var checkout = []Workspace{{Name: "web", Path: "/src/web"}, {Name: "api", Path: "/src/api"}, {Name: "proto", Path: "/src/proto"}}
+
+func TestOpenSessionsPutsEveryRepoOnTheBranch(t *testing.T) {
+	f := newFake()
+	f.srv.AddSession("api-pay", "/src/api.pay")
+	f.env = map[string]map[string]string{"/src/web.feat/pay": {"PORT": "3001"}}
+
+	res, err := Open("checkout", checkout, "feat/pay", LayoutSessions, f.ops())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	var got []string
+	for _, m := range res.Members {
+		got = append(got, m.Session+"@"+m.Dir)
+	}
+	want := "web-feat-pay@/src/web.feat/pay,api-pay@/src/api.pay,proto-feat-pay@/src/proto.feat/pay"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("members = %v", got)
+	}
+	if !res.Members[0].Created || res.Members[1].Created || res.Session != "web-feat-pay" {
+		t.Fatalf("result = %+v", res)
+	}
+	if strings.Join(f.provision, ",") != "/src/web.feat/pay,/src/proto.feat/pay" {
+		t.Fatalf("provisioned %v, want only the created worktrees", f.provision)
+	}
+	if got := f.srv.Environment("web-feat-pay", "PORT"); got != "3001" {
+		t.Fatalf("PORT = %q, want the setup variables in the new session", got)
+	}
+}
+
+func TestOpenWindowsSharesOneSession(t *testing.T) {
+	f := newFake()
+	res, err := Open("checkout", checkout, "", LayoutWindows, f.ops())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	want := []string{
+		"new-session checkout /src/web",
+		"rename-window checkout: web",
+		"new-window checkout api /src/api",
+		"new-window checkout proto /src/proto",
+	}
+	if got := f.tmuxCalls(); strings.Join(got, "\n") != strings.Join(want, "\n") || res.Session != "checkout" {
+		t.Fatalf("calls = %q", got)
+	}
+
+	// Reopening only adds the windows that are missing.
+	f.srv = tmuxtest.New()
+	f.srv.AddSession("checkout", "/src/web")
+	_ = f.srv.RenameWindow("checkout:0", "web")
+	_ = f.srv.NewWindowInSession("checkout", "api", "/src/api", "")
+	before := len(f.tmuxCalls())
+	if _, err := Open("checkout", checkout, "", LayoutWindows, f.ops()); err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	if got := f.tmuxCalls()[before:]; strings.Join(got, "\n") != "new-window checkout proto /src/proto" {
+		t.Fatalf("reopen calls = %q", got)
+	}
+}
+
+func TestOpenLeavesTmuxAloneWhenARepoFails(t *testing.T) {
+	f := newFake()
+	broken := append(checkout[:1:1], Workspace{Name: "broken", Path: "/src/broken"})
+	_, err := Open("checkout", broken, "feat/x", LayoutSessions, f.ops())
+	if err == nil || !strings.Contains(err.Error(), "broken: not a git repository") {
+		t.Fatalf("err = %v", err)
+	}
+	if got := f.tmuxCalls(); len(got) != 0 {
+		t.Fatalf("tmux touched after a failure: %q", got)
+	}
+	if strings.Join(f.removed, ",") != "/src/web feat/x" || len(f.provision) != 0 {
+		t.Fatalf("removed %v, provisioned %v; want the created worktree rolled back", f.removed, f.provision)
+	}
+}
+
+func TestOpenRollsBackWhenSetupFails(t *testing.T) {
+	f := newFake()
+	f.broken = map[string]error{"/src/proto.feat/pay": errors.New("npm install failed")}
+	_, err := Open("checkout", checkout, "feat/pay", LayoutSessions, f.ops())
+	if err == nil || !strings.Contains(err.Error(), "proto: setup: npm install failed") {
+		t.Fatalf("err = %v", err)
+	}
+	if got := f.tmuxCalls(); len(got) != 0 {
+		t.Fatalf("tmux touched after a failed setup: %q", got)
+	}
+	if strings.Join(f.removed, ",") != "/src/web feat/pay,/src/proto feat/pay" {
+		t.Fatalf("removed %v, want both created worktrees rolled back", f.removed)
+	}
+}
+
+func TestOpenReportsWorktreesItCouldNotRollBack(t *testing.T) {
+	f := newFake()
+	f.broken = map[string]error{"/src/web.feat/pay": errors.New("exit 1")}
+	ops := f.ops()
+	ops.Remove = func(dir, branch string) error { return errors.New("locked") }
+	_, err := Open("checkout", checkout, "feat/pay", LayoutSessions, ops)
+	if err == nil || !strings.Contains(err.Error(), "web: created worktree /src/web.feat/pay kept: locked") {
+		t.Fatalf("err = %v, want the kept worktree named", err)
+	}
+}
+
+func TestOpenWindowsLastRepoWinsForSharedVariables(t *testing.T) {
+	f := newFake()
+	f.env = map[string]map[string]string{
+		"/src/web.feat/pay":   {"PORT": "3001", "WEB": "1"},
+		"/src/proto.feat/pay": {"PORT": "3002"},
+	}
+	if _, err := Open("checkout", checkout, "feat/pay", LayoutWindows, f.ops()); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got := f.srv.Environment("checkout-feat-pay", "PORT"); got != "3002" {
+		t.Fatalf("PORT = %q, want the later repo's value", got)
+	}
+	if got := f.srv.Environment("checkout-feat-pay", "WEB"); got != "1" {
+		t.Fatalf("WEB = %q, want variables without a clash kept", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	stats := map[string]wsdata.WorkspaceStats{
+		"/src/web": {Worktrees: []wsdata.WorktreeStat{
+			{Branch: "main", IsMain: true, Modified: true, Ahead: 2, Added: 10, Deleted: 3},
+			{Branch: "feat/pay", Ahead: 5},
+		}},
+		"/src/api": {Worktrees: []wsdata.WorktreeStat{
+			{Branch: "main", IsMain: true, Ahead: 2, Behind: 1},
+			{Branch: "feat/pay", Untracked: true},
+		}},
+	}
+	paths := []string{"/src/web", "/src/api", "/src/proto"}
+	if got := Summarize(paths, "", stats).String(); got != "3 repos  1 dirty  ↑4 ↓1  +10 -3" {
+		t.Fatalf("main summary = %q", got)
+	}
+	if got := Summarize(paths, "feat/pay", stats).String(); got != "3 repos  1 dirty  ↑5" {
+		t.Fatalf("branch summary = %q", got)
+	}
+}
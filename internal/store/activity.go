@@ -0,0 +1,138 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ActivityEvent is a state change of one activity source: a tmux client
+// focusing a session (Kind "focus") or an agent changing state (Kind
+// "agent"). Source identifies the client or pane; the event lasts until the
+// next event of the same source.
+type ActivityEvent struct {
+	At        time.Time
+	Kind      string
+	Source    string
+	Workspace string
+	Branch    string
+	Agent     string
+	State     string
+}
+
+// AppendActivityEvent records an activity event.
+func AppendActivityEvent(e ActivityEvent) error {
+	db, err := open()
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(`INSERT INTO activity_events(at, kind, source, workspace_path, branch, agent, state)
+		VALUES(?, ?, ?, ?, ?, ?, ?)`,
+		e.At.UnixMilli(), e.Kind, e.Source, e.Workspace, e.Branch, e.Agent, e.State); err != nil {
+		return fmt.Errorf("append activity event: %w", err)
+	}
+	return nil
+}
+
+// AppendActivityChange records e unless the latest event of its source
+// already says the same, and reports whether it did. The check and the
+// insert are one statement, so hooks firing together for one source cannot
+// both record the change.
+func AppendActivityChange(e ActivityEvent) (bool, error) {
+	db, err := open()
+	if err != nil {
+		return false, err
+	}
+	res, err := db.Exec(`INSERT INTO activity_events(at, kind, source, workspace_path, branch, agent, state)
+		SELECT ?, ?, ?, ?, ?, ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM (
+			SELECT workspace_path, branch, agent, state FROM activity_events
+			WHERE kind = ? AND source = ? ORDER BY at DESC, id DESC LIMIT 1) last
+			WHERE last.workspace_path = ? AND last.branch = ? AND last.agent = ? AND last.state = ?)`,
+		e.At.UnixMilli(), e.Kind, e.Source, e.Workspace, e.Branch, e.Agent, e.State,
+		e.Kind, e.Source, e.Workspace, e.Branch, e.Agent, e.State)
+	if err != nil {
+		return false, fmt.Errorf("append activity change: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("append activity change: %w", err)
+	}
+	return n > 0, nil
+}
+
+// PruneActivityEvents deletes the events before cutoff, except the latest
+// one of each source, which still tells where a span running at cutoff
+// belongs.
+func PruneActivityEvents(cutoff time.Time) error {
+	db, err := open()
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(`DELETE FROM activity_events WHERE at < ?
+		AND id NOT IN (SELECT MAX(id) FROM activity_events GROUP BY kind, source)`, cutoff.UnixMilli()); err != nil {
+		return fmt.Errorf("prune activity events: %w", err)
+	}
+	return nil
+}
+
+// LastActivityEvent returns the latest event of a source.
+func LastActivityEvent(kind, source string) (ActivityEvent, bool, error) {
+	db, err := open()
+	if err != nil {
+		return ActivityEvent{}, false, err
+	}
+	row := db.QueryRow(`SELECT at, kind, source, workspace_path, branch, agent, state
+		FROM activity_events WHERE kind = ? AND source = ? ORDER BY at DESC, id DESC LIMIT 1`, kind, source)
+	e, err := scanActivityEvent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ActivityEvent{}, false, nil
+	}
+	if err != nil {
+		return ActivityEvent{}, false, fmt.Errorf("last activity event: %w", err)
+	}
+	return e, true, nil
+}
+
+// LoadActivityEvents returns the events at or after since in time order,
+// preceded by the last earlier event of each source so that a span already
+// running at since is not lost.
+func LoadActivityEvents(since time.Time) ([]ActivityEvent, error) {
+	db, err := open()
+	if err != nil {
+		return nil, err
+	}
+	ms := since.UnixMilli()
+	rows, err := db.Query(`SELECT at, kind, source, workspace_path, branch, agent, state FROM activity_events
+		WHERE at >= ?
+		OR id IN (SELECT MAX(id) FROM activity_events WHERE at < ? GROUP BY kind, source)
+		ORDER BY at, id`, ms, ms)
+	if err != nil {
+		return nil, fmt.Errorf("query activity events: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var events []ActivityEvent
+	for rows.Next() {
+		e, err := scanActivityEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan activity event: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate activity events: %w", err)
+	}
+	return events, nil
+}
+
+func scanActivityEvent(row rowScanner) (ActivityEvent, error) {
+	var e ActivityEvent
+	var at int64
+	if err := row.Scan(&at, &e.Kind, &e.Source, &e.Workspace, &e.Branch, &e.Agent, &e.State); err != nil {
+		return ActivityEvent{}, err
+	}
+	e.At = time.UnixMilli(at)
+	return e, nil
+}
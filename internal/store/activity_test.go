@@ -0,0 +1,89 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadActivityEventsKeepsRunningSpans(t *testing.T) {
+	useTempHome(t)
+
+	base := time.UnixMilli(1_700_000_000_000)
+	events := []ActivityEvent{
+		{At: base, Kind: "focus", Source: "/dev/ttys001", Workspace: "/src/old", State: "focused"},
+		{At: base.Add(time.Hour), Kind: "focus", Source: "/dev/ttys001", Workspace: "/src/web", Branch: "main", State: "focused"},
+		{At: base.Add(2 * time.Hour), Kind: "agent", Source: "%3", Workspace: "/src/api", Agent: "claude", State: "working"},
+		{At: base.Add(3 * time.Hour), Kind: "focus", Source: "/dev/ttys001", State: "blurred"},
+	}
+	for _, e := range events {
+		if err := AppendActivityEvent(e); err != nil {
+			t.Fatalf("AppendActivityEvent: %v", err)
+		}
+	}
+
+	got, err := LoadActivityEvents(base.Add(90 * time.Minute))
+	if err != nil {
+		t.Fatalf("LoadActivityEvents: %v", err)
+	}
+	if len(got) != 3 || got[0].Workspace != "/src/web" || got[1].Agent != "claude" || got[2].State != "blurred" {
+		t.Fatalf("events = %+v", got)
+	}
+
+	last, ok, err := LastActivityEvent("focus", "/dev/ttys001")
+	if err != nil || !ok || last.State != "blurred" || !last.At.Equal(base.Add(3*time.Hour)) {
+		t.Fatalf("LastActivityEvent = %+v, %v, %v", last, ok, err)
+	}
+	if _, ok, _ := LastActivityEvent("agent", "%9"); ok {
+		t.Fatal("expected no event for an unknown source")
+	}
+}
+
+func TestAppendActivityChangeDropsRepeats(t *testing.T) {
+	useTempHome(t)
+
+	base := time.UnixMilli(1_700_000_000_000)
+	focus := ActivityEvent{At: base, Kind: "focus", Source: "/dev/ttys001", Workspace: "/src/web", State: "focused"}
+	other := focus
+	other.Source = "/dev/ttys002"
+	for i, tc := range []struct {
+		e    ActivityEvent
+		want bool
+	}{
+		{focus, true},
+		{focus, false},
+		{other, true},
+		{ActivityEvent{At: base.Add(time.Minute), Kind: "focus", Source: "/dev/ttys001", State: "blurred"}, true},
+		{focus, true},
+	} {
+		got, err := AppendActivityChange(tc.e)
+		if err != nil || got != tc.want {
+			t.Fatalf("append %d = %v, %v; want %v", i, got, err, tc.want)
+		}
+	}
+}
+
+func TestPruneActivityEventsKeepsLatestPerSource(t *testing.T) {
+	useTempHome(t)
+
+	base := time.UnixMilli(1_700_000_000_000)
+	for _, e := range []ActivityEvent{
+		{At: base, Kind: "focus", Source: "/dev/ttys001", Workspace: "/src/old", State: "focused"},
+		{At: base.Add(time.Hour), Kind: "focus", Source: "/dev/ttys001", Workspace: "/src/web", State: "focused"},
+		{At: base.Add(2 * time.Hour), Kind: "agent", Source: "%3", Agent: "claude", State: "working"},
+		{At: base.Add(5 * time.Hour), Kind: "agent", Source: "%3", Agent: "claude", State: "idle"},
+	} {
+		if err := AppendActivityEvent(e); err != nil {
+			t.Fatalf("AppendActivityEvent: %v", err)
+		}
+	}
+	if err := PruneActivityEvents(base.Add(4 * time.Hour)); err != nil {
+		t.Fatalf("PruneActivityEvents: %v", err)
+	}
+	got, err := LoadActivityEvents(time.UnixMilli(0))
+	if err != nil {
+		t.Fatalf("LoadActivityEvents: %v", err)
+	}
+	if len(got) != 2 || got[0].Workspace != "/src/web" || got[1].State != "idle" {
+		t.Fatalf("events = %+v", got)
+	}
+}
@@ -21,7 +21,7 @@ type migration func(tx *sql.Tx) error
 
 // migrations is the ordered list of schema migrations.
 // The schema version equals len(migrations) — adding a new entry auto-bumps it.
-var migrations = []migration{migrateV1, migrateV2, migrateV3, migrateV4, migrateV5}
+var migrations = []migration{migrateV1, migrateV2, migrateV3, migrateV4, migrateV5, migrateV6, migrateV7, migrateV8, migrateV9}
 
 func schemaVersion() int { return len(migrations) }
 
@@ -220,6 +220,92 @@ func migrateV5(tx *sql.Tx) error {
 	return nil
 }
 
+// migrateV6 adds workspace pinning, free-form workspace tags and named tag
+// filter presets for the dashboard.
+func migrateV6(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE workspaces ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0;`,
+		`CREATE TABLE workspace_tags (
+			workspace_path TEXT NOT NULL,
+			tag TEXT NOT NULL,
+			PRIMARY KEY (workspace_path, tag)
+		);`,
+		`CREATE INDEX idx_workspace_tags_tag ON workspace_tags(tag);`,
+		`CREATE TABLE workspace_filters (
+			name TEXT PRIMARY KEY,
+			tags TEXT NOT NULL,
+			created_at INTEGER NOT NULL
+		);`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("v6: %w", err)
+		}
+	}
+	return nil
+}
+
+// migrateV7 creates the tables holding named workspace stacks and their members.
+func migrateV7(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE workspace_stacks (
+			name TEXT PRIMARY KEY,
+			layout TEXT NOT NULL DEFAULT '',
+			created_at INTEGER NOT NULL
+		);`,
+		`CREATE TABLE workspace_stack_members (
+			stack_name TEXT NOT NULL,
+			workspace_path TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			PRIMARY KEY (stack_name, workspace_path)
+		);`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("v7: %w", err)
+		}
+	}
+	return nil
+}
+
+// migrateV8 creates the activity_events log behind the activity feed.
+func migrateV8(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE activity_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			at INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			source TEXT NOT NULL,
+			workspace_path TEXT NOT NULL DEFAULT '',
+			branch TEXT NOT NULL DEFAULT '',
+			agent TEXT NOT NULL DEFAULT '',
+			state TEXT NOT NULL DEFAULT ''
+		);`,
+		`CREATE INDEX idx_activity_events_at ON activity_events(at);`,
+		`CREATE INDEX idx_activity_events_source ON activity_events(kind, source, at);`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("v8: %w", err)
+		}
+	}
+	return nil
+}
+
+// migrateV9 creates the frecency table that ranks sessions, commands and other picks.
+func migrateV9(tx *sql.Tx) error {
+	if _, err := tx.Exec(`CREATE TABLE frecency (
+		kind TEXT NOT NULL,
+		key TEXT NOT NULL,
+		score REAL NOT NULL,
+		updated_at INTEGER NOT NULL,
+		PRIMARY KEY (kind, key)
+	);`); err != nil {
+		return fmt.Errorf("v9: %w", err)
+	}
+	return nil
+}
+
 func migrateV3(tx *sql.Tx) error {
 	stmts := []string{
 		`CREATE TABLE workspace_stats (
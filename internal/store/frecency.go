@@ -0,0 +1,118 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	legacyRecencyFile = "recency.json"
+
+	// FrecencyCommand is the kind legacy palette recency is imported as.
+	FrecencyCommand = "command"
+)
+
+// FrecencyEntry is a usage score as of UpdatedAt. Callers decay it to the
+// present before comparing or adding to it.
+type FrecencyEntry struct {
+	Score     float64
+	UpdatedAt time.Time
+}
+
+// LoadFrecency returns the entries of one kind (commands, sessions, ...)
+// keyed by item.
+func LoadFrecency(kind string) (map[string]FrecencyEntry, error) {
+	db, err := open()
+	if err != nil {
+		return nil, err
+	}
+	if err := importLegacyRecency(db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT key, score, updated_at FROM frecency WHERE kind = ?`, kind)
+	if err != nil {
+		return nil, fmt.Errorf("query frecency: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make(map[string]FrecencyEntry)
+	for rows.Next() {
+		var key string
+		var e FrecencyEntry
+		var updatedAt int64
+		if err := rows.Scan(&key, &e.Score, &updatedAt); err != nil {
+			return nil, fmt.Errorf("scan frecency: %w", err)
+		}
+		e.UpdatedAt = time.UnixMilli(updatedAt)
+		out[key] = e
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate frecency: %w", err)
+	}
+	return out, nil
+}
+
+// SaveFrecency stores the entry of one item.
+func SaveFrecency(kind, key string, e FrecencyEntry) error {
+	db, err := open()
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(`INSERT INTO frecency(kind, key, score, updated_at) VALUES(?, ?, ?, ?)
+		ON CONFLICT(kind, key) DO UPDATE SET score = excluded.score, updated_at = excluded.updated_at`,
+		kind, key, e.Score, e.UpdatedAt.UnixMilli()); err != nil {
+		return fmt.Errorf("save frecency %s/%q: %w", kind, key, err)
+	}
+	return nil
+}
+
+// importLegacyRecency turns the last-used timestamps of recency.json into
+// single uses at that time.
+func importLegacyRecency(db *sql.DB) error {
+	empty, err := tableEmpty(db, tableFrecency)
+	if err != nil || !empty {
+		return err
+	}
+
+	data, err := os.ReadFile(legacyRecencyPath()) //nolint:gosec // path derives from the user's home dir
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read legacy recency: %w", err)
+	}
+
+	var payload struct {
+		Commands map[string]time.Time `json:"commands"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil || len(payload.Commands) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin import legacy recency: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for id, at := range payload.Commands {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO frecency(kind, key, score, updated_at) VALUES(?, ?, 1, ?)`,
+			FrecencyCommand, id, at.UnixMilli()); err != nil {
+			return fmt.Errorf("import legacy recency %q: %w", id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit import legacy recency: %w", err)
+	}
+	return nil
+}
+
+func legacyRecencyPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, configDir, legacyRecencyFile)
+}
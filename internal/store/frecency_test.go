@@ -0,0 +1,34 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFrecencyImportsLegacyRecency(t *testing.T) {
+	home := useTempHome(t)
+	writeFile(t, filepath.Join(home, configDir, legacyRecencyFile),
+		[]byte(`{"commands":{"switch_session":"2026-10-01T09:00:00Z"}}`))
+
+	commands, err := LoadFrecency(FrecencyCommand)
+	if err != nil {
+		t.Fatalf("LoadFrecency: %v", err)
+	}
+	want := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	if e := commands["switch_session"]; e.Score != 1 || !e.UpdatedAt.Equal(want) {
+		t.Fatalf("commands = %+v", commands)
+	}
+
+	at := want.Add(time.Hour)
+	if err := SaveFrecency("session", "kitmux", FrecencyEntry{Score: 2.5, UpdatedAt: at}); err != nil {
+		t.Fatalf("SaveFrecency: %v", err)
+	}
+	if err := SaveFrecency("session", "kitmux", FrecencyEntry{Score: 3, UpdatedAt: at}); err != nil {
+		t.Fatalf("SaveFrecency: %v", err)
+	}
+	sessions, _ := LoadFrecency("session")
+	if len(sessions) != 1 || sessions["kitmux"].Score != 3 {
+		t.Fatalf("sessions = %+v", sessions)
+	}
+}
@@ -21,6 +21,7 @@ const (
 	tableSessionSnapshots  = "session_snapshots"
 	tableRepoRoots         = "repo_roots"
 	tableWorktreeStats     = "worktree_stats"
+	tableFrecency          = "frecency"
 )
 
 // DiffStat is the persisted worktree stat record.
@@ -397,6 +398,8 @@ func tableCountQuery(table string) string {
 		return `SELECT COUNT(*) FROM repo_roots`
 	case tableWorktreeStats:
 		return `SELECT COUNT(*) FROM worktree_stats`
+	case tableFrecency:
+		return `SELECT COUNT(*) FROM frecency`
 	default:
 		return ""
 	}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WorkspaceStack is a named group of workspaces opened together. Layout is
+// how the stack is opened by default; Workspaces are paths in opening order.
+type WorkspaceStack struct {
+	Name       string
+	Layout     string
+	Workspaces []string
+}
+
+// LoadWorkspaceStacks returns the saved stacks ordered by name.
+func LoadWorkspaceStacks() ([]WorkspaceStack, error) {
+	db, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT s.name, s.layout, m.workspace_path
+		FROM workspace_stacks s
+		LEFT JOIN workspace_stack_members m ON m.stack_name = s.name
+		ORDER BY s.name, m.position`)
+	if err != nil {
+		return nil, fmt.Errorf("query workspace stacks: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var stacks []WorkspaceStack
+	for rows.Next() {
+		var name, layout string
+		var path *string
+		if err := rows.Scan(&name, &layout, &path); err != nil {
+			return nil, fmt.Errorf("scan workspace stack: %w", err)
+		}
+		if len(stacks) == 0 || stacks[len(stacks)-1].Name != name {
+			stacks = append(stacks, WorkspaceStack{Name: name, Layout: layout})
+		}
+		if path != nil {
+			last := &stacks[len(stacks)-1]
+			last.Workspaces = append(last.Workspaces, *path)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate workspace stacks: %w", err)
+	}
+	return stacks, nil
+}
+
+// SaveWorkspaceStack creates or replaces the stack with the given name.
+func SaveWorkspaceStack(stack WorkspaceStack) error {
+	name := strings.TrimSpace(stack.Name)
+	if name == "" || len(stack.Workspaces) == 0 {
+		return fmt.Errorf("save workspace stack: name and workspaces are required")
+	}
+	db, err := open()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin save workspace stack: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`INSERT INTO workspace_stacks(name, layout, created_at) VALUES(?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET layout = excluded.layout`,
+		name, stack.Layout, time.Now().UnixNano()); err != nil {
+		return fmt.Errorf("save workspace stack %q: %w", name, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM workspace_stack_members WHERE stack_name = ?`, name); err != nil {
+		return fmt.Errorf("clear workspace stack %q: %w", name, err)
+	}
+	for i, path := range stack.Workspaces {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO workspace_stack_members(stack_name, workspace_path, position) VALUES(?, ?, ?)`,
+			name, path, i); err != nil {
+			return fmt.Errorf("insert workspace stack member %q/%q: %w", name, path, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteWorkspaceStack removes a stack. The workspaces stay registered.
+func DeleteWorkspaceStack(name string) error {
+	db, err := open()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin delete workspace stack: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM workspace_stack_members WHERE stack_name = ?`, name); err != nil {
+		return fmt.Errorf("delete workspace stack members %q: %w", name, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM workspace_stacks WHERE name = ?`, name); err != nil {
+		return fmt.Errorf("delete workspace stack %q: %w", name, err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWorkspaceStacksRoundTrip(t *testing.T) {
+	useTempHome(t)
+
+	stack := WorkspaceStack{Name: "checkout", Layout: "windows", Workspaces: []string{"/tmp/web", "/tmp/api", "/tmp/proto"}}
+	if err := SaveWorkspaceStack(stack); err != nil {
+		t.Fatalf("SaveWorkspaceStack: %v", err)
+	}
+	if err := SaveWorkspaceStack(WorkspaceStack{Name: "infra", Workspaces: []string{"/tmp/ops"}}); err != nil {
+		t.Fatalf("SaveWorkspaceStack: %v", err)
+	}
+	stacks, err := LoadWorkspaceStacks()
+	if err != nil {
+		t.Fatalf("LoadWorkspaceStacks: %v", err)
+	}
+	if len(stacks) != 2 || !reflect.DeepEqual(stacks[0], stack) {
+		t.Fatalf("stacks = %+v", stacks)
+	}
+
+	// Saving again replaces the members and keeps their new order.
+	stack.Workspaces = []string{"/tmp/api", "/tmp/web"}
+	if err := SaveWorkspaceStack(stack); err != nil {
+		t.Fatalf("SaveWorkspaceStack: %v", err)
+	}
+	if err := DeleteWorkspaceStack("infra"); err != nil {
+		t.Fatalf("DeleteWorkspaceStack: %v", err)
+	}
+	stacks, _ = LoadWorkspaceStacks()
+	if len(stacks) != 1 || !reflect.DeepEqual(stacks[0].Workspaces, stack.Workspaces) {
+		t.Fatalf("stacks = %+v", stacks)
+	}
+
+	if err := SaveWorkspaceStack(WorkspaceStack{Name: "empty"}); err == nil {
+		t.Fatal("expected an error for a stack without workspaces")
+	}
+}
@@ -125,6 +125,49 @@ func ReplaceWorkspaceStats(workspacePath string, stats []WorktreeStat, refreshed
 	return nil
 }
 
+// UpsertWorktreeStat replaces the cached row for one worktree, leaving the
+// rest of the workspace and its last_stats_refresh untouched.
+func UpsertWorktreeStat(stat WorktreeStat, refreshedAt time.Time) error {
+	db, err := open()
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`INSERT INTO workspace_stats(
+		workspace_path, branch, worktree_path, added, deleted,
+		staged, modified, untracked, ahead, behind, is_main,
+		commit_sha, commit_ts, updated_at
+		) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(workspace_path, worktree_path) DO UPDATE SET
+		branch = excluded.branch, added = excluded.added, deleted = excluded.deleted,
+		staged = excluded.staged, modified = excluded.modified, untracked = excluded.untracked,
+		ahead = excluded.ahead, behind = excluded.behind, is_main = excluded.is_main,
+		commit_sha = excluded.commit_sha, commit_ts = excluded.commit_ts,
+		updated_at = excluded.updated_at`,
+		stat.WorkspacePath, stat.Branch, stat.WorktreePath, stat.Added, stat.Deleted,
+		boolToInt(stat.Staged), boolToInt(stat.Modified), boolToInt(stat.Untracked),
+		stat.Ahead, stat.Behind, boolToInt(stat.IsMain),
+		stat.CommitSHA, stat.CommitTS, refreshedAt.UnixNano(),
+	); err != nil {
+		return fmt.Errorf("upsert workspace stat %q/%q: %w", stat.WorkspacePath, stat.WorktreePath, err)
+	}
+	return nil
+}
+
+// DeleteWorktreeStat removes the cached row for one worktree.
+func DeleteWorktreeStat(workspacePath, worktreePath string) error {
+	db, err := open()
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`DELETE FROM workspace_stats WHERE workspace_path = ? AND worktree_path = ?`,
+		workspacePath, worktreePath); err != nil {
+		return fmt.Errorf("delete workspace stat: %w", err)
+	}
+	return nil
+}
+
 // LoadWorkspaceMeta returns meta for a single workspace, or zero-value if
 // not present.
 func LoadWorkspaceMeta(workspacePath string) (WorkspaceMeta, error) {
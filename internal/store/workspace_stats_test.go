@@ -135,3 +135,47 @@ func TestTouchWorkspaceOpenedUpdatesMeta(t *testing.T) {
 		t.Error("expected LastOpenedAt populated")
 	}
 }
+
+func TestUpsertWorktreeStatTouchesOneRow(t *testing.T) {
+	useTempHome(t)
+
+	refreshed := time.Unix(100, 0)
+	rows := []WorktreeStat{
+		{Branch: "main", WorktreePath: "/r", IsMain: true},
+		{Branch: "feat", WorktreePath: "/r.feat", Added: 1},
+	}
+	if err := ReplaceWorkspaceStats("/r", rows, refreshed); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if err := UpsertWorktreeStat(WorktreeStat{
+		WorkspacePath: "/r", Branch: "feat", WorktreePath: "/r.feat", Added: 7, Modified: true,
+	}, time.Unix(200, 0)); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	got, err := LoadWorkspaceStats("/r")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	byPath := map[string]WorktreeStat{}
+	for _, s := range got {
+		byPath[s.WorktreePath] = s
+	}
+	if len(byPath) != 2 || byPath["/r.feat"].Added != 7 || !byPath["/r.feat"].Modified {
+		t.Fatalf("stats = %+v", got)
+	}
+	if !byPath["/r"].UpdatedAt.Equal(refreshed) {
+		t.Fatalf("main row updated_at = %v, want untouched", byPath["/r"].UpdatedAt)
+	}
+	meta, _ := LoadWorkspaceMeta("/r")
+	if !meta.LastStatsRefresh.Equal(refreshed) {
+		t.Fatalf("last refresh = %v, want untouched", meta.LastStatsRefresh)
+	}
+
+	if err := DeleteWorktreeStat("/r", "/r.feat"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if got, _ := LoadWorkspaceStats("/r"); len(got) != 1 {
+		t.Fatalf("stats after delete = %+v", got)
+	}
+}
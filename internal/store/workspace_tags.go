@@ -0,0 +1,154 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WorkspaceFilter is a named tag filter preset of the workspaces dashboard.
+type WorkspaceFilter struct {
+	Name string
+	Tags []string
+}
+
+// NormalizeTags lowercases and trims tags, drops empty and repeated ones and
+// sorts the rest.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	var out []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// SetWorkspacePinned pins or unpins a registered workspace.
+func SetWorkspacePinned(path string, pinned bool) error {
+	db, err := open()
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(`UPDATE workspaces SET pinned = ? WHERE path = ?`, boolToInt(pinned), path); err != nil {
+		return fmt.Errorf("pin workspace %q: %w", path, err)
+	}
+	return nil
+}
+
+// SetWorkspaceTags replaces the tags of a workspace.
+func SetWorkspaceTags(path string, tags []string) error {
+	db, err := open()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin set workspace tags: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM workspace_tags WHERE workspace_path = ?`, path); err != nil {
+		return fmt.Errorf("clear workspace tags %q: %w", path, err)
+	}
+	if err := insertWorkspaceTags(tx, path, tags); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertWorkspaceTags(tx *sql.Tx, path string, tags []string) error {
+	for _, tag := range NormalizeTags(tags) {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO workspace_tags(workspace_path, tag) VALUES(?, ?)`, path, tag); err != nil {
+			return fmt.Errorf("insert workspace tag %q/%q: %w", path, tag, err)
+		}
+	}
+	return nil
+}
+
+func loadWorkspaceTags(db *sql.DB) (map[string][]string, error) {
+	rows, err := db.Query(`SELECT workspace_path, tag FROM workspace_tags ORDER BY workspace_path, tag`)
+	if err != nil {
+		return nil, fmt.Errorf("query workspace tags: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make(map[string][]string)
+	for rows.Next() {
+		var path, tag string
+		if err := rows.Scan(&path, &tag); err != nil {
+			return nil, fmt.Errorf("scan workspace tag: %w", err)
+		}
+		out[path] = append(out[path], tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate workspace tags: %w", err)
+	}
+	return out, nil
+}
+
+// LoadWorkspaceFilters returns the saved filter presets ordered by name.
+func LoadWorkspaceFilters() ([]WorkspaceFilter, error) {
+	db, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT name, tags FROM workspace_filters ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("query workspace filters: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var filters []WorkspaceFilter
+	for rows.Next() {
+		var name, tags string
+		if err := rows.Scan(&name, &tags); err != nil {
+			return nil, fmt.Errorf("scan workspace filter: %w", err)
+		}
+		filters = append(filters, WorkspaceFilter{Name: name, Tags: NormalizeTags(strings.Split(tags, ","))})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate workspace filters: %w", err)
+	}
+	return filters, nil
+}
+
+// SaveWorkspaceFilter creates or replaces the preset with the filter's name.
+func SaveWorkspaceFilter(filter WorkspaceFilter) error {
+	name := strings.TrimSpace(filter.Name)
+	tags := NormalizeTags(filter.Tags)
+	if name == "" || len(tags) == 0 {
+		return fmt.Errorf("save workspace filter: name and tags are required")
+	}
+	db, err := open()
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(`INSERT INTO workspace_filters(name, tags, created_at) VALUES(?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET tags = excluded.tags`,
+		name, strings.Join(tags, ","), time.Now().UnixNano()); err != nil {
+		return fmt.Errorf("save workspace filter %q: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteWorkspaceFilter removes a saved preset.
+func DeleteWorkspaceFilter(name string) error {
+	db, err := open()
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(`DELETE FROM workspace_filters WHERE name = ?`, name); err != nil {
+		return fmt.Errorf("delete workspace filter %q: %w", name, err)
+	}
+	return nil
+}
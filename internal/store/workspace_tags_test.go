@@ -0,0 +1,81 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWorkspaceTagsAndPinRoundTrip(t *testing.T) {
+	useTempHome(t)
+
+	for _, path := range []string{"/tmp/api", "/tmp/web"} {
+		if _, err := AddWorkspace(path[5:], path); err != nil {
+			t.Fatalf("AddWorkspace: %v", err)
+		}
+	}
+	if err := SetWorkspaceTags("/tmp/api", []string{" Work", "infra", "work", ""}); err != nil {
+		t.Fatalf("SetWorkspaceTags: %v", err)
+	}
+	if err := SetWorkspacePinned("/tmp/web", true); err != nil {
+		t.Fatalf("SetWorkspacePinned: %v", err)
+	}
+
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		t.Fatalf("LoadWorkspaces: %v", err)
+	}
+	if !reflect.DeepEqual(workspaces[0].Tags, []string{"infra", "work"}) || workspaces[0].Pinned {
+		t.Fatalf("api = %+v", workspaces[0])
+	}
+	if workspaces[1].Tags != nil || !workspaces[1].Pinned {
+		t.Fatalf("web = %+v", workspaces[1])
+	}
+
+	// SaveWorkspaces keeps pins and tags.
+	if err := SaveWorkspaces(workspaces); err != nil {
+		t.Fatalf("SaveWorkspaces: %v", err)
+	}
+	again, _ := LoadWorkspaces()
+	if !reflect.DeepEqual(again, workspaces) {
+		t.Fatalf("after save = %+v, want %+v", again, workspaces)
+	}
+
+	if _, err := RemoveWorkspace("/tmp/api"); err != nil {
+		t.Fatalf("RemoveWorkspace: %v", err)
+	}
+	if _, err := AddWorkspace("api", "/tmp/api"); err != nil {
+		t.Fatalf("AddWorkspace: %v", err)
+	}
+	again, _ = LoadWorkspaces()
+	if again[0].Tags != nil {
+		t.Fatalf("tags survived removal: %+v", again[0])
+	}
+}
+
+func TestWorkspaceFilters(t *testing.T) {
+	useTempHome(t)
+
+	if err := SaveWorkspaceFilter(WorkspaceFilter{Name: "day job", Tags: []string{"work", "infra"}}); err != nil {
+		t.Fatalf("SaveWorkspaceFilter: %v", err)
+	}
+	if err := SaveWorkspaceFilter(WorkspaceFilter{Name: "day job", Tags: []string{"work"}}); err != nil {
+		t.Fatalf("SaveWorkspaceFilter replace: %v", err)
+	}
+	if err := SaveWorkspaceFilter(WorkspaceFilter{Name: "empty"}); err == nil {
+		t.Fatal("expected an error for a preset without tags")
+	}
+	filters, err := LoadWorkspaceFilters()
+	if err != nil {
+		t.Fatalf("LoadWorkspaceFilters: %v", err)
+	}
+	want := []WorkspaceFilter{{Name: "day job", Tags: []string{"work"}}}
+	if !reflect.DeepEqual(filters, want) {
+		t.Fatalf("filters = %+v, want %+v", filters, want)
+	}
+	if err := DeleteWorkspaceFilter("day job"); err != nil {
+		t.Fatalf("DeleteWorkspaceFilter: %v", err)
+	}
+	if filters, _ := LoadWorkspaceFilters(); len(filters) != 0 {
+		t.Fatalf("filters after delete = %+v", filters)
+	}
+}
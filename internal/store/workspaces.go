@@ -17,6 +17,9 @@ type Workspace struct {
 	Path       string `json:"path"`
 	AddedAt    int64  `json:"added_at"`
 	LastSeenAt int64  `json:"last_seen_at"`
+	// Pinned workspaces sort ahead of the rest of the dashboard.
+	Pinned bool     `json:"pinned,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
 }
 
 func LoadWorkspaces() ([]Workspace, error) {
@@ -29,7 +32,12 @@ func LoadWorkspaces() ([]Workspace, error) {
 		return nil, err
 	}
 
-	rows, err := db.Query(`SELECT name, path, added_at, last_seen_at FROM workspaces ORDER BY name, path`)
+	tags, err := loadWorkspaceTags(db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT name, path, added_at, last_seen_at, pinned FROM workspaces ORDER BY name, path`)
 	if err != nil {
 		return nil, fmt.Errorf("query workspaces: %w", err)
 	}
@@ -38,9 +46,12 @@ func LoadWorkspaces() ([]Workspace, error) {
 	var workspaces []Workspace
 	for rows.Next() {
 		var w Workspace
-		if err := rows.Scan(&w.Name, &w.Path, &w.AddedAt, &w.LastSeenAt); err != nil {
+		var pinned int
+		if err := rows.Scan(&w.Name, &w.Path, &w.AddedAt, &w.LastSeenAt, &pinned); err != nil {
 			return nil, fmt.Errorf("scan workspace: %w", err)
 		}
+		w.Pinned = pinned != 0
+		w.Tags = tags[w.Path]
 		workspaces = append(workspaces, w)
 	}
 	if err := rows.Err(); err != nil {
@@ -66,7 +77,11 @@ func SaveWorkspaces(workspaces []Workspace) error {
 		return fmt.Errorf("clear workspaces: %w", err)
 	}
 
-	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO workspaces(path, name, added_at, last_seen_at) VALUES(?, ?, ?, ?)`)
+	if _, err := tx.Exec(`DELETE FROM workspace_tags`); err != nil {
+		return fmt.Errorf("clear workspace tags: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO workspaces(path, name, added_at, last_seen_at, pinned) VALUES(?, ?, ?, ?, ?)`)
 	if err != nil {
 		return fmt.Errorf("prepare insert workspace: %w", err)
 	}
@@ -77,9 +92,12 @@ func SaveWorkspaces(workspaces []Workspace) error {
 		if lastSeenAt == 0 {
 			lastSeenAt = w.AddedAt
 		}
-		if _, err := stmt.Exec(w.Path, w.Name, w.AddedAt, lastSeenAt); err != nil {
+		if _, err := stmt.Exec(w.Path, w.Name, w.AddedAt, lastSeenAt, boolToInt(w.Pinned)); err != nil {
 			return fmt.Errorf("insert workspace %q: %w", w.Path, err)
 		}
+		if err := insertWorkspaceTags(tx, w.Path, w.Tags); err != nil {
+			return err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -124,14 +142,27 @@ func RemoveWorkspace(path string) (bool, error) {
 		return false, err
 	}
 
-	result, err := db.Exec(`DELETE FROM workspaces WHERE path = ?`, path)
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("begin remove workspace: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.Exec(`DELETE FROM workspaces WHERE path = ?`, path)
 	if err != nil {
 		return false, fmt.Errorf("delete workspace %q: %w", path, err)
 	}
+	if _, err := tx.Exec(`DELETE FROM workspace_tags WHERE workspace_path = ?`, path); err != nil {
+		return false, fmt.Errorf("delete workspace tags %q: %w", path, err)
+	}
 	rows, err := result.RowsAffected()
 	if err != nil {
 		return false, fmt.Errorf("rows affected removing workspace %q: %w", path, err)
 	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit remove workspace: %w", err)
+	}
 	return rows > 0, nil
 }
 
@@ -0,0 +1,96 @@
+// Package subproject finds the subprojects of a monorepo: directories below
+// the repository root holding a marker file such as package.json or go.mod.
+// Subprojects are units of work inside one worktree; git state and stats
+// stay with the worktree they belong to.
+package subproject
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+)
+
+// Subproject is a directory below a worktree root. Rel is its slash-separated
+// path relative to that root, e.g. "services/api".
+type Subproject struct {
+	Rel    string
+	Marker string // marker file that matched
+}
+
+// skipDirs are never searched: dependency trees and build output.
+var skipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"target":       true,
+	"dist":         true,
+	"build":        true,
+}
+
+// Detect lists the subprojects of the worktree at root using the markers and
+// depth from the repository's .kitmux.toml.
+func Detect(root string) []Subproject {
+	repo, _ := config.LoadRepo(root)
+	return Find(root, repo.SubprojectMarkers(), repo.SubprojectDepth())
+}
+
+// Find searches at most depth levels below root for directories holding one
+// of markers. The root itself is not a subproject, and the search does not
+// descend into a subproject, hidden directories, nested repositories or
+// dependency directories. Results are sorted by path.
+func Find(root string, markers []string, depth int) []Subproject {
+	var out []Subproject
+	find(root, "", markers, depth, &out)
+	sort.Slice(out, func(i, j int) bool { return out[i].Rel < out[j].Rel })
+	return out
+}
+
+func find(root, rel string, markers []string, depth int, out *[]Subproject) {
+	if depth <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() || name[0] == '.' || skipDirs[name] {
+			continue
+		}
+		childRel := name
+		if rel != "" {
+			childRel = rel + "/" + name
+		}
+		dir := filepath.Join(root, filepath.FromSlash(childRel))
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			continue
+		}
+		if marker := matchMarker(dir, markers); marker != "" {
+			*out = append(*out, Subproject{Rel: childRel, Marker: marker})
+			continue
+		}
+		find(root, childRel, markers, depth-1, out)
+	}
+}
+
+func matchMarker(dir string, markers []string) string {
+	for _, marker := range markers {
+		if info, err := os.Stat(filepath.Join(dir, marker)); err == nil && !info.IsDir() {
+			return marker
+		}
+	}
+	return ""
+}
+
+// Containing returns the subproject of subprojects that contains rel, the
+// slash-separated path of a directory relative to the worktree root.
+func Containing(subprojects []Subproject, rel string) (Subproject, bool) {
+	for _, sp := range subprojects {
+		if rel == sp.Rel || (len(rel) > len(sp.Rel) && rel[:len(sp.Rel)] == sp.Rel && rel[len(sp.Rel)] == '/') {
+			return sp, true
+		}
+	}
+	return Subproject{}, false
+}
@@ -0,0 +1,57 @@
+package subproject
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func touch(t *testing.T, root, rel string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindListsMarkedDirectories(t *testing.T) {
+	root := t.TempDir()
+	touch(t, root, "package.json")
+	touch(t, root, "services/api/go.mod")
+	touch(t, root, "services/api/web/package.json") // inside a subproject
+	touch(t, root, "services/web/package.json")
+	touch(t, root, "node_modules/left-pad/package.json")
+	touch(t, root, ".cache/tool/package.json")
+	touch(t, root, "tools/gen/BUILD.bazel")
+	touch(t, root, "deep/a/b/c/go.mod") // below depth
+	touch(t, root, "vendored/.git")
+	touch(t, root, "vendored/go.mod")
+
+	got := Find(root, []string{"package.json", "go.mod", "BUILD.bazel"}, 3)
+	want := []Subproject{
+		{Rel: "services/api", Marker: "go.mod"},
+		{Rel: "services/web", Marker: "package.json"},
+		{Rel: "tools/gen", Marker: "BUILD.bazel"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Find() = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Find()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestContaining(t *testing.T) {
+	subs := []Subproject{{Rel: "services/api"}, {Rel: "services/web"}}
+	if sp, ok := Containing(subs, "services/api/cmd"); !ok || sp.Rel != "services/api" {
+		t.Fatalf("Containing() = %+v, %v", sp, ok)
+	}
+	if _, ok := Containing(subs, "services/apix"); ok {
+		t.Fatal("services/apix is not inside services/api")
+	}
+}
@@ -84,6 +84,23 @@ var (
 	DiffRemoved = lipgloss.NewStyle().
 			Foreground(Red)
 
+	DiffHunk = lipgloss.NewStyle().
+			Foreground(Accent)
+
+	// Syntax styles for the diff viewer
+	SyntaxKeyword = lipgloss.NewStyle().
+			Foreground(Purple)
+
+	SyntaxString = lipgloss.NewStyle().
+			Foreground(Yellow)
+
+	SyntaxNumber = lipgloss.NewStyle().
+			Foreground(Accent)
+
+	SyntaxComment = lipgloss.NewStyle().
+			Foreground(Dim).
+			Italic(true)
+
 	// Thread view selection (full-width highlight bar, codex-style)
 	SelectionBar = lipgloss.NewStyle().
 			Background(Dim)
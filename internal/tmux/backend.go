@@ -0,0 +1,99 @@
+package tmux
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+)
+
+// Backend names accepted by UseBackend.
+const (
+	BackendAuto    = "auto"
+	BackendControl = "control"
+	BackendExec    = "exec"
+)
+
+var activeControl atomic.Pointer[Control]
+
+// controlSubscriptions turn pane state that tmux does not announce on its
+// own (agent hook options, the running command) into change events.
+var controlSubscriptions = []struct {
+	name, target, format string
+}{
+	{"kitmux-panes", "%*", "#{pane_current_command}|#{@kitmux_agent_state}|#{pane_title}"},
+}
+
+// UseBackend starts a control-mode connection for the lifetime of a TUI
+// when backend allows it. "auto" only connects from inside tmux. The
+// returned stop func is always safe to call; when no connection could be
+// made every command keeps using exec.
+func UseBackend(backend string) (stop func()) {
+	noop := func() {}
+	switch backend {
+	case BackendExec:
+		return noop
+	case BackendAuto:
+		if os.Getenv("TMUX") == "" {
+			return noop
+		}
+	}
+	session, err := currentSessionID()
+	if err != nil || session == "" {
+		return noop
+	}
+	c, err := StartControl(session)
+	if err != nil {
+		return noop
+	}
+	for _, sub := range controlSubscriptions {
+		_ = c.Subscribe(sub.name, sub.target, sub.format)
+	}
+	activeControl.Store(c)
+	go func() {
+		<-c.Done()
+		activeControl.CompareAndSwap(c, nil)
+	}()
+	return func() {
+		activeControl.CompareAndSwap(c, nil)
+		_ = c.Close()
+	}
+}
+
+// ControlActive reports whether commands currently go over control mode.
+func ControlActive() bool {
+	return activeControl.Load() != nil
+}
+
+// ActiveControl returns the live control connection, or nil.
+func ActiveControl() *Control {
+	return activeControl.Load()
+}
+
+func currentSessionID() (string, error) {
+	out, err := exec.Command("tmux", "display-message", "-p", "#{session_id}").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// output runs a tmux command that does not depend on the calling client or
+// pane, over control mode when connected and through exec otherwise.
+func output(args ...string) (string, error) {
+	if c := activeControl.Load(); c != nil {
+		out, err := c.Run(args...)
+		if !errors.Is(err, ErrControlClosed) {
+			return out, err
+		}
+	}
+	out, err := exec.Command("tmux", args...).Output()
+	return string(out), err
+}
+
+// run is output without the result.
+func run(args ...string) error {
+	_, err := output(args...)
+	return err
+}
@@ -3,12 +3,13 @@ package tmux
 import (
 	"fmt"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 )
 
 // ListSessions returns all tmux sessions.
-func ListSessions() ([]Session, error) {
+func (Exec) ListSessions() ([]Session, error) {
 	format := strings.Join([]string{
 		"#{session_name}",
 		"#{session_windows}",
@@ -27,12 +28,11 @@ func ListSessions() ([]Session, error) {
 		"#{@kitmux_agent_title_display}",
 		"#{@kitmux_initial_title}",
 	}, "\t")
-	out, err := exec.Command("tmux", "list-sessions", "-F",
-		format).Output()
+	out, err := output("list-sessions", "-F", format)
 	if err != nil {
 		return nil, fmt.Errorf("list-sessions: %w", err)
 	}
-	return parseSessionsOutput(string(out)), nil
+	return parseSessionsOutput(out), nil
 }
 
 func parseSessionsOutput(output string) []Session {
@@ -186,14 +186,14 @@ func ListThreads() ([]Session, error) {
 }
 
 // ListWindows returns windows for a given session.
-func ListWindows(session string) ([]Window, error) {
-	out, err := exec.Command("tmux", "list-windows", "-t", session, "-F",
-		"#{window_index}\t#{window_name}\t#{?window_active,1,0}").Output()
+func (Exec) ListWindows(session string) ([]Window, error) {
+	out, err := output("list-windows", "-t", session, "-F",
+		"#{window_index}\t#{window_name}\t#{?window_active,1,0}")
 	if err != nil {
 		return nil, fmt.Errorf("list-windows: %w", err)
 	}
 	var windows []Window
-	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
 		if line == "" {
 			continue
 		}
@@ -213,7 +213,7 @@ func ListWindows(session string) ([]Window, error) {
 }
 
 // CurrentSession returns the name of the current tmux session.
-func CurrentSession() (string, error) {
+func (Exec) CurrentSession() (string, error) {
 	out, err := exec.Command("tmux", "display-message", "-p", "#{session_name}").Output()
 	if err != nil {
 		return "", fmt.Errorf("display-message: %w", err)
@@ -221,7 +221,7 @@ func CurrentSession() (string, error) {
 	return strings.TrimSpace(string(out)), nil
 }
 
-func CurrentThreadContext() (ThreadContext, error) {
+func (Exec) CurrentThreadContext() (ThreadContext, error) {
 	format := strings.Join([]string{
 		"#{session_name}",
 		"#{pane_id}",
@@ -249,7 +249,7 @@ func CurrentThreadContext() (ThreadContext, error) {
 	return ctx, nil
 }
 
-func CurrentPaneTitle() (string, error) {
+func (Exec) CurrentPaneTitle() (string, error) {
 	out, err := exec.Command("tmux", "display-message", "-p", "#{pane_title}").Output()
 	if err != nil {
 		return "", fmt.Errorf("display-message pane title: %w", err)
@@ -257,7 +257,7 @@ func CurrentPaneTitle() (string, error) {
 	return strings.TrimSpace(string(out)), nil
 }
 
-func CurrentPanePath() (string, error) {
+func (Exec) CurrentPanePath() (string, error) {
 	out, err := exec.Command("tmux", "display-message", "-p", "#{pane_current_path}").Output()
 	if err != nil {
 		return "", fmt.Errorf("display-message pane path: %w", err)
@@ -265,7 +265,7 @@ func CurrentPanePath() (string, error) {
 	return strings.TrimSpace(string(out)), nil
 }
 
-func CurrentClientWidth() (int, error) {
+func (Exec) CurrentClientWidth() (int, error) {
 	out, err := exec.Command("tmux", "display-message", "-p", "#{client_width}").Output()
 	if err != nil {
 		return 0, fmt.Errorf("display-message client width: %w", err)
@@ -278,49 +278,69 @@ func CurrentClientWidth() (int, error) {
 }
 
 // HasSession returns true if a session with the given name exists.
-func HasSession(name string) bool {
-	return exec.Command("tmux", "has-session", "-t", name).Run() == nil
+func (Exec) HasSession(name string) bool {
+	return run("has-session", "-t", name) == nil
 }
 
 // SwitchClient switches the current tmux client to the given target.
-func SwitchClient(target string) error {
+func (Exec) SwitchClient(target string) error {
 	return exec.Command("tmux", "switch-client", "-t", target).Run()
 }
 
-func SelectWindow(target string) error {
+func (Exec) SelectWindow(target string) error {
 	return exec.Command("tmux", "select-window", "-t", target).Run()
 }
 
-func SelectPane(target string) error {
+func (Exec) SelectPane(target string) error {
 	return exec.Command("tmux", "select-pane", "-t", target).Run()
 }
 
 // KillSession kills the named session.
-func KillSession(name string) error {
-	return exec.Command("tmux", "kill-session", "-t", name).Run()
+func (Exec) KillSession(name string) error {
+	return run("kill-session", "-t", name)
+}
+
+// DetachSessionClients detaches every client attached to the named session.
+func (Exec) DetachSessionClients(name string) error {
+	return run("detach-client", "-s", name)
 }
 
 // RenameSession renames a session from old to newName.
-func RenameSession(old, newName string) error {
-	return exec.Command("tmux", "rename-session", "-t", old, newName).Run()
+func (Exec) RenameSession(old, newName string) error {
+	return run("rename-session", "-t", old, newName)
 }
 
 // RenameWindow renames a window given a tmux target (e.g. "session:0").
-func RenameWindow(target, newName string) error {
-	return exec.Command("tmux", "rename-window", "-t", target, newName).Run()
+func (Exec) RenameWindow(target, newName string) error {
+	return run("rename-window", "-t", target, newName)
 }
 
 // NewSessionInDir creates a detached session with the given name and working directory.
-func NewSessionInDir(name, dir string) error {
-	return exec.Command("tmux", "new-session", "-d", "-s", name, "-c", dir).Run()
+func (Exec) NewSessionInDir(name, dir string) error {
+	return run("new-session", "-d", "-s", name, "-c", dir)
+}
+
+// NewSessionWithEnv creates a detached session in dir whose environment,
+// and so every pane's, includes env from the start.
+func (Exec) NewSessionWithEnv(name, dir string, env map[string]string) error {
+	args := []string{"new-session", "-d", "-s", name, "-c", dir}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-e", k+"="+env[k])
+	}
+	return run(args...)
 }
 
 // NewSessionDetached creates a detached session with the given name.
-func NewSessionDetached(name string) error {
-	return exec.Command("tmux", "new-session", "-d", "-s", name).Run()
+func (Exec) NewSessionDetached(name string) error {
+	return run("new-session", "-d", "-s", name)
 }
 
-func NewSessionWithCommand(name, dir, command string) (string, error) {
+func (Exec) NewSessionWithCommand(name, dir, command string) (string, error) {
 	args := []string{
 		"new-session", "-d",
 		"-P", "-F", "#{pane_id}",
@@ -332,35 +352,41 @@ func NewSessionWithCommand(name, dir, command string) (string, error) {
 	if command != "" {
 		args = append(args, command)
 	}
-	out, err := exec.Command("tmux", args...).Output()
+	out, err := output(args...)
 	if err != nil {
 		return "", fmt.Errorf("new-session: %w", err)
 	}
-	return strings.TrimSpace(string(out)), nil
+	return strings.TrimSpace(out), nil
 }
 
-func SetSessionOption(target, option, value string) error {
-	return exec.Command("tmux", "set-option", "-t", target, option, value).Run()
+func (Exec) SetSessionOption(target, option, value string) error {
+	return run("set-option", "-t", target, option, value)
 }
 
-func SetCurrentSessionOption(option, value string) error {
+// SetEnvironment sets a variable in the session environment, which panes
+// created afterwards inherit.
+func (Exec) SetEnvironment(target, name, value string) error {
+	return run("set-environment", "-t", target, name, value)
+}
+
+func (Exec) SetCurrentSessionOption(option, value string) error {
 	return exec.Command("tmux", "set-option", "-q", option, value).Run()
 }
 
-func SetWindowOption(target, option, value string) error {
-	return exec.Command("tmux", "set-window-option", "-t", target, option, value).Run()
+func (Exec) SetWindowOption(target, option, value string) error {
+	return run("set-window-option", "-t", target, option, value)
 }
 
-func SetPaneOption(target, option, value string) error {
-	return exec.Command("tmux", "set-option", "-p", "-t", target, option, value).Run()
+func (Exec) SetPaneOption(target, option, value string) error {
+	return run("set-option", "-p", "-t", target, option, value)
 }
 
-func SetCurrentPaneOption(option, value string) error {
+func (Exec) SetCurrentPaneOption(option, value string) error {
 	return exec.Command("tmux", "set-option", "-p", "-q", option, value).Run()
 }
 
-func SetPaneTitle(target, title string) error {
-	return exec.Command("tmux", "select-pane", "-t", target, "-T", title).Run()
+func (Exec) SetPaneTitle(target, title string) error {
+	return run("select-pane", "-t", target, "-T", title)
 }
 
 func SetThreadTitle(sessionName, title string) error {
@@ -379,56 +405,62 @@ func SetAgentSessionID(sessionName, id string) error {
 	return SetSessionOption(sessionName, "@kitmux_agent_session_id", id)
 }
 
-func ShowSessionOption(target, option string) (string, error) {
-	out, err := exec.Command("tmux", "show-option", "-qv", "-t", target, option).Output()
+func (Exec) ShowSessionOption(target, option string) (string, error) {
+	out, err := output("show-option", "-qv", "-t", target, option)
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(string(out)), nil
+	return strings.TrimSpace(out), nil
 }
 
-func ShowPaneOption(target, option string) (string, error) {
-	out, err := exec.Command("tmux", "show-option", "-p", "-qv", "-t", target, option).Output()
+func (Exec) ShowPaneOption(target, option string) (string, error) {
+	out, err := output("show-option", "-p", "-qv", "-t", target, option)
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(string(out)), nil
+	return strings.TrimSpace(out), nil
 }
 
-func RefreshClients(sessionName string) error {
-	out, err := exec.Command("tmux", "list-clients", "-t", sessionName, "-F", "#{client_name}").Output()
+func (Exec) RefreshClients(sessionName string) error {
+	out, err := output("list-clients", "-t", sessionName, "-F", "#{client_name}")
 	if err != nil {
 		return err
 	}
-	for _, client := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+	for _, client := range strings.Split(strings.TrimSpace(out), "\n") {
 		if client == "" {
 			continue
 		}
-		_ = exec.Command("tmux", "refresh-client", "-t", client).Run()
+		_ = run("refresh-client", "-t", client)
 	}
 	return nil
 }
 
-func SetHook(target, hook, command string) error {
-	return exec.Command("tmux", "set-hook", "-t", target, hook, command).Run()
+func (Exec) SetHook(target, hook, command string) error {
+	return run("set-hook", "-t", target, hook, command)
+}
+
+// SetGlobalHook sets a hook for every session that has no hook of its own
+// by that name.
+func (Exec) SetGlobalHook(hook, command string) error {
+	return run("set-hook", "-g", hook, command)
 }
 
 // SendKeys sends keystrokes to a tmux target pane.
-func SendKeys(target, keys string) error {
-	return exec.Command("tmux", "send-keys", "-t", target, keys, "Enter").Run()
+func (Exec) SendKeys(target, keys string) error {
+	return run("send-keys", "-t", target, keys, "Enter")
 }
 
 // SplitWindow creates a horizontal split running the given command.
-func SplitWindow(command string) error {
+func (Exec) SplitWindow(command string) error {
 	return exec.Command("tmux", "split-window", "-h", command).Run()
 }
 
 // NewWindowWithCommand creates a new window running the given command.
-func NewWindowWithCommand(name, command string) error {
+func (Exec) NewWindowWithCommand(name, command string) error {
 	return exec.Command("tmux", "new-window", "-n", name, command).Run()
 }
 
-func NewWindowInDir(name, dir, command string) (string, error) {
+func (Exec) NewWindowInDir(name, dir, command string) (string, error) {
 	out, err := exec.Command("tmux", "new-window",
 		"-P", "-F", "#{pane_id}",
 		"-n", name,
@@ -444,7 +476,7 @@ func NewWindowInDir(name, dir, command string) (string, error) {
 // NewWindowInSession creates a new window inside an existing session,
 // optionally starting in `dir` and running `command`. It does not make the
 // new window active — callers that want focus should switch-client afterwards.
-func NewWindowInSession(session, name, dir, command string) error {
+func (Exec) NewWindowInSession(session, name, dir, command string) error {
 	args := []string{"new-window", "-d", "-t", session + ":"}
 	if name != "" {
 		args = append(args, "-n", name)
@@ -455,10 +487,10 @@ func NewWindowInSession(session, name, dir, command string) error {
 	if command != "" {
 		args = append(args, command)
 	}
-	return exec.Command("tmux", args...).Run()
+	return run(args...)
 }
 
-func NewWindowInSessionPaneID(session, name, dir, command string) (string, error) {
+func (Exec) NewWindowInSessionPaneID(session, name, dir, command string) (string, error) {
 	args := []string{
 		"new-window", "-d",
 		"-P", "-F", "#{pane_id}",
@@ -473,14 +505,14 @@ func NewWindowInSessionPaneID(session, name, dir, command string) (string, error
 	if command != "" {
 		args = append(args, command)
 	}
-	out, err := exec.Command("tmux", args...).Output()
+	out, err := output(args...)
 	if err != nil {
 		return "", fmt.Errorf("new-window: %w", err)
 	}
-	return strings.TrimSpace(string(out)), nil
+	return strings.TrimSpace(out), nil
 }
 
-func SplitWindowInDir(targetPane, dir, command string) (string, error) {
+func (Exec) SplitWindowInDir(targetPane, dir, command string) (string, error) {
 	args := []string{
 		"split-window", "-h",
 		"-P", "-F", "#{pane_id}",
@@ -500,7 +532,7 @@ func SplitWindowInDir(targetPane, dir, command string) (string, error) {
 	return strings.TrimSpace(string(out)), nil
 }
 
-func SplitWindowInDirPercent(targetPane, dir, command string, percent int) (string, error) {
+func (Exec) SplitWindowInDirPercent(targetPane, dir, command string, percent int) (string, error) {
 	args := []string{
 		"split-window", "-h",
 		"-p", strconv.Itoa(percent),
@@ -521,7 +553,7 @@ func SplitWindowInDirPercent(targetPane, dir, command string, percent int) (stri
 	return strings.TrimSpace(string(out)), nil
 }
 
-func RespawnPaneInDir(targetPane, dir, command string) error {
+func (Exec) RespawnPaneInDir(targetPane, dir, command string) error {
 	args := []string{"respawn-pane", "-k"}
 	if targetPane != "" {
 		args = append(args, "-t", targetPane)
@@ -538,12 +570,12 @@ func RespawnPaneInDir(targetPane, dir, command string) error {
 	return nil
 }
 
-func SelectLayout(target, layout string) error {
-	return exec.Command("tmux", "select-layout", "-t", target, layout).Run()
+func (Exec) SelectLayout(target, layout string) error {
+	return run("select-layout", "-t", target, layout)
 }
 
 // ListPanes returns all panes across all sessions with their running commands.
-func ListPanes() ([]Pane, error) {
+func (Exec) ListPanes() ([]Pane, error) {
 	format := strings.Join([]string{
 		"#{session_name}",
 		"#{window_index}",
@@ -561,12 +593,12 @@ func ListPanes() ([]Pane, error) {
 		"#{@kitmux_agent_title_prefix}",
 		"#{@kitmux_agent_title_display}",
 	}, "\t")
-	out, err := exec.Command("tmux", "list-panes", "-a", "-F", format).Output()
+	out, err := output("list-panes", "-a", "-F", format)
 	if err != nil {
 		return nil, fmt.Errorf("list-panes: %w", err)
 	}
 	var panes []Pane
-	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
 		if pane, ok := parsePaneLine(line); ok {
 			panes = append(panes, pane)
 		}
@@ -629,13 +661,13 @@ func applyPaneOptionalFields(pane *Pane, parts []string) {
 }
 
 // DisplayPopup opens a tmux popup running the given command.
-func DisplayPopup(command, width, height string) error {
+func (Exec) DisplayPopup(command, width, height string) error {
 	return exec.Command("tmux", "display-popup",
 		"-d", "#{pane_current_path}",
 		"-w", width, "-h", height, "-E", command).Run()
 }
 
 // DisplayMessage shows a transient message in the tmux status area.
-func DisplayMessage(message string) error {
+func (Exec) DisplayMessage(message string) error {
 	return exec.Command("tmux", "display-message", message).Run()
 }
@@ -0,0 +1,268 @@
+package tmux
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// ErrControlClosed is returned by Control.Run once the control-mode
+// connection has exited. Callers fall back to exec on this error.
+var ErrControlClosed = errors.New("tmux control connection closed")
+
+// Event is a control-mode notification such as %sessions-changed or
+// %window-add. Name has the leading % stripped.
+type Event struct {
+	Name string
+	Args []string
+}
+
+// Control is a long-lived `tmux -C` connection. Commands are written as
+// soon as they are issued and replies are matched in order, so concurrent
+// callers pipeline over the same connection.
+type Control struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeMu sync.Mutex // orders writes so replies match pending
+
+	mu      sync.Mutex
+	pending []chan controlReply
+	closed  bool
+
+	events chan Event
+	done   chan struct{}
+}
+
+type controlReply struct {
+	out string
+	err error
+}
+
+// StartControl attaches a control-mode client to session. The client does
+// not receive pane output and does not affect window sizes. -u keeps tmux
+// from replacing tabs and non-ASCII bytes in command output with "_".
+func StartControl(session string) (*Control, error) {
+	cmd := exec.Command("tmux", "-u", "-C", "attach-session", "-t", session, "-f", "no-output,ignore-size")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("control stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("control stdout: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start control client: %w", err)
+	}
+	c := newControl(stdin, stdout)
+	c.cmd = cmd
+	return c, nil
+}
+
+func newControl(stdin io.WriteCloser, stdout io.Reader) *Control {
+	c := &Control{
+		stdin:  stdin,
+		events: make(chan Event, 64),
+		done:   make(chan struct{}),
+	}
+	go c.readLoop(stdout)
+	return c
+}
+
+// Events delivers notifications until the connection closes. Events are
+// dropped rather than blocking the reader when nobody is listening.
+func (c *Control) Events() <-chan Event { return c.events }
+
+// Done is closed when the connection exits.
+func (c *Control) Done() <-chan struct{} { return c.done }
+
+// Run sends one command and waits for its output.
+func (c *Control) Run(args ...string) (string, error) {
+	reply, err := c.send([][]string{args})
+	if err != nil {
+		return "", err
+	}
+	r := <-reply[0]
+	return r.out, r.err
+}
+
+// RunBatch sends every command in a single write and returns their outputs
+// in order. errs[i] is non-nil when command i failed.
+func (c *Control) RunBatch(cmds [][]string) (outs []string, errs []error) {
+	outs = make([]string, len(cmds))
+	errs = make([]error, len(cmds))
+	replies, err := c.send(cmds)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return outs, errs
+	}
+	for i, ch := range replies {
+		r := <-ch
+		outs[i], errs[i] = r.out, r.err
+	}
+	return outs, errs
+}
+
+// Subscribe asks tmux to report changes of format on target (for example
+// "%*" for every pane) as "subscription-changed" events.
+func (c *Control) Subscribe(name, target, format string) error {
+	_, err := c.Run("refresh-client", "-B", name+":"+target+":"+format)
+	return err
+}
+
+// Close detaches the control client and waits for it to exit.
+func (c *Control) Close() error {
+	c.writeMu.Lock()
+	_ = c.stdin.Close()
+	c.writeMu.Unlock()
+	<-c.done
+	if c.cmd != nil {
+		_ = c.cmd.Wait()
+	}
+	return nil
+}
+
+func (c *Control) send(cmds [][]string) ([]chan controlReply, error) {
+	var b strings.Builder
+	replies := make([]chan controlReply, len(cmds))
+	for i, args := range cmds {
+		b.WriteString(quoteCommand(args))
+		b.WriteByte('\n')
+		replies[i] = make(chan controlReply, 1)
+	}
+
+	// Register replies before writing, but never hold mu across the write:
+	// the reader needs mu to deliver replies while tmux waits for us to
+	// drain its output. A failed write is reported through the replies
+	// once the reader sees the process exit.
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrControlClosed
+	}
+	c.pending = append(c.pending, replies...)
+	c.mu.Unlock()
+	_, _ = io.WriteString(c.stdin, b.String())
+	return replies, nil
+}
+
+func (c *Control) readLoop(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var (
+		inBlock bool
+		ours    bool
+		lines   []string
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inBlock {
+			switch {
+			case strings.HasPrefix(line, "%end "), strings.HasPrefix(line, "%error "):
+				inBlock = false
+				if ours {
+					c.deliver(lines, strings.HasPrefix(line, "%error "))
+				}
+			default:
+				lines = append(lines, line)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "%begin ") {
+			inBlock = true
+			lines = nil
+			// Replies to our own commands carry flags=1; the block for the
+			// initial attach-session carries 0.
+			ours = strings.HasSuffix(line, " 1")
+			continue
+		}
+		if ev, ok := parseEvent(line); ok {
+			if ev.Name == "exit" {
+				break
+			}
+			select {
+			case c.events <- ev:
+			default:
+			}
+		}
+	}
+	c.shutdown()
+}
+
+func (c *Control) deliver(lines []string, failed bool) {
+	c.mu.Lock()
+	if len(c.pending) == 0 {
+		c.mu.Unlock()
+		return
+	}
+	reply := c.pending[0]
+	c.pending = c.pending[1:]
+	c.mu.Unlock()
+
+	if failed {
+		reply <- controlReply{err: errors.New(strings.Join(lines, "; "))}
+		return
+	}
+	out := strings.Join(lines, "\n")
+	if out != "" {
+		out += "\n"
+	}
+	reply <- controlReply{out: out}
+}
+
+func (c *Control) shutdown() {
+	c.mu.Lock()
+	c.closed = true
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+	for _, reply := range pending {
+		reply <- controlReply{err: ErrControlClosed}
+	}
+	close(c.events)
+	close(c.done)
+}
+
+func parseEvent(line string) (Event, bool) {
+	if !strings.HasPrefix(line, "%") || len(line) < 2 {
+		return Event{}, false
+	}
+	fields := strings.Fields(line[1:])
+	if len(fields) == 0 {
+		return Event{}, false
+	}
+	return Event{Name: fields[0], Args: fields[1:]}, true
+}
+
+// quoteCommand renders args as a tmux command line. Every argument is
+// double-quoted so spaces, semicolons and $ survive the tmux parser.
+func quoteCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+var argEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	`$`, `\$`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+)
+
+func quoteArg(arg string) string {
+	return `"` + argEscaper.Replace(arg) + `"`
+}
@@ -0,0 +1,139 @@
+package tmux
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeControlServer answers each command line the way `tmux -C` does.
+type fakeControlServer struct {
+	out     *io.PipeWriter
+	mu      sync.Mutex
+	written []string
+}
+
+func startFakeControl(t *testing.T, reply func(cmd string) (string, bool)) (*Control, *fakeControlServer) {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	srv := &fakeControlServer{out: outW}
+	c := newControl(inW, outR)
+	go func() {
+		_, _ = io.WriteString(outW, "%begin 1 1 0\n%end 1 1 0\n%session-changed $0 main\n")
+		scanner := bufio.NewScanner(inR)
+		n := 2
+		for scanner.Scan() {
+			line := scanner.Text()
+			srv.mu.Lock()
+			srv.written = append(srv.written, line)
+			srv.mu.Unlock()
+			body, ok := reply(line)
+			fmt.Fprintf(outW, "%%begin 1 %d 1\n", n)
+			if body != "" {
+				fmt.Fprintln(outW, body)
+			}
+			if ok {
+				fmt.Fprintf(outW, "%%end 1 %d 1\n", n)
+			} else {
+				fmt.Fprintf(outW, "%%error 1 %d 1\n", n)
+			}
+			n++
+		}
+		_ = outW.Close()
+	}()
+	t.Cleanup(func() { _ = c.Close() })
+	return c, srv
+}
+
+func (s *fakeControlServer) send(line string) {
+	_, _ = io.WriteString(s.out, line+"\n")
+}
+
+func TestControlRunReturnsOutputAndErrors(t *testing.T) {
+	c, _ := startFakeControl(t, func(cmd string) (string, bool) {
+		if strings.HasPrefix(cmd, `"bogus"`) {
+			return "unknown command: bogus", false
+		}
+		return "alpha\nbeta", true
+	})
+
+	out, err := c.Run("list-sessions", "-F", "#{session_name}")
+	if err != nil || out != "alpha\nbeta\n" {
+		t.Fatalf("Run() = %q, %v", out, err)
+	}
+	if _, err := c.Run("bogus"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Fatalf("expected tmux error, got %v", err)
+	}
+}
+
+func TestControlPipelinesConcurrentCommands(t *testing.T) {
+	c, srv := startFakeControl(t, func(cmd string) (string, bool) {
+		return cmd, true
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			arg := fmt.Sprintf("value-%d", i)
+			out, err := c.Run("show-option", arg)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if want := quoteCommand([]string{"show-option", arg}) + "\n"; out != want {
+				errs <- fmt.Errorf("reply mismatch: got %q want %q", out, want)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	outs, batchErrs := c.RunBatch([][]string{{"a"}, {"b"}})
+	if outs[0] != "\"a\"\n" || outs[1] != "\"b\"\n" || batchErrs[0] != nil || batchErrs[1] != nil {
+		t.Fatalf("RunBatch() = %q, %v", outs, batchErrs)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.written) != 22 {
+		t.Fatalf("expected 22 commands written, got %d", len(srv.written))
+	}
+}
+
+func TestControlDeliversEventsAndCloses(t *testing.T) {
+	c, srv := startFakeControl(t, func(string) (string, bool) { return "", true })
+
+	ev := <-c.Events()
+	if ev.Name != "session-changed" {
+		t.Fatalf("first event = %+v", ev)
+	}
+	srv.send("%window-add @3")
+	ev = <-c.Events()
+	if ev.Name != "window-add" || len(ev.Args) != 1 || ev.Args[0] != "@3" {
+		t.Fatalf("event = %+v", ev)
+	}
+
+	srv.send("%exit")
+	<-c.Done()
+	if _, err := c.Run("list-sessions"); !errors.Is(err, ErrControlClosed) {
+		t.Fatalf("expected ErrControlClosed, got %v", err)
+	}
+}
+
+func TestQuoteArgEscapesTmuxSyntax(t *testing.T) {
+	got := quoteCommand([]string{"set-option", "-t", "s", "@x", "a \"b\" $HOME; c\\d\nnext"})
+	want := `"set-option" "-t" "s" "@x" "a \"b\" \$HOME; c\\d\nnext"`
+	if got != want {
+		t.Fatalf("quoteCommand() = %s, want %s", got, want)
+	}
+}
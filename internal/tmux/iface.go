@@ -0,0 +1,216 @@
+package tmux
+
+import "sync/atomic"
+
+// Client is everything kitmux asks of a tmux server. Exec talks to the real
+// server; tmuxtest.Server simulates one in memory for tests. The package-level
+// functions forward to the client installed with UseClient, so code that
+// calls tmux.ListSessions and friends can be exercised against a fake.
+type Client interface {
+	// Sessions.
+	ListSessions() ([]Session, error)
+	HasSession(name string) bool
+	NewSessionInDir(name, dir string) error
+	NewSessionWithEnv(name, dir string, env map[string]string) error
+	NewSessionDetached(name string) error
+	NewSessionWithCommand(name, dir, command string) (string, error)
+	KillSession(name string) error
+	RenameSession(old, newName string) error
+	DetachSessionClients(name string) error
+	SwitchClient(target string) error
+	RefreshClients(sessionName string) error
+	CurrentSession() (string, error)
+	CurrentThreadContext() (ThreadContext, error)
+	CurrentClientWidth() (int, error)
+
+	// Windows.
+	ListWindows(session string) ([]Window, error)
+	NewWindowWithCommand(name, command string) error
+	NewWindowInDir(name, dir, command string) (string, error)
+	NewWindowInSession(session, name, dir, command string) error
+	NewWindowInSessionPaneID(session, name, dir, command string) (string, error)
+	RenameWindow(target, newName string) error
+	SelectWindow(target string) error
+	SelectLayout(target, layout string) error
+
+	// Panes.
+	ListPanes() ([]Pane, error)
+	SelectPane(target string) error
+	SplitWindow(command string) error
+	SplitWindowInDir(targetPane, dir, command string) (string, error)
+	SplitWindowInDirPercent(targetPane, dir, command string, percent int) (string, error)
+	RespawnPaneInDir(targetPane, dir, command string) error
+	SendKeys(target, keys string) error
+	SetPaneTitle(target, title string) error
+	CurrentPaneTitle() (string, error)
+	CurrentPanePath() (string, error)
+
+	// Options.
+	SetSessionOption(target, option, value string) error
+	SetCurrentSessionOption(option, value string) error
+	SetWindowOption(target, option, value string) error
+	SetPaneOption(target, option, value string) error
+	SetCurrentPaneOption(option, value string) error
+	ShowSessionOption(target, option string) (string, error)
+	ShowPaneOption(target, option string) (string, error)
+	SetEnvironment(target, name, value string) error
+
+	// Hooks.
+	SetHook(target, hook, command string) error
+	SetGlobalHook(hook, command string) error
+
+	// Popups and messages.
+	DisplayPopup(command, width, height string) error
+	DisplayMessage(message string) error
+}
+
+// Exec is the real tmux client. Commands that do not depend on the calling
+// client go over the control connection when UseBackend started one, and
+// through the tmux binary otherwise.
+type Exec struct{}
+
+var _ Client = Exec{}
+
+type clientBox struct{ client Client }
+
+var defaultClient atomic.Pointer[clientBox]
+
+func init() {
+	defaultClient.Store(&clientBox{client: Exec{}})
+}
+
+// Default returns the client the package-level functions use.
+func Default() Client {
+	return defaultClient.Load().client
+}
+
+// UseClient makes c the client behind the package-level functions until
+// restore is called.
+func UseClient(c Client) (restore func()) {
+	prev := defaultClient.Swap(&clientBox{client: c})
+	return func() { defaultClient.Store(prev) }
+}
+
+func ListSessions() ([]Session, error) { return Default().ListSessions() }
+
+func ListWindows(session string) ([]Window, error) { return Default().ListWindows(session) }
+
+func CurrentSession() (string, error) { return Default().CurrentSession() }
+
+func CurrentThreadContext() (ThreadContext, error) { return Default().CurrentThreadContext() }
+
+func CurrentPaneTitle() (string, error) { return Default().CurrentPaneTitle() }
+
+func CurrentPanePath() (string, error) { return Default().CurrentPanePath() }
+
+func CurrentClientWidth() (int, error) { return Default().CurrentClientWidth() }
+
+func HasSession(name string) bool { return Default().HasSession(name) }
+
+func SwitchClient(target string) error { return Default().SwitchClient(target) }
+
+func SelectWindow(target string) error { return Default().SelectWindow(target) }
+
+func SelectPane(target string) error { return Default().SelectPane(target) }
+
+func KillSession(name string) error { return Default().KillSession(name) }
+
+func DetachSessionClients(name string) error { return Default().DetachSessionClients(name) }
+
+func RenameSession(old, newName string) error { return Default().RenameSession(old, newName) }
+
+func RenameWindow(target, newName string) error { return Default().RenameWindow(target, newName) }
+
+func NewSessionInDir(name, dir string) error { return Default().NewSessionInDir(name, dir) }
+
+func NewSessionWithEnv(name, dir string, env map[string]string) error {
+	return Default().NewSessionWithEnv(name, dir, env)
+}
+
+func NewSessionDetached(name string) error { return Default().NewSessionDetached(name) }
+
+func NewSessionWithCommand(name, dir, command string) (string, error) {
+	return Default().NewSessionWithCommand(name, dir, command)
+}
+
+func SetSessionOption(target, option, value string) error {
+	return Default().SetSessionOption(target, option, value)
+}
+
+func SetCurrentSessionOption(option, value string) error {
+	return Default().SetCurrentSessionOption(option, value)
+}
+
+func SetWindowOption(target, option, value string) error {
+	return Default().SetWindowOption(target, option, value)
+}
+
+func SetPaneOption(target, option, value string) error {
+	return Default().SetPaneOption(target, option, value)
+}
+
+func SetCurrentPaneOption(option, value string) error {
+	return Default().SetCurrentPaneOption(option, value)
+}
+
+func SetPaneTitle(target, title string) error { return Default().SetPaneTitle(target, title) }
+
+func ShowSessionOption(target, option string) (string, error) {
+	return Default().ShowSessionOption(target, option)
+}
+
+func ShowPaneOption(target, option string) (string, error) {
+	return Default().ShowPaneOption(target, option)
+}
+
+func SetEnvironment(target, name, value string) error {
+	return Default().SetEnvironment(target, name, value)
+}
+
+func RefreshClients(sessionName string) error { return Default().RefreshClients(sessionName) }
+
+func SetHook(target, hook, command string) error { return Default().SetHook(target, hook, command) }
+
+func SetGlobalHook(hook, command string) error { return Default().SetGlobalHook(hook, command) }
+
+func SendKeys(target, keys string) error { return Default().SendKeys(target, keys) }
+
+func SplitWindow(command string) error { return Default().SplitWindow(command) }
+
+func NewWindowWithCommand(name, command string) error {
+	return Default().NewWindowWithCommand(name, command)
+}
+
+func NewWindowInDir(name, dir, command string) (string, error) {
+	return Default().NewWindowInDir(name, dir, command)
+}
+
+func NewWindowInSession(session, name, dir, command string) error {
+	return Default().NewWindowInSession(session, name, dir, command)
+}
+
+func NewWindowInSessionPaneID(session, name, dir, command string) (string, error) {
+	return Default().NewWindowInSessionPaneID(session, name, dir, command)
+}
+
+func SplitWindowInDir(targetPane, dir, command string) (string, error) {
+	return Default().SplitWindowInDir(targetPane, dir, command)
+}
+
+func SplitWindowInDirPercent(targetPane, dir, command string, percent int) (string, error) {
+	return Default().SplitWindowInDirPercent(targetPane, dir, command, percent)
+}
+
+func RespawnPaneInDir(targetPane, dir, command string) error {
+	return Default().RespawnPaneInDir(targetPane, dir, command)
+}
+
+func SelectLayout(target, layout string) error { return Default().SelectLayout(target, layout) }
+
+func ListPanes() ([]Pane, error) { return Default().ListPanes() }
+
+func DisplayPopup(command, width, height string) error {
+	return Default().DisplayPopup(command, width, height)
+}
+
+func DisplayMessage(message string) error { return Default().DisplayMessage(message) }
@@ -0,0 +1,1147 @@
+// Package tmuxtest provides an in-memory tmux server for tests.
+//
+// Server implements tmux.Client. It keeps sessions, windows and panes with
+// their options and hooks, and reflects the @kitmux_* options into the
+// Session and Pane values it lists the way the real format strings do.
+// Install swaps it in behind the package-level tmux functions.
+package tmuxtest
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/tmux"
+)
+
+// DefaultShell is the command reported by panes created without one.
+const DefaultShell = "zsh"
+
+// Server is a fake tmux server. The zero value is not usable; call New.
+type Server struct {
+	mu sync.Mutex
+
+	sessions []*session
+	nextPane int
+	nextPID  int
+
+	// The single attached client, as seen from inside tmux.
+	clientSession string
+	clientWidth   int
+
+	calls       []string
+	messages    []string
+	popups      []string
+	globalHooks map[string]string
+	failures    map[string]error
+	now         func() time.Time
+}
+
+type session struct {
+	name     string
+	path     string
+	attached bool
+	activity int64
+	options  map[string]string
+	env      map[string]string
+	hooks    map[string]string
+	windows  []*window
+}
+
+type window struct {
+	index   int
+	name    string
+	active  bool
+	options map[string]string
+	panes   []*pane
+}
+
+type pane struct {
+	id      string
+	index   int
+	active  bool
+	command string // reported as #{pane_current_command}
+	start   string // the command line the pane was started with
+	pid     int
+	path    string
+	title   string
+	options map[string]string
+	sent    []string
+}
+
+var _ tmux.Client = (*Server)(nil)
+
+// New returns an empty server with an 80 column client.
+func New() *Server {
+	return &Server{
+		nextPID:     1000,
+		clientWidth: 80,
+		globalHooks: map[string]string{},
+		failures:    map[string]error{},
+		now:         time.Now,
+	}
+}
+
+// Install starts a new server and routes the package-level tmux functions
+// to it for the rest of the test.
+func Install(t testing.TB) *Server {
+	t.Helper()
+	s := New()
+	t.Cleanup(tmux.UseClient(s))
+	return s
+}
+
+// SetNow fixes the clock used for session activity.
+func (s *Server) SetNow(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// SetClientWidth sets the width reported by CurrentClientWidth.
+func (s *Server) SetClientWidth(width int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clientWidth = width
+}
+
+// Fail makes every later call of the named tmux command (for example
+// "kill-session") return err. A nil err clears the failure.
+func (s *Server) Fail(command string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.failures, command)
+		return
+	}
+	s.failures[command] = err
+}
+
+// Calls returns every command run so far, one "command arg..." per entry.
+func (s *Server) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+// Messages returns the text passed to DisplayMessage.
+func (s *Server) Messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+// Popups returns the commands passed to DisplayPopup.
+func (s *Server) Popups() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.popups...)
+}
+
+// AddSession creates a detached session in dir and returns its first pane.
+func (s *Server) AddSession(name, dir string) string {
+	id, err := s.NewSessionWithCommand(name, dir, "")
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// Attach points the client at target, marks the session attached and
+// makes target's pane the current one.
+func (s *Server) Attach(target string) {
+	if err := s.SwitchClient(target); err != nil {
+		panic(err)
+	}
+}
+
+// SetActivity sets a session's last activity time.
+func (s *Server) SetActivity(name string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess := s.findSession(name); sess != nil {
+		sess.activity = at.Unix()
+	}
+}
+
+// SetPaneCommand changes the command a pane reports as running.
+func (s *Server) SetPaneCommand(target, command string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, _, p, err := s.resolve(target); err == nil {
+		p.command = command
+	}
+}
+
+// SessionNames returns the names of all sessions, sorted.
+func (s *Server) SessionNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		names = append(names, sess.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SessionOption returns a session option, or "" when unset.
+func (s *Server) SessionOption(target, option string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, _, _, err := s.resolve(target)
+	if err != nil {
+		return ""
+	}
+	return sess.options[option]
+}
+
+// Environment returns a session environment variable, or "" when unset.
+func (s *Server) Environment(target, name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, _, _, err := s.resolve(target)
+	if err != nil {
+		return ""
+	}
+	return sess.env[name]
+}
+
+// WindowOption returns a window option, or "" when unset.
+func (s *Server) WindowOption(target, option string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, w, _, err := s.resolve(target)
+	if err != nil {
+		return ""
+	}
+	return w.options[option]
+}
+
+// PaneOption returns an option set on the pane itself, or "" when unset.
+func (s *Server) PaneOption(target, option string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _, p, err := s.resolve(target)
+	if err != nil {
+		return ""
+	}
+	return p.options[option]
+}
+
+// Hook returns the command of a session hook, or "" when unset.
+func (s *Server) Hook(target, hook string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, _, _, err := s.resolve(target)
+	if err != nil {
+		return ""
+	}
+	return sess.hooks[hook]
+}
+
+// GlobalHook returns the command of a global hook.
+func (s *Server) GlobalHook(hook string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.globalHooks[hook]
+}
+
+// PaneTitle returns a pane's title.
+func (s *Server) PaneTitle(target string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _, p, err := s.resolve(target)
+	if err != nil {
+		return ""
+	}
+	return p.title
+}
+
+// PaneCommand returns the command a pane was started with or last
+// respawned with.
+func (s *Server) PaneCommand(target string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _, p, err := s.resolve(target)
+	if err != nil {
+		return ""
+	}
+	return p.start
+}
+
+// PanePath returns a pane's working directory.
+func (s *Server) PanePath(target string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _, p, err := s.resolve(target)
+	if err != nil {
+		return ""
+	}
+	return p.path
+}
+
+// SentKeys returns the keys sent to a pane, one entry per SendKeys call.
+func (s *Server) SentKeys(target string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _, p, err := s.resolve(target)
+	if err != nil {
+		return nil
+	}
+	return append([]string(nil), p.sent...)
+}
+
+// Sessions.
+
+func (s *Server) ListSessions() ([]tmux.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("list-sessions"); err != nil {
+		return nil, err
+	}
+	out := make([]tmux.Session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		out = append(out, sess.snapshot())
+	}
+	return out, nil
+}
+
+func (s *Server) HasSession(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.begin("has-session", name) != nil {
+		return false
+	}
+	return s.findSession(name) != nil
+}
+
+func (s *Server) NewSessionInDir(name, dir string) error {
+	_, err := s.newSession("new-session", name, dir, "")
+	return err
+}
+
+func (s *Server) NewSessionWithEnv(name, dir string, env map[string]string) error {
+	if _, err := s.newSession("new-session", name, dir, ""); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range env {
+		s.findSession(name).env[k] = v
+	}
+	return nil
+}
+
+func (s *Server) NewSessionDetached(name string) error {
+	_, err := s.newSession("new-session", name, "", "")
+	return err
+}
+
+func (s *Server) NewSessionWithCommand(name, dir, command string) (string, error) {
+	return s.newSession("new-session", name, dir, command)
+}
+
+func (s *Server) newSession(command, name, dir, shell string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin(command, name, dir, shell); err != nil {
+		return "", err
+	}
+	if name == "" || strings.ContainsAny(name, ":.") {
+		return "", fmt.Errorf("invalid session name: %s", name)
+	}
+	if s.findSession(name) != nil {
+		return "", fmt.Errorf("duplicate session: %s", name)
+	}
+	sess := &session{
+		name:     name,
+		path:     dir,
+		activity: s.now().Unix(),
+		options:  map[string]string{},
+		env:      map[string]string{},
+		hooks:    map[string]string{},
+	}
+	w := s.newWindow(sess, "", dir, shell)
+	w.active = true
+	s.sessions = append(s.sessions, sess)
+	return w.panes[0].id, nil
+}
+
+func (s *Server) KillSession(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("kill-session", name); err != nil {
+		return err
+	}
+	for i, sess := range s.sessions {
+		if sess.name != name {
+			continue
+		}
+		s.sessions = append(s.sessions[:i], s.sessions[i+1:]...)
+		if s.clientSession == name {
+			// tmux moves the client to another session, or detaches it.
+			s.clientSession = ""
+			if len(s.sessions) > 0 {
+				s.clientSession = s.sessions[0].name
+				s.sessions[0].attached = true
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("can't find session: %s", name)
+}
+
+func (s *Server) RenameSession(old, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("rename-session", old, newName); err != nil {
+		return err
+	}
+	sess, _, _, err := s.resolve(old)
+	if err != nil {
+		return err
+	}
+	if s.findSession(newName) != nil {
+		return fmt.Errorf("duplicate session: %s", newName)
+	}
+	if s.clientSession == sess.name {
+		s.clientSession = newName
+	}
+	sess.name = newName
+	return nil
+}
+
+func (s *Server) DetachSessionClients(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("detach-client", name); err != nil {
+		return err
+	}
+	sess, _, _, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+	sess.attached = false
+	if s.clientSession == sess.name {
+		s.clientSession = ""
+	}
+	return nil
+}
+
+func (s *Server) SwitchClient(target string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("switch-client", target); err != nil {
+		return err
+	}
+	sess, w, p, err := s.resolve(target)
+	if err != nil {
+		return err
+	}
+	if prev := s.findSession(s.clientSession); prev != nil {
+		prev.attached = false
+	}
+	s.clientSession = sess.name
+	sess.attached = true
+	sess.selectWindow(w)
+	w.selectPane(p)
+	return nil
+}
+
+func (s *Server) RefreshClients(sessionName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("refresh-client", sessionName); err != nil {
+		return err
+	}
+	_, _, _, err := s.resolve(sessionName)
+	return err
+}
+
+func (s *Server) CurrentSession() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("display-message", "#{session_name}"); err != nil {
+		return "", err
+	}
+	sess, _, _, err := s.current()
+	if err != nil {
+		return "", err
+	}
+	return sess.name, nil
+}
+
+func (s *Server) CurrentThreadContext() (tmux.ThreadContext, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("display-message", "thread-context"); err != nil {
+		return tmux.ThreadContext{}, err
+	}
+	sess, w, p, err := s.current()
+	if err != nil {
+		return tmux.ThreadContext{}, err
+	}
+	return tmux.ThreadContext{
+		SessionName: sess.name,
+		PaneID:      p.id,
+		Thread:      lookup("@kitmux_thread", p, w, sess) == "1",
+		AgentID:     lookup("@kitmux_agent", p, w, sess),
+	}, nil
+}
+
+func (s *Server) CurrentClientWidth() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("display-message", "#{client_width}"); err != nil {
+		return 0, err
+	}
+	if _, _, _, err := s.current(); err != nil {
+		return 0, err
+	}
+	return s.clientWidth, nil
+}
+
+// Windows.
+
+func (s *Server) ListWindows(name string) ([]tmux.Window, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("list-windows", name); err != nil {
+		return nil, err
+	}
+	sess, _, _, err := s.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]tmux.Window, 0, len(sess.windows))
+	for _, w := range sess.windows {
+		out = append(out, tmux.Window{
+			SessionName: name,
+			Index:       w.index,
+			Name:        w.name,
+			Active:      w.active,
+		})
+	}
+	return out, nil
+}
+
+func (s *Server) NewWindowWithCommand(name, command string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("new-window", name, command); err != nil {
+		return err
+	}
+	sess, _, _, err := s.current()
+	if err != nil {
+		return err
+	}
+	sess.selectWindow(s.newWindow(sess, name, sess.path, command))
+	return nil
+}
+
+func (s *Server) NewWindowInDir(name, dir, command string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("new-window", name, dir, command); err != nil {
+		return "", err
+	}
+	sess, _, _, err := s.current()
+	if err != nil {
+		return "", err
+	}
+	w := s.newWindow(sess, name, dir, command)
+	sess.selectWindow(w)
+	return w.panes[0].id, nil
+}
+
+func (s *Server) NewWindowInSession(name, windowName, dir, command string) error {
+	_, err := s.NewWindowInSessionPaneID(name, windowName, dir, command)
+	return err
+}
+
+func (s *Server) NewWindowInSessionPaneID(name, windowName, dir, command string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("new-window", name, windowName, dir, command); err != nil {
+		return "", err
+	}
+	sess, _, _, err := s.resolve(name)
+	if err != nil {
+		return "", err
+	}
+	if dir == "" {
+		dir = sess.path
+	}
+	w := s.newWindow(sess, windowName, dir, command)
+	return w.panes[0].id, nil
+}
+
+func (s *Server) RenameWindow(target, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("rename-window", target, newName); err != nil {
+		return err
+	}
+	_, w, _, err := s.resolve(target)
+	if err != nil {
+		return err
+	}
+	w.name = newName
+	return nil
+}
+
+func (s *Server) SelectWindow(target string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("select-window", target); err != nil {
+		return err
+	}
+	sess, w, _, err := s.resolve(target)
+	if err != nil {
+		return err
+	}
+	sess.selectWindow(w)
+	return nil
+}
+
+func (s *Server) SelectLayout(target, layout string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("select-layout", target, layout); err != nil {
+		return err
+	}
+	_, _, _, err := s.resolve(target)
+	return err
+}
+
+// Panes.
+
+func (s *Server) ListPanes() ([]tmux.Pane, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("list-panes"); err != nil {
+		return nil, err
+	}
+	var out []tmux.Pane
+	for _, sess := range s.sessions {
+		for _, w := range sess.windows {
+			for _, p := range w.panes {
+				out = append(out, p.snapshot(sess, w))
+			}
+		}
+	}
+	return out, nil
+}
+
+func (s *Server) SelectPane(target string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("select-pane", target); err != nil {
+		return err
+	}
+	_, w, p, err := s.resolve(target)
+	if err != nil {
+		return err
+	}
+	w.selectPane(p)
+	return nil
+}
+
+func (s *Server) SplitWindow(command string) error {
+	_, err := s.split("", "", "", command)
+	return err
+}
+
+func (s *Server) SplitWindowInDir(targetPane, dir, command string) (string, error) {
+	return s.split("", targetPane, dir, command)
+}
+
+// SplitWindowInDirPercent records the size as "split-window -p N ..." in
+// Calls; the fake has no geometry.
+func (s *Server) SplitWindowInDirPercent(targetPane, dir, command string, percent int) (string, error) {
+	return s.split("-p "+strconv.Itoa(percent), targetPane, dir, command)
+}
+
+func (s *Server) split(size, target, dir, command string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("split-window", size, target, dir, command); err != nil {
+		return "", err
+	}
+	sess, w, _, err := s.resolveOrCurrent(target)
+	if err != nil {
+		return "", err
+	}
+	if dir == "" {
+		dir = sess.path
+	}
+	p := s.newPane(w, dir, command)
+	w.selectPane(p)
+	return p.id, nil
+}
+
+func (s *Server) RespawnPaneInDir(targetPane, dir, command string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("respawn-pane", targetPane, dir, command); err != nil {
+		return err
+	}
+	_, _, p, err := s.resolveOrCurrent(targetPane)
+	if err != nil {
+		return err
+	}
+	if dir != "" {
+		p.path = dir
+	}
+	p.command = paneCommand(command)
+	p.start = command
+	p.pid = s.pid()
+	return nil
+}
+
+func (s *Server) SendKeys(target, keys string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("send-keys", target, keys); err != nil {
+		return err
+	}
+	_, _, p, err := s.resolveOrCurrent(target)
+	if err != nil {
+		return err
+	}
+	p.sent = append(p.sent, keys)
+	return nil
+}
+
+func (s *Server) SetPaneTitle(target, title string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("select-pane", target, "-T", title); err != nil {
+		return err
+	}
+	_, _, p, err := s.resolve(target)
+	if err != nil {
+		return err
+	}
+	p.title = title
+	return nil
+}
+
+func (s *Server) CurrentPaneTitle() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("display-message", "#{pane_title}"); err != nil {
+		return "", err
+	}
+	_, _, p, err := s.current()
+	if err != nil {
+		return "", err
+	}
+	return p.title, nil
+}
+
+func (s *Server) CurrentPanePath() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("display-message", "#{pane_current_path}"); err != nil {
+		return "", err
+	}
+	_, _, p, err := s.current()
+	if err != nil {
+		return "", err
+	}
+	return p.path, nil
+}
+
+// Options.
+
+func (s *Server) SetSessionOption(target, option, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("set-option", target, option, value); err != nil {
+		return err
+	}
+	sess, _, _, err := s.resolve(target)
+	if err != nil {
+		return err
+	}
+	sess.options[option] = value
+	return nil
+}
+
+func (s *Server) SetEnvironment(target, name, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("set-environment", target, name, value); err != nil {
+		return err
+	}
+	sess, _, _, err := s.resolve(target)
+	if err != nil {
+		return err
+	}
+	sess.env[name] = value
+	return nil
+}
+
+func (s *Server) SetCurrentSessionOption(option, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("set-option", option, value); err != nil {
+		return err
+	}
+	sess, _, _, err := s.current()
+	if err != nil {
+		return err
+	}
+	sess.options[option] = value
+	return nil
+}
+
+func (s *Server) SetWindowOption(target, option, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("set-window-option", target, option, value); err != nil {
+		return err
+	}
+	_, w, _, err := s.resolve(target)
+	if err != nil {
+		return err
+	}
+	w.options[option] = value
+	return nil
+}
+
+func (s *Server) SetPaneOption(target, option, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("set-option -p", target, option, value); err != nil {
+		return err
+	}
+	_, _, p, err := s.resolve(target)
+	if err != nil {
+		return err
+	}
+	p.options[option] = value
+	return nil
+}
+
+func (s *Server) SetCurrentPaneOption(option, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("set-option -p", option, value); err != nil {
+		return err
+	}
+	_, _, p, err := s.current()
+	if err != nil {
+		return err
+	}
+	p.options[option] = value
+	return nil
+}
+
+func (s *Server) ShowSessionOption(target, option string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("show-option", target, option); err != nil {
+		return "", err
+	}
+	sess, _, _, err := s.resolve(target)
+	if err != nil {
+		return "", err
+	}
+	return sess.options[option], nil
+}
+
+func (s *Server) ShowPaneOption(target, option string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("show-option -p", target, option); err != nil {
+		return "", err
+	}
+	_, _, p, err := s.resolve(target)
+	if err != nil {
+		return "", err
+	}
+	return p.options[option], nil
+}
+
+// Hooks.
+
+func (s *Server) SetHook(target, hook, command string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("set-hook", target, hook, command); err != nil {
+		return err
+	}
+	sess, _, _, err := s.resolve(target)
+	if err != nil {
+		return err
+	}
+	sess.hooks[hook] = command
+	return nil
+}
+
+func (s *Server) SetGlobalHook(hook, command string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("set-hook -g", hook, command); err != nil {
+		return err
+	}
+	s.globalHooks[hook] = command
+	return nil
+}
+
+// Popups and messages.
+
+func (s *Server) DisplayPopup(command, width, height string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("display-popup", command, width, height); err != nil {
+		return err
+	}
+	s.popups = append(s.popups, command)
+	return nil
+}
+
+func (s *Server) DisplayMessage(message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.begin("display-message", message); err != nil {
+		return err
+	}
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+// begin records a call and returns the failure configured for it.
+func (s *Server) begin(command string, args ...string) error {
+	parts := []string{command}
+	for _, arg := range args {
+		if arg != "" {
+			parts = append(parts, arg)
+		}
+	}
+	s.calls = append(s.calls, strings.Join(parts, " "))
+	return s.failures[strings.Fields(command)[0]]
+}
+
+func (s *Server) findSession(name string) *session {
+	for _, sess := range s.sessions {
+		if sess.name == name {
+			return sess
+		}
+	}
+	return nil
+}
+
+// resolve understands the targets kitmux uses: "%id" panes, "session",
+// "session:", "session:index" and "session:index.pane".
+func (s *Server) resolve(target string) (*session, *window, *pane, error) {
+	if strings.HasPrefix(target, "%") {
+		for _, sess := range s.sessions {
+			for _, w := range sess.windows {
+				for _, p := range w.panes {
+					if p.id == target {
+						return sess, w, p, nil
+					}
+				}
+			}
+		}
+		return nil, nil, nil, fmt.Errorf("can't find pane: %s", target)
+	}
+	name, rest, hasWindow := strings.Cut(target, ":")
+	sess := s.findSession(name)
+	if sess == nil {
+		return nil, nil, nil, fmt.Errorf("can't find session: %s", name)
+	}
+	w := sess.activeWindow()
+	windowPart, panePart, hasPane := strings.Cut(rest, ".")
+	if hasWindow && windowPart != "" {
+		idx, err := strconv.Atoi(windowPart)
+		w = nil
+		for _, candidate := range sess.windows {
+			if (err == nil && candidate.index == idx) || (err != nil && candidate.name == windowPart) {
+				w = candidate
+				break
+			}
+		}
+		if w == nil {
+			return nil, nil, nil, fmt.Errorf("can't find window: %s", windowPart)
+		}
+	}
+	p := w.activePane()
+	if hasPane {
+		idx, err := strconv.Atoi(panePart)
+		p = nil
+		for _, candidate := range w.panes {
+			if err == nil && candidate.index == idx {
+				p = candidate
+				break
+			}
+		}
+		if p == nil {
+			return nil, nil, nil, fmt.Errorf("can't find pane: %s", panePart)
+		}
+	}
+	return sess, w, p, nil
+}
+
+// resolveOrCurrent treats "" and "!" (the last pane, which in a one-client
+// fake is the current one) as the client's current pane.
+func (s *Server) resolveOrCurrent(target string) (*session, *window, *pane, error) {
+	if target == "" || target == "!" {
+		return s.current()
+	}
+	return s.resolve(target)
+}
+
+func (s *Server) current() (*session, *window, *pane, error) {
+	sess := s.findSession(s.clientSession)
+	if sess == nil {
+		return nil, nil, nil, fmt.Errorf("no current client")
+	}
+	w := sess.activeWindow()
+	return sess, w, w.activePane(), nil
+}
+
+func (s *Server) newWindow(sess *session, name, dir, command string) *window {
+	index := 0
+	for _, w := range sess.windows {
+		if w.index >= index {
+			index = w.index + 1
+		}
+	}
+	w := &window{index: index, name: name, options: map[string]string{}}
+	p := s.newPane(w, dir, command)
+	if w.name == "" {
+		w.name = p.command
+	}
+	sess.windows = append(sess.windows, w)
+	return w
+}
+
+func (s *Server) newPane(w *window, dir, command string) *pane {
+	p := &pane{
+		id:      fmt.Sprintf("%%%d", s.nextPane),
+		index:   len(w.panes),
+		command: paneCommand(command),
+		start:   command,
+		pid:     s.pid(),
+		path:    dir,
+		title:   "localhost",
+		options: map[string]string{},
+	}
+	s.nextPane++
+	w.panes = append(w.panes, p)
+	if len(w.panes) == 1 {
+		p.active = true
+	}
+	return p
+}
+
+func (s *Server) pid() int {
+	s.nextPID++
+	return s.nextPID
+}
+
+// paneCommand approximates #{pane_current_command}: the base name of the
+// first word of the command, or the default shell.
+func paneCommand(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return DefaultShell
+	}
+	return filepath.Base(fields[0])
+}
+
+func (sess *session) activeWindow() *window {
+	for _, w := range sess.windows {
+		if w.active {
+			return w
+		}
+	}
+	return sess.windows[0]
+}
+
+func (sess *session) selectWindow(target *window) {
+	for _, w := range sess.windows {
+		w.active = w == target
+	}
+}
+
+func (w *window) activePane() *pane {
+	for _, p := range w.panes {
+		if p.active {
+			return p
+		}
+	}
+	return w.panes[0]
+}
+
+func (w *window) selectPane(target *pane) {
+	for _, p := range w.panes {
+		p.active = p == target
+	}
+}
+
+func (sess *session) snapshot() tmux.Session {
+	opt := func(name string) string { return sess.options[name] }
+	updated, _ := strconv.ParseInt(opt("@kitmux_agent_updated"), 10, 64)
+	return tmux.Session{
+		Name:              sess.name,
+		Windows:           len(sess.windows),
+		Attached:          sess.attached,
+		Path:              sess.path,
+		Activity:          sess.activity,
+		Thread:            opt("@kitmux_thread") == "1",
+		AgentID:           opt("@kitmux_agent"),
+		AgentState:        opt("@kitmux_agent_state"),
+		AgentEvent:        opt("@kitmux_agent_event"),
+		AgentDetail:       opt("@kitmux_agent_detail"),
+		AgentUpdated:      updated,
+		ThreadTitle:       opt("@kitmux_thread_title"),
+		AgentTitlePrefix:  opt("@kitmux_agent_title_prefix"),
+		AgentTitleDisplay: opt("@kitmux_agent_title_display"),
+		InitialTitle:      opt("@kitmux_initial_title"),
+		AgentSessionID:    opt("@kitmux_agent_session_id"),
+	}
+}
+
+func (p *pane) snapshot(sess *session, w *window) tmux.Pane {
+	opt := func(name string) string { return lookup(name, p, w, sess) }
+	updated, _ := strconv.ParseInt(opt("@kitmux_agent_updated"), 10, 64)
+	return tmux.Pane{
+		SessionName:       sess.name,
+		WindowIndex:       w.index,
+		PaneIndex:         p.index,
+		ID:                p.id,
+		Command:           p.command,
+		PID:               p.pid,
+		Path:              p.path,
+		Title:             p.title,
+		AgentState:        opt("@kitmux_agent_state"),
+		AgentEvent:        opt("@kitmux_agent_event"),
+		AgentDetail:       opt("@kitmux_agent_detail"),
+		AgentUpdated:      updated,
+		AgentTitlePrefix:  opt("@kitmux_agent_title_prefix"),
+		AgentTitleDisplay: opt("@kitmux_agent_title_display"),
+		AgentSessionID:    opt("@kitmux_agent_session_id"),
+	}
+}
+
+// lookup resolves a user option in a pane format the way tmux does: pane,
+// then window, then session.
+func lookup(name string, p *pane, w *window, sess *session) string {
+	for _, options := range []map[string]string{p.options, w.options, sess.options} {
+		if value, ok := options[name]; ok {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+package tmuxtest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/miltonparedes/kitmux/internal/tmux"
+)
+
+func TestInstallRoutesPackageFunctions(t *testing.T) {
+	srv := Install(t)
+	srv.AddSession("app", "/tmp/app")
+
+	if !tmux.HasSession("app") {
+		t.Fatal("expected tmux.HasSession to see the fake session")
+	}
+	if err := tmux.RenameSession("app", "web"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if got := srv.SessionNames(); !reflect.DeepEqual(got, []string{"web"}) {
+		t.Fatalf("sessions = %v, want [web]", got)
+	}
+}
+
+func TestNewSessionRejectsDuplicates(t *testing.T) {
+	srv := New()
+	srv.AddSession("app", "/tmp/app")
+
+	if err := srv.NewSessionInDir("app", "/tmp/other"); err == nil || err.Error() != "duplicate session: app" {
+		t.Fatalf("err = %v, want duplicate session", err)
+	}
+}
+
+func TestResolveTargets(t *testing.T) {
+	srv := New()
+	first := srv.AddSession("app", "/tmp/app")
+	if _, err := srv.NewWindowInSessionPaneID("app", "logs", "/tmp/logs", "tail -f log"); err != nil {
+		t.Fatalf("new window: %v", err)
+	}
+
+	for target, want := range map[string]string{
+		first:        "/tmp/app",
+		"app":        "/tmp/app",
+		"app:":       "/tmp/app",
+		"app:1":      "/tmp/logs",
+		"app:logs":   "/tmp/logs",
+		"app:1.0":    "/tmp/logs",
+		"missing:0":  "",
+		"app:9":      "",
+		"%not-there": "",
+	} {
+		if got := srv.PanePath(target); got != want {
+			t.Errorf("PanePath(%q) = %q, want %q", target, got, want)
+		}
+	}
+	if got := srv.PaneCommand("app:logs"); got != "tail -f log" {
+		t.Fatalf("PaneCommand = %q", got)
+	}
+	panes, _ := srv.ListPanes()
+	if len(panes) != 2 || panes[1].Command != "tail" {
+		t.Fatalf("panes = %+v, want tail as the reported command", panes)
+	}
+}
+
+func TestOptionsReflectIntoListings(t *testing.T) {
+	srv := New()
+	pane := srv.AddSession("droid-app", "/tmp/app")
+	_ = srv.SetSessionOption("droid-app", "@kitmux_thread", "1")
+	_ = srv.SetSessionOption("droid-app", "@kitmux_agent_state", "working")
+	_ = srv.SetPaneOption(pane, "@kitmux_agent_state", "input")
+
+	sessions, _ := srv.ListSessions()
+	if len(sessions) != 1 || !sessions[0].Thread || sessions[0].AgentState != "working" {
+		t.Fatalf("sessions = %+v", sessions)
+	}
+	panes, _ := srv.ListPanes()
+	if len(panes) != 1 || panes[0].AgentState != "input" {
+		t.Fatalf("panes = %+v, want pane option to win", panes)
+	}
+	if got, _ := srv.ShowPaneOption(pane, "@kitmux_thread"); got != "" {
+		t.Fatalf("show-option -p inherited %q from the session", got)
+	}
+}
+
+func TestKillSessionMovesClient(t *testing.T) {
+	srv := New()
+	srv.AddSession("a", "/tmp/a")
+	srv.Attach(srv.AddSession("b", "/tmp/b"))
+
+	if err := srv.KillSession("b"); err != nil {
+		t.Fatalf("kill: %v", err)
+	}
+	if current, _ := srv.CurrentSession(); current != "a" {
+		t.Fatalf("current = %q, want a", current)
+	}
+	if err := srv.KillSession("b"); err == nil {
+		t.Fatal("expected error killing a missing session")
+	}
+}
+
+func TestCurrentRequiresClient(t *testing.T) {
+	srv := New()
+	srv.AddSession("a", "/tmp/a")
+
+	if _, err := srv.CurrentSession(); err == nil {
+		t.Fatal("expected error without an attached client")
+	}
+}
+
+func TestFailAndCalls(t *testing.T) {
+	srv := New()
+	srv.AddSession("a", "/tmp/a")
+	srv.Fail("kill-session", errors.New("refused"))
+
+	if err := srv.KillSession("a"); err == nil || err.Error() != "refused" {
+		t.Fatalf("err = %v, want refused", err)
+	}
+	if got := srv.SessionNames(); len(got) != 1 {
+		t.Fatalf("failed kill removed the session: %v", got)
+	}
+	want := []string{"new-session a /tmp/a", "kill-session a"}
+	if got := srv.Calls(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls = %#v, want %#v", got, want)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/miltonparedes/kitmux/internal/agents"
 	"github.com/miltonparedes/kitmux/internal/app/messages"
 	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/frecency"
 )
 
 // Model is the agents list view.
@@ -22,11 +23,22 @@ func New() Model {
 	agentList := agents.DefaultAgents()
 	return Model{
 		agents:    agentList,
-		modeIndex: make([]int, len(agentList)),
+		cursor:    agents.Index(agentList, config.DefaultAgent()),
+		modeIndex: agents.ModeIndexes(agentList, config.AgentMode),
 	}
 }
 
-func (m Model) Init() tea.Cmd { return nil }
+// scoresLoadedMsg carries agent frecency for reordering the list.
+type scoresLoadedMsg struct {
+	scores frecency.Scores
+}
+
+// Init loads agent frecency so the most used agents list first.
+func (m Model) Init() tea.Cmd {
+	return func() tea.Msg {
+		return scoresLoadedMsg{scores: frecency.Load(frecency.KindAgent)}
+	}
+}
 
 func (m *Model) SetSize(w, h int) {
 	m.width = w
@@ -38,6 +50,12 @@ func (m Model) IsEditing() bool { return false }
 
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case scoresLoadedMsg:
+		m.agents = frecency.Sort(agents.DefaultAgents(), msg.scores, func(a agents.Agent) string { return a.ID })
+		m.cursor = agents.Index(m.agents, config.DefaultAgent())
+		m.modeIndex = agents.ModeIndexes(m.agents, config.AgentMode)
+		m.scroll = 0
+		return m, nil
 	case tea.MouseMsg:
 		return m.handleMouse(msg)
 	case tea.KeyMsg:
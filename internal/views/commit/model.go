@@ -0,0 +1,230 @@
+package commit
+
+import (
+	"os"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/commitmsg"
+	"github.com/miltonparedes/kitmux/internal/gitdiff"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+)
+
+// Model drafts a commit message for the staged changes of a worktree with
+// the configured generator and lets the user edit it before committing.
+type Model struct {
+	dir       string // requested directory, "" for the current pane's
+	root      string
+	gen       commitmsg.Generator
+	hasGen    bool
+	files     []gitdiff.File
+	stagedAll bool
+
+	input      textarea.Model
+	loading    bool
+	generating bool
+	committing bool
+	status     string
+	failed     bool
+
+	width  int
+	height int
+}
+
+type (
+	loadedMsg struct {
+		root      string
+		files     []gitdiff.File
+		gen       commitmsg.Generator
+		hasGen    bool
+		stagedAll bool
+		err       error
+	}
+	generatedMsg struct {
+		msg string
+		err error
+	}
+	committedMsg struct {
+		err error
+	}
+	unstagedMsg struct {
+		err error
+	}
+)
+
+// Package-level seams for tests.
+var (
+	generate = func(g commitmsg.Generator, dir string) (string, error) { return g.Generate(dir) }
+	commit   = commitmsg.Commit
+	unstage  = commitmsg.Unstage
+)
+
+// New builds a commit view for the worktree containing dir. An empty dir
+// uses the current tmux pane's directory.
+func New(dir string) Model {
+	ta := textarea.New()
+	ta.Placeholder = "commit message"
+	ta.ShowLineNumbers = false
+	ta.Prompt = " "
+	ta.Focus()
+	return Model{dir: dir, input: ta, loading: true}
+}
+
+func (m *Model) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.input.SetWidth(max(w-2, 10))
+	m.input.SetHeight(max(h-m.chromeHeight(), 3))
+}
+
+func (m Model) Init() tea.Cmd {
+	dir := m.dir
+	return tea.Batch(textarea.Blink, func() tea.Msg {
+		if dir == "" {
+			dir = currentDir()
+		}
+		return load(dir)
+	})
+}
+
+func currentDir() string {
+	if path, err := tmux.CurrentPanePath(); err == nil && path != "" {
+		return path
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// load stages everything when nothing is staged yet, as the commit
+// commands of the worktree backends do, and lists the staged files.
+// Cancelling the view unstages them again.
+func load(dir string) loadedMsg {
+	t, err := gitdiff.Open(dir)
+	if err != nil {
+		return loadedMsg{err: err}
+	}
+	msg := loadedMsg{root: t.Dir}
+	staged, err := commitmsg.Staged(t.Dir)
+	if err != nil {
+		msg.err = err
+		return msg
+	}
+	if !staged {
+		if msg.err = commitmsg.StageAll(t.Dir); msg.err != nil {
+			return msg
+		}
+		msg.stagedAll = true
+	}
+	msg.files, msg.err = t.Files(gitdiff.Staged)
+	msg.gen, msg.hasGen = commitmsg.ForDir(t.Dir)
+	return msg
+}
+
+func (m Model) generateCmd() tea.Cmd {
+	g, dir := m.gen, m.root
+	return func() tea.Msg {
+		msg, err := generate(g, dir)
+		return generatedMsg{msg: msg, err: err}
+	}
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case loadedMsg:
+		return m.handleLoaded(msg)
+	case generatedMsg:
+		m.generating = false
+		if msg.err != nil {
+			m.status, m.failed = msg.err.Error(), true
+			return m, nil
+		}
+		m.input.SetValue(msg.msg)
+		m.status, m.failed = "", false
+		return m, nil
+	case committedMsg:
+		m.committing = false
+		if msg.err != nil {
+			m.status, m.failed = msg.err.Error(), true
+			return m, nil
+		}
+		return m, func() tea.Msg { return messages.CloseCommitMsg{Committed: true} }
+	case unstagedMsg:
+		if msg.err != nil {
+			m.status, m.failed = "restore index: "+msg.err.Error(), true
+			return m, nil
+		}
+		return m, func() tea.Msg { return messages.CloseCommitMsg{} }
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m Model) handleLoaded(msg loadedMsg) (Model, tea.Cmd) {
+	m.loading = false
+	m.root, m.files, m.gen, m.hasGen, m.stagedAll = msg.root, msg.files, msg.gen, msg.hasGen, msg.stagedAll
+	m.SetSize(m.width, m.height)
+	if msg.err != nil {
+		m.status, m.failed = msg.err.Error(), true
+		return m, nil
+	}
+	if len(m.files) == 0 {
+		m.status, m.failed = commitmsg.ErrNothingStaged.Error(), true
+		return m, nil
+	}
+	if !m.hasGen {
+		m.status = "no commit generator configured; write the message"
+		return m, nil
+	}
+	m.generating = true
+	return m, m.generateCmd()
+}
+
+func (m Model) handleKey(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		return m.cancel()
+	case "ctrl+s":
+		if m.busy() || len(m.files) == 0 {
+			return m, nil
+		}
+		m.committing = true
+		dir, text := m.root, m.input.Value()
+		return m, func() tea.Msg { return committedMsg{err: commit(dir, text)} }
+	case "ctrl+r":
+		if m.busy() || !m.hasGen || len(m.files) == 0 {
+			return m, nil
+		}
+		m.generating = true
+		m.status, m.failed = "", false
+		return m, m.generateCmd()
+	}
+	if m.committing {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// cancel closes the view, first unstaging what opening it staged so the
+// index is left as it was. It waits while loading or committing, when the
+// index is still changing.
+func (m Model) cancel() (Model, tea.Cmd) {
+	if m.loading || m.committing {
+		return m, nil
+	}
+	if !m.stagedAll {
+		return m, func() tea.Msg { return messages.CloseCommitMsg{} }
+	}
+	dir := m.root
+	return m, func() tea.Msg { return unstagedMsg{err: unstage(dir)} }
+}
+
+func (m Model) busy() bool {
+	return m.loading || m.generating || m.committing
+}
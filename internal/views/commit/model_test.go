@@ -0,0 +1,156 @@
+package commit
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/commitmsg"
+	"github.com/miltonparedes/kitmux/internal/gitdiff"
+)
+
+func stubSeams(t *testing.T, draft string, commitErr error) *string {
+	t.Helper()
+	var committed string
+	prevGenerate, prevCommit := generate, commit
+	generate = func(commitmsg.Generator, string) (string, error) { return draft, nil }
+	commit = func(_, msg string) error {
+		committed = msg
+		return commitErr
+	}
+	t.Cleanup(func() { generate, commit = prevGenerate, prevCommit })
+	return &committed
+}
+
+func loadedModel(hasGen bool) (Model, tea.Cmd) {
+	m := New("/repo")
+	m.SetSize(80, 24)
+	return m.Update(loadedMsg{
+		root:   "/repo",
+		files:  []gitdiff.File{{Path: "login.go", Status: gitdiff.StatusModified, Added: 3}},
+		gen:    commitmsg.Generator{Command: "llm"},
+		hasGen: hasGen,
+	})
+}
+
+func TestGeneratedMessageIsEditedAndCommitted(t *testing.T) {
+	committed := stubSeams(t, "fix: handle expired sessions", nil)
+
+	m, cmd := loadedModel(true)
+	if !m.generating || cmd == nil {
+		t.Fatal("expected generation to start once the staged files load")
+	}
+	m, _ = m.Update(cmd())
+	if got := m.input.Value(); got != "fix: handle expired sessions" {
+		t.Fatalf("draft = %q", got)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("!")})
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	if cmd == nil {
+		t.Fatal("ctrl+s should commit")
+	}
+	m, cmd = m.Update(cmd())
+	if *committed != "fix: handle expired sessions!" {
+		t.Fatalf("committed %q", *committed)
+	}
+	if cmd == nil {
+		t.Fatal("expected the view to close after committing")
+	}
+	if msg, ok := cmd().(messages.CloseCommitMsg); !ok || !msg.Committed {
+		t.Fatalf("close message = %#v", msg)
+	}
+}
+
+func TestCommitFailureStaysOpen(t *testing.T) {
+	stubSeams(t, "feat: avatars", errors.New("git commit: pre-commit hook failed"))
+
+	m, cmd := loadedModel(true)
+	m, _ = m.Update(cmd())
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	m, cmd = m.Update(cmd())
+	if cmd != nil || !m.failed || !strings.Contains(m.View(), "pre-commit hook failed") {
+		t.Fatalf("expected the error in the footer:\n%s", m.View())
+	}
+}
+
+func TestWithoutGeneratorWritesByHand(t *testing.T) {
+	stubSeams(t, "", nil)
+
+	m, cmd := loadedModel(false)
+	if cmd != nil || m.generating {
+		t.Fatal("nothing to generate without a generator")
+	}
+	if !strings.Contains(m.View(), "no commit generator configured") {
+		t.Fatalf("view:\n%s", m.View())
+	}
+}
+
+func TestLoadStagesEverythingWhenNothingIsStaged(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := load(dir)
+	if msg.err != nil || !msg.stagedAll || len(msg.files) != 1 || msg.files[0].Path != "a.txt" {
+		t.Fatalf("load() = %+v", msg)
+	}
+}
+
+func TestOpenThenEscLeavesIndexUnchanged(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	stubSeams(t, "feat: add a", nil)
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(dir)
+	m, _ = m.Update(load(dir))
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd == nil {
+		t.Fatal("expected esc to close the view")
+	}
+	m, cmd = m.Update(cmd())
+	if cmd == nil {
+		t.Fatalf("view stayed open: %s", m.status)
+	}
+	if _, ok := cmd().(messages.CloseCommitMsg); !ok {
+		t.Fatalf("msg = %#v, want CloseCommitMsg", cmd())
+	}
+	if staged, err := commitmsg.Staged(dir); err != nil || staged {
+		t.Fatalf("staged = %v, %v; cancelling must restore the index", staged, err)
+	}
+}
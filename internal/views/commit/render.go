@@ -0,0 +1,117 @@
+package commit
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/miltonparedes/kitmux/internal/gitdiff"
+	"github.com/miltonparedes/kitmux/internal/theme"
+)
+
+// maxFileRows caps the staged file list above the editor.
+const maxFileRows = 6
+
+func (m Model) fileRows() int {
+	switch {
+	case len(m.files) == 0:
+		return 1
+	case len(m.files) > maxFileRows:
+		return maxFileRows + 1
+	}
+	return len(m.files)
+}
+
+// chromeHeight is the number of rows around the editor: header, staged
+// files, two separators and the footer.
+func (m Model) chromeHeight() int {
+	return m.fileRows() + 4
+}
+
+func (m Model) View() string {
+	var b strings.Builder
+	sep := " " + theme.TreeConnector.Render(strings.Repeat("─", max(m.width-2, 1)))
+
+	b.WriteString(m.header())
+	b.WriteString("\n")
+	for _, line := range m.fileLines() {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	b.WriteString(sep)
+	b.WriteString("\n")
+	b.WriteString(m.input.View())
+	b.WriteString("\n")
+	b.WriteString(sep)
+	b.WriteString("\n")
+	b.WriteString(m.footer())
+	return b.String()
+}
+
+func (m Model) header() string {
+	parts := []string{" " + theme.PanelTitle.Render("Commit")}
+	if m.root != "" {
+		parts = append(parts, theme.TreeMeta.Render(filepath.Base(m.root)))
+	}
+	if m.stagedAll {
+		parts = append(parts, theme.DirtyBadge.Render("staged all changes"))
+	}
+	if m.gen.Conventional {
+		parts = append(parts, theme.HelpStyle.Render("conventional"))
+	}
+	return ansi.Truncate(strings.Join(parts, "  "), max(m.width, 1), "…")
+}
+
+func (m Model) fileLines() []string {
+	if len(m.files) == 0 {
+		text := " No staged changes"
+		if m.loading {
+			text = " Loading…"
+		}
+		return []string{theme.HelpStyle.Render(text)}
+	}
+	var lines []string
+	for i, f := range m.files {
+		if i == maxFileRows {
+			lines = append(lines, theme.TreeMeta.Render(fmt.Sprintf("   +%d more", len(m.files)-maxFileRows)))
+			break
+		}
+		lines = append(lines, renderFile(f, m.width))
+	}
+	return lines
+}
+
+func renderFile(f gitdiff.File, width int) string {
+	stats := ""
+	if f.Binary {
+		stats = theme.TreeMeta.Render("bin")
+	} else {
+		stats = theme.DiffAdded.Render(fmt.Sprintf("+%d", f.Added)) + " " + theme.DiffRemoved.Render(fmt.Sprintf("-%d", f.Deleted))
+	}
+	path := f.Path
+	if f.OldPath != "" && f.OldPath != f.Path {
+		path = f.OldPath + " → " + f.Path
+	}
+	line := " " + theme.DirtyBadge.Render(f.Status) + " " + path + "  " + stats
+	return ansi.Truncate(line, max(width, 1), "…")
+}
+
+func (m Model) footer() string {
+	switch {
+	case m.generating:
+		return theme.HelpStyle.Render(" generating message with " + m.gen.Command + "…")
+	case m.committing:
+		return theme.HelpStyle.Render(" committing…")
+	case m.status != "" && m.failed:
+		return theme.DiffRemoved.Render(" " + ansi.Truncate(m.status, max(m.width-2, 1), "…"))
+	case m.status != "":
+		return theme.HelpStyle.Render(" " + m.status)
+	}
+	help := " ctrl+s commit  esc cancel"
+	if m.hasGen {
+		help = " ctrl+s commit  ctrl+r regenerate  esc cancel"
+	}
+	return theme.HelpStyle.Render(help)
+}
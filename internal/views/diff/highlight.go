@@ -0,0 +1,62 @@
+package diff
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/miltonparedes/kitmux/internal/theme"
+)
+
+// highlighter colors code by token type with the lexer chroma picks for a
+// file name. Lines are lexed one at a time, so constructs spanning lines
+// such as block comments are only recognized on their first line.
+type highlighter struct {
+	lexer chroma.Lexer
+}
+
+func newHighlighter(path string) highlighter {
+	lexer := lexers.Match(filepath.Base(path))
+	if lexer == nil {
+		return highlighter{}
+	}
+	return highlighter{lexer: chroma.Coalesce(lexer)}
+}
+
+func (h highlighter) line(text string) string {
+	text = strings.ReplaceAll(text, "\t", "    ")
+	if h.lexer == nil {
+		return text
+	}
+	it, err := h.lexer.Tokenise(nil, text)
+	if err != nil {
+		return text
+	}
+	var b strings.Builder
+	for _, tok := range it.Tokens() {
+		value := strings.TrimSuffix(tok.Value, "\n")
+		if style, ok := tokenStyle(tok.Type); ok && strings.TrimSpace(value) != "" {
+			b.WriteString(style.Render(value))
+		} else {
+			b.WriteString(value)
+		}
+	}
+	return b.String()
+}
+
+func tokenStyle(t chroma.TokenType) (lipgloss.Style, bool) {
+	switch {
+	case t.InCategory(chroma.Comment):
+		return theme.SyntaxComment, true
+	case t.InCategory(chroma.Keyword):
+		return theme.SyntaxKeyword, true
+	case t.InSubCategory(chroma.LiteralString):
+		return theme.SyntaxString, true
+	case t.InSubCategory(chroma.LiteralNumber):
+		return theme.SyntaxNumber, true
+	}
+	return lipgloss.Style{}, false
+}
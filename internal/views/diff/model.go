@@ -0,0 +1,447 @@
+package diff
+
+import (
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/gitdiff"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+)
+
+type focus int
+
+const (
+	focusFiles focus = iota
+	focusDiff
+)
+
+// row is one rendered line of the diff column. Hunk headers have kind '@'.
+type row struct {
+	hunk int
+	kind byte
+	text string // already highlighted
+}
+
+// Model is the diff viewer: the changed files of a worktree on the left
+// and the selected file's unified diff on the right.
+type Model struct {
+	dir    string // requested directory, "" for the current pane's
+	target gitdiff.Target
+	src    gitdiff.Source
+
+	files      []gitdiff.File
+	fileCursor int
+	fileScroll int
+
+	diff       gitdiff.FileDiff
+	rows       []row
+	hunkCursor int
+	lineScroll int
+
+	focus   focus
+	confirm string // pending discard prompt
+	status  string
+	failed  bool // status is an error
+	loading bool
+
+	width  int
+	height int
+}
+
+type (
+	targetLoadedMsg struct {
+		target gitdiff.Target
+		err    error
+	}
+	filesLoadedMsg struct {
+		src   gitdiff.Source
+		files []gitdiff.File
+		keep  string // path to keep selected
+		err   error
+	}
+	diffLoadedMsg struct {
+		src  gitdiff.Source
+		path string
+		diff gitdiff.FileDiff
+		err  error
+	}
+	actionDoneMsg struct {
+		text string
+		err  error
+	}
+)
+
+// New builds a diff viewer for the worktree containing dir. An empty dir
+// uses the current tmux pane's directory.
+func New(dir string) Model {
+	return Model{dir: dir, loading: true}
+}
+
+func (m *Model) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+}
+
+// IsEditing reports whether a discard confirmation is waiting for y/n.
+func (m Model) IsEditing() bool {
+	return m.confirm != ""
+}
+
+func (m Model) Init() tea.Cmd {
+	dir := m.dir
+	return func() tea.Msg {
+		if dir == "" {
+			dir = currentDir()
+		}
+		t, err := gitdiff.Open(dir)
+		return targetLoadedMsg{target: t, err: err}
+	}
+}
+
+func currentDir() string {
+	if path, err := tmux.CurrentPanePath(); err == nil && path != "" {
+		return path
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// Reload re-reads the changed files, keeping the selected one.
+func (m Model) Reload() tea.Cmd {
+	if m.target.Dir == "" {
+		return nil
+	}
+	keep := ""
+	if f, ok := m.selectedFile(); ok {
+		keep = f.Path
+	}
+	return m.loadFiles(keep)
+}
+
+func (m Model) loadFiles(keep string) tea.Cmd {
+	t, src := m.target, m.src
+	return func() tea.Msg {
+		files, err := t.Files(src)
+		return filesLoadedMsg{src: src, files: files, keep: keep, err: err}
+	}
+}
+
+func (m Model) loadDiff() tea.Cmd {
+	f, ok := m.selectedFile()
+	if !ok {
+		return nil
+	}
+	t, src := m.target, m.src
+	return func() tea.Msg {
+		fd, err := t.Load(src, f)
+		return diffLoadedMsg{src: src, path: f.Path, diff: fd, err: err}
+	}
+}
+
+func (m Model) selectedFile() (gitdiff.File, bool) {
+	if m.fileCursor < 0 || m.fileCursor >= len(m.files) {
+		return gitdiff.File{}, false
+	}
+	return m.files[m.fileCursor], true
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case targetLoadedMsg:
+		if msg.err != nil {
+			m.loading = false
+			return m.setError(msg.err), nil
+		}
+		m.target = msg.target
+		return m, m.loadFiles("")
+	case filesLoadedMsg:
+		return m.handleFilesLoaded(msg)
+	case diffLoadedMsg:
+		return m.handleDiffLoaded(msg), nil
+	case actionDoneMsg:
+		if msg.err != nil {
+			m = m.setError(msg.err)
+		} else {
+			m.status, m.failed = msg.text, false
+		}
+		return m, m.Reload()
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m Model) setError(err error) Model {
+	m.status, m.failed = err.Error(), true
+	return m
+}
+
+func (m Model) handleFilesLoaded(msg filesLoadedMsg) (Model, tea.Cmd) {
+	if msg.src != m.src {
+		return m, nil
+	}
+	m.loading = false
+	if msg.err != nil {
+		return m.setError(msg.err), nil
+	}
+	m.files = msg.files
+	m.fileCursor = min(m.fileCursor, len(m.files)-1)
+	for i, f := range m.files {
+		if msg.keep != "" && f.Path == msg.keep {
+			m.fileCursor = i
+		}
+	}
+	m.fileCursor = max(m.fileCursor, 0)
+	m.ensureFileVisible()
+	if len(m.files) == 0 {
+		m.diff, m.rows = gitdiff.FileDiff{}, nil
+		m.focus = focusFiles
+		return m, nil
+	}
+	return m, m.loadDiff()
+}
+
+func (m Model) handleDiffLoaded(msg diffLoadedMsg) Model {
+	f, ok := m.selectedFile()
+	if msg.src != m.src || !ok || f.Path != msg.path {
+		return m
+	}
+	if msg.err != nil {
+		return m.setError(msg.err)
+	}
+	samePath := m.diff.File.Path == msg.path
+	m.diff = msg.diff
+	m.rows = buildRows(msg.diff)
+	if !samePath || m.hunkCursor >= len(m.diff.Hunks) {
+		m.hunkCursor = 0
+		m.lineScroll = 0
+	}
+	m.lineScroll = min(m.lineScroll, max(len(m.rows)-1, 0))
+	return m
+}
+
+func buildRows(fd gitdiff.FileDiff) []row {
+	hl := newHighlighter(fd.File.Path)
+	var rows []row
+	for i, h := range fd.Hunks {
+		rows = append(rows, row{hunk: i, kind: '@', text: h.Header})
+		for _, line := range h.Lines {
+			text := line.Text
+			if line.Kind != '-' && line.Kind != '\\' {
+				text = hl.line(text)
+			}
+			rows = append(rows, row{hunk: i, kind: line.Kind, text: text})
+		}
+	}
+	return rows
+}
+
+func (m Model) handleKey(msg tea.KeyMsg) (Model, tea.Cmd) {
+	if m.confirm != "" {
+		return m.handleConfirm(msg)
+	}
+	switch msg.String() {
+	case "esc", "q":
+		if m.focus == focusDiff {
+			m.focus = focusFiles
+			return m, nil
+		}
+		return m, func() tea.Msg { return messages.CloseDiffMsg{} }
+	case "j", "down":
+		return m.move(1)
+	case "k", "up":
+		return m.move(-1)
+	case "ctrl+d", "pgdown":
+		m.scrollDiff(m.bodyHeight() / 2)
+		return m, nil
+	case "ctrl+u", "pgup":
+		m.scrollDiff(-m.bodyHeight() / 2)
+		return m, nil
+	case "n", "]":
+		m.jumpHunk(1)
+		return m, nil
+	case "p", "[":
+		m.jumpHunk(-1)
+		return m, nil
+	case "tab", "l", "right", "enter":
+		if len(m.rows) > 0 {
+			m.focus = focusDiff
+		}
+		return m, nil
+	case "shift+tab", "h", "left":
+		m.focus = focusFiles
+		return m, nil
+	case "1":
+		return m.switchSource(gitdiff.Unstaged)
+	case "2":
+		return m.switchSource(gitdiff.Staged)
+	case "3":
+		return m.switchSource(gitdiff.Base)
+	case "v":
+		return m.switchSource((m.src + 1) % 3)
+	case "r":
+		return m, m.Reload()
+	case "c":
+		if m.target.Dir == "" {
+			return m, nil
+		}
+		dir := m.target.Dir
+		return m, func() tea.Msg { return messages.OpenCommitMsg{Dir: dir} }
+	case "s":
+		return m, m.stage()
+	case "u":
+		return m, m.unstage()
+	case "x":
+		return m.askDiscard(), nil
+	}
+	return m, nil
+}
+
+func (m Model) move(delta int) (Model, tea.Cmd) {
+	if m.focus == focusDiff {
+		m.scrollDiff(delta)
+		return m, nil
+	}
+	next := m.fileCursor + delta
+	if next < 0 || next >= len(m.files) {
+		return m, nil
+	}
+	m.fileCursor = next
+	m.ensureFileVisible()
+	return m, m.loadDiff()
+}
+
+func (m Model) switchSource(src gitdiff.Source) (Model, tea.Cmd) {
+	if src == m.src {
+		return m, nil
+	}
+	m.src = src
+	m.files, m.rows, m.diff = nil, nil, gitdiff.FileDiff{}
+	m.fileCursor, m.fileScroll, m.hunkCursor, m.lineScroll = 0, 0, 0, 0
+	m.focus = focusFiles
+	m.loading = true
+	return m, m.loadFiles("")
+}
+
+// scrollDiff moves the diff by delta rows; the hunk under the top row
+// becomes the current one.
+func (m *Model) scrollDiff(delta int) {
+	limit := max(len(m.rows)-m.bodyHeight(), 0)
+	m.lineScroll = min(max(m.lineScroll+delta, 0), limit)
+	if m.lineScroll < len(m.rows) {
+		m.hunkCursor = m.rows[m.lineScroll].hunk
+	}
+}
+
+func (m *Model) jumpHunk(delta int) {
+	next := m.hunkCursor + delta
+	if next < 0 || next >= len(m.diff.Hunks) {
+		return
+	}
+	m.hunkCursor = next
+	for i, r := range m.rows {
+		if r.kind == '@' && r.hunk == next {
+			m.lineScroll = i
+			break
+		}
+	}
+	m.focus = focusDiff
+}
+
+// onHunk reports whether actions target the current hunk rather than the
+// whole file.
+func (m Model) onHunk() bool {
+	return m.focus == focusDiff && m.hunkCursor < len(m.diff.Hunks)
+}
+
+func (m Model) stage() tea.Cmd {
+	f, ok := m.selectedFile()
+	if !ok || m.src == gitdiff.Staged {
+		return nil
+	}
+	t, fd, hunk := m.target, m.diff, m.hunkCursor
+	if m.onHunk() {
+		if m.src == gitdiff.Base {
+			return actionCmd("", gitdiff.ErrBaseView)
+		}
+		return func() tea.Msg {
+			return actionDoneMsg{text: fmt.Sprintf("staged hunk %d of %s", hunk+1, f.Path), err: t.StageHunk(fd, hunk)}
+		}
+	}
+	return func() tea.Msg {
+		return actionDoneMsg{text: "staged " + f.Path, err: t.Stage(f)}
+	}
+}
+
+func (m Model) unstage() tea.Cmd {
+	f, ok := m.selectedFile()
+	if !ok || m.src == gitdiff.Unstaged {
+		return nil
+	}
+	t, fd, hunk := m.target, m.diff, m.hunkCursor
+	if m.onHunk() {
+		if m.src == gitdiff.Base {
+			return actionCmd("", gitdiff.ErrBaseView)
+		}
+		return func() tea.Msg {
+			return actionDoneMsg{text: fmt.Sprintf("unstaged hunk %d of %s", hunk+1, f.Path), err: t.UnstageHunk(fd, hunk)}
+		}
+	}
+	return func() tea.Msg {
+		return actionDoneMsg{text: "unstaged " + f.Path, err: t.Unstage(f)}
+	}
+}
+
+func (m Model) askDiscard() Model {
+	f, ok := m.selectedFile()
+	if !ok {
+		return m
+	}
+	if m.src == gitdiff.Base {
+		return m.setError(gitdiff.ErrBaseView)
+	}
+	switch {
+	case f.Untracked():
+		m.confirm = "delete untracked " + f.Path
+	case m.onHunk():
+		m.confirm = fmt.Sprintf("discard hunk %d of %s", m.hunkCursor+1, f.Path)
+	default:
+		m.confirm = fmt.Sprintf("discard %s changes to %s", m.src, f.Path)
+	}
+	return m
+}
+
+func (m Model) handleConfirm(msg tea.KeyMsg) (Model, tea.Cmd) {
+	prompt := m.confirm
+	m.confirm = ""
+	if msg.String() != "y" && msg.String() != "Y" {
+		return m, nil
+	}
+	f, _ := m.selectedFile()
+	t, src, fd, hunk := m.target, m.src, m.diff, m.hunkCursor
+	if m.onHunk() && !f.Untracked() {
+		return m, func() tea.Msg {
+			return actionDoneMsg{text: prompt, err: t.DiscardHunk(src, fd, hunk)}
+		}
+	}
+	return m, func() tea.Msg {
+		return actionDoneMsg{text: prompt, err: t.Discard(src, f)}
+	}
+}
+
+func actionCmd(text string, err error) tea.Cmd {
+	return func() tea.Msg { return actionDoneMsg{text: text, err: err} }
+}
+
+func (m *Model) ensureFileVisible() {
+	visible := m.bodyHeight()
+	if m.fileCursor < m.fileScroll {
+		m.fileScroll = m.fileCursor
+	}
+	if m.fileCursor >= m.fileScroll+visible {
+		m.fileScroll = m.fileCursor - visible + 1
+	}
+}
@@ -0,0 +1,176 @@
+package diff
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+)
+
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+	} {
+		runGit(t, dir, args...)
+	}
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", "initial")
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func keyMsg(s string) tea.KeyMsg {
+	switch s {
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// settle runs cmd and feeds its messages back into the model until no
+// command is left.
+func settle(t *testing.T, m Model, cmd tea.Cmd) Model {
+	t.Helper()
+	for i := 0; cmd != nil; i++ {
+		if i > 10 {
+			t.Fatal("model did not settle")
+		}
+		m, cmd = m.Update(cmd())
+	}
+	return m
+}
+
+func loaded(t *testing.T, dir string) Model {
+	t.Helper()
+	m := New(dir)
+	m.SetSize(100, 30)
+	return settle(t, m, m.Init())
+}
+
+func TestLoadsChangedFilesAndDiff(t *testing.T) {
+	dir := gitRepo(t)
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() { println(\"hi\") }\n")
+	writeFile(t, dir, "notes.txt", "todo\n")
+
+	m := loaded(t, dir)
+	if len(m.files) != 2 || m.files[0].Path != "main.go" || !m.files[1].Untracked() {
+		t.Fatalf("files = %+v", m.files)
+	}
+	if len(m.diff.Hunks) != 1 {
+		t.Fatalf("diff = %+v", m.diff)
+	}
+	out := m.View()
+	for _, want := range []string{"Diff", "1 unstaged", "main.go", "notes.txt", "@@", "println"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("view misses %q:\n%s", want, out)
+		}
+	}
+
+	m = settle(t, m, func() tea.Msg { return keyMsg("j") })
+	if m.diff.File.Path != "notes.txt" {
+		t.Fatalf("diff after j = %s", m.diff.File.Path)
+	}
+}
+
+func TestStageHunkAndSwitchSource(t *testing.T) {
+	dir := gitRepo(t)
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() { println(\"hi\") }\n")
+	m := loaded(t, dir)
+
+	m, _ = m.Update(keyMsg("tab"))
+	if m.focus != focusDiff {
+		t.Fatal("tab did not focus the diff")
+	}
+	m, cmd := m.Update(keyMsg("s"))
+	m = settle(t, m, cmd)
+	if m.failed || !strings.Contains(m.status, "staged hunk 1 of main.go") {
+		t.Fatalf("status = %q (failed %v)", m.status, m.failed)
+	}
+	if len(m.files) != 0 {
+		t.Fatalf("unstaged files after staging = %+v", m.files)
+	}
+	if out := runGit(t, dir, "diff", "--cached", "--name-only"); strings.TrimSpace(out) != "main.go" {
+		t.Fatalf("index = %q", out)
+	}
+
+	m, cmd = m.Update(keyMsg("2"))
+	m = settle(t, m, cmd)
+	if len(m.files) != 1 || len(m.diff.Hunks) != 1 {
+		t.Fatalf("staged view = %+v", m.files)
+	}
+}
+
+func TestDiscardAsksForConfirmation(t *testing.T) {
+	dir := gitRepo(t)
+	writeFile(t, dir, "main.go", "package main\n")
+	m := loaded(t, dir)
+
+	m, cmd := m.Update(keyMsg("x"))
+	if cmd != nil || !m.IsEditing() || !strings.Contains(m.View(), "discard unstaged changes to main.go? y/n") {
+		t.Fatalf("no confirmation:\n%s", m.View())
+	}
+	m, cmd = m.Update(keyMsg("n"))
+	if cmd != nil || m.IsEditing() {
+		t.Fatal("n did not cancel")
+	}
+
+	m, _ = m.Update(keyMsg("x"))
+	m, cmd = m.Update(keyMsg("y"))
+	m = settle(t, m, cmd)
+	if len(m.files) != 0 {
+		t.Fatalf("files after discard = %+v", m.files)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "main.go"))
+	if !strings.Contains(string(data), "func main") {
+		t.Fatalf("main.go not restored:\n%s", data)
+	}
+}
+
+func TestEscLeavesDiffFocusThenCloses(t *testing.T) {
+	dir := gitRepo(t)
+	writeFile(t, dir, "main.go", "package main\n")
+	m := loaded(t, dir)
+
+	m, _ = m.Update(keyMsg("tab"))
+	m, cmd := m.Update(keyMsg("esc"))
+	if cmd != nil || m.focus != focusFiles {
+		t.Fatal("esc in the diff should return to the file list")
+	}
+	_, cmd = m.Update(keyMsg("esc"))
+	if cmd == nil {
+		t.Fatal("esc in the file list should close the viewer")
+	}
+	if _, ok := cmd().(messages.CloseDiffMsg); !ok {
+		t.Fatal("expected CloseDiffMsg")
+	}
+}
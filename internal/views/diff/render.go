@@ -0,0 +1,210 @@
+package diff
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/miltonparedes/kitmux/internal/gitdiff"
+	"github.com/miltonparedes/kitmux/internal/theme"
+)
+
+// stackWidth is the width below which the file list sits above the diff
+// instead of beside it.
+const stackWidth = 70
+
+// bodyHeight is the number of rows between the header and the footer.
+func (m Model) bodyHeight() int {
+	return max(m.height-3, 1)
+}
+
+func (m Model) View() string {
+	var b strings.Builder
+	b.WriteString(m.header())
+	b.WriteString("\n")
+
+	body := m.bodyHeight()
+	var lines []string
+	if m.width < stackWidth {
+		fileRows := min(max(len(m.files), 1), max(body/3, 1))
+		lines = append(m.fileLines(m.width, fileRows), " "+theme.TreeConnector.Render(strings.Repeat("─", max(m.width-2, 1))))
+		lines = append(lines, m.diffLines(m.width, body-len(lines))...)
+	} else {
+		listW := min(max(m.width/3, 24), 48)
+		files := m.fileLines(listW, body)
+		diff := m.diffLines(m.width-listW-1, body)
+		sep := theme.TreeConnector.Render("│")
+		for i := 0; i < body; i++ {
+			lines = append(lines, padTo(at(files, i), listW)+sep+at(diff, i))
+		}
+	}
+	for i := 0; i < body; i++ {
+		b.WriteString(at(lines, i))
+		b.WriteString("\n")
+	}
+
+	b.WriteString(" " + theme.TreeConnector.Render(strings.Repeat("─", max(m.width-2, 1))))
+	b.WriteString("\n")
+	b.WriteString(m.footer())
+	return b.String()
+}
+
+func (m Model) header() string {
+	repo := filepath.Base(m.target.Dir)
+	if m.target.Dir == "" {
+		repo = "…"
+	}
+	parts := []string{" " + theme.PanelTitle.Render("Diff"), theme.TreeMeta.Render(repo)}
+	for _, src := range []gitdiff.Source{gitdiff.Unstaged, gitdiff.Staged, gitdiff.Base} {
+		label := fmt.Sprintf("%d %s", src+1, src)
+		if src == gitdiff.Base && m.target.Base != "" {
+			label += " (" + m.target.Base + ")"
+		}
+		if src == m.src {
+			parts = append(parts, theme.AttachedBadge.Render(label))
+		} else {
+			parts = append(parts, theme.HelpStyle.Render(label))
+		}
+	}
+	return ansi.Truncate(strings.Join(parts, "  "), m.width, "…")
+}
+
+func (m Model) fileLines(width, height int) []string {
+	if len(m.files) == 0 {
+		text := " No changes"
+		if m.loading {
+			text = " Loading…"
+		}
+		return []string{theme.HelpStyle.Render(text)}
+	}
+	scroll := m.fileScroll
+	if m.fileCursor >= scroll+height {
+		scroll = m.fileCursor - height + 1
+	}
+	var lines []string
+	for i := scroll; i < len(m.files) && len(lines) < height; i++ {
+		lines = append(lines, renderFile(m.files[i], i == m.fileCursor, m.focus == focusFiles, width))
+	}
+	return lines
+}
+
+func renderFile(f gitdiff.File, selected, focused bool, width int) string {
+	stats := ""
+	switch {
+	case f.Binary:
+		stats = theme.TreeMeta.Render("bin")
+	default:
+		if f.Added > 0 {
+			stats = theme.DiffAdded.Render(fmt.Sprintf("+%d", f.Added))
+		}
+		if f.Deleted > 0 {
+			if stats != "" {
+				stats += " "
+			}
+			stats += theme.DiffRemoved.Render(fmt.Sprintf("-%d", f.Deleted))
+		}
+	}
+	path := f.Path
+	if f.OldPath != "" && f.OldPath != f.Path {
+		path = f.OldPath + " → " + f.Path
+	}
+	room := max(width-4-lipgloss.Width(stats)-1, 1)
+	path = ansi.Truncate(path, room, "…")
+	if selected && focused {
+		path = theme.TreeNodeSelected.Render(path)
+	} else if selected {
+		path = theme.PanelTitleInactive.Render(path)
+	}
+	marker := " "
+	if selected {
+		marker = theme.SelectionBar.Render("▎")
+	}
+	left := marker + statusStyle(f.Status).Render(f.Status) + " " + path
+	gap := max(width-lipgloss.Width(left)-lipgloss.Width(stats)-1, 1)
+	return left + strings.Repeat(" ", gap) + stats
+}
+
+func statusStyle(status string) lipgloss.Style {
+	switch status {
+	case gitdiff.StatusAdded, gitdiff.StatusUntracked:
+		return theme.DiffAdded
+	case gitdiff.StatusDeleted:
+		return theme.DiffRemoved
+	case gitdiff.StatusRenamed:
+		return theme.DiffHunk
+	}
+	return theme.DirtyBadge
+}
+
+func (m Model) diffLines(width, height int) []string {
+	if height <= 0 {
+		return nil
+	}
+	if len(m.rows) == 0 {
+		if f, ok := m.selectedFile(); ok && f.Binary {
+			return []string{theme.HelpStyle.Render(" Binary file")}
+		}
+		return nil
+	}
+	var lines []string
+	for i := m.lineScroll; i < len(m.rows) && len(lines) < height; i++ {
+		lines = append(lines, m.renderRow(m.rows[i], width))
+	}
+	return lines
+}
+
+func (m Model) renderRow(r row, width int) string {
+	gutter := " "
+	if r.hunk == m.hunkCursor && m.focus == focusDiff {
+		gutter = theme.SelectionBar.Render("▎")
+	}
+	var line string
+	switch r.kind {
+	case '@':
+		line = theme.DiffHunk.Render(r.text)
+	case '+':
+		line = theme.DiffAdded.Render("+") + r.text
+	case '-':
+		line = theme.DiffRemoved.Render("-" + r.text)
+	case '\\':
+		line = theme.TreeMeta.Render("\\" + r.text)
+	default:
+		line = " " + r.text
+	}
+	return gutter + ansi.Truncate(line, max(width-1, 1), "…")
+}
+
+func (m Model) footer() string {
+	if m.confirm != "" {
+		return theme.AttachedBadge.Render(" " + m.confirm + "? y/n")
+	}
+	if m.status != "" {
+		if m.failed {
+			return theme.DiffRemoved.Render(" " + ansi.Truncate(m.status, max(m.width-2, 1), "…"))
+		}
+		return theme.HelpStyle.Render(" " + m.status)
+	}
+	target := "file"
+	if m.focus == focusDiff {
+		target = "hunk"
+	}
+	help := fmt.Sprintf(" s/u/x stage/unstage/discard %s  n/p hunk  tab focus  1/2/3 source  c commit  q back", target)
+	return theme.HelpStyle.Render(ansi.Truncate(help, max(m.width, 1), "…"))
+}
+
+func at(lines []string, i int) string {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return ""
+}
+
+func padTo(s string, width int) string {
+	if w := lipgloss.Width(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return ansi.Truncate(s, width, "")
+}
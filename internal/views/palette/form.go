@@ -0,0 +1,141 @@
+package palette
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sahilm/fuzzy"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/command"
+)
+
+// form asks for a command's parameters one at a time.
+type form struct {
+	cmd     command.Command
+	step    int
+	args    map[string]string
+	input   textinput.Model
+	options []string // the current choice's options
+	matches []string // options matching the input
+	cursor  int
+	err     string
+}
+
+func newForm(cmd command.Command) *form {
+	ti := textinput.New()
+	ti.Prompt = "> "
+	ti.CharLimit = 128
+	f := &form{cmd: cmd, args: map[string]string{}, input: ti}
+	f.enterStep()
+	return f
+}
+
+func (f *form) param() command.Param {
+	return f.cmd.Params[f.step]
+}
+
+// enterStep prepares the input for the current parameter.
+func (f *form) enterStep() {
+	p := f.param()
+	f.input.SetValue("")
+	f.input.Placeholder = ""
+	f.input.Focus()
+	f.options = nil
+	f.cursor = 0
+	f.err = ""
+	switch p.Kind {
+	case command.ParamChoice:
+		f.options = p.Options(f.args)
+		f.input.Placeholder = "Filter..."
+	case command.ParamConfirm:
+		f.input.Blur()
+	}
+	f.refilter()
+}
+
+func (f *form) refilter() {
+	query := f.input.Value()
+	if query == "" {
+		f.matches = f.options
+	} else {
+		found := fuzzy.Find(query, f.options)
+		f.matches = make([]string, len(found))
+		for i, m := range found {
+			f.matches[i] = f.options[m.Index]
+		}
+	}
+	f.cursor = 0
+}
+
+// update handles a key. done is set when the form was answered or
+// cancelled; cmd runs the command once every parameter has a value.
+func (f *form) update(msg tea.KeyMsg) (cmd tea.Cmd, done bool) {
+	if msg.String() == "esc" {
+		return nil, true
+	}
+	p := f.param()
+	if p.Kind == command.ParamConfirm {
+		switch msg.String() {
+		case "y", "Y":
+			return f.answer(command.Yes)
+		case "n", "N", "enter":
+			return nil, true
+		}
+		return nil, false
+	}
+
+	switch msg.String() {
+	case "enter":
+		return f.submit()
+	case "up", "ctrl+k":
+		if f.cursor > 0 {
+			f.cursor--
+		}
+		return nil, false
+	case "down", "ctrl+j":
+		if f.cursor < len(f.matches)-1 {
+			f.cursor++
+		}
+		return nil, false
+	}
+	prev := f.input.Value()
+	f.input, _ = f.input.Update(msg)
+	if f.input.Value() != prev {
+		f.err = ""
+		f.refilter()
+	}
+	return nil, false
+}
+
+func (f *form) submit() (tea.Cmd, bool) {
+	if f.param().Kind == command.ParamChoice && len(f.options) > 0 {
+		if len(f.matches) == 0 {
+			f.err = "no match"
+			return nil, false
+		}
+		return f.answer(f.matches[f.cursor])
+	}
+	value := strings.TrimSpace(f.input.Value())
+	if value == "" {
+		f.err = "required"
+		return nil, false
+	}
+	return f.answer(value)
+}
+
+// answer records the current parameter and moves on, running the command
+// after the last one.
+func (f *form) answer(value string) (tea.Cmd, bool) {
+	f.args[f.param().Name] = value
+	f.step++
+	if f.step < len(f.cmd.Params) {
+		f.enterStep()
+		return nil, false
+	}
+	id, args := f.cmd.ID, f.args
+	return func() tea.Msg {
+		return messages.ExecuteCommandMsg{ID: id, Args: args}
+	}, true
+}
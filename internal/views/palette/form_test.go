@@ -0,0 +1,73 @@
+package palette
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/command"
+)
+
+// stubSessions makes every session parameter offer names.
+func stubSessions(t *testing.T, names ...string) {
+	t.Helper()
+	orig := listCommands
+	t.Cleanup(func() { listCommands = orig })
+	listCommands = func() []command.Command {
+		cmds := command.DefaultCommands()
+		for _, c := range cmds {
+			for i := range c.Params {
+				if c.Params[i].Name == "session" {
+					c.Params[i].Options = func(map[string]string) []string { return names }
+				}
+			}
+		}
+		return cmds
+	}
+}
+
+func TestFormAsksForEachParamThenRuns(t *testing.T) {
+	stubSessions(t, "api", "web")
+	stubScores(t, nil)
+	m := New()
+	m.Reset()
+	if !m.StartForm("rename_session") {
+		t.Fatal("expected rename_session to take parameters")
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("we")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(m.View(), "New name for web") {
+		t.Fatalf("expected the name prompt after picking a session:\n%s", m.View())
+	}
+
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil || !m.InForm() {
+		t.Fatal("an empty name should be refused")
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("web-v2")})
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.InForm() || cmd == nil {
+		t.Fatal("expected the form to finish with a command")
+	}
+	msg, ok := cmd().(messages.ExecuteCommandMsg)
+	if !ok || msg.ID != "rename_session" || msg.Args["session"] != "web" || msg.Args["name"] != "web-v2" {
+		t.Fatalf("msg = %#v", cmd())
+	}
+}
+
+func TestFormConfirmNoCancels(t *testing.T) {
+	stubSessions(t, "api")
+	m := New()
+	m.StartForm("kill_session")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(m.View(), "Kill api?") {
+		t.Fatalf("expected confirm prompt:\n%s", m.View())
+	}
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if m.InForm() || cmd != nil {
+		t.Fatal("n should cancel without running")
+	}
+}
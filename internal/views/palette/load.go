@@ -0,0 +1,38 @@
+package palette
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/command"
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/plugin"
+)
+
+// detectEnv describes the current pane; swapped in tests.
+var detectEnv = command.DetectEnv
+
+// EnvMsg carries the Env found by DetectEnvCmd.
+type EnvMsg struct {
+	Env command.Env
+}
+
+// DetectEnvCmd describes the current pane off the UI and reports back with
+// EnvMsg; it runs git and tmux, so opening the palette never waits on it.
+func DetectEnvCmd() tea.Cmd {
+	return func() tea.Msg {
+		return EnvMsg{Env: detectEnv()}
+	}
+}
+
+// LoadPlugins shakes hands with the plugins off the UI and reports back
+// with PluginsLoadedMsg, so opening the palette never waits on them.
+func LoadPlugins() tea.Cmd {
+	if !config.Plugins() {
+		return nil
+	}
+	return func() tea.Msg {
+		plugin.Load()
+		return messages.PluginsLoadedMsg{}
+	}
+}
@@ -3,20 +3,34 @@ package palette
 import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/sahilm/fuzzy"
 
 	"github.com/miltonparedes/kitmux/internal/app/messages"
-	"github.com/miltonparedes/kitmux/internal/recency"
+	"github.com/miltonparedes/kitmux/internal/command"
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/frecency"
+)
+
+// Sources of the palette; swapped in tests.
+var (
+	loadScores   = frecency.Load
+	listCommands = command.Commands
 )
 
 type Model struct {
-	commands []Command
-	filtered []Command
-	input    textinput.Model
-	cursor   int
-	scroll   int
-	height   int
-	width    int
+	commands []command.Command
+	filtered []command.Command
+	scores   frecency.Scores
+	// env is where the palette runs, nil until SetEnv; unavailable maps
+	// the IDs of commands that cannot run there to why.
+	env         *command.Env
+	unavailable map[string]string
+	notice      string // why the chosen command did not run
+	form        *form  // parameters of the chosen command, while asking
+	input       textinput.Model
+	cursor      int
+	scroll      int
+	height      int
+	width       int
 }
 
 func New() Model {
@@ -26,7 +40,7 @@ func New() Model {
 	ti.CharLimit = 64
 	ti.Focus()
 
-	cmds := DefaultCommands()
+	cmds := listCommands()
 	return Model{
 		commands: cmds,
 		filtered: cmds,
@@ -39,23 +53,88 @@ func (m *Model) SetSize(w, h int) {
 	m.height = h
 }
 
+// Reset prepares the palette to be shown: it checks which commands can run
+// here, ranks them by frecency and clears the query. Unavailable commands
+// are listed last, dimmed, or left out with KITMUX_PALETTE_UNAVAILABLE=hide.
 func (m *Model) Reset() {
+	m.form = nil
+	m.notice = ""
 	m.input.SetValue("")
 	m.input.Focus()
-	store := recency.Load()
-	m.commands = recency.SortByRecency(DefaultCommands(), store.Commands, func(c Command) string {
-		return c.ID
-	})
+	m.scores = loadScores(frecency.KindCommand)
+	m.refresh()
 	m.filtered = m.commands
 	m.cursor = 0
 	m.scroll = 0
 }
 
+// SetEnv tells the palette where it runs, once DetectEnvCmd has found out,
+// and checks the listed commands against it.
+func (m *Model) SetEnv(env command.Env) {
+	m.env = &env
+	m.Refresh()
+}
+
+// Refresh lists the commands again, keeping the query, once more of them
+// are known, such as after the plugins loaded.
+func (m *Model) Refresh() {
+	if m.form != nil {
+		return
+	}
+	m.refresh()
+	m.refilter()
+}
+
+// refresh checks which commands can run here and ranks them by frecency.
+// Until the env is known every command is listed as available.
+func (m *Model) refresh() {
+	hide := config.HideUnavailable()
+	m.unavailable = map[string]string{}
+	var cmds []command.Command
+	for _, c := range listCommands() {
+		if m.env == nil {
+			cmds = append(cmds, c)
+			continue
+		}
+		if reason := c.Unavailable(*m.env); reason != "" {
+			m.unavailable[c.ID] = reason
+			if hide {
+				continue
+			}
+		}
+		cmds = append(cmds, c)
+	}
+	m.commands = m.availableFirst(frecency.Sort(cmds, m.scores, func(c command.Command) string {
+		return c.ID
+	}))
+}
+
 func (m Model) Init() tea.Cmd {
 	return textinput.Blink
 }
 
+// StartForm asks for the parameters of command id, as if it had been
+// picked from the list. It reports false for unknown commands and commands
+// without parameters.
+func (m *Model) StartForm(id string) bool {
+	for _, cmd := range listCommands() {
+		if cmd.ID == id && len(cmd.Params) > 0 {
+			m.form = newForm(cmd)
+			return true
+		}
+	}
+	return false
+}
+
+// InForm reports whether the palette is asking for a command's parameters.
+func (m Model) InForm() bool {
+	return m.form != nil
+}
+
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	if m.form != nil {
+		return m.updateForm(msg)
+	}
 	switch msg := msg.(type) {
 	case tea.MouseMsg:
 		return m.handleMouse(msg)
@@ -66,7 +145,11 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	}
 
 	var cmd tea.Cmd
+	prev := m.input.Value()
 	m.input, cmd = m.input.Update(msg)
+	if m.input.Value() != prev {
+		m.notice = ""
+	}
 	m.refilter()
 	return m, cmd
 }
@@ -95,7 +178,7 @@ func (m Model) handleMouseLeft(msg tea.MouseMsg) (Model, tea.Cmd) {
 	if idx < 0 || idx >= len(m.filtered) {
 		return m, nil
 	}
-	return m, executeCmdByID(m.filtered[idx].ID)
+	return m.run(m.filtered[idx])
 }
 
 func (m Model) handleKey(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
@@ -118,8 +201,8 @@ func (m Model) handleEnter() (Model, tea.Cmd, bool) {
 	if m.cursor < 0 || m.cursor >= len(m.filtered) {
 		return m, nil, false
 	}
-	cmd := m.filtered[m.cursor]
-	return m, executeCmdByID(cmd.ID), true
+	updated, cmd := m.run(m.filtered[m.cursor])
+	return updated, cmd, true
 }
 
 func (m Model) handleAltDigit(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
@@ -127,8 +210,34 @@ func (m Model) handleAltDigit(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
 	if idx < 0 || idx >= len(m.filtered) {
 		return m, nil, true
 	}
-	cmd := m.filtered[idx]
-	return m, executeCmdByID(cmd.ID), true
+	updated, cmd := m.run(m.filtered[idx])
+	return updated, cmd, true
+}
+
+// run executes cmd, asking for its parameters first when it has any. An
+// unavailable command only says why.
+func (m Model) run(cmd command.Command) (Model, tea.Cmd) {
+	if reason, ok := m.unavailable[cmd.ID]; ok {
+		m.notice = cmd.Title + ": " + reason
+		return m, nil
+	}
+	if len(cmd.Params) > 0 {
+		m.form = newForm(cmd)
+		return m, textinput.Blink
+	}
+	return m, executeCmdByID(cmd.ID)
+}
+
+func (m Model) updateForm(msg tea.Msg) (Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	cmd, done := m.form.update(key)
+	if done {
+		m.form = nil
+	}
+	return m, cmd
 }
 
 func (m *Model) moveCursor(delta int) {
@@ -151,19 +260,40 @@ func (m *Model) refilter() {
 		m.filtered = m.commands
 	} else {
 		titles := make([]string, len(m.commands))
+		ids := make([]string, len(m.commands))
 		for i, c := range m.commands {
 			titles[i] = c.Title
+			ids[i] = c.ID
 		}
-		matches := fuzzy.Find(query, titles)
-		m.filtered = make([]Command, len(matches))
-		for i, match := range matches {
-			m.filtered[i] = m.commands[match.Index]
+		ranked := frecency.Rank(query, titles, ids, m.scores)
+		filtered := make([]command.Command, len(ranked))
+		for i, idx := range ranked {
+			filtered[i] = m.commands[idx]
 		}
+		m.filtered = m.availableFirst(filtered)
 	}
 	m.cursor = 0
 	m.scroll = 0
 }
 
+// availableFirst moves unavailable commands after the others, keeping the
+// order within each group.
+func (m Model) availableFirst(cmds []command.Command) []command.Command {
+	if len(m.unavailable) == 0 {
+		return cmds
+	}
+	out := make([]command.Command, 0, len(cmds))
+	var dimmed []command.Command
+	for _, c := range cmds {
+		if _, ok := m.unavailable[c.ID]; ok {
+			dimmed = append(dimmed, c)
+		} else {
+			out = append(out, c)
+		}
+	}
+	return append(out, dimmed...)
+}
+
 func executeCmdByID(id string) tea.Cmd {
 	return func() tea.Msg {
 		return messages.ExecuteCommandMsg{ID: id}
@@ -1,9 +1,35 @@
 package palette
 
 import (
+	"strings"
 	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/command"
+	"github.com/miltonparedes/kitmux/internal/frecency"
 )
 
+func stubScores(t *testing.T, scores frecency.Scores) {
+	t.Helper()
+	prev := loadScores
+	t.Cleanup(func() { loadScores = prev })
+	loadScores = func(string) frecency.Scores { return scores }
+}
+
+func stubEnv(t *testing.T, env command.Env) {
+	t.Helper()
+	prev := detectEnv
+	t.Cleanup(func() { detectEnv = prev })
+	detectEnv = func() command.Env { return env }
+}
+
+// everywhere meets every requirement.
+var everywhere = command.Env{
+	GitRepo: true, Worktree: true, Branch: "feat/x", SSH: true, Thread: true,
+	HasBinary: func(string) bool { return true },
+}
+
 func TestEnsureVisible_scrollsDown(t *testing.T) {
 	m := New()
 	m.height = 7 // avail=4, maxVisible=2 (2 items fit in 3 lines)
@@ -31,6 +57,7 @@ func TestEnsureVisible_scrollsUp(t *testing.T) {
 }
 
 func TestReset_clearsScroll(t *testing.T) {
+	stubScores(t, nil)
 	m := New()
 	m.scroll = 5
 	m.cursor = 5
@@ -42,3 +69,147 @@ func TestReset_clearsScroll(t *testing.T) {
 		t.Errorf("expected cursor=0 after Reset, got %d", m.cursor)
 	}
 }
+
+func TestRefreshListsPluginsLoadedLater(t *testing.T) {
+	stubScores(t, nil)
+	orig := listCommands
+	t.Cleanup(func() { listCommands = orig })
+	listCommands = command.DefaultCommands
+	m := New()
+	m.SetEnv(everywhere)
+	m.Reset()
+	m.input.SetValue("pull")
+	m.refilter()
+
+	listCommands = func() []command.Command {
+		return append(command.DefaultCommands(), command.Command{ID: "plugin:gh:prs", Title: "Pull Requests", Category: "Plugin"})
+	}
+	m.Refresh()
+	if m.input.Value() != "pull" {
+		t.Fatalf("query = %q, want it kept", m.input.Value())
+	}
+	if len(m.filtered) == 0 || m.filtered[0].ID != "plugin:gh:prs" {
+		t.Fatalf("filtered = %v, want the plugin command first", m.filtered)
+	}
+}
+
+func TestLoadPluginsIsOffByDefault(t *testing.T) {
+	t.Setenv("KITMUX_PLUGINS", "")
+	if LoadPlugins() != nil {
+		t.Fatal("plugins should not load unless KITMUX_PLUGINS is on")
+	}
+}
+
+func TestDetectEnvCmdDeliversEnv(t *testing.T) {
+	stubEnv(t, everywhere)
+	msg, ok := DetectEnvCmd()().(EnvMsg)
+	if !ok || !msg.Env.GitRepo || msg.Env.Branch != "feat/x" {
+		t.Fatalf("msg = %#v, want the detected env", msg)
+	}
+}
+
+func TestCommandsAreAvailableUntilEnvArrives(t *testing.T) {
+	stubScores(t, nil)
+	m := New()
+	m.Reset()
+	if len(m.unavailable) != 0 {
+		t.Fatalf("unavailable = %v before the env is known", m.unavailable)
+	}
+	m.input.SetValue("Merge")
+	m.refilter()
+
+	m.SetEnv(command.Env{HasBinary: func(string) bool { return false }})
+	if m.unavailable["wt_merge"] != "not in a worktree" {
+		t.Fatalf("wt_merge reason = %q", m.unavailable["wt_merge"])
+	}
+	if m.input.Value() != "Merge" {
+		t.Fatalf("query = %q, want it kept", m.input.Value())
+	}
+}
+
+func TestRefilterRanksFrequentCommandsFirst(t *testing.T) {
+	stubScores(t, frecency.Scores{"launch_opencode": 5})
+	m := New()
+	m.SetEnv(everywhere)
+	m.Reset()
+	if m.filtered[0].ID != "launch_opencode" {
+		t.Fatalf("first command = %q", m.filtered[0].ID)
+	}
+
+	m.input.SetValue("launch")
+	m.refilter()
+	if m.filtered[0].ID != "launch_opencode" {
+		t.Fatalf("first match = %q", m.filtered[0].ID)
+	}
+}
+
+func TestResetDimsUnavailableCommandsLast(t *testing.T) {
+	stubScores(t, frecency.Scores{"wt_merge": 5})
+	m := New()
+	m.SetEnv(command.Env{HasBinary: func(string) bool { return false }})
+	m.SetSize(120, 10)
+	m.Reset()
+
+	if got := m.unavailable["wt_merge"]; got != "not in a worktree" {
+		t.Fatalf("wt_merge reason = %q", got)
+	}
+	if m.filtered[0].Unavailable(command.Env{}) != "" {
+		t.Fatalf("first command %q should be available", m.filtered[0].ID)
+	}
+	seenDimmed := false
+	for _, c := range m.filtered {
+		_, dimmed := m.unavailable[c.ID]
+		if seenDimmed && !dimmed {
+			t.Fatalf("%s listed after an unavailable command", c.ID)
+		}
+		seenDimmed = seenDimmed || dimmed
+	}
+	if !seenDimmed {
+		t.Fatal("unavailable commands should still be listed")
+	}
+	m.input.SetValue("Merge Worktree")
+	m.refilter()
+	if !strings.Contains(m.View(), "(not in a worktree)") {
+		t.Fatal("view should show why wt_merge is unavailable")
+	}
+}
+
+func TestResetHidesUnavailableCommands(t *testing.T) {
+	stubScores(t, nil)
+	t.Setenv("KITMUX_PALETTE_UNAVAILABLE", "hide")
+	m := New()
+	m.SetEnv(command.Env{HasBinary: func(string) bool { return false }})
+	m.Reset()
+	for _, c := range m.filtered {
+		if c.ID == "wt_merge" {
+			t.Fatal("wt_merge should be hidden outside a worktree")
+		}
+	}
+}
+
+func TestEnterOnUnavailableCommandShowsReason(t *testing.T) {
+	stubScores(t, nil)
+	m := New()
+	m.SetEnv(command.Env{Branch: "main", Worktree: true, HasBinary: func(string) bool { return false }})
+	m.Reset()
+	m.input.SetValue("Merge")
+	m.refilter()
+	idx := -1
+	for i, c := range m.filtered {
+		if c.ID == "wt_merge" {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		t.Fatal("wt_merge not listed")
+	}
+	m.cursor = idx
+
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil {
+		t.Fatal("unavailable command should not run")
+	}
+	if !strings.Contains(m.notice, "on main") {
+		t.Fatalf("notice = %q", m.notice)
+	}
+}
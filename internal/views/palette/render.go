@@ -4,14 +4,21 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/miltonparedes/kitmux/internal/command"
 	"github.com/miltonparedes/kitmux/internal/theme"
 )
 
 func (m Model) View() string {
+	if m.form != nil {
+		return m.viewForm()
+	}
 	var b strings.Builder
 
 	// Input
 	b.WriteString(" " + m.input.View())
+	if m.notice != "" {
+		b.WriteString("  " + theme.DiffRemoved.Render(m.notice))
+	}
 	b.WriteString("\n")
 
 	// Separator under input
@@ -36,6 +43,16 @@ func (m Model) View() string {
 	for i := start; i < end; i++ {
 		cmd := m.filtered[i]
 		cat := theme.PaletteCategory.Render(cmd.Category)
+		if reason, ok := m.unavailable[cmd.ID]; ok {
+			// Dimmed, with the reason it cannot run here.
+			fmt.Fprintf(&b, "    %s  %s  %s\n",
+				theme.TreeMeta.Render(cmd.Title), cat, theme.TreeMeta.Render("("+reason+")"))
+			if i < end-1 {
+				b.WriteString(itemSep)
+				b.WriteString("\n")
+			}
+			continue
+		}
 
 		if i < 9 {
 			b.WriteString(theme.TreeMeta.Render(fmt.Sprintf("%d", i+1)))
@@ -63,3 +80,52 @@ func (m Model) View() string {
 
 	return b.String()
 }
+
+// viewForm renders the parameters answered so far and the current prompt.
+func (m Model) viewForm() string {
+	f := m.form
+	var b strings.Builder
+
+	b.WriteString(" " + theme.PaletteItemSelected.Render(f.cmd.Title))
+	b.WriteString("\n")
+	sepW := m.width - 2
+	if sepW < 1 {
+		sepW = 1
+	}
+	b.WriteString(" " + theme.TreeConnector.Render(strings.Repeat("─", sepW)))
+	b.WriteString("\n")
+
+	for _, p := range f.cmd.Params[:f.step] {
+		fmt.Fprintf(&b, "   %s %s\n", theme.TreeMeta.Render(p.Name+":"), f.args[p.Name])
+	}
+
+	p := f.param()
+	prompt := command.PromptFor(p, f.args)
+	if p.Kind == command.ParamConfirm {
+		fmt.Fprintf(&b, " %s %s %s\n", theme.PaletteItemSelected.Render("▸"), prompt, theme.TreeMeta.Render("[y/N]"))
+		return b.String()
+	}
+	fmt.Fprintf(&b, " %s %s\n", theme.PaletteItemSelected.Render("▸"), prompt)
+	b.WriteString(" " + f.input.View())
+	if f.err != "" {
+		b.WriteString("  " + theme.DiffRemoved.Render(f.err))
+	}
+	b.WriteString("\n")
+
+	limit := m.height - 4 - f.step
+	if limit < 1 {
+		limit = 1
+	}
+	start := 0
+	if f.cursor >= limit {
+		start = f.cursor - limit + 1
+	}
+	for i := start; i < len(f.matches) && i < start+limit; i++ {
+		if i == f.cursor {
+			fmt.Fprintf(&b, "   %s %s\n", theme.PaletteItemSelected.Render("▸"), theme.PaletteItemSelected.Render(f.matches[i]))
+		} else {
+			fmt.Fprintf(&b, "     %s\n", theme.PaletteItem.Render(f.matches[i]))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,222 @@
+// Package pluginlist shows the items of a plugin's list provider and sends
+// the selected one back to the plugin.
+package pluginlist
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sahilm/fuzzy"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/plugin"
+)
+
+// Plugin calls; swapped in tests.
+var (
+	currentContext = plugin.CurrentContext
+	listItems      = func(p plugin.Plugin, ctx plugin.Context, provider string) ([]plugin.Item, error) {
+		return p.List(ctx, provider)
+	}
+	selectItem = func(p plugin.Plugin, ctx plugin.Context, provider string, item plugin.Item) (plugin.Result, error) {
+		return p.Select(ctx, provider, item)
+	}
+	runCommand = func(p plugin.Plugin, ctx plugin.Context, id string) (plugin.Result, error) {
+		return p.Run(ctx, id)
+	}
+)
+
+type loadedMsg struct {
+	ctx   plugin.Context
+	items []plugin.Item
+	err   error
+}
+
+type Model struct {
+	plugin   plugin.Plugin
+	provider string
+	title    string
+	ctx      plugin.Context
+	items    []plugin.Item
+	filtered []plugin.Item
+	loaded   bool
+	err      error
+	input    textinput.Model
+	cursor   int
+	scroll   int
+	height   int
+	width    int
+}
+
+// New lists the items of p's provider.
+func New(p plugin.Plugin, provider string) Model {
+	title := provider
+	for _, pr := range p.Providers {
+		if pr.ID == provider && pr.Title != "" {
+			title = pr.Title
+		}
+	}
+	ti := textinput.New()
+	ti.Prompt = "> "
+	ti.Placeholder = "Filter " + title + "..."
+	ti.CharLimit = 128
+	ti.Focus()
+	return Model{plugin: p, provider: provider, title: title, input: ti}
+}
+
+func (m Model) Init() tea.Cmd {
+	p, provider := m.plugin, m.provider
+	return tea.Batch(textinput.Blink, func() tea.Msg {
+		ctx := currentContext()
+		items, err := listItems(p, ctx, provider)
+		return loadedMsg{ctx: ctx, items: items, err: err}
+	})
+}
+
+func (m *Model) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+}
+
+// IsEditing reports true: every key but the app's esc and ctrl+c goes to
+// the filter.
+func (m Model) IsEditing() bool { return true }
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case loadedMsg:
+		m.ctx = msg.ctx
+		m.items = msg.items
+		m.err = msg.err
+		m.loaded = true
+		m.refilter()
+		return m, nil
+	case tea.MouseMsg:
+		return m.handleMouse(msg)
+	case tea.KeyMsg:
+		if updated, cmd, handled := m.handleKey(msg); handled {
+			return updated, cmd
+		}
+	}
+
+	var cmd tea.Cmd
+	prev := m.input.Value()
+	m.input, cmd = m.input.Update(msg)
+	if m.input.Value() != prev {
+		m.refilter()
+	}
+	return m, cmd
+}
+
+func (m Model) handleMouse(msg tea.MouseMsg) (Model, tea.Cmd) {
+	switch msg.Button {
+	case tea.MouseButtonLeft:
+		if msg.Action != tea.MouseActionRelease {
+			return m, nil
+		}
+		idx := m.scroll + msg.Y - 2
+		if msg.Y < 2 || idx >= len(m.filtered) {
+			return m, nil
+		}
+		return m, m.selectCmd(m.filtered[idx])
+	case tea.MouseButtonWheelUp:
+		m.moveCursor(-1)
+	case tea.MouseButtonWheelDown:
+		m.moveCursor(1)
+	}
+	return m, nil
+}
+
+func (m Model) handleKey(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
+	switch msg.String() {
+	case "enter":
+		if m.cursor < 0 || m.cursor >= len(m.filtered) {
+			return m, nil, true
+		}
+		return m, m.selectCmd(m.filtered[m.cursor]), true
+	case "up", "ctrl+k":
+		m.moveCursor(-1)
+		return m, nil, true
+	case "down", "ctrl+j":
+		m.moveCursor(1)
+		return m, nil, true
+	}
+	return m, nil, false
+}
+
+func (m Model) selectCmd(item plugin.Item) tea.Cmd {
+	p, ctx, provider := m.plugin, m.ctx, m.provider
+	return func() tea.Msg {
+		return resultMsg(selectItem(p, ctx, provider, item))
+	}
+}
+
+// RunCmd runs a plugin command that has no list and reports its result.
+func RunCmd(p plugin.Plugin, id string) tea.Cmd {
+	return func() tea.Msg {
+		return resultMsg(runCommand(p, currentContext(), id))
+	}
+}
+
+// resultMsg turns what a plugin answered into the app message that carries
+// it out.
+func resultMsg(res plugin.Result, err error) tea.Msg {
+	switch {
+	case err != nil:
+		return messages.CommandDoneMsg{Err: err}
+	case res.SwitchSession != "":
+		return messages.SwitchSessionMsg{Name: res.SwitchSession}
+	case res.SwitchWindow != "":
+		return messages.SwitchWindowMsg{Target: res.SwitchWindow}
+	case res.Popup != "":
+		return messages.RunPopupMsg{Command: res.Popup, Width: "80%", Height: "80%"}
+	}
+	return messages.CommandDoneMsg{Message: res.Message}
+}
+
+func (m *Model) moveCursor(delta int) {
+	m.cursor += delta
+	if m.cursor >= len(m.filtered) {
+		m.cursor = len(m.filtered) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	m.ensureVisible()
+}
+
+func (m *Model) refilter() {
+	query := m.input.Value()
+	if query == "" {
+		m.filtered = m.items
+	} else {
+		titles := make([]string, len(m.items))
+		for i, it := range m.items {
+			titles[i] = it.Title
+		}
+		found := fuzzy.Find(query, titles)
+		m.filtered = make([]plugin.Item, len(found))
+		for i, f := range found {
+			m.filtered[i] = m.items[f.Index]
+		}
+	}
+	m.cursor = 0
+	m.scroll = 0
+}
+
+func (m Model) maxVisible() int {
+	avail := m.height - 2
+	if avail < 1 {
+		avail = 1
+	}
+	return avail
+}
+
+func (m *Model) ensureVisible() {
+	visible := m.maxVisible()
+	if m.cursor < m.scroll {
+		m.scroll = m.cursor
+	}
+	if m.cursor >= m.scroll+visible {
+		m.scroll = m.cursor - visible + 1
+	}
+}
@@ -0,0 +1,84 @@
+package pluginlist
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/plugin"
+)
+
+func stubPlugin(t *testing.T) *plugin.Item {
+	t.Helper()
+	var selected plugin.Item
+	origCtx, origList, origSelect := currentContext, listItems, selectItem
+	currentContext = func() plugin.Context { return plugin.Context{Session: "work"} }
+	listItems = func(_ plugin.Plugin, ctx plugin.Context, provider string) ([]plugin.Item, error) {
+		if ctx.Session != "work" || provider != "prs" {
+			t.Errorf("list(%+v, %q)", ctx, provider)
+		}
+		return []plugin.Item{
+			{ID: "1", Title: "Fix login"},
+			{ID: "2", Title: "Add search"},
+		}, nil
+	}
+	selectItem = func(_ plugin.Plugin, _ plugin.Context, _ string, item plugin.Item) (plugin.Result, error) {
+		selected = item
+		return plugin.Result{SwitchSession: "pr-" + item.ID}, nil
+	}
+	t.Cleanup(func() { currentContext, listItems, selectItem = origCtx, origList, origSelect })
+	return &selected
+}
+
+func TestFilterAndSelectSendsItemBack(t *testing.T) {
+	selected := stubPlugin(t)
+	m := New(plugin.Plugin{Name: "gh", Providers: []plugin.Provider{{ID: "prs", Title: "Pull Requests"}}}, "prs")
+	if m.title != "Pull Requests" {
+		t.Fatalf("title = %q", m.title)
+	}
+	m.SetSize(80, 20)
+	ctx := currentContext()
+	items, err := listItems(m.plugin, ctx, m.provider)
+	m, _ = m.Update(loadedMsg{ctx: ctx, items: items, err: err})
+
+	for _, r := range "search" {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if len(m.filtered) != 1 || m.filtered[0].ID != "2" {
+		t.Fatalf("filtered = %+v, want Add search", m.filtered)
+	}
+
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("enter should select the item")
+	}
+	msg := cmd()
+	if !reflect.DeepEqual(msg, messages.SwitchSessionMsg{Name: "pr-2"}) {
+		t.Fatalf("msg = %#v", msg)
+	}
+	if selected.ID != "2" {
+		t.Fatalf("selected = %+v", *selected)
+	}
+}
+
+func TestResultMsg(t *testing.T) {
+	boom := errors.New("boom")
+	cases := []struct {
+		res  plugin.Result
+		err  error
+		want tea.Msg
+	}{
+		{err: boom, want: messages.CommandDoneMsg{Err: boom}},
+		{res: plugin.Result{SwitchWindow: "s:1"}, want: messages.SwitchWindowMsg{Target: "s:1"}},
+		{res: plugin.Result{Popup: "gh pr view"}, want: messages.RunPopupMsg{Command: "gh pr view", Width: "80%", Height: "80%"}},
+		{res: plugin.Result{Message: "done"}, want: messages.CommandDoneMsg{Message: "done"}},
+	}
+	for _, c := range cases {
+		if got := resultMsg(c.res, c.err); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("resultMsg(%+v, %v) = %#v, want %#v", c.res, c.err, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package pluginlist
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miltonparedes/kitmux/internal/plugin"
+	"github.com/miltonparedes/kitmux/internal/theme"
+)
+
+func (m Model) View() string {
+	var b strings.Builder
+
+	b.WriteString(" " + m.input.View())
+	b.WriteString("\n")
+
+	sepW := m.width - 2
+	if sepW < 1 {
+		sepW = 1
+	}
+	b.WriteString(" " + theme.TreeConnector.Render(strings.Repeat("─", sepW)))
+	b.WriteString("\n")
+
+	switch {
+	case !m.loaded:
+		b.WriteString(theme.HelpStyle.Render("  loading " + m.title + "..."))
+		return b.String()
+	case m.err != nil:
+		b.WriteString(theme.HelpStyle.Render("  " + m.err.Error()))
+		return b.String()
+	case len(m.filtered) == 0:
+		b.WriteString(theme.HelpStyle.Render("  no matches"))
+		return b.String()
+	}
+
+	end := m.scroll + m.maxVisible()
+	if end > len(m.filtered) {
+		end = len(m.filtered)
+	}
+	for i := m.scroll; i < end; i++ {
+		b.WriteString(renderItem(m.filtered[i], i == m.cursor))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func renderItem(it plugin.Item, selected bool) string {
+	detail := ""
+	if it.Description != "" {
+		detail = "  " + theme.TreeMeta.Render(it.Description)
+	}
+	if selected {
+		return fmt.Sprintf(" %s %s%s",
+			theme.PaletteItemSelected.Render("▸"),
+			theme.PaletteItemSelected.Render(it.Title), detail)
+	}
+	return fmt.Sprintf("   %s%s", theme.PaletteItem.Render(it.Title), detail)
+}
@@ -0,0 +1,274 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/command"
+	"github.com/miltonparedes/kitmux/internal/frecency"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/views/threads"
+	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+// Kind is the type of a search result.
+type Kind string
+
+const (
+	KindSession   Kind = "session"
+	KindThread    Kind = "thread"
+	KindWindow    Kind = "window"
+	KindWorkspace Kind = "workspace"
+	KindWorktree  Kind = "worktree"
+	KindCommand   Kind = "command"
+)
+
+// prefixes narrow a query to one kind, e.g. "s:api" or "t: refactor".
+var prefixes = map[string]Kind{
+	"s":   KindSession,
+	"t":   KindThread,
+	"win": KindWindow,
+	"w":   KindWorkspace,
+	"wt":  KindWorktree,
+	"c":   KindCommand,
+}
+
+// parseQuery splits a leading kind prefix off query. Unknown prefixes are
+// part of the query, so "feat:x" still matches a branch.
+func parseQuery(query string) (Kind, string) {
+	prefix, rest, ok := strings.Cut(query, ":")
+	if !ok {
+		return "", query
+	}
+	kind, ok := prefixes[strings.ToLower(strings.TrimSpace(prefix))]
+	if !ok {
+		return "", query
+	}
+	return kind, strings.TrimSpace(rest)
+}
+
+// Item is one search result. Label is what the query matches; Note and
+// Detail are shown beside it.
+type Item struct {
+	Kind   Kind
+	Label  string
+	Note   string // e.g. "dirty" for a worktree with local changes
+	Detail string // where the item lives: a path, session or category
+	key    string // frecency key, scored under the item's frecency kind
+	open   tea.Cmd
+}
+
+// Open returns the natural action for the item.
+func (it Item) Open() tea.Cmd {
+	return it.open
+}
+
+// Sources are where Collect reads each kind of item from.
+type Sources struct {
+	Sessions   func() ([]tmux.Session, error)
+	Windows    func(session string) ([]tmux.Window, error)
+	Threads    func() []threads.Row
+	Workspaces func() []wsreg.Workspace
+	Worktrees  func() (map[string]wsdata.WorkspaceStats, error)
+	Commands   func() []command.Command
+	Scores     func(kind string) frecency.Scores
+}
+
+// DefaultSources reads live tmux state, the workspace registry and the
+// cached worktree stats.
+func DefaultSources() Sources {
+	return Sources{
+		Sessions:   tmux.ListSessions,
+		Windows:    tmux.ListWindows,
+		Threads:    threads.LoadAll,
+		Workspaces: wsreg.LoadRegistry,
+		Worktrees:  wsdata.NewStatsService().LoadAllCached,
+		Commands:   command.Commands,
+		Scores:     frecency.Load,
+	}
+}
+
+// recordUse counts opening a workspace or worktree; swapped in tests.
+var recordUse = frecency.Record
+
+// frecencyKinds maps result kinds to the frecency they rank by. Threads
+// share the session scores of the session hosting them.
+var frecencyKinds = map[Kind]string{
+	KindSession:   frecency.KindSession,
+	KindThread:    frecency.KindSession,
+	KindWorkspace: frecency.KindWorkspace,
+	KindWorktree:  frecency.KindDir,
+	KindCommand:   frecency.KindCommand,
+}
+
+// scoreKey is the key of an item in the scores Collect returns.
+func scoreKey(kind Kind, key string) string {
+	return string(kind) + "\x00" + key
+}
+
+// Collect gathers every searchable item, grouped by kind, along with the
+// frecency scores to rank them by.
+func Collect(src Sources) ([]Item, frecency.Scores) {
+	sessions, _ := src.Sessions()
+	normal := tmux.NormalSessions(sessions)
+	sessionAt := make(map[string]string, len(normal))
+	taken := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		taken[s.Name] = true
+	}
+	for _, s := range normal {
+		if _, ok := sessionAt[s.Path]; !ok {
+			sessionAt[s.Path] = s.Name
+		}
+	}
+
+	var items []Item
+	for _, s := range normal {
+		items = append(items, Item{
+			Kind:   KindSession,
+			Label:  s.Name,
+			Detail: shortPath(s.Path),
+			key:    s.Name,
+			open:   switchSession(s.Name),
+		})
+	}
+	for _, row := range src.Threads() {
+		items = append(items, Item{
+			Kind:   KindThread,
+			Label:  threads.Label(row),
+			Detail: threadDetail(row),
+			key:    row.SessionName,
+			open:   threads.OpenCmd(row),
+		})
+	}
+	for _, s := range normal {
+		windows, err := src.Windows(s.Name)
+		if err != nil {
+			continue
+		}
+		for _, w := range windows {
+			target := fmt.Sprintf("%s:%d", s.Name, w.Index)
+			items = append(items, Item{
+				Kind:   KindWindow,
+				Label:  w.Name,
+				Detail: target,
+				open: func() tea.Msg {
+					return messages.SwitchWindowMsg{Target: target}
+				},
+			})
+		}
+	}
+
+	workspaces := src.Workspaces()
+	for _, ws := range workspaces {
+		items = append(items, Item{
+			Kind:   KindWorkspace,
+			Label:  ws.Name,
+			Detail: shortPath(ws.Path),
+			key:    ws.Path,
+			open:   openPath(frecency.KindWorkspace, ws.Name, ws.Path, sessionAt, taken),
+		})
+	}
+	stats, _ := src.Worktrees()
+	for _, ws := range workspaces {
+		for _, wt := range stats[ws.Path].Worktrees {
+			if wt.WorktreePath == "" {
+				continue
+			}
+			item := Item{
+				Kind:   KindWorktree,
+				Label:  wt.Branch,
+				Detail: ws.Name,
+				key:    wt.WorktreePath,
+				open:   openPath(frecency.KindDir, ws.Name+"-"+wt.Branch, wt.WorktreePath, sessionAt, taken),
+			}
+			if wt.Dirty() {
+				item.Note = "dirty"
+			}
+			items = append(items, item)
+		}
+	}
+
+	for _, c := range src.Commands() {
+		id := c.ID
+		items = append(items, Item{
+			Kind:   KindCommand,
+			Label:  c.Title,
+			Detail: c.Category,
+			key:    id,
+			open: func() tea.Msg {
+				return messages.ExecuteCommandMsg{ID: id}
+			},
+		})
+	}
+
+	scores := frecency.Scores{}
+	loaded := map[string]frecency.Scores{}
+	for kind, fk := range frecencyKinds {
+		if _, ok := loaded[fk]; !ok {
+			loaded[fk] = src.Scores(fk)
+		}
+		for key, score := range loaded[fk] {
+			scores[scoreKey(kind, key)] = score
+		}
+	}
+	return items, scores
+}
+
+func switchSession(name string) tea.Cmd {
+	return func() tea.Msg {
+		return messages.SwitchSessionMsg{Name: name}
+	}
+}
+
+// openPath switches to the session already rooted at path, or creates one
+// named after name (suffixed when taken) when there is none.
+func openPath(kind, name, path string, sessionAt map[string]string, taken map[string]bool) tea.Cmd {
+	if existing, ok := sessionAt[path]; ok {
+		return func() tea.Msg {
+			recordUse(kind, path)
+			return messages.SwitchSessionMsg{Name: existing}
+		}
+	}
+	name = uniqueName(name, taken)
+	return func() tea.Msg {
+		recordUse(kind, path)
+		return messages.CreateSessionInDirMsg{Name: name, Dir: path}
+	}
+}
+
+func uniqueName(name string, taken map[string]bool) string {
+	if !taken[name] {
+		return name
+	}
+	for i := 2; i <= 99; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+	return name
+}
+
+func threadDetail(row threads.Row) string {
+	switch {
+	case row.Project != "" && row.Branch != "":
+		return row.Project + " " + row.Branch
+	case row.Project != "":
+		return row.Project
+	}
+	return row.SessionName
+}
+
+func shortPath(path string) string {
+	home, _ := os.UserHomeDir()
+	if home != "" && strings.HasPrefix(path, home) {
+		return "~" + path[len(home):]
+	}
+	return path
+}
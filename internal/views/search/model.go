@@ -0,0 +1,174 @@
+// Package search is the global search view: one query matched against
+// sessions, threads, windows, workspaces, worktrees and palette commands.
+package search
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/frecency"
+)
+
+// collect gathers the items; swapped in tests.
+var collect = func() ([]Item, frecency.Scores) {
+	return Collect(DefaultSources())
+}
+
+type loadedMsg struct {
+	items  []Item
+	scores frecency.Scores
+}
+
+type Model struct {
+	items    []Item
+	filtered []Item
+	scores   frecency.Scores
+	loaded   bool
+	input    textinput.Model
+	cursor   int
+	scroll   int
+	height   int
+	width    int
+}
+
+func New() Model {
+	ti := textinput.New()
+	ti.Prompt = "> "
+	ti.Placeholder = "Search everything (s: t: win: w: wt: c:)..."
+	ti.CharLimit = 128
+	ti.Focus()
+	return Model{input: ti}
+}
+
+func (m Model) Init() tea.Cmd {
+	return tea.Batch(textinput.Blink, func() tea.Msg {
+		items, scores := collect()
+		return loadedMsg{items: items, scores: scores}
+	})
+}
+
+func (m *Model) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+}
+
+// IsEditing reports true: every key but the app's esc and ctrl+c goes to
+// the query.
+func (m Model) IsEditing() bool { return true }
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case loadedMsg:
+		m.items = msg.items
+		m.scores = msg.scores
+		m.loaded = true
+		m.refilter()
+		return m, nil
+	case tea.MouseMsg:
+		return m.handleMouse(msg)
+	case tea.KeyMsg:
+		if updated, cmd, handled := m.handleKey(msg); handled {
+			return updated, cmd
+		}
+	}
+
+	var cmd tea.Cmd
+	prev := m.input.Value()
+	m.input, cmd = m.input.Update(msg)
+	if m.input.Value() != prev {
+		m.refilter()
+	}
+	return m, cmd
+}
+
+func (m Model) handleMouse(msg tea.MouseMsg) (Model, tea.Cmd) {
+	switch msg.Button {
+	case tea.MouseButtonLeft:
+		if msg.Action != tea.MouseActionRelease {
+			return m, nil
+		}
+		idx := m.scroll + msg.Y - 2
+		if msg.Y < 2 || idx >= len(m.filtered) {
+			return m, nil
+		}
+		return m, m.filtered[idx].Open()
+	case tea.MouseButtonWheelUp:
+		m.moveCursor(-1)
+	case tea.MouseButtonWheelDown:
+		m.moveCursor(1)
+	}
+	return m, nil
+}
+
+func (m Model) handleKey(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
+	switch msg.String() {
+	case "enter":
+		if m.cursor < 0 || m.cursor >= len(m.filtered) {
+			return m, nil, true
+		}
+		return m, m.filtered[m.cursor].Open(), true
+	case "up", "ctrl+k":
+		m.moveCursor(-1)
+		return m, nil, true
+	case "down", "ctrl+j":
+		m.moveCursor(1)
+		return m, nil, true
+	}
+	return m, nil, false
+}
+
+func (m *Model) moveCursor(delta int) {
+	m.cursor += delta
+	if m.cursor >= len(m.filtered) {
+		m.cursor = len(m.filtered) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	m.ensureVisible()
+}
+
+// refilter narrows the items to the query's kind prefix, then ranks them
+// by fuzzy match and frecency.
+func (m *Model) refilter() {
+	kind, query := parseQuery(m.input.Value())
+	var pool []Item
+	for _, it := range m.items {
+		if kind == "" || it.Kind == kind {
+			pool = append(pool, it)
+		}
+	}
+	labels := make([]string, len(pool))
+	keys := make([]string, len(pool))
+	for i, it := range pool {
+		labels[i] = it.Label
+		if it.key != "" {
+			keys[i] = scoreKey(it.Kind, it.key)
+		}
+	}
+	ranked := frecency.Rank(query, labels, keys, m.scores)
+	m.filtered = make([]Item, len(ranked))
+	for i, idx := range ranked {
+		m.filtered[i] = pool[idx]
+	}
+	m.cursor = 0
+	m.scroll = 0
+}
+
+func (m Model) maxVisible() int {
+	avail := m.height - 2
+	if avail < 1 {
+		avail = 1
+	}
+	return avail
+}
+
+func (m *Model) ensureVisible() {
+	visible := m.maxVisible()
+	if m.cursor < m.scroll {
+		m.scroll = m.cursor
+	}
+	if m.cursor >= m.scroll+visible {
+		m.scroll = m.cursor - visible + 1
+	}
+}
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/command"
+	"github.com/miltonparedes/kitmux/internal/frecency"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/views/threads"
+	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+func stubSources(t *testing.T) (Sources, *[]string) {
+	t.Helper()
+	var recorded []string
+	original := recordUse
+	recordUse = func(kind, key string) { recorded = append(recorded, kind+" "+key) }
+	t.Cleanup(func() { recordUse = original })
+
+	return Sources{
+		Sessions: func() ([]tmux.Session, error) {
+			return []tmux.Session{
+				{Name: "api-fix", Path: "/src/api"},
+				{Name: "infra", Path: "/elsewhere"},
+			}, nil
+		},
+		Windows: func(session string) ([]tmux.Window, error) {
+			if session != "api-fix" {
+				return nil, nil
+			}
+			return []tmux.Window{{SessionName: session, Index: 2, Name: "editor"}}, nil
+		},
+		Threads: func() []threads.Row {
+			return []threads.Row{{
+				Kind:        threads.RowHeadless,
+				AgentSymbol: "✳",
+				Title:       "refactor auth",
+				SessionName: "kitmux-thread-1",
+			}}
+		},
+		Workspaces: func() []wsreg.Workspace {
+			return []wsreg.Workspace{
+				{Name: "api", Path: "/src/api"},
+				{Name: "infra", Path: "/src/infra"},
+			}
+		},
+		Worktrees: func() (map[string]wsdata.WorkspaceStats, error) {
+			return map[string]wsdata.WorkspaceStats{
+				"/src/api": {Worktrees: []wsdata.WorktreeStat{
+					{Branch: "main", WorktreePath: "/src/api", IsMain: true},
+					{Branch: "feat/x", WorktreePath: "/src/api-feat-x", Modified: true},
+				}},
+			}, nil
+		},
+		Commands: func() []command.Command {
+			return []command.Command{{ID: "view_diff", Title: "Diff View", Category: "View"}}
+		},
+		Scores: func(kind string) frecency.Scores {
+			if kind == frecency.KindSession {
+				return frecency.Scores{"infra": 4}
+			}
+			return nil
+		},
+	}, &recorded
+}
+
+func find(t *testing.T, items []Item, kind Kind, label string) Item {
+	t.Helper()
+	for _, it := range items {
+		if it.Kind == kind && it.Label == label {
+			return it
+		}
+	}
+	t.Fatalf("no %s %q in %+v", kind, label, items)
+	return Item{}
+}
+
+func TestCollectOpensEachKindNaturally(t *testing.T) {
+	src, recorded := stubSources(t)
+	items, _ := Collect(src)
+
+	cases := []struct {
+		kind  Kind
+		label string
+		want  tea.Msg
+	}{
+		{KindSession, "api-fix", messages.SwitchSessionMsg{Name: "api-fix"}},
+		{KindThread, "✳ refactor auth", messages.SwitchSessionMsg{Name: "kitmux-thread-1"}},
+		{KindWindow, "editor", messages.SwitchWindowMsg{Target: "api-fix:2"}},
+		{KindWorkspace, "api", messages.SwitchSessionMsg{Name: "api-fix"}},
+		// "infra" is taken by a session rooted elsewhere.
+		{KindWorkspace, "infra", messages.CreateSessionInDirMsg{Name: "infra-2", Dir: "/src/infra"}},
+		{KindWorktree, "feat/x", messages.CreateSessionInDirMsg{Name: "api-feat/x", Dir: "/src/api-feat-x"}},
+		{KindCommand, "Diff View", messages.ExecuteCommandMsg{ID: "view_diff"}},
+	}
+	for _, tc := range cases {
+		it := find(t, items, tc.kind, tc.label)
+		if got := it.Open()(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s %q opened %#v, want %#v", tc.kind, tc.label, got, tc.want)
+		}
+	}
+	if it := find(t, items, KindWorktree, "feat/x"); it.Note != "dirty" {
+		t.Errorf("feat/x note = %q, want dirty", it.Note)
+	}
+	if len(*recorded) != 3 {
+		t.Errorf("recorded = %v, want the two workspaces and the worktree", *recorded)
+	}
+}
+
+func TestPrefixNarrowsSearchToOneKind(t *testing.T) {
+	src, _ := stubSources(t)
+	items, scores := Collect(src)
+	m := New()
+	m, _ = m.Update(loadedMsg{items: items, scores: scores})
+
+	m.input.SetValue("w: ")
+	m.refilter()
+	if len(m.filtered) != 2 {
+		t.Fatalf("w: matched %d items, want both workspaces: %+v", len(m.filtered), m.filtered)
+	}
+	for _, it := range m.filtered {
+		if it.Kind != KindWorkspace {
+			t.Fatalf("w: matched a %s", it.Kind)
+		}
+	}
+
+	m.input.SetValue("s:")
+	m.refilter()
+	if len(m.filtered) != 2 || m.filtered[0].Label != "infra" {
+		t.Fatalf("s: = %+v, want sessions with the most used first", m.filtered)
+	}
+
+	if kind, query := parseQuery("feat:x"); kind != "" || query != "feat:x" {
+		t.Fatalf("unknown prefix should stay in the query, got %q %q", kind, query)
+	}
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miltonparedes/kitmux/internal/theme"
+)
+
+func (m Model) View() string {
+	var b strings.Builder
+
+	b.WriteString(" " + m.input.View())
+	b.WriteString("\n")
+
+	sepW := m.width - 2
+	if sepW < 1 {
+		sepW = 1
+	}
+	b.WriteString(" " + theme.TreeConnector.Render(strings.Repeat("─", sepW)))
+	b.WriteString("\n")
+
+	if !m.loaded {
+		b.WriteString(theme.HelpStyle.Render("  loading..."))
+		return b.String()
+	}
+	if len(m.filtered) == 0 {
+		b.WriteString(theme.HelpStyle.Render("  no matches"))
+		return b.String()
+	}
+
+	end := m.scroll + m.maxVisible()
+	if end > len(m.filtered) {
+		end = len(m.filtered)
+	}
+	for i := m.scroll; i < end; i++ {
+		b.WriteString(m.renderItem(m.filtered[i], i == m.cursor))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (m Model) renderItem(it Item, selected bool) string {
+	label := it.Label
+	if it.Note != "" {
+		label += " (" + it.Note + ")"
+	}
+	kind := theme.PaletteCategory.Render(fmt.Sprintf("%s:", it.Kind))
+	detail := ""
+	if it.Detail != "" {
+		detail = "  " + theme.TreeMeta.Render(it.Detail)
+	}
+	if selected {
+		return fmt.Sprintf(" %s %s %s%s",
+			theme.PaletteItemSelected.Render("▸"), kind,
+			theme.PaletteItemSelected.Render(label), detail)
+	}
+	return fmt.Sprintf("   %s %s%s", kind, theme.PaletteItem.Render(label), detail)
+}
@@ -0,0 +1,451 @@
+package sessions
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+// bulkAction identifies the operation applied to every marked session.
+type bulkAction int
+
+const (
+	bulkNone bulkAction = iota
+	bulkKill
+	bulkRename
+	bulkArchive
+	bulkDetach
+)
+
+func (a bulkAction) verb() string {
+	switch a {
+	case bulkKill:
+		return "kill"
+	case bulkRename:
+		return "rename"
+	case bulkArchive:
+		return "archive"
+	case bulkDetach:
+		return "detach"
+	default:
+		return ""
+	}
+}
+
+func (a bulkAction) pastTense() string {
+	switch a {
+	case bulkKill:
+		return "killed"
+	case bulkRename:
+		return "renamed"
+	case bulkArchive:
+		return "archived"
+	case bulkDetach:
+		return "detached"
+	default:
+		return ""
+	}
+}
+
+// bulkItem is one row of a bulk plan. Skip holds the reason a marked session
+// is listed but left untouched (e.g. the main worktree cannot be archived).
+type bulkItem struct {
+	SessionName string
+	NewName     string // bulkRename target
+	Path        string // bulkArchive worktree path
+	RepoRoot    string // bulkArchive workspace path
+	Skip        string
+}
+
+// bulkPlan is the full list shown in the single confirmation prompt.
+type bulkPlan struct {
+	action bulkAction
+	items  []bulkItem
+}
+
+func (p bulkPlan) actionable() int {
+	n := 0
+	for _, it := range p.items {
+		if it.Skip == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// bulkDoneMsg reports the outcome of a bulk run and carries the reloaded
+// session list so the tree refreshes in the same message.
+type bulkDoneMsg struct {
+	action    bulkAction
+	done      int
+	failed    []string
+	sessions  []tmux.Session
+	repoRoots map[string]string
+}
+
+var (
+	renameTmuxSession      = tmux.RenameSession
+	detachTmuxClients      = tmux.DetachSessionClients
+	archiveSessionWorktree = wsreg.AddArchivedWorktree
+	resolveWorktreeRoot    = wsdata.ResolveWorktreeRoot
+)
+
+// rememberSessions keeps the path and repo root of each session so bulk
+// actions can act on the underlying worktree.
+func (m *Model) rememberSessions(sessions []tmux.Session, repoRoots map[string]string) {
+	m.sessionPaths = make(map[string]string, len(sessions))
+	for _, s := range sessions {
+		m.sessionPaths[s.Name] = s.Path
+	}
+	m.repoRoots = repoRoots
+	m.pruneMarks()
+}
+
+// pruneMarks drops marks for sessions that no longer exist.
+func (m *Model) pruneMarks() {
+	for name := range m.marked {
+		if _, ok := m.sessionPaths[name]; !ok {
+			delete(m.marked, name)
+		}
+	}
+}
+
+func (m *Model) setMark(name string, on bool) {
+	if name == "" {
+		return
+	}
+	if m.marked == nil {
+		m.marked = make(map[string]bool)
+	}
+	if on {
+		m.marked[name] = true
+		return
+	}
+	delete(m.marked, name)
+}
+
+// toggleMark marks or unmarks the session under the cursor. On a group
+// header it behaves like markGroup.
+func (m *Model) toggleMark() {
+	node := m.selected()
+	if node == nil {
+		return
+	}
+	if node.Kind != KindSession {
+		m.markGroup()
+		return
+	}
+	m.setMark(node.SessionName, !m.marked[node.SessionName])
+}
+
+// markGroup marks every session in the repo group under the cursor, or
+// unmarks them all when the group is already fully marked.
+func (m *Model) markGroup() {
+	names := m.groupSessionNames(m.selected())
+	if len(names) == 0 {
+		return
+	}
+	allMarked := true
+	for _, name := range names {
+		if !m.marked[name] {
+			allMarked = false
+			break
+		}
+	}
+	for _, name := range names {
+		m.setMark(name, !allMarked)
+	}
+}
+
+// markVisible marks every session currently shown, which during a search
+// means every match of the filter.
+func (m *Model) markVisible() {
+	for _, node := range m.visible {
+		if node.Kind == KindSession {
+			m.setMark(node.SessionName, true)
+		}
+	}
+}
+
+func (m *Model) clearMarks() {
+	m.marked = make(map[string]bool)
+}
+
+// groupSessionNames returns the sessions of the root group containing node.
+func (m Model) groupSessionNames(node *TreeNode) []string {
+	if node == nil {
+		return nil
+	}
+	for _, root := range m.roots {
+		if root != node && !inGroup(root, node.SessionName) {
+			continue
+		}
+		var names []string
+		if root.Kind == KindSession {
+			names = append(names, root.SessionName)
+		}
+		for _, c := range root.Children {
+			if c.Kind == KindSession {
+				names = append(names, c.SessionName)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// inGroup matches by session name because search results are flat copies of
+// the tree nodes.
+func inGroup(root *TreeNode, name string) bool {
+	if name == "" {
+		return false
+	}
+	if root.SessionName == name {
+		return true
+	}
+	for _, c := range root.Children {
+		if c.SessionName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// markedNames returns the marked sessions in tree order so the confirmation
+// list matches what the user sees.
+func (m Model) markedNames() []string {
+	var names []string
+	for _, node := range Flatten(expandedCopy(m.roots)) {
+		if node.Kind == KindSession && m.marked[node.SessionName] {
+			names = append(names, node.SessionName)
+		}
+	}
+	return names
+}
+
+// handleBulkKey handles multi-select keys. Bulk actions only take over d/r
+// while something is marked; A and D fall back to the cursor session.
+func (m Model) handleBulkKey(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
+	switch msg.String() {
+	case "x":
+		m.toggleMark()
+		return m, nil, true
+	case "X":
+		m.markGroup()
+		return m, nil, true
+	case "u":
+		m.clearMarks()
+		return m, nil, true
+	case "esc":
+		if m.HasMarks() {
+			m.clearMarks()
+			return m, nil, true
+		}
+	case "d":
+		if m.HasMarks() {
+			return m.startBulk(bulkKill), nil, true
+		}
+	case "r":
+		if m.HasMarks() {
+			return m.startBulkRename()
+		}
+	case "A":
+		return m.startBulk(bulkArchive), nil, true
+	case "D":
+		return m.startBulk(bulkDetach), nil, true
+	}
+	return m, nil, false
+}
+
+// bulkTargets returns the marked sessions, or the cursor session when
+// nothing is marked.
+func (m Model) bulkTargets() []string {
+	if names := m.markedNames(); len(names) > 0 {
+		return names
+	}
+	if name := m.SelectedSessionName(); name != "" {
+		return []string{name}
+	}
+	return nil
+}
+
+func (m Model) startBulk(action bulkAction) Model {
+	names := m.bulkTargets()
+	if len(names) == 0 {
+		return m
+	}
+	m.status = ""
+	m.bulk = bulkPlan{action: action, items: m.planItems(action, names)}
+	return m
+}
+
+func (m Model) planItems(action bulkAction, names []string) []bulkItem {
+	items := make([]bulkItem, 0, len(names))
+	for _, name := range names {
+		item := bulkItem{SessionName: name}
+		if action == bulkArchive {
+			item.Path = m.sessionPaths[name]
+			item.RepoRoot = m.repoRoots[name]
+			item.Skip = archiveSkipReason(item)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func archiveSkipReason(item bulkItem) string {
+	switch {
+	case item.RepoRoot == "" || item.Path == "":
+		return "not a git worktree"
+	case item.Path == item.RepoRoot:
+		return "main worktree"
+	default:
+		return ""
+	}
+}
+
+func (m Model) startBulkRename() (Model, tea.Cmd, bool) {
+	m.status = ""
+	m.bulkRenaming = true
+	m.bulkRenameInput.SetValue("")
+	m.bulkRenameInput.Focus()
+	return m, textinput.Blink, true
+}
+
+func (m Model) handleBulkRename(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.bulkRenaming = false
+		return m, nil
+	case "enter":
+		pattern := strings.TrimSpace(m.bulkRenameInput.Value())
+		if pattern == "" {
+			return m, nil
+		}
+		m.bulkRenaming = false
+		m.bulk = bulkPlan{action: bulkRename, items: m.renameItems(pattern)}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.bulkRenameInput, cmd = m.bulkRenameInput.Update(msg)
+	return m, cmd
+}
+
+// renameItems expands the pattern for every marked session and flags names
+// that would collide with an existing session or another rename.
+func (m Model) renameItems(pattern string) []bulkItem {
+	names := m.markedNames()
+	taken := make(map[string]bool, len(m.sessionPaths))
+	for name := range m.sessionPaths {
+		taken[name] = true
+	}
+	for _, name := range names {
+		delete(taken, name)
+	}
+	items := make([]bulkItem, 0, len(names))
+	for i, name := range names {
+		item := bulkItem{SessionName: name, NewName: expandRenamePattern(pattern, name, i+1)}
+		switch {
+		case item.NewName == name:
+			item.Skip = "unchanged"
+		case taken[item.NewName]:
+			item.Skip = "name taken"
+		default:
+			taken[item.NewName] = true
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// expandRenamePattern substitutes {name} with the current session name and
+// {n} with its 1-based position. A pattern without either placeholder gets
+// "-{n}" appended so every session still ends up with a unique name.
+func expandRenamePattern(pattern, name string, n int) string {
+	if !strings.Contains(pattern, "{name}") && !strings.Contains(pattern, "{n}") {
+		pattern += "-{n}"
+	}
+	return strings.NewReplacer("{name}", name, "{n}", strconv.Itoa(n)).Replace(pattern)
+}
+
+func (m Model) handleBulkConfirm(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		plan := m.bulk
+		m.bulk = bulkPlan{}
+		if plan.actionable() == 0 {
+			return m, nil
+		}
+		return m, runBulkCmd(plan)
+	case "n", "N", "esc":
+		m.bulk = bulkPlan{}
+	}
+	return m, nil
+}
+
+// runBulkCmd applies the plan one session at a time, keeps going past
+// failures, and reloads the session list afterwards.
+func runBulkCmd(plan bulkPlan) tea.Cmd {
+	return func() tea.Msg {
+		res := bulkDoneMsg{action: plan.action}
+		for _, item := range plan.items {
+			if item.Skip != "" {
+				continue
+			}
+			if err := applyBulkItem(plan.action, item); err != nil {
+				res.failed = append(res.failed, fmt.Sprintf("%s (%v)", item.SessionName, err))
+				continue
+			}
+			res.done++
+		}
+		sessions, _ := listTmuxSessions()
+		res.sessions = tmux.NormalSessions(sessions)
+		res.repoRoots = resolveRepoRoots(res.sessions)
+		return res
+	}
+}
+
+func applyBulkItem(action bulkAction, item bulkItem) error {
+	switch action {
+	case bulkKill:
+		return killTmuxSession(item.SessionName)
+	case bulkRename:
+		return renameTmuxSession(item.SessionName, item.NewName)
+	case bulkDetach:
+		return detachTmuxClients(item.SessionName)
+	case bulkArchive:
+		// The session may sit in a subdirectory; archive the worktree
+		// itself, and only then kill the session so a failed archive
+		// leaves it running.
+		root := resolveWorktreeRoot(item.Path)
+		switch {
+		case root == "":
+			return fmt.Errorf("%s is not in a worktree", item.Path)
+		case filepath.Clean(root) == filepath.Clean(item.RepoRoot):
+			return fmt.Errorf("main worktree")
+		}
+		if !archiveSessionWorktree(item.RepoRoot, root) {
+			return fmt.Errorf("archive %s failed", root)
+		}
+		return killTmuxSession(item.SessionName)
+	}
+	return nil
+}
+
+func (m Model) handleBulkDone(msg bulkDoneMsg) (Model, tea.Cmd) {
+	m.clearMarks()
+	updated, cmd := m.handleSessionsLoaded(sessionsLoadedMsg{sessions: msg.sessions, repoRoots: msg.repoRoots})
+	if len(msg.failed) > 0 {
+		updated.status = fmt.Sprintf("%s %d, failed: %s",
+			msg.action.pastTense(), msg.done, strings.Join(msg.failed, ", "))
+	}
+	return updated, cmd
+}
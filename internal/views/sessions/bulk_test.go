@@ -0,0 +1,229 @@
+package sessions
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/tmux"
+)
+
+func modelWithRepoGroup() Model {
+	m := New()
+	m.SetSize(80, 20)
+	sessions := []tmux.Session{
+		{Name: "app", Path: "/repos/app"},
+		{Name: "app-feat-a", Path: "/repos/app-feat-a"},
+		{Name: "app-feat-b", Path: "/repos/app-feat-b"},
+		{Name: "notes", Path: "/tmp/notes"},
+	}
+	repoRoots := map[string]string{
+		"app":        "/repos/app",
+		"app-feat-a": "/repos/app",
+		"app-feat-b": "/repos/app",
+	}
+	m.rememberSessions(sessions, repoRoots)
+	m.roots = BuildTree(sessions, repoRoots)
+	m.visible = Flatten(m.roots)
+	return m
+}
+
+func stubBulkTmux(t *testing.T) *[]string {
+	t.Helper()
+	originalKill := killTmuxSession
+	originalList := listTmuxSessions
+	originalRename := renameTmuxSession
+	originalDetach := detachTmuxClients
+	originalArchive := archiveSessionWorktree
+	originalResolve := resolveWorktreeRoot
+	t.Cleanup(func() {
+		killTmuxSession = originalKill
+		listTmuxSessions = originalList
+		renameTmuxSession = originalRename
+		detachTmuxClients = originalDetach
+		archiveSessionWorktree = originalArchive
+		resolveWorktreeRoot = originalResolve
+	})
+
+	var calls []string
+	killTmuxSession = func(name string) error {
+		calls = append(calls, "kill "+name)
+		return nil
+	}
+	renameTmuxSession = func(old, newName string) error {
+		calls = append(calls, "rename "+old+" "+newName)
+		return nil
+	}
+	detachTmuxClients = func(name string) error {
+		calls = append(calls, "detach "+name)
+		return nil
+	}
+	archiveSessionWorktree = func(root, path string) bool {
+		calls = append(calls, "archive "+root+" "+path)
+		return true
+	}
+	resolveWorktreeRoot = func(dir string) string {
+		root, _, _ := strings.Cut(dir, "/sub")
+		return root
+	}
+	listTmuxSessions = func() ([]tmux.Session, error) { return nil, nil }
+	return &calls
+}
+
+func TestMarkGroupSelectsWholeRepoGroup(t *testing.T) {
+	m := modelWithRepoGroup()
+	m.cursor = 0 // "app" group root
+
+	updated, _ := m.Update(sessionKeyMsg("X"))
+	m = updated
+	got := m.markedNames()
+	want := []string{"app", "app-feat-a", "app-feat-b"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("marked = %v, want %v", got, want)
+	}
+
+	updated, _ = m.Update(sessionKeyMsg("X"))
+	m = updated
+	if m.HasMarks() {
+		t.Fatalf("second X should unmark the group, got %v", m.markedNames())
+	}
+}
+
+func TestBulkKillConfirmsOnceForAllMarked(t *testing.T) {
+	calls := stubBulkTmux(t)
+	m := modelWithRepoGroup()
+
+	for _, name := range []string{"app-feat-a", "notes"} {
+		m.setMark(name, true)
+	}
+	updated, _ := m.Update(sessionKeyMsg("d"))
+	m = updated
+	if m.bulk.action != bulkKill || len(m.bulk.items) != 2 {
+		t.Fatalf("expected kill plan for 2 sessions, got %+v", m.bulk)
+	}
+	if !strings.Contains(m.View(), "app-feat-a") || !strings.Contains(m.View(), "notes") {
+		t.Fatalf("confirmation should list every session:\n%s", m.View())
+	}
+
+	updated, cmd := m.Update(sessionKeyMsg("y"))
+	m = updated
+	if cmd == nil {
+		t.Fatal("expected bulk command")
+	}
+	msg := cmd()
+	if got := strings.Join(*calls, ","); got != "kill app-feat-a,kill notes" {
+		t.Fatalf("calls = %q", got)
+	}
+	updated, _ = m.Update(msg)
+	if updated.HasMarks() {
+		t.Fatal("marks should clear after a bulk run")
+	}
+}
+
+func TestBulkRenameExpandsPatternAndSkipsCollisions(t *testing.T) {
+	calls := stubBulkTmux(t)
+	m := modelWithRepoGroup()
+	m.setMark("app-feat-a", true)
+	m.setMark("app-feat-b", true)
+
+	items := m.renameItems("notes")
+	if items[0].NewName != "notes-1" || items[1].NewName != "notes-2" {
+		t.Fatalf("unexpected names: %+v", items)
+	}
+
+	items = m.renameItems("old-{name}")
+	if items[0].NewName != "old-app-feat-a" || items[0].Skip != "" {
+		t.Fatalf("unexpected item: %+v", items[0])
+	}
+
+	m.bulk = bulkPlan{action: bulkRename, items: []bulkItem{
+		{SessionName: "app-feat-a", NewName: "notes", Skip: "name taken"},
+		{SessionName: "app-feat-b", NewName: "old-b"},
+	}}
+	_, cmd := m.Update(sessionKeyMsg("y"))
+	cmd()
+	if got := strings.Join(*calls, ","); got != "rename app-feat-b old-b" {
+		t.Fatalf("calls = %q", got)
+	}
+}
+
+func TestBulkArchiveSkipsMainWorktree(t *testing.T) {
+	calls := stubBulkTmux(t)
+	m := modelWithRepoGroup()
+	m.setMark("app", true)
+	m.setMark("app-feat-b", true)
+
+	updated, _ := m.Update(sessionKeyMsg("A"))
+	m = updated
+	if m.bulk.actionable() != 1 {
+		t.Fatalf("expected one archivable session, got %+v", m.bulk.items)
+	}
+	_, cmd := m.Update(sessionKeyMsg("y"))
+	cmd()
+	want := "archive /repos/app /repos/app-feat-b,kill app-feat-b"
+	if got := strings.Join(*calls, ","); got != want {
+		t.Fatalf("calls = %q, want %q", got, want)
+	}
+}
+
+func TestBulkArchiveArchivesWorktreeRootBeforeKilling(t *testing.T) {
+	calls := stubBulkTmux(t)
+	archiveSessionWorktree = func(root, path string) bool {
+		*calls = append(*calls, "archive "+root+" "+path)
+		return path != "/repos/app-feat-a"
+	}
+	m := modelWithRepoGroup()
+	m.sessionPaths["app-feat-b"] = "/repos/app-feat-b/sub/pkg"
+	m.setMark("app-feat-a", true)
+	m.setMark("app-feat-b", true)
+
+	updated, _ := m.Update(sessionKeyMsg("A"))
+	_, cmd := updated.Update(sessionKeyMsg("y"))
+	done := cmd().(bulkDoneMsg)
+	want := "archive /repos/app /repos/app-feat-a,archive /repos/app /repos/app-feat-b,kill app-feat-b"
+	if got := strings.Join(*calls, ","); got != want {
+		t.Fatalf("calls = %q, want %q; a failed archive must keep its session", got, want)
+	}
+	if done.done != 1 || len(done.failed) != 1 || !strings.Contains(done.failed[0], "app-feat-a") {
+		t.Fatalf("done = %d, failed = %v", done.done, done.failed)
+	}
+}
+
+func TestBulkReportsFailures(t *testing.T) {
+	stubBulkTmux(t)
+	detachTmuxClients = func(name string) error {
+		if name == "notes" {
+			return errors.New("no clients")
+		}
+		return nil
+	}
+	m := modelWithRepoGroup()
+	m.setMark("app-feat-a", true)
+	m.setMark("notes", true)
+
+	updated, _ := m.Update(sessionKeyMsg("D"))
+	m = updated
+	_, cmd := m.Update(sessionKeyMsg("y"))
+	updated, _ = m.Update(cmd())
+	if !strings.Contains(updated.StatusLine(), "notes (no clients)") {
+		t.Fatalf("expected failure in status line, got %q", updated.StatusLine())
+	}
+}
+
+func TestSearchCtrlAMarksAllMatches(t *testing.T) {
+	m := modelWithRepoGroup()
+	updated, _ := m.Update(sessionKeyMsg("/"))
+	m = updated
+	for _, r := range "feat" {
+		updated, _ = m.Update(sessionKeyMsg(string(r)))
+		m = updated
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlA})
+	m = updated
+	got := strings.Join(m.markedNames(), ",")
+	if got != "app-feat-a,app-feat-b" {
+		t.Fatalf("marked = %q", got)
+	}
+}
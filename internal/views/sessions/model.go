@@ -6,11 +6,11 @@ import (
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/sahilm/fuzzy"
 
 	"github.com/miltonparedes/kitmux/internal/app/messages"
 	"github.com/miltonparedes/kitmux/internal/cache"
 	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/frecency"
 	"github.com/miltonparedes/kitmux/internal/tmux"
 	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
 )
@@ -31,8 +31,21 @@ type Model struct {
 	searchInput textinput.Model
 	picking     bool // zoxide directory picker active
 	picker      zoxidePicker
-	justLoaded  bool // set on sessionsLoadedMsg, cleared by ConsumeLoaded
 	status      string
+
+	// Multi-select: marked session names and the bulk action awaiting
+	// confirmation. sessionPaths/repoRoots mirror the last load so bulk
+	// actions can resolve the worktree behind each session.
+	marked          map[string]bool
+	bulk            bulkPlan
+	bulkRenaming    bool
+	bulkRenameInput textinput.Model
+	sessionPaths    map[string]string
+	repoRoots       map[string]string
+
+	// scores are the session frecency scores from the last load; search
+	// ranks matches with them.
+	scores frecency.Scores
 }
 
 func New() Model {
@@ -45,10 +58,17 @@ func New() Model {
 	si.Placeholder = "search sessions..."
 	si.CharLimit = 128
 
+	bi := textinput.New()
+	bi.Prompt = "Rename pattern: "
+	bi.Placeholder = "old-{name}"
+	bi.CharLimit = 64
+
 	return Model{
-		renameInput: ri,
-		searchInput: si,
-		picker:      newZoxidePicker(),
+		renameInput:     ri,
+		searchInput:     si,
+		bulkRenameInput: bi,
+		marked:          make(map[string]bool),
+		picker:          newZoxidePicker(),
 	}
 }
 
@@ -63,7 +83,13 @@ func (m *Model) SetSize(w, h int) {
 
 // IsEditing returns true when the user is in an input mode (rename, confirm, picking).
 func (m Model) IsEditing() bool {
-	return m.confirming || m.renaming || m.searching || m.picking
+	return m.confirming || m.renaming || m.searching || m.picking ||
+		m.bulkRenaming || m.bulk.action != bulkNone
+}
+
+// HasMarks reports whether any session is marked for a bulk action.
+func (m Model) HasMarks() bool {
+	return len(m.marked) > 0
 }
 
 // SelectedSessionName returns the session name under the cursor, or empty string.
@@ -82,6 +108,7 @@ type sessionStats struct {
 type sessionsLoadedMsg struct {
 	sessions  []tmux.Session
 	repoRoots map[string]string
+	scores    frecency.Scores
 }
 
 type statsLoadedMsg struct {
@@ -103,6 +130,8 @@ type cachedSnapshotMsg struct {
 var (
 	listTmuxSessions = tmux.ListSessions
 	killTmuxSession  = tmux.KillSession
+	loadScores       = frecency.Load
+	recordUse        = frecency.Record
 )
 
 func (m Model) loadSessions() tea.Msg {
@@ -120,7 +149,11 @@ func (m Model) loadSessions() tea.Msg {
 		curr.RepoRootsRefreshedAt = repoRootsRefreshedAt
 	})
 
-	return sessionsLoadedMsg{sessions: sessions, repoRoots: repoRoots}
+	return sessionsLoadedMsg{
+		sessions:  sessions,
+		repoRoots: repoRoots,
+		scores:    loadScores(frecency.KindSession),
+	}
 }
 
 // loadSessionsCached emits a cached snapshot first (if available), then
@@ -271,8 +304,10 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	case statsLoadedMsg:
 		applyStats(m.roots, msg.stats)
 		return m, nil
+	case bulkDoneMsg:
+		return m.handleBulkDone(msg)
 	case zoxideEntriesLoadedMsg:
-		m.picker.setEntries(msg.entries)
+		m.picker.setEntries(msg.entries, msg.scores)
 		return m, nil
 	case tea.MouseMsg:
 		return m.handleMouse(msg)
@@ -283,6 +318,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 }
 
 func (m Model) handleCachedSnapshot(msg cachedSnapshotMsg) (Model, tea.Cmd) {
+	m.rememberSessions(msg.sessions, msg.repoRoots)
 	m.roots = BuildTree(msg.sessions, msg.repoRoots)
 	m.visible = Flatten(m.roots)
 	m.clampCursor()
@@ -294,13 +330,16 @@ func (m Model) handleCachedSnapshot(msg cachedSnapshotMsg) (Model, tea.Cmd) {
 
 func (m Model) handleSessionsLoaded(msg sessionsLoadedMsg) (Model, tea.Cmd) {
 	m.status = ""
+	if msg.scores != nil {
+		m.scores = msg.scores
+	}
+	m.rememberSessions(msg.sessions, msg.repoRoots)
 	m.roots = BuildTree(msg.sessions, msg.repoRoots)
 	if snapStats := sharedStatsForSessions(msg.sessions); len(snapStats) > 0 {
 		applyStats(m.roots, snapStats)
 	}
 	m.visible = Flatten(m.roots)
 	m.clampCursor()
-	m.justLoaded = true
 	sessions := msg.sessions
 	repoRoots := msg.repoRoots
 	return m, tea.Batch(m.emitCursorChange(), func() tea.Msg {
@@ -356,6 +395,10 @@ func (m Model) handleMouseLeft(msg tea.MouseMsg) (Model, tea.Cmd) {
 
 func (m Model) routeKey(msg tea.KeyMsg) (Model, tea.Cmd) {
 	switch {
+	case m.bulk.action != bulkNone:
+		return m.handleBulkConfirm(msg)
+	case m.bulkRenaming:
+		return m.handleBulkRename(msg)
 	case m.picking:
 		return m.handlePicker(msg)
 	case m.searching:
@@ -400,6 +443,9 @@ func (m Model) handleNormal(msg tea.KeyMsg) (Model, tea.Cmd) {
 		}
 		return updated, nil
 	}
+	if updated, cmd, handled := m.handleBulkKey(msg); handled {
+		return updated, cmd
+	}
 	if updated, cmd, handled := m.handleNormalAction(msg); handled {
 		return updated, cmd
 	}
@@ -687,6 +733,9 @@ func (m Model) handleSearch(msg tea.KeyMsg) (Model, tea.Cmd) {
 		m.clampCursor()
 		m.ensureVisible()
 		return m, nil
+	case "ctrl+a":
+		m.markVisible()
+		return m, nil
 	}
 
 	var cmd tea.Cmd
@@ -707,41 +756,24 @@ func (m *Model) filterSessions() {
 		}
 	}
 
-	query := m.searchInput.Value()
-	if query == "" {
-		// Show all sessions flat
-		flat := make([]*TreeNode, len(sessions))
-		for i, s := range sessions {
-			flat[i] = &TreeNode{
-				Kind:        KindSession,
-				Name:        s.SessionName,
-				SessionName: s.SessionName,
-				Windows:     s.Windows,
-				Attached:    s.Attached,
-				Depth:       0,
-			}
-		}
-		m.visible = flat
-	} else {
-		names := make([]string, len(sessions))
-		for i, s := range sessions {
-			names[i] = s.SessionName
+	names := make([]string, len(sessions))
+	for i, s := range sessions {
+		names[i] = s.SessionName
+	}
+	ranked := frecency.Rank(m.searchInput.Value(), names, names, m.scores)
+	flat := make([]*TreeNode, len(ranked))
+	for i, idx := range ranked {
+		s := sessions[idx]
+		flat[i] = &TreeNode{
+			Kind:        KindSession,
+			Name:        s.SessionName,
+			SessionName: s.SessionName,
+			Windows:     s.Windows,
+			Attached:    s.Attached,
+			Depth:       0,
 		}
-		matches := fuzzy.Find(query, names)
-		flat := make([]*TreeNode, len(matches))
-		for i, match := range matches {
-			s := sessions[match.Index]
-			flat[i] = &TreeNode{
-				Kind:        KindSession,
-				Name:        s.SessionName,
-				SessionName: s.SessionName,
-				Windows:     s.Windows,
-				Attached:    s.Attached,
-				Depth:       0,
-			}
-		}
-		m.visible = flat
 	}
+	m.visible = flat
 	m.cursor = 0
 	m.scroll = 0
 }
@@ -824,16 +856,6 @@ func (m Model) HasData() bool {
 	return len(m.visible) > 0
 }
 
-// ConsumeLoaded returns true once after a sessionsLoadedMsg was processed,
-// then resets the flag. Use this to gate deferred actions on a fresh reload.
-func (m *Model) ConsumeLoaded() bool {
-	if m.justLoaded {
-		m.justLoaded = false
-		return true
-	}
-	return false
-}
-
 // SetPickingMode activates the zoxide directory picker state.
 // Use this from a value-receiver context (e.g. Init) where pointer-receiver
 // mutations would be lost.
@@ -7,7 +7,9 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/miltonparedes/kitmux/internal/frecency"
 	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
 )
 
 func sessionKeyMsg(s string) tea.KeyMsg {
@@ -103,3 +105,62 @@ func TestConfirmKillReportsFailure(t *testing.T) {
 		t.Fatalf("expected failure in status line, got %q", m.StatusLine())
 	}
 }
+
+func TestKillSessionAgainstServer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := tmuxtest.Install(t)
+	srv.AddSession("alpha", "/tmp/alpha")
+	srv.AddSession("beta", "/tmp/beta")
+
+	m := New()
+	m, _ = m.Update(m.loadSessions())
+	if len(m.visible) != 2 {
+		t.Fatalf("visible = %d, want 2", len(m.visible))
+	}
+	for i, node := range m.visible {
+		if node.SessionName == "beta" {
+			m.cursor = i
+		}
+	}
+
+	m, _ = m.Update(sessionKeyMsg("d"))
+	m, cmd := m.Update(sessionKeyMsg("y"))
+	if cmd == nil {
+		t.Fatal("expected kill command")
+	}
+	m, _ = m.Update(cmd())
+
+	if got := srv.SessionNames(); len(got) != 1 || got[0] != "alpha" {
+		t.Fatalf("server sessions = %v, want [alpha]", got)
+	}
+	if len(m.visible) != 1 || m.visible[0].SessionName != "alpha" {
+		t.Fatalf("visible after reload = %+v", m.visible)
+	}
+}
+
+func TestSearchRanksFrequentSessionsFirst(t *testing.T) {
+	m := New()
+	m, _ = m.Update(sessionsLoadedMsg{
+		sessions: []tmux.Session{
+			{Name: "api", Path: "/tmp/api"},
+			{Name: "app", Path: "/tmp/app"},
+			{Name: "notes", Path: "/tmp/notes"},
+		},
+		scores: frecency.Scores{"notes": 5, "app": 2},
+	})
+
+	m, _ = m.Update(sessionKeyMsg("/"))
+	if got := m.visible[0].SessionName; got != "notes" {
+		t.Fatalf("empty search first = %q, want notes", got)
+	}
+
+	m, _ = m.Update(sessionKeyMsg("a"))
+	m, _ = m.Update(sessionKeyMsg("p"))
+	var got []string
+	for _, n := range m.visible {
+		got = append(got, n.SessionName)
+	}
+	if strings.Join(got, ",") != "app,api" {
+		t.Fatalf("ranked = %v, want [app api]", got)
+	}
+}
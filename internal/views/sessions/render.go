@@ -12,6 +12,9 @@ func (m Model) View() string {
 	if m.picking {
 		return m.viewPicker()
 	}
+	if m.bulk.action != bulkNone {
+		return m.viewBulkConfirm()
+	}
 
 	if len(m.visible) == 0 {
 		return theme.HelpStyle.Render(" No sessions found")
@@ -59,7 +62,7 @@ func (m Model) View() string {
 		} else {
 			b.WriteString(" ")
 		}
-		b.WriteString(renderNode(node, selected, m.width-1))
+		b.WriteString(renderNode(node, selected, m.marked[node.SessionName], m.width-1))
 		b.WriteString("\n")
 
 		if i < end-1 {
@@ -100,13 +103,66 @@ func (m Model) StatusLine() string {
 	if m.renaming {
 		return " " + m.renameInput.View()
 	}
+	if m.bulkRenaming {
+		return " " + m.bulkRenameInput.View()
+	}
 	if m.searching {
 		return " " + m.searchInput.View()
 	}
 	if m.status != "" {
 		return theme.DiffRemoved.Render(" " + m.status)
 	}
-	return theme.HelpStyle.Render(" ⏎ switch  ␣ fold  J/K group  / search  n open  d kill  r rename  q quit")
+	if m.HasMarks() {
+		return theme.AttachedBadge.Render(fmt.Sprintf(" %d marked", len(m.marked))) +
+			theme.HelpStyle.Render("  d kill  r rename  A archive  D detach  u clear")
+	}
+	return theme.HelpStyle.Render(" ⏎ switch  ␣ fold  J/K group  x mark  / search  n open  d kill  r rename  q quit")
+}
+
+// viewBulkConfirm lists every session a bulk action will touch so a single
+// y/n covers the whole batch.
+func (m Model) viewBulkConfirm() string {
+	var b strings.Builder
+	sepW := m.width - 2
+	if sepW < 1 {
+		sepW = 1
+	}
+	avail := m.height - 2
+	if avail < 1 {
+		avail = 1
+	}
+	lines := 0
+	for i, item := range m.bulk.items {
+		if lines >= avail-1 && i < len(m.bulk.items)-1 {
+			b.WriteString(theme.HelpStyle.Render(fmt.Sprintf(" … %d more", len(m.bulk.items)-i)))
+			b.WriteString("\n")
+			lines++
+			break
+		}
+		b.WriteString(renderBulkItem(m.bulk.action, item))
+		b.WriteString("\n")
+		lines++
+	}
+	for lines < avail {
+		b.WriteString("\n")
+		lines++
+	}
+	b.WriteString(" " + theme.TreeConnector.Render(strings.Repeat("─", sepW)))
+	b.WriteString("\n")
+	prompt := fmt.Sprintf(" %s %d session(s)? y/n", m.bulk.action.verb(), m.bulk.actionable())
+	b.WriteString(theme.AttachedBadge.Render(prompt))
+	return b.String()
+}
+
+func renderBulkItem(action bulkAction, item bulkItem) string {
+	label := item.SessionName
+	if action == bulkRename {
+		label += " → " + item.NewName
+	}
+	if item.Skip != "" {
+		return theme.TreeMeta.Render(fmt.Sprintf("   %s  (skip: %s)", label, item.Skip))
+	}
+	return fmt.Sprintf(" %s %s", theme.DiffRemoved.Render(action.verb()), theme.TreeNodeNormal.Render(label))
 }
 
 func (m Model) viewPicker() string {
@@ -186,11 +242,11 @@ func (m Model) viewPicker() string {
 	return b.String()
 }
 
-func renderNode(node *TreeNode, selected bool, width int) string {
+func renderNode(node *TreeNode, selected, marked bool, width int) string {
 	if node.Kind == KindGroupHeader {
 		return renderGroupHeader(node, selected)
 	}
-	left := renderSessionLeft(node, selected)
+	left := renderSessionLeft(node, selected, marked)
 	right := renderDiffStats(node)
 	return joinSessionLine(left, right, width)
 }
@@ -207,9 +263,12 @@ func renderGroupHeader(node *TreeNode, selected bool) string {
 	return " " + theme.TreeGroupHeader.Render(name)
 }
 
-func renderSessionLeft(node *TreeNode, selected bool) string {
+func renderSessionLeft(node *TreeNode, selected, marked bool) string {
 	metaStr := theme.TreeMeta.Render(sessionMeta(node))
 	nameStr := styledSessionName(node.Name, selected)
+	if marked {
+		nameStr = theme.AttachedBadge.Render("✓ ") + nameStr
+	}
 	if node.Depth > 0 {
 		connector := theme.TreeConnector.Render("┊ ")
 		return fmt.Sprintf(" %s%s  %s", connector, nameStr, metaStr)
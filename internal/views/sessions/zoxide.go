@@ -10,9 +10,9 @@ import (
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/sahilm/fuzzy"
 
 	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/frecency"
 	"github.com/miltonparedes/kitmux/internal/tmux"
 	"github.com/miltonparedes/kitmux/internal/worktree"
 )
@@ -31,6 +31,7 @@ type ZoxideEntry struct {
 type zoxidePicker struct {
 	all      []ZoxideEntry
 	filtered []ZoxideEntry
+	scores   frecency.Scores // directory frecency, keyed by path
 	input    textinput.Model
 	cursor   int
 	scroll   int
@@ -47,14 +48,19 @@ func newZoxidePicker() zoxidePicker {
 
 type zoxideEntriesLoadedMsg struct {
 	entries []ZoxideEntry
+	scores  frecency.Scores
 }
 
+// loadZoxideEntries lists zoxide's directories with the ones picked most
+// often here first; zoxide's own order breaks ties.
 func loadZoxideEntries() tea.Msg {
 	entries, err := queryZoxide()
 	if err != nil {
 		return zoxideEntriesLoadedMsg{}
 	}
-	return zoxideEntriesLoadedMsg{entries: entries}
+	scores := loadScores(frecency.KindDir)
+	entries = frecency.Sort(entries, scores, func(e ZoxideEntry) string { return e.Path })
+	return zoxideEntriesLoadedMsg{entries: entries, scores: scores}
 }
 
 func queryZoxide() ([]ZoxideEntry, error) {
@@ -92,29 +98,27 @@ func queryZoxide() ([]ZoxideEntry, error) {
 	return entries, nil
 }
 
-func (p *zoxidePicker) setEntries(entries []ZoxideEntry) {
+func (p *zoxidePicker) setEntries(entries []ZoxideEntry, scores frecency.Scores) {
 	p.all = entries
+	p.scores = scores
 	p.filtered = entries
 	p.cursor = 0
 	p.scroll = 0
 }
 
 func (p *zoxidePicker) filter() {
-	query := p.input.Value()
-	if query == "" {
-		p.filtered = p.all
-	} else {
-		shorts := make([]string, len(p.all))
-		for i, e := range p.all {
-			shorts[i] = e.Short
-		}
-		matches := fuzzy.Find(query, shorts)
-		filtered := make([]ZoxideEntry, len(matches))
-		for i, m := range matches {
-			filtered[i] = p.all[m.Index]
-		}
-		p.filtered = filtered
+	shorts := make([]string, len(p.all))
+	paths := make([]string, len(p.all))
+	for i, e := range p.all {
+		shorts[i] = e.Short
+		paths[i] = e.Path
+	}
+	ranked := frecency.Rank(p.input.Value(), shorts, paths, p.scores)
+	filtered := make([]ZoxideEntry, len(ranked))
+	for i, idx := range ranked {
+		filtered[i] = p.all[idx]
 	}
+	p.filtered = filtered
 	p.cursor = 0
 	p.scroll = 0
 }
@@ -184,6 +188,7 @@ func isGitRepo(dir string) bool {
 // instead; on name collision a numeric suffix is appended.
 func openZoxideEntry(entry ZoxideEntry) tea.Cmd {
 	return func() tea.Msg {
+		recordUse(frecency.KindDir, entry.Path)
 		name, dir := resolveWorkspace(entry.Path)
 
 		sessions, err := tmux.ListSessions()
@@ -1,28 +1,30 @@
 package sidepanel
 
 import (
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/sahilm/fuzzy"
 
 	"github.com/miltonparedes/kitmux/internal/agents"
 	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/command"
 	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/discovery"
+	"github.com/miltonparedes/kitmux/internal/frecency"
 	"github.com/miltonparedes/kitmux/internal/tmux"
-	"github.com/miltonparedes/kitmux/internal/views/palette"
 	workspacesreg "github.com/miltonparedes/kitmux/internal/workspaces"
 	"github.com/miltonparedes/kitmux/internal/worktree"
 )
 
-var execCommand = func(name string, args ...string) (string, error) {
-	out, err := exec.Command(name, args...).Output()
-	return string(out), err
-}
+// dirSources returns the discovery sources listed after the current
+// project's own directories in the directory picker; swapped in tests.
+var dirSources = discovery.Configured
+
+// loadScores reads directory frecency; swapped in tests.
+var loadScores = frecency.Load
 
 type actionKind int
 
@@ -31,6 +33,7 @@ const (
 	actionExecuteCommand
 	actionRunPopup
 	actionLaunchAgent
+	actionOpenDiff
 )
 
 type action struct {
@@ -86,6 +89,7 @@ type Model struct {
 
 	dirs         []dirEntry
 	filteredDirs []dirEntry
+	dirScores    frecency.Scores
 	dirCursor    int
 	dirScroll    int
 	selectedDir  dirEntry
@@ -104,7 +108,8 @@ type projectStatsLoadedMsg struct {
 }
 
 type dirsLoadedMsg struct {
-	dirs []dirEntry
+	dirs   []dirEntry
+	scores frecency.Scores
 }
 
 type sidepanelRefreshMsg struct{}
@@ -120,6 +125,7 @@ func New() Model {
 			{title: "Launch Agent", description: "Choose directory and agent", kind: actionLaunchAgent},
 			commandAction("open_local_editor"),
 			popupAction("tool_lazygit", "lazygit"),
+			diffAction(),
 			popupAction("tool_lumen_diff", "lumen diff"),
 			viewAction("worktrees", "view_worktrees"),
 			viewAction("sessions", "view_sessions"),
@@ -151,6 +157,15 @@ func (m Model) Reload() tea.Cmd {
 	}
 }
 
+// WorktreeChanged reloads the project summary when the workspace watcher
+// saw changes in the worktree it shows.
+func (m Model) WorktreeChanged(worktreePath string) tea.Cmd {
+	if m.project.Path == "" || m.project.Path != worktreePath {
+		return nil
+	}
+	return m.LoadProject()
+}
+
 func (m Model) LoadProject() tea.Cmd {
 	return func() tea.Msg {
 		return projectStatsLoadedMsg{stats: loadProjectStats()}
@@ -167,11 +182,16 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		return m, nil
 	case dirsLoadedMsg:
 		m.dirs = msg.dirs
+		m.dirScores = msg.scores
 		m.refilterDirs()
 		return m, nil
 	case messages.SidepanelCommandDoneMsg:
 		return m, tea.Batch(m.Reload(), m.LoadProject())
 	case sidepanelRefreshMsg:
+		if tmux.ControlActive() {
+			// Pane changes arrive as tmux notifications; only poll git.
+			return m, tea.Batch(m.LoadProject(), refreshSidepanel())
+		}
 		return m, tea.Batch(m.Reload(), m.LoadProject(), refreshSidepanel())
 	case tea.MouseMsg:
 		return m.handleMouse(msg)
@@ -292,7 +312,9 @@ func (m Model) handleDirPickerKey(msg tea.KeyMsg) (Model, tea.Cmd) {
 		}
 		m.selectedDir = m.filteredDirs[m.dirCursor]
 		m.mode = modeAgentPicker
-		m.agentCursor = 0
+		repo, _ := config.LoadRepo(m.selectedDir.Path)
+		m.agentCursor = agents.Index(m.agentList, repo.DefaultAgent())
+		m.agentModeIndex = agents.ModeIndexes(m.agentList, repo.AgentMode)
 		return m, nil
 	case "down", "ctrl+j":
 		if m.dirCursor < len(m.filteredDirs)-1 {
@@ -357,6 +379,7 @@ func (m Model) selectedCmd() tea.Cmd {
 	if !ok {
 		return nil
 	}
+	dir := m.project.Path
 	return func() tea.Msg {
 		switch a.kind {
 		case actionSwitchView:
@@ -365,6 +388,8 @@ func (m Model) selectedCmd() tea.Cmd {
 			return messages.ExecuteCommandMsg{ID: a.value}
 		case actionRunPopup:
 			return messages.RunPopupMsg{Command: a.value, Width: "100%", Height: "100%", Stay: true}
+		case actionOpenDiff:
+			return messages.OpenDiffMsg{Dir: dir}
 		default:
 			return nil
 		}
@@ -412,7 +437,8 @@ func (m *Model) clampCursor() {
 
 func (m Model) startAgentLaunch() Model {
 	m.mode = modeDirPicker
-	m.dirs = buildDirEntries(m.project.Path)
+	m.dirScores = loadScores(frecency.KindDir)
+	m.dirs = buildDirEntries(m.project.Path, m.dirScores)
 	m.filteredDirs = m.dirs
 	m.dirCursor = 0
 	m.dirScroll = 0
@@ -428,7 +454,8 @@ func (m Model) StartAgentLaunchForTest() Model {
 func (m Model) loadDirs() tea.Cmd {
 	current := m.project.Path
 	return func() tea.Msg {
-		return dirsLoadedMsg{dirs: buildDirEntries(current)}
+		scores := loadScores(frecency.KindDir)
+		return dirsLoadedMsg{dirs: buildDirEntries(current, scores), scores: scores}
 	}
 }
 
@@ -438,13 +465,15 @@ func (m *Model) refilterDirs() {
 		m.filteredDirs = m.dirs
 	} else {
 		items := make([]string, len(m.dirs))
+		paths := make([]string, len(m.dirs))
 		for i, d := range m.dirs {
 			items[i] = d.Name + " " + d.Path
+			paths[i] = d.Path
 		}
-		matches := fuzzy.Find(query, items)
-		m.filteredDirs = make([]dirEntry, len(matches))
-		for i, match := range matches {
-			m.filteredDirs[i] = m.dirs[match.Index]
+		ranked := frecency.Rank(query, items, paths, m.dirScores)
+		m.filteredDirs = make([]dirEntry, len(ranked))
+		for i, idx := range ranked {
+			m.filteredDirs[i] = m.dirs[idx]
 		}
 	}
 	m.dirCursor = 0
@@ -475,7 +504,9 @@ func (m *Model) ensureDirVisible() {
 	}
 }
 
-func buildDirEntries(current string) []dirEntry {
+// buildDirEntries lists the directories offered for an agent launch: the
+// current project first, then the rest ordered by frecency.
+func buildDirEntries(current string, scores frecency.Scores) []dirEntry {
 	seen := make(map[string]bool)
 	var dirs []dirEntry
 	add := func(path string) {
@@ -487,12 +518,17 @@ func buildDirEntries(current string) []dirEntry {
 		dirs = append(dirs, dirEntry{Name: filepath.Base(path), Path: path})
 	}
 	add(current)
+	pinned := len(dirs)
 	addSessionDirs(add, current)
 	addWorktreeDirs(add, current)
 	for _, ws := range workspacesreg.LoadRegistry() {
 		add(ws.Path)
 	}
-	addZoxideDirs(add)
+	for _, d := range discovery.Collect(dirSources()) {
+		add(d.Path)
+	}
+	rest := frecency.Sort(dirs[pinned:], scores, func(d dirEntry) string { return d.Path })
+	copy(dirs[pinned:], rest)
 	return dirs
 }
 
@@ -521,24 +557,6 @@ func addWorktreeDirs(add func(string), current string) {
 	}
 }
 
-func addZoxideDirs(add func(string)) {
-	out, err := execCommand("zoxide", "query", "-ls")
-	if err != nil {
-		return
-	}
-	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		idx := strings.IndexByte(line, ' ')
-		if idx < 0 {
-			continue
-		}
-		add(strings.TrimSpace(line[idx+1:]))
-	}
-}
-
 func filterActivityPanes(panes []tmux.Pane) []tmux.Pane {
 	filtered := make([]tmux.Pane, 0, len(panes))
 	for _, pane := range panes {
@@ -580,7 +598,7 @@ func refreshSidepanel() tea.Cmd {
 }
 
 func commandAction(id string) action {
-	cmd, ok := palette.FindCommand(id)
+	cmd, ok := command.FindCommand(id)
 	if !ok {
 		return action{title: id, kind: actionExecuteCommand, value: id}
 	}
@@ -592,21 +610,30 @@ func commandAction(id string) action {
 	}
 }
 
-func popupAction(id, command string) action {
-	cmd, ok := palette.FindCommand(id)
+func popupAction(id, shell string) action {
+	cmd, ok := command.FindCommand(id)
 	if !ok {
-		return action{title: command, kind: actionRunPopup, value: command}
+		return action{title: shell, kind: actionRunPopup, value: shell}
 	}
 	return action{
 		title:       cmd.Title,
 		description: cmd.Description,
 		kind:        actionRunPopup,
-		value:       command,
+		value:       shell,
+	}
+}
+
+func diffAction() action {
+	a := action{title: "Diff", kind: actionOpenDiff}
+	if cmd, ok := command.FindCommand("view_diff"); ok {
+		a.title = strings.TrimSuffix(cmd.Title, " View")
+		a.description = cmd.Description
 	}
+	return a
 }
 
 func viewAction(view, id string) action {
-	cmd, ok := palette.FindCommand(id)
+	cmd, ok := command.FindCommand(id)
 	if !ok {
 		return action{title: view, kind: actionSwitchView, value: view}
 	}
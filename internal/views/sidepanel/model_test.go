@@ -8,7 +8,10 @@ import (
 
 	"github.com/miltonparedes/kitmux/internal/app/messages"
 	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/discovery"
+	"github.com/miltonparedes/kitmux/internal/frecency"
 	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
 )
 
 func TestModelNavigationAndPopupAction(t *testing.T) {
@@ -271,7 +274,7 @@ func TestLaunchAgentFlowSelectsAgentAfterDirectory(t *testing.T) {
 
 func TestDirectoryPickerIncludesZoxideResults(t *testing.T) {
 	stubDirSources(t)
-	dirs := buildDirEntries("/tmp/current")
+	dirs := buildDirEntries("/tmp/current", nil)
 	found := false
 	for _, dir := range dirs {
 		if dir.Path == "/tmp/zoxide-repo" {
@@ -283,20 +286,52 @@ func TestDirectoryPickerIncludesZoxideResults(t *testing.T) {
 	}
 }
 
+func TestDirectoryPickerRanksFrequentDirsAfterCurrent(t *testing.T) {
+	stubDirSources(t)
+	dirs := buildDirEntries("/tmp/current", frecency.Scores{"/tmp/zoxide-repo": 3})
+	if len(dirs) < 2 || dirs[0].Path != "/tmp/current" || dirs[1].Path != "/tmp/zoxide-repo" {
+		t.Fatalf("expected current then most used dir, got %+v", dirs)
+	}
+}
+
 func stubDirSources(t *testing.T) {
 	t.Helper()
-	original := execCommand
-	execCommand = func(name string, args ...string) (string, error) {
-		if name == "zoxide" {
-			return "10 /tmp/zoxide-repo\n", nil
-		}
-		return original(name, args...)
+	original := dirSources
+	dirSources = func() []discovery.Source {
+		return []discovery.Source{{Name: discovery.SourceZoxide, List: func() ([]discovery.Dir, error) {
+			return []discovery.Dir{{Path: "/tmp/zoxide-repo", Score: 10}}, nil
+		}}}
 	}
 	t.Cleanup(func() {
-		execCommand = original
+		dirSources = original
 	})
 }
 
 func key(s string) tea.KeyMsg {
 	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
 }
+
+func TestReloadReadsAgentActivityFromServer(t *testing.T) {
+	srv := tmuxtest.Install(t)
+	srv.AddSession("repo", "/tmp/repo")
+	agentPane, err := srv.SplitWindowInDir("repo:0.0", "/tmp/repo", "codex")
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	if err := srv.SetPaneOption(agentPane, "@kitmux_agent_state", "input"); err != nil {
+		t.Fatalf("set state: %v", err)
+	}
+
+	m := New()
+	m, _ = m.Update(m.Reload()())
+
+	if len(m.activities) != 1 {
+		t.Fatalf("expected one agent activity, got %d", len(m.activities))
+	}
+	if m.activities[0].Pane.ID != agentPane {
+		t.Fatalf("expected pane %s, got %s", agentPane, m.activities[0].Pane.ID)
+	}
+	if !m.activities[0].NeedsInput {
+		t.Fatal("expected needs input activity")
+	}
+}
@@ -85,7 +85,10 @@ type threadStatusMsg struct {
 	text string
 }
 
-const refreshEveryFrames = 6
+const (
+	refreshEveryFrames   = 6
+	controlRefreshFactor = 10
+)
 
 const (
 	agentStateWorking    = "working"
@@ -171,7 +174,7 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		return m, nil
 	case tickMsg:
 		m.spinnerFrame++
-		if m.spinnerFrame%refreshEveryFrames == 0 {
+		if m.spinnerFrame%refreshFrames() == 0 {
 			return m, tea.Batch(tickCmd(), m.loadCmd())
 		}
 		return m, tickCmd()
@@ -413,6 +416,11 @@ func (m Model) loadCmd() tea.Cmd {
 	return loadCmd(m.loadOptions())
 }
 
+// Reload re-reads threads from tmux.
+func (m Model) Reload() tea.Cmd {
+	return m.loadCmd()
+}
+
 func loadCmd(opts ...loadOptions) tea.Cmd {
 	return func() tea.Msg {
 		return loadRows(opts...)
@@ -425,11 +433,17 @@ func (m Model) syncSupportAndLoadCmd() tea.Cmd {
 
 func syncSupportAndLoadCmd(opts ...loadOptions) tea.Cmd {
 	return func() tea.Msg {
-		_, _ = agentthread.InstallAllSupport(agentthread.DefaultOps())
+		_, _ = agentthread.InstallAllSupport(tmux.Default())
 		return loadRows(opts...)
 	}
 }
 
+// LoadAll reads every agent thread regardless of directory, for lists
+// outside this view such as global search.
+func LoadAll() []Row {
+	return loadRows(loadOptions{showAll: true}).rows
+}
+
 func loadRows(opts ...loadOptions) loadedMsg {
 	sessions, _ := listThreadSessions()
 	panes, _ := listThreadPanes()
@@ -869,12 +883,26 @@ func agentStatePriority(state string) int {
 	}
 }
 
+// refreshFrames is the polling period in spinner frames. With a control-mode
+// connection tmux pushes changes, so polling only backs it up.
+func refreshFrames() int {
+	if tmux.ControlActive() {
+		return refreshEveryFrames * controlRefreshFactor
+	}
+	return refreshEveryFrames
+}
+
 func tickCmd() tea.Cmd {
 	return tea.Tick(140*time.Millisecond, func(time.Time) tea.Msg {
 		return tickMsg{}
 	})
 }
 
+// OpenCmd focuses the thread: its session when headless, its pane otherwise.
+func OpenCmd(row Row) tea.Cmd {
+	return openRowCmd(row)
+}
+
 func openRowCmd(row Row) tea.Cmd {
 	if row.Kind == RowHeadless {
 		return func() tea.Msg {
@@ -933,7 +961,7 @@ func relaunchHeadless(row Row) error {
 		TargetPane:   target,
 		AgentID:      row.AgentID,
 		InitialTitle: rowTitle(row),
-	}, agentthread.DefaultOps())
+	}, tmux.Default())
 	return nil
 }
 
@@ -1043,7 +1071,7 @@ func newHeadlessCmd(agent agents.Agent, launchDir string, opts ...loadOptions) t
 			return loadRows(opts...)
 		}
 		dir := resolveLaunchDir(launchDir)
-		resolved, err := createThread(agentthread.Spec{AgentID: agent.ID, Dir: dir}, agentthread.DefaultOps())
+		resolved, err := createThread(agentthread.Spec{AgentID: agent.ID, Dir: dir}, tmux.Default())
 		if err != nil {
 			_ = tmux.DisplayMessage(fmt.Sprintf("create thread: %v", err))
 			return loadRows(opts...)
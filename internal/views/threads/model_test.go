@@ -18,6 +18,7 @@ import (
 	"github.com/miltonparedes/kitmux/internal/agentthread"
 	"github.com/miltonparedes/kitmux/internal/app/messages"
 	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
 )
 
 const (
@@ -956,7 +957,7 @@ func TestRelaunchHeadlessUsesPersistedDroidSessionID(t *testing.T) {
 		return nil
 	}
 	var supportSpec agentthread.SupportSpec
-	applyThreadSupport = func(spec agentthread.SupportSpec, _ agentthread.Ops) error {
+	applyThreadSupport = func(spec agentthread.SupportSpec, _ tmux.Client) error {
 		supportSpec = spec
 		return nil
 	}
@@ -1031,7 +1032,7 @@ func TestRelaunchHeadlessCanonicalizesDroidChildSessionID(t *testing.T) {
 		respawnCommand = command
 		return nil
 	}
-	applyThreadSupport = func(agentthread.SupportSpec, agentthread.Ops) error {
+	applyThreadSupport = func(agentthread.SupportSpec, tmux.Client) error {
 		return nil
 	}
 
@@ -1199,7 +1200,7 @@ func TestNewHeadlessUsesLaunchDir(t *testing.T) {
 
 	var gotSpec agentthread.Spec
 	var installedAgent string
-	createThread = func(spec agentthread.Spec, _ agentthread.Ops) (agentthread.Resolved, error) {
+	createThread = func(spec agentthread.Spec, _ tmux.Client) (agentthread.Resolved, error) {
 		gotSpec = spec
 		return agentthread.Resolved{SessionName: "droid-current"}, nil
 	}
@@ -1233,7 +1234,7 @@ func TestNewHeadlessReportsHookInstallFailure(t *testing.T) {
 		installThreadHooks = originalInstallHooks
 	})
 
-	createThread = func(agentthread.Spec, agentthread.Ops) (agentthread.Resolved, error) {
+	createThread = func(agentthread.Spec, tmux.Client) (agentthread.Resolved, error) {
 		t.Fatal("createThread should not run when hook install fails")
 		return agentthread.Resolved{}, nil
 	}
@@ -1251,3 +1252,41 @@ func TestNewHeadlessReportsHookInstallFailure(t *testing.T) {
 		t.Fatalf("msg = %#v, want loadedMsg", msg)
 	}
 }
+
+func TestHeadlessThreadLifecycleAgainstServer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	originalInstallHooks := installThreadHooks
+	t.Cleanup(func() {
+		installThreadHooks = originalInstallHooks
+	})
+	installThreadHooks = func(string) error { return nil }
+
+	srv := tmuxtest.Install(t)
+	agent, ok := agents.Find(droidAgentID)
+	if !ok {
+		t.Fatal("missing droid agent")
+	}
+
+	msg := newHeadlessCmd(agent, "/repo/app")()
+	switched, ok := msg.(messages.SwitchSessionMsg)
+	if !ok {
+		t.Fatalf("msg = %#v, want SwitchSessionMsg", msg)
+	}
+	if got := srv.SessionOption(switched.Name, "@kitmux_thread"); got != "1" {
+		t.Fatalf("@kitmux_thread = %q, want 1", got)
+	}
+
+	loaded := loadRows(loadOptions{showAll: true})
+	if len(loaded.rows) != 1 || loaded.rows[0].SessionName != switched.Name {
+		t.Fatalf("rows = %+v, want the new thread", loaded.rows)
+	}
+
+	msg = killHeadlessCmd(switched.Name, loadOptions{showAll: true})()
+	reloaded, ok := msg.(loadedMsg)
+	if !ok {
+		t.Fatalf("message = %T, want loadedMsg", msg)
+	}
+	if len(reloaded.rows) != 0 || len(srv.SessionNames()) != 0 {
+		t.Fatalf("expected thread gone, rows %+v sessions %v", reloaded.rows, srv.SessionNames())
+	}
+}
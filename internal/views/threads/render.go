@@ -326,6 +326,11 @@ func stateColor(state string) lipgloss.Color {
 
 // rowTitle returns the thread title without a leading status glyph, since the
 // state icon is rendered separately in its own column.
+// Label is the agent symbol and title a thread is listed under.
+func Label(row Row) string {
+	return strings.TrimSpace(rowSymbol(row) + " " + rowTitle(row))
+}
+
 func rowTitle(row Row) string {
 	title := row.Title
 	if title == "" {
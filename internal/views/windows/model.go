@@ -58,6 +58,21 @@ func (m *Model) LoadSession(name string) tea.Cmd {
 
 func (m Model) Init() tea.Cmd { return nil }
 
+// Reload re-reads the windows of the loaded session.
+func (m Model) Reload() tea.Cmd {
+	name := m.sessionName
+	if name == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		wins, err := tmux.ListWindows(name)
+		if err != nil {
+			return windowsLoadedMsg{session: name}
+		}
+		return windowsLoadedMsg{session: name, windows: wins}
+	}
+}
+
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case windowsLoadedMsg:
@@ -0,0 +1,277 @@
+package workspaces
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/theme"
+	"github.com/miltonparedes/kitmux/internal/worktreeupdate"
+)
+
+var worktreeUpdateOps = worktreeupdate.DefaultOps
+
+// updateState backs modeBulkUpdate: the linked worktrees of one workspace
+// with how far behind the base branch they are, the batch the user picks
+// to rebase or merge, and the outcome of the last run.
+type updateState struct {
+	ws         workspaceEntry
+	base       string
+	method     string
+	candidates []worktreeupdate.Candidate
+	selected   []bool
+	outcomes   map[string]worktreeupdate.Outcome // by worktree path
+	cursor     int
+	scroll     int
+	loading    bool
+	confirming bool
+	running    bool
+}
+
+type (
+	updatePlannedMsg struct {
+		workspace  string
+		base       string
+		candidates []worktreeupdate.Candidate
+		err        error
+	}
+	updateDoneMsg struct {
+		workspace string
+		outcomes  []worktreeupdate.Outcome
+	}
+)
+
+// startBulkUpdate opens the bulk update for the selected workspace.
+func (m Model) startBulkUpdate() (Model, tea.Cmd) {
+	if len(m.workspaces) == 0 {
+		return m, nil
+	}
+	m.mode = modeBulkUpdate
+	m.update = updateState{ws: m.workspaces[m.wsCursor], method: worktreeupdate.MethodRebase, loading: true}
+	return m, planUpdateCmd(m.update.ws.Path)
+}
+
+func planUpdateCmd(workspace string) tea.Cmd {
+	return func() tea.Msg {
+		base, candidates, err := worktreeupdate.Plan(workspace, worktreeUpdateOps())
+		return updatePlannedMsg{workspace: workspace, base: base, candidates: candidates, err: err}
+	}
+}
+
+func (m Model) handleUpdatePlanned(msg updatePlannedMsg) (tea.Model, tea.Cmd) {
+	if m.mode != modeBulkUpdate || msg.workspace != m.update.ws.Path {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.mode = modeNormal
+		return m, m.pushToast("update failed: "+msg.err.Error(), toastError)
+	}
+	u := &m.update
+	u.loading = false
+	u.base = msg.base
+	u.candidates = msg.candidates
+	u.selected = make([]bool, len(msg.candidates))
+	for i, c := range msg.candidates {
+		u.selected[i] = c.Updatable()
+	}
+	u.outcomes = nil
+	u.cursor = 0
+	u.scroll = 0
+	return m, nil
+}
+
+func (m Model) handleUpdateDone(msg updateDoneMsg) (tea.Model, tea.Cmd) {
+	if m.mode != modeBulkUpdate || msg.workspace != m.update.ws.Path {
+		return m, loadDataCmd(m.stats_svc)
+	}
+	u := &m.update
+	u.running = false
+	u.outcomes = make(map[string]worktreeupdate.Outcome, len(msg.outcomes))
+	level := toastInfo
+	for _, o := range msg.outcomes {
+		u.outcomes[o.Worktree.WorktreePath] = o
+		if o.Status == worktreeupdate.StatusConflict || o.Status == worktreeupdate.StatusFailed {
+			level = toastError
+		}
+	}
+	return m, tea.Batch(m.pushToast(worktreeupdate.Summary(msg.outcomes), level), loadDataCmd(m.stats_svc))
+}
+
+func (m Model) handleBulkUpdate(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	u := &m.update
+	if u.confirming {
+		if msg.String() != "y" && msg.String() != "Y" {
+			u.confirming = false
+			return m, nil
+		}
+		batch := m.updateBatch()
+		workspace, base, method := u.ws.Path, u.base, u.method
+		u.confirming = false
+		u.running = true
+		return m, func() tea.Msg {
+			return updateDoneMsg{workspace: workspace, outcomes: worktreeupdate.Apply(workspace, base, method, batch, worktreeUpdateOps())}
+		}
+	}
+	if u.loading || u.running {
+		if (msg.String() == "esc" || msg.String() == "q") && u.loading {
+			m.mode = modeNormal
+		}
+		return m, nil
+	}
+	switch msg.String() {
+	case "j", "down":
+		if u.cursor < len(u.candidates)-1 {
+			u.cursor++
+		}
+		m.ensureUpdateVisible()
+	case "k", "up":
+		if u.cursor > 0 {
+			u.cursor--
+		}
+		m.ensureUpdateVisible()
+	case " ", "space":
+		if u.outcomes == nil && u.cursor < len(u.candidates) && u.candidates[u.cursor].Updatable() {
+			u.selected[u.cursor] = !u.selected[u.cursor]
+		}
+	case "m":
+		if u.method == worktreeupdate.MethodRebase {
+			u.method = worktreeupdate.MethodMerge
+		} else {
+			u.method = worktreeupdate.MethodRebase
+		}
+	case "r":
+		u.loading = true
+		return m, planUpdateCmd(u.ws.Path)
+	case keyEnter:
+		if u.outcomes != nil {
+			return m, nil
+		}
+		if len(m.updateBatch()) == 0 {
+			return m, m.pushToast("nothing selected", toastInfo)
+		}
+		u.confirming = true
+	case "esc", "q":
+		m.mode = modeNormal
+		m.update = updateState{}
+	}
+	return m, nil
+}
+
+// updateBatch returns the selected candidates in list order.
+func (m Model) updateBatch() []worktreeupdate.Candidate {
+	var batch []worktreeupdate.Candidate
+	for i, c := range m.update.candidates {
+		if m.update.selected[i] && c.Updatable() {
+			batch = append(batch, c)
+		}
+	}
+	return batch
+}
+
+func (m *Model) ensureUpdateVisible() {
+	visible := m.cleanupVisibleRows()
+	u := &m.update
+	if u.cursor < u.scroll {
+		u.scroll = u.cursor
+	}
+	if u.cursor >= u.scroll+visible {
+		u.scroll = u.cursor - visible + 1
+	}
+}
+
+func (m Model) viewBulkUpdate() string {
+	var b strings.Builder
+	innerW := m.innerWidth()
+	u := m.update
+
+	title := "Update " + u.ws.Name
+	if u.base != "" {
+		title += fmt.Sprintf(" (%s onto %s)", u.method, u.base)
+	}
+	b.WriteString(" " + theme.TreeGroupHeader.Render(title))
+	b.WriteString("\n")
+	mainSep := " " + theme.TreeConnector.Render(strings.Repeat("─", innerW))
+	b.WriteString(mainSep)
+	b.WriteString("\n")
+
+	avail := m.cleanupVisibleRows()
+	used := 0
+	switch {
+	case u.loading:
+		b.WriteString(" " + theme.HelpStyle.Render("checking worktrees…"))
+		b.WriteString("\n")
+		used++
+	case len(u.candidates) == 0:
+		b.WriteString(" " + theme.HelpStyle.Render("no linked worktrees"))
+		b.WriteString("\n")
+		used++
+	}
+	if !u.loading {
+		for i := u.scroll; i < len(u.candidates) && used < avail; i++ {
+			c := u.candidates[i]
+			o, ran := u.outcomes[c.Worktree.WorktreePath]
+			b.WriteString(renderUpdateRow(c, u.base, u.selected[i], i == u.cursor, o, ran))
+			b.WriteString("\n")
+			used++
+		}
+	}
+	padTo(&b, used, avail)
+
+	b.WriteString(mainSep)
+	b.WriteString("\n")
+	b.WriteString(m.updateFooter())
+	return b.String()
+}
+
+func renderUpdateRow(c worktreeupdate.Candidate, base string, selected, cursor bool, o worktreeupdate.Outcome, ran bool) string {
+	box := "[ ]"
+	switch {
+	case !c.Updatable():
+		box = " - "
+	case selected:
+		box = "[x]"
+	}
+	meta := fmt.Sprintf("%d behind %s", c.Behind, base)
+	if c.Skip != "" {
+		meta += "  kept: " + c.Skip
+	}
+	if ran {
+		meta += "  " + renderOutcome(o)
+	}
+	if cursor {
+		return fmt.Sprintf(" %s %s %s  %s", theme.PaletteItemSelected.Render("▸"), box,
+			theme.TreeNodeSelected.Render(c.Worktree.Branch), theme.TreeMeta.Render(meta))
+	}
+	return fmt.Sprintf("   %s %s  %s", box, theme.TreeNodeNormal.Render(c.Worktree.Branch), theme.TreeMeta.Render(meta))
+}
+
+func renderOutcome(o worktreeupdate.Outcome) string {
+	switch o.Status {
+	case worktreeupdate.StatusUpdated:
+		return theme.CleanBadge.Render("✓ updated")
+	case worktreeupdate.StatusConflict:
+		return theme.DirtyBadge.Render("⚠ conflict in "+strings.Join(o.Files, ", ")) + theme.TreeMeta.Render(" ("+o.Detail+")")
+	case worktreeupdate.StatusFailed:
+		return theme.DirtyBadge.Render("✗ " + o.Detail)
+	case worktreeupdate.StatusSkipped:
+		return theme.TreeMeta.Render("skipped: " + o.Detail)
+	}
+	return theme.TreeMeta.Render(o.Status)
+}
+
+func (m Model) updateFooter() string {
+	if m.toast != "" {
+		return m.renderToast()
+	}
+	u := m.update
+	switch {
+	case u.confirming:
+		return theme.AttachedBadge.Render(fmt.Sprintf(" %s %d worktree(s) onto %s? y/n", u.method, len(m.updateBatch()), u.base))
+	case u.running:
+		return theme.HelpStyle.Render(" updating…")
+	case u.outcomes != nil:
+		return theme.HelpStyle.Render(" r check again  esc back")
+	}
+	return theme.HelpStyle.Render(" space toggle  m rebase/merge  ⏎ update selected  esc back")
+}
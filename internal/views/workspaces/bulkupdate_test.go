@@ -0,0 +1,75 @@
+package workspaces
+
+import (
+	"strings"
+	"testing"
+
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+	"github.com/miltonparedes/kitmux/internal/worktreeupdate"
+)
+
+func stubUpdateOps(t *testing.T) *[]string {
+	t.Helper()
+	original := worktreeUpdateOps
+	t.Cleanup(func() { worktreeUpdateOps = original })
+
+	var updated []string
+	worktreeUpdateOps = func() worktreeupdate.Ops {
+		return worktreeupdate.Ops{
+			LoadStats: func(string) ([]wsdata.WorktreeStat, error) {
+				return []wsdata.WorktreeStat{
+					{WorktreePath: "/home/user/kitmux", Branch: "main", IsMain: true},
+					{WorktreePath: "/home/user/kitmux-feature", Branch: "feature"},
+					{WorktreePath: "/home/user/kitmux-clash", Branch: "clash"},
+					{WorktreePath: "/home/user/kitmux-experiment", Branch: "experiment", Modified: true},
+				}, nil
+			},
+			BaseBranch: func(string) string { return "" },
+			Behind:     func(string, string) (int, error) { return 2, nil },
+			Dirty:      func(string) (bool, error) { return false, nil },
+			Update: func(path, _, method string) error {
+				updated = append(updated, method+" "+path)
+				if strings.HasSuffix(path, "clash") {
+					return &worktreeupdate.ConflictError{Files: []string{"go.mod"}}
+				}
+				return nil
+			},
+			Invalidate: func(string) {},
+		}
+	}
+	return &updated
+}
+
+func TestBulkUpdateStopsAtConflictAndReports(t *testing.T) {
+	updated := stubUpdateOps(t)
+	m := newSeededModel()
+
+	next, cmd := m.Update(keyMsg("U"))
+	m = runCmd(t, next.(Model), cmd)
+	if m.mode != modeBulkUpdate || len(m.updateBatch()) != 2 {
+		t.Fatalf("mode = %v, batch = %d", m.mode, len(m.updateBatch()))
+	}
+	if view := m.View(); !strings.Contains(view, "[x] feature") || !strings.Contains(view, "kept: uncommitted changes") {
+		t.Fatalf("unexpected view:\n%s", view)
+	}
+
+	next, _ = m.Update(keyMsg("m"))
+	next, _ = next.(Model).Update(keyMsg("enter"))
+	m = next.(Model)
+	if !strings.Contains(m.View(), "merge 2 worktree(s) onto main? y/n") {
+		t.Fatalf("missing confirmation:\n%s", m.View())
+	}
+	next, cmd = m.Update(keyMsg("y"))
+	m = runCmd(t, next.(Model), cmd)
+	if len(*updated) != 2 || !strings.HasPrefix((*updated)[0], "merge ") {
+		t.Fatalf("updated = %v", *updated)
+	}
+	if m.mode != modeBulkUpdate || !strings.Contains(m.toast, "1 conflict") {
+		t.Fatalf("mode = %v, toast = %q", m.mode, m.toast)
+	}
+	m.toast = ""
+	view := m.View()
+	if !strings.Contains(view, "✓ updated") || !strings.Contains(view, "conflict in go.mod") {
+		t.Fatalf("missing per-worktree results:\n%s", view)
+	}
+}
@@ -0,0 +1,268 @@
+package workspaces
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/sessionprune"
+	"github.com/miltonparedes/kitmux/internal/theme"
+	"github.com/miltonparedes/kitmux/internal/worktreeprune"
+)
+
+var worktreeCleanupOps = worktreeprune.DefaultOps
+
+// cleanupState backs modeCleanup: every linked worktree of one workspace
+// with its classification, and the batch of removals the user accepts.
+type cleanupState struct {
+	ws         workspaceEntry
+	candidates []worktreeprune.Candidate
+	selected   []bool
+	cursor     int
+	scroll     int
+	force      bool // dirty worktrees may be selected
+	loading    bool
+	confirming bool
+}
+
+type (
+	cleanupPlannedMsg struct {
+		workspace  string
+		force      bool
+		candidates []worktreeprune.Candidate
+		err        error
+	}
+	cleanupDoneMsg struct {
+		res worktreeprune.Result
+	}
+)
+
+// startCleanup opens cleanup mode for the selected workspace.
+func (m Model) startCleanup() (Model, tea.Cmd) {
+	if len(m.workspaces) == 0 {
+		return m, nil
+	}
+	m.mode = modeCleanup
+	m.cleanup = cleanupState{ws: m.workspaces[m.wsCursor], loading: true}
+	return m, planCleanupCmd(m.cleanup.ws.Path, false)
+}
+
+func planCleanupCmd(workspace string, force bool) tea.Cmd {
+	return func() tea.Msg {
+		staleAfter, err := sessionprune.ParseIdle(config.WorktreeStale())
+		if err != nil {
+			staleAfter = 0
+		}
+		opts := worktreeprune.Options{StaleAfter: staleAfter, Force: force}
+		candidates, err := worktreeprune.Plan(workspace, opts, worktreeCleanupOps())
+		return cleanupPlannedMsg{workspace: workspace, force: force, candidates: candidates, err: err}
+	}
+}
+
+func (m Model) handleCleanupPlanned(msg cleanupPlannedMsg) (tea.Model, tea.Cmd) {
+	if m.mode != modeCleanup || msg.workspace != m.cleanup.ws.Path || msg.force != m.cleanup.force {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.mode = modeNormal
+		return m, m.pushToast("cleanup failed: "+msg.err.Error(), toastError)
+	}
+	m.cleanup.loading = false
+	m.cleanup.candidates = msg.candidates
+	m.cleanup.selected = make([]bool, len(msg.candidates))
+	for i, c := range msg.candidates {
+		m.cleanup.selected[i] = c.Removable()
+	}
+	m.cleanup.cursor = 0
+	m.cleanup.scroll = 0
+	return m, nil
+}
+
+func (m Model) handleCleanupDone(msg cleanupDoneMsg) (tea.Model, tea.Cmd) {
+	m.mode = modeNormal
+	m.cleanup = cleanupState{}
+	text := fmt.Sprintf("removed %d worktree(s), killed %d session(s)", len(msg.res.Removed), len(msg.res.Killed))
+	level := toastInfo
+	if len(msg.res.Failed) > 0 {
+		text += "; failed: " + strings.Join(msg.res.Failed, ", ")
+		level = toastError
+	}
+	return m, tea.Batch(m.pushToast(text, level), loadDataCmd(m.stats_svc))
+}
+
+func (m Model) handleCleanup(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := &m.cleanup
+	if c.confirming {
+		switch msg.String() {
+		case "y", "Y":
+			batch := m.cleanupBatch()
+			workspace := c.ws.Path
+			c.confirming = false
+			c.loading = true
+			return m, func() tea.Msg {
+				return cleanupDoneMsg{res: worktreeprune.Apply(workspace, batch, worktreeCleanupOps())}
+			}
+		default:
+			c.confirming = false
+			return m, nil
+		}
+	}
+	if c.loading {
+		if msg.String() == "esc" || msg.String() == "q" {
+			m.mode = modeNormal
+		}
+		return m, nil
+	}
+	switch msg.String() {
+	case "j", "down":
+		if c.cursor < len(c.candidates)-1 {
+			c.cursor++
+		}
+		m.ensureCleanupVisible()
+	case "k", "up":
+		if c.cursor > 0 {
+			c.cursor--
+		}
+		m.ensureCleanupVisible()
+	case " ", "space":
+		if c.cursor < len(c.candidates) && c.candidates[c.cursor].Skip == "" {
+			c.selected[c.cursor] = !c.selected[c.cursor]
+		}
+	case "F":
+		// Replan so dirty worktrees lose (or regain) their protection.
+		c.force = !c.force
+		c.loading = true
+		return m, planCleanupCmd(c.ws.Path, c.force)
+	case keyEnter:
+		if len(m.cleanupBatch()) == 0 {
+			return m, m.pushToast("nothing selected", toastInfo)
+		}
+		c.confirming = true
+	case "esc", "q":
+		m.mode = modeNormal
+		m.cleanup = cleanupState{}
+	}
+	return m, nil
+}
+
+// cleanupBatch returns the selected candidates.
+func (m Model) cleanupBatch() []worktreeprune.Candidate {
+	var batch []worktreeprune.Candidate
+	for i, c := range m.cleanup.candidates {
+		if m.cleanup.selected[i] && c.Skip == "" {
+			batch = append(batch, c)
+		}
+	}
+	return batch
+}
+
+func (m *Model) ensureCleanupVisible() {
+	visible := m.cleanupVisibleRows()
+	c := &m.cleanup
+	if c.cursor < c.scroll {
+		c.scroll = c.cursor
+	}
+	if c.cursor >= c.scroll+visible {
+		c.scroll = c.cursor - visible + 1
+	}
+}
+
+func (m Model) cleanupVisibleRows() int {
+	rows := m.height - 4
+	if rows < 1 {
+		rows = 1
+	}
+	return rows
+}
+
+func (m Model) viewCleanup() string {
+	var b strings.Builder
+	innerW := m.innerWidth()
+	c := m.cleanup
+
+	title := "Clean up " + c.ws.Name
+	if c.force {
+		title += " (dirty allowed)"
+	}
+	b.WriteString(" " + theme.TreeGroupHeader.Render(title))
+	b.WriteString("\n")
+	mainSep := " " + theme.TreeConnector.Render(strings.Repeat("─", innerW))
+	b.WriteString(mainSep)
+	b.WriteString("\n")
+
+	avail := m.cleanupVisibleRows()
+	used := 0
+	switch {
+	case c.loading:
+		b.WriteString(" " + theme.HelpStyle.Render("classifying worktrees…"))
+		b.WriteString("\n")
+		used++
+	case len(c.candidates) == 0:
+		b.WriteString(" " + theme.HelpStyle.Render("no linked worktrees"))
+		b.WriteString("\n")
+		used++
+	}
+	if !c.loading {
+		for i := c.scroll; i < len(c.candidates) && used < avail; i++ {
+			b.WriteString(renderCleanupRow(c.candidates[i], c.selected[i], i == c.cursor))
+			b.WriteString("\n")
+			used++
+		}
+	}
+	padTo(&b, used, avail)
+
+	b.WriteString(mainSep)
+	b.WriteString("\n")
+	b.WriteString(m.cleanupFooter())
+	return b.String()
+}
+
+func renderCleanupRow(c worktreeprune.Candidate, selected, cursor bool) string {
+	box := "[ ]"
+	switch {
+	case c.Skip != "":
+		box = " - "
+	case selected:
+		box = "[x]"
+	}
+	meta := sessionprune.FormatIdle(c.Age) + "  " + c.Describe()
+	if len(c.Sessions) > 0 {
+		meta += fmt.Sprintf("  %d session(s)", len(c.Sessions))
+	}
+	if c.Skip != "" {
+		meta += "  kept: " + c.Skip
+	}
+	if cursor {
+		return fmt.Sprintf(" %s %s %s  %s", theme.PaletteItemSelected.Render("▸"), box,
+			theme.TreeNodeSelected.Render(c.Worktree.Branch), theme.TreeMeta.Render(meta))
+	}
+	return fmt.Sprintf("   %s %s  %s", box, theme.TreeNodeNormal.Render(c.Worktree.Branch), theme.TreeMeta.Render(meta))
+}
+
+func (m Model) cleanupFooter() string {
+	if m.toast != "" {
+		return m.renderToast()
+	}
+	if m.cleanup.confirming {
+		batch := m.cleanupBatch()
+		sessions, dirty := 0, 0
+		for _, c := range batch {
+			sessions += len(c.Sessions)
+			if c.Worktree.Dirty() {
+				dirty++
+			}
+		}
+		prompt := fmt.Sprintf(" remove %d worktree(s) and kill %d session(s)", len(batch), sessions)
+		if dirty > 0 {
+			prompt += fmt.Sprintf(", discarding changes in %d", dirty)
+		}
+		return theme.AttachedBadge.Render(prompt + "? y/n")
+	}
+	force := "F allow dirty"
+	if m.cleanup.force {
+		force = "F protect dirty"
+	}
+	return theme.HelpStyle.Render(" space toggle  ⏎ remove selected  " + force + "  esc back")
+}
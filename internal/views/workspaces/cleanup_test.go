@@ -0,0 +1,117 @@
+package workspaces
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+	"github.com/miltonparedes/kitmux/internal/worktreeprune"
+)
+
+func stubCleanupOps(t *testing.T) *[]string {
+	t.Helper()
+	original := worktreeCleanupOps
+	t.Cleanup(func() { worktreeCleanupOps = original })
+
+	now := time.Unix(1_700_000_000, 0)
+	srv := tmuxtest.New()
+	for _, sess := range testSessions() {
+		srv.AddSession(sess.Name, sess.Path)
+	}
+	var removed []string
+	worktreeCleanupOps = func() worktreeprune.Ops {
+		return worktreeprune.Ops{
+			Tmux: srv,
+			LoadStats: func(string) ([]wsdata.WorktreeStat, error) {
+				return []wsdata.WorktreeStat{
+					{WorktreePath: "/home/user/kitmux", Branch: "main", IsMain: true},
+					{WorktreePath: "/home/user/kitmux-feature", Branch: "feature", CommitTS: now.Add(-time.Hour).Unix()},
+					{WorktreePath: "/home/user/kitmux-experiment", Branch: "experiment", Modified: true},
+				}, nil
+			},
+			MergedBranches: func(string, string) (map[string]bool, error) {
+				return map[string]bool{"feature": true, "experiment": true}, nil
+			},
+			GoneBranches: func(string) (map[string]bool, error) { return nil, nil },
+			RemoveWorktree: func(_ string, wt wsdata.WorktreeStat, _ bool) error {
+				removed = append(removed, wt.Branch)
+				return nil
+			},
+			Forget: func(string, string) {},
+			Now:    func() time.Time { return now },
+		}
+	}
+	return &removed
+}
+
+// runCmd executes cmd and feeds its message back into the model.
+func runCmd(t *testing.T, m Model, cmd tea.Cmd) Model {
+	t.Helper()
+	if cmd == nil {
+		t.Fatal("expected a command")
+	}
+	next, _ := m.Update(cmd())
+	return next.(Model)
+}
+
+func TestCleanupPreselectsRemovableAndKeepsDirty(t *testing.T) {
+	removed := stubCleanupOps(t)
+	m := newSeededModel()
+
+	next, cmd := m.Update(keyMsg("C"))
+	m = runCmd(t, next.(Model), cmd)
+	if m.mode != modeCleanup || len(m.cleanup.candidates) != 2 {
+		t.Fatalf("mode = %v, candidates = %d", m.mode, len(m.cleanup.candidates))
+	}
+	view := m.View()
+	if !strings.Contains(view, "[x] feature") || !strings.Contains(view, "kept: uncommitted changes") {
+		t.Fatalf("unexpected view:\n%s", view)
+	}
+
+	// Toggling a protected row is a no-op.
+	for i, c := range m.cleanup.candidates {
+		if c.Worktree.Branch == "experiment" {
+			m.cleanup.cursor = i
+		}
+	}
+	next, _ = m.Update(keyMsg(" "))
+	m = next.(Model)
+	if len(m.cleanupBatch()) != 1 {
+		t.Fatalf("batch = %v", m.cleanupBatch())
+	}
+
+	next, _ = m.Update(keyMsg("enter"))
+	m = next.(Model)
+	if !strings.Contains(m.View(), "remove 1 worktree(s) and kill 1 session(s)? y/n") {
+		t.Fatalf("missing confirmation:\n%s", m.View())
+	}
+	next, cmd = m.Update(keyMsg("y"))
+	m = runCmd(t, next.(Model), cmd)
+	if strings.Join(*removed, ",") != "feature" {
+		t.Fatalf("removed = %v", *removed)
+	}
+	if m.mode != modeNormal || !strings.Contains(m.toast, "removed 1 worktree(s)") {
+		t.Fatalf("mode = %v, toast = %q", m.mode, m.toast)
+	}
+}
+
+func TestCleanupForceAllowsDirty(t *testing.T) {
+	stubCleanupOps(t)
+	m := newSeededModel()
+
+	next, cmd := m.Update(keyMsg("C"))
+	m = runCmd(t, next.(Model), cmd)
+	next, cmd = m.Update(keyMsg("F"))
+	m = runCmd(t, next.(Model), cmd)
+	if !m.cleanup.force || len(m.cleanupBatch()) != 2 {
+		t.Fatalf("force = %v, batch = %d", m.cleanup.force, len(m.cleanupBatch()))
+	}
+	next, _ = m.Update(keyMsg("enter"))
+	if !strings.Contains(next.(Model).View(), "discarding changes in 1") {
+		t.Fatalf("confirmation does not warn about dirty worktrees:\n%s", next.(Model).View())
+	}
+}
@@ -0,0 +1,77 @@
+package workspaces
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/theme"
+)
+
+// openConflicts drills into the predicted conflicts of the selected worktree.
+func (m Model) openConflicts() (Model, tea.Cmd) {
+	br, ok := m.selectedBranch()
+	if m.focus != colDetail || !ok {
+		return m, nil
+	}
+	if br.Conflicts == 0 {
+		return m, m.pushToast("no predicted conflicts for "+br.Name, toastInfo)
+	}
+	m.conflictsFor = br
+	m.mode = modeConflicts
+	return m, nil
+}
+
+func (m Model) handleConflicts(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q", "!":
+		m.mode = modeNormal
+	}
+	return m, nil
+}
+
+func (m Model) viewConflicts() string {
+	var b strings.Builder
+	innerW := m.innerWidth()
+	br := m.conflictsFor
+
+	b.WriteString(" " + theme.TreeGroupHeader.Render("Predicted conflicts: "+br.Name))
+	b.WriteString("\n")
+	mainSep := " " + theme.TreeConnector.Render(strings.Repeat("─", innerW))
+	b.WriteString(mainSep)
+	b.WriteString("\n")
+
+	avail := m.height - 4
+	used := 0
+	var ws string
+	if len(m.workspaces) > 0 {
+		ws = m.workspaces[m.wsCursor].Path
+	}
+	wc := m.conflicts[ws]
+	for _, c := range wc.For(br.Path) {
+		if used >= avail {
+			break
+		}
+		label := "vs " + c.OtherBranch
+		if c.WithBase() {
+			label = "vs " + wc.Base + " (base)"
+		}
+		b.WriteString(" " + theme.TreeNodeNormal.Render(label))
+		b.WriteString("\n")
+		used++
+		for _, f := range c.Files {
+			if used >= avail {
+				break
+			}
+			b.WriteString("   " + theme.TreeMeta.Render(f))
+			b.WriteString("\n")
+			used++
+		}
+	}
+	padTo(&b, used, avail)
+
+	b.WriteString(mainSep)
+	b.WriteString("\n")
+	b.WriteString(theme.HelpStyle.Render(" committed changes only  esc back"))
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package workspaces
+
+import (
+	"strings"
+	"testing"
+
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+func TestConflictsBadgeAndDrillDown(t *testing.T) {
+	m := newSeededModel()
+	next, _ := m.Update(conflictsLoadedMsg{conflicts: map[string]wsdata.WorkspaceConflicts{
+		"/home/user/kitmux": {
+			Path: "/home/user/kitmux",
+			Base: "main",
+			Conflicts: []wsdata.Conflict{
+				{Path: "/home/user/kitmux-feature", Branch: "feature", Files: []string{"go.mod"}},
+				{
+					Path: "/home/user/kitmux-experiment", Branch: "experiment",
+					OtherPath: "/home/user/kitmux-feature", OtherBranch: "feature",
+					Files: []string{"internal/app/app.go"},
+				},
+			},
+		},
+	}})
+	m = next.(Model)
+
+	counts := make(map[string]int)
+	for _, br := range m.branches {
+		counts[br.Name] = br.Conflicts
+	}
+	if counts["feature"] != 2 || counts["experiment"] != 1 || counts["main"] != 0 {
+		t.Fatalf("conflict counts = %v", counts)
+	}
+	if !strings.Contains(m.View(), "⚠2") {
+		t.Fatalf("badge missing:\n%s", m.View())
+	}
+
+	m.focus = colDetail
+	for i, br := range m.branches {
+		if br.Name == "feature" {
+			m.detCursor = i
+		}
+	}
+	next, _ = m.Update(keyMsg("!"))
+	m = next.(Model)
+	if m.mode != modeConflicts {
+		t.Fatalf("mode = %v, want modeConflicts", m.mode)
+	}
+	view := m.View()
+	for _, want := range []string{"vs main (base)", "go.mod", "vs experiment", "internal/app/app.go"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("drill-down missing %q:\n%s", want, view)
+		}
+	}
+	if strings.Index(view, "vs main (base)") > strings.Index(view, "vs experiment") {
+		t.Fatalf("base conflict should be listed first:\n%s", view)
+	}
+
+	next, _ = m.Update(keyMsg("esc"))
+	if next.(Model).mode != modeNormal {
+		t.Fatal("esc did not close the drill-down")
+	}
+}
+
+func TestStatsLoadedPredictsConflictsSeparately(t *testing.T) {
+	m := newSeededModel()
+	m.stats_svc = wsdata.NewStatsService()
+	ws := wsdata.WorkspaceStats{Path: "/home/user/kitmux", Worktrees: []wsdata.WorktreeStat{
+		{WorktreePath: "/home/user/kitmux", Branch: "main", IsMain: true},
+	}}
+	next, cmd := m.Update(statsLoadedMsg{wsStats: map[string]wsdata.WorkspaceStats{ws.Path: ws}})
+	m = next.(Model)
+	if _, ok := m.conflicts[ws.Path]; ok {
+		t.Fatal("conflicts were computed with the stats")
+	}
+	if cmd == nil {
+		t.Fatal("expected a conflicts command")
+	}
+	msg, ok := cmd().(conflictsLoadedMsg)
+	if !ok {
+		t.Fatalf("cmd() = %#v, want conflictsLoadedMsg", cmd())
+	}
+	if c := msg.conflicts[ws.Path]; c.Path != ws.Path {
+		t.Fatalf("conflicts = %+v", msg.conflicts)
+	}
+}
@@ -47,7 +47,11 @@ func (m *Model) buildBranches(wsEntry workspaceEntry) []branchEntry {
 	result := make([]branchEntry, 0, len(active)+len(inactive))
 	result = append(result, active...)
 	result = append(result, inactive...)
-	return result
+	conflicts := m.conflicts[wsEntry.Path]
+	for i := range result {
+		result[i].Conflicts = len(conflicts.For(result[i].Path))
+	}
+	return m.nestSubprojects(wsEntry, result)
 }
 
 func indexWorktreeStats(stats WorkspaceStatsAlias) map[string]int {
@@ -1,20 +1,22 @@
 package workspaces
 
-import "github.com/sahilm/fuzzy"
+import "github.com/miltonparedes/kitmux/internal/frecency"
 
-func (z *zoxidePicker) filter() {
+func (z *dirPicker) filter() {
 	query := z.input.Value()
 	if query == "" {
 		z.filtered = z.all
 	} else {
 		shorts := make([]string, len(z.all))
+		paths := make([]string, len(z.all))
 		for i, e := range z.all {
 			shorts[i] = e.Short
+			paths[i] = e.Path
 		}
-		matches := fuzzy.Find(query, shorts)
-		filtered := make([]zoxideEntry, len(matches))
-		for i, m := range matches {
-			filtered[i] = z.all[m.Index]
+		ranked := frecency.Rank(query, shorts, paths, z.scores)
+		filtered := make([]dirEntry, len(ranked))
+		for i, idx := range ranked {
+			filtered[i] = z.all[idx]
 		}
 		z.filtered = filtered
 	}
@@ -22,14 +24,14 @@ func (z *zoxidePicker) filter() {
 	z.scroll = 0
 }
 
-func (z *zoxidePicker) selected() *zoxideEntry {
+func (z *dirPicker) selected() *dirEntry {
 	if z.cursor >= 0 && z.cursor < len(z.filtered) {
 		return &z.filtered[z.cursor]
 	}
 	return nil
 }
 
-func (z *zoxidePicker) clampCursor() {
+func (z *dirPicker) clampCursor() {
 	if z.cursor < 0 {
 		z.cursor = 0
 	}
@@ -41,7 +43,7 @@ func (z *zoxidePicker) clampCursor() {
 	}
 }
 
-func (z *zoxidePicker) ensureVisible(maxVisible int) {
+func (z *dirPicker) ensureVisible(maxVisible int) {
 	if maxVisible < 1 {
 		maxVisible = 1
 	}
@@ -3,6 +3,8 @@ package workspaces
 import (
 	"strings"
 	"testing"
+
+	"github.com/miltonparedes/kitmux/internal/frecency"
 )
 
 func TestFilteredIndicesFuzzy(t *testing.T) {
@@ -11,17 +13,24 @@ func TestFilteredIndicesFuzzy(t *testing.T) {
 		{Name: "api", Path: "/b"},
 		{Name: "dotfiles", Path: "/c"},
 	}
-	got := filteredWorkspaceIndices(wss, "api")
+	got := filteredWorkspaceIndices(wss, "api", nil)
 	if len(got) != 1 || got[0] != 1 {
 		t.Fatalf("expected [1], got %v", got)
 	}
 
-	got = filteredWorkspaceIndices(wss, "")
+	got = filteredWorkspaceIndices(wss, "", nil)
 	if len(got) != 3 {
 		t.Fatalf("expected all indices, got %v", got)
 	}
 
-	got = filteredWorkspaceIndices(wss, "zzzzz")
+	// Among similar matches the most used workspace wins.
+	wss = append(wss, workspaceEntry{Name: "kitmux-feature", Path: "/d"})
+	got = filteredWorkspaceIndices(wss, "kit", frecency.Scores{"/d": 5})
+	if len(got) != 2 || got[0] != 3 {
+		t.Fatalf("expected [3 0], got %v", got)
+	}
+
+	got = filteredWorkspaceIndices(wss, "zzzzz", nil)
 	if len(got) != 0 {
 		t.Fatalf("expected no matches, got %v", got)
 	}
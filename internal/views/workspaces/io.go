@@ -1,15 +1,13 @@
 package workspaces
 
 import (
-	"os"
-	"os/exec"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/miltonparedes/kitmux/internal/discovery"
+	"github.com/miltonparedes/kitmux/internal/frecency"
 	"github.com/miltonparedes/kitmux/internal/tmux"
 	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
 	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
@@ -42,7 +40,8 @@ func loadInitialSnapshot(svc *wsdata.StatsService) tea.Msg {
 		}
 	}
 
-	wsreg.SortWorkspaces(projs, activePaths)
+	scores := loadScores(frecency.KindWorkspace)
+	wsreg.SortWorkspaces(projs, activePaths, scores)
 
 	var cached map[string]wsdata.WorkspaceStats
 	if svc != nil {
@@ -60,17 +59,21 @@ func loadInitialSnapshot(svc *wsdata.StatsService) tea.Msg {
 			Path:     p.Path,
 			Active:   act > 0,
 			Activity: act,
+			Pinned:   p.Pinned,
+			Tags:     p.Tags,
 		}
 	}
 	archived := wsreg.LoadArchivedWorktrees()
 
 	return dataLoadedMsg{
-		workspaces: entries,
-		sessions:   sess,
-		repoRoots:  repoRoots,
-		wtByPath:   wtByPath,
-		panes:      panes,
-		archived:   archived,
+		workspaces:  entries,
+		sessions:    sess,
+		repoRoots:   repoRoots,
+		wtByPath:    wtByPath,
+		panes:       panes,
+		archived:    archived,
+		scores:      scores,
+		agentScores: loadScores(frecency.KindAgent),
 	}
 }
 
@@ -133,6 +136,26 @@ func refreshStatsCmd(svc *wsdata.StatsService, workspacePath string) tea.Cmd {
 	}
 }
 
+// cachedStatsCmd reloads one workspace's stats from SQLite. The workspace
+// watcher has already rewritten the changed row, so nothing is refetched.
+func cachedStatsCmd(svc *wsdata.StatsService, workspacePath string) tea.Cmd {
+	if svc == nil || workspacePath == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		ws, err := svc.LoadCached(workspacePath)
+		if err != nil {
+			return nil
+		}
+		return statsLoadedMsg{
+			wsStats:  map[string]wsdata.WorkspaceStats{workspacePath: ws},
+			stats:    flattenSessionStats(ws, nil),
+			refresh:  time.Now(),
+			workPath: workspacePath,
+		}
+	}
+}
+
 // refreshAllStatsCmd fans out one goroutine per workspace so `wt list` calls
 // run in parallel. Results are collected behind a mutex and delivered as a
 // single statsLoadedMsg — we intentionally avoid dispatching one Bubble Tea
@@ -171,6 +194,33 @@ func refreshAllStatsCmd(svc *wsdata.StatsService, workspaces []workspaceEntry) t
 	}
 }
 
+// conflictsCmd predicts merge conflicts for freshly loaded stats in its own
+// command, so slow merge-tree runs never hold back the stats themselves.
+func conflictsCmd(svc *wsdata.StatsService, stats map[string]wsdata.WorkspaceStats) tea.Cmd {
+	if svc == nil || len(stats) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		var (
+			mu sync.Mutex
+			wg sync.WaitGroup
+		)
+		conflicts := make(map[string]wsdata.WorkspaceConflicts, len(stats))
+		for path, ws := range stats {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c := svc.Conflicts(ws)
+				mu.Lock()
+				conflicts[path] = c
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+		return conflictsLoadedMsg{conflicts: conflicts}
+	}
+}
+
 // flattenSessionStats converts a WorkspaceStats into a per-session map using
 // the provided path→session-name index. When index is nil the helper still
 // returns a populated-by-path map, which callers may key differently.
@@ -202,39 +252,35 @@ func flattenAllSessionStats(all map[string]wsdata.WorkspaceStats) map[string]ses
 	return out
 }
 
-// loadZoxide queries zoxide for recent directories. Errors surface as an
-// empty result; the caller can decide to show a toast.
-func loadZoxide() tea.Cmd {
+// dirSources returns the directory sources of the add-workspace picker;
+// swapped in tests.
+var dirSources = discovery.Configured
+
+// Frecency seams; swapped in tests.
+var (
+	loadScores = frecency.Load
+	recordUse  = frecency.Record
+)
+
+// recordUseCmd counts a use of key off the UI goroutine.
+func recordUseCmd(kind, key string) tea.Cmd {
 	return func() tea.Msg {
-		entries, _ := queryZoxide()
-		return zoxideLoadedMsg{entries: entries}
+		recordUse(kind, key)
+		return nil
 	}
 }
 
-func queryZoxide() ([]zoxideEntry, error) {
-	out, err := exec.Command("zoxide", "query", "-ls").Output()
-	if err != nil {
-		return nil, err
-	}
-	home, _ := os.UserHomeDir()
-	var entries []zoxideEntry
-	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
-		if line == "" {
-			continue
-		}
-		line = strings.TrimSpace(line)
-		idx := strings.IndexByte(line, ' ')
-		if idx < 0 {
-			continue
-		}
-		scoreStr := line[:idx]
-		path := strings.TrimSpace(line[idx+1:])
-		score, _ := strconv.ParseFloat(scoreStr, 64)
-		short := path
-		if home != "" && strings.HasPrefix(path, home) {
-			short = "~" + path[len(home):]
+// loadDirs lists candidate directories from every configured discovery
+// source. Unavailable sources are skipped.
+func loadDirs() tea.Cmd {
+	return func() tea.Msg {
+		found := discovery.Collect(dirSources())
+		entries := make([]dirEntry, len(found))
+		for i, d := range found {
+			entries[i] = dirEntry{Score: d.Score, Path: d.Path, Short: d.Short(), Source: d.Source}
 		}
-		entries = append(entries, zoxideEntry{Score: score, Path: path, Short: short})
+		scores := loadScores(frecency.KindDir)
+		entries = frecency.Sort(entries, scores, func(e dirEntry) string { return e.Path })
+		return dirsLoadedMsg{entries: entries, scores: scores}
 	}
-	return entries, nil
 }
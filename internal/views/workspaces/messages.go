@@ -3,6 +3,7 @@ package workspaces
 import (
 	"time"
 
+	"github.com/miltonparedes/kitmux/internal/frecency"
 	"github.com/miltonparedes/kitmux/internal/tmux"
 	"github.com/miltonparedes/kitmux/internal/workspaces/data"
 	"github.com/miltonparedes/kitmux/internal/worktree"
@@ -29,6 +30,15 @@ const (
 	modeAgentPicker
 	modeActionPicker
 	modeHelp
+	modeCleanup
+	modeConflicts
+	modeBulkUpdate
+	modeEditTags
+	modeTagFilter
+	modeSaveFilter
+	modeStacks
+	modeStackBranch
+	modeSaveStack
 )
 
 type confirmAction int
@@ -86,6 +96,8 @@ type workspaceEntry struct {
 	Deleted    int
 	Worktrees  int
 	DirtyCount int
+	Pinned     bool
+	Tags       []string
 }
 
 // branchEntry represents a session or worktree in the right column.
@@ -104,6 +116,13 @@ type branchEntry struct {
 	Untracked   bool
 	Ahead       int
 	Behind      int
+	Conflicts   int // worktrees (or the base branch) it conflicts with
+	// Subproject is the path of a subproject row relative to its worktree,
+	// Parent and ParentBranch that worktree's path and branch.
+	Subproject   string
+	Parent       string
+	ParentBranch string
+	Subprojects  int // collapsed subprojects below a worktree row
 }
 
 // agentEntry represents a detected running agent or the launch action.
@@ -130,6 +149,9 @@ type dataLoadedMsg struct {
 	wtByPath   map[string][]worktree.Worktree
 	panes      []tmux.Pane
 	archived   map[string]map[string]bool
+	// scores and agentScores are the frecency of workspace paths and agents.
+	scores      frecency.Scores
+	agentScores frecency.Scores
 }
 
 // statsLoadedMsg is dispatched when live worktree stats arrive from StatsService.
@@ -140,9 +162,16 @@ type statsLoadedMsg struct {
 	workPath string // "" for full reload, else single-workspace delta
 }
 
-// zoxideLoadedMsg delivers zoxide query results to the workspace picker.
-type zoxideLoadedMsg struct {
-	entries []zoxideEntry
+// conflictsLoadedMsg delivers the merge conflicts predicted from the stats
+// of the last statsLoadedMsg, keyed by workspace path.
+type conflictsLoadedMsg struct {
+	conflicts map[string]data.WorkspaceConflicts
+}
+
+// dirsLoadedMsg delivers the discovered directories to the workspace picker.
+type dirsLoadedMsg struct {
+	entries []dirEntry
+	scores  frecency.Scores
 }
 
 // toastMsg is a transient status-line message.
@@ -8,6 +8,9 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/miltonparedes/kitmux/internal/agents"
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/frecency"
+	"github.com/miltonparedes/kitmux/internal/subproject"
 	"github.com/miltonparedes/kitmux/internal/tmux"
 	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
 	"github.com/miltonparedes/kitmux/internal/worktree"
@@ -44,8 +47,16 @@ type Model struct {
 
 	// Cached workspace-level stats keyed by workspace path.
 	wsStats map[string]wsdata.WorkspaceStats
+	// Predicted merge conflicts keyed by workspace path.
+	conflicts map[string]wsdata.WorkspaceConflicts
+	// conflictsFor is the worktree drilled into by modeConflicts.
+	conflictsFor branchEntry
 	// Archived worktrees hidden from the detail view.
 	archived map[string]map[string]bool
+	// Subprojects detected per workspace, and the worktree paths whose
+	// subproject rows are expanded (space).
+	subprojects map[string][]subproject.Subproject
+	expanded    map[string]bool
 
 	// All panes for agent detection
 	panes []tmux.Pane
@@ -57,9 +68,31 @@ type Model struct {
 
 	// Filter (/)
 	filter textinput.Model
-
-	// Workspace picker (n/f) — zoxide
-	zoxide zoxidePicker
+	// wsScores and agentScores rank workspaces and agents by frecency.
+	wsScores    frecency.Scores
+	agentScores frecency.Scores
+
+	// allWorkspaces is the full registry; workspaces is the part of it the
+	// tag filter (#) lets through.
+	allWorkspaces []workspaceEntry
+	tagFilter     []string
+	filterName    string // preset the tag filter came from, if any
+	tagPicker     tagPickerState
+	// Tag editing (t) and preset naming share one input.
+	tagInput   textinput.Model
+	tagsFor    workspaceEntry
+	presetTags []string
+
+	// Stacks (S): the picker and its name/branch input.
+	stackPicker stackPickerState
+	stackInput  textinput.Model
+
+	// Workspace picker (n/f) — discovered directories
+	dirs dirPicker
+
+	// Worktree cleanup (C)
+	cleanup cleanupState
+	update  updateState
 
 	// New branch input
 	newBranch   textinput.Model
@@ -100,18 +133,21 @@ type Model struct {
 	stats_svc *wsdata.StatsService
 }
 
-type zoxideEntry struct {
-	Score float64
-	Path  string
-	Short string
+type dirEntry struct {
+	Score  float64
+	Path   string
+	Short  string
+	Source string // discovery source that found the directory
 }
 
-type zoxidePicker struct {
-	all      []zoxideEntry
-	filtered []zoxideEntry
+type dirPicker struct {
+	all      []dirEntry
+	filtered []dirEntry
+	loaded   bool
 	input    textinput.Model
 	cursor   int
 	scroll   int
+	scores   frecency.Scores
 }
 
 type agentPickerState struct {
@@ -120,6 +156,16 @@ type agentPickerState struct {
 	modeIndex []int
 }
 
+// withRepoDefaults orders the agents by frecency and preselects the agent
+// and modes set in the .kitmux.toml of the repository at dir.
+func (p agentPickerState) withRepoDefaults(dir string, scores frecency.Scores) agentPickerState {
+	p.agents = frecency.Sort(p.agents, scores, func(a agents.Agent) string { return a.ID })
+	repo, _ := config.LoadRepo(dir)
+	p.cursor = agents.Index(p.agents, repo.DefaultAgent())
+	p.modeIndex = agents.ModeIndexes(p.agents, repo.AgentMode)
+	return p
+}
+
 // New builds a Model with default inputs and the production stats service.
 func New() Model {
 	return newWithService(wsdata.NewStatsService())
@@ -141,13 +187,21 @@ func newWithService(svc *wsdata.StatsService) Model {
 	bi.Placeholder = "new-feature"
 	bi.CharLimit = 128
 
+	ti := textinput.New()
+	ti.CharLimit = 128
+
+	si := textinput.New()
+	si.CharLimit = 128
+
 	agentList := agents.DefaultAgents()
 	return Model{
-		stats:     make(map[string]sessionStats),
-		wsStats:   make(map[string]wsdata.WorkspaceStats),
-		filter:    fi,
-		zoxide:    zoxidePicker{input: zi},
-		newBranch: bi,
+		stats:      make(map[string]sessionStats),
+		wsStats:    make(map[string]wsdata.WorkspaceStats),
+		filter:     fi,
+		dirs:       dirPicker{input: zi},
+		newBranch:  bi,
+		tagInput:   ti,
+		stackInput: si,
 		agentPicker: agentPickerState{
 			agents:    agentList,
 			modeIndex: make([]int, len(agentList)),
@@ -161,17 +215,24 @@ func (m Model) Init() tea.Cmd {
 	return loadDataCmd(svc)
 }
 
-// InitAddMode returns an Init command that also opens the zoxide "add workspace"
+// StatsChanged reloads the cached stats of a workspace after the workspace
+// watcher refreshed one of its worktrees.
+func (m Model) StatsChanged(workspacePath string) tea.Cmd {
+	return cachedStatsCmd(m.stats_svc, workspacePath)
+}
+
+// InitAddMode returns an Init command that also opens the "add workspace"
 // picker once initial data has loaded.
 func (m *Model) InitAddMode() tea.Cmd {
 	m.mode = modeWorkspaceSearch
-	m.zoxide.input.SetValue("")
-	m.zoxide.input.Focus()
-	m.zoxide.all = nil
-	m.zoxide.filtered = nil
-	m.zoxide.cursor = 0
-	m.zoxide.scroll = 0
-	return tea.Batch(loadDataCmd(m.stats_svc), textinput.Blink, loadZoxide())
+	m.dirs.input.SetValue("")
+	m.dirs.input.Focus()
+	m.dirs.all = nil
+	m.dirs.filtered = nil
+	m.dirs.loaded = false
+	m.dirs.cursor = 0
+	m.dirs.scroll = 0
+	return tea.Batch(loadDataCmd(m.stats_svc), textinput.Blink, loadDirs())
 }
 
 // SetSize is called by the host (app.Model or standalone program) on window
@@ -189,7 +250,10 @@ func (m Model) IsEditing() bool {
 	case modeFiltering, modeWorkspaceSearch,
 		modeNewBranch, modeNewBranchAgent,
 		modeAgentAttachChoice, modeAttachBranchPicker,
-		modeConfirm, modeAgentPicker, modeActionPicker, modeHelp:
+		modeConfirm, modeAgentPicker, modeActionPicker, modeHelp,
+		modeCleanup, modeConflicts, modeBulkUpdate,
+		modeEditTags, modeTagFilter, modeSaveFilter,
+		modeStacks, modeStackBranch, modeSaveStack:
 		return true
 	default:
 		return false
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/miltonparedes/kitmux/internal/agents"
 	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
 	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
 	"github.com/miltonparedes/kitmux/internal/worktree"
 )
@@ -65,6 +67,7 @@ func seedModel(projects []workspaceEntry, sessions []tmux.Session, repoRoots map
 	m.width = 120
 	m.height = 30
 	m.workspaces = projects
+	m.allWorkspaces = projects
 	m.sessions = sessions
 	m.repoRoots = repoRoots
 	m.wtByPath = wtByPath
@@ -942,14 +945,14 @@ func TestViewColumns_FooterChangesWithFocus(t *testing.T) {
 func TestViewProjectSearch_Renders(t *testing.T) {
 	m := newSeededModel()
 	m.mode = modeWorkspaceSearch
-	m.zoxide.all = []zoxideEntry{
+	m.dirs.all = []dirEntry{
 		{Score: 100, Path: "/home/user/test", Short: "~/test"},
 	}
-	m.zoxide.filtered = m.zoxide.all
+	m.dirs.filtered = m.dirs.all
 
 	output := m.View()
 	if !strings.Contains(output, "~/test") {
-		t.Error("expected zoxide entry in search view")
+		t.Error("expected directory entry in search view")
 	}
 }
 
@@ -1544,17 +1547,17 @@ func TestContentHeight(t *testing.T) {
 	}
 }
 
-// --- Zoxide picker helpers ---
+// --- Directory picker helpers ---
 
-func TestZoxideFilter(t *testing.T) {
-	z := &zoxidePicker{
-		all: []zoxideEntry{
+func TestDirPickerFilter(t *testing.T) {
+	z := &dirPicker{
+		all: []dirEntry{
 			{Score: 100, Path: "/home/user/kitmux", Short: "~/kitmux"},
 			{Score: 80, Path: "/home/user/api", Short: "~/api"},
 			{Score: 60, Path: "/home/user/dotfiles", Short: "~/dotfiles"},
 		},
 	}
-	z.input = New().zoxide.input
+	z.input = New().dirs.input
 	z.input.SetValue("kit")
 	z.filter()
 
@@ -1566,13 +1569,13 @@ func TestZoxideFilter(t *testing.T) {
 	}
 }
 
-func TestZoxideFilterEmpty(t *testing.T) {
-	z := &zoxidePicker{
-		all: []zoxideEntry{
+func TestDirPickerFilterEmpty(t *testing.T) {
+	z := &dirPicker{
+		all: []dirEntry{
 			{Score: 100, Path: "/home/user/kitmux", Short: "~/kitmux"},
 		},
 	}
-	z.input = New().zoxide.input
+	z.input = New().dirs.input
 	z.input.SetValue("")
 	z.filter()
 
@@ -1581,9 +1584,9 @@ func TestZoxideFilterEmpty(t *testing.T) {
 	}
 }
 
-func TestZoxideSelected(t *testing.T) {
-	z := &zoxidePicker{
-		filtered: []zoxideEntry{
+func TestDirPickerSelected(t *testing.T) {
+	z := &dirPicker{
+		filtered: []dirEntry{
 			{Score: 100, Path: "/home/user/kitmux", Short: "~/kitmux"},
 		},
 		cursor: 0,
@@ -1665,3 +1668,79 @@ func TestFullNavigationFlow(t *testing.T) {
 		t.Error("expected dotfiles at cursor")
 	}
 }
+
+func TestAttachSessionAndAgentCreatesSessionOnServer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("KITMUX_AGENT_SIDEPANEL", "off")
+	srv := tmuxtest.Install(t)
+	srv.Attach(srv.AddSession("kitmux", "/home/user/kitmux"))
+	srv.AddSession("kitmux-feature", "/home/user/elsewhere")
+
+	agent, ok := agents.Find("codex")
+	if !ok {
+		t.Fatal("missing codex agent")
+	}
+	wt := worktree.Worktree{Path: "/home/user/kitmux-feature", Branch: "feature"}
+	sessName, msg := attachSessionAndAgent("kitmux", "feature", wt, nil, &agent, agent.Modes[0])
+	if _, ok := msg.(switchDoneMsg); !ok {
+		t.Fatalf("message = %#v, want switchDoneMsg", msg)
+	}
+	if sessName != "kitmux-feature-2" {
+		t.Fatalf("session = %q, want kitmux-feature-2", sessName)
+	}
+
+	if current, _ := srv.CurrentSession(); current != "kitmux-feature-2" {
+		t.Fatalf("current session = %q, want kitmux-feature-2", current)
+	}
+	if got := srv.PanePath("kitmux-feature-2:0.0"); got != wt.Path {
+		t.Fatalf("session dir = %q, want %q", got, wt.Path)
+	}
+	windows, err := srv.ListWindows("kitmux-feature-2")
+	if err != nil || len(windows) != 1 || windows[0].Name != "codex" {
+		t.Fatalf("windows = %+v, err %v", windows, err)
+	}
+	sent := srv.SentKeys("kitmux-feature-2:0.0")
+	if len(sent) != 1 || !strings.Contains(sent[0], "codex") {
+		t.Fatalf("sent keys = %q, want the codex command", sent)
+	}
+}
+
+func TestOpenWorktreeRunsSetupBeforeSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("KITMUX_AGENT_SIDEPANEL", "off")
+	dir := filepath.Join(t.TempDir(), "app.feature")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "-C", dir, "init", "-q").CombinedOutput(); err != nil {
+		t.Skipf("git init: %v %s", err, out)
+	}
+	toml := "[[worktree.setup]]\nenv = { PORT = \"3001\" }\n"
+	if err := os.WriteFile(filepath.Join(dir, ".kitmux.toml"), []byte(toml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := tmuxtest.Install(t)
+	srv.Attach(srv.AddSession("kitmux", "/home/user/kitmux"))
+
+	agent, _ := agents.Find("codex")
+	wt := worktree.Worktree{Path: dir, Branch: "feature"}
+	if msg := openWorktree("app", "feature", wt, true, &agent, agent.Modes[0]); msg != (switchDoneMsg{}) {
+		t.Fatalf("message = %#v, want switchDoneMsg", msg)
+	}
+
+	popup, session := -1, -1
+	for i, call := range srv.Calls() {
+		switch {
+		case strings.HasPrefix(call, "display-popup kitmux worktrees setup"):
+			popup = i
+		case strings.HasPrefix(call, "new-session app-feature"):
+			session = i
+		}
+	}
+	if popup < 0 || session < popup {
+		t.Fatalf("calls = %q, want the setup popup before the session", srv.Calls())
+	}
+	if got := srv.Environment("app-feature", "PORT"); got != "3001" {
+		t.Fatalf("session PORT = %q, want it set when the session is created", got)
+	}
+}
@@ -22,6 +22,16 @@ func (m Model) View() string {
 		return m.viewActionPicker()
 	case modeHelp:
 		return m.viewHelp()
+	case modeCleanup:
+		return m.viewCleanup()
+	case modeConflicts:
+		return m.viewConflicts()
+	case modeBulkUpdate:
+		return m.viewBulkUpdate()
+	case modeTagFilter, modeSaveFilter:
+		return m.viewTagFilter()
+	case modeStacks, modeStackBranch, modeSaveStack:
+		return m.viewStacks()
 	case modeAgentPicker, modeNewBranchAgent:
 		return m.viewAgentPicker()
 	case modeAgentAttachChoice:
@@ -101,13 +111,31 @@ func (m Model) renderLeftColumn(width, avail int) string {
 	var b strings.Builder
 	used := 0
 
-	b.WriteString(columnHeader("Workspaces", m.focus == colWorkspaces))
+	title := "Workspaces"
+	if label := m.filterLabel(); label != "" {
+		title += " " + label
+	}
+	if m.mode == modeEditTags {
+		b.WriteString(" " + m.tagInput.View())
+	} else {
+		b.WriteString(columnHeader(title, m.focus == colWorkspaces))
+	}
 	b.WriteString("\n")
 	used++
 	b.WriteString(rowSep(width))
 	b.WriteString("\n")
 	used++
 
+	if len(m.workspaces) == 0 && len(m.tagFilter) > 0 {
+		b.WriteString(" " + theme.HelpStyle.Render("No workspaces match"))
+		b.WriteString("\n")
+		used++
+		b.WriteString(" " + theme.HelpStyle.Render("press esc to clear the filter"))
+		b.WriteString("\n")
+		used++
+		padTo(&b, used, avail)
+		return b.String()
+	}
 	if len(m.workspaces) == 0 {
 		b.WriteString(" " + theme.HelpStyle.Render("No workspaces"))
 		b.WriteString("\n")
@@ -158,7 +186,7 @@ func (m Model) renderFilteredLeft(width, avail int) string {
 	b.WriteString("\n")
 	used++
 
-	idxs := filteredWorkspaceIndices(m.workspaces, m.filter.Value())
+	idxs := filteredWorkspaceIndices(m.workspaces, m.filter.Value(), m.wsScores)
 	if len(idxs) == 0 {
 		b.WriteString(" " + theme.HelpStyle.Render("no matches"))
 		b.WriteString("\n")
@@ -199,11 +227,23 @@ func (m Model) renderWorkspaceLine(p workspaceEntry, selected bool, width int) s
 	} else {
 		name = "   " + theme.TreeNodeNormal.Render(p.Name)
 	}
+	if p.Pinned {
+		name += " " + theme.TreeMeta.Render("★")
+	}
 	if p.Active {
 		name += " " + theme.AttachedBadge.Render("●")
 	}
 	summary := workspaceDiffSummary(p)
-	if summary == "" || width <= 0 {
+	if width <= 0 {
+		return name
+	}
+	if len(p.Tags) > 0 {
+		tagged := name + " " + theme.TreeMeta.Render("#"+strings.Join(p.Tags, " #"))
+		if lipgloss.Width(tagged)+lipgloss.Width(summary)+2 <= width {
+			name = tagged
+		}
+	}
+	if summary == "" {
 		return name
 	}
 	leftW := lipgloss.Width(name)
@@ -350,6 +390,9 @@ func (m Model) renderNewBranchOverlay(width, avail int) string {
 }
 
 func (m Model) renderBranch(br branchEntry, selected bool, width int) string {
+	if br.Subproject != "" {
+		br.Name = "  └ " + br.Name
+	}
 	var left string
 	if br.IsSession {
 		left = renderSessionBranchLeft(br, selected)
@@ -406,6 +449,12 @@ func joinBranchLine(left, right string, width int) string {
 
 func branchDiffStats(br branchEntry) string {
 	var parts []string
+	if br.Subprojects > 0 {
+		parts = append(parts, theme.TreeMeta.Render(fmt.Sprintf("%d sub", br.Subprojects)))
+	}
+	if br.Conflicts > 0 {
+		parts = append(parts, theme.DirtyBadge.Render(fmt.Sprintf("⚠%d", br.Conflicts)))
+	}
 	if br.DiffAdded > 0 {
 		parts = append(parts, theme.DiffAdded.Render(fmt.Sprintf("+%d", br.DiffAdded)))
 	}
@@ -455,6 +504,8 @@ func (m Model) footer() string {
 		return theme.HelpStyle.Render(" ⏎ choose  j/k nav  esc back")
 	case modeAttachBranchPicker:
 		return theme.HelpStyle.Render(" ⏎ select branch  j/k nav  esc back")
+	case modeEditTags:
+		return theme.HelpStyle.Render(" ⏎ save tags (comma-separated)  esc cancel")
 	}
 	if m.focus == colDetail {
 		return theme.HelpStyle.Render(" j/k nav  ⏎ open  h back  c new worktree  x actions  ? help")
@@ -480,7 +531,7 @@ func (m Model) viewProjectSearch() string {
 
 	innerW := m.innerWidth()
 
-	b.WriteString(" " + m.zoxide.input.View())
+	b.WriteString(" " + m.dirs.input.View())
 	b.WriteString("\n")
 	mainSep := " " + theme.TreeConnector.Render(strings.Repeat("─", innerW))
 	itemSep := " " + theme.TreeMeta.Render(strings.Repeat("─", innerW))
@@ -494,9 +545,11 @@ func (m Model) viewProjectSearch() string {
 	}
 
 	used := 0
-	if len(m.zoxide.filtered) == 0 {
-		if len(m.zoxide.all) == 0 {
+	if len(m.dirs.filtered) == 0 {
+		if !m.dirs.loaded {
 			b.WriteString(theme.HelpStyle.Render(" Loading..."))
+		} else if len(m.dirs.all) == 0 {
+			b.WriteString(theme.HelpStyle.Render(" No directories found; install zoxide or set KITMUX_DISCOVERY_ROOTS"))
 		} else {
 			b.WriteString(theme.HelpStyle.Render(" No matches"))
 		}
@@ -508,20 +561,23 @@ func (m Model) viewProjectSearch() string {
 	if maxVisible < 1 {
 		maxVisible = 1
 	}
-	end := m.zoxide.scroll + maxVisible
-	if end > len(m.zoxide.filtered) {
-		end = len(m.zoxide.filtered)
+	end := m.dirs.scroll + maxVisible
+	if end > len(m.dirs.filtered) {
+		end = len(m.dirs.filtered)
 	}
 
-	for i := m.zoxide.scroll; i < end; i++ {
-		e := m.zoxide.filtered[i]
-		sel := i == m.zoxide.cursor
+	for i := m.dirs.scroll; i < end; i++ {
+		e := m.dirs.filtered[i]
+		sel := i == m.dirs.cursor
 		if sel {
 			b.WriteString(" " + theme.PaletteItemSelected.Render("▸") + " " +
 				theme.TreeNodeSelected.Render(e.Short))
 		} else {
 			b.WriteString("   " + theme.TreeNodeNormal.Render(e.Short))
 		}
+		if e.Source != "" {
+			b.WriteString("  " + theme.TreeMeta.Render(e.Source))
+		}
 		b.WriteString("\n")
 		used++
 		if i < end-1 && used < avail-1 {
@@ -765,6 +821,15 @@ func (m Model) viewHelp() string {
 		"h/esc        back",
 		"c            new worktree",
 		"x            actions (archive/delete/remove workspace)",
+		"C            clean up merged/stale worktrees",
+		"U            rebase/merge the base branch into worktrees",
+		"!            predicted conflicts of a worktree (⚠)",
+		"p            pin/unpin workspace",
+		"t            edit workspace tags",
+		"#            filter by tag or saved preset",
+		"S            stacks: open several workspaces on one branch",
+		"v            diff viewer for the selected worktree",
+		"space        show/hide subprojects of a worktree",
 		"a / A        launch agent (window/split)",
 		"/            filter workspaces",
 		"n / f        add/find workspace",
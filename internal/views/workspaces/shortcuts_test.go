@@ -104,7 +104,7 @@ func TestNewBranchTabIgnoredWhenEmpty(t *testing.T) {
 	}
 }
 
-func TestFOpensZoxideFromEitherColumn(t *testing.T) {
+func TestFOpensDirPickerFromEitherColumn(t *testing.T) {
 	m := newSeededModel()
 	updated, _ := m.Update(keyMsg("f"))
 	m = updated.(Model)
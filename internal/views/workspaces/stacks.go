@@ -0,0 +1,290 @@
+package workspaces
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/stack"
+	"github.com/miltonparedes/kitmux/internal/theme"
+	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
+)
+
+// Stack seams; swapped in tests.
+var (
+	loadStacks  = wsreg.LoadStacks
+	saveStack   = wsreg.SaveStack
+	deleteStack = wsreg.DeleteStack
+	openStack   = func(name string, members []stack.Workspace, branch, layout string) (stack.Result, error) {
+		return stack.Open(name, members, branch, layout, stack.DefaultOps())
+	}
+)
+
+type stackPickerState struct {
+	stacks []wsreg.Stack
+	cursor int
+	scroll int
+}
+
+// stackOpenedMsg reports a stack opened from the dashboard.
+type stackOpenedMsg struct {
+	res stack.Result
+	err error
+}
+
+// openStacks lists the saved stacks (S).
+func (m Model) openStacks() Model {
+	m.mode = modeStacks
+	m.stackPicker = stackPickerState{stacks: loadStacks()}
+	return m
+}
+
+func (m Model) selectedStack() (wsreg.Stack, bool) {
+	p := m.stackPicker
+	if p.cursor < 0 || p.cursor >= len(p.stacks) {
+		return wsreg.Stack{}, false
+	}
+	return p.stacks[p.cursor], true
+}
+
+func (m Model) handleStacks(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := &m.stackPicker
+	switch msg.String() {
+	case "esc", "q":
+		m.mode = modeNormal
+	case "j", "down":
+		if p.cursor < len(p.stacks)-1 {
+			p.cursor++
+		}
+		m.ensureStackVisible()
+	case "k", "up":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+		m.ensureStackVisible()
+	case keyEnter:
+		if _, ok := m.selectedStack(); !ok {
+			return m, nil
+		}
+		m.mode = modeStackBranch
+		m.stackInput.Prompt = "Branch: "
+		m.stackInput.Placeholder = "empty opens each repo's main worktree"
+		m.stackInput.SetValue("")
+		m.stackInput.Focus()
+		return m, textinput.Blink
+	case "tab":
+		s, ok := m.selectedStack()
+		if !ok {
+			return m, nil
+		}
+		s.Layout = stack.LayoutWindows
+		if stack.NormalizeLayout(p.stacks[p.cursor].Layout) == stack.LayoutWindows {
+			s.Layout = stack.LayoutSessions
+		}
+		if !saveStack(s) {
+			return m, m.pushToast("could not save stack "+s.Name, toastError)
+		}
+		p.stacks[p.cursor] = s
+	case "n":
+		if len(m.workspaces) == 0 {
+			return m, m.pushToast("no workspaces to stack", toastInfo)
+		}
+		m.mode = modeSaveStack
+		m.stackInput.Prompt = "Stack name: "
+		m.stackInput.Placeholder = strings.TrimPrefix(m.filterLabel(), "#")
+		m.stackInput.SetValue("")
+		m.stackInput.Focus()
+		return m, textinput.Blink
+	case "d":
+		s, ok := m.selectedStack()
+		if !ok {
+			return m, nil
+		}
+		if !deleteStack(s.Name) {
+			return m, m.pushToast("could not delete stack "+s.Name, toastError)
+		}
+		p.stacks = loadStacks()
+		if p.cursor >= len(p.stacks) {
+			p.cursor = len(p.stacks) - 1
+		}
+		return m, m.pushToast("deleted stack "+s.Name, toastInfo)
+	}
+	return m, nil
+}
+
+// handleSaveStack names a new stack made of the workspaces currently listed,
+// so a tag filter (#) is the quickest way to pick its members.
+func (m Model) handleSaveStack(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeStacks
+		m.stackInput.Blur()
+		return m, nil
+	case keyEnter:
+		name := strings.TrimSpace(m.stackInput.Value())
+		if name == "" {
+			name = m.stackInput.Placeholder
+		}
+		if name == "" {
+			return m, nil
+		}
+		m.stackInput.Blur()
+		m.mode = modeStacks
+		s := wsreg.Stack{Name: name, Layout: stack.LayoutSessions}
+		for _, w := range m.workspaces {
+			s.Workspaces = append(s.Workspaces, w.Path)
+		}
+		if !saveStack(s) {
+			return m, m.pushToast("could not save stack "+name, toastError)
+		}
+		m.stackPicker.stacks = loadStacks()
+		for i, st := range m.stackPicker.stacks {
+			if st.Name == name {
+				m.stackPicker.cursor = i
+			}
+		}
+		return m, m.pushToast(fmt.Sprintf("saved stack %s (%d workspaces)", name, len(s.Workspaces)), toastInfo)
+	}
+	var cmd tea.Cmd
+	m.stackInput, cmd = m.stackInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) handleStackBranch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeStacks
+		m.stackInput.Blur()
+		return m, nil
+	case keyEnter:
+		s, ok := m.selectedStack()
+		if !ok {
+			return m, nil
+		}
+		m.stackInput.Blur()
+		m.mode = modeStacks
+		return m, m.openStackCmd(s, m.stackInput.Value())
+	}
+	var cmd tea.Cmd
+	m.stackInput, cmd = m.stackInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) openStackCmd(s wsreg.Stack, branch string) tea.Cmd {
+	registry := make([]wsreg.Workspace, 0, len(m.allWorkspaces))
+	for _, w := range m.allWorkspaces {
+		registry = append(registry, wsreg.Workspace{Name: w.Name, Path: w.Path})
+	}
+	members, _ := stack.Members(s, registry)
+	svc := m.stats_svc
+	return func() tea.Msg {
+		res, err := openStack(s.Name, members, branch, s.Layout)
+		if err == nil && svc != nil {
+			for _, mem := range res.Members {
+				if mem.Created {
+					_ = svc.Invalidate(mem.Workspace)
+				}
+			}
+		}
+		return stackOpenedMsg{res: res, err: err}
+	}
+}
+
+func (m Model) handleStackOpened(msg stackOpenedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		return m, m.pushToast(msg.err.Error(), toastError)
+	}
+	m.mode = modeNormal
+	return m, m.switchTo(msg.res.Session)
+}
+
+func (m *Model) ensureStackVisible() {
+	visible := m.cleanupVisibleRows() / 2
+	if visible < 1 {
+		visible = 1
+	}
+	p := &m.stackPicker
+	if p.cursor < p.scroll {
+		p.scroll = p.cursor
+	}
+	if p.cursor >= p.scroll+visible {
+		p.scroll = p.cursor - visible + 1
+	}
+}
+
+func (m Model) viewStacks() string {
+	var b strings.Builder
+	innerW := m.innerWidth()
+
+	if m.mode == modeStacks {
+		b.WriteString(" " + theme.TreeGroupHeader.Render("Stacks"))
+	} else {
+		b.WriteString(" " + m.stackInput.View())
+	}
+	b.WriteString("\n")
+	mainSep := " " + theme.TreeConnector.Render(strings.Repeat("─", innerW))
+	b.WriteString(mainSep)
+	b.WriteString("\n")
+
+	avail := m.cleanupVisibleRows()
+	used := 0
+	p := m.stackPicker
+	names := make(map[string]string, len(m.allWorkspaces))
+	for _, w := range m.allWorkspaces {
+		names[w.Path] = w.Name
+	}
+	for i := p.scroll; i < len(p.stacks) && used+1 < avail; i++ {
+		b.WriteString(m.renderStackRow(p.stacks[i], i == p.cursor, names))
+		used += 2
+	}
+	if len(p.stacks) == 0 {
+		b.WriteString(" " + theme.HelpStyle.Render("no stacks yet; n saves the listed workspaces as one"))
+		b.WriteString("\n")
+		used++
+	}
+	padTo(&b, used, avail)
+
+	b.WriteString(mainSep)
+	b.WriteString("\n")
+	switch {
+	case m.toast != "":
+		b.WriteString(m.renderToast())
+	case m.mode == modeStackBranch:
+		b.WriteString(theme.HelpStyle.Render(" ⏎ open on this branch  esc back"))
+	case m.mode == modeSaveStack:
+		b.WriteString(theme.HelpStyle.Render(fmt.Sprintf(" ⏎ save the %d listed workspaces  esc back", len(m.workspaces))))
+	default:
+		b.WriteString(theme.HelpStyle.Render(" ⏎ open  tab layout  n new from list  d delete  esc back"))
+	}
+	return b.String()
+}
+
+// renderStackRow draws a stack on two lines: name, layout and the combined
+// summary, then its members. The summary covers the main worktrees, or the
+// branch being typed for the stack under the cursor.
+func (m Model) renderStackRow(s wsreg.Stack, cursor bool, names map[string]string) string {
+	branch := ""
+	if cursor && m.mode == modeStackBranch {
+		branch = m.stackInput.Value()
+	}
+	summary := stack.Summarize(s.Workspaces, branch, m.wsStats)
+	meta := theme.TreeMeta.Render(stack.NormalizeLayout(s.Layout) + "  " + summary.String())
+	var line string
+	if cursor {
+		line = fmt.Sprintf(" %s %s  %s", theme.PaletteItemSelected.Render("▸"), theme.TreeNodeSelected.Render(s.Name), meta)
+	} else {
+		line = fmt.Sprintf("   %s  %s", theme.TreeNodeNormal.Render(s.Name), meta)
+	}
+	members := make([]string, 0, len(s.Workspaces))
+	for _, p := range s.Workspaces {
+		if name, ok := names[p]; ok {
+			members = append(members, name)
+		} else {
+			members = append(members, filepath.Base(p)+"?")
+		}
+	}
+	return line + "\n     " + theme.HelpStyle.Render(strings.Join(members, " · ")) + "\n"
+}
@@ -0,0 +1,81 @@
+package workspaces
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/kitmux/internal/stack"
+	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+// stubStacks keeps stacks in memory and records opens.
+func stubStacks(t *testing.T) (*[]wsreg.Stack, *[]string) {
+	t.Helper()
+	prevLoad, prevSave, prevDelete, prevOpen := loadStacks, saveStack, deleteStack, openStack
+	t.Cleanup(func() { loadStacks, saveStack, deleteStack, openStack = prevLoad, prevSave, prevDelete, prevOpen })
+
+	var stacks []wsreg.Stack
+	var opened []string
+	loadStacks = func() []wsreg.Stack { return stacks }
+	saveStack = func(s wsreg.Stack) bool {
+		for i := range stacks {
+			if stacks[i].Name == s.Name {
+				stacks[i] = s
+				return true
+			}
+		}
+		stacks = append(stacks, s)
+		return true
+	}
+	deleteStack = func(string) bool { return true }
+	openStack = func(name string, members []stack.Workspace, branch, layout string) (stack.Result, error) {
+		for _, m := range members {
+			opened = append(opened, m.Name)
+		}
+		opened = append(opened, "branch="+branch, "layout="+layout)
+		return stack.Result{Session: name + "-" + branch}, nil
+	}
+	return &stacks, &opened
+}
+
+func TestStackFromListedWorkspacesOpensOnBranch(t *testing.T) {
+	stacks, opened := stubStacks(t)
+	m := newSeededModel()
+	m.wsStats = map[string]wsdata.WorkspaceStats{
+		"/home/user/kitmux": {Worktrees: []wsdata.WorktreeStat{
+			{Branch: "main", IsMain: true, Modified: true, Ahead: 2},
+			{Branch: "feat/pay", Behind: 4},
+		}},
+		"/home/user/api": {Worktrees: []wsdata.WorktreeStat{{Branch: "main", IsMain: true, Ahead: 1}}},
+	}
+
+	m = press(m, "S", "n")
+	if m.mode != modeSaveStack {
+		t.Fatalf("mode = %v", m.mode)
+	}
+	m = press(typeText(m, "all"), "enter", "tab")
+	if len(*stacks) != 1 || len((*stacks)[0].Workspaces) != 3 || (*stacks)[0].Layout != stack.LayoutWindows {
+		t.Fatalf("stacks = %+v", *stacks)
+	}
+	if view := m.View(); !strings.Contains(view, "3 repos  1 dirty  ↑3") || !strings.Contains(view, "kitmux · api · dotfiles") {
+		t.Fatalf("summary missing:\n%s", view)
+	}
+
+	m = press(m, "enter")
+	if m.mode != modeStackBranch {
+		t.Fatalf("mode = %v", m.mode)
+	}
+	m = typeText(m, "feat/pay")
+	if view := m.View(); !strings.Contains(view, "3 repos  ↓4") {
+		t.Fatalf("summary does not follow the typed branch:\n%s", view)
+	}
+	next, cmd := m.Update(keyMsg("enter"))
+	m = runCmd(t, next.(Model), cmd)
+	if strings.Join(*opened, ",") != "kitmux,api,dotfiles,branch=feat/pay,layout=windows" {
+		t.Fatalf("opened = %v", *opened)
+	}
+	if m.mode != modeNormal {
+		t.Fatalf("mode after open = %v", m.mode)
+	}
+}
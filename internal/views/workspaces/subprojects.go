@@ -0,0 +1,186 @@
+package workspaces
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/subproject"
+)
+
+// detectSubprojects lists the subprojects of a workspace; swapped in tests.
+var detectSubprojects = subproject.Detect
+
+// subprojectsLoadedMsg delivers the subprojects detected in each workspace.
+type subprojectsLoadedMsg struct {
+	subprojects map[string][]subproject.Subproject
+}
+
+// loadSubprojectsCmd detects subprojects in the root of every workspace.
+// Worktrees of a workspace share its layout, so the root stands in for all
+// of them.
+func loadSubprojectsCmd(workspaces []workspaceEntry) tea.Cmd {
+	if len(workspaces) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, len(workspaces))
+	for _, w := range workspaces {
+		paths = append(paths, w.Path)
+	}
+	return func() tea.Msg {
+		found := make(map[string][]subproject.Subproject, len(paths))
+		for _, p := range paths {
+			if subs := detectSubprojects(p); len(subs) > 0 {
+				found[p] = subs
+			}
+		}
+		return subprojectsLoadedMsg{subprojects: found}
+	}
+}
+
+func (m Model) handleSubprojectsLoaded(msg subprojectsLoadedMsg) (tea.Model, tea.Cmd) {
+	m.subprojects = msg.subprojects
+	m.refreshBranches()
+	return m, nil
+}
+
+// refreshBranches rebuilds the branch rows of the selected workspace and
+// keeps the cursor on the same row when it is still listed.
+func (m *Model) refreshBranches() {
+	if len(m.workspaces) == 0 {
+		return
+	}
+	current, _ := m.selectedBranch()
+	m.branches = m.buildBranches(m.workspaces[m.wsCursor])
+	m.detailItems = len(m.branches) + len(m.agentEntries)
+	for i, br := range m.branches {
+		if br.Path == current.Path && br.SessionName == current.SessionName {
+			m.detCursor = i
+		}
+	}
+	m.clampDetCursor()
+	m.ensureDetVisible()
+}
+
+// toggleSubprojects expands or collapses the subproject rows of the
+// worktree under the cursor.
+func (m Model) toggleSubprojects() (Model, tea.Cmd) {
+	br, ok := m.selectedBranch()
+	if !ok || m.focus != colDetail {
+		return m, nil
+	}
+	if br.Subproject != "" {
+		br = branchEntry{Path: br.Parent}
+	}
+	if len(m.workspaces) == 0 || len(m.subprojects[m.workspaces[m.wsCursor].Path]) == 0 {
+		return m, m.pushToast("no subprojects in this workspace", toastInfo)
+	}
+	if m.expanded == nil {
+		m.expanded = make(map[string]bool)
+	}
+	m.expanded[br.Path] = !m.expanded[br.Path]
+	m.refreshBranches()
+	if !m.expanded[br.Path] {
+		for i, b := range m.branches {
+			if b.Path == br.Path {
+				m.detCursor = i
+			}
+		}
+	}
+	return m, nil
+}
+
+// nestSubprojects moves sessions rooted inside another worktree of the list
+// under that worktree and, for expanded worktrees, adds a row per detected
+// subproject that has no session yet. Child rows carry no stats of their
+// own: git state belongs to the worktree.
+func (m *Model) nestSubprojects(ws workspaceEntry, entries []branchEntry) []branchEntry {
+	subs := m.subprojects[ws.Path]
+	children := make(map[string][]branchEntry)
+	var top, nested []branchEntry
+	for _, e := range entries {
+		if _, _, ok := parentWorktree(entries, e.Path); ok && e.IsSession {
+			nested = append(nested, e)
+		} else {
+			top = append(top, e)
+		}
+	}
+	for _, e := range nested {
+		parent, rel, _ := parentWorktree(top, e.Path)
+		e.Subproject = rel
+		if sp, ok := subproject.Containing(subs, rel); ok && sp.Rel != rel {
+			e.Subproject = sp.Rel
+		}
+		e.Name = rel
+		e.Parent = parent.Path
+		e.ParentBranch = parent.Name
+		e.DiffAdded, e.DiffDel, e.Ahead, e.Behind = 0, 0, 0, 0
+		e.Staged, e.Modified, e.Untracked, e.IsMain = false, false, false, false
+		children[parent.Path] = append(children[parent.Path], e)
+	}
+
+	out := make([]branchEntry, 0, len(entries))
+	for _, t := range top {
+		rows := children[t.Path]
+		if m.expanded[t.Path] {
+			listed := make(map[string]bool, len(rows))
+			for _, r := range rows {
+				listed[r.Subproject] = true
+			}
+			for _, sp := range subs {
+				if listed[sp.Rel] {
+					continue
+				}
+				p := filepath.Join(t.Path, filepath.FromSlash(sp.Rel))
+				rows = append(rows, branchEntry{
+					Name:         sp.Rel,
+					Path:         p,
+					Subproject:   sp.Rel,
+					Parent:       t.Path,
+					ParentBranch: t.Name,
+				})
+			}
+		} else {
+			t.Subprojects = len(subs)
+		}
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+		out = append(out, t)
+		out = append(out, rows...)
+	}
+	return out
+}
+
+// parentWorktree finds the entry whose path most closely contains p. Paths
+// reached through a hidden directory, such as worktrees kept in
+// .worktrees/, are not nested.
+func parentWorktree(entries []branchEntry, p string) (branchEntry, string, bool) {
+	var best branchEntry
+	rel := ""
+	for _, e := range entries {
+		if e.Path == "" || len(e.Path) <= len(best.Path) {
+			continue
+		}
+		r, ok := strings.CutPrefix(p, e.Path+string(filepath.Separator))
+		if !ok {
+			continue
+		}
+		r = filepath.ToSlash(r)
+		if strings.HasPrefix(r, ".") || strings.Contains(r, "/.") {
+			continue
+		}
+		best, rel = e, r
+	}
+	return best, rel, rel != ""
+}
+
+// sessionBranch is the name a new session for br is derived from: the
+// branch for worktrees, "<branch>-<dir>" for subprojects.
+func (br branchEntry) sessionBranch() string {
+	if br.Subproject == "" {
+		return br.Name
+	}
+	return br.ParentBranch + "-" + path.Base(br.Subproject)
+}
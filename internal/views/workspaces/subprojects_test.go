@@ -0,0 +1,51 @@
+package workspaces
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/kitmux/internal/subproject"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+)
+
+func TestSubprojectSessionsNestUnderTheirWorktree(t *testing.T) {
+	sessions := append(testSessions(), tmux.Session{Name: "kitmux-main-api", Path: "/home/user/kitmux/services/api/cmd", Windows: 1})
+	roots := testRepoRoots()
+	roots["kitmux-main-api"] = "/home/user/kitmux"
+	m := seedModel(testWorkspaces(), sessions, roots, testWtByPath(), testPanes())
+	next, _ := m.Update(subprojectsLoadedMsg{subprojects: map[string][]subproject.Subproject{
+		"/home/user/kitmux": {{Rel: "services/api"}, {Rel: "services/web"}},
+	}})
+	m = next.(Model)
+
+	var names []string
+	for _, br := range m.branches {
+		names = append(names, br.Name)
+	}
+	if strings.Join(names, ",") != "main,services/api/cmd,feature,experiment" {
+		t.Fatalf("branches = %v", names)
+	}
+	child := m.branches[1]
+	if child.Subproject != "services/api" || child.Parent != "/home/user/kitmux" || !child.IsSession {
+		t.Fatalf("child = %+v", child)
+	}
+	if m.branches[0].Subprojects != 2 || !strings.Contains(m.View(), "2 sub") {
+		t.Fatalf("collapsed count not shown:\n%s", m.View())
+	}
+
+	// space on the worktree lists every subproject; new sessions are named
+	// after the branch and the subproject directory.
+	m = press(m, "l", " ")
+	if len(m.branches) != 5 || m.branches[2].Name != "services/web" || m.branches[2].IsSession {
+		t.Fatalf("expanded = %+v", m.branches)
+	}
+	if got := m.branches[2].sessionBranch(); got != "main-web" {
+		t.Fatalf("sessionBranch = %q", got)
+	}
+	if m.branches[2].Path != "/home/user/kitmux/services/web" {
+		t.Fatalf("path = %q", m.branches[2].Path)
+	}
+	if items := press(m, "j", "j").availableActionItems(); items != nil {
+		t.Fatalf("subproject rows offer worktree actions: %+v", items)
+	}
+}
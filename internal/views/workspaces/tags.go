@@ -0,0 +1,373 @@
+package workspaces
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/miltonparedes/kitmux/internal/theme"
+	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
+)
+
+// Registry seams; swapped in tests.
+var (
+	setWorkspacePinned = wsreg.SetPinned
+	setWorkspaceTags   = wsreg.SetTags
+	loadTagFilters     = wsreg.LoadFilters
+	saveTagFilter      = wsreg.SaveFilter
+	deleteTagFilter    = wsreg.DeleteFilter
+)
+
+type tagRowKind int
+
+const (
+	tagRowAll tagRowKind = iota
+	tagRowPreset
+	tagRowTag
+)
+
+// tagRow is one line of the tag filter picker (#): clear the filter, a
+// saved preset, or a tag in use with the number of workspaces carrying it.
+type tagRow struct {
+	kind  tagRowKind
+	name  string
+	tags  []string
+	count int
+}
+
+type tagPickerState struct {
+	rows     []tagRow
+	cursor   int
+	scroll   int
+	selected map[string]bool
+}
+
+// SetTagFilter limits the dashboard to workspaces carrying any of tags.
+// Used by `kitmux workspaces --tag`.
+func (m *Model) SetTagFilter(tags []string) {
+	m.tagFilter = wsreg.ParseTags(strings.Join(tags, ","))
+	m.filterName = ""
+	m.applyTagFilter()
+}
+
+// applyTagFilter rebuilds the visible workspace list from allWorkspaces,
+// keeping the cursor on the same workspace when it is still listed.
+func (m *Model) applyTagFilter() {
+	current := ""
+	if m.wsCursor < len(m.workspaces) {
+		current = m.workspaces[m.wsCursor].Path
+	}
+	m.workspaces = m.workspaces[:0:0]
+	for _, w := range m.allWorkspaces {
+		if wsreg.HasAnyTag(w.Tags, m.tagFilter) {
+			m.workspaces = append(m.workspaces, w)
+		}
+	}
+	m.wsCursor = 0
+	for i, w := range m.workspaces {
+		if w.Path == current {
+			m.wsCursor = i
+		}
+	}
+	m.clampWorkspaceCursor()
+	m.ensureWorkspaceVisible()
+	m.applyWorkspaceSummary()
+	m.rebuildDetail()
+}
+
+// filterLabel names the active filter for the column header.
+func (m Model) filterLabel() string {
+	if len(m.tagFilter) == 0 {
+		return ""
+	}
+	if m.filterName != "" {
+		return m.filterName
+	}
+	return "#" + strings.Join(m.tagFilter, " #")
+}
+
+// updateWorkspace applies fn to the registry entry at path and refilters.
+func (m *Model) updateWorkspace(path string, fn func(*workspaceEntry)) {
+	for i := range m.allWorkspaces {
+		if m.allWorkspaces[i].Path == path {
+			fn(&m.allWorkspaces[i])
+		}
+	}
+	m.applyTagFilter()
+}
+
+func (m Model) togglePin() (Model, tea.Cmd) {
+	if len(m.workspaces) == 0 {
+		return m, nil
+	}
+	ws := m.workspaces[m.wsCursor]
+	if !setWorkspacePinned(ws.Path, !ws.Pinned) {
+		return m, m.pushToast("could not pin "+ws.Name, toastError)
+	}
+	m.updateWorkspace(ws.Path, func(w *workspaceEntry) { w.Pinned = !ws.Pinned })
+	verb := "pinned "
+	if ws.Pinned {
+		verb = "unpinned "
+	}
+	return m, tea.Batch(m.pushToast(verb+ws.Name, toastInfo), loadDataCmd(m.stats_svc))
+}
+
+func (m Model) startEditTags() (Model, tea.Cmd) {
+	if len(m.workspaces) == 0 {
+		return m, nil
+	}
+	m.tagsFor = m.workspaces[m.wsCursor]
+	m.mode = modeEditTags
+	m.tagInput.Prompt = "Tags: "
+	m.tagInput.Placeholder = "work, oss"
+	m.tagInput.SetValue(strings.Join(m.tagsFor.Tags, ", "))
+	m.tagInput.CursorEnd()
+	m.tagInput.Focus()
+	return m, textinput.Blink
+}
+
+func (m Model) handleEditTags(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeNormal
+		m.tagInput.Blur()
+		return m, nil
+	case keyEnter:
+		m.mode = modeNormal
+		m.tagInput.Blur()
+		ws := m.tagsFor
+		tags := wsreg.ParseTags(m.tagInput.Value())
+		if !setWorkspaceTags(ws.Path, tags) {
+			return m, m.pushToast("could not tag "+ws.Name, toastError)
+		}
+		m.updateWorkspace(ws.Path, func(w *workspaceEntry) { w.Tags = tags })
+		return m, loadDataCmd(m.stats_svc)
+	}
+	var cmd tea.Cmd
+	m.tagInput, cmd = m.tagInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) openTagFilter() Model {
+	m.mode = modeTagFilter
+	m.tagPicker = tagPickerState{rows: m.tagRows(), selected: map[string]bool{}}
+	if m.filterName == "" {
+		for _, tag := range m.tagFilter {
+			m.tagPicker.selected[tag] = true
+		}
+	}
+	return m
+}
+
+// tagRows lists "all", the saved presets, then every tag in use.
+func (m Model) tagRows() []tagRow {
+	rows := []tagRow{{kind: tagRowAll, name: "all workspaces", count: len(m.allWorkspaces)}}
+	for _, f := range loadTagFilters() {
+		rows = append(rows, tagRow{kind: tagRowPreset, name: f.Name, tags: f.Tags})
+	}
+	counts := map[string]int{}
+	for _, w := range m.allWorkspaces {
+		for _, tag := range w.Tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		rows = append(rows, tagRow{kind: tagRowTag, name: tag, tags: []string{tag}, count: counts[tag]})
+	}
+	return rows
+}
+
+// pickedTags returns the toggled tags, or the tag under the cursor when
+// none are toggled.
+func (m Model) pickedTags() []string {
+	var tags []string
+	for tag, on := range m.tagPicker.selected {
+		if on {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 && m.tagPicker.cursor < len(m.tagPicker.rows) {
+		if row := m.tagPicker.rows[m.tagPicker.cursor]; row.kind == tagRowTag {
+			tags = row.tags
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func (m Model) handleTagFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := &m.tagPicker
+	switch msg.String() {
+	case "esc", "q":
+		m.mode = modeNormal
+	case "j", "down":
+		if p.cursor < len(p.rows)-1 {
+			p.cursor++
+		}
+		m.ensureTagVisible()
+	case "k", "up":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+		m.ensureTagVisible()
+	case " ", "space":
+		if row := p.rows[p.cursor]; row.kind == tagRowTag {
+			p.selected[row.name] = !p.selected[row.name]
+		}
+	case keyEnter:
+		row := p.rows[p.cursor]
+		switch {
+		case row.kind == tagRowAll:
+			m.tagFilter, m.filterName = nil, ""
+		case row.kind == tagRowPreset:
+			m.tagFilter, m.filterName = row.tags, row.name
+		default:
+			m.tagFilter, m.filterName = m.pickedTags(), ""
+		}
+		m.mode = modeNormal
+		m.applyTagFilter()
+	case "s":
+		tags := m.pickedTags()
+		if len(tags) == 0 {
+			tags = m.tagFilter
+		}
+		if len(tags) == 0 {
+			return m, m.pushToast("pick tags to save first", toastInfo)
+		}
+		m.presetTags = tags
+		m.mode = modeSaveFilter
+		m.tagInput.Prompt = "Preset name: "
+		m.tagInput.Placeholder = strings.Join(tags, "+")
+		m.tagInput.SetValue("")
+		m.tagInput.Focus()
+		return m, textinput.Blink
+	case "d":
+		row := p.rows[p.cursor]
+		if row.kind != tagRowPreset {
+			return m, nil
+		}
+		if !deleteTagFilter(row.name) {
+			return m, m.pushToast("could not delete preset "+row.name, toastError)
+		}
+		if m.filterName == row.name {
+			m.filterName = ""
+		}
+		p.rows = m.tagRows()
+		if p.cursor >= len(p.rows) {
+			p.cursor = len(p.rows) - 1
+		}
+		return m, m.pushToast("deleted preset "+row.name, toastInfo)
+	}
+	return m, nil
+}
+
+func (m Model) handleSaveFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeTagFilter
+		m.tagInput.Blur()
+		return m, nil
+	case keyEnter:
+		name := strings.TrimSpace(m.tagInput.Value())
+		if name == "" {
+			name = m.tagInput.Placeholder
+		}
+		m.tagInput.Blur()
+		if !saveTagFilter(wsreg.Filter{Name: name, Tags: m.presetTags}) {
+			m.mode = modeTagFilter
+			return m, m.pushToast("could not save preset "+name, toastError)
+		}
+		m.mode = modeNormal
+		m.tagFilter, m.filterName = m.presetTags, name
+		m.applyTagFilter()
+		return m, m.pushToast("saved preset "+name, toastInfo)
+	}
+	var cmd tea.Cmd
+	m.tagInput, cmd = m.tagInput.Update(msg)
+	return m, cmd
+}
+
+func (m *Model) ensureTagVisible() {
+	visible := m.cleanupVisibleRows()
+	p := &m.tagPicker
+	if p.cursor < p.scroll {
+		p.scroll = p.cursor
+	}
+	if p.cursor >= p.scroll+visible {
+		p.scroll = p.cursor - visible + 1
+	}
+}
+
+func (m Model) viewTagFilter() string {
+	var b strings.Builder
+	innerW := m.innerWidth()
+
+	if m.mode == modeSaveFilter {
+		b.WriteString(" " + m.tagInput.View())
+	} else {
+		b.WriteString(" " + theme.TreeGroupHeader.Render("Filter workspaces by tag"))
+	}
+	b.WriteString("\n")
+	mainSep := " " + theme.TreeConnector.Render(strings.Repeat("─", innerW))
+	b.WriteString(mainSep)
+	b.WriteString("\n")
+
+	avail := m.cleanupVisibleRows()
+	used := 0
+	p := m.tagPicker
+	for i := p.scroll; i < len(p.rows) && used < avail; i++ {
+		b.WriteString(m.renderTagRow(p.rows[i], i == p.cursor))
+		b.WriteString("\n")
+		used++
+	}
+	if len(p.rows) == 1 {
+		b.WriteString(" " + theme.HelpStyle.Render("no tags yet; press t on a workspace to add some"))
+		b.WriteString("\n")
+		used++
+	}
+	padTo(&b, used, avail)
+
+	b.WriteString(mainSep)
+	b.WriteString("\n")
+	switch {
+	case m.toast != "":
+		b.WriteString(m.renderToast())
+	case m.mode == modeSaveFilter:
+		b.WriteString(theme.HelpStyle.Render(" ⏎ save preset " + strings.Join(m.presetTags, "+") + "  esc back"))
+	default:
+		b.WriteString(theme.HelpStyle.Render(" space toggle  ⏎ apply  s save preset  d delete preset  esc back"))
+	}
+	return b.String()
+}
+
+func (m Model) renderTagRow(row tagRow, cursor bool) string {
+	var label, meta string
+	switch row.kind {
+	case tagRowAll:
+		label = row.name
+		meta = fmt.Sprintf("%d", row.count)
+	case tagRowPreset:
+		label = "★ " + row.name
+		meta = "#" + strings.Join(row.tags, " #")
+	default:
+		box := "[ ]"
+		if m.tagPicker.selected[row.name] {
+			box = "[x]"
+		}
+		label = box + " #" + row.name
+		meta = fmt.Sprintf("%d", row.count)
+	}
+	if cursor {
+		return fmt.Sprintf(" %s %s  %s", theme.PaletteItemSelected.Render("▸"),
+			theme.TreeNodeSelected.Render(label), theme.TreeMeta.Render(meta))
+	}
+	return fmt.Sprintf("   %s  %s", theme.TreeNodeNormal.Render(label), theme.TreeMeta.Render(meta))
+}
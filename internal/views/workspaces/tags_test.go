@@ -0,0 +1,113 @@
+package workspaces
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
+)
+
+// stubTagRegistry keeps tag, pin and preset writes in memory.
+func stubTagRegistry(t *testing.T) *[]wsreg.Filter {
+	t.Helper()
+	prevPin, prevTags := setWorkspacePinned, setWorkspaceTags
+	prevLoad, prevSave, prevDelete := loadTagFilters, saveTagFilter, deleteTagFilter
+	t.Cleanup(func() {
+		setWorkspacePinned, setWorkspaceTags = prevPin, prevTags
+		loadTagFilters, saveTagFilter, deleteTagFilter = prevLoad, prevSave, prevDelete
+	})
+
+	var filters []wsreg.Filter
+	setWorkspacePinned = func(string, bool) bool { return true }
+	setWorkspaceTags = func(string, []string) bool { return true }
+	loadTagFilters = func() []wsreg.Filter { return filters }
+	saveTagFilter = func(f wsreg.Filter) bool {
+		filters = append(filters, f)
+		return true
+	}
+	deleteTagFilter = func(string) bool { return true }
+	return &filters
+}
+
+func typeText(m Model, text string) Model {
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+	return next.(Model)
+}
+
+func press(m Model, keys ...string) Model {
+	for _, k := range keys {
+		next, _ := m.Update(keyMsg(k))
+		m = next.(Model)
+	}
+	return m
+}
+
+func TestTagsFilterAndSavePreset(t *testing.T) {
+	filters := stubTagRegistry(t)
+	m := newSeededModel()
+
+	// Tag kitmux and api as work; api is also oss.
+	m = press(m, "t")
+	if m.mode != modeEditTags {
+		t.Fatalf("mode = %v", m.mode)
+	}
+	m = press(typeText(m, "Work"), "enter", "j", "t")
+	m = press(typeText(m, "work, oss"), "enter")
+	if got := m.allWorkspaces[1].Tags; strings.Join(got, ",") != "oss,work" {
+		t.Fatalf("api tags = %v", got)
+	}
+
+	// Filter on oss via the picker: all, oss, work.
+	m = press(m, "#")
+	if m.mode != modeTagFilter || len(m.tagPicker.rows) != 3 {
+		t.Fatalf("mode = %v, rows = %+v", m.mode, m.tagPicker.rows)
+	}
+	m = press(m, "j", "enter")
+	if len(m.workspaces) != 1 || m.workspaces[0].Name != "api" {
+		t.Fatalf("filtered = %+v", m.workspaces)
+	}
+	if !strings.Contains(m.View(), "Workspaces #oss") {
+		t.Fatalf("header does not name the filter:\n%s", m.View())
+	}
+
+	// oss stays toggled on reopening; add work and save both as a preset.
+	m = press(m, "#", "j", "j", " ", "s")
+	if m.mode != modeSaveFilter {
+		t.Fatalf("mode = %v", m.mode)
+	}
+	m = press(typeText(m, "day job"), "enter")
+	if len(*filters) != 1 || (*filters)[0].Name != "day job" || strings.Join((*filters)[0].Tags, ",") != "oss,work" {
+		t.Fatalf("filters = %+v", *filters)
+	}
+	if len(m.workspaces) != 2 || m.filterLabel() != "day job" {
+		t.Fatalf("workspaces = %d, label = %q", len(m.workspaces), m.filterLabel())
+	}
+
+	// esc clears the filter before quitting.
+	m = press(m, "esc")
+	if len(m.workspaces) != 3 || len(m.tagFilter) != 0 {
+		t.Fatalf("esc kept the filter: %d workspaces", len(m.workspaces))
+	}
+}
+
+func TestPinnedWorkspaceShowsMarker(t *testing.T) {
+	stubTagRegistry(t)
+	m := press(newSeededModel(), "j", "j", "p")
+	if !m.allWorkspaces[2].Pinned || !strings.Contains(m.View(), "dotfiles ★") {
+		t.Fatalf("pin not shown:\n%s", m.View())
+	}
+}
+
+func TestSetTagFilterAppliesOnLoad(t *testing.T) {
+	m := New()
+	m.SetTagFilter([]string{"Work"})
+	entries := testWorkspaces()
+	entries[1].Tags = []string{"work"}
+	next, _ := m.Update(dataLoadedMsg{workspaces: entries})
+	m = next.(Model)
+	if len(m.workspaces) != 1 || m.workspaces[0].Name != "api" {
+		t.Fatalf("workspaces = %+v", m.workspaces)
+	}
+}
@@ -2,26 +2,26 @@ package workspaces
 
 import (
 	"fmt"
-	"os/exec"
 	"path/filepath"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/sahilm/fuzzy"
 
 	"github.com/miltonparedes/kitmux/internal/agentlaunch"
 	"github.com/miltonparedes/kitmux/internal/agents"
+	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/frecency"
 	"github.com/miltonparedes/kitmux/internal/tmux"
 	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
 	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
 	"github.com/miltonparedes/kitmux/internal/worktree"
+	"github.com/miltonparedes/kitmux/internal/worktreesetup"
 )
 
 var (
-	workspaceAgentLaunchOps = agentlaunch.DefaultOps()
-	listWorkspaceSessions   = tmux.ListSessions
-	killWorkspaceSession    = tmux.KillSession
-	removeWorktreeInDir     = worktree.RemoveInDir
+	listWorkspaceSessions = tmux.ListSessions
+	killWorkspaceSession  = tmux.KillSession
+	removeWorktreeInDir   = worktree.RemoveInDir
 )
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -34,15 +34,31 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleDataLoaded(msg)
 	case statsLoadedMsg:
 		return m.handleStatsLoaded(msg)
+	case conflictsLoadedMsg:
+		return m.handleConflictsLoaded(msg)
 	case switchDoneMsg:
 		return m, tea.Quit
+	case cleanupPlannedMsg:
+		return m.handleCleanupPlanned(msg)
+	case cleanupDoneMsg:
+		return m.handleCleanupDone(msg)
+	case updatePlannedMsg:
+		return m.handleUpdatePlanned(msg)
+	case updateDoneMsg:
+		return m.handleUpdateDone(msg)
+	case stackOpenedMsg:
+		return m.handleStackOpened(msg)
+	case subprojectsLoadedMsg:
+		return m.handleSubprojectsLoaded(msg)
 	case actionDoneMsg:
 		return m, loadDataCmd(m.stats_svc)
-	case zoxideLoadedMsg:
-		m.zoxide.all = msg.entries
-		m.zoxide.filtered = msg.entries
-		m.zoxide.cursor = 0
-		m.zoxide.scroll = 0
+	case dirsLoadedMsg:
+		m.dirs.all = msg.entries
+		m.dirs.scores = msg.scores
+		m.dirs.loaded = true
+		m.dirs.filtered = msg.entries
+		m.dirs.cursor = 0
+		m.dirs.scroll = 0
 		return m, nil
 	case toastMsg:
 		return m, m.pushToast(msg.text, msg.level)
@@ -60,21 +76,21 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) handleDataLoaded(msg dataLoadedMsg) (tea.Model, tea.Cmd) {
-	m.workspaces = msg.workspaces
+	m.allWorkspaces = msg.workspaces
 	m.sessions = msg.sessions
 	m.repoRoots = msg.repoRoots
 	m.wtByPath = msg.wtByPath
 	m.panes = msg.panes
 	m.archived = msg.archived
-	m.clampWorkspaceCursor()
+	m.wsScores = msg.scores
+	m.agentScores = msg.agentScores
 	if m.stats_svc != nil && len(m.wsStats) == 0 {
 		if cached, err := m.stats_svc.LoadAllCached(); err == nil {
 			m.wsStats = cached
 		}
 	}
-	m.applyWorkspaceSummary()
-	m.rebuildDetail()
-	return m, refreshAllStatsCmd(m.stats_svc, m.workspaces)
+	m.applyTagFilter()
+	return m, tea.Batch(refreshAllStatsCmd(m.stats_svc, m.allWorkspaces), loadSubprojectsCmd(m.allWorkspaces))
 }
 
 func (m Model) handleStatsLoaded(msg statsLoadedMsg) (tea.Model, tea.Cmd) {
@@ -98,6 +114,18 @@ func (m Model) handleStatsLoaded(msg statsLoadedMsg) (tea.Model, tea.Cmd) {
 	}
 	m.applyWorkspaceSummary()
 	m.rebuildDetail()
+	return m, conflictsCmd(m.stats_svc, msg.wsStats)
+}
+
+func (m Model) handleConflictsLoaded(msg conflictsLoadedMsg) (tea.Model, tea.Cmd) {
+	if m.conflicts == nil {
+		m.conflicts = make(map[string]wsdata.WorkspaceConflicts, len(msg.conflicts))
+	}
+	for path, c := range msg.conflicts {
+		m.conflicts[path] = c
+	}
+	m.applyWorkspaceSummary()
+	m.rebuildDetail()
 	return m, nil
 }
 
@@ -109,6 +137,24 @@ func (m Model) routeKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleActionPicker(msg)
 	case modeHelp:
 		return m.handleHelp(msg)
+	case modeCleanup:
+		return m.handleCleanup(msg)
+	case modeConflicts:
+		return m.handleConflicts(msg)
+	case modeBulkUpdate:
+		return m.handleBulkUpdate(msg)
+	case modeEditTags:
+		return m.handleEditTags(msg)
+	case modeTagFilter:
+		return m.handleTagFilter(msg)
+	case modeSaveFilter:
+		return m.handleSaveFilter(msg)
+	case modeStacks:
+		return m.handleStacks(msg)
+	case modeStackBranch:
+		return m.handleStackBranch(msg)
+	case modeSaveStack:
+		return m.handleSaveStack(msg)
 	case modeFiltering:
 		return m.handleFilter(msg)
 	case modeWorkspaceSearch:
@@ -384,11 +430,11 @@ func (m Model) handleKeyAction(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
 		return m, textinput.Blink, true
 	case "n":
 		if m.focus == colWorkspaces {
-			return m.startZoxideSearch(), tea.Batch(textinput.Blink, loadZoxide()), true
+			return m.startDirSearch(), tea.Batch(textinput.Blink, loadDirs()), true
 		}
 		return m, nil, true
 	case "f":
-		return m.startZoxideSearch(), tea.Batch(textinput.Blink, loadZoxide()), true
+		return m.startDirSearch(), tea.Batch(textinput.Blink, loadDirs()), true
 	case "a":
 		return m.startAgentAttach(agentTargetWindow), nil, true
 	case "A":
@@ -405,18 +451,43 @@ func (m Model) handleKeyAction(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
 			return m, textinput.Blink, true
 		}
 		return m, nil, true
+	case "C":
+		model, cmd := m.startCleanup()
+		return model, cmd, true
+	case "U":
+		model, cmd := m.startBulkUpdate()
+		return model, cmd, true
+	case "p":
+		model, cmd := m.togglePin()
+		return model, cmd, true
+	case "t":
+		model, cmd := m.startEditTags()
+		return model, cmd, true
+	case "#":
+		return m.openTagFilter(), nil, true
+	case "S":
+		return m.openStacks(), nil, true
+	case "!":
+		model, cmd := m.openConflicts()
+		return model, cmd, true
+	case "v":
+		return m, m.openDiff(), true
+	case " ", "space":
+		model, cmd := m.toggleSubprojects()
+		return model, cmd, true
 	}
 	return m, nil, false
 }
 
-func (m Model) startZoxideSearch() Model {
+func (m Model) startDirSearch() Model {
 	m.mode = modeWorkspaceSearch
-	m.zoxide.input.SetValue("")
-	m.zoxide.input.Focus()
-	m.zoxide.all = nil
-	m.zoxide.filtered = nil
-	m.zoxide.cursor = 0
-	m.zoxide.scroll = 0
+	m.dirs.input.SetValue("")
+	m.dirs.input.Focus()
+	m.dirs.all = nil
+	m.dirs.filtered = nil
+	m.dirs.loaded = false
+	m.dirs.cursor = 0
+	m.dirs.scroll = 0
 	return m
 }
 
@@ -437,6 +508,11 @@ func (m Model) handleKeySystem(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
 			m.focus = colWorkspaces
 			return m, nil, true
 		}
+		if msg.String() == "esc" && len(m.tagFilter) > 0 {
+			m.tagFilter, m.filterName = nil, ""
+			m.applyTagFilter()
+			return m, nil, true
+		}
 		return m, tea.Quit, true
 	}
 	return m, nil, false
@@ -464,12 +540,16 @@ func (m Model) moveCursor(delta int) (tea.Model, tea.Cmd) {
 func (m Model) activateDetailItem() (tea.Model, tea.Cmd) {
 	if m.detCursor < len(m.branches) {
 		b := m.branches[m.detCursor]
+		var used tea.Cmd
+		if len(m.workspaces) > 0 {
+			used = recordUseCmd(frecency.KindWorkspace, m.workspaces[m.wsCursor].Path)
+		}
 		if b.IsSession && b.SessionName != "" {
-			return m, m.switchTo(b.SessionName)
+			return m, tea.Batch(used, m.switchTo(b.SessionName))
 		}
 		if len(m.workspaces) > 0 {
 			proj := m.workspaces[m.wsCursor]
-			return m, m.openWorktreeSession(proj.Name, b)
+			return m, tea.Batch(used, m.openWorktreeSession(proj.Name, b))
 		}
 		return m, nil
 	}
@@ -503,7 +583,7 @@ func (m Model) handleFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.mode = modeNormal
 		m.filter.Blur()
 		// Accept: if a single filtered row matches, snap the cursor to it.
-		matches := filteredWorkspaceIndices(m.workspaces, m.filter.Value())
+		matches := filteredWorkspaceIndices(m.workspaces, m.filter.Value(), m.wsScores)
 		if len(matches) > 0 {
 			m.wsCursor = matches[0]
 			m.clampWorkspaceCursor()
@@ -525,7 +605,9 @@ func (m Model) handleFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func filteredWorkspaceIndices(workspaces []workspaceEntry, query string) []int {
+// filteredWorkspaceIndices lists the workspaces matching query, most used
+// first among similar matches. An empty query keeps the dashboard order.
+func filteredWorkspaceIndices(workspaces []workspaceEntry, query string, scores frecency.Scores) []int {
 	if query == "" {
 		out := make([]int, len(workspaces))
 		for i := range workspaces {
@@ -534,19 +616,16 @@ func filteredWorkspaceIndices(workspaces []workspaceEntry, query string) []int {
 		return out
 	}
 	names := make([]string, len(workspaces))
+	paths := make([]string, len(workspaces))
 	for i, w := range workspaces {
 		names[i] = w.Name
+		paths[i] = w.Path
 	}
-	matches := fuzzy.Find(query, names)
-	out := make([]int, 0, len(matches))
-	for _, m := range matches {
-		out = append(out, m.Index)
-	}
-	return out
+	return frecency.Rank(query, names, paths, scores)
 }
 
 func (m *Model) moveFilteredCursor(delta int) {
-	idxs := filteredWorkspaceIndices(m.workspaces, m.filter.Value())
+	idxs := filteredWorkspaceIndices(m.workspaces, m.filter.Value(), m.wsScores)
 	if len(idxs) == 0 {
 		return
 	}
@@ -571,7 +650,7 @@ func (m *Model) moveFilteredCursor(delta int) {
 }
 
 func (m *Model) clampFilteredCursor() {
-	idxs := filteredWorkspaceIndices(m.workspaces, m.filter.Value())
+	idxs := filteredWorkspaceIndices(m.workspaces, m.filter.Value(), m.wsScores)
 	if len(idxs) == 0 {
 		return
 	}
@@ -586,7 +665,7 @@ func (m *Model) clampFilteredCursor() {
 	m.rebuildDetail()
 }
 
-// -------- Project search (zoxide) --------
+// -------- Project search (discovered directories) --------
 
 func (m Model) handleWorkspaceSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
@@ -594,34 +673,35 @@ func (m Model) handleWorkspaceSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.mode = modeNormal
 		return m, nil
 	case keyEnter:
-		if sel := m.zoxide.selected(); sel != nil {
+		if sel := m.dirs.selected(); sel != nil {
 			path := sel.Path
 			name := filepath.Base(path)
 			added := wsreg.AddWorkspace(name, path)
 			m.mode = modeNormal
+			used := recordUseCmd(frecency.KindDir, path)
 			if !added {
 				// Path already tracked; just focus it.
-				return m, tea.Batch(loadDataCmd(m.stats_svc), func() tea.Msg {
+				return m, tea.Batch(used, loadDataCmd(m.stats_svc), func() tea.Msg {
 					return toastMsg{text: "already registered: " + name, level: toastInfo}
 				})
 			}
-			return m, loadDataCmd(m.stats_svc)
+			return m, tea.Batch(used, loadDataCmd(m.stats_svc))
 		}
 		return m, nil
 	case "up", "ctrl+k":
-		m.zoxide.cursor--
-		m.zoxide.clampCursor()
-		m.zoxide.ensureVisible(m.height - 4)
+		m.dirs.cursor--
+		m.dirs.clampCursor()
+		m.dirs.ensureVisible(m.height - 4)
 		return m, nil
 	case "down", "ctrl+j":
-		m.zoxide.cursor++
-		m.zoxide.clampCursor()
-		m.zoxide.ensureVisible(m.height - 4)
+		m.dirs.cursor++
+		m.dirs.clampCursor()
+		m.dirs.ensureVisible(m.height - 4)
 		return m, nil
 	}
 	var cmd tea.Cmd
-	m.zoxide.input, cmd = m.zoxide.input.Update(msg)
-	m.zoxide.filter()
+	m.dirs.input, cmd = m.dirs.input.Update(msg)
+	m.dirs.filter()
 	return m, cmd
 }
 
@@ -649,7 +729,7 @@ func (m Model) handleNewBranch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.mode = modeNewBranchAgent
 		m.agentPickerIntent = agentIntentNewWorktreeAgent
 		m.agentPickerTarget = agentTargetWindow
-		m.agentPicker.cursor = 0
+		m.agentPicker = m.agentPicker.withRepoDefaults(m.newBranchWs.Path, m.agentScores)
 		return m, nil
 	}
 	var cmd tea.Cmd
@@ -696,7 +776,8 @@ func (m Model) handleNewBranchAgent(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		a := m.agentPicker.agents[m.agentPicker.cursor]
 		mode := a.Modes[m.agentPicker.modeIndex[m.agentPicker.cursor]]
-		return m, m.createWorktreeAndOpen(m.newBranchWs.Name, m.newBranchWs.Path, branch, &a, mode)
+		return m, tea.Batch(recordUseCmd(frecency.KindAgent, a.ID),
+			m.createWorktreeAndOpen(m.newBranchWs.Name, m.newBranchWs.Path, branch, &a, mode))
 	}
 	return m, nil
 }
@@ -765,7 +846,7 @@ func (m Model) deleteWorktree(workspacePath, worktreePath, branch string) tea.Cm
 			}
 		}
 		if err := removeWorktreeInDir(workspacePath, branch); err != nil {
-			return toastMsg{text: "wt remove failed: " + err.Error(), level: toastError}
+			return toastMsg{text: "remove worktree failed: " + err.Error(), level: toastError}
 		}
 		_ = wsreg.RemoveArchivedWorktree(workspacePath, worktreePath)
 		if svc != nil {
@@ -813,7 +894,7 @@ func (m Model) availableActionItems() []actionMenuItem {
 		return nil
 	}
 	br := m.branches[m.detCursor]
-	if br.IsMain || isMainBranch(br.Name) {
+	if br.IsMain || isMainBranch(br.Name) || br.Subproject != "" {
 		return nil
 	}
 
@@ -913,6 +994,23 @@ func (m Model) dispatchDeleteWorktreeAction() (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// openDiff opens the diff viewer on the selected worktree, or on the
+// selected workspace from the workspaces column.
+func (m Model) openDiff() tea.Cmd {
+	dir := ""
+	if m.focus == colDetail {
+		if br, ok := m.selectedBranch(); ok {
+			dir = br.Path
+		}
+	} else if m.wsCursor >= 0 && m.wsCursor < len(m.workspaces) {
+		dir = m.workspaces[m.wsCursor].Path
+	}
+	if dir == "" {
+		return nil
+	}
+	return func() tea.Msg { return messages.OpenDiffMsg{Dir: dir} }
+}
+
 func (m Model) selectedBranch() (branchEntry, bool) {
 	if m.detCursor < 0 || m.detCursor >= len(m.branches) {
 		return branchEntry{}, false
@@ -961,7 +1059,8 @@ func (m Model) handleAgentPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		a := m.agentPicker.agents[m.agentPicker.cursor]
 		mode := a.Modes[m.agentPicker.modeIndex[m.agentPicker.cursor]]
 		m.mode = modeNormal
-		return m, m.attachAgentToBranch(m.attachBranch, a, mode, m.agentPickerTarget)
+		return m, tea.Batch(recordUseCmd(frecency.KindAgent, a.ID),
+			m.attachAgentToBranch(m.attachBranch, a, mode, m.agentPickerTarget))
 	}
 	return m, nil
 }
@@ -989,7 +1088,7 @@ func (m Model) startAgentAttach(target agentTarget) tea.Model {
 // openAgentPickerFor prepares the picker to attach an agent to `br`.
 func (m Model) openAgentPickerFor(br branchEntry, target agentTarget) Model {
 	m.mode = modeAgentPicker
-	m.agentPicker.cursor = 0
+	m.agentPicker = m.agentPicker.withRepoDefaults(br.Path, m.agentScores)
 	m.agentPickerIntent = agentIntentAttachBranch
 	m.agentPickerTarget = target
 	m.attachBranch = br
@@ -1078,7 +1177,7 @@ func (m Model) openWorktreeSession(project string, b branchEntry) tea.Cmd {
 		if b.Path == "" {
 			return toastMsg{text: "worktree missing path", level: toastWarn}
 		}
-		sessName, _, err := ensureSessionForPath(project, b.Name, b.Path)
+		sessName, _, err := ensureSessionForPath(project, b.sessionBranch(), b.Path, nil)
 		if err != nil {
 			return toastMsg{text: err.Error(), level: toastError}
 		}
@@ -1087,22 +1186,23 @@ func (m Model) openWorktreeSession(project string, b branchEntry) tea.Cmd {
 	}
 }
 
-// createWorktreeAndOpen runs `wt switch --create <branch>` in projPath, then
+// createWorktreeAndOpen creates a worktree for branch in projPath, then
 // ensures exactly one tmux session lives at the resulting worktree path.
 // If a session already points at that path (e.g. the worktree or branch
 // pre-existed) we reuse it instead of spawning a `-2` duplicate. When the
 // caller also requested an agent we add a dedicated window for it — the
 // existing window 0 is left alone so we never clobber a running shell.
-// Worktree placement is fully delegated to worktrunk.
+// Worktree placement is delegated to the worktree backend.
 func (m Model) createWorktreeAndOpen(project, projPath, branch string, agent *agents.Agent, mode agents.AgentMode) tea.Cmd {
 	svc := m.stats_svc
 	return func() tea.Msg {
-		if err := ensureWorktreeBranch(projPath, branch); err != nil {
+		created, err := ensureWorktreeBranch(projPath, branch)
+		if err != nil {
 			return toastMsg{text: err.Error(), level: toastError}
 		}
 		wts, err := worktree.ListInDir(projPath)
 		if err != nil {
-			return toastMsg{text: "wt list failed: " + err.Error(), level: toastError}
+			return toastMsg{text: "list worktrees failed: " + err.Error(), level: toastError}
 		}
 		if svc != nil {
 			_ = svc.Invalidate(projPath)
@@ -1111,17 +1211,31 @@ func (m Model) createWorktreeAndOpen(project, projPath, branch string, agent *ag
 		if !ok {
 			return actionDoneMsg{}
 		}
-		return attachSessionAndAgent(project, branch, wt, agent, mode)
+		return openWorktree(project, branch, wt, created, agent, mode)
 	}
 }
 
-// ensureWorktreeBranch creates the branch if it doesn't exist yet.
+// openWorktree runs the repo's setup steps on a freshly created worktree
+// before opening its session and agent, so nothing starts in a worktree
+// that is not provisioned yet. The session is created with the variables
+// the env steps set, so every pane sees them.
+func openWorktree(project, branch string, wt worktree.Worktree, created bool, agent *agents.Agent, mode agents.AgentMode) tea.Msg {
+	var env map[string]string
+	if created {
+		env, _ = worktreesetup.Provision(tmux.Default(), wt.Path)
+	}
+	_, msg := attachSessionAndAgent(project, branch, wt, env, agent, mode)
+	return msg
+}
+
+// ensureWorktreeBranch creates the branch if it doesn't exist yet and
+// reports whether a new worktree was added.
 // We probe first because `wt switch --create` against an existing branch can
 // generate sibling directories with numeric suffixes.
-func ensureWorktreeBranch(projPath, branch string) error {
+func ensureWorktreeBranch(projPath, branch string) (bool, error) {
 	existing, err := worktree.ListInDir(projPath)
 	if err != nil {
-		return fmt.Errorf("wt list failed: %w", err)
+		return false, fmt.Errorf("list worktrees failed: %w", err)
 	}
 	alreadyExists := false
 	for _, wt := range existing {
@@ -1130,17 +1244,10 @@ func ensureWorktreeBranch(projPath, branch string) error {
 			break
 		}
 	}
-	args := []string{"switch", "--no-cd"}
-	if !alreadyExists {
-		args = append(args, "--create")
+	if err := worktree.SwitchInDir(projPath, branch, !alreadyExists); err != nil {
+		return false, fmt.Errorf("switch worktree failed: %w", err)
 	}
-	args = append(args, branch)
-	cmd := exec.Command("wt", args...)
-	cmd.Dir = projPath
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("wt switch failed: %w", err)
-	}
-	return nil
+	return !alreadyExists, nil
 }
 
 func findWorktreeByBranch(wts []worktree.Worktree, branch string) (worktree.Worktree, bool) {
@@ -1152,18 +1259,18 @@ func findWorktreeByBranch(wts []worktree.Worktree, branch string) (worktree.Work
 	return worktree.Worktree{}, false
 }
 
-func attachSessionAndAgent(project, branch string, wt worktree.Worktree, agent *agents.Agent, mode agents.AgentMode) tea.Msg {
-	sessName, freshSession, err := ensureSessionForPath(project, branch, wt.Path)
+func attachSessionAndAgent(project, branch string, wt worktree.Worktree, env map[string]string, agent *agents.Agent, mode agents.AgentMode) (string, tea.Msg) {
+	sessName, freshSession, err := ensureSessionForPath(project, branch, wt.Path, env)
 	if err != nil {
-		return toastMsg{text: err.Error(), level: toastError}
+		return "", toastMsg{text: err.Error(), level: toastError}
 	}
 	if agent != nil {
 		if err := spawnAgentForSession(sessName, wt.Path, *agent, mode, freshSession); err != nil {
-			return toastMsg{text: err.Error(), level: toastError}
+			return sessName, toastMsg{text: err.Error(), level: toastError}
 		}
 	}
 	_ = tmux.SwitchClient(sessName)
-	return switchDoneMsg{}
+	return sessName, switchDoneMsg{}
 }
 
 func spawnAgentForSession(sessName, worktreePath string, agent agents.Agent, mode agents.AgentMode, freshSession bool) error {
@@ -1176,14 +1283,14 @@ func spawnAgentForSession(sessName, worktreePath string, agent agents.Agent, mod
 		Target:        agentlaunch.TargetWindow,
 		FreshSession:  freshSession,
 		OpenSidepanel: true,
-	}, workspaceAgentLaunchOps)
+	}, tmux.Default())
 }
 
 // ensureSessionForPath returns the tmux session that already points at
-// worktreePath, or creates one named "<project>-<branch>" when none exists.
-// The boolean indicates whether a new session was created (true) or an
-// existing one was reused (false).
-func ensureSessionForPath(project, branch, worktreePath string) (string, bool, error) {
+// worktreePath, or creates one named "<project>-<branch>" with env when
+// none exists. The boolean indicates whether a new session was created
+// (true) or an existing one was reused (false).
+func ensureSessionForPath(project, branch, worktreePath string, env map[string]string) (string, bool, error) {
 	if sessions, err := tmux.ListSessions(); err == nil {
 		for _, s := range sessions {
 			if s.Path == worktreePath {
@@ -1192,7 +1299,7 @@ func ensureSessionForPath(project, branch, worktreePath string) (string, bool, e
 		}
 	}
 	sessName := uniqueSessName(project + "-" + branch)
-	if err := tmux.NewSessionInDir(sessName, worktreePath); err != nil {
+	if err := tmux.NewSessionWithEnv(sessName, worktreePath, env); err != nil {
 		return "", false, fmt.Errorf("tmux new-session failed: %w", err)
 	}
 	return sessName, true, nil
@@ -1211,7 +1318,7 @@ func (m Model) attachAgentToBranch(br branchEntry, agent agents.Agent, mode agen
 	if dir == "" {
 		dir = proj.Path
 	}
-	branchName := br.Name
+	branchName := br.sessionBranch()
 	if branchName == "" {
 		branchName = "shell"
 	}
@@ -1219,7 +1326,7 @@ func (m Model) attachAgentToBranch(br branchEntry, agent agents.Agent, mode agen
 
 	return func() tea.Msg {
 		if sessName == "" {
-			resolved, fresh, err := ensureSessionForPath(proj.Name, branchName, dir)
+			resolved, fresh, err := ensureSessionForPath(proj.Name, branchName, dir, nil)
 			if err != nil {
 				return toastMsg{text: err.Error(), level: toastError}
 			}
@@ -1257,7 +1364,7 @@ func launchWorkspaceSessionAgent(
 		Target:        launchTarget(target),
 		FreshSession:  freshSession,
 		OpenSidepanel: true,
-	}, workspaceAgentLaunchOps)
+	}, tmux.Default())
 }
 
 func launchTarget(target agentTarget) agentlaunch.Target {
@@ -5,6 +5,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/miltonparedes/kitmux/internal/app/messages"
+	"github.com/miltonparedes/kitmux/internal/commitmsg"
 	"github.com/miltonparedes/kitmux/internal/config"
 	"github.com/miltonparedes/kitmux/internal/worktree"
 )
@@ -202,9 +203,18 @@ func (m Model) handleWorktreeAction(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
 		}
 		return m, nil, true
 	case "m":
-		return m, popupCmd("wt merge"), true
+		return m, popupCmd(worktree.Current().MergeCommand()), true
 	case "c":
-		return m, popupCmd("wt step commit"), true
+		if _, ok := commitmsg.FromRepo(config.CurrentRepo()); ok {
+			return m, func() tea.Msg { return messages.OpenCommitMsg{} }, true
+		}
+		return m, popupCmd(worktree.Current().CommitCommand()), true
+	case "v":
+		if wt := m.selected(); wt != nil {
+			dir := wt.Path
+			return m, func() tea.Msg { return messages.OpenDiffMsg{Dir: dir} }, true
+		}
+		return m, nil, true
 	}
 	return m, nil, false
 }
@@ -248,7 +258,7 @@ func (m Model) handleDescribe(msg tea.KeyMsg) (Model, tea.Cmd) {
 		m.describing = false
 		desc := m.describeInput.Value()
 		if desc != "" {
-			branch := worktree.GenerateBranchName(desc)
+			branch := worktree.GenerateBranchNameWithPrefixes(desc, config.BranchPrefixes())
 			m.branchInput.SetValue(branch)
 			m.confirmingBranch = true
 			m.branchInput.Focus()
@@ -311,7 +321,7 @@ func (m Model) handleConfirm(msg tea.KeyMsg) (Model, tea.Cmd) {
 		if wt := m.selected(); wt != nil {
 			branch := wt.Branch
 			return m, func() tea.Msg {
-				return messages.RemoveWorktreeMsg{Branch: branch}
+				return messages.RemoveWorktreeMsg{Branch: branch, Source: "worktrees"}
 			}
 		}
 	case "n", "N", "esc":
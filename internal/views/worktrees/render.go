@@ -88,7 +88,7 @@ func (m Model) StatusLine() string {
 	if m.creating {
 		return " " + m.newInput.View()
 	}
-	return theme.HelpStyle.Render(" ⏎ switch  n new  N describe  d rm  q quit")
+	return theme.HelpStyle.Render(" ⏎ switch  n new  N describe  v diff  d rm  q quit")
 }
 
 func renderWorktree(w *worktree.Worktree, selected bool) string {
@@ -0,0 +1,222 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Conflict is a predicted merge conflict between a worktree's branch and
+// either the base branch (OtherPath empty) or another worktree's branch.
+type Conflict struct {
+	Path        string
+	Branch      string
+	OtherPath   string
+	OtherBranch string
+	Files       []string
+}
+
+// WithBase reports whether the conflict is against the base branch.
+func (c Conflict) WithBase() bool {
+	return c.OtherPath == ""
+}
+
+// WorkspaceConflicts holds the conflicts predicted between the committed
+// state of a workspace's worktrees. Uncommitted changes are not considered.
+type WorkspaceConflicts struct {
+	Path      string
+	Base      string // branch of the main worktree
+	Conflicts []Conflict
+	Err       error
+}
+
+// For returns the conflicts involving the worktree at path, each oriented so
+// that Path is the worktree itself. Base conflicts come first.
+func (w WorkspaceConflicts) For(path string) []Conflict {
+	var out []Conflict
+	for _, c := range w.Conflicts {
+		switch path {
+		case c.Path:
+			out = append(out, c)
+		case c.OtherPath:
+			out = append(out, Conflict{
+				Path:        c.OtherPath,
+				Branch:      c.OtherBranch,
+				OtherPath:   c.Path,
+				OtherBranch: c.Branch,
+				Files:       c.Files,
+			})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].WithBase() && !out[j].WithBase() })
+	return out
+}
+
+// conflictGit is the git plumbing conflict prediction needs. Injected so
+// tests can avoid real repositories.
+type conflictGit struct {
+	// mergeTree returns the files that conflict when merging b into a.
+	mergeTree func(dir, a, b string) ([]string, error)
+	// isAncestor reports whether a is already contained in b.
+	isAncestor func(dir, a, b string) bool
+}
+
+var defaultConflictGit = conflictGit{mergeTree: gitMergeTree, isAncestor: gitIsAncestor}
+
+// mergeCache memoizes merge-tree and ancestry results by commit pair, which
+// pins down a branch pair at its current tips. Commits are immutable, so
+// entries never go stale; they only pile up, and a dashboard session sees
+// few enough commits for that not to matter.
+type mergeCache struct {
+	mu        sync.Mutex
+	files     map[[2]string][]string
+	ancestors map[[2]string]bool // keyed {a, b}: is a contained in b
+}
+
+func (c *mergeCache) get(a, b string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	files, ok := c.files[mergeKey(a, b)]
+	return files, ok
+}
+
+func (c *mergeCache) put(a, b string, files []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.files == nil {
+		c.files = make(map[[2]string][]string)
+	}
+	c.files[mergeKey(a, b)] = files
+}
+
+func (c *mergeCache) ancestor(a, b string) (contained, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	contained, ok = c.ancestors[[2]string{a, b}]
+	return contained, ok
+}
+
+func (c *mergeCache) putAncestor(a, b string, contained bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ancestors == nil {
+		c.ancestors = make(map[[2]string]bool)
+	}
+	c.ancestors[[2]string{a, b}] = contained
+}
+
+func mergeKey(a, b string) [2]string {
+	if b < a {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// Conflicts predicts which worktrees of ws conflict with the base branch or
+// with each other, using `git merge-tree` on their current commits. Nothing
+// is checked out or written to the repository. Worktrees whose commit is
+// already in the base branch have nothing left to merge and are skipped.
+func (s *StatsService) Conflicts(ws WorkspaceStats) WorkspaceConflicts {
+	out := WorkspaceConflicts{Path: ws.Path}
+	var base WorktreeStat
+	for _, wt := range ws.Worktrees {
+		if wt.IsMain {
+			base = wt
+		}
+	}
+	if base.CommitSHA == "" {
+		return out
+	}
+	out.Base = base.Branch
+
+	var pending []WorktreeStat
+	for _, wt := range ws.Worktrees {
+		if wt.IsMain || wt.CommitSHA == "" || wt.CommitSHA == base.CommitSHA {
+			continue
+		}
+		if s.isAncestor(ws.Path, wt.CommitSHA, base.CommitSHA) {
+			continue
+		}
+		pending = append(pending, wt)
+	}
+
+	check := func(a, b WorktreeStat, withBase bool) error {
+		files, ok := s.merges.get(a.CommitSHA, b.CommitSHA)
+		if !ok {
+			var err error
+			if files, err = s.git.mergeTree(ws.Path, a.CommitSHA, b.CommitSHA); err != nil {
+				return err
+			}
+			s.merges.put(a.CommitSHA, b.CommitSHA, files)
+		}
+		if len(files) == 0 {
+			return nil
+		}
+		c := Conflict{Path: b.WorktreePath, Branch: b.Branch, Files: files}
+		if !withBase {
+			c.OtherPath, c.OtherBranch = a.WorktreePath, a.Branch
+		}
+		out.Conflicts = append(out.Conflicts, c)
+		return nil
+	}
+	for i, wt := range pending {
+		if err := check(base, wt, true); err != nil {
+			out.Err = err
+			return out
+		}
+		for _, other := range pending[i+1:] {
+			if err := check(other, wt, false); err != nil {
+				out.Err = err
+				return out
+			}
+		}
+	}
+	return out
+}
+
+// isAncestor reports whether commit a is already contained in b, asking
+// git only the first time a pair is seen.
+func (s *StatsService) isAncestor(dir, a, b string) bool {
+	if contained, ok := s.merges.ancestor(a, b); ok {
+		return contained
+	}
+	contained := s.git.isAncestor(dir, a, b)
+	s.merges.putAncestor(a, b, contained)
+	return contained
+}
+
+// gitMergeTree runs a merge of a and b entirely in memory (git 2.38+). Exit
+// status 1 means the merge has conflicts; the conflicted paths follow the
+// resulting tree's ID in the output.
+func gitMergeTree(dir, a, b string) ([]string, error) {
+	cmd := exec.Command("git", "-C", dir, "merge-tree", "--write-tree", "--name-only", "--no-messages", a, b)
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return nil, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+	default:
+		return nil, fmt.Errorf("git merge-tree: %w", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	seen := make(map[string]bool)
+	var files []string
+	for _, line := range lines[1:] {
+		if line == "" {
+			break
+		}
+		if !seen[line] {
+			seen[line] = true
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+func gitIsAncestor(dir, a, b string) bool {
+	return exec.Command("git", "-C", dir, "merge-base", "--is-ancestor", a, b).Run() == nil
+}
@@ -0,0 +1,124 @@
+package data
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		t.Fatalf("git %v: %v", args, err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestConflictsPredictsPairsAndBase(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := filepath.Join(t.TempDir(), "app")
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+	} {
+		runGit(t, root, args...)
+	}
+	writeTestFile(t, root, "a.txt", "one\n")
+	writeTestFile(t, root, "b.txt", "one\n")
+	runGit(t, root, "add", "-A")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+
+	commitOn := func(branch, file, content string) string {
+		runGit(t, root, "checkout", "-q", "-b", branch, "main")
+		writeTestFile(t, root, file, content)
+		runGit(t, root, "commit", "-q", "-am", branch)
+		return gitOutput(t, root, "rev-parse", "HEAD")
+	}
+	left := commitOn("left", "a.txt", "left\n")
+	right := commitOn("right", "a.txt", "right\n")
+	other := commitOn("other", "b.txt", "other\n")
+	runGit(t, root, "checkout", "-q", "main")
+	writeTestFile(t, root, "b.txt", "main\n")
+	runGit(t, root, "commit", "-q", "-am", "main moves")
+	mainSHA := gitOutput(t, root, "rev-parse", "HEAD")
+	runGit(t, root, "branch", "-q", "merged", "HEAD~1")
+	merged := gitOutput(t, root, "rev-parse", "merged")
+
+	svc := newStatsService(nil)
+	got := svc.Conflicts(WorkspaceStats{Path: root, Worktrees: []WorktreeStat{
+		{Branch: "main", WorktreePath: root, IsMain: true, CommitSHA: mainSHA},
+		{Branch: "left", WorktreePath: root + ".left", CommitSHA: left},
+		{Branch: "right", WorktreePath: root + ".right", CommitSHA: right},
+		{Branch: "other", WorktreePath: root + ".other", CommitSHA: other},
+		{Branch: "merged", WorktreePath: root + ".merged", CommitSHA: merged},
+	}})
+	if got.Err != nil {
+		t.Fatalf("Conflicts() error = %v", got.Err)
+	}
+	if got.Base != "main" {
+		t.Fatalf("Base = %q", got.Base)
+	}
+	if len(got.Conflicts) != 2 {
+		t.Fatalf("Conflicts = %+v, want left/right and other/base", got.Conflicts)
+	}
+
+	left1 := got.For(root + ".left")
+	if len(left1) != 1 || left1[0].OtherBranch != "right" || !reflect.DeepEqual(left1[0].Files, []string{"a.txt"}) {
+		t.Fatalf("For(left) = %+v", left1)
+	}
+	otherConflicts := got.For(root + ".other")
+	if len(otherConflicts) != 1 || !otherConflicts[0].WithBase() || !reflect.DeepEqual(otherConflicts[0].Files, []string{"b.txt"}) {
+		t.Fatalf("For(other) = %+v", otherConflicts)
+	}
+	if c := got.For(root + ".merged"); len(c) != 0 {
+		t.Fatalf("branch already in base reported conflicts: %+v", c)
+	}
+}
+
+func TestConflictsCachesByCommitPair(t *testing.T) {
+	calls, ancestry := 0, 0
+	svc := newStatsService(nil)
+	svc.git = conflictGit{
+		mergeTree: func(_, a, b string) ([]string, error) {
+			calls++
+			if mergeKey(a, b) == mergeKey("b1", "c1") {
+				return []string{"x.go"}, nil
+			}
+			return nil, nil
+		},
+		isAncestor: func(string, string, string) bool {
+			ancestry++
+			return false
+		},
+	}
+	ws := WorkspaceStats{Path: "/repo", Worktrees: []WorktreeStat{
+		{Branch: "main", WorktreePath: "/repo", IsMain: true, CommitSHA: "m1"},
+		{Branch: "b", WorktreePath: "/repo.b", CommitSHA: "b1"},
+		{Branch: "c", WorktreePath: "/repo.c", CommitSHA: "c1"},
+	}}
+	first := svc.Conflicts(ws)
+	second := svc.Conflicts(ws)
+	if calls != 3 {
+		t.Fatalf("merge-tree ran %d times, want 3 (cached on the second pass)", calls)
+	}
+	if ancestry != 2 {
+		t.Fatalf("ancestry checked %d times, want 2 (cached on the second pass)", ancestry)
+	}
+	if !reflect.DeepEqual(first, second) || len(first.Conflicts) != 1 {
+		t.Fatalf("first = %+v, second = %+v", first, second)
+	}
+	c := first.For("/repo.b")
+	if len(c) != 1 || c[0].Branch != "b" || c[0].OtherBranch != "c" {
+		t.Fatalf("For(b) = %+v", c)
+	}
+}
@@ -147,6 +147,16 @@ func ResolveRepoRoot(dir string) string {
 	return filepath.Dir(filepath.Clean(commonDir))
 }
 
+// ResolveWorktreeRoot returns the root of the worktree containing dir, or
+// empty string when the path is not tracked by git.
+func ResolveWorktreeRoot(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
 // ResolveGitBranch returns the current branch for a directory, or empty.
 func ResolveGitBranch(dir string) string {
 	out, err := exec.Command("git", "-C", dir, "branch", "--show-current").Output()
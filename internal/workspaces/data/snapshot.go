@@ -1,6 +1,7 @@
 package data
 
 import (
+	"github.com/miltonparedes/kitmux/internal/frecency"
 	"github.com/miltonparedes/kitmux/internal/tmux"
 	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
 )
@@ -37,7 +38,7 @@ func LoadSnapshot(stats *StatsService) (Snapshot, error) {
 	}
 
 	workspaces := wsreg.LoadRegistry()
-	wsreg.SortWorkspaces(workspaces, activePaths)
+	wsreg.SortWorkspaces(workspaces, activePaths, frecency.Load(frecency.KindWorkspace))
 
 	cached, err := stats.LoadAllCached()
 	if err != nil {
@@ -1,6 +1,9 @@
 package data
 
 import (
+	"errors"
+	"io/fs"
+	"os"
 	"sync"
 	"time"
 
@@ -9,13 +12,20 @@ import (
 )
 
 // fetchFunc fetches live worktrees for a given workspace path. Injected so
-// tests can avoid calling the real `wt` binary.
+// tests can avoid calling git or the real `wt` binary.
 type fetchFunc func(workspacePath string) ([]worktree.Worktree, error)
 
+// inspectFunc reads a single worktree. Injected for the same reason.
+type inspectFunc func(worktreePath string) (worktree.Worktree, error)
+
 // StatsService caches workspace stats in SQLite and refreshes them on demand
 // with single-flight coalescing so concurrent callers share one `wt list` run.
 type StatsService struct {
-	fetch fetchFunc
+	fetch   fetchFunc
+	inspect inspectFunc
+	git     conflictGit
+
+	merges mergeCache
 
 	mu       sync.Mutex
 	inflight map[string]*refreshCall
@@ -35,7 +45,7 @@ type RefreshResult struct {
 	Err           error
 }
 
-// NewStatsService builds a service backed by `wt list`.
+// NewStatsService builds a service backed by the configured worktree backend.
 func NewStatsService() *StatsService {
 	return newStatsService(defaultFetch)
 }
@@ -43,6 +53,8 @@ func NewStatsService() *StatsService {
 func newStatsService(fetch fetchFunc) *StatsService {
 	return &StatsService{
 		fetch:    fetch,
+		inspect:  worktree.Inspect,
+		git:      defaultConflictGit,
 		inflight: make(map[string]*refreshCall),
 	}
 }
@@ -143,21 +155,7 @@ func (s *StatsService) doRefresh(workspacePath string) RefreshResult {
 
 	rows := make([]store.WorktreeStat, 0, len(wts))
 	for _, wt := range wts {
-		rows = append(rows, store.WorktreeStat{
-			WorkspacePath: workspacePath,
-			Branch:        wt.Branch,
-			WorktreePath:  wt.Path,
-			Added:         wt.WorkingTree.Diff.Added,
-			Deleted:       wt.WorkingTree.Diff.Deleted,
-			Staged:        wt.WorkingTree.Staged,
-			Modified:      wt.WorkingTree.Modified,
-			Untracked:     wt.WorkingTree.Untracked,
-			Ahead:         wt.Remote.Ahead,
-			Behind:        wt.Remote.Behind,
-			IsMain:        wt.IsMain,
-			CommitSHA:     wt.Commit.SHA,
-			CommitTS:      wt.Commit.Timestamp,
-		})
+		rows = append(rows, statRow(workspacePath, wt))
 	}
 	if err := store.ReplaceWorkspaceStats(workspacePath, rows, now); err != nil {
 		cached, _ := s.LoadCached(workspacePath)
@@ -168,6 +166,37 @@ func (s *StatsService) doRefresh(workspacePath string) RefreshResult {
 	return RefreshResult{WorkspacePath: workspacePath, Stats: fresh}
 }
 
+// RefreshWorktree re-reads a single worktree of a workspace and rewrites
+// only its cached row. A worktree that no longer exists loses its row.
+func (s *StatsService) RefreshWorktree(workspacePath, worktreePath string) error {
+	if _, err := os.Stat(worktreePath); errors.Is(err, fs.ErrNotExist) {
+		return store.DeleteWorktreeStat(workspacePath, worktreePath)
+	}
+	wt, err := s.inspect(worktreePath)
+	if err != nil {
+		return err
+	}
+	return store.UpsertWorktreeStat(statRow(workspacePath, wt), time.Now())
+}
+
+func statRow(workspacePath string, wt worktree.Worktree) store.WorktreeStat {
+	return store.WorktreeStat{
+		WorkspacePath: workspacePath,
+		Branch:        wt.Branch,
+		WorktreePath:  wt.Path,
+		Added:         wt.WorkingTree.Diff.Added,
+		Deleted:       wt.WorkingTree.Diff.Deleted,
+		Staged:        wt.WorkingTree.Staged,
+		Modified:      wt.WorkingTree.Modified,
+		Untracked:     wt.WorkingTree.Untracked,
+		Ahead:         wt.Remote.Ahead,
+		Behind:        wt.Remote.Behind,
+		IsMain:        wt.IsMain,
+		CommitSHA:     wt.Commit.SHA,
+		CommitTS:      wt.Commit.Timestamp,
+	}
+}
+
 // Invalidate removes the cached stats for a workspace, forcing the next
 // Refresh to bypass any freshness check.
 func (s *StatsService) Invalidate(workspacePath string) error {
@@ -152,3 +152,43 @@ func TestLastRefreshReflectsRecentRefresh(t *testing.T) {
 		t.Fatalf("expected last refresh after %v, got %v", before, ts)
 	}
 }
+
+func TestRefreshWorktreeReadsOnlyThatWorktree(t *testing.T) {
+	useTempHome(t)
+
+	dir := t.TempDir()
+	svc := newStatsService(func(string) ([]worktree.Worktree, error) {
+		return []worktree.Worktree{
+			sampleWorktree("main", "/tmp/ws", 0, 0, true),
+			sampleWorktree("feature", dir, 0, 0, false),
+		}, nil
+	})
+	if res := svc.Refresh("/tmp/ws"); res.Err != nil {
+		t.Fatalf("refresh: %v", res.Err)
+	}
+	svc.fetch = func(string) ([]worktree.Worktree, error) {
+		t.Fatal("RefreshWorktree listed the whole workspace")
+		return nil, nil
+	}
+	var inspected []string
+	svc.inspect = func(path string) (worktree.Worktree, error) {
+		inspected = append(inspected, path)
+		return sampleWorktree("feature", path, 4, 1, false), nil
+	}
+
+	if err := svc.RefreshWorktree("/tmp/ws", dir); err != nil {
+		t.Fatalf("refresh worktree: %v", err)
+	}
+	if len(inspected) != 1 || inspected[0] != dir {
+		t.Fatalf("inspected = %v", inspected)
+	}
+	cached, _ := svc.LoadCached("/tmp/ws")
+	if added, deleted := cached.TotalDiff(); added != 4 || deleted != 1 || len(cached.Worktrees) != 2 {
+		t.Fatalf("cached = %+v", cached)
+	}
+
+	// A worktree whose directory is gone loses its row without a git call.
+	if err := svc.RefreshWorktree("/tmp/ws", "/tmp/ws-gone"); err != nil || len(inspected) != 1 {
+		t.Fatalf("refresh of a removed worktree: err %v, inspected %v", err, inspected)
+	}
+}
@@ -0,0 +1,480 @@
+package data
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
+)
+
+const (
+	// watchSettle is how long a worktree must be quiet before its stats are
+	// refreshed. Saves, checkouts and rebases arrive as bursts.
+	watchSettle = 300 * time.Millisecond
+	// watchMaxDelay bounds how long a constantly busy worktree waits.
+	watchMaxDelay = 3 * time.Second
+	// maxWatchedDirs caps the directories watched per worktree so a huge
+	// checkout cannot exhaust inotify watches.
+	maxWatchedDirs = 2048
+)
+
+// WorktreeChange is sent after a worktree's cached stats were refreshed
+// because its files or git state changed.
+type WorktreeChange struct {
+	WorkspacePath string
+	WorktreePath  string
+}
+
+// Watcher refreshes cached worktree stats when files change. It watches
+// each worktree's git dir (index, HEAD), the repository's refs, and the
+// working directory minus ignored paths, and refreshes only the worktree
+// that changed. Worktrees added or removed later are picked up from the
+// repository's worktrees directory.
+type Watcher struct {
+	svc     *StatsService
+	fs      *fsnotify.Watcher
+	settle  time.Duration
+	changes chan WorktreeChange
+	done    chan struct{}
+	ready   chan struct{} // closed once every workspace is watched
+
+	mu    sync.Mutex
+	trees []*watchedTree
+}
+
+type watchedTree struct {
+	workspace string
+	path      string
+	branch    string
+	isMain    bool
+	gitDir    string
+	commonDir string
+	ignored   []string
+	dirs      int
+}
+
+var activeWatcher atomic.Pointer[Watcher]
+
+// ActiveWatcher returns the watcher started by UseWatcher, or nil.
+func ActiveWatcher() *Watcher {
+	return activeWatcher.Load()
+}
+
+// UseWatcher watches every registered workspace for the lifetime of a TUI.
+// The returned stop func is always safe to call; when the watcher cannot
+// start, views keep refreshing on load and on demand.
+func UseWatcher() (stop func()) {
+	paths := make([]string, 0)
+	for _, ws := range wsreg.LoadRegistry() {
+		paths = append(paths, ws.Path)
+	}
+	w, err := NewWatcher(NewStatsService(), paths, watchSettle)
+	if err != nil {
+		return func() {}
+	}
+	activeWatcher.Store(w)
+	return func() {
+		activeWatcher.CompareAndSwap(w, nil)
+		_ = w.Close()
+	}
+}
+
+// NewWatcher starts watching the worktrees of the given workspaces in the
+// background. The worktree list comes from cached stats, refreshed once
+// when missing.
+func NewWatcher(svc *StatsService, workspaces []string, settle time.Duration) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		svc:     svc,
+		fs:      fsw,
+		settle:  settle,
+		changes: make(chan WorktreeChange, 64),
+		done:    make(chan struct{}),
+		ready:   make(chan struct{}),
+	}
+	go func() {
+		defer close(w.ready)
+		for _, ws := range workspaces {
+			w.addWorkspace(ws)
+		}
+	}()
+	go w.loop()
+	return w, nil
+}
+
+// Changes delivers one WorktreeChange per refreshed worktree. It is closed
+// by Close.
+func (w *Watcher) Changes() <-chan WorktreeChange {
+	return w.changes
+}
+
+func (w *Watcher) Close() error {
+	select {
+	case <-w.done:
+		return nil
+	default:
+	}
+	close(w.done)
+	return w.fs.Close()
+}
+
+func (w *Watcher) addWorkspace(workspacePath string) {
+	stats, err := w.svc.LoadCached(workspacePath)
+	if err != nil || len(stats.Worktrees) == 0 {
+		stats = w.svc.Refresh(workspacePath).Stats
+	}
+	for _, wt := range stats.Worktrees {
+		if wt.WorktreePath != "" {
+			w.addWorktree(workspacePath, wt)
+		}
+	}
+}
+
+func (w *Watcher) addWorktree(workspacePath string, wt WorktreeStat) {
+	tree, err := newWatchedTree(workspacePath, wt)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	w.trees = append(w.trees, tree)
+	w.watchTree(tree)
+	w.mu.Unlock()
+}
+
+// rescan re-lists a workspace after a worktree was added or removed,
+// watching the new ones and forgetting the removed ones. It returns the
+// paths of the worktrees that came or went.
+func (w *Watcher) rescan(workspacePath string) []string {
+	res := w.svc.Refresh(workspacePath)
+	if res.Err != nil {
+		return nil
+	}
+	present := make(map[string]WorktreeStat)
+	for _, wt := range res.Stats.Worktrees {
+		if wt.WorktreePath != "" {
+			present[filepath.Clean(wt.WorktreePath)] = wt
+		}
+	}
+
+	var changed []string
+	watched := make(map[string]bool)
+	w.mu.Lock()
+	kept := w.trees[:0]
+	for _, tree := range w.trees {
+		if tree.workspace == workspacePath {
+			if _, ok := present[tree.path]; !ok {
+				changed = append(changed, tree.path)
+				continue
+			}
+			watched[tree.path] = true
+		}
+		kept = append(kept, tree)
+	}
+	w.trees = kept
+	w.mu.Unlock()
+
+	for path, wt := range present {
+		if !watched[path] {
+			w.addWorktree(workspacePath, wt)
+			changed = append(changed, path)
+		}
+	}
+	return changed
+}
+
+func newWatchedTree(workspacePath string, wt WorktreeStat) (*watchedTree, error) {
+	out, err := gitLines(wt.WorktreePath, "rev-parse", "--absolute-git-dir", "--git-common-dir")
+	if err != nil || len(out) < 2 {
+		return nil, err
+	}
+	common := out[1]
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(wt.WorktreePath, common)
+	}
+	tree := &watchedTree{
+		workspace: workspacePath,
+		path:      filepath.Clean(wt.WorktreePath),
+		branch:    wt.Branch,
+		isMain:    wt.IsMain,
+		gitDir:    filepath.Clean(out[0]),
+		commonDir: filepath.Clean(common),
+	}
+	if ignored, err := gitLines(tree.path, "ls-files", "--others", "--ignored", "--exclude-standard", "--directory"); err == nil {
+		for _, rel := range ignored {
+			if strings.HasSuffix(rel, "/") {
+				tree.ignored = append(tree.ignored, filepath.Join(tree.path, rel))
+			}
+		}
+	}
+	return tree, nil
+}
+
+func (w *Watcher) watchTree(tree *watchedTree) {
+	_ = w.fs.Add(tree.gitDir)
+	_ = w.fs.Add(tree.commonDir)
+	_ = w.fs.Add(filepath.Join(tree.commonDir, "worktrees"))
+	w.addTree(filepath.Join(tree.commonDir, "refs"))
+	w.addDirs(tree, tree.path)
+}
+
+// addTree watches root and every directory below it.
+func (w *Watcher) addTree(root string) {
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		_ = w.fs.Add(path)
+		return nil
+	})
+}
+
+// addDirs watches the working directory below root, skipping .git and
+// ignored directories.
+func (w *Watcher) addDirs(tree *watchedTree, root string) {
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" || tree.isIgnored(path) {
+			return filepath.SkipDir
+		}
+		if tree.dirs >= maxWatchedDirs {
+			return filepath.SkipAll
+		}
+		if w.fs.Add(path) == nil {
+			tree.dirs++
+		}
+		return nil
+	})
+}
+
+func (w *Watcher) loop() {
+	defer close(w.changes)
+
+	pending := make(map[*watchedTree][]string)
+	rescans := make(map[string]bool)
+	var timer <-chan time.Time
+	var first time.Time
+	for {
+		select {
+		case <-w.done:
+			return
+		case ev, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			for _, hit := range w.route(ev) {
+				if hit.rescan {
+					rescans[hit.tree.workspace] = true
+					continue
+				}
+				pending[hit.tree] = append(pending[hit.tree], hit.file)
+			}
+			if len(pending) == 0 && len(rescans) == 0 {
+				continue
+			}
+			if first.IsZero() {
+				first = time.Now()
+			}
+			wait := w.settle
+			if rest := watchMaxDelay - time.Since(first); rest < wait {
+				wait = max(rest, 0)
+			}
+			timer = time.After(wait)
+		case _, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+		case <-timer:
+			w.flush(pending, rescans)
+			pending = make(map[*watchedTree][]string)
+			rescans = make(map[string]bool)
+			timer = nil
+			first = time.Time{}
+		}
+	}
+}
+
+type watchHit struct {
+	tree   *watchedTree
+	file   string // working-directory file, empty for git state
+	rescan bool   // a worktree of the repository was added or removed
+}
+
+// route maps an event to the worktrees it affects.
+func (w *Watcher) route(ev fsnotify.Event) []watchHit {
+	path := filepath.Clean(ev.Name)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var hits []watchHit
+	for _, tree := range w.trees {
+		rel := ""
+		if within(path, tree.commonDir) {
+			rel = filepath.ToSlash(strings.TrimPrefix(path, tree.commonDir+string(filepath.Separator)))
+		}
+		switch {
+		case worktreeAdmin(rel):
+			if ev.Has(fsnotify.Create) || ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+				hits = append(hits, watchHit{tree: tree, rescan: true})
+			}
+			if rel == "worktrees" && ev.Has(fsnotify.Create) {
+				_ = w.fs.Add(path)
+			}
+		case filepath.Dir(path) == tree.gitDir:
+			switch filepath.Base(path) {
+			case "index", "HEAD":
+				hits = append(hits, watchHit{tree: tree})
+			}
+		case within(path, tree.commonDir):
+			if refAffects(tree, w.trees, rel) {
+				hits = append(hits, watchHit{tree: tree})
+			}
+			if ev.Has(fsnotify.Create) && strings.HasPrefix(rel, "refs/") && isDir(path) {
+				w.addTree(path)
+			}
+		case within(path, tree.path):
+			if tree.isIgnored(path) || within(path, filepath.Join(tree.path, ".git")) {
+				continue
+			}
+			if ev.Has(fsnotify.Create) && isDir(path) {
+				w.addDirs(tree, path)
+			}
+			hits = append(hits, watchHit{tree: tree, file: path})
+		}
+	}
+	return hits
+}
+
+// refAffects reports whether a change to ref (relative to the common git
+// dir) matters to tree. A branch ref touches the worktree on that branch,
+// and the main branch or remote refs touch every worktree of the repo
+// because ahead/behind counts move.
+func refAffects(tree *watchedTree, trees []*watchedTree, ref string) bool {
+	ref = filepath.ToSlash(ref)
+	switch {
+	case ref == "packed-refs", strings.HasPrefix(ref, "refs/remotes/"):
+		return true
+	case strings.HasPrefix(ref, "refs/heads/"):
+		branch := strings.TrimSuffix(strings.TrimPrefix(ref, "refs/heads/"), ".lock")
+		if branch == tree.branch {
+			return true
+		}
+		for _, other := range trees {
+			if other.isMain && other.commonDir == tree.commonDir && other.branch == branch {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// worktreeAdmin reports whether rel, relative to the common git dir, is
+// the worktrees directory or one worktree's entry in it; git creates and
+// deletes those as worktrees are added and removed.
+func worktreeAdmin(rel string) bool {
+	name, ok := strings.CutPrefix(rel, "worktrees/")
+	return rel == "worktrees" || (ok && name != "" && !strings.Contains(name, "/"))
+}
+
+func (w *Watcher) flush(pending map[*watchedTree][]string, rescans map[string]bool) {
+	for workspace := range rescans {
+		for _, changed := range w.rescan(workspace) {
+			if !w.send(WorktreeChange{WorkspacePath: workspace, WorktreePath: changed}) {
+				return
+			}
+		}
+	}
+	for tree, files := range pending {
+		if rescans[tree.workspace] || !tree.relevant(files) {
+			continue
+		}
+		if err := w.svc.RefreshWorktree(tree.workspace, tree.path); err != nil {
+			continue
+		}
+		if !w.send(WorktreeChange{WorkspacePath: tree.workspace, WorktreePath: tree.path}) {
+			return
+		}
+	}
+}
+
+// send delivers a change, reporting false once the watcher is closed.
+func (w *Watcher) send(change WorktreeChange) bool {
+	select {
+	case w.changes <- change:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// relevant reports whether a batch of events should refresh the worktree.
+// Git state changes always do; file changes only when git does not ignore
+// every one of them.
+func (t *watchedTree) relevant(files []string) bool {
+	var paths []string
+	for _, f := range files {
+		if f == "" {
+			return true
+		}
+		paths = append(paths, f)
+	}
+	cmd := exec.Command("git", "-C", t.path, "check-ignore", "--stdin")
+	cmd.Stdin = strings.NewReader(strings.Join(paths, "\n") + "\n")
+	out, err := cmd.Output()
+	if err != nil {
+		// Exit status 1 means nothing matched an ignore rule.
+		return true
+	}
+	ignored := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		ignored[filepath.Clean(line)] = true
+	}
+	for _, p := range paths {
+		if !ignored[p] {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *watchedTree) isIgnored(path string) bool {
+	for _, dir := range t.ignored {
+		if within(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func within(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func gitLines(dir string, args ...string) ([]string, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSpace(string(out))
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
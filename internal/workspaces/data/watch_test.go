@@ -0,0 +1,143 @@
+package data
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/worktree"
+)
+
+func TestWatcherRefreshesChangedWorktree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	useTempHome(t)
+	root := filepath.Join(t.TempDir(), "app")
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+	} {
+		runGit(t, root, args...)
+	}
+	writeTestFile(t, root, ".gitignore", "*.log\nbuild/\n")
+	writeTestFile(t, root, "main.go", "package main\n")
+	runGit(t, root, "add", "-A")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+	if err := os.MkdirAll(filepath.Join(root, "build"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := newStatsService(worktree.Git{}.List)
+	w, err := NewWatcher(svc, []string{root}, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("watcher: %v", err)
+	}
+	t.Cleanup(func() { _ = w.Close() })
+	<-w.ready
+
+	writeTestFile(t, root, "debug.log", "noise\n")
+	writeTestFile(t, filepath.Join(root, "build"), "out.bin", "noise\n")
+	select {
+	case change := <-w.Changes():
+		t.Fatalf("ignored files triggered a refresh: %+v", change)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	writeTestFile(t, root, "main.go", "package main\n\nfunc main() {}\n")
+	select {
+	case change := <-w.Changes():
+		if change.WorkspacePath != root || change.WorktreePath != root {
+			t.Fatalf("change = %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no refresh after editing a tracked file")
+	}
+
+	cached, err := svc.LoadCached(root)
+	if err != nil || len(cached.Worktrees) != 1 {
+		t.Fatalf("cached = %+v, err %v", cached, err)
+	}
+	if got := cached.Worktrees[0]; !got.Modified || got.Added != 2 {
+		t.Fatalf("refreshed stat = %+v", got)
+	}
+}
+
+func TestWatcherWatchesWorktreesAddedLater(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	useTempHome(t)
+	root := filepath.Join(t.TempDir(), "app")
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test"},
+	} {
+		runGit(t, root, args...)
+	}
+	writeTestFile(t, root, "main.go", "package main\n")
+	runGit(t, root, "add", "-A")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+
+	svc := newStatsService(worktree.Git{}.List)
+	w, err := NewWatcher(svc, []string{root}, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("watcher: %v", err)
+	}
+	t.Cleanup(func() { _ = w.Close() })
+	<-w.ready
+
+	feature := root + ".feat"
+	runGit(t, root, "worktree", "add", "-q", "-b", "feat", feature)
+	waitForChange(t, w, feature)
+
+	writeTestFile(t, feature, "main.go", "package main\n\nfunc main() {}\n")
+	for modified := false; !modified; {
+		waitForChange(t, w, feature)
+		cached, _ := svc.LoadCached(root)
+		for _, wt := range cached.Worktrees {
+			modified = modified || (wt.WorktreePath == feature && wt.Modified)
+		}
+	}
+}
+
+// waitForChange waits for a change to worktree, skipping others.
+func waitForChange(t *testing.T, w *Watcher, worktree string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case change := <-w.Changes():
+			if change.WorktreePath == worktree {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no change for %s", worktree)
+		}
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v (%s)", args, err, out)
+	}
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,6 +2,7 @@ package workspaces
 
 import (
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/miltonparedes/kitmux/internal/store"
@@ -10,6 +11,12 @@ import (
 // Workspace is an alias for store.Workspace so callers keep a clean import.
 type Workspace = store.Workspace
 
+// Filter is a saved tag filter preset.
+type Filter = store.WorkspaceFilter
+
+// Stack is a named group of workspaces opened together.
+type Stack = store.WorkspaceStack
+
 var registryMu sync.Mutex
 
 // LoadRegistry reads the persisted workspace list.
@@ -94,9 +101,108 @@ func PurgeArchivedWorktreesForWorkspace(workspacePath string) bool {
 	return err == nil
 }
 
-// SortWorkspaces sorts active workspaces first (by activity desc), then inactive alphabetically.
-func SortWorkspaces(workspaces []Workspace, activePaths map[string]int64) {
+// SetPinned pins or unpins a workspace. Returns true on success.
+func SetPinned(path string, pinned bool) bool {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	return store.SetWorkspacePinned(path, pinned) == nil
+}
+
+// SetTags replaces the tags of a workspace. Returns true on success.
+func SetTags(path string, tags []string) bool {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	return store.SetWorkspaceTags(path, tags) == nil
+}
+
+// LoadFilters returns the saved tag filter presets.
+func LoadFilters() []Filter {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	filters, err := store.LoadWorkspaceFilters()
+	if err != nil {
+		return nil
+	}
+	return filters
+}
+
+// SaveFilter creates or replaces a tag filter preset. Returns true on success.
+func SaveFilter(f Filter) bool {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	return store.SaveWorkspaceFilter(f) == nil
+}
+
+// DeleteFilter removes a tag filter preset. Returns true on success.
+func DeleteFilter(name string) bool {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	return store.DeleteWorkspaceFilter(name) == nil
+}
+
+// LoadStacks returns the saved workspace stacks.
+func LoadStacks() []Stack {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	stacks, err := store.LoadWorkspaceStacks()
+	if err != nil {
+		return nil
+	}
+	return stacks
+}
+
+// SaveStack creates or replaces a workspace stack. Returns true on success.
+func SaveStack(s Stack) bool {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	return store.SaveWorkspaceStack(s) == nil
+}
+
+// DeleteStack removes a workspace stack. Returns true on success.
+func DeleteStack(name string) bool {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	return store.DeleteWorkspaceStack(name) == nil
+}
+
+// ParseTags splits user input such as "work, oss infra" into normalized tags.
+func ParseTags(input string) []string {
+	return store.NormalizeTags(strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' '
+	}))
+}
+
+// HasAnyTag reports whether tags shares a tag with filter. An empty filter
+// matches everything.
+func HasAnyTag(tags, filter []string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, want := range filter {
+		for _, tag := range tags {
+			if tag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SortWorkspaces sorts pinned workspaces first, then active workspaces (by
+// activity desc), then inactive ones by frecency score and name.
+func SortWorkspaces(workspaces []Workspace, activePaths map[string]int64, scores map[string]float64) {
 	sort.SliceStable(workspaces, func(i, j int) bool {
+		if workspaces[i].Pinned != workspaces[j].Pinned {
+			return workspaces[i].Pinned
+		}
 		ai := activePaths[workspaces[i].Path]
 		aj := activePaths[workspaces[j].Path]
 		if (ai > 0) != (aj > 0) {
@@ -105,6 +211,9 @@ func SortWorkspaces(workspaces []Workspace, activePaths map[string]int64) {
 		if ai > 0 && aj > 0 {
 			return ai > aj
 		}
+		if si, sj := scores[workspaces[i].Path], scores[workspaces[j].Path]; si != sj {
+			return si > sj
+		}
 		return workspaces[i].Name < workspaces[j].Name
 	})
 }
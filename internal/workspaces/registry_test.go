@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -68,3 +69,31 @@ func TestAddWorkspace_DedupesByPath(t *testing.T) {
 		t.Fatalf("expected original workspace to remain, got %+v", loaded[0])
 	}
 }
+
+func TestSortWorkspaces_PinnedFirst(t *testing.T) {
+	workspaces := []Workspace{
+		{Name: "api", Path: "/api"},
+		{Name: "web", Path: "/web"},
+		{Name: "notes", Path: "/notes", Pinned: true},
+		{Name: "infra", Path: "/infra"},
+	}
+	SortWorkspaces(workspaces, map[string]int64{"/web": 10}, map[string]float64{"/infra": 2})
+
+	var names []string
+	for _, w := range workspaces {
+		names = append(names, w.Name)
+	}
+	if strings.Join(names, ",") != "notes,web,infra,api" {
+		t.Fatalf("order = %v", names)
+	}
+}
+
+func TestParseTagsAndHasAnyTag(t *testing.T) {
+	tags := ParseTags("Work, oss  infra,work")
+	if strings.Join(tags, ",") != "infra,oss,work" {
+		t.Fatalf("ParseTags = %v", tags)
+	}
+	if !HasAnyTag(tags, nil) || !HasAnyTag(tags, []string{"oss"}) || HasAnyTag(tags, []string{"home"}) {
+		t.Fatal("HasAnyTag mismatch")
+	}
+}
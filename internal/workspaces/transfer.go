@@ -0,0 +1,244 @@
+package workspaces
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/store"
+)
+
+// ExportVersion is the format version written by Export and accepted by
+// ReadExport.
+const ExportVersion = 1
+
+// Export is the portable form of the registry written by
+// `kitmux workspaces export`. Paths under the home directory are stored as
+// "~/…" so the file can be imported on a machine with another home.
+type Export struct {
+	Version    int                 `json:"version"`
+	ExportedAt int64               `json:"exported_at"`
+	Workspaces []ExportedWorkspace `json:"workspaces"`
+	Filters    []ExportedFilter    `json:"filters,omitempty"`
+}
+
+// ExportedWorkspace is one registered workspace with its tags, pin,
+// timestamps and archived worktrees.
+type ExportedWorkspace struct {
+	Name              string   `json:"name"`
+	Path              string   `json:"path"`
+	Pinned            bool     `json:"pinned,omitempty"`
+	Tags              []string `json:"tags,omitempty"`
+	AddedAt           int64    `json:"added_at"`
+	LastSeenAt        int64    `json:"last_seen_at,omitempty"`
+	LastOpenedAt      int64    `json:"last_opened_at,omitempty"`
+	ArchivedWorktrees []string `json:"archived_worktrees,omitempty"`
+}
+
+// ExportedFilter is a saved tag filter preset.
+type ExportedFilter struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// BuildExport snapshots the registry, relativizing paths to home.
+func BuildExport(home string) (Export, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	workspaces, err := store.LoadWorkspaces()
+	if err != nil {
+		return Export{}, err
+	}
+	archived, err := store.LoadArchivedWorktrees()
+	if err != nil {
+		return Export{}, err
+	}
+	filters, err := store.LoadWorkspaceFilters()
+	if err != nil {
+		return Export{}, err
+	}
+
+	out := Export{Version: ExportVersion, ExportedAt: time.Now().Unix()}
+	for _, w := range workspaces {
+		ew := ExportedWorkspace{
+			Name:       w.Name,
+			Path:       homeRelative(w.Path, home),
+			Pinned:     w.Pinned,
+			Tags:       w.Tags,
+			AddedAt:    w.AddedAt,
+			LastSeenAt: w.LastSeenAt,
+		}
+		if meta, err := store.LoadWorkspaceMeta(w.Path); err == nil && !meta.LastOpenedAt.IsZero() {
+			ew.LastOpenedAt = meta.LastOpenedAt.Unix()
+		}
+		for wt := range archived[w.Path] {
+			ew.ArchivedWorktrees = append(ew.ArchivedWorktrees, homeRelative(wt, home))
+		}
+		sort.Strings(ew.ArchivedWorktrees)
+		out.Workspaces = append(out.Workspaces, ew)
+	}
+	for _, f := range filters {
+		out.Filters = append(out.Filters, ExportedFilter(f))
+	}
+	return out, nil
+}
+
+// WriteExport encodes e as indented JSON.
+func WriteExport(w io.Writer, e Export) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+// ReadExport decodes an export file, rejecting versions it does not know.
+func ReadExport(r io.Reader) (Export, error) {
+	var e Export
+	if err := json.NewDecoder(r).Decode(&e); err != nil {
+		return Export{}, fmt.Errorf("decode workspaces export: %w", err)
+	}
+	if e.Version < 1 || e.Version > ExportVersion {
+		return Export{}, fmt.Errorf("unsupported workspaces export version %d", e.Version)
+	}
+	return e, nil
+}
+
+// ImportResult reports what Import did, or would do on a dry run. Paths
+// are absolute.
+type ImportResult struct {
+	Added   []string // newly registered workspaces
+	Merged  []string // already registered; tags, pin and archive merged in
+	Missing []string // not on disk; skipped
+	Filters []string // presets added
+}
+
+// Import merges e into the registry. Workspaces whose directory does not
+// exist are skipped and reported. Existing workspaces keep their name; tags
+// and archived worktrees are unioned and a pin is kept. Presets whose name
+// is taken are left alone. With dryRun nothing is written.
+func Import(e Export, home string, dryRun bool) (ImportResult, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	current, err := store.LoadWorkspaces()
+	if err != nil {
+		return ImportResult{}, err
+	}
+	byPath := make(map[string]int, len(current))
+	for i, w := range current {
+		byPath[w.Path] = i
+	}
+
+	var res ImportResult
+	var kept []ExportedWorkspace
+	for _, ew := range e.Workspaces {
+		path := expandHome(ew.Path, home)
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			res.Missing = append(res.Missing, path)
+			continue
+		}
+		kept = append(kept, ew)
+		if i, ok := byPath[path]; ok {
+			w := &current[i]
+			w.Pinned = w.Pinned || ew.Pinned
+			w.Tags = store.NormalizeTags(append(w.Tags, ew.Tags...))
+			res.Merged = append(res.Merged, path)
+			continue
+		}
+		name := ew.Name
+		if name == "" {
+			name = filepath.Base(path)
+		}
+		added := ew.AddedAt
+		if added == 0 {
+			added = time.Now().Unix()
+		}
+		byPath[path] = len(current)
+		current = append(current, Workspace{
+			Name:       name,
+			Path:       path,
+			AddedAt:    added,
+			LastSeenAt: ew.LastSeenAt,
+			Pinned:     ew.Pinned,
+			Tags:       store.NormalizeTags(ew.Tags),
+		})
+		res.Added = append(res.Added, path)
+	}
+
+	existing, err := store.LoadWorkspaceFilters()
+	if err != nil {
+		return ImportResult{}, err
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, f := range existing {
+		taken[f.Name] = true
+	}
+	var filters []store.WorkspaceFilter
+	for _, f := range e.Filters {
+		if taken[f.Name] || len(store.NormalizeTags(f.Tags)) == 0 {
+			continue
+		}
+		taken[f.Name] = true
+		filters = append(filters, store.WorkspaceFilter(f))
+		res.Filters = append(res.Filters, f.Name)
+	}
+
+	if dryRun || (len(kept) == 0 && len(filters) == 0) {
+		return res, nil
+	}
+	if err := store.SaveWorkspaces(current); err != nil {
+		return res, err
+	}
+	for _, ew := range kept {
+		path := expandHome(ew.Path, home)
+		for _, wt := range ew.ArchivedWorktrees {
+			if err := store.AddArchivedWorktree(path, expandHome(wt, home)); err != nil {
+				return res, err
+			}
+		}
+		if ew.LastOpenedAt > 0 {
+			meta, err := store.LoadWorkspaceMeta(path)
+			if err == nil && meta.LastOpenedAt.Unix() < ew.LastOpenedAt {
+				if err := store.TouchWorkspaceOpened(path, time.Unix(ew.LastOpenedAt, 0)); err != nil {
+					return res, err
+				}
+			}
+		}
+	}
+	for _, f := range filters {
+		if err := store.SaveWorkspaceFilter(f); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// homeRelative rewrites a path under home as "~/…".
+func homeRelative(path, home string) string {
+	if home == "" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if rel, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+		return "~/" + filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// expandHome is the inverse of homeRelative.
+func expandHome(path, home string) string {
+	if path == "~" {
+		return home
+	}
+	if rel, ok := strings.CutPrefix(path, "~/"); ok && home != "" {
+		return filepath.Join(home, filepath.FromSlash(rel))
+	}
+	return path
+}
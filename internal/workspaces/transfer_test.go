@@ -0,0 +1,82 @@
+package workspaces
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	oldHome := t.TempDir()
+	t.Setenv("HOME", oldHome)
+
+	app := filepath.Join(oldHome, "code", "app")
+	if !AddWorkspace("app", app) || !AddWorkspace("tmp", "/nonexistent/tmp") {
+		t.Fatal("add workspaces")
+	}
+	SetPinned(app, true)
+	SetTags(app, []string{"work"})
+	AddArchivedWorktree(app, app+".old")
+	SaveFilter(Filter{Name: "day job", Tags: []string{"work"}})
+
+	export, err := BuildExport(oldHome)
+	if err != nil {
+		t.Fatalf("BuildExport() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteExport(&buf, export); err != nil {
+		t.Fatalf("WriteExport() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"path": "~/code/app"`) {
+		t.Fatalf("path not relative to home:\n%s", buf.String())
+	}
+
+	// Import on a machine with another home where app already exists.
+	newHome := t.TempDir()
+	t.Setenv("HOME", newHome)
+	newApp := filepath.Join(newHome, "code", "app")
+	if err := os.MkdirAll(newApp, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if !AddWorkspace("my-app", newApp) {
+		t.Fatal("add existing workspace")
+	}
+	SetTags(newApp, []string{"oss"})
+
+	read, err := ReadExport(&buf)
+	if err != nil {
+		t.Fatalf("ReadExport() error = %v", err)
+	}
+	dry, err := Import(read, newHome, true)
+	if err != nil {
+		t.Fatalf("dry Import() error = %v", err)
+	}
+	if len(dry.Merged) != 1 || len(dry.Missing) != 1 || dry.Missing[0] != "/nonexistent/tmp" {
+		t.Fatalf("dry run = %+v", dry)
+	}
+	if got := LoadRegistry(); len(got) != 1 || got[0].Pinned {
+		t.Fatalf("dry run wrote the registry: %+v", got)
+	}
+
+	if _, err := Import(read, newHome, false); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	got := LoadRegistry()
+	if len(got) != 1 || got[0].Name != "my-app" || !got[0].Pinned || strings.Join(got[0].Tags, ",") != "oss,work" {
+		t.Fatalf("registry = %+v", got)
+	}
+	if !LoadArchivedWorktrees()[newApp][newApp+".old"] {
+		t.Fatalf("archived worktree not imported: %+v", LoadArchivedWorktrees())
+	}
+	if filters := LoadFilters(); len(filters) != 1 || filters[0].Name != "day job" {
+		t.Fatalf("filters = %+v", filters)
+	}
+}
+
+func TestReadExportRejectsUnknownVersion(t *testing.T) {
+	if _, err := ReadExport(strings.NewReader(`{"version": 99}`)); err == nil {
+		t.Fatal("expected an error for version 99")
+	}
+}
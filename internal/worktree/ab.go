@@ -8,16 +8,24 @@ import (
 	"strings"
 )
 
-func PrepareABWorktrees(cwd, baseBranch string) (string, string, error) {
+// ABWorktrees are the two worktrees an A/B run uses.
+type ABWorktrees struct {
+	CodexPath  string
+	ClaudePath string
+	// Created lists the paths this call added, as opposed to reused.
+	Created []string
+}
+
+func PrepareABWorktrees(cwd, baseBranch string) (ABWorktrees, error) {
 	root, err := gitOutput(cwd, "rev-parse", "--show-toplevel")
 	if err != nil {
-		return "", "", fmt.Errorf("resolve repo root: %w", err)
+		return ABWorktrees{}, fmt.Errorf("resolve repo root: %w", err)
 	}
 	if strings.TrimSpace(baseBranch) == "" {
 		baseBranch = "main"
 	}
 	if !branchExists(root, baseBranch) {
-		return "", "", fmt.Errorf("base branch %q does not exist", baseBranch)
+		return ABWorktrees{}, fmt.Errorf("base branch %q does not exist", baseBranch)
 	}
 
 	repoName := filepath.Base(root)
@@ -28,29 +36,37 @@ func PrepareABWorktrees(cwd, baseBranch string) (string, string, error) {
 	codexBranch := abBranchName(baseBranch, "codex")
 	claudeBranch := abBranchName(baseBranch, "claude")
 
-	if err := ensureWorktree(root, codexPath, codexBranch, baseBranch); err != nil {
-		return "", "", err
-	}
-	if err := ensureWorktree(root, claudePath, claudeBranch, baseBranch); err != nil {
-		return "", "", err
+	ab := ABWorktrees{CodexPath: codexPath, ClaudePath: claudePath}
+	for _, wt := range []struct{ path, branch string }{
+		{codexPath, codexBranch},
+		{claudePath, claudeBranch},
+	} {
+		created, err := ensureWorktree(root, wt.path, wt.branch, baseBranch)
+		if err != nil {
+			return ABWorktrees{}, err
+		}
+		if created {
+			ab.Created = append(ab.Created, wt.path)
+		}
 	}
-
-	return codexPath, claudePath, nil
+	return ab, nil
 }
 
-func ensureWorktree(repoRoot, path, branch, baseBranch string) error {
+// ensureWorktree adds a worktree for branch at path unless it is already
+// there, and reports whether it added one.
+func ensureWorktree(repoRoot, path, branch, baseBranch string) (bool, error) {
 	ok, err := isKnownWorktree(repoRoot, path)
 	if err != nil {
-		return fmt.Errorf("check worktree %q: %w", path, err)
+		return false, fmt.Errorf("check worktree %q: %w", path, err)
 	}
 	if ok {
-		return nil
+		return false, nil
 	}
 
 	if _, err := os.Stat(path); err == nil {
-		return fmt.Errorf("path exists and is not a git worktree: %s", path)
+		return false, fmt.Errorf("path exists and is not a git worktree: %s", path)
 	} else if !os.IsNotExist(err) {
-		return fmt.Errorf("stat worktree path: %w", err)
+		return false, fmt.Errorf("stat worktree path: %w", err)
 	}
 
 	var cmd *exec.Cmd
@@ -60,9 +76,9 @@ func ensureWorktree(repoRoot, path, branch, baseBranch string) error {
 		cmd = exec.Command("git", "-C", repoRoot, "worktree", "add", "-b", branch, path, baseBranch)
 	}
 	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("git worktree add %s: %w (%s)", branch, err, strings.TrimSpace(string(out)))
+		return false, fmt.Errorf("git worktree add %s: %w (%s)", branch, err, strings.TrimSpace(string(out)))
 	}
-	return nil
+	return true, nil
 }
 
 func isKnownWorktree(repoRoot, path string) (bool, error) {
@@ -17,39 +17,21 @@ var nonSlug = regexp.MustCompile(`[^a-z0-9-]+`)
 //	"update payment flow"          → feat/update-payment-flow
 //	"something random"             → wip/something-random
 func GenerateBranchName(description string) string {
+	return GenerateBranchNameWithPrefixes(description, nil)
+}
+
+// GenerateBranchNameWithPrefixes is GenerateBranchName with per-intent
+// prefix overrides, e.g. {"fix": "bugfix"} turns fix/… into bugfix/….
+func GenerateBranchNameWithPrefixes(description string, prefixes map[string]string) string {
 	desc := strings.TrimSpace(strings.ToLower(description))
 	if desc == "" {
 		return ""
 	}
 
-	words := strings.Fields(desc)
-	prefix := "wip"
-	body := words
+	prefix, body := Intent(desc)
 
-	if len(words) > 1 {
-		switch words[0] {
-		case "fix", "bugfix", "hotfix":
-			prefix = "fix"
-			body = words[1:]
-		case "add", "feat", "feature", "implement":
-			prefix = "feat"
-			body = words[1:]
-		case "refactor", "cleanup", "clean":
-			prefix = "refactor"
-			body = words[1:]
-		case "update", "improve", "enhance":
-			prefix = "feat"
-			body = words // keep the verb
-		case "test", "tests":
-			prefix = "test"
-			body = words[1:]
-		case "docs", "doc", "document":
-			prefix = "docs"
-			body = words[1:]
-		case "chore":
-			prefix = "chore"
-			body = words[1:]
-		}
+	if custom, ok := prefixes[prefix]; ok && custom != "" {
+		prefix = custom
 	}
 
 	slug := nonSlug.ReplaceAllString(strings.Join(body, "-"), "")
@@ -64,3 +46,51 @@ func GenerateBranchName(description string) string {
 	}
 	return prefix + "/" + slug
 }
+
+// Intent detects the intent of a description from its leading word: fix,
+// feat, refactor, test, docs, chore, or wip when none matches. body is the
+// rest of the description in lower case, keeping verbs such as "update"
+// that carry meaning.
+func Intent(description string) (intent string, body []string) {
+	words := strings.Fields(strings.ToLower(description))
+	if len(words) < 2 {
+		return "wip", words
+	}
+	switch words[0] {
+	case "fix", "bugfix", "hotfix":
+		return "fix", words[1:]
+	case "add", "feat", "feature", "implement":
+		return "feat", words[1:]
+	case "refactor", "cleanup", "clean":
+		return "refactor", words[1:]
+	case "update", "improve", "enhance":
+		return "feat", words // keep the verb
+	case "test", "tests":
+		return "test", words[1:]
+	case "docs", "doc", "document":
+		return "docs", words[1:]
+	case "chore":
+		return "chore", words[1:]
+	}
+	return "wip", words
+}
+
+// BranchIntent is the intent a branch name's prefix stands for, undoing
+// the overrides in prefixes, or "" when the branch has no known prefix.
+func BranchIntent(branch string, prefixes map[string]string) string {
+	prefix, _, ok := strings.Cut(branch, "/")
+	if !ok {
+		return ""
+	}
+	prefix = strings.ToLower(prefix)
+	for intent, custom := range prefixes {
+		if custom == prefix {
+			return intent
+		}
+	}
+	switch prefix {
+	case "fix", "feat", "refactor", "test", "docs", "chore", "wip":
+		return prefix
+	}
+	return ""
+}
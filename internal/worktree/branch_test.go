@@ -0,0 +1,53 @@
+package worktree
+
+import "testing"
+
+func TestGenerateBranchName(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"fix login redirect bug":  "fix/login-redirect-bug",
+		"add user avatars":        "feat/user-avatars",
+		"update payment flow":     "feat/update-payment-flow",
+		"something random":        "wip/something-random",
+		"docs explain the config": "docs/explain-the-config",
+	}
+	for desc, want := range cases {
+		if got := GenerateBranchName(desc); got != want {
+			t.Errorf("GenerateBranchName(%q) = %q, want %q", desc, got, want)
+		}
+	}
+}
+
+func TestGenerateBranchNameWithPrefixes(t *testing.T) {
+	t.Parallel()
+
+	prefixes := map[string]string{"fix": "bugfix", "wip": "spike"}
+	if got := GenerateBranchNameWithPrefixes("fix login redirect", prefixes); got != "bugfix/login-redirect" {
+		t.Fatalf("fix = %q", got)
+	}
+	if got := GenerateBranchNameWithPrefixes("something random", prefixes); got != "spike/something-random" {
+		t.Fatalf("wip = %q", got)
+	}
+	if got := GenerateBranchNameWithPrefixes("add avatars", prefixes); got != "feat/avatars" {
+		t.Fatalf("feat = %q", got)
+	}
+}
+
+func TestBranchIntent(t *testing.T) {
+	t.Parallel()
+
+	prefixes := map[string]string{"fix": "bugfix"}
+	cases := map[string]string{
+		"fix/login":     "fix",
+		"bugfix/login":  "fix",
+		"docs/readme":   "docs",
+		"feature/login": "",
+		"main":          "",
+	}
+	for branch, want := range cases {
+		if got := BranchIntent(branch, prefixes); got != want {
+			t.Errorf("BranchIntent(%q) = %q, want %q", branch, got, want)
+		}
+	}
+}
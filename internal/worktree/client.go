@@ -1,12 +1,54 @@
 package worktree
 
 import (
-	"encoding/json"
-	"fmt"
 	"os/exec"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+)
+
+// Backend names accepted by KITMUX_WORKTREE_BACKEND.
+const (
+	BackendAuto = "auto"
+	BackendWT   = "wt"
+	BackendGit  = "git"
 )
 
-// List returns all worktrees via `wt list --format=json`.
+// Backend lists and manages the worktrees of a repository. dir is any
+// directory inside the repo; empty means the current working directory.
+type Backend interface {
+	Name() string
+	List(dir string) ([]Worktree, error)
+	// Switch makes sure branch has a worktree, creating the branch first
+	// when create is set.
+	Switch(dir, branch string, create bool) error
+	Remove(dir, branch string) error
+	// Merge lands the branch checked out in dir on target (the default
+	// branch when empty) and removes its worktree.
+	Merge(dir, target string) error
+	// MergeCommand and CommitCommand are interactive shell commands for
+	// popups run from inside a worktree.
+	MergeCommand() string
+	CommitCommand() string
+}
+
+var lookPath = exec.LookPath
+
+// Current returns the backend selected by config. "auto" prefers worktrunk
+// and falls back to plain git when `wt` is not installed.
+func Current() Backend {
+	switch config.WorktreeBackend() {
+	case BackendWT:
+		return Worktrunk{}
+	case BackendGit:
+		return Git{}
+	}
+	if _, err := lookPath("wt"); err == nil {
+		return Worktrunk{}
+	}
+	return Git{}
+}
+
+// List returns all worktrees of the repo in the current directory.
 func List() ([]Worktree, error) {
 	return ListInDir("")
 }
@@ -14,29 +56,22 @@ func List() ([]Worktree, error) {
 // ListInDir returns all worktrees for the repo at the given directory.
 // If dir is empty, it uses the current working directory.
 func ListInDir(dir string) ([]Worktree, error) {
-	cmd := exec.Command("wt", "list", "--format=json")
-	if dir != "" {
-		cmd.Dir = dir
-	}
-	out, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("wt list: %w", err)
-	}
-	var wts []Worktree
-	if err := json.Unmarshal(out, &wts); err != nil {
-		return nil, fmt.Errorf("wt list parse: %w", err)
-	}
-	return wts, nil
+	return Current().List(dir)
 }
 
-// SwitchTo switches to a worktree branch via `wt switch <branch>`.
+// SwitchTo switches to a worktree branch, creating its worktree if needed.
 func SwitchTo(branch string) error {
-	return exec.Command("wt", "switch", branch).Run()
+	return Current().Switch("", branch, false)
+}
+
+// SwitchInDir makes sure branch has a worktree in the repo at dir.
+func SwitchInDir(dir, branch string, create bool) error {
+	return Current().Switch(dir, branch, create)
 }
 
 // Create creates and switches to a new worktree branch.
 func Create(branch string) error {
-	return exec.Command("wt", "switch", "--create", branch).Run()
+	return Current().Switch("", branch, true)
 }
 
 // Remove removes a worktree branch.
@@ -47,9 +82,10 @@ func Remove(branch string) error {
 // RemoveInDir removes a worktree branch from the repo rooted at dir.
 // If dir is empty, it uses the current working directory.
 func RemoveInDir(dir, branch string) error {
-	cmd := exec.Command("wt", "remove", branch)
-	if dir != "" {
-		cmd.Dir = dir
-	}
-	return cmd.Run()
+	return Current().Remove(dir, branch)
+}
+
+// Merge merges the worktree at dir into target and removes it.
+func Merge(dir, target string) error {
+	return Current().Merge(dir, target)
 }
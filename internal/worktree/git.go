@@ -0,0 +1,454 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Git manages worktrees with plain git. It fills the same Worktree fields
+// worktrunk reports, places new worktrees next to the main one as
+// "<repo>.<branch>" the way worktrunk does by default, and merges by
+// rebasing onto the target and fast-forwarding it.
+type Git struct{}
+
+var _ Backend = Git{}
+
+func (Git) Name() string { return BackendGit }
+
+func (Git) List(dir string) ([]Worktree, error) {
+	out, err := gitOutput(dir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("git worktree list: %w", err)
+	}
+	entries := parseWorktreeList(out)
+	main, ok := mainEntry(entries)
+	if !ok {
+		return nil, nil
+	}
+	current, _ := gitOutput(dir, "rev-parse", "--show-toplevel")
+
+	wts := make([]Worktree, 0, len(entries))
+	for _, e := range entries {
+		if !e.bare {
+			wts = append(wts, describe(e, main, current))
+		}
+	}
+	return wts, nil
+}
+
+// Inspect reads the single worktree at path with plain git, filling the
+// fields List would. It spares running status in every other worktree of
+// the repository when only this one changed.
+func Inspect(path string) (Worktree, error) {
+	out, err := gitOutput(path, "worktree", "list", "--porcelain")
+	if err != nil {
+		return Worktree{}, fmt.Errorf("git worktree list: %w", err)
+	}
+	entries := parseWorktreeList(out)
+	main, _ := mainEntry(entries)
+	for _, e := range entries {
+		if !e.bare && samePath(e.path, path) {
+			return describe(e, main, ""), nil
+		}
+	}
+	return Worktree{}, fmt.Errorf("%s is not a worktree", path)
+}
+
+// describe reads the state of worktree e. current is the worktree the
+// caller is in, if any.
+func describe(e, main worktreeEntry, current string) Worktree {
+	wt := Worktree{
+		Branch:    e.branch,
+		Path:      e.path,
+		Kind:      "worktree",
+		IsMain:    e.path == main.path,
+		IsCurrent: current != "" && samePath(current, e.path),
+	}
+	if e.prunable {
+		// The directory is gone; git still lists it until pruned.
+		wt.Commit = Commit{SHA: e.head, ShortSHA: shortSHA(e.head)}
+		return wt
+	}
+	wt.Commit = readCommit(e.path, e.head)
+	if status, err := gitOutput(e.path, "status", "--porcelain=v2", "--branch"); err == nil {
+		wt.WorkingTree, wt.Remote = parseStatusV2(status)
+	}
+	if numstat, err := gitOutput(e.path, "diff", "HEAD", "--numstat"); err == nil {
+		wt.WorkingTree.Diff = parseNumstat(numstat)
+	}
+	wt.MainState = mainState(e.path, main.branch, e.branch, wt.IsMain)
+	wt.Symbols = symbols(wt.WorkingTree)
+	return wt
+}
+
+func (g Git) Switch(dir, branch string, create bool) error {
+	branch = strings.TrimSpace(branch)
+	if branch == "" {
+		return errors.New("missing branch name")
+	}
+	wts, err := g.List(dir)
+	if err != nil {
+		return err
+	}
+	if len(wts) == 0 {
+		return errors.New("not a git repository")
+	}
+	for _, wt := range wts {
+		if wt.Branch == branch {
+			return nil
+		}
+	}
+	main := wts[0]
+	path := siblingPath(main.Path, branch)
+	switch {
+	case branchExists(main.Path, branch):
+		return gitRun(main.Path, "worktree", "add", path, branch)
+	case create:
+		base := main.Branch
+		if base == "" {
+			base = "HEAD"
+		}
+		return gitRun(main.Path, "worktree", "add", "-b", branch, path, base)
+	default:
+		return fmt.Errorf("branch %q does not exist", branch)
+	}
+}
+
+// Remove removes the worktree and deletes the branch when it is merged,
+// mirroring `wt remove`. Unmerged branches are kept.
+func (g Git) Remove(dir, branch string) error {
+	wts, err := g.List(dir)
+	if err != nil {
+		return err
+	}
+	wt, ok := findBranch(wts, branch)
+	if !ok {
+		return fmt.Errorf("no worktree for branch %q", branch)
+	}
+	if wt.IsMain {
+		return errors.New("cannot remove the main worktree")
+	}
+	if err := gitRun(wts[0].Path, "worktree", "remove", wt.Path); err != nil {
+		return err
+	}
+	_ = gitRun(wts[0].Path, "branch", "-d", branch)
+	return nil
+}
+
+// Merge rebases the branch checked out in dir onto target, fast-forwards
+// target to it, then removes the worktree and branch.
+func (g Git) Merge(dir, target string) error {
+	wts, err := g.List(dir)
+	if err != nil {
+		return err
+	}
+	var source Worktree
+	for _, wt := range wts {
+		if wt.IsCurrent {
+			source = wt
+		}
+	}
+	switch {
+	case source.Path == "":
+		return errors.New("not inside a worktree")
+	case source.Branch == "":
+		return errors.New("worktree has a detached HEAD")
+	}
+	if target == "" {
+		target = wts[0].Branch
+	}
+	if target == "" || target == source.Branch {
+		return fmt.Errorf("nothing to merge %s into", source.Branch)
+	}
+	if dirty(source.WorkingTree) {
+		return fmt.Errorf("%s has uncommitted changes; commit them first", source.Path)
+	}
+
+	if err := gitRun(source.Path, "rebase", target); err != nil {
+		_ = gitRun(source.Path, "rebase", "--abort")
+		return err
+	}
+	if into, ok := findBranch(wts, target); ok {
+		err = gitRun(into.Path, "merge", "--ff-only", source.Branch)
+	} else {
+		err = gitRun(wts[0].Path, "fetch", ".", source.Branch+":"+target)
+	}
+	if err != nil {
+		return err
+	}
+	if source.IsMain {
+		return nil
+	}
+	if err := gitRun(wts[0].Path, "worktree", "remove", source.Path); err != nil {
+		return err
+	}
+	return gitRun(wts[0].Path, "branch", "-d", source.Branch)
+}
+
+// MainPath returns the main worktree of the repository containing dir.
+func MainPath(dir string) (string, error) {
+	out, err := gitOutput(dir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return "", fmt.Errorf("git worktree list: %w", err)
+	}
+	main, ok := mainEntry(parseWorktreeList(out))
+	if !ok {
+		return "", errors.New("not a git repository")
+	}
+	return main.path, nil
+}
+
+// MainBranch returns the branch checked out in the main worktree of the
+// repository containing dir.
+func MainBranch(dir string) (string, error) {
+	out, err := gitOutput(dir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return "", fmt.Errorf("git worktree list: %w", err)
+	}
+	main, ok := mainEntry(parseWorktreeList(out))
+	if !ok || main.branch == "" {
+		return "", errors.New("main worktree has no branch")
+	}
+	return main.branch, nil
+}
+
+// mainEntry returns the main worktree: the first one git lists, or in a
+// bare repository, which has no checkout of its own, the first worktree
+// added to it.
+func mainEntry(entries []worktreeEntry) (worktreeEntry, bool) {
+	for _, e := range entries {
+		if !e.bare {
+			return e, true
+		}
+	}
+	return worktreeEntry{}, false
+}
+
+func (Git) MergeCommand() string  { return "kitmux worktrees merge" }
+func (Git) CommitCommand() string { return "git add -A && git commit" }
+
+type worktreeEntry struct {
+	path     string
+	head     string
+	branch   string
+	bare     bool
+	prunable bool
+}
+
+// parseWorktreeList parses `git worktree list --porcelain`: blocks of
+// "key value" lines separated by blank lines, main worktree first.
+func parseWorktreeList(out string) []worktreeEntry {
+	var entries []worktreeEntry
+	var cur *worktreeEntry
+	for _, line := range strings.Split(out, "\n") {
+		key, value, _ := strings.Cut(strings.TrimRight(line, "\r"), " ")
+		switch key {
+		case "worktree":
+			entries = append(entries, worktreeEntry{path: value})
+			cur = &entries[len(entries)-1]
+		case "HEAD":
+			if cur != nil {
+				cur.head = value
+			}
+		case "branch":
+			if cur != nil {
+				cur.branch = strings.TrimPrefix(value, "refs/heads/")
+			}
+		case "bare":
+			if cur != nil {
+				cur.bare = true
+			}
+		case "prunable":
+			if cur != nil {
+				cur.prunable = true
+			}
+		}
+	}
+	return entries
+}
+
+// parseStatusV2 reads `git status --porcelain=v2 --branch`.
+func parseStatusV2(out string) (WorkingTree, Remote) {
+	var wt WorkingTree
+	var remote Remote
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		switch line[0] {
+		case '#':
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				continue
+			}
+			switch fields[1] {
+			case "branch.upstream":
+				remote.Name, remote.Branch, _ = strings.Cut(fields[2], "/")
+			case "branch.ab":
+				if len(fields) == 4 {
+					remote.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[2], "+"))
+					remote.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[3], "-"))
+				}
+			}
+		case '?':
+			wt.Untracked = true
+		case '1', '2', 'u':
+			if len(line) < 4 {
+				continue
+			}
+			x, y := line[2], line[3]
+			if x != '.' {
+				wt.Staged = true
+			}
+			if y == 'M' || y == 'T' || line[0] == 'u' {
+				wt.Modified = true
+			}
+			if x == 'D' || y == 'D' {
+				wt.Deleted = true
+			}
+			if line[0] == '2' {
+				wt.Renamed = true
+			}
+		}
+	}
+	return wt, remote
+}
+
+// parseNumstat sums `git diff --numstat`. Binary files report "-" and are
+// skipped.
+func parseNumstat(out string) Diff {
+	var d Diff
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		added, errA := strconv.Atoi(fields[0])
+		deleted, errD := strconv.Atoi(fields[1])
+		if errA != nil || errD != nil {
+			continue
+		}
+		d.Added += added
+		d.Deleted += deleted
+	}
+	return d
+}
+
+// symbols renders working tree state with worktrunk's markers.
+func symbols(wt WorkingTree) string {
+	var b strings.Builder
+	if wt.Staged {
+		b.WriteString("+")
+	}
+	if wt.Modified {
+		b.WriteString("!")
+	}
+	if wt.Untracked {
+		b.WriteString("?")
+	}
+	if wt.Renamed {
+		b.WriteString("»")
+	}
+	if wt.Deleted {
+		b.WriteString("✘")
+	}
+	return b.String()
+}
+
+func dirty(wt WorkingTree) bool {
+	return wt.Staged || wt.Modified || wt.Untracked || wt.Renamed || wt.Deleted
+}
+
+// mainState compares a branch with the main worktree's branch.
+func mainState(path, mainBranch, branch string, isMain bool) string {
+	if isMain {
+		return "is_main"
+	}
+	if mainBranch == "" || branch == "" {
+		return ""
+	}
+	out, err := gitOutput(path, "rev-list", "--left-right", "--count", mainBranch+"..."+branch)
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return ""
+	}
+	behind, _ := strconv.Atoi(fields[0])
+	ahead, _ := strconv.Atoi(fields[1])
+	switch {
+	case ahead > 0 && behind > 0:
+		return "diverged"
+	case ahead > 0:
+		return "ahead"
+	case behind > 0:
+		return "behind"
+	default:
+		return "integrated"
+	}
+}
+
+func readCommit(path, head string) Commit {
+	c := Commit{SHA: head, ShortSHA: shortSHA(head)}
+	out, err := gitOutput(path, "log", "-1", "--format=%h%x00%ct%x00%s")
+	if err != nil {
+		return c
+	}
+	parts := strings.SplitN(out, "\x00", 3)
+	if len(parts) != 3 {
+		return c
+	}
+	c.ShortSHA = parts[0]
+	c.Timestamp, _ = strconv.ParseInt(parts[1], 10, 64)
+	c.Message = parts[2]
+	return c
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// siblingPath places a branch's worktree next to the main one.
+func siblingPath(mainPath, branch string) string {
+	name := strings.NewReplacer("/", "-", "\\", "-").Replace(branch)
+	return filepath.Join(filepath.Dir(mainPath), filepath.Base(mainPath)+"."+name)
+}
+
+func findBranch(wts []Worktree, branch string) (Worktree, bool) {
+	for _, wt := range wts {
+		if wt.Branch == branch {
+			return wt, true
+		}
+	}
+	return Worktree{}, false
+}
+
+func samePath(a, b string) bool {
+	if ra, err := filepath.EvalSymlinks(a); err == nil {
+		a = ra
+	}
+	if rb, err := filepath.EvalSymlinks(b); err == nil {
+		b = rb
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+func gitRun(dir string, args ...string) error {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s: %w (%s)", args[0], err, trimOutput(out))
+	}
+	return nil
+}
+
+func trimOutput(out []byte) string {
+	return strings.TrimSpace(string(out))
+}
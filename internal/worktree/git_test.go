@@ -0,0 +1,222 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseWorktreeList(t *testing.T) {
+	t.Parallel()
+
+	out := strings.Join([]string{
+		"worktree /src/app",
+		"HEAD 1111111111111111111111111111111111111111",
+		"branch refs/heads/main",
+		"",
+		"worktree /src/app.feat-login",
+		"HEAD 2222222222222222222222222222222222222222",
+		"branch refs/heads/feat/login",
+		"",
+		"worktree /src/app.detached",
+		"HEAD 3333333333333333333333333333333333333333",
+		"detached",
+		"prunable gitdir file points to non-existent location",
+		"",
+	}, "\n")
+
+	got := parseWorktreeList(out)
+	if len(got) != 3 {
+		t.Fatalf("got %d entries, want 3: %+v", len(got), got)
+	}
+	if got[0].path != "/src/app" || got[0].branch != "main" {
+		t.Fatalf("main entry = %+v", got[0])
+	}
+	if got[1].branch != "feat/login" || got[1].head[:4] != "2222" {
+		t.Fatalf("feature entry = %+v", got[1])
+	}
+	if got[2].branch != "" || !got[2].prunable {
+		t.Fatalf("detached entry = %+v", got[2])
+	}
+}
+
+func TestParseStatusV2(t *testing.T) {
+	t.Parallel()
+
+	out := strings.Join([]string{
+		"# branch.oid 1111111111111111111111111111111111111111",
+		"# branch.head feat/login",
+		"# branch.upstream origin/feat/login",
+		"# branch.ab +2 -1",
+		"1 M. N... 100644 100644 100644 aaa bbb staged.go",
+		"1 .M N... 100644 100644 100644 aaa bbb edited.go",
+		"2 R. N... 100644 100644 100644 aaa bbb R100 new.go\told.go",
+		"1 .D N... 100644 100644 000000 aaa aaa gone.go",
+		"? scratch.txt",
+	}, "\n")
+
+	wt, remote := parseStatusV2(out)
+	want := WorkingTree{Staged: true, Modified: true, Untracked: true, Renamed: true, Deleted: true}
+	if wt != want {
+		t.Fatalf("working tree = %+v, want %+v", wt, want)
+	}
+	if remote != (Remote{Name: "origin", Branch: "feat/login", Ahead: 2, Behind: 1}) {
+		t.Fatalf("remote = %+v", remote)
+	}
+	if got := symbols(wt); got != "+!?»✘" {
+		t.Fatalf("symbols = %q", got)
+	}
+}
+
+func TestParseNumstatSkipsBinary(t *testing.T) {
+	t.Parallel()
+
+	got := parseNumstat("3\t1\ta.go\n-\t-\timage.png\n10\t0\tb.go\n")
+	if got != (Diff{Added: 13, Deleted: 1}) {
+		t.Fatalf("diff = %+v", got)
+	}
+}
+
+func TestSiblingPath(t *testing.T) {
+	t.Parallel()
+
+	if got := siblingPath("/src/app", "feat/login"); got != "/src/app.feat-login" {
+		t.Fatalf("siblingPath = %q", got)
+	}
+}
+
+func TestGitBackendLifecycle(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := filepath.Join(t.TempDir(), "app")
+	gitInit(t, root)
+
+	g := Git{}
+	if err := g.Switch(root, "feat/login", false); err == nil {
+		t.Fatal("expected switch to a missing branch to fail without create")
+	}
+	if err := g.Switch(root, "feat/login", true); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	featurePath := root + ".feat-login"
+	writeFile(t, featurePath, "login.go", "package app\n\nfunc Login() {}\n")
+
+	wts, err := g.List(featurePath)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(wts) != 2 {
+		t.Fatalf("got %d worktrees, want 2", len(wts))
+	}
+	main, feature := wts[0], wts[1]
+	if !main.IsMain || main.Branch != "main" || main.MainState != "is_main" || main.IsCurrent {
+		t.Fatalf("main = %+v", main)
+	}
+	if feature.Branch != "feat/login" || !feature.IsCurrent || !feature.WorkingTree.Untracked || feature.Symbols != "?" {
+		t.Fatalf("feature = %+v", feature)
+	}
+	if feature.Commit.Message != "initial" || feature.Commit.Timestamp == 0 {
+		t.Fatalf("commit = %+v", feature.Commit)
+	}
+
+	if err := g.Merge(featurePath, ""); err == nil || !strings.Contains(err.Error(), "uncommitted") {
+		t.Fatalf("merge of dirty worktree err = %v", err)
+	}
+	git(t, featurePath, "add", "-A")
+	git(t, featurePath, "commit", "-m", "add login")
+
+	wts, _ = g.List(featurePath)
+	if wts[1].MainState != "ahead" {
+		t.Fatalf("feature main state = %q, want ahead", wts[1].MainState)
+	}
+
+	if err := g.Merge(featurePath, ""); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "login.go")); err != nil {
+		t.Fatalf("expected login.go on main: %v", err)
+	}
+	if _, err := os.Stat(featurePath); !os.IsNotExist(err) {
+		t.Fatalf("expected feature worktree removed, stat err = %v", err)
+	}
+	if branchExists(root, "feat/login") {
+		t.Fatal("expected merged branch deleted")
+	}
+
+	if err := g.Switch(root, "spike", true); err != nil {
+		t.Fatalf("create spike: %v", err)
+	}
+	if err := g.Remove(root, "spike"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if wts, _ := g.List(root); len(wts) != 1 {
+		t.Fatalf("worktrees after remove = %+v", wts)
+	}
+
+	if err := g.Switch(root, "docs", true); err != nil {
+		t.Fatalf("create docs: %v", err)
+	}
+	if main, err := MainPath(root + ".docs"); err != nil || !samePath(main, root) {
+		t.Fatalf("MainPath = %q, %v; want %q", main, err, root)
+	}
+}
+
+func TestGitBackendSkipsBareMain(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	base := t.TempDir()
+	src := filepath.Join(base, "src")
+	gitInit(t, src)
+	bare := filepath.Join(base, "app.git")
+	git(t, base, "clone", "-q", "--bare", src, bare)
+	git(t, bare, "worktree", "add", "-q", filepath.Join(base, "app"), "main")
+	git(t, bare, "worktree", "add", "-q", "-b", "feat", filepath.Join(base, "app.feat"), "main")
+
+	wts, err := Git{}.List(filepath.Join(base, "app.feat"))
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(wts) != 2 {
+		t.Fatalf("got %d worktrees, want 2: %+v", len(wts), wts)
+	}
+	if !wts[0].IsMain || wts[0].Branch != "main" || wts[0].MainState != "is_main" || wts[1].IsMain {
+		t.Fatalf("worktrees = %+v, want the main checkout as main", wts)
+	}
+	if main, err := MainPath(bare); err != nil || !samePath(main, filepath.Join(base, "app")) {
+		t.Fatalf("MainPath = %q, %v", main, err)
+	}
+	if branch, err := MainBranch(bare); err != nil || branch != "main" {
+		t.Fatalf("MainBranch = %q, %v", branch, err)
+	}
+}
+
+func gitInit(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "init", "-q", "-b", "main")
+	git(t, dir, "config", "user.email", "test@example.com")
+	git(t, dir, "config", "user.name", "Test")
+	writeFile(t, dir, "README.md", "app\n")
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-q", "-m", "initial")
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if err := gitRun(dir, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,70 @@
+package worktree
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+)
+
+// Worktrunk shells out to the `wt` CLI.
+type Worktrunk struct{}
+
+var _ Backend = Worktrunk{}
+
+func (Worktrunk) Name() string { return BackendWT }
+
+// List parses `wt list --format=json`.
+func (Worktrunk) List(dir string) ([]Worktree, error) {
+	out, err := wtCommand(dir, "list", "--format=json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("wt list: %w", err)
+	}
+	var wts []Worktree
+	if err := json.Unmarshal(out, &wts); err != nil {
+		return nil, fmt.Errorf("wt list parse: %w", err)
+	}
+	return wts, nil
+}
+
+// Switch runs `wt switch`. Without a directory it behaves like the
+// interactive command; with one it passes --no-cd.
+func (Worktrunk) Switch(dir, branch string, create bool) error {
+	args := []string{"switch"}
+	if dir != "" {
+		args = append(args, "--no-cd")
+	}
+	if create {
+		args = append(args, "--create")
+	}
+	args = append(args, branch)
+	if err := wtCommand(dir, args...).Run(); err != nil {
+		return fmt.Errorf("wt switch: %w", err)
+	}
+	return nil
+}
+
+func (Worktrunk) Remove(dir, branch string) error {
+	return wtCommand(dir, "remove", branch).Run()
+}
+
+func (Worktrunk) Merge(dir, target string) error {
+	args := []string{"merge"}
+	if target != "" {
+		args = append(args, target)
+	}
+	if out, err := wtCommand(dir, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("wt merge: %w (%s)", err, trimOutput(out))
+	}
+	return nil
+}
+
+func (Worktrunk) MergeCommand() string  { return "wt merge" }
+func (Worktrunk) CommitCommand() string { return "wt step commit" }
+
+func wtCommand(dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command("wt", args...)
+	if dir != "" {
+		cmd.Dir = dir
+	}
+	return cmd
+}
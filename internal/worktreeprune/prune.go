@@ -0,0 +1,301 @@
+// Package worktreeprune classifies the worktrees of a workspace as merged,
+// gone upstream, stale, clean or dirty, and removes the ones that are done
+// together with the tmux sessions rooted in them.
+package worktreeprune
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	wsreg "github.com/miltonparedes/kitmux/internal/workspaces"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+	"github.com/miltonparedes/kitmux/internal/worktree"
+)
+
+// Reasons a worktree is proposed for removal.
+const (
+	ReasonMerged = "merged"
+	ReasonGone   = "gone upstream"
+	ReasonStale  = "stale"
+)
+
+// Skip reasons reported for worktrees that are kept.
+const (
+	SkipDirty    = "uncommitted changes"
+	SkipAttached = "session attached"
+	SkipDetached = "detached HEAD"
+)
+
+// Candidate is a linked worktree of the workspace. Reasons is empty when
+// nothing suggests the worktree is done; Skip names the rule that protects
+// it otherwise.
+type Candidate struct {
+	Worktree wsdata.WorktreeStat
+	Reasons  []string
+	Age      time.Duration // since the last commit
+	Sessions []string      // tmux sessions rooted in the worktree
+	Skip     string
+}
+
+// Removable reports whether the candidate is proposed for removal.
+func (c Candidate) Removable() bool {
+	return len(c.Reasons) > 0 && c.Skip == ""
+}
+
+// Options tune the classification.
+type Options struct {
+	// StaleAfter is how long a worktree may go without a commit before it
+	// is considered abandoned. Zero disables the check.
+	StaleAfter time.Duration
+	// Force proposes dirty worktrees too. Their changes are discarded.
+	Force bool
+}
+
+// Result summarizes a cleanup run.
+type Result struct {
+	Removed []string
+	Killed  []string
+	Failed  []string
+}
+
+// Ops are the side effects of a cleanup: worktree stats and branch state
+// from git, the tmux server, removal and the clock.
+type Ops struct {
+	Tmux           tmux.Client
+	LoadStats      func(workspace string) ([]wsdata.WorktreeStat, error)
+	MergedBranches func(workspace, base string) (map[string]bool, error)
+	GoneBranches   func(workspace string) (map[string]bool, error)
+	RemoveWorktree func(workspace string, wt wsdata.WorktreeStat, force bool) error
+	Forget         func(workspace, worktreePath string)
+	Now            func() time.Time
+}
+
+// DefaultOps cleans up against git and the real tmux server.
+func DefaultOps() Ops {
+	svc := wsdata.NewStatsService()
+	return Ops{
+		Tmux: tmux.Default(),
+		LoadStats: func(workspace string) ([]wsdata.WorktreeStat, error) {
+			res := svc.Refresh(workspace)
+			return res.Stats.Worktrees, res.Err
+		},
+		MergedBranches: mergedBranches,
+		GoneBranches:   goneBranches,
+		RemoveWorktree: removeWorktree,
+		Forget: func(workspace, worktreePath string) {
+			_ = wsreg.RemoveArchivedWorktree(workspace, worktreePath)
+			_ = svc.Invalidate(workspace)
+		},
+		Now: time.Now,
+	}
+}
+
+// Plan classifies every linked worktree of workspace, oldest commit first.
+// Stats are refreshed first so dirty state is current.
+func Plan(workspace string, opts Options, ops Ops) ([]Candidate, error) {
+	stats, err := ops.LoadStats(workspace)
+	if err != nil {
+		return nil, fmt.Errorf("load worktree stats: %w", err)
+	}
+	base := ""
+	for _, st := range stats {
+		if st.IsMain {
+			base = st.Branch
+		}
+	}
+	merged := map[string]bool{}
+	if base != "" {
+		if merged, err = ops.MergedBranches(workspace, base); err != nil {
+			return nil, fmt.Errorf("list merged branches: %w", err)
+		}
+	}
+	gone, err := ops.GoneBranches(workspace)
+	if err != nil {
+		return nil, fmt.Errorf("list upstream branches: %w", err)
+	}
+	sessions, err := ops.Tmux.ListSessions()
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	now := ops.Now()
+
+	var out []Candidate
+	for _, st := range stats {
+		if st.IsMain || st.WorktreePath == "" {
+			continue
+		}
+		c := Candidate{Worktree: st}
+		if st.CommitTS > 0 {
+			c.Age = now.Sub(time.Unix(st.CommitTS, 0))
+		}
+		if st.Branch != "" && merged[st.Branch] {
+			c.Reasons = append(c.Reasons, ReasonMerged)
+		}
+		if st.Branch != "" && gone[st.Branch] {
+			c.Reasons = append(c.Reasons, ReasonGone)
+		}
+		if opts.StaleAfter > 0 && st.CommitTS > 0 && c.Age >= opts.StaleAfter {
+			c.Reasons = append(c.Reasons, ReasonStale)
+		}
+		attached := false
+		for _, s := range sessions {
+			if s.Path != "" && within(s.Path, st.WorktreePath, stats) {
+				c.Sessions = append(c.Sessions, s.Name)
+				attached = attached || s.Attached
+			}
+		}
+		switch {
+		case st.Branch == "":
+			c.Skip = SkipDetached
+		case attached:
+			c.Skip = SkipAttached
+		case st.Dirty() && !opts.Force:
+			c.Skip = SkipDirty
+		}
+		out = append(out, c)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Age > out[j].Age })
+	return out, nil
+}
+
+// within reports whether dir belongs to the worktree at path rather than to
+// a worktree nested inside it.
+func within(dir, path string, stats []wsdata.WorktreeStat) bool {
+	dir, path = filepath.Clean(dir), filepath.Clean(path)
+	if dir != path && !strings.HasPrefix(dir, path+string(filepath.Separator)) {
+		return false
+	}
+	for _, other := range stats {
+		p := filepath.Clean(other.WorktreePath)
+		if len(p) > len(path) && (dir == p || strings.HasPrefix(dir, p+string(filepath.Separator))) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply kills the sessions of the given candidates and then removes them,
+// so nothing still runs in a worktree as it goes away. Callers pass the
+// batch the user accepted, usually the Removable ones; candidates with a
+// skip reason are never touched. A worktree whose session survives is
+// kept. Dirty worktrees only get here when planned with Force and are
+// removed forcibly. Failures do not stop the run.
+func Apply(workspace string, candidates []Candidate, ops Ops) Result {
+	var res Result
+	for _, c := range candidates {
+		if c.Skip != "" {
+			continue
+		}
+		wt := c.Worktree
+		killed := true
+		for _, name := range c.Sessions {
+			if err := ops.Tmux.KillSession(name); err != nil {
+				res.Failed = append(res.Failed, fmt.Sprintf("session %s (%v)", name, err))
+				killed = false
+				continue
+			}
+			res.Killed = append(res.Killed, name)
+		}
+		if !killed {
+			res.Failed = append(res.Failed, fmt.Sprintf("%s (session still running)", wt.Branch))
+			continue
+		}
+		if err := ops.RemoveWorktree(workspace, wt, wt.Dirty()); err != nil {
+			res.Failed = append(res.Failed, fmt.Sprintf("%s (%v)", wt.Branch, err))
+			continue
+		}
+		res.Removed = append(res.Removed, wt.WorktreePath)
+		ops.Forget(workspace, wt.WorktreePath)
+	}
+	return res
+}
+
+// Describe renders a candidate's classification: reasons, or "active" when
+// there are none, followed by clean or dirty.
+func (c Candidate) Describe() string {
+	parts := append([]string(nil), c.Reasons...)
+	if len(parts) == 0 {
+		parts = append(parts, "active")
+	}
+	if c.Worktree.Dirty() {
+		parts = append(parts, "dirty")
+	} else {
+		parts = append(parts, "clean")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// mergedBranches lists the branches merged into base that carry work of
+// their own. A branch created off base and never committed to is merged in
+// git's eyes but is not done; its reflog holds nothing but its creation.
+// Without a reflog, a branch still at base's tip is taken to be such a one.
+func mergedBranches(workspace, base string) (map[string]bool, error) {
+	tip, err := exec.Command("git", "-C", workspace, "rev-parse", base).Output()
+	if err != nil {
+		return nil, err
+	}
+	baseTip := strings.TrimSpace(string(tip))
+	return branchSet(workspace, func(line string) (string, bool) {
+		branch, sha, _ := strings.Cut(line, "\t")
+		committed, known := committedTo(workspace, branch)
+		if !known {
+			committed = sha != baseTip
+		}
+		return branch, committed
+	}, "branch", "--format=%(refname:short)%09%(objectname)", "--merged", base)
+}
+
+// committedTo reports whether branch moved after it was created, and
+// whether its reflog could tell.
+func committedTo(workspace, branch string) (committed, known bool) {
+	out, err := exec.Command("git", "-C", workspace, "reflog", "show", "--format=%gs", "refs/heads/"+branch).Output()
+	if err != nil || strings.TrimSpace(string(out)) == "" {
+		return false, false
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if !strings.HasPrefix(line, "branch: Created from") {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+func goneBranches(workspace string) (map[string]bool, error) {
+	return branchSet(workspace, func(line string) (string, bool) {
+		branch, track, _ := strings.Cut(line, "\t")
+		return branch, track == "[gone]"
+	}, "for-each-ref", "--format=%(refname:short)%09%(upstream:track)", "refs/heads")
+}
+
+func branchSet(dir string, keep func(string) (string, bool), args ...string) (map[string]bool, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if branch, ok := keep(line); ok && branch != "" {
+			set[branch] = true
+		}
+	}
+	return set, nil
+}
+
+// removeWorktree uses the configured backend, or git directly when the
+// worktree has changes to discard. The branch is only deleted when merged.
+func removeWorktree(workspace string, wt wsdata.WorktreeStat, force bool) error {
+	if !force {
+		return worktree.RemoveInDir(workspace, wt.Branch)
+	}
+	out, err := exec.Command("git", "-C", workspace, "worktree", "remove", "--force", wt.WorktreePath).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git worktree remove: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
+	_ = exec.Command("git", "-C", workspace, "branch", "-d", wt.Branch).Run()
+	return nil
+}
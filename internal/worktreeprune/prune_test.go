@@ -0,0 +1,261 @@
+package worktreeprune
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+var testNow = time.Unix(1_700_000_000, 0)
+
+func daysAgo(d int) int64 {
+	return testNow.Add(-time.Duration(d) * 24 * time.Hour).Unix()
+}
+
+type fakeOps struct {
+	srv       *tmuxtest.Server
+	removed   []string
+	forced    []string
+	forgotten []string
+	outlived  []string // sessions still running in a worktree as it was removed
+	failOn    string
+}
+
+func (f *fakeOps) ops(stats []wsdata.WorktreeStat, sessions []tmux.Session, merged, gone []string) Ops {
+	set := func(names []string) map[string]bool {
+		m := make(map[string]bool)
+		for _, n := range names {
+			m[n] = true
+		}
+		return m
+	}
+	f.srv = tmuxtest.New()
+	for _, s := range sessions {
+		target := f.srv.AddSession(s.Name, s.Path)
+		if s.Attached {
+			f.srv.Attach(target)
+		}
+	}
+	return Ops{
+		Tmux:           f.srv,
+		LoadStats:      func(string) ([]wsdata.WorktreeStat, error) { return stats, nil },
+		MergedBranches: func(string, string) (map[string]bool, error) { return set(merged), nil },
+		GoneBranches:   func(string) (map[string]bool, error) { return set(gone), nil },
+		RemoveWorktree: func(_ string, wt wsdata.WorktreeStat, force bool) error {
+			live, _ := f.srv.ListSessions()
+			for _, s := range live {
+				if within(s.Path, wt.WorktreePath, stats) {
+					f.outlived = append(f.outlived, s.Name)
+				}
+			}
+			if wt.Branch == f.failOn {
+				return errors.New("locked")
+			}
+			f.removed = append(f.removed, wt.Branch)
+			if force {
+				f.forced = append(f.forced, wt.Branch)
+			}
+			return nil
+		},
+		Forget: func(_, path string) { f.forgotten = append(f.forgotten, path) },
+		Now:    func() time.Time { return testNow },
+	}
+}
+
+// killed lists the sessions the cleanup killed on the fake server.
+func (f *fakeOps) killed() []string {
+	var names []string
+	for _, call := range f.srv.Calls() {
+		if name, ok := strings.CutPrefix(call, "kill-session "); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func testStats() []wsdata.WorktreeStat {
+	return []wsdata.WorktreeStat{
+		{WorktreePath: "/repos/app", Branch: "main", IsMain: true, CommitTS: daysAgo(0)},
+		{WorktreePath: "/repos/app.merged", Branch: "merged", CommitTS: daysAgo(3)},
+		{WorktreePath: "/repos/app.gone", Branch: "gone", CommitTS: daysAgo(2)},
+		{WorktreePath: "/repos/app.old", Branch: "old", CommitTS: daysAgo(30)},
+		{WorktreePath: "/repos/app.active", Branch: "active", CommitTS: daysAgo(1)},
+		{WorktreePath: "/repos/app.dirty", Branch: "dirty", CommitTS: daysAgo(40), Modified: true},
+		{WorktreePath: "/repos/app.busy", Branch: "busy", CommitTS: daysAgo(50)},
+		{WorktreePath: "/repos/app.head", CommitTS: daysAgo(60)},
+	}
+}
+
+func TestPlanClassifiesWorktrees(t *testing.T) {
+	sessions := []tmux.Session{
+		{Name: "app", Path: "/repos/app"},
+		{Name: "merged", Path: "/repos/app.merged/src"},
+		{Name: "busy", Path: "/repos/app.busy", Attached: true},
+	}
+	f := &fakeOps{}
+	got, err := Plan("/repos/app", Options{StaleAfter: 14 * 24 * time.Hour},
+		f.ops(testStats(), sessions, []string{"main", "merged"}, []string{"gone"}))
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+
+	type row struct {
+		reasons  string
+		skip     string
+		sessions string
+	}
+	rows := make(map[string]row)
+	var order []string
+	for _, c := range got {
+		key := c.Worktree.Branch
+		if key == "" {
+			key = "(detached)"
+		}
+		order = append(order, key)
+		rows[key] = row{strings.Join(c.Reasons, ","), c.Skip, strings.Join(c.Sessions, ",")}
+	}
+	want := map[string]row{
+		"merged":     {reasons: ReasonMerged, sessions: "merged"},
+		"gone":       {reasons: ReasonGone},
+		"old":        {reasons: ReasonStale},
+		"active":     {},
+		"dirty":      {reasons: ReasonStale, skip: SkipDirty},
+		"busy":       {reasons: ReasonStale, skip: SkipAttached, sessions: "busy"},
+		"(detached)": {reasons: ReasonStale, skip: SkipDetached},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("classification = %#v, want %#v", rows, want)
+	}
+	if order[0] != "(detached)" || order[len(order)-1] != "active" {
+		t.Fatalf("expected oldest first, got %v", order)
+	}
+}
+
+func TestPlanForceProposesDirty(t *testing.T) {
+	f := &fakeOps{}
+	got, err := Plan("/repos/app", Options{StaleAfter: 14 * 24 * time.Hour, Force: true},
+		f.ops(testStats(), nil, nil, nil))
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	for _, c := range got {
+		if c.Worktree.Branch == "dirty" {
+			if !c.Removable() {
+				t.Fatalf("dirty worktree not removable with Force: skip %q", c.Skip)
+			}
+			if c.Describe() != "stale, dirty" {
+				t.Fatalf("Describe() = %q", c.Describe())
+			}
+			return
+		}
+	}
+	t.Fatal("dirty worktree missing from plan")
+}
+
+func TestApplyRemovesAndKillsSessions(t *testing.T) {
+	sessions := []tmux.Session{
+		{Name: "merged", Path: "/repos/app.merged"},
+		{Name: "old", Path: "/repos/app.old"},
+	}
+	f := &fakeOps{failOn: "old"}
+	ops := f.ops(testStats(), sessions, []string{"merged"}, nil)
+	candidates, err := Plan("/repos/app", Options{StaleAfter: 14 * 24 * time.Hour, Force: true}, ops)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+
+	var batch []Candidate
+	for _, c := range candidates {
+		if c.Removable() {
+			batch = append(batch, c)
+		}
+	}
+	res := Apply("/repos/app", batch, ops)
+
+	if !reflect.DeepEqual(f.removed, []string{"busy", "dirty", "merged"}) {
+		t.Fatalf("removed = %v", f.removed)
+	}
+	if !reflect.DeepEqual(f.forced, []string{"dirty"}) {
+		t.Fatalf("forced = %v, want only the dirty worktree", f.forced)
+	}
+	if !reflect.DeepEqual(f.killed(), []string{"old", "merged"}) {
+		t.Fatalf("killed = %v", f.killed())
+	}
+	if len(f.outlived) != 0 {
+		t.Fatalf("sessions %v still ran in worktrees being removed", f.outlived)
+	}
+	if len(res.Failed) != 1 || !strings.HasPrefix(res.Failed[0], "old") {
+		t.Fatalf("Failed = %v", res.Failed)
+	}
+	if len(f.forgotten) != len(res.Removed) {
+		t.Fatalf("forgot %v, removed %v", f.forgotten, res.Removed)
+	}
+}
+
+func TestApplySkipsProtectedCandidates(t *testing.T) {
+	f := &fakeOps{}
+	ops := f.ops(testStats(), nil, nil, nil)
+	res := Apply("/repos/app", []Candidate{{
+		Worktree: wsdata.WorktreeStat{WorktreePath: "/repos/app.dirty", Branch: "dirty", Modified: true},
+		Reasons:  []string{ReasonStale},
+		Skip:     SkipDirty,
+	}}, ops)
+	if len(f.removed) != 0 || len(res.Removed) != 0 {
+		t.Fatalf("removed a protected worktree: %v", f.removed)
+	}
+}
+
+func TestApplyKeepsWorktreeWhenSessionSurvives(t *testing.T) {
+	f := &fakeOps{}
+	ops := f.ops(testStats(), []tmux.Session{{Name: "merged", Path: "/repos/app.merged"}}, []string{"merged"}, nil)
+	f.srv.Fail("kill-session", errors.New("permission denied"))
+	res := Apply("/repos/app", []Candidate{{
+		Worktree: wsdata.WorktreeStat{WorktreePath: "/repos/app.merged", Branch: "merged"},
+		Reasons:  []string{ReasonMerged},
+		Sessions: []string{"merged"},
+	}}, ops)
+	if len(f.removed) != 0 || len(res.Removed) != 0 {
+		t.Fatalf("removed %v while its session still runs", f.removed)
+	}
+	if len(res.Failed) != 2 {
+		t.Fatalf("Failed = %v, want the session and the kept worktree", res.Failed)
+	}
+}
+
+func TestMergedBranchesIgnoresBranchesWithoutCommits(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		args = append([]string{"-C", dir, "-c", "user.email=test@example.com", "-c", "user.name=Test"}, args...)
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v (%s)", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	git("commit", "-q", "--allow-empty", "-m", "initial")
+	git("branch", "fresh")
+	git("switch", "-q", "-c", "done")
+	git("commit", "-q", "--allow-empty", "-m", "work")
+	git("switch", "-q", "main")
+	git("merge", "-q", "--ff-only", "done")
+	git("branch", "later")
+	git("commit", "-q", "--allow-empty", "-m", "more")
+
+	got, err := mergedBranches(dir, "main")
+	if err != nil {
+		t.Fatalf("mergedBranches() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string]bool{"done": true, "main": true}) {
+		t.Fatalf("merged = %v, want only branches with commits of their own", got)
+	}
+}
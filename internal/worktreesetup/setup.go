@@ -0,0 +1,281 @@
+// Package worktreesetup provisions freshly created worktrees with the steps
+// listed under [[worktree.setup]] in the repository's .kitmux.toml: copying
+// or symlinking untracked files from the main worktree, running setup
+// commands and setting the tmux session environment.
+package worktreesetup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/tmux"
+	"github.com/miltonparedes/kitmux/internal/worktree"
+)
+
+// Target is the worktree being provisioned.
+type Target struct {
+	Main    string // main worktree, the source for copy and symlink steps
+	Path    string // the new worktree
+	Session string // tmux session for env steps; empty skips the tmux side
+}
+
+// Steps returns the setup steps of the repository containing dir.
+func Steps(dir string) ([]config.SetupStep, error) {
+	repo, err := config.LoadRepo(dir)
+	if err != nil {
+		return nil, err
+	}
+	return repo.Worktree.Setup, nil
+}
+
+// HasSteps reports whether the repository containing dir provisions new
+// worktrees.
+func HasSteps(dir string) bool {
+	steps, err := Steps(dir)
+	return err == nil && len(steps) > 0
+}
+
+// Env returns the variables the env steps set, later steps winning. A
+// session opened on a worktree after its setup ran is created with them.
+func Env(steps []config.SetupStep) map[string]string {
+	var env map[string]string
+	for _, step := range steps {
+		if step.Kind() != "env" {
+			continue
+		}
+		if env == nil {
+			env = make(map[string]string)
+		}
+		for k, v := range step.Env {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// Export prefixes command with exports of env, for a pane that should start
+// with a worktree's variables when its session is shared with others.
+func Export(env map[string]string, command string) string {
+	if len(env) == 0 {
+		return command
+	}
+	var b strings.Builder
+	for _, key := range sortedKeys(env) {
+		b.WriteString("export " + key + "=" + shellQuote(env[key]) + "; ")
+	}
+	return b.String() + command
+}
+
+// Provision runs the setup steps for the freshly created worktree at path
+// in a popup on client and returns the variables its env steps set, so the
+// session opened on it afterwards starts with them. The popup streams the
+// steps and stays up until dismissed. Repos without steps are left alone.
+func Provision(client tmux.Client, path string) (map[string]string, error) {
+	steps, err := Steps(path)
+	if err != nil || len(steps) == 0 {
+		return nil, err
+	}
+	if err := client.DisplayPopup(Command("", path), "80%", "80%"); err != nil {
+		return nil, fmt.Errorf("setup popup: %w", err)
+	}
+	return Env(steps), nil
+}
+
+// Command is the shell command that provisions paths in a popup or pane,
+// streaming output and waiting for a key once done.
+func Command(session string, paths ...string) string {
+	parts := []string{"kitmux", "worktrees", "setup", "--wait"}
+	if session != "" {
+		parts = append(parts, "--session", shellQuote(session))
+	}
+	for _, p := range paths {
+		parts = append(parts, shellQuote(p))
+	}
+	return strings.Join(parts, " ")
+}
+
+// MainWorktree returns the main worktree of the repository containing dir.
+func MainWorktree(dir string) (string, error) {
+	return worktree.MainPath(dir)
+}
+
+// Run executes steps in order, writing progress and command output to out.
+// It stops at the first failing step and returns its error. The worktree is
+// left in place either way.
+func Run(t Target, steps []config.SetupStep, out io.Writer) error {
+	env := os.Environ()
+	for i, step := range steps {
+		_, _ = fmt.Fprintf(out, "==> [%d/%d] %s\n", i+1, len(steps), describe(step))
+		var err error
+		switch step.Kind() {
+		case "copy":
+			err = eachSource(t, step.Copy, out, copyPath)
+		case "symlink":
+			err = eachSource(t, step.Symlink, out, func(src, dst string) error {
+				return os.Symlink(src, dst)
+			})
+		case "run":
+			err = runCommand(t.Path, step.Run, env, out)
+		case "env":
+			env, err = setEnv(t.Session, step.Env, env, out)
+		default:
+			err = errors.New("step must set exactly one of copy, symlink, run or env")
+		}
+		if err != nil {
+			return fmt.Errorf("step %d (%s): %w", i+1, step.Kind(), err)
+		}
+	}
+	return nil
+}
+
+func describe(step config.SetupStep) string {
+	switch step.Kind() {
+	case "copy":
+		return "copy " + strings.Join(step.Copy, " ")
+	case "symlink":
+		return "symlink " + strings.Join(step.Symlink, " ")
+	case "run":
+		return "run " + step.Run
+	case "env":
+		return "env " + strings.Join(sortedKeys(step.Env), " ")
+	}
+	return "invalid step"
+}
+
+// eachSource resolves patterns against the main worktree and applies fn to
+// every match whose destination in the new worktree does not exist yet.
+// Patterns with no match are reported and skipped: a missing .env is not a
+// reason to fail the whole setup.
+func eachSource(t Target, patterns []string, out io.Writer, fn func(src, dst string) error) error {
+	for _, pattern := range patterns {
+		if filepath.IsAbs(pattern) || escapes(pattern) {
+			return fmt.Errorf("%s: path must be inside the worktree", pattern)
+		}
+		matches, err := filepath.Glob(filepath.Join(t.Main, pattern))
+		if err != nil {
+			return fmt.Errorf("%s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			_, _ = fmt.Fprintf(out, "    %s: not in the main worktree, skipped\n", pattern)
+			continue
+		}
+		for _, src := range matches {
+			rel, err := filepath.Rel(t.Main, src)
+			if err != nil {
+				return err
+			}
+			dst := filepath.Join(t.Path, rel)
+			if _, err := os.Lstat(dst); err == nil {
+				_, _ = fmt.Fprintf(out, "    %s: already present, kept\n", rel)
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+				return err
+			}
+			if err := fn(src, dst); err != nil {
+				return fmt.Errorf("%s: %w", rel, err)
+			}
+			_, _ = fmt.Fprintf(out, "    %s\n", rel)
+		}
+	}
+	return nil
+}
+
+func escapes(rel string) bool {
+	clean := filepath.Clean(rel)
+	return clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
+
+// copyPath copies a file, symlink or directory tree, keeping file modes.
+func copyPath(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func runCommand(dir, command string, env []string, out io.Writer) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return cmd.Run()
+}
+
+// setEnv sets the variables in the tmux session and in the environment of
+// later run steps.
+func setEnv(session string, vars map[string]string, env []string, out io.Writer) ([]string, error) {
+	if session == "" {
+		_, _ = fmt.Fprintln(out, "    no tmux session; applied to later run steps only")
+	}
+	for _, key := range sortedKeys(vars) {
+		if session != "" {
+			if err := tmux.SetEnvironment(session, key, vars[key]); err != nil {
+				return env, fmt.Errorf("set %s: %w", key, err)
+			}
+		}
+		env = append(env, key+"="+vars[key])
+	}
+	return env, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
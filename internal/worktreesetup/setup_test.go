@@ -0,0 +1,181 @@
+package worktreesetup
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+	"github.com/miltonparedes/kitmux/internal/tmux/tmuxtest"
+)
+
+func TestRunProvisionsWorktree(t *testing.T) {
+	main, path := newWorktree(t)
+	writeFile(t, main, ".env", "SECRET=1\n")
+	writeFile(t, filepath.Join(main, "node_modules", "left-pad"), "index.js", "module.exports = 1\n")
+	writeFile(t, filepath.Join(main, "cache"), "a.bin", "a")
+
+	srv := tmuxtest.Install(t)
+	srv.AddSession("app-feat", path)
+
+	steps := []config.SetupStep{
+		{Copy: []string{".env", ".env.local", "cache"}},
+		{Symlink: []string{"node_modules"}},
+		{Env: map[string]string{"PORT": "3001"}},
+		{Run: `echo "$PORT" > port.txt`},
+	}
+	var out bytes.Buffer
+	if err := Run(Target{Main: main, Path: path, Session: "app-feat"}, steps, &out); err != nil {
+		t.Fatalf("Run: %v\n%s", err, out.String())
+	}
+
+	if got := readFile(t, path, ".env"); got != "SECRET=1\n" {
+		t.Fatalf(".env = %q", got)
+	}
+	if got := readFile(t, path, "cache/a.bin"); got != "a" {
+		t.Fatalf("cache/a.bin = %q", got)
+	}
+	if link, err := os.Readlink(filepath.Join(path, "node_modules")); err != nil || link != filepath.Join(main, "node_modules") {
+		t.Fatalf("node_modules link = %q, %v", link, err)
+	}
+	if got := srv.Environment("app-feat", "PORT"); got != "3001" {
+		t.Fatalf("session PORT = %q", got)
+	}
+	if got := readFile(t, path, "port.txt"); got != "3001\n" {
+		t.Fatalf("port.txt = %q; run steps should see env steps", got)
+	}
+	if !strings.Contains(out.String(), ".env.local: not in the main worktree, skipped") {
+		t.Fatalf("missing source not reported:\n%s", out.String())
+	}
+
+	// A second run keeps what is already there.
+	writeFile(t, path, ".env", "SECRET=local\n")
+	out.Reset()
+	if err := Run(Target{Main: main, Path: path}, steps[:1], &out); err != nil {
+		t.Fatalf("rerun: %v", err)
+	}
+	if got := readFile(t, path, ".env"); got != "SECRET=local\n" {
+		t.Fatalf(".env overwritten: %q", got)
+	}
+}
+
+func TestRunStopsAtFailedStepAndKeepsWorktree(t *testing.T) {
+	main, path := newWorktree(t)
+
+	steps := []config.SetupStep{
+		{Run: "echo installing; exit 3"},
+		{Run: "touch after"},
+	}
+	var out bytes.Buffer
+	err := Run(Target{Main: main, Path: path}, steps, &out)
+	if err == nil || !strings.Contains(err.Error(), "step 1 (run)") {
+		t.Fatalf("err = %v", err)
+	}
+	if !strings.Contains(out.String(), "installing") {
+		t.Fatalf("command output not streamed:\n%s", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(path, "after")); !os.IsNotExist(err) {
+		t.Fatal("steps after a failure must not run")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("worktree removed: %v", err)
+	}
+}
+
+func TestRunRejectsPathsOutsideWorktree(t *testing.T) {
+	main, path := newWorktree(t)
+	err := Run(Target{Main: main, Path: path}, []config.SetupStep{{Copy: []string{"../secrets"}}}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("expected an error for a path outside the worktree")
+	}
+}
+
+func TestEnvMergesEnvSteps(t *testing.T) {
+	steps := []config.SetupStep{
+		{Env: map[string]string{"PORT": "3001", "MODE": "dev"}},
+		{Run: "true"},
+		{Env: map[string]string{"PORT": "3002"}},
+	}
+	got := Env(steps)
+	if len(got) != 2 || got["PORT"] != "3002" || got["MODE"] != "dev" {
+		t.Fatalf("Env = %v", got)
+	}
+	if Env(steps[1:2]) != nil {
+		t.Fatal("Env without env steps should be nil")
+	}
+}
+
+func TestExportPrefixesCommand(t *testing.T) {
+	got := Export(map[string]string{"PORT": "3001", "NAME": "it's"}, "exec codex")
+	want := `export NAME='it'"'"'s'; export PORT='3001'; exec codex`
+	if got != want {
+		t.Fatalf("Export = %q, want %q", got, want)
+	}
+	if Export(nil, "exec codex") != "exec codex" {
+		t.Fatal("Export without env should leave the command alone")
+	}
+}
+
+func TestCommandQuotesArguments(t *testing.T) {
+	got := Command("it's", "/src/app.feat", "/src/my app")
+	want := `kitmux worktrees setup --wait --session 'it'"'"'s' '/src/app.feat' '/src/my app'`
+	if got != want {
+		t.Fatalf("Command = %q, want %q", got, want)
+	}
+}
+
+func TestMainWorktree(t *testing.T) {
+	main, path := newWorktree(t)
+	got, err := MainWorktree(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved, _ := filepath.EvalSymlinks(main); got != main && got != resolved {
+		t.Fatalf("MainWorktree = %q, want %q", got, main)
+	}
+}
+
+// newWorktree creates a repository with one commit and a linked worktree.
+func newWorktree(t *testing.T) (main, path string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	main = filepath.Join(dir, "app")
+	path = filepath.Join(dir, "app.feat")
+	writeFile(t, main, "README.md", "app\n")
+	git(t, main, "init", "-q", "-b", "main")
+	git(t, main, "-c", "user.email=test@example.com", "-c", "user.name=Test", "commit", "-q", "--allow-empty", "-m", "initial")
+	git(t, main, "worktree", "add", "-q", "-b", "feat", path)
+	return main, path
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v (%s)", args, err, out)
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
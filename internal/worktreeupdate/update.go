@@ -0,0 +1,254 @@
+// Package worktreeupdate brings the linked worktrees of a workspace up to
+// date with the base branch, rebasing or merging one worktree at a time and
+// stopping at the first conflict.
+package worktreeupdate
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/miltonparedes/kitmux/internal/config"
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+// Methods of bringing a worktree up to date.
+const (
+	MethodRebase = "rebase"
+	MethodMerge  = "merge"
+)
+
+// Skip reasons for worktrees that are not updated.
+const (
+	SkipDirty    = "uncommitted changes"
+	SkipDetached = "detached HEAD"
+	SkipCurrent  = "up to date"
+)
+
+// Outcome statuses reported per worktree.
+const (
+	StatusUpdated  = "updated"
+	StatusSkipped  = "skipped"
+	StatusConflict = "conflict"
+	StatusFailed   = "failed"
+	StatusNotRun   = "not run"
+)
+
+// Candidate is a linked worktree of the workspace with the number of base
+// commits it is missing. Skip names the rule, or the error, that keeps it
+// out of the update.
+type Candidate struct {
+	Worktree wsdata.WorktreeStat
+	Behind   int
+	Skip     string
+}
+
+// Updatable reports whether the candidate is proposed for an update.
+func (c Candidate) Updatable() bool {
+	return c.Skip == ""
+}
+
+// Outcome is what happened to one worktree of the batch. Files lists the
+// conflicting paths of a conflict; Detail explains a skip or failure.
+type Outcome struct {
+	Worktree wsdata.WorktreeStat
+	Status   string
+	Detail   string
+	Files    []string
+}
+
+// ConflictError is returned by Ops.Update when the rebase or merge stopped
+// on conflicts. The update is aborted, leaving the worktree as it was.
+type ConflictError struct {
+	Files []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflict in %s", strings.Join(e.Files, ", "))
+}
+
+// Ops are the side effects of an update: worktree stats, the configured
+// base branch, git queries and the rebase or merge itself.
+type Ops struct {
+	LoadStats  func(workspace string) ([]wsdata.WorktreeStat, error)
+	BaseBranch func(workspace string) string
+	Behind     func(path, base string) (int, error)
+	Dirty      func(path string) (bool, error)
+	Update     func(path, base, method string) error
+	Invalidate func(workspace string)
+}
+
+// DefaultOps updates real worktrees with git and refreshes their cached
+// stats afterwards.
+func DefaultOps() Ops {
+	svc := wsdata.NewStatsService()
+	return Ops{
+		LoadStats: func(workspace string) ([]wsdata.WorktreeStat, error) {
+			res := svc.Refresh(workspace)
+			return res.Stats.Worktrees, res.Err
+		},
+		BaseBranch: func(workspace string) string {
+			repo, _ := config.LoadRepo(workspace)
+			return repo.BaseBranch
+		},
+		Behind: behind,
+		Dirty:  dirty,
+		Update: update,
+		Invalidate: func(workspace string) {
+			_ = svc.Invalidate(workspace)
+		},
+	}
+}
+
+// Plan lists the linked worktrees of workspace with how far behind the
+// base branch they are. The base is the repository's base_branch setting,
+// else the main worktree's branch. Stats are refreshed first so dirty state
+// is current. A worktree that cannot be compared is skipped with the error
+// as its reason.
+func Plan(workspace string, ops Ops) (base string, candidates []Candidate, err error) {
+	stats, err := ops.LoadStats(workspace)
+	if err != nil {
+		return "", nil, fmt.Errorf("load worktree stats: %w", err)
+	}
+	base = ops.BaseBranch(workspace)
+	for _, st := range stats {
+		if st.IsMain && base == "" {
+			base = st.Branch
+		}
+	}
+	if base == "" {
+		return "", nil, errors.New("no base branch: the main worktree has no branch checked out")
+	}
+	for _, st := range stats {
+		if st.IsMain || st.WorktreePath == "" || st.Branch == base {
+			continue
+		}
+		c := Candidate{Worktree: st}
+		behind, behindErr := ops.Behind(st.WorktreePath, base)
+		c.Behind = behind
+		switch {
+		case behindErr != nil:
+			// A prunable worktree whose directory is gone, say; keep
+			// planning the others.
+			c.Skip = behindErr.Error()
+		case st.Branch == "":
+			c.Skip = SkipDetached
+		case st.Dirty():
+			c.Skip = SkipDirty
+		case c.Behind == 0:
+			c.Skip = SkipCurrent
+		}
+		candidates = append(candidates, c)
+	}
+	return base, candidates, nil
+}
+
+// Apply updates the batch in order with method. A worktree that became
+// dirty since planning is skipped. The run stops at the first conflict; the
+// worktrees after it are reported as not run. Stats are invalidated at the
+// end so the dashboard picks up the new commits.
+func Apply(workspace, base, method string, batch []Candidate, ops Ops) []Outcome {
+	defer ops.Invalidate(workspace)
+	out := make([]Outcome, 0, len(batch))
+	stopped := false
+	for _, c := range batch {
+		o := Outcome{Worktree: c.Worktree}
+		switch {
+		case stopped:
+			o.Status = StatusNotRun
+		case c.Skip != "":
+			o.Status, o.Detail = StatusSkipped, c.Skip
+		default:
+			o = run(c, base, method, ops)
+			stopped = o.Status == StatusConflict
+		}
+		out = append(out, o)
+	}
+	return out
+}
+
+func run(c Candidate, base, method string, ops Ops) Outcome {
+	o := Outcome{Worktree: c.Worktree}
+	if isDirty, err := ops.Dirty(c.Worktree.WorktreePath); err != nil {
+		o.Status, o.Detail = StatusFailed, err.Error()
+		return o
+	} else if isDirty {
+		o.Status, o.Detail = StatusSkipped, SkipDirty
+		return o
+	}
+	err := ops.Update(c.Worktree.WorktreePath, base, method)
+	var conflict *ConflictError
+	switch {
+	case err == nil:
+		o.Status = StatusUpdated
+	case errors.As(err, &conflict):
+		o.Status, o.Files = StatusConflict, conflict.Files
+		o.Detail = "aborted, worktree unchanged"
+	default:
+		o.Status, o.Detail = StatusFailed, err.Error()
+	}
+	return o
+}
+
+// Summary counts outcomes by status, e.g. "2 updated, 1 skipped, 1 conflict".
+func Summary(outcomes []Outcome) string {
+	counts := map[string]int{}
+	for _, o := range outcomes {
+		counts[o.Status]++
+	}
+	var parts []string
+	for _, status := range []string{StatusUpdated, StatusSkipped, StatusConflict, StatusFailed, StatusNotRun} {
+		if counts[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+	if len(parts) == 0 {
+		return "nothing to update"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func behind(path, base string) (int, error) {
+	out, err := git(path, "rev-list", "--count", "HEAD.."+base)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(out))
+}
+
+func dirty(path string) (bool, error) {
+	out, err := git(path, "status", "--porcelain")
+	return strings.TrimSpace(out) != "", err
+}
+
+// update rebases or merges base into the worktree at path. On conflicts it
+// aborts and returns a *ConflictError naming the unmerged files.
+func update(path, base, method string) error {
+	args := []string{"rebase", base}
+	if method == MethodMerge {
+		args = []string{"merge", "--no-edit", base}
+	}
+	_, err := git(path, args...)
+	if err == nil {
+		return nil
+	}
+	files, _ := git(path, "diff", "--name-only", "--diff-filter=U")
+	if strings.TrimSpace(files) == "" {
+		return err
+	}
+	_, _ = git(path, args[0], "--abort")
+	return &ConflictError{Files: strings.Split(strings.TrimSpace(files), "\n")}
+}
+
+func git(dir string, args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return string(out), fmt.Errorf("git %s: %s", args[0], strings.SplitN(msg, "\n", 2)[0])
+		}
+		return string(out), fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,182 @@
+package worktreeupdate
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	wsdata "github.com/miltonparedes/kitmux/internal/workspaces/data"
+)
+
+type fakeOps struct {
+	stats   []wsdata.WorktreeStat
+	behind  map[string]int
+	broken  map[string]error
+	dirty   map[string]bool
+	results map[string]error
+	updated []string
+}
+
+func (f *fakeOps) ops() Ops {
+	return Ops{
+		LoadStats:  func(string) ([]wsdata.WorktreeStat, error) { return f.stats, nil },
+		BaseBranch: func(string) string { return "" },
+		Behind:     func(path, _ string) (int, error) { return f.behind[path], f.broken[path] },
+		Dirty:      func(path string) (bool, error) { return f.dirty[path], nil },
+		Update: func(path, _, _ string) error {
+			f.updated = append(f.updated, path)
+			return f.results[path]
+		},
+		Invalidate: func(string) {},
+	}
+}
+
+func TestPlanSkipsDirtyAndCurrentWorktrees(t *testing.T) {
+	f := &fakeOps{
+		stats: []wsdata.WorktreeStat{
+			{Branch: "main", WorktreePath: "/repo", IsMain: true},
+			{Branch: "behind", WorktreePath: "/wt/behind"},
+			{Branch: "dirty", WorktreePath: "/wt/dirty", Modified: true},
+			{Branch: "current", WorktreePath: "/wt/current"},
+		},
+		behind: map[string]int{"/wt/behind": 3, "/wt/dirty": 1},
+	}
+	base, candidates, err := Plan("/repo", f.ops())
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if base != "main" {
+		t.Fatalf("base = %q", base)
+	}
+	skips := map[string]string{}
+	for _, c := range candidates {
+		skips[c.Worktree.Branch] = c.Skip
+	}
+	want := map[string]string{"behind": "", "dirty": SkipDirty, "current": SkipCurrent}
+	if !reflect.DeepEqual(skips, want) {
+		t.Fatalf("skips = %v, want %v", skips, want)
+	}
+}
+
+func TestPlanSkipsWorktreesItCannotCompare(t *testing.T) {
+	f := &fakeOps{
+		stats: []wsdata.WorktreeStat{
+			{Branch: "main", WorktreePath: "/repo", IsMain: true},
+			{Branch: "gone", WorktreePath: "/wt/gone"},
+			{Branch: "behind", WorktreePath: "/wt/behind"},
+		},
+		behind: map[string]int{"/wt/behind": 2},
+		broken: map[string]error{"/wt/gone": errors.New("no such directory")},
+	}
+	_, candidates, err := Plan("/repo", f.ops())
+	if err != nil {
+		t.Fatalf("Plan() error = %v; one broken worktree must not abort the plan", err)
+	}
+	if len(candidates) != 2 || candidates[0].Skip != "no such directory" || !candidates[1].Updatable() {
+		t.Fatalf("candidates = %+v", candidates)
+	}
+}
+
+func TestApplyStopsAtFirstConflict(t *testing.T) {
+	f := &fakeOps{
+		dirty:   map[string]bool{"/wt/late-dirty": true},
+		results: map[string]error{"/wt/conflict": &ConflictError{Files: []string{"a.go"}}},
+	}
+	batch := []Candidate{
+		{Worktree: wsdata.WorktreeStat{Branch: "ok", WorktreePath: "/wt/ok"}},
+		{Worktree: wsdata.WorktreeStat{Branch: "late-dirty", WorktreePath: "/wt/late-dirty"}},
+		{Worktree: wsdata.WorktreeStat{Branch: "conflict", WorktreePath: "/wt/conflict"}},
+		{Worktree: wsdata.WorktreeStat{Branch: "after", WorktreePath: "/wt/after"}},
+	}
+	out := Apply("/repo", "main", MethodRebase, batch, f.ops())
+
+	var statuses []string
+	for _, o := range out {
+		statuses = append(statuses, o.Status)
+	}
+	want := []string{StatusUpdated, StatusSkipped, StatusConflict, StatusNotRun}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Fatalf("statuses = %v, want %v", statuses, want)
+	}
+	if !reflect.DeepEqual(out[2].Files, []string{"a.go"}) {
+		t.Fatalf("conflict files = %v", out[2].Files)
+	}
+	if !reflect.DeepEqual(f.updated, []string{"/wt/ok", "/wt/conflict"}) {
+		t.Fatalf("updated = %v", f.updated)
+	}
+	if got := Summary(out); got != "1 updated, 1 skipped, 1 conflict, 1 not run" {
+		t.Fatalf("Summary() = %q", got)
+	}
+}
+
+func TestUpdateRebasesAndAbortsConflicts(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	runGit(t, root, "init", "-q", "-b", "main", repo)
+	runGit(t, repo, "config", "user.email", "test@example.com")
+	runGit(t, repo, "config", "user.name", "Test")
+	commitFile(t, repo, "shared.txt", "base\n", "initial")
+	for _, branch := range []string{"clean", "clash"} {
+		runGit(t, repo, "worktree", "add", "-q", "-b", branch, filepath.Join(root, branch))
+	}
+	commitFile(t, filepath.Join(root, "clean"), "own.txt", "mine\n", "own work")
+	commitFile(t, filepath.Join(root, "clash"), "shared.txt", "clash\n", "clashing work")
+	commitFile(t, repo, "shared.txt", "moved\n", "main moves")
+
+	clean := filepath.Join(root, "clean")
+	if n, err := behind(clean, "main"); err != nil || n != 1 {
+		t.Fatalf("behind() = %d, %v", n, err)
+	}
+	for _, method := range []string{MethodRebase, MethodMerge} {
+		clash := filepath.Join(root, "clash")
+		head := revParse(t, clash)
+		err := update(clash, "main", method)
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) || !reflect.DeepEqual(conflict.Files, []string{"shared.txt"}) {
+			t.Fatalf("%s: update() error = %v", method, err)
+		}
+		if revParse(t, clash) != head {
+			t.Fatalf("%s: conflicting update moved HEAD", method)
+		}
+		if d, _ := dirty(clash); d {
+			t.Fatalf("%s: conflicting update left changes behind", method)
+		}
+	}
+
+	if err := update(clean, "main", MethodRebase); err != nil {
+		t.Fatalf("update() error = %v", err)
+	}
+	if n, _ := behind(clean, "main"); n != 0 {
+		t.Fatalf("still %d behind after rebase", n)
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func commitFile(t *testing.T, dir, name, content, msg string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", msg)
+}
+
+func revParse(t *testing.T, dir string) string {
+	t.Helper()
+	return strings.TrimSpace(runGit(t, dir, "rev-parse", "HEAD"))
+}